@@ -0,0 +1,211 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/cluster"
+	"github.com/banzaicloud/pipeline/internal/application"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+)
+
+// ApplicationAPI implements the multi-cluster application functions.
+type ApplicationAPI struct {
+	reconciler   *cluster.ApplicationReconciler
+	store        *application.Store
+	log          logrus.FieldLogger
+	errorHandler emperror.Handler
+}
+
+// NewApplicationAPI returns a new ApplicationAPI instance.
+func NewApplicationAPI(
+	reconciler *cluster.ApplicationReconciler,
+	store *application.Store,
+	log logrus.FieldLogger,
+	errorHandler emperror.Handler,
+) *ApplicationAPI {
+	return &ApplicationAPI{
+		reconciler:   reconciler,
+		store:        store,
+		log:          log,
+		errorHandler: errorHandler,
+	}
+}
+
+// CreateApplication creates an application and installs it on the matching clusters.
+func (a *ApplicationAPI) CreateApplication(c *gin.Context) {
+	request, ok := a.bindRequest(c)
+	if !ok {
+		return
+	}
+
+	app, err := a.store.Create(auth.GetCurrentOrganization(c.Request).ID, auth.GetCurrentUser(c.Request).ID, *request)
+	if err != nil {
+		a.handleError(c, err, "failed to create application")
+		return
+	}
+
+	a.reconciler.Sync(app.ID)
+
+	c.JSON(http.StatusCreated, app)
+}
+
+// ListApplications lists the applications of the organization.
+func (a *ApplicationAPI) ListApplications(c *gin.Context) {
+	applications, err := a.store.List(auth.GetCurrentOrganization(c.Request).ID)
+	if err != nil {
+		a.handleError(c, err, "failed to list applications")
+		return
+	}
+
+	c.JSON(http.StatusOK, applications)
+}
+
+// GetApplication returns an application with its sync status on every matching cluster.
+func (a *ApplicationAPI) GetApplication(c *gin.Context) {
+	applicationID, ok := a.getApplicationID(c)
+	if !ok {
+		return
+	}
+
+	app, err := a.store.Get(auth.GetCurrentOrganization(c.Request).ID, applicationID)
+	if err != nil {
+		a.handleError(c, err, "failed to get application")
+		return
+	}
+
+	c.JSON(http.StatusOK, app)
+}
+
+// UpdateApplication updates an application and upgrades it on the matching clusters.
+func (a *ApplicationAPI) UpdateApplication(c *gin.Context) {
+	applicationID, ok := a.getApplicationID(c)
+	if !ok {
+		return
+	}
+
+	request, ok := a.bindRequest(c)
+	if !ok {
+		return
+	}
+
+	app, err := a.store.Update(auth.GetCurrentOrganization(c.Request).ID, applicationID, *request)
+	if err != nil {
+		a.handleError(c, err, "failed to update application")
+		return
+	}
+
+	a.reconciler.Sync(app.ID)
+
+	c.JSON(http.StatusOK, app)
+}
+
+// SyncApplication installs or upgrades an application on every matching cluster that is out of sync.
+func (a *ApplicationAPI) SyncApplication(c *gin.Context) {
+	applicationID, ok := a.getApplicationID(c)
+	if !ok {
+		return
+	}
+
+	app, err := a.store.Get(auth.GetCurrentOrganization(c.Request).ID, applicationID)
+	if err != nil {
+		a.handleError(c, err, "failed to get application")
+		return
+	}
+
+	a.reconciler.Sync(app.ID)
+
+	c.JSON(http.StatusAccepted, app)
+}
+
+// DeleteApplication deletes an application and removes its release from every cluster in the background.
+func (a *ApplicationAPI) DeleteApplication(c *gin.Context) {
+	applicationID, ok := a.getApplicationID(c)
+	if !ok {
+		return
+	}
+
+	if err := a.reconciler.Delete(auth.GetCurrentOrganization(c.Request).ID, applicationID); err != nil {
+		a.handleError(c, err, "failed to delete application")
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+func (a *ApplicationAPI) bindRequest(c *gin.Context) (*application.Request, bool) {
+	var request application.Request
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "failed to parse request",
+			Error:   err.Error(),
+		})
+
+		return nil, false
+	}
+
+	if err := request.Validate(); err != nil {
+		a.handleError(c, err, "invalid application")
+
+		return nil, false
+	}
+
+	return &request, true
+}
+
+func (a *ApplicationAPI) getApplicationID(c *gin.Context) (uint, bool) {
+	applicationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid application ID",
+			Error:   err.Error(),
+		})
+
+		return 0, false
+	}
+
+	return uint(applicationID), true
+}
+
+func (a *ApplicationAPI) handleError(c *gin.Context, err error, message string) {
+	statusCode := http.StatusInternalServerError
+
+	switch errors.Cause(err) {
+	case application.ErrApplicationNotFound:
+		statusCode = http.StatusNotFound
+	case application.ErrInvalidApplication:
+		statusCode = http.StatusBadRequest
+	case application.ErrApplicationAlreadyExists:
+		statusCode = http.StatusConflict
+	default:
+		a.errorHandler.Handle(emperror.Wrap(err, message))
+	}
+
+	c.AbortWithStatusJSON(statusCode, pkgCommon.ErrorResponse{
+		Code:    statusCode,
+		Message: message,
+		Error:   err.Error(),
+	})
+}
@@ -0,0 +1,224 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/internal/audit"
+	"github.com/banzaicloud/pipeline/pkg/common"
+	"github.com/gin-gonic/gin"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultAuditPageSize is the number of audit events returned when no limit is requested.
+const defaultAuditPageSize = 100
+
+// Audit event export formats
+const (
+	auditExportCSV        = "csv"
+	auditExportJSONLines  = "jsonl"
+	auditExportTimeFormat = time.RFC3339Nano
+)
+
+// ListAuditEventsResponse is a page of audit events.
+type ListAuditEventsResponse struct {
+	Events     []audit.Event `json:"events"`
+	NextCursor string        `json:"nextCursor,omitempty"`
+}
+
+// AuditAPI implements the audit log query functions.
+type AuditAPI struct {
+	events       *audit.Events
+	log          logrus.FieldLogger
+	errorHandler emperror.Handler
+}
+
+// NewAuditAPI returns a new AuditAPI instance.
+func NewAuditAPI(events *audit.Events, log logrus.FieldLogger, errorHandler emperror.Handler) *AuditAPI {
+	return &AuditAPI{
+		events:       events,
+		log:          log,
+		errorHandler: errorHandler,
+	}
+}
+
+// ListEvents returns a page of the audit events of an organization, newest first.
+func (a *AuditAPI) ListEvents(c *gin.Context) {
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	filter, err := parseAuditEventFilter(c)
+	if err != nil {
+		a.handleError(c, err, "invalid audit event filter")
+		return
+	}
+
+	var cursor uint64
+	if value := c.Query("cursor"); value != "" {
+		cursor, err = strconv.ParseUint(value, 10, 0)
+		if err != nil {
+			a.handleError(c, errors.WithMessage(audit.ErrInvalidFilter, "invalid cursor"), "invalid audit event filter")
+			return
+		}
+	}
+
+	limit := defaultAuditPageSize
+	if value := c.Query("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > audit.MaxPageSize {
+			a.handleError(c, errors.WithMessagef(audit.ErrInvalidFilter, "limit must be between 1 and %d", audit.MaxPageSize), "invalid audit event filter")
+			return
+		}
+	}
+
+	events, next, err := a.events.List(organizationID, filter, uint(cursor), limit)
+	if err != nil {
+		a.handleError(c, err, "failed to list audit events")
+		return
+	}
+
+	response := ListAuditEventsResponse{Events: events}
+	if next != 0 {
+		response.NextCursor = strconv.FormatUint(uint64(next), 10)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ExportEvents streams every audit event of an organization matching the filter as CSV or JSON lines.
+func (a *AuditAPI) ExportEvents(c *gin.Context) {
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	filter, err := parseAuditEventFilter(c)
+	if err != nil {
+		a.handleError(c, err, "invalid audit event filter")
+		return
+	}
+
+	format := c.DefaultQuery("format", auditExportJSONLines)
+
+	var write func(audit.Event) error
+	var flush func() error
+
+	switch format {
+	case auditExportJSONLines:
+		c.Header("Content-Type", "application/x-ndjson")
+
+		encoder := json.NewEncoder(c.Writer)
+		write = func(event audit.Event) error {
+			return encoder.Encode(event)
+		}
+		flush = func() error {
+			return nil
+		}
+
+	case auditExportCSV:
+		c.Header("Content-Type", "text/csv")
+
+		writer := csv.NewWriter(c.Writer)
+		_ = writer.Write([]string{
+			"id", "time", "userId", "method", "path", "statusCode",
+			"responseTime", "responseSize", "clientIp", "userAgent", "correlationId",
+		})
+
+		write = func(event audit.Event) error {
+			return writer.Write([]string{
+				strconv.FormatUint(uint64(event.ID), 10),
+				event.Time.Format(auditExportTimeFormat),
+				strconv.FormatUint(uint64(event.UserID), 10),
+				event.Method,
+				event.Path,
+				strconv.Itoa(event.StatusCode),
+				strconv.Itoa(event.ResponseTime),
+				strconv.Itoa(event.ResponseSize),
+				event.ClientIP,
+				event.UserAgent,
+				event.CorrelationID,
+			})
+		}
+		flush = func() error {
+			writer.Flush()
+
+			return writer.Error()
+		}
+
+	default:
+		a.handleError(c, errors.WithMessage(audit.ErrInvalidFilter, "format must be csv or jsonl"), "invalid export format")
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=audit."+format)
+	c.Status(http.StatusOK)
+
+	err = a.events.Each(organizationID, filter, write)
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		// The response is already being streamed, the error can only be logged
+		a.errorHandler.Handle(emperror.WrapWith(err, "failed to export audit events", "organization", organizationID))
+	}
+}
+
+func parseAuditEventFilter(c *gin.Context) (audit.EventFilter, error) {
+	filter := audit.EventFilter{
+		Method:     c.Query("method"),
+		PathPrefix: c.Query("path"),
+		Status:     c.Query("status"),
+	}
+
+	if value := c.Query("userId"); value != "" {
+		userID, err := strconv.ParseUint(value, 10, 0)
+		if err != nil {
+			return filter, errors.WithMessage(audit.ErrInvalidFilter, "invalid user ID")
+		}
+		filter.UserID = uint(userID)
+	}
+
+	for param, target := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := c.Query(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return filter, errors.WithMessage(audit.ErrInvalidFilter, param+" must be an RFC3339 timestamp")
+			}
+			*target = &t
+		}
+	}
+
+	return filter, nil
+}
+
+func (a *AuditAPI) handleError(c *gin.Context, err error, message string) {
+	statusCode := http.StatusInternalServerError
+
+	if errors.Cause(err) == audit.ErrInvalidFilter {
+		statusCode = http.StatusBadRequest
+	} else {
+		a.errorHandler.Handle(emperror.Wrap(err, message))
+	}
+
+	c.AbortWithStatusJSON(statusCode, common.ErrorResponse{
+		Code:    statusCode,
+		Message: message,
+		Error:   err.Error(),
+	})
+}
@@ -39,6 +39,8 @@ import (
 	"github.com/banzaicloud/pipeline/internal/cloudinfo"
 	intCluster "github.com/banzaicloud/pipeline/internal/cluster"
 	"github.com/banzaicloud/pipeline/internal/cluster/resourcesummary"
+	"github.com/banzaicloud/pipeline/internal/clusterprofile"
+	"github.com/banzaicloud/pipeline/internal/cost"
 	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
 	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
 	"github.com/banzaicloud/pipeline/pkg/k8sclient"
@@ -59,6 +61,9 @@ type ClusterAPI struct {
 	errorHandler    emperror.Handler
 	clusterCreators ClusterCreators
 	clusterDeleters ClusterDeleters
+	clusterUpdaters ClusterUpdaters
+	costEstimator   *cost.Estimator
+	clusterProfiles *clusterprofile.Profiles
 }
 
 type ClusterCreators struct {
@@ -69,6 +74,10 @@ type ClusterDeleters struct {
 	PKEOnAzure driver.AzurePKEClusterDeleter
 }
 
+type ClusterUpdaters struct {
+	PKEOnAzure driver.AzurePKEClusterUpdater
+}
+
 // NewClusterAPI returns a new ClusterAPI instance.
 func NewClusterAPI(
 	clusterManager *cluster.Manager,
@@ -79,7 +88,9 @@ func NewClusterAPI(
 	externalBaseURL string,
 	clusterCreators ClusterCreators,
 	clusterDeleters ClusterDeleters,
-
+	clusterUpdaters ClusterUpdaters,
+	costEstimator *cost.Estimator,
+	clusterProfiles *clusterprofile.Profiles,
 ) *ClusterAPI {
 	return &ClusterAPI{
 		clusterManager:  clusterManager,
@@ -91,6 +102,9 @@ func NewClusterAPI(
 		errorHandler:    errorHandler,
 		clusterCreators: clusterCreators,
 		clusterDeleters: clusterDeleters,
+		clusterUpdaters: clusterUpdaters,
+		costEstimator:   costEstimator,
+		clusterProfiles: clusterProfiles,
 	}
 }
 
@@ -478,7 +492,7 @@ func (a *ClusterAPI) ProxyToCluster(c *gin.Context) {
 }
 
 // ListClusterSecrets returns
-func ListClusterSecrets(c *gin.Context) {
+func (a *SecretAPI) ListClusterSecrets(c *gin.Context) {
 	commonCluster, ok := getClusterFromRequest(c)
 	if !ok {
 		return
@@ -516,7 +530,7 @@ func ListClusterSecrets(c *gin.Context) {
 		query.Tags = append(query.Tags, releaseTag)
 	}
 
-	secrets, err := secret.RestrictedStore.List(organizationID, &query)
+	secrets, err := a.secrets.List(organizationID, &query)
 	if err != nil {
 		log.Errorf("Error during listing secrets: %s", err.Error())
 		c.AbortWithStatusJSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
@@ -527,6 +541,10 @@ func ListClusterSecrets(c *gin.Context) {
 		return
 	}
 
+	if query.Values && !a.authorizeSecretValues(c, secrets) {
+		return
+	}
+
 	log.Info("Listing secrets succeeded")
 
 	c.JSON(http.StatusOK, secrets)
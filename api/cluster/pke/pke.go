@@ -82,4 +82,7 @@ func (a *API) RegisterRoutes(r gin.IRouter) {
 	r.POST("leader", a.PostLeaderElection)
 	r.GET("leader", a.GetLeaderElection)
 	r.DELETE("leader", a.DeleteLeaderElection)
+	r.POST("upgrade", a.PostUpgrade)
+	r.POST("upgrade/pause", a.PostPauseUpgrade)
+	r.POST("upgrade/resume", a.PostResumeUpgrade)
 }
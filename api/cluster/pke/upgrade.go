@@ -0,0 +1,155 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pke
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/banzaicloud/pipeline/internal/providers/pke/pkeworkflow"
+	"github.com/banzaicloud/pipeline/pkg/common"
+	"github.com/gin-gonic/gin"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+	"go.uber.org/cadence/.gen/go/shared"
+	"go.uber.org/cadence/client"
+)
+
+// UpgradeRequest describes a Kubernetes version upgrade request
+type UpgradeRequest struct {
+	KubernetesVersion string `json:"kubernetesVersion" binding:"required"`
+}
+
+type upgrader interface {
+	UpgradePKECluster(ctx context.Context, kubernetesVersion string, workflowClient client.Client, externalBaseURL string) error
+}
+
+// PostUpgrade starts upgrading the Kubernetes version of the cluster
+func (a *API) PostUpgrade(c *gin.Context) {
+	commonCluster, log, ok := a.getCluster(c)
+	if !ok {
+		return
+	}
+
+	var request UpgradeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	clusterUpgrader, ok := commonCluster.(upgrader)
+	if !ok {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Kubernetes version upgrade is not supported for this cluster",
+		})
+		return
+	}
+
+	err := clusterUpgrader.UpgradePKECluster(c.Request.Context(), request.KubernetesVersion, a.workflowClient, a.externalBaseURL)
+	if err != nil {
+		if e, ok := errors.Cause(err).(interface{ IsInvalid() bool }); ok && e.IsInvalid() {
+			c.JSON(http.StatusBadRequest, common.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		a.errorHandler.Handle(err)
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "failed to start upgrade",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	log.WithField("kubernetesVersion", request.KubernetesVersion).Info("cluster upgrade started")
+
+	c.Status(http.StatusAccepted)
+}
+
+// PostPauseUpgrade pauses a running upgrade before replacing the next node
+func (a *API) PostPauseUpgrade(c *gin.Context) {
+	a.controlUpgrade(c, pkeworkflow.PauseUpgradeCommand)
+}
+
+// PostResumeUpgrade resumes a paused upgrade
+func (a *API) PostResumeUpgrade(c *gin.Context) {
+	a.controlUpgrade(c, pkeworkflow.ResumeUpgradeCommand)
+}
+
+func (a *API) controlUpgrade(c *gin.Context, command string) {
+	commonCluster, log, ok := a.getCluster(c)
+	if !ok {
+		return
+	}
+
+	workflowIDGetter, ok := commonCluster.(interface{ GetCurrentWorkflowID() string })
+	if !ok {
+		c.JSON(http.StatusBadRequest, common.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Kubernetes version upgrade is not supported for this cluster",
+		})
+		return
+	}
+	workflowID := workflowIDGetter.GetCurrentWorkflowID()
+
+	desc, err := a.workflowClient.DescribeWorkflowExecution(c.Request.Context(), workflowID, "")
+	if _, ok := err.(*shared.EntityNotExistsError); ok || (err == nil && !isRunningUpgrade(desc)) {
+		c.JSON(http.StatusNotFound, common.ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "there is no running upgrade for this cluster",
+		})
+		return
+	} else if err != nil {
+		err := emperror.Wrap(err, "could not describe workflow")
+		a.errorHandler.Handle(err)
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "could not describe workflow",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	err = a.workflowClient.SignalWorkflow(c.Request.Context(), workflowID, "", pkeworkflow.UpgradeClusterControlSignalName, command)
+	if err != nil {
+		err := emperror.Wrap(err, "could not signal workflow")
+		a.errorHandler.Handle(err)
+		c.JSON(http.StatusInternalServerError, common.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "could not signal workflow",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	log.WithField("command", command).Info("cluster upgrade signaled")
+
+	c.Status(http.StatusAccepted)
+}
+
+func isRunningUpgrade(desc *shared.DescribeWorkflowExecutionResponse) bool {
+	info := desc.WorkflowExecutionInfo
+
+	return info != nil && info.CloseStatus == nil && info.Type != nil && info.Type.GetName() == pkeworkflow.UpgradeClusterWorkflowName
+}
@@ -0,0 +1,251 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ghodss/yaml"
+	"github.com/gin-gonic/gin"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/cluster"
+	intAuth "github.com/banzaicloud/pipeline/internal/auth"
+	"github.com/banzaicloud/pipeline/internal/clusterbulk"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	pkgHelm "github.com/banzaicloud/pipeline/pkg/helm"
+	"github.com/banzaicloud/pipeline/secret"
+)
+
+// ClusterBulkAPI implements the bulk cluster operation functions.
+type ClusterBulkAPI struct {
+	runner       *cluster.BulkRunner
+	store        *clusterbulk.Store
+	enforcer     intAuth.Enforcer
+	log          logrus.FieldLogger
+	errorHandler emperror.Handler
+}
+
+// NewClusterBulkAPI returns a new ClusterBulkAPI instance.
+func NewClusterBulkAPI(
+	runner *cluster.BulkRunner,
+	store *clusterbulk.Store,
+	enforcer intAuth.Enforcer,
+	log logrus.FieldLogger,
+	errorHandler emperror.Handler,
+) *ClusterBulkAPI {
+	return &ClusterBulkAPI{
+		runner:       runner,
+		store:        store,
+		enforcer:     enforcer,
+		log:          log,
+		errorHandler: errorHandler,
+	}
+}
+
+// StartOperation starts a bulk operation on the selected clusters of the organization.
+func (a *ClusterBulkAPI) StartOperation(c *gin.Context) {
+	var request clusterbulk.Request
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "failed to parse request",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err := request.Validate(); err != nil {
+		a.handleError(c, err, "invalid cluster bulk operation")
+		return
+	}
+
+	organization := auth.GetCurrentOrganization(c.Request)
+	user := auth.GetCurrentUser(c.Request)
+
+	clusters, err := a.runner.Select(c.Request.Context(), organization.ID, request.Selector)
+	if err != nil {
+		a.handleError(c, err, "failed to select clusters")
+		return
+	}
+
+	// The action is authorized the same way as the single cluster endpoint it replaces.
+	for _, commonCluster := range clusters {
+		routes, err := clusterActionRoutes(organization.ID, commonCluster.GetID(), request)
+		if err != nil {
+			a.handleError(c, err, "invalid cluster bulk operation")
+			return
+		}
+
+		for _, route := range routes {
+			granted, err := a.enforcer.Enforce(organization, user, route.path, route.method)
+			if err != nil {
+				a.handleError(c, err, "failed to check permissions")
+				return
+			}
+
+			if !granted {
+				c.AbortWithStatusJSON(http.StatusForbidden, pkgCommon.ErrorResponse{
+					Code:    http.StatusForbidden,
+					Message: fmt.Sprintf("%s action is not allowed on cluster %s", request.Action, commonCluster.GetName()),
+				})
+				return
+			}
+		}
+	}
+
+	operation, err := a.runner.Start(organization.ID, user.ID, request, clusters)
+	if err != nil {
+		a.handleError(c, err, "failed to start cluster bulk operation")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, operation)
+}
+
+// ListOperations lists the bulk operations of the organization.
+func (a *ClusterBulkAPI) ListOperations(c *gin.Context) {
+	operations, err := a.store.List(auth.GetCurrentOrganization(c.Request).ID)
+	if err != nil {
+		a.handleError(c, err, "failed to list cluster bulk operations")
+		return
+	}
+
+	c.JSON(http.StatusOK, operations)
+}
+
+// GetOperation returns a bulk operation with the progress of every selected cluster.
+func (a *ClusterBulkAPI) GetOperation(c *gin.Context) {
+	operationID, ok := a.getOperationID(c)
+	if !ok {
+		return
+	}
+
+	operation, err := a.store.Get(auth.GetCurrentOrganization(c.Request).ID, operationID)
+	if err != nil {
+		a.handleError(c, err, "failed to get cluster bulk operation")
+		return
+	}
+
+	c.JSON(http.StatusOK, operation)
+}
+
+// CancelOperation cancels a running bulk operation.
+func (a *ClusterBulkAPI) CancelOperation(c *gin.Context) {
+	operationID, ok := a.getOperationID(c)
+	if !ok {
+		return
+	}
+
+	operation, err := a.runner.Cancel(auth.GetCurrentOrganization(c.Request).ID, operationID)
+	if err != nil {
+		a.handleError(c, err, "failed to cancel cluster bulk operation")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, operation)
+}
+
+// clusterRoute is a single cluster endpoint identified by its method and path.
+type clusterRoute struct {
+	method string
+	path   string
+}
+
+// clusterActionRoutes returns the single cluster endpoints of a bulk operation action.
+// The deployment action installs or upgrades the release depending on the cluster,
+// so it requires access to both the create and the upgrade endpoint.
+func clusterActionRoutes(organizationID uint, clusterID uint, request clusterbulk.Request) ([]clusterRoute, error) {
+	clusterPath := fmt.Sprintf("/api/v1/orgs/%d/clusters/%d", organizationID, clusterID)
+
+	switch request.Action {
+	case clusterbulk.PostHooksAction:
+		return []clusterRoute{{http.MethodPut, clusterPath + "/posthooks"}}, nil
+	case clusterbulk.InstallSecretAction:
+		var params clusterbulk.InstallSecretParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return nil, errors.WithMessage(clusterbulk.ErrInvalidOperation, "invalid action parameters: "+err.Error())
+		}
+
+		return []clusterRoute{{http.MethodPost, clusterPath + "/secrets/" + params.Name}}, nil
+	case clusterbulk.DeploymentAction:
+		var params pkgHelm.CreateUpdateDeploymentRequest
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return nil, errors.WithMessage(clusterbulk.ErrInvalidOperation, "invalid action parameters: "+err.Error())
+		}
+
+		routes := []clusterRoute{
+			{http.MethodPost, clusterPath + "/deployments"},
+			{http.MethodPut, clusterPath + "/deployments/" + params.ReleaseName},
+		}
+
+		// Referenced secrets are authorized the same way as by the single cluster endpoints.
+		values, err := yaml.Marshal(params.Values)
+		if err != nil {
+			return nil, errors.WithMessage(clusterbulk.ErrInvalidOperation, "invalid deployment values: "+err.Error())
+		}
+
+		for _, name := range cluster.SecretReferenceNames(string(values)) {
+			secretPath := fmt.Sprintf("/api/v1/orgs/%d/secrets/%s", organizationID, secret.GenerateSecretIDFromName(name))
+			routes = append(routes, clusterRoute{http.MethodGet, secretPath})
+		}
+
+		return routes, nil
+	default:
+		return []clusterRoute{{http.MethodDelete, clusterPath}}, nil
+	}
+}
+
+func (a *ClusterBulkAPI) getOperationID(c *gin.Context) (uint, bool) {
+	operationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid operation ID",
+			Error:   err.Error(),
+		})
+
+		return 0, false
+	}
+
+	return uint(operationID), true
+}
+
+func (a *ClusterBulkAPI) handleError(c *gin.Context, err error, message string) {
+	statusCode := http.StatusInternalServerError
+
+	switch errors.Cause(err) {
+	case clusterbulk.ErrOperationNotFound:
+		statusCode = http.StatusNotFound
+	case clusterbulk.ErrInvalidOperation:
+		statusCode = http.StatusBadRequest
+	case clusterbulk.ErrOperationFinished:
+		statusCode = http.StatusConflict
+	default:
+		a.errorHandler.Handle(emperror.Wrap(err, message))
+	}
+
+	c.AbortWithStatusJSON(statusCode, pkgCommon.ErrorResponse{
+		Code:    statusCode,
+		Message: message,
+		Error:   err.Error(),
+	})
+}
@@ -0,0 +1,76 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/banzaicloud/pipeline/internal/clusterbulk"
+	"github.com/banzaicloud/pipeline/secret"
+)
+
+func TestClusterActionRoutes(t *testing.T) {
+	t.Run("deployment", func(t *testing.T) {
+		request := clusterbulk.Request{
+			Action: clusterbulk.DeploymentAction,
+			Params: json.RawMessage(`{"name":"stable/nginx","releaseName":"web"}`),
+		}
+
+		routes, err := clusterActionRoutes(1, 2, request)
+		require.NoError(t, err)
+
+		assert.Equal(
+			t,
+			[]clusterRoute{
+				{http.MethodPost, "/api/v1/orgs/1/clusters/2/deployments"},
+				{http.MethodPut, "/api/v1/orgs/1/clusters/2/deployments/web"},
+			},
+			routes,
+		)
+	})
+
+	t.Run("deployment with secret references", func(t *testing.T) {
+		request := clusterbulk.Request{
+			Action: clusterbulk.DeploymentAction,
+			Params: json.RawMessage(`{"name":"stable/mysql","releaseName":"db","values":{"existingSecret":"{{ secretRef \"my-db\" }}"}}`),
+		}
+
+		routes, err := clusterActionRoutes(1, 2, request)
+		require.NoError(t, err)
+
+		assert.Contains(
+			t,
+			routes,
+			clusterRoute{http.MethodGet, "/api/v1/orgs/1/secrets/" + secret.GenerateSecretIDFromName("my-db")},
+		)
+	})
+
+	t.Run("invalid secret params", func(t *testing.T) {
+		request := clusterbulk.Request{
+			Action: clusterbulk.InstallSecretAction,
+			Params: json.RawMessage(`{"name":1}`),
+		}
+
+		_, err := clusterActionRoutes(1, 2, request)
+
+		assert.Equal(t, clusterbulk.ErrInvalidOperation, errors.Cause(err))
+	})
+}
@@ -0,0 +1,105 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goph/emperror"
+	"github.com/sirupsen/logrus"
+
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/cluster"
+	ginutils "github.com/banzaicloud/pipeline/internal/platform/gin/utils"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	"github.com/banzaicloud/pipeline/secret"
+)
+
+// CloneCluster creates a new cluster with the settings of an existing one.
+// The selected deployments and secrets are copied once the new cluster is running.
+func (a *ClusterAPI) CloneCluster(c *gin.Context) {
+	source, ok := a.clusterGetter.GetClusterFromRequest(c)
+	if !ok {
+		return
+	}
+
+	var request pkgCluster.CloneClusterRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "error parsing request",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if request.SecretId == "" && request.SecretName != "" {
+		request.SecretId = secret.GenerateSecretIDFromName(request.SecretName)
+	}
+
+	orgID := auth.GetCurrentOrganization(c.Request).ID
+	userID := auth.GetCurrentUser(c.Request).ID
+
+	logger := a.logger.WithFields(logrus.Fields{
+		"organization":    orgID,
+		"user":            userID,
+		"cluster":         request.Name,
+		"sourceClusterId": source.GetID(),
+	})
+
+	options := cluster.CloneOptions{
+		Name:           request.Name,
+		Location:       request.Location,
+		SecretID:       request.SecretId,
+		PostHooks:      request.PostHooks,
+		Deployments:    request.Deployments,
+		AllDeployments: request.AllDeployments,
+		Secrets:        request.Secrets,
+	}
+
+	createClusterRequest, warnings, err := cluster.NewCloneRequest(source, options)
+	if err != nil {
+		errResp := creationErrorResponse(logger, err)
+		c.JSON(errResp.Code, errResp)
+		return
+	}
+
+	ctx := ginutils.Context(context.Background(), c)
+
+	clone, errResp := a.createCluster(ctx, createClusterRequest, orgID, userID, createClusterRequest.PostHooks)
+	if errResp != nil {
+		c.JSON(errResp.Code, errResp)
+		return
+	}
+
+	if options.CopiesResources() {
+		go func() {
+			// the request context is canceled when the response is sent
+			err := a.clusterManager.CopyClusterResources(context.Background(), source, clone.GetID(), options)
+			if err != nil {
+				a.errorHandler.Handle(emperror.With(err, "clusterId", clone.GetID(), "sourceClusterId", source.GetID()))
+			}
+		}()
+	}
+
+	c.JSON(http.StatusAccepted, pkgCluster.CloneClusterResponse{
+		Name:       clone.GetName(),
+		ResourceID: clone.GetID(),
+		Warnings:   warnings,
+	})
+}
@@ -0,0 +1,124 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/banzaicloud/pipeline/api/common"
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/cluster"
+	"github.com/banzaicloud/pipeline/internal/cost"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	"github.com/gin-gonic/gin"
+	"github.com/goph/emperror"
+	"github.com/sirupsen/logrus"
+)
+
+// ClusterCostAPI implements the cluster cost estimation functions.
+type ClusterCostAPI struct {
+	clusterManager *cluster.Manager
+	clusterGetter  common.ClusterGetter
+	estimator      *cost.Estimator
+	log            logrus.FieldLogger
+	errorHandler   emperror.Handler
+}
+
+// NewClusterCostAPI returns a new ClusterCostAPI instance.
+func NewClusterCostAPI(
+	clusterManager *cluster.Manager,
+	clusterGetter common.ClusterGetter,
+	estimator *cost.Estimator,
+	log logrus.FieldLogger,
+	errorHandler emperror.Handler,
+) *ClusterCostAPI {
+	return &ClusterCostAPI{
+		clusterManager: clusterManager,
+		clusterGetter:  clusterGetter,
+		estimator:      estimator,
+		log:            log,
+		errorHandler:   errorHandler,
+	}
+}
+
+// GetClusterCost returns the estimated hourly, monthly and month-to-date cost of a cluster.
+func (a *ClusterCostAPI) GetClusterCost(c *gin.Context) {
+	commonCluster, ok := a.clusterGetter.GetClusterFromRequest(c)
+	if !ok {
+		return
+	}
+
+	clusterCost, err := a.estimateCluster(c, commonCluster, time.Now())
+	if err != nil {
+		a.errorHandler.Handle(emperror.With(err, "clusterId", commonCluster.GetID()))
+
+		c.AbortWithStatusJSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "failed to estimate cluster cost",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, clusterCost)
+}
+
+// GetOrganizationCost returns the estimated cost of every cluster of an organization.
+// Clusters whose cost cannot be estimated are left out of the rollup.
+func (a *ClusterCostAPI) GetOrganizationCost(c *gin.Context) {
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	clusters, err := a.clusterManager.GetClusters(c.Request.Context(), organizationID)
+	if err != nil {
+		a.errorHandler.Handle(emperror.With(err, "organization", organizationID))
+
+		c.AbortWithStatusJSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "failed to list clusters",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	now := time.Now()
+	clusterCosts := make([]cost.ClusterCost, 0, len(clusters))
+
+	for _, commonCluster := range clusters {
+		clusterCost, err := a.estimateCluster(c, commonCluster, now)
+		if err != nil {
+			a.errorHandler.Handle(emperror.With(err, "organization", organizationID, "clusterId", commonCluster.GetID()))
+			continue
+		}
+
+		clusterCosts = append(clusterCosts, *clusterCost)
+	}
+
+	c.JSON(http.StatusOK, cost.Rollup(clusterCosts))
+}
+
+func (a *ClusterCostAPI) estimateCluster(c *gin.Context, commonCluster cluster.CommonCluster, now time.Time) (*cost.ClusterCost, error) {
+	status, err := commonCluster.GetStatus()
+	if err != nil {
+		return nil, emperror.Wrap(err, "failed to get cluster status")
+	}
+
+	clusterCost, err := a.estimator.EstimateCluster(c.Request.Context(), status, now)
+	if err != nil {
+		return nil, emperror.Wrap(err, "failed to estimate cluster cost")
+	}
+
+	return clusterCost, nil
+}
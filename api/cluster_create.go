@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/mitchellh/mapstructure"
 
@@ -25,8 +26,10 @@ import (
 	"github.com/banzaicloud/pipeline/auth"
 	"github.com/banzaicloud/pipeline/cluster"
 	intCluster "github.com/banzaicloud/pipeline/internal/cluster"
+	"github.com/banzaicloud/pipeline/internal/clusterprofile"
+	"github.com/banzaicloud/pipeline/internal/cost"
 	ginutils "github.com/banzaicloud/pipeline/internal/platform/gin/utils"
-	"github.com/banzaicloud/pipeline/model/defaults"
+	"github.com/banzaicloud/pipeline/internal/providers/azure/pke/driver"
 	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
 	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
 	"github.com/banzaicloud/pipeline/secret"
@@ -36,6 +39,12 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// CreateClusterPlanResponse describes Pipeline's CreateCluster API response in dry run mode
+type CreateClusterPlanResponse struct {
+	*cluster.CreationPlan
+	Cost *cost.Estimate `json:"cost,omitempty"`
+}
+
 func decodeRequest(input map[string]interface{}, output interface{}) error {
 	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
 		Result:  output,
@@ -88,17 +97,25 @@ func (a *ClusterAPI) handleCreationError(ctx *gin.Context, err error) {
 func (a *ClusterAPI) CreateCluster(c *gin.Context) {
 	a.logger.Info("Cluster creation started")
 
-	ctx := ginutils.Context(context.Background(), c)
-
-	orgID := auth.GetCurrentOrganization(c.Request).ID
-	userID := auth.GetCurrentUser(c.Request).ID
-
 	var requestBody map[string]interface{}
 	if err := c.ShouldBindJSON(&requestBody); err != nil {
 		a.errorHandler.Handle(err)
 		pkgCommon.ErrorResponseWithStatus(c, http.StatusBadRequest, err)
 		return
 	}
+
+	a.createClusterFromRequestBody(c, requestBody)
+}
+
+// createClusterFromRequestBody creates a K8S cluster from a decoded create request.
+// With the dryRun query parameter set it only returns the creation plan.
+func (a *ClusterAPI) createClusterFromRequestBody(c *gin.Context, requestBody map[string]interface{}) {
+	ctx := ginutils.Context(context.Background(), c)
+
+	orgID := auth.GetCurrentOrganization(c.Request).ID
+	userID := auth.GetCurrentUser(c.Request).ID
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dryRun", "false"))
+
 	if _, ok := requestBody["type"]; !ok {
 		a.logger.Info("request body did not match v2 structure, trying legacy path")
 		var createClusterRequest pkgCluster.CreateClusterRequest
@@ -118,6 +135,17 @@ func (a *ClusterAPI) CreateCluster(c *gin.Context) {
 			createClusterRequest.SecretId = secret.GenerateSecretIDFromName(createClusterRequest.SecretName)
 		}
 
+		if dryRun {
+			plan, err := a.planCluster(ctx, &createClusterRequest, orgID, userID, createClusterRequest.PostHooks)
+			if err != nil {
+				c.JSON(err.Code, err)
+				return
+			}
+
+			c.JSON(http.StatusOK, plan)
+			return
+		}
+
 		commonCluster, err := a.createCluster(ctx, &createClusterRequest, orgID, userID, createClusterRequest.PostHooks)
 		if err != nil {
 			c.JSON(err.Code, err)
@@ -150,6 +178,11 @@ func (a *ClusterAPI) CreateCluster(c *gin.Context) {
 
 	switch createClusterRequestBase.Type {
 	case clusterAPI.PKEOnAzure:
+		if errResp := a.applyPKEOnAzureProfile(orgID, requestBody); errResp != nil {
+			ginutils.ReplyWithErrorResponse(c, errResp)
+			return
+		}
+
 		var req clusterAPI.CreatePKEOnAzureClusterRequest
 		if ok := a.parseRequest(c, requestBody, &req); !ok {
 			return
@@ -179,6 +212,18 @@ func (a *ClusterAPI) CreateCluster(c *gin.Context) {
 			}
 		}
 		params := req.ToAzurePKEClusterCreationParams(orgID, userID)
+
+		if dryRun {
+			plan, err := a.planPKEOnAzureCluster(ctx, params)
+			if err = emperror.Wrap(err, "failed to plan cluster from request"); err != nil {
+				a.handleCreationError(c, err)
+				return
+			}
+
+			c.JSON(http.StatusOK, plan)
+			return
+		}
+
 		azurePKECluster, err := a.clusterCreators.PKEOnAzure.Create(ctx, params)
 		if err = emperror.Wrap(err, "failed to create cluster from request"); err != nil {
 			a.handleCreationError(c, err)
@@ -199,6 +244,35 @@ func (a *ClusterAPI) CreateCluster(c *gin.Context) {
 	})
 }
 
+// applyPKEOnAzureProfile fills the fields missing from a PKE on Azure cluster creation request from the selected profile.
+func (a *ClusterAPI) applyPKEOnAzureProfile(organizationID uint, requestBody map[string]interface{}) *pkgCommon.ErrorResponse {
+	profileName, _ := requestBody["profileName"].(string)
+	if profileName == "" {
+		return nil
+	}
+
+	profile, err := a.clusterProfiles.Get(organizationID, clusterprofile.TypePKEOnAzure, profileName)
+	if err != nil {
+		return &pkgCommon.ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "error during getting profile",
+			Error:   err.Error(),
+		}
+	}
+
+	if _, ok := requestBody["location"]; !ok && profile.Location != "" {
+		requestBody["location"] = profile.Location
+	}
+
+	for key, value := range profile.Properties.PKEOnAzure {
+		if _, ok := requestBody[key]; !ok {
+			requestBody[key] = value
+		}
+	}
+
+	return nil
+}
+
 // createCluster creates a K8S cluster in the cloud.
 func (a *ClusterAPI) createCluster(
 	ctx context.Context,
@@ -213,48 +287,175 @@ func (a *ClusterAPI) createCluster(
 		"cluster":      createClusterRequest.Name,
 	})
 
+	createClusterRequest, commonCluster, errResp := a.prepareCreateClusterRequest(createClusterRequest, organizationID, userID, logger)
+	if errResp != nil {
+		return nil, errResp
+	}
+
+	creationCtx := a.newCreationContext(createClusterRequest, organizationID, userID, postHooks)
+
+	creator := cluster.NewClusterCreator(createClusterRequest, commonCluster, a.workflowClient)
+
+	commonCluster, err := a.clusterManager.CreateCluster(ctx, creationCtx, creator)
+	if err != nil {
+		return nil, creationErrorResponse(logger, err)
+	}
+
+	return commonCluster, nil
+}
+
+// planCluster validates a cluster creation request and returns what would be created without creating anything.
+func (a *ClusterAPI) planCluster(
+	ctx context.Context,
+	createClusterRequest *pkgCluster.CreateClusterRequest,
+	organizationID uint,
+	userID uint,
+	postHooks pkgCluster.PostHooks,
+) (*CreateClusterPlanResponse, *pkgCommon.ErrorResponse) {
+	logger := a.logger.WithFields(logrus.Fields{
+		"organization": organizationID,
+		"user":         userID,
+		"cluster":      createClusterRequest.Name,
+		"dryRun":       true,
+	})
+
+	createClusterRequest, commonCluster, errResp := a.prepareCreateClusterRequest(createClusterRequest, organizationID, userID, logger)
+	if errResp != nil {
+		return nil, errResp
+	}
+
+	creationCtx := a.newCreationContext(createClusterRequest, organizationID, userID, postHooks)
+
+	creator := cluster.NewClusterCreator(createClusterRequest, commonCluster, a.workflowClient)
+
+	plan, err := a.clusterManager.PlanClusterCreation(ctx, creationCtx, creator, createClusterRequest, commonCluster)
+	if err != nil {
+		return nil, creationErrorResponse(logger, err)
+	}
+
+	return a.estimatePlanCost(ctx, plan, logger), nil
+}
+
+// planPKEOnAzureCluster validates a PKE on Azure cluster creation request and returns what would be created without creating anything.
+func (a *ClusterAPI) planPKEOnAzureCluster(
+	ctx context.Context,
+	params driver.AzurePKEClusterCreationParams,
+) (*CreateClusterPlanResponse, error) {
+	logger := a.logger.WithFields(logrus.Fields{
+		"organization": params.OrganizationID,
+		"user":         params.CreatedBy,
+		"cluster":      params.Name,
+		"dryRun":       true,
+	})
+
+	params, err := a.clusterCreators.PKEOnAzure.Prepare(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	nodePools := make([]cluster.NodePoolPlan, 0, len(params.NodePools))
+	for _, nodePool := range params.NodePools {
+		nodePoolPlan := cluster.NodePoolPlan{
+			Name:         nodePool.Name,
+			Change:       cluster.NodePoolAdded,
+			InstanceType: nodePool.InstanceType,
+			Count:        nodePool.Count,
+			Autoscaling:  nodePool.Autoscaling,
+		}
+
+		if nodePool.Autoscaling {
+			nodePoolPlan.MinCount = nodePool.Min
+			nodePoolPlan.MaxCount = nodePool.Max
+		}
+
+		nodePools = append(nodePools, nodePoolPlan)
+	}
+
+	postHooks := make(pkgCluster.PostHooks, len(params.Features))
+	for _, feature := range params.Features {
+		postHooks[feature.Kind] = feature.Params
+	}
+
+	plan := cluster.NewTypedCreationPlan(pkgCluster.Azure, pkgCluster.PKE, params.Network.Location, nodePools, postHooks)
+
+	return a.estimatePlanCost(ctx, plan, logger), nil
+}
+
+// estimatePlanCost attaches the estimated cost of the planned node pools to a creation plan.
+func (a *ClusterAPI) estimatePlanCost(ctx context.Context, plan *cluster.CreationPlan, logger logrus.FieldLogger) *CreateClusterPlanResponse {
+	response := &CreateClusterPlanResponse{
+		CreationPlan: plan,
+	}
+
+	if a.costEstimator != nil {
+		nodePools := make([]cost.NodePool, 0, len(plan.NodePools))
+		for _, nodePool := range plan.NodePools {
+			nodePools = append(nodePools, cost.NodePool{
+				Name:         nodePool.Name,
+				InstanceType: nodePool.InstanceType,
+				Count:        nodePool.Count,
+				Spot:         nodePool.Spot,
+			})
+		}
+
+		estimate, err := a.costEstimator.EstimateNodePools(ctx, plan.Cloud, plan.Distribution, plan.Location, nodePools)
+		if err != nil {
+			logger.Warnf("failed to estimate cluster cost: %s", err.Error())
+
+			plan.Warnings = append(plan.Warnings, "cost estimate is not available: "+err.Error())
+		} else {
+			response.Cost = estimate
+		}
+	}
+
+	return response
+}
+
+// prepareCreateClusterRequest fills the request from the selected profile and creates a (not yet persisted) cluster from it.
+func (a *ClusterAPI) prepareCreateClusterRequest(
+	createClusterRequest *pkgCluster.CreateClusterRequest,
+	organizationID uint,
+	userID uint,
+	logger logrus.FieldLogger,
+) (*pkgCluster.CreateClusterRequest, cluster.CommonCluster, *pkgCommon.ErrorResponse) {
 	// TODO: refactor profile handling as well?
 	if len(createClusterRequest.ProfileName) != 0 {
 		logger = logger.WithField("profile", createClusterRequest.ProfileName)
 
 		logger.Info("fill data from profile")
 
-		distribution := pkgCluster.Unknown
-		switch createClusterRequest.Cloud {
-		case pkgCluster.Amazon:
-			distribution = pkgCluster.EKS
-		case pkgCluster.Azure:
-			distribution = pkgCluster.AKS
-		case pkgCluster.Google:
-			distribution = pkgCluster.GKE
-		case pkgCluster.Oracle:
-			distribution = pkgCluster.OKE
-		default:
-			return nil, &pkgCommon.ErrorResponse{
+		// The profile replaces the properties of the request, so they are not accepted instead of being discarded
+		if createClusterRequest.Properties != nil && createClusterRequest.Properties.CreateClusterPKE != nil {
+			return nil, nil, &pkgCommon.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "PKE properties cannot be combined with a profile, set the distribution to pke instead",
+			}
+		}
+
+		profileType, err := clusterprofile.ProfileType(createClusterRequest.Cloud, createClusterRequest.Distribution)
+		if err != nil {
+			return nil, nil, &pkgCommon.ErrorResponse{
 				Code:    http.StatusBadRequest,
 				Message: "unsupported cloud type",
-				Error:   "unsupported cloud type",
+				Error:   err.Error(),
 			}
 		}
 
-		profile, err := defaults.GetProfile(distribution, createClusterRequest.ProfileName)
+		profileResponse, err := a.clusterProfiles.Get(organizationID, profileType, createClusterRequest.ProfileName)
 		if err != nil {
-			return nil, &pkgCommon.ErrorResponse{
+			return nil, nil, &pkgCommon.ErrorResponse{
 				Code:    http.StatusNotFound,
 				Message: "error during getting profile",
 				Error:   err.Error(),
 			}
 		}
 
-		logger.Info("create profile response")
-		profileResponse := profile.GetProfile()
-
 		logger.Info("create cluster request from profile")
 		newRequest, err := profileResponse.CreateClusterRequest(createClusterRequest)
 		if err != nil {
 			logger.Errorf("error during getting cluster request from profile: %s", err.Error())
 
-			return nil, &pkgCommon.ErrorResponse{
+			return nil, nil, &pkgCommon.ErrorResponse{
 				Code:    http.StatusBadRequest,
 				Message: "Error creating request from profile",
 				Error:   err.Error(),
@@ -278,14 +479,23 @@ func (a *ClusterAPI) createCluster(
 	commonCluster, err := cluster.CreateCommonClusterFromRequest(createClusterRequest, organizationID, userID)
 	if err != nil {
 		log.Errorf("error during create common cluster from request: %s", err.Error())
-		return nil, &pkgCommon.ErrorResponse{
+		return nil, nil, &pkgCommon.ErrorResponse{
 			Code:    http.StatusBadRequest,
 			Message: err.Error(),
 			Error:   err.Error(),
 		}
 	}
 
-	creationCtx := cluster.CreationContext{
+	return createClusterRequest, commonCluster, nil
+}
+
+func (a *ClusterAPI) newCreationContext(
+	createClusterRequest *pkgCluster.CreateClusterRequest,
+	organizationID uint,
+	userID uint,
+	postHooks pkgCluster.PostHooks,
+) cluster.CreationContext {
+	return cluster.CreationContext{
 		OrganizationID:  organizationID,
 		UserID:          userID,
 		Name:            createClusterRequest.Name,
@@ -295,28 +505,24 @@ func (a *ClusterAPI) createCluster(
 		PostHooks:       postHooks,
 		ExternalBaseURL: a.externalBaseURL,
 	}
+}
 
-	creator := cluster.NewClusterCreator(createClusterRequest, commonCluster, a.workflowClient)
-
-	commonCluster, err = a.clusterManager.CreateCluster(ctx, creationCtx, creator)
-
+func creationErrorResponse(logger logrus.FieldLogger, err error) *pkgCommon.ErrorResponse {
 	if err == cluster.ErrAlreadyExists || isInvalid(err) {
 		logger.Debugf("invalid cluster creation: %s", err.Error())
 
-		return nil, &pkgCommon.ErrorResponse{
+		return &pkgCommon.ErrorResponse{
 			Code:    http.StatusBadRequest,
 			Message: err.Error(),
 			Error:   err.Error(),
 		}
-	} else if err != nil {
-		logger.Errorf("error during cluster creation: %s", err.Error())
-
-		return nil, &pkgCommon.ErrorResponse{
-			Code:    http.StatusInternalServerError,
-			Message: err.Error(),
-			Error:   err.Error(),
-		}
 	}
 
-	return commonCluster, nil
+	logger.Errorf("error during cluster creation: %s", err.Error())
+
+	return &pkgCommon.ErrorResponse{
+		Code:    http.StatusInternalServerError,
+		Message: err.Error(),
+		Error:   err.Error(),
+	}
 }
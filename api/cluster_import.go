@@ -0,0 +1,99 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/cluster"
+	ginutils "github.com/banzaicloud/pipeline/internal/platform/gin/utils"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	"github.com/banzaicloud/pipeline/secret"
+)
+
+// importClusterID is the cluster ID path segment of the cluster import endpoint.
+const importClusterID = "import"
+
+// ImportCluster imports an existing EKS, GKE or AKS cluster and its node pools.
+// The router doesn't allow a static path segment next to the cluster ID wildcard,
+// so this handler is registered on the cluster ID path and only serves the import segment.
+func (a *ClusterAPI) ImportCluster(c *gin.Context) {
+	if c.Param("id") != importClusterID {
+		c.JSON(http.StatusNotFound, pkgCommon.ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "not found",
+		})
+		return
+	}
+
+	var request pkgCluster.ImportClusterRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "error parsing request",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if request.SecretId == "" {
+		if request.SecretName == "" {
+			c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "either secretId or secretName has to be set",
+			})
+			return
+		}
+
+		request.SecretId = secret.GenerateSecretIDFromName(request.SecretName)
+	}
+
+	orgID := auth.GetCurrentOrganization(c.Request).ID
+	userID := auth.GetCurrentUser(c.Request).ID
+
+	logger := a.logger.WithFields(logrus.Fields{
+		"organization": orgID,
+		"user":         userID,
+		"cluster":      request.Name,
+	})
+
+	importCtx := cluster.ImportContext{
+		OrganizationID: orgID,
+		UserID:         userID,
+		Name:           request.Name,
+		Provider:       request.Cloud,
+		Location:       request.Location,
+		SecretID:       request.SecretId,
+		ResourceGroup:  request.ResourceGroup,
+	}
+
+	commonCluster, err := a.clusterManager.ImportCluster(ginutils.Context(context.Background(), c), importCtx)
+	if err != nil {
+		errResp := creationErrorResponse(logger, err)
+		c.JSON(errResp.Code, errResp)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, pkgCluster.CreateClusterResponse{
+		Name:       commonCluster.GetName(),
+		ResourceID: commonCluster.GetID(),
+	})
+}
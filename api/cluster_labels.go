@@ -0,0 +1,122 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/banzaicloud/pipeline/api/common"
+	"github.com/banzaicloud/pipeline/cluster"
+	"github.com/banzaicloud/pipeline/internal/clusterlabel"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+)
+
+// ClusterLabelAPI implements the cluster label functions.
+type ClusterLabelAPI struct {
+	store         *clusterlabel.Store
+	reconciler    *cluster.ApplicationReconciler
+	clusterGetter common.ClusterGetter
+	log           logrus.FieldLogger
+	errorHandler  emperror.Handler
+}
+
+// NewClusterLabelAPI returns a new ClusterLabelAPI instance.
+func NewClusterLabelAPI(
+	store *clusterlabel.Store,
+	reconciler *cluster.ApplicationReconciler,
+	clusterGetter common.ClusterGetter,
+	log logrus.FieldLogger,
+	errorHandler emperror.Handler,
+) *ClusterLabelAPI {
+	return &ClusterLabelAPI{
+		store:         store,
+		reconciler:    reconciler,
+		clusterGetter: clusterGetter,
+		log:           log,
+		errorHandler:  errorHandler,
+	}
+}
+
+// GetLabels returns the labels of a cluster.
+func (a *ClusterLabelAPI) GetLabels(c *gin.Context) {
+	commonCluster, ok := a.clusterGetter.GetClusterFromRequest(c)
+	if !ok {
+		return
+	}
+
+	labels, err := a.store.Get(commonCluster.GetID())
+	if err != nil {
+		a.handleError(c, err, "failed to get cluster labels")
+		return
+	}
+
+	c.JSON(http.StatusOK, labels)
+}
+
+// SetLabels replaces the labels of a cluster and syncs the applications selecting the cluster by its labels.
+func (a *ClusterLabelAPI) SetLabels(c *gin.Context) {
+	commonCluster, ok := a.clusterGetter.GetClusterFromRequest(c)
+	if !ok {
+		return
+	}
+
+	var labels map[string]string
+	if err := c.ShouldBindJSON(&labels); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "failed to parse request",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err := a.store.Set(commonCluster.GetID(), labels); err != nil {
+		a.handleError(c, err, "failed to set cluster labels")
+		return
+	}
+
+	// the labels are saved, failing to sync applications is only reported
+	if err := a.reconciler.ClusterChanged(commonCluster); err != nil {
+		a.errorHandler.Handle(emperror.With(err, "clusterId", commonCluster.GetID()))
+	}
+
+	if labels == nil {
+		labels = map[string]string{}
+	}
+
+	c.JSON(http.StatusOK, labels)
+}
+
+func (a *ClusterLabelAPI) handleError(c *gin.Context, err error, message string) {
+	statusCode := http.StatusInternalServerError
+
+	switch errors.Cause(err) {
+	case clusterlabel.ErrInvalidLabels:
+		statusCode = http.StatusBadRequest
+	default:
+		a.errorHandler.Handle(emperror.Wrap(err, message))
+	}
+
+	c.AbortWithStatusJSON(statusCode, pkgCommon.ErrorResponse{
+		Code:    statusCode,
+		Message: message,
+		Error:   err.Error(),
+	})
+}
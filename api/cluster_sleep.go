@@ -0,0 +1,208 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/banzaicloud/pipeline/api/common"
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/cluster"
+	"github.com/banzaicloud/pipeline/internal/clustersleep"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	"github.com/gin-gonic/gin"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// ClusterSleepAPI implements the cluster sleep schedule functions.
+type ClusterSleepAPI struct {
+	store         *clustersleep.Store
+	sleeper       *cluster.Sleeper
+	clusterGetter common.ClusterGetter
+	log           logrus.FieldLogger
+	errorHandler  emperror.Handler
+}
+
+// ClusterSleepScheduleResponse describes a cluster sleep schedule.
+type ClusterSleepScheduleResponse struct {
+	*clustersleep.Schedule
+
+	Warning string `json:"warning,omitempty"`
+}
+
+// ClusterSleepRequest describes a manual cluster sleep request.
+type ClusterSleepRequest struct {
+	ScaleToMinimum bool `json:"scaleToMinimum,omitempty"`
+}
+
+// ClusterSleepResponse describes the response of a manual cluster sleep request.
+type ClusterSleepResponse struct {
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// NewClusterSleepAPI returns a new ClusterSleepAPI instance.
+func NewClusterSleepAPI(
+	store *clustersleep.Store,
+	sleeper *cluster.Sleeper,
+	clusterGetter common.ClusterGetter,
+	log logrus.FieldLogger,
+	errorHandler emperror.Handler,
+) *ClusterSleepAPI {
+	return &ClusterSleepAPI{
+		store:         store,
+		sleeper:       sleeper,
+		clusterGetter: clusterGetter,
+		log:           log,
+		errorHandler:  errorHandler,
+	}
+}
+
+// GetSleepSchedule returns the sleep schedule of a cluster.
+func (a *ClusterSleepAPI) GetSleepSchedule(c *gin.Context) {
+	commonCluster, ok := a.clusterGetter.GetClusterFromRequest(c)
+	if !ok {
+		return
+	}
+
+	schedule, err := a.store.GetSchedule(commonCluster.GetID())
+	if err != nil {
+		a.handleError(c, err, "failed to get sleep schedule")
+		return
+	}
+
+	c.JSON(http.StatusOK, newClusterSleepScheduleResponse(commonCluster, schedule))
+}
+
+// SetSleepSchedule creates or updates the sleep schedule of a cluster.
+func (a *ClusterSleepAPI) SetSleepSchedule(c *gin.Context) {
+	commonCluster, ok := a.clusterGetter.GetClusterFromRequest(c)
+	if !ok {
+		return
+	}
+
+	var request clustersleep.Schedule
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "failed to parse request",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	schedule, err := a.store.SetSchedule(commonCluster.GetID(), auth.GetCurrentUser(c.Request).ID, request)
+	if err != nil {
+		a.handleError(c, err, "failed to set sleep schedule")
+		return
+	}
+
+	c.JSON(http.StatusOK, newClusterSleepScheduleResponse(commonCluster, schedule))
+}
+
+// DeleteSleepSchedule deletes the sleep schedule of a cluster.
+func (a *ClusterSleepAPI) DeleteSleepSchedule(c *gin.Context) {
+	commonCluster, ok := a.clusterGetter.GetClusterFromRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := a.store.DeleteSchedule(commonCluster.GetID()); err != nil {
+		a.handleError(c, err, "failed to delete sleep schedule")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// SleepCluster puts a cluster to sleep immediately.
+func (a *ClusterSleepAPI) SleepCluster(c *gin.Context) {
+	commonCluster, ok := a.clusterGetter.GetClusterFromRequest(c)
+	if !ok {
+		return
+	}
+
+	var request ClusterSleepRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "failed to parse request",
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+
+	warnings, err := a.sleeper.Sleep(c.Request.Context(), commonCluster, auth.GetCurrentUser(c.Request).ID, request.ScaleToMinimum)
+	if err != nil {
+		a.handleError(c, err, "failed to put cluster to sleep")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, ClusterSleepResponse{
+		Warnings: warnings,
+	})
+}
+
+// WakeCluster wakes a sleeping cluster up immediately.
+func (a *ClusterSleepAPI) WakeCluster(c *gin.Context) {
+	commonCluster, ok := a.clusterGetter.GetClusterFromRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := a.sleeper.Wake(c.Request.Context(), commonCluster, auth.GetCurrentUser(c.Request).ID); err != nil {
+		a.handleError(c, err, "failed to wake cluster up")
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+func newClusterSleepScheduleResponse(commonCluster cluster.CommonCluster, schedule *clustersleep.Schedule) ClusterSleepScheduleResponse {
+	response := ClusterSleepScheduleResponse{
+		Schedule: schedule,
+	}
+
+	if !cluster.CanSleep(commonCluster) {
+		response.Warning = fmt.Sprintf("%s clusters cannot be put to sleep, the schedule has no effect", commonCluster.GetCloud())
+	}
+
+	return response
+}
+
+func (a *ClusterSleepAPI) handleError(c *gin.Context, err error, message string) {
+	statusCode := http.StatusInternalServerError
+
+	switch cause := errors.Cause(err); {
+	case cause == clustersleep.ErrScheduleNotFound:
+		statusCode = http.StatusNotFound
+	case cause == clustersleep.ErrInvalidSchedule, isInvalid(err):
+		statusCode = http.StatusBadRequest
+	case isPreconditionFailed(err):
+		statusCode = http.StatusPreconditionFailed
+	default:
+		a.errorHandler.Handle(emperror.Wrap(err, message))
+	}
+
+	c.AbortWithStatusJSON(statusCode, pkgCommon.ErrorResponse{
+		Code:    statusCode,
+		Message: message,
+		Error:   err.Error(),
+	})
+}
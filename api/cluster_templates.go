@@ -0,0 +1,242 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/internal/clustertemplate"
+	"github.com/banzaicloud/pipeline/pkg/common"
+	"github.com/gin-gonic/gin"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// ClusterTemplateRequest describes a cluster template create or update request.
+type ClusterTemplateRequest struct {
+	Name        string                     `json:"name"`
+	Description string                     `json:"description,omitempty"`
+	Variables   []clustertemplate.Variable `json:"variables,omitempty"`
+	Template    map[string]interface{}     `json:"template" binding:"required"`
+}
+
+// InstantiateClusterTemplateRequest describes a cluster template instantiation request.
+// A zero version instantiates the latest version of the template.
+type InstantiateClusterTemplateRequest struct {
+	Version   uint                   `json:"version,omitempty"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+// ClusterTemplateAPI implements the cluster template functions.
+type ClusterTemplateAPI struct {
+	templates    *clustertemplate.Templates
+	clusterAPI   *ClusterAPI
+	log          logrus.FieldLogger
+	errorHandler emperror.Handler
+}
+
+// NewClusterTemplateAPI returns a new ClusterTemplateAPI instance.
+func NewClusterTemplateAPI(
+	templates *clustertemplate.Templates,
+	clusterAPI *ClusterAPI,
+	log logrus.FieldLogger,
+	errorHandler emperror.Handler,
+) *ClusterTemplateAPI {
+	return &ClusterTemplateAPI{
+		templates:    templates,
+		clusterAPI:   clusterAPI,
+		log:          log,
+		errorHandler: errorHandler,
+	}
+}
+
+// ListTemplates lists the latest version of the cluster templates of an organization.
+func (a *ClusterTemplateAPI) ListTemplates(c *gin.Context) {
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	templates, err := a.templates.List(organizationID)
+	if err != nil {
+		a.handleError(c, err, "failed to list cluster templates")
+		return
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+// GetTemplate returns a version of a cluster template, the latest one by default.
+func (a *ClusterTemplateAPI) GetTemplate(c *gin.Context) {
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	version, ok := getTemplateVersion(c)
+	if !ok {
+		return
+	}
+
+	template, err := a.templates.Get(organizationID, c.Param("name"), version)
+	if err != nil {
+		a.handleError(c, err, "failed to get cluster template")
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// ListTemplateVersions lists every version of a cluster template.
+func (a *ClusterTemplateAPI) ListTemplateVersions(c *gin.Context) {
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	templates, err := a.templates.Versions(organizationID, c.Param("name"))
+	if err != nil {
+		a.handleError(c, err, "failed to list cluster template versions")
+		return
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+// CreateTemplate creates a new cluster template.
+func (a *ClusterTemplateAPI) CreateTemplate(c *gin.Context) {
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+	userID := auth.GetCurrentUser(c.Request).ID
+
+	var request ClusterTemplateRequest
+	if !bindClusterTemplateRequest(c, &request) {
+		return
+	}
+
+	template, err := a.templates.Create(organizationID, userID, request.toTemplate())
+	if err != nil {
+		a.handleError(c, err, "failed to create cluster template")
+		return
+	}
+
+	c.JSON(http.StatusCreated, template)
+}
+
+// UpdateTemplate stores a new version of a cluster template.
+func (a *ClusterTemplateAPI) UpdateTemplate(c *gin.Context) {
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+	userID := auth.GetCurrentUser(c.Request).ID
+
+	var request ClusterTemplateRequest
+	if !bindClusterTemplateRequest(c, &request) {
+		return
+	}
+	request.Name = c.Param("name")
+
+	template, err := a.templates.Update(organizationID, userID, request.toTemplate())
+	if err != nil {
+		a.handleError(c, err, "failed to update cluster template")
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// DeleteTemplate deletes every version of a cluster template.
+func (a *ClusterTemplateAPI) DeleteTemplate(c *gin.Context) {
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	if err := a.templates.Delete(organizationID, c.Param("name")); err != nil {
+		a.handleError(c, err, "failed to delete cluster template")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// InstantiateTemplate renders a cluster template and creates a cluster from it through the normal create flow.
+// The dryRun query parameter is handled the same way as for cluster creation.
+func (a *ClusterTemplateAPI) InstantiateTemplate(c *gin.Context) {
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	var request InstantiateClusterTemplateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, common.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "failed to parse request",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	requestBody, err := a.templates.Render(organizationID, c.Param("name"), request.Version, request.Variables)
+	if err != nil {
+		a.handleError(c, err, "failed to render cluster template")
+		return
+	}
+
+	a.clusterAPI.createClusterFromRequestBody(c, requestBody)
+}
+
+func (r ClusterTemplateRequest) toTemplate() clustertemplate.Template {
+	return clustertemplate.Template{
+		Name:        r.Name,
+		Description: r.Description,
+		Variables:   r.Variables,
+		Template:    r.Template,
+	}
+}
+
+func bindClusterTemplateRequest(c *gin.Context, request *ClusterTemplateRequest) bool {
+	if err := c.ShouldBindJSON(request); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, common.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "failed to parse request",
+			Error:   err.Error(),
+		})
+		return false
+	}
+
+	return true
+}
+
+func getTemplateVersion(c *gin.Context) (uint, bool) {
+	version, err := strconv.ParseUint(c.DefaultQuery("version", "0"), 10, 0)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, common.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid template version",
+			Error:   err.Error(),
+		})
+		return 0, false
+	}
+
+	return uint(version), true
+}
+
+func (a *ClusterTemplateAPI) handleError(c *gin.Context, err error, message string) {
+	statusCode := http.StatusInternalServerError
+
+	switch errors.Cause(err) {
+	case clustertemplate.ErrTemplateNotFound:
+		statusCode = http.StatusNotFound
+	case clustertemplate.ErrTemplateAlreadyExists:
+		statusCode = http.StatusConflict
+	case clustertemplate.ErrInvalidTemplate, clustertemplate.ErrInvalidVariables:
+		statusCode = http.StatusBadRequest
+	default:
+		a.errorHandler.Handle(emperror.Wrap(err, message))
+	}
+
+	c.AbortWithStatusJSON(statusCode, common.ErrorResponse{
+		Code:    statusCode,
+		Message: message,
+		Error:   err.Error(),
+	})
+}
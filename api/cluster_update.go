@@ -17,6 +17,7 @@ package api
 import (
 	"context"
 	"net/http"
+	"strconv"
 
 	"github.com/banzaicloud/pipeline/auth"
 	"github.com/banzaicloud/pipeline/cluster"
@@ -61,36 +62,34 @@ func (a *ClusterAPI) UpdateCluster(c *gin.Context) {
 		ClusterID:      commonCluster.GetID(),
 	}
 
+	if commonCluster.GetDistribution() == pkgCluster.PKE && commonCluster.GetCloud() == pkgCluster.Azure {
+		var err error
+		commonCluster, err = a.clusterUpdaters.PKEOnAzure.UpdatableCluster(commonCluster, updateCtx.UserID)
+		if err != nil {
+			a.handleUpdateError(c, err)
+			return
+		}
+	}
+
 	updater := cluster.NewCommonClusterUpdater(updateRequest, commonCluster, updateCtx.UserID, a.workflowClient, a.externalBaseURL)
 
 	ctx := ginutils.Context(context.Background(), c)
 
-	err := a.clusterManager.UpdateCluster(ctx, updateCtx, updater)
-	if err != nil {
-		if isInvalid(err) {
-			c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
-				Code:    http.StatusBadRequest,
-				Message: errors.Cause(err).Error(),
-			})
-
+	if dryRun, _ := strconv.ParseBool(c.DefaultQuery("dryRun", "false")); dryRun {
+		plan, err := a.clusterManager.PlanClusterUpdate(ctx, updateCtx, updater)
+		if err != nil {
+			a.handleUpdateError(c, err)
 			return
-		} else if isPreconditionFailed(err) {
-			c.JSON(http.StatusPreconditionFailed, pkgCommon.ErrorResponse{
-				Code:    http.StatusPreconditionFailed,
-				Message: errors.Cause(err).Error(),
-			})
-
-			return
-		} else {
-			errorHandler.Handle(err)
+		}
 
-			c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
-				Code:    http.StatusInternalServerError,
-				Message: "cluster update failed",
-			})
+		c.JSON(http.StatusOK, plan)
+		return
+	}
 
-			return
-		}
+	err := a.clusterManager.UpdateCluster(ctx, updateCtx, updater)
+	if err != nil {
+		a.handleUpdateError(c, err)
+		return
 	}
 
 	c.JSON(http.StatusAccepted, UpdateClusterResponse{
@@ -122,37 +121,49 @@ func (a *ClusterAPI) UpdateNodePools(c *gin.Context) {
 		ClusterID:      commonCluster.GetID(),
 	}
 
+	if commonCluster.GetDistribution() == pkgCluster.PKE && commonCluster.GetCloud() == pkgCluster.Azure {
+		var err error
+		commonCluster, err = a.clusterUpdaters.PKEOnAzure.UpdatableCluster(commonCluster, updateCtx.UserID)
+		if err != nil {
+			a.handleUpdateError(c, err)
+			return
+		}
+	}
+
 	updater := cluster.NewCommonNodepoolUpdater(updateRequest, commonCluster, updateCtx.UserID)
 	ctx := ginutils.Context(context.Background(), c)
 	err := a.clusterManager.UpdateCluster(ctx, updateCtx, updater)
 	if err != nil {
-		if isInvalid(err) {
-			c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
-				Code:    http.StatusBadRequest,
-				Message: errors.Cause(err).Error(),
-			})
+		a.handleUpdateError(c, err)
+		return
+	}
 
-			return
-		} else if isPreconditionFailed(err) {
-			c.JSON(http.StatusPreconditionFailed, pkgCommon.ErrorResponse{
-				Code:    http.StatusPreconditionFailed,
-				Message: errors.Cause(err).Error(),
-			})
+	c.JSON(http.StatusAccepted, UpdateClusterResponse{
+		Status: http.StatusAccepted,
+	})
+}
 
-			return
-		} else {
-			errorHandler.Handle(err)
+func (a *ClusterAPI) handleUpdateError(c *gin.Context, err error) {
+	if isInvalid(err) {
+		c.JSON(http.StatusBadRequest, pkgCommon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: errors.Cause(err).Error(),
+		})
 
-			c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
-				Code:    http.StatusInternalServerError,
-				Message: "cluster update failed",
-			})
+		return
+	} else if isPreconditionFailed(err) {
+		c.JSON(http.StatusPreconditionFailed, pkgCommon.ErrorResponse{
+			Code:    http.StatusPreconditionFailed,
+			Message: errors.Cause(err).Error(),
+		})
 
-			return
-		}
+		return
 	}
 
-	c.JSON(http.StatusAccepted, UpdateClusterResponse{
-		Status: http.StatusAccepted,
+	errorHandler.Handle(err)
+
+	c.JSON(http.StatusInternalServerError, pkgCommon.ErrorResponse{
+		Code:    http.StatusInternalServerError,
+		Message: "cluster update failed",
 	})
 }
@@ -18,26 +18,32 @@ import (
 	"encoding/base64"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/banzaicloud/pipeline/auth"
 	"github.com/banzaicloud/pipeline/cluster"
 	"github.com/banzaicloud/pipeline/helm"
+	intAuth "github.com/banzaicloud/pipeline/internal/auth"
 	"github.com/banzaicloud/pipeline/internal/platform/gin/correlationid"
 	pkgCommmon "github.com/banzaicloud/pipeline/pkg/common"
 	pkgHelm "github.com/banzaicloud/pipeline/pkg/helm"
+	"github.com/banzaicloud/pipeline/secret"
 	"github.com/ghodss/yaml"
 	"github.com/gin-gonic/gin"
+	"github.com/goph/emperror"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	k8sHelm "k8s.io/helm/pkg/helm"
-	"k8s.io/helm/pkg/helm/environment"
 	"k8s.io/helm/pkg/proto/hapi/release"
 	rls "k8s.io/helm/pkg/proto/hapi/services"
 	"k8s.io/helm/pkg/repo"
 )
 
+// defaultDeploymentHistoryMax is the number of revisions returned by default, the same as in the helm CLI
+const defaultDeploymentHistoryMax = 256
+
 // ChartQuery describes a query to get available helm chart's list
 type ChartQuery struct {
 	Name    string `form:"name"`
@@ -65,8 +71,20 @@ func GetK8sConfig(c *gin.Context) ([]byte, bool) {
 	return kubeConfig, true
 }
 
+// DeploymentAPI implements the Helm deployment functions that resolve secret references of the user.
+type DeploymentAPI struct {
+	enforcer intAuth.Enforcer
+}
+
+// NewDeploymentAPI returns a new DeploymentAPI instance.
+func NewDeploymentAPI(enforcer intAuth.Enforcer) *DeploymentAPI {
+	return &DeploymentAPI{
+		enforcer: enforcer,
+	}
+}
+
 // CreateDeployment creates a Helm deployment
-func CreateDeployment(c *gin.Context) {
+func (a *DeploymentAPI) CreateDeployment(c *gin.Context) {
 	commonCluster, ok := getClusterFromRequest(c)
 	if ok != true {
 		return
@@ -82,6 +100,18 @@ func CreateDeployment(c *gin.Context) {
 		return
 	}
 
+	parsedRequest.values, err = cluster.ResolveDeploymentSecrets(
+		commonCluster,
+		parsedRequest.namespace,
+		parsedRequest.values,
+		parsedRequest.dryRun,
+		a.secretReferenceAuthorizer(c),
+	)
+	if err != nil {
+		replyWithSecretReferenceError(c, err)
+		return
+	}
+
 	installOptions := []k8sHelm.InstallOption{
 		k8sHelm.InstallWait(parsedRequest.wait),
 		k8sHelm.ValueOverrides(parsedRequest.values),
@@ -391,7 +421,7 @@ func GetTillerStatus(c *gin.Context) {
 }
 
 //UpgradeDeployment - Upgrades helm deployment, if --reuse-value is specified reuses the last release's value.
-func UpgradeDeployment(c *gin.Context) {
+func (a *DeploymentAPI) UpgradeDeployment(c *gin.Context) {
 	name := c.Param("name")
 	log.Infof("Upgrading deployment: %s", name)
 	commonCluster, ok := getClusterFromRequest(c)
@@ -409,6 +439,12 @@ func UpgradeDeployment(c *gin.Context) {
 		return
 	}
 
+	parsedRequest.values, err = resolveReleaseSecrets(commonCluster, name, parsedRequest.values, false, a.secretReferenceAuthorizer(c))
+	if err != nil {
+		replyWithSecretReferenceError(c, err)
+		return
+	}
+
 	release, err := helm.UpgradeDeployment(name, parsedRequest.deploymentName,
 		parsedRequest.deploymentVersion, parsedRequest.deploymentPackage, parsedRequest.values,
 		parsedRequest.reuseValues, parsedRequest.kubeConfig, helm.GenerateHelmRepoEnv(parsedRequest.organizationName))
@@ -434,6 +470,53 @@ func UpgradeDeployment(c *gin.Context) {
 	return
 }
 
+// DiffDeployment shows the changes an upgrade of a helm deployment would make without applying it
+func (a *DeploymentAPI) DiffDeployment(c *gin.Context) {
+	name := c.Param("name")
+	log.Infof("diffing deployment upgrade: [%s]", name)
+	commonCluster, ok := getClusterFromRequest(c)
+	if !ok {
+		return
+	}
+	parsedRequest, err := parseCreateUpdateDeploymentRequest(c, commonCluster)
+	if err != nil {
+		log.Error(err.Error())
+		c.JSON(http.StatusBadRequest, pkgCommmon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error during parsing request!",
+			Error:   errors.Cause(err).Error(),
+		})
+		return
+	}
+
+	parsedRequest.values, err = resolveReleaseSecrets(commonCluster, name, parsedRequest.values, true, a.secretReferenceAuthorizer(c))
+	if err != nil {
+		replyWithSecretReferenceError(c, err)
+		return
+	}
+
+	response, err := helm.DiffDeployment(name, parsedRequest.deploymentName,
+		parsedRequest.deploymentVersion, parsedRequest.deploymentPackage, parsedRequest.values,
+		parsedRequest.reuseValues, parsedRequest.kubeConfig, helm.GenerateHelmRepoEnv(parsedRequest.organizationName))
+	if err != nil {
+		httpStatusCode := http.StatusInternalServerError
+		if _, ok := err.(*helm.DeploymentNotFoundError); ok {
+			httpStatusCode = http.StatusNotFound
+		} else {
+			log.Error("Error during diffing deployment upgrade: ", err.Error())
+		}
+
+		c.JSON(httpStatusCode, pkgCommmon.ErrorResponse{
+			Code:    httpStatusCode,
+			Message: "Error diffing deployment upgrade",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
 //DeleteDeployment deletes a Helm deployment
 func DeleteDeployment(c *gin.Context) {
 	name := c.Param("name")
@@ -460,6 +543,152 @@ func DeleteDeployment(c *gin.Context) {
 	})
 }
 
+// GetDeploymentHistory lists the revisions of a helm deployment
+func GetDeploymentHistory(c *gin.Context) {
+	name := c.Param("name")
+	log.Infof("getting history of deployment: [%s]", name)
+
+	max := int64(defaultDeploymentHistoryMax)
+	if maxParam := c.Query("max"); maxParam != "" {
+		var err error
+		max, err = strconv.ParseInt(maxParam, 10, 32)
+		if err != nil || max < 1 {
+			c.JSON(http.StatusBadRequest, pkgCommmon.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "invalid max parameter",
+			})
+			return
+		}
+	}
+
+	kubeConfig, ok := GetK8sConfig(c)
+	if !ok {
+		return
+	}
+
+	revisions, err := helm.GetDeploymentHistory(name, kubeConfig, int32(max))
+	if err != nil {
+		httpStatusCode := http.StatusInternalServerError
+		if _, ok := err.(*helm.DeploymentNotFoundError); ok {
+			httpStatusCode = http.StatusNotFound
+		} else {
+			log.Error("Error during getting deployment history: ", err.Error())
+		}
+
+		c.JSON(httpStatusCode, pkgCommmon.ErrorResponse{
+			Code:    httpStatusCode,
+			Message: "Error getting deployment history",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+// RollbackDeployment rolls a helm deployment back to a previous revision
+func RollbackDeployment(c *gin.Context) {
+	name := c.Param("name")
+	log.Infof("rolling back deployment: [%s]", name)
+
+	var request pkgHelm.RollbackDeploymentRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, pkgCommmon.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error parsing request",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	kubeConfig, ok := GetK8sConfig(c)
+	if !ok {
+		return
+	}
+
+	response, err := helm.RollbackDeployment(name, kubeConfig, request)
+	if err != nil {
+		httpStatusCode := http.StatusInternalServerError
+		if _, ok := err.(*helm.DeploymentNotFoundError); ok {
+			httpStatusCode = http.StatusNotFound
+		} else {
+			log.Error("Error during rolling back deployment: ", err.Error())
+		}
+
+		c.JSON(httpStatusCode, pkgCommmon.ErrorResponse{
+			Code:    httpStatusCode,
+			Message: "Error rolling back deployment",
+			Error:   err.Error(),
+		})
+		return
+	}
+	log.Infof("Rollback of deployment [%s] to revision %d succeeded", name, request.Revision)
+
+	c.JSON(http.StatusOK, response)
+}
+
+// secretReferenceAuthorizer allows referencing the secrets the current user is allowed to read.
+func (a *DeploymentAPI) secretReferenceAuthorizer(c *gin.Context) cluster.SecretReferenceAuthorizer {
+	organization := auth.GetCurrentOrganization(c.Request)
+	user := auth.GetCurrentUser(c.Request)
+
+	return func(secretID string) error {
+		path := fmt.Sprintf("/api/v1/orgs/%d/secrets/%s", organization.ID, secretID)
+
+		granted, err := a.enforcer.Enforce(organization, user, path, http.MethodGet)
+		if err != nil {
+			return emperror.WrapWith(err, "failed to check permissions", "secret", secretID)
+		}
+
+		if !granted {
+			return errors.WithMessage(cluster.ErrSecretReferenceDenied, secretID)
+		}
+
+		return nil
+	}
+}
+
+// replyWithSecretReferenceError responds with the error of resolving the secret references of deployment values.
+func replyWithSecretReferenceError(c *gin.Context, err error) {
+	log.Errorf("Error during resolving secret references. %s", err.Error())
+
+	statusCode := http.StatusBadRequest
+	if errors.Cause(err) == cluster.ErrSecretReferenceDenied {
+		statusCode = http.StatusForbidden
+	}
+
+	c.JSON(statusCode, pkgCommmon.ErrorResponse{
+		Code:    statusCode,
+		Message: "Error resolving secret references",
+		Error:   err.Error(),
+	})
+}
+
+// resolveReleaseSecrets resolves the secret references of the values of an existing release in its namespace
+func resolveReleaseSecrets(
+	commonCluster cluster.CommonCluster,
+	releaseName string,
+	values []byte,
+	dryRun bool,
+	authorize cluster.SecretReferenceAuthorizer,
+) ([]byte, error) {
+	if !cluster.HasSecretReference(string(values)) {
+		return values, nil
+	}
+
+	kubeConfig, err := commonCluster.GetK8sConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "Error getting kubeconfig:")
+	}
+
+	deployment, err := helm.GetDeployment(releaseName, kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return cluster.ResolveDeploymentSecrets(commonCluster, deployment.Namespace, values, dryRun, authorize)
+}
+
 type parsedDeploymentRequest struct {
 	deploymentName        string
 	deploymentVersion     string
@@ -525,7 +754,7 @@ func HelmReposGet(c *gin.Context) {
 
 	log.Info("Get helm repository")
 
-	response, err := helm.ReposGet(helm.GenerateHelmRepoEnv(auth.GetCurrentOrganization(c.Request).Name))
+	response, err := helm.ReposGet(auth.GetCurrentOrganization(c.Request).Name)
 	if err != nil {
 		log.Errorf("Error during get helm repo list: %s", err.Error())
 		c.JSON(http.StatusInternalServerError, pkgCommmon.ErrorResponse{
@@ -543,7 +772,7 @@ func HelmReposGet(c *gin.Context) {
 func HelmReposAdd(c *gin.Context) {
 	log.Info("Add helm repository")
 
-	var r *repo.Entry
+	var r pkgHelm.RepositoryRequest
 	err := c.BindJSON(&r)
 	if err != nil {
 		log.Errorf("Error parsing request: %s", err.Error())
@@ -554,9 +783,10 @@ func HelmReposAdd(c *gin.Context) {
 		})
 		return
 	}
+	resolveRepositorySecret(&r)
 
-	helmEnv := helm.GenerateHelmRepoEnv(auth.GetCurrentOrganization(c.Request).Name)
-	_, err = helm.ReposAdd(helmEnv, r)
+	orgName := auth.GetCurrentOrganization(c.Request).Name
+	_, err = helm.ReposAdd(orgName, r)
 	if err != nil {
 		log.Errorf("Error adding helm repo: %s", err.Error())
 		c.JSON(http.StatusBadRequest, pkgCommmon.ErrorResponse{
@@ -567,7 +797,7 @@ func HelmReposAdd(c *gin.Context) {
 		return
 	}
 
-	sendResponseWithRepo(c, helmEnv, r.Name)
+	sendResponseWithRepo(c, orgName, r.Name)
 
 	return
 }
@@ -578,11 +808,10 @@ func HelmReposDelete(c *gin.Context) {
 
 	repoName := c.Param("name")
 	log.Debugf("repoName: %s", repoName)
-	helmEnv := helm.GenerateHelmRepoEnv(auth.GetCurrentOrganization(c.Request).Name)
-	err := helm.ReposDelete(helmEnv, repoName)
+	err := helm.ReposDelete(auth.GetCurrentOrganization(c.Request).Name, repoName)
 	if err != nil {
 		log.Error("Error during get helm repo delete.", err.Error())
-		if err.Error() == helm.ErrRepoNotFound.Error() {
+		if err == helm.ErrRepoNotFound {
 			c.JSON(http.StatusOK, pkgHelm.DeleteResponse{
 				Status:  http.StatusOK,
 				Message: err.Error(),
@@ -612,7 +841,7 @@ func HelmReposModify(c *gin.Context) {
 	repoName := c.Param("name")
 	log.Debugf("repoName: %s", repoName)
 
-	var newRepo *repo.Entry
+	var newRepo pkgHelm.RepositoryRequest
 	err := c.BindJSON(&newRepo)
 	if err != nil {
 		log.Errorf("Error parsing request: %s", err.Error())
@@ -623,8 +852,10 @@ func HelmReposModify(c *gin.Context) {
 		})
 		return
 	}
-	helmEnv := helm.GenerateHelmRepoEnv(auth.GetCurrentOrganization(c.Request).Name)
-	errModify := helm.ReposModify(helmEnv, repoName, newRepo)
+	resolveRepositorySecret(&newRepo)
+
+	orgName := auth.GetCurrentOrganization(c.Request).Name
+	errModify := helm.ReposModify(orgName, repoName, newRepo)
 	if errModify != nil {
 		if errModify == helm.ErrRepoNotFound {
 			c.JSON(http.StatusNotFound, pkgCommmon.ErrorResponse{
@@ -644,7 +875,11 @@ func HelmReposModify(c *gin.Context) {
 		return
 	}
 
-	sendResponseWithRepo(c, helmEnv, newRepo.Name)
+	if newRepo.Name != "" {
+		repoName = newRepo.Name
+	}
+
+	sendResponseWithRepo(c, orgName, repoName)
 
 	return
 }
@@ -655,8 +890,8 @@ func HelmReposUpdate(c *gin.Context) {
 
 	repoName := c.Param("name")
 	log.Debugf("repoName: %s", repoName)
-	helmEnv := helm.GenerateHelmRepoEnv(auth.GetCurrentOrganization(c.Request).Name)
-	errUpdate := helm.ReposUpdate(helmEnv, repoName)
+	orgName := auth.GetCurrentOrganization(c.Request).Name
+	errUpdate := helm.ReposUpdate(orgName, repoName)
 	if errUpdate != nil {
 		log.Errorf("Error during helm repo update. %s", errUpdate.Error())
 		c.JSON(http.StatusNotFound, pkgCommmon.ErrorResponse{
@@ -667,11 +902,18 @@ func HelmReposUpdate(c *gin.Context) {
 		return
 	}
 
-	sendResponseWithRepo(c, helmEnv, repoName)
+	sendResponseWithRepo(c, orgName, repoName)
 
 	return
 }
 
+// resolveRepositorySecret fills the secret ID of a repository request referencing a secret by name
+func resolveRepositorySecret(request *pkgHelm.RepositoryRequest) {
+	if request.SecretID == "" && request.SecretName != "" {
+		request.SecretID = secret.GenerateSecretIDFromName(request.SecretName)
+	}
+}
+
 //HelmCharts get available helm chart's list
 func HelmCharts(c *gin.Context) {
 	log.Info("Get helm repository charts")
@@ -742,9 +984,9 @@ func HelmChart(c *gin.Context) {
 	return
 }
 
-func sendResponseWithRepo(c *gin.Context, helmEnv environment.EnvSettings, repoName string) {
+func sendResponseWithRepo(c *gin.Context, orgName string, repoName string) {
 
-	entries, err := helm.ReposGet(helmEnv)
+	entries, err := helm.ReposGet(orgName)
 	if err != nil {
 		log.Errorf("Error during getting helm repo: %s", err.Error())
 		c.JSON(http.StatusBadRequest, pkgCommmon.ErrorResponse{
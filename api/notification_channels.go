@@ -0,0 +1,252 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/internal/notification"
+	"github.com/banzaicloud/pipeline/pkg/common"
+	"github.com/gin-gonic/gin"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultDeliveryListLimit is the number of deliveries returned when no limit is requested.
+const defaultDeliveryListLimit = 50
+
+// NotificationChannelAPI implements the notification channel functions.
+type NotificationChannelAPI struct {
+	channels     *notification.Channels
+	notifier     *notification.Notifier
+	log          logrus.FieldLogger
+	errorHandler emperror.Handler
+}
+
+// NewNotificationChannelAPI returns a new NotificationChannelAPI instance.
+func NewNotificationChannelAPI(
+	channels *notification.Channels,
+	notifier *notification.Notifier,
+	log logrus.FieldLogger,
+	errorHandler emperror.Handler,
+) *NotificationChannelAPI {
+	return &NotificationChannelAPI{
+		channels:     channels,
+		notifier:     notifier,
+		log:          log,
+		errorHandler: errorHandler,
+	}
+}
+
+// ListChannels lists the notification channels of an organization.
+func (a *NotificationChannelAPI) ListChannels(c *gin.Context) {
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	channels, err := a.channels.List(organizationID)
+	if err != nil {
+		a.handleError(c, err, "failed to list notification channels")
+		return
+	}
+
+	c.JSON(http.StatusOK, channels)
+}
+
+// GetChannel returns a notification channel.
+func (a *NotificationChannelAPI) GetChannel(c *gin.Context) {
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	channelID, ok := getChannelID(c)
+	if !ok {
+		return
+	}
+
+	channel, err := a.channels.Get(organizationID, channelID)
+	if err != nil {
+		a.handleError(c, err, "failed to get notification channel")
+		return
+	}
+
+	c.JSON(http.StatusOK, channel)
+}
+
+// CreateChannel creates a notification channel. Channels are enabled unless requested otherwise.
+func (a *NotificationChannelAPI) CreateChannel(c *gin.Context) {
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	request := notification.Channel{Enabled: true}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, common.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "failed to parse request",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	channel, err := a.channels.Create(organizationID, request)
+	if err != nil {
+		a.handleError(c, err, "failed to create notification channel")
+		return
+	}
+
+	c.JSON(http.StatusCreated, channel)
+}
+
+// UpdateChannel replaces a notification channel.
+func (a *NotificationChannelAPI) UpdateChannel(c *gin.Context) {
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	channelID, ok := getChannelID(c)
+	if !ok {
+		return
+	}
+
+	request := notification.Channel{Enabled: true}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, common.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "failed to parse request",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	channel, err := a.channels.Update(organizationID, channelID, request)
+	if err != nil {
+		a.handleError(c, err, "failed to update notification channel")
+		return
+	}
+
+	c.JSON(http.StatusOK, channel)
+}
+
+// DeleteChannel deletes a notification channel.
+func (a *NotificationChannelAPI) DeleteChannel(c *gin.Context) {
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	channelID, ok := getChannelID(c)
+	if !ok {
+		return
+	}
+
+	if err := a.channels.Delete(organizationID, channelID); err != nil {
+		a.handleError(c, err, "failed to delete notification channel")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// TestChannel sends a test notification through a channel.
+func (a *NotificationChannelAPI) TestChannel(c *gin.Context) {
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	channelID, ok := getChannelID(c)
+	if !ok {
+		return
+	}
+
+	err := a.notifier.Test(c.Request.Context(), organizationID, channelID)
+	if err == notification.ErrChannelNotFound {
+		a.handleError(c, err, "failed to test notification channel")
+		return
+	} else if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadGateway, common.ErrorResponse{
+			Code:    http.StatusBadGateway,
+			Message: "failed to send test notification",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListDeliveries lists the latest notification deliveries of an organization.
+func (a *NotificationChannelAPI) ListDeliveries(c *gin.Context) {
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	var channelID uint
+	if value := c.Query("channelId"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 0)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, common.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "invalid channel ID",
+				Error:   err.Error(),
+			})
+			return
+		}
+		channelID = uint(id)
+	}
+
+	limit := defaultDeliveryListLimit
+	if value := c.Query("limit"); value != "" {
+		l, err := strconv.Atoi(value)
+		if err != nil || l < 1 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, common.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "invalid limit",
+				Error:   "limit must be a positive integer",
+			})
+			return
+		}
+		limit = l
+	}
+
+	deliveries, err := a.notifier.ListDeliveries(organizationID, channelID, limit)
+	if err != nil {
+		a.handleError(c, err, "failed to list notification deliveries")
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+func getChannelID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("channelId"), 10, 0)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, common.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid channel ID",
+			Error:   err.Error(),
+		})
+		return 0, false
+	}
+
+	return uint(id), true
+}
+
+func (a *NotificationChannelAPI) handleError(c *gin.Context, err error, message string) {
+	statusCode := http.StatusInternalServerError
+
+	switch errors.Cause(err) {
+	case notification.ErrChannelNotFound:
+		statusCode = http.StatusNotFound
+	case notification.ErrInvalidChannel:
+		statusCode = http.StatusBadRequest
+	default:
+		a.errorHandler.Handle(emperror.Wrap(err, message))
+	}
+
+	c.AbortWithStatusJSON(statusCode, common.ErrorResponse{
+		Code:    statusCode,
+		Message: message,
+		Error:   err.Error(),
+	})
+}
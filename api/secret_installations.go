@@ -0,0 +1,81 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/banzaicloud/pipeline/auth"
+	intAuth "github.com/banzaicloud/pipeline/internal/auth"
+	"github.com/banzaicloud/pipeline/internal/secret/installation"
+	"github.com/banzaicloud/pipeline/pkg/common"
+	secretTypes "github.com/banzaicloud/pipeline/pkg/secret"
+	"github.com/banzaicloud/pipeline/secret"
+	"github.com/gin-gonic/gin"
+	"github.com/goph/emperror"
+	"github.com/sirupsen/logrus"
+)
+
+// secretLister lists the secrets of an organization.
+type secretLister interface {
+	List(organizationID uint, query *secretTypes.ListSecretsQuery) ([]*secret.SecretItemResponse, error)
+}
+
+// SecretAPI implements the secret functions that need access control beyond the route
+// or keep the installed copies of secrets in sync.
+type SecretAPI struct {
+	secrets       secretLister
+	installations *installation.Manager
+	enforcer      intAuth.Enforcer
+	log           logrus.FieldLogger
+	errorHandler  emperror.Handler
+}
+
+// NewSecretAPI returns a new SecretAPI instance.
+func NewSecretAPI(
+	secrets secretLister,
+	installations *installation.Manager,
+	enforcer intAuth.Enforcer,
+	log logrus.FieldLogger,
+	errorHandler emperror.Handler,
+) *SecretAPI {
+	return &SecretAPI{
+		secrets:       secrets,
+		installations: installations,
+		enforcer:      enforcer,
+		log:           log,
+		errorHandler:  errorHandler,
+	}
+}
+
+// ListSecretInstallations returns the clusters and namespaces a secret is installed into.
+func (a *SecretAPI) ListSecretInstallations(c *gin.Context) {
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+	secretID := getSecretID(c)
+
+	installations, err := a.installations.ListInstallations(organizationID, secretID)
+	if err != nil {
+		a.errorHandler.Handle(emperror.With(err, "organization", organizationID, "secret", secretID))
+
+		c.AbortWithStatusJSON(http.StatusInternalServerError, common.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error during listing secret installations",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, installations)
+}
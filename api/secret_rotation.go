@@ -0,0 +1,147 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/internal/secret/rotation"
+	"github.com/banzaicloud/pipeline/pkg/common"
+	"github.com/banzaicloud/pipeline/secret"
+	"github.com/gin-gonic/gin"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// SecretRotationAPI implements the secret rotation functions.
+type SecretRotationAPI struct {
+	rotator      *rotation.Rotator
+	log          logrus.FieldLogger
+	errorHandler emperror.Handler
+}
+
+// NewSecretRotationAPI returns a new SecretRotationAPI instance.
+func NewSecretRotationAPI(rotator *rotation.Rotator, log logrus.FieldLogger, errorHandler emperror.Handler) *SecretRotationAPI {
+	return &SecretRotationAPI{
+		rotator:      rotator,
+		log:          log,
+		errorHandler: errorHandler,
+	}
+}
+
+// GetRotationPolicy returns the rotation policy of a secret.
+func (a *SecretRotationAPI) GetRotationPolicy(c *gin.Context) {
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	policy, err := a.rotator.GetPolicy(organizationID, getSecretID(c))
+	if err != nil {
+		a.handleError(c, err, "failed to get rotation policy")
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// SetRotationPolicy creates or updates the rotation policy of a secret.
+func (a *SecretRotationAPI) SetRotationPolicy(c *gin.Context) {
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	var request rotation.Policy
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, common.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "failed to parse request",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	policy, err := a.rotator.SetPolicy(organizationID, getSecretID(c), request)
+	if err != nil {
+		a.handleError(c, err, "failed to set rotation policy")
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// DeleteRotationPolicy deletes the rotation policy of a secret.
+func (a *SecretRotationAPI) DeleteRotationPolicy(c *gin.Context) {
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+
+	if err := a.rotator.DeletePolicy(organizationID, getSecretID(c)); err != nil {
+		a.handleError(c, err, "failed to delete rotation policy")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RotateSecret regenerates the values of a secret immediately.
+func (a *SecretRotationAPI) RotateSecret(c *gin.Context) {
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+	secretID := getSecretID(c)
+
+	item, err := a.rotator.Rotate(c.Request.Context(), organizationID, secretID)
+	if item == nil {
+		a.handleError(c, err, "failed to rotate secret")
+		return
+	}
+
+	var errorMsg string
+	if err != nil {
+		// The secret is rotated, but some of its installations could not be updated
+		a.errorHandler.Handle(emperror.With(err, "organization", organizationID, "secret", secretID))
+		errorMsg = err.Error()
+	}
+
+	c.JSON(http.StatusOK, secret.CreateSecretResponse{
+		Name:      item.Name,
+		Type:      item.Type,
+		ID:        secretID,
+		Error:     errorMsg,
+		UpdatedAt: item.UpdatedAt,
+		UpdatedBy: item.UpdatedBy,
+		Version:   item.Version,
+	})
+}
+
+func (a *SecretRotationAPI) handleError(c *gin.Context, err error, message string) {
+	statusCode := http.StatusInternalServerError
+
+	switch cause := errors.Cause(err); {
+	case cause == rotation.ErrPolicyNotFound, cause == secret.ErrSecretNotExists:
+		statusCode = http.StatusNotFound
+	case cause == rotation.ErrInvalidPolicy, cause == secret.ErrSecretNotRotatable:
+		statusCode = http.StatusBadRequest
+	case secret.IsCASError(err):
+		statusCode = http.StatusConflict
+	default:
+		switch cause.(type) {
+		case secret.ForbiddenError, secret.ReadOnlyError:
+			statusCode = http.StatusBadRequest
+		default:
+			a.errorHandler.Handle(emperror.Wrap(err, message))
+		}
+	}
+
+	c.AbortWithStatusJSON(statusCode, common.ErrorResponse{
+		Code:    statusCode,
+		Message: message,
+		Error:   err.Error(),
+	})
+}
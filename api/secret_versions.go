@@ -0,0 +1,146 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/pkg/common"
+	"github.com/banzaicloud/pipeline/secret"
+	"github.com/gin-gonic/gin"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+)
+
+// ListSecretVersions returns the available versions of a secret
+func ListSecretVersions(c *gin.Context) {
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+	secretID := getSecretID(c)
+
+	versions, err := secret.RestrictedStore.ListVersions(organizationID, secretID)
+	if err != nil {
+		handleSecretVersionError(c, err, "Error during listing secret versions")
+		return
+	}
+
+	c.JSON(http.StatusOK, versions)
+}
+
+// GetSecretVersion returns a specific version of a secret
+func GetSecretVersion(c *gin.Context) {
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+	secretID := getSecretID(c)
+
+	version, ok := parseSecretVersion(c)
+	if !ok {
+		return
+	}
+
+	item, err := secret.RestrictedStore.GetVersion(organizationID, secretID, version)
+	if err != nil {
+		handleSecretVersionError(c, err, "Error during getting secret version")
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
+
+// RestoreSecretVersion makes an older version of a secret the current one
+// If sync is requested, the installed copies of the secret are updated as well.
+func (a *SecretAPI) RestoreSecretVersion(c *gin.Context) {
+	organizationID := auth.GetCurrentOrganization(c.Request).ID
+	secretID := getSecretID(c)
+
+	version, ok := parseSecretVersion(c)
+	if !ok {
+		return
+	}
+
+	sync, _ := strconv.ParseBool(c.DefaultQuery("sync", "false"))
+
+	log.Infof("restoring secret %d/%s to version %d", organizationID, secretID, version)
+
+	err := secret.RestrictedStore.RestoreVersion(organizationID, secretID, version, auth.GetCurrentUser(c.Request).Login)
+	if err != nil {
+		handleSecretVersionError(c, err, "Error during restoring secret version")
+		return
+	}
+
+	s, err := secret.RestrictedStore.Get(organizationID, secretID)
+	if err != nil {
+		handleSecretVersionError(c, err, "Error during getting secret")
+		return
+	}
+
+	var errorMsg string
+	if sync {
+		if err := a.installations.ReinstallSecret(c.Request.Context(), organizationID, s); err != nil {
+			a.errorHandler.Handle(emperror.With(err, "organization", organizationID, "secret", secretID))
+			errorMsg = err.Error()
+		}
+	}
+
+	c.JSON(http.StatusOK, secret.CreateSecretResponse{
+		Name:      s.Name,
+		Type:      s.Type,
+		ID:        secretID,
+		Error:     errorMsg,
+		UpdatedAt: s.UpdatedAt,
+		UpdatedBy: s.UpdatedBy,
+		Version:   s.Version,
+	})
+}
+
+func parseSecretVersion(c *gin.Context) (int, bool) {
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		c.AbortWithStatusJSON(http.StatusBadRequest, common.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid secret version",
+			Error:   "version must be a positive integer",
+		})
+		return 0, false
+	}
+
+	return version, true
+}
+
+func handleSecretVersionError(c *gin.Context, err error, message string) {
+	statusCode := http.StatusInternalServerError
+
+	switch errors.Cause(err).(type) {
+	case secret.ForbiddenError, secret.ReadOnlyError:
+		statusCode = http.StatusBadRequest
+	}
+
+	switch {
+	case errors.Cause(err) == secret.ErrSecretNotExists, errors.Cause(err) == secret.ErrSecretVersionNotExists:
+		statusCode = http.StatusNotFound
+	case secret.IsCASError(err):
+		statusCode = http.StatusConflict
+	}
+
+	if statusCode == http.StatusInternalServerError {
+		log.Errorf("%s: %s", message, err.Error())
+	}
+
+	c.AbortWithStatusJSON(statusCode, common.ErrorResponse{
+		Code:    statusCode,
+		Message: message,
+		Error:   err.Error(),
+	})
+}
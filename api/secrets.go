@@ -32,6 +32,7 @@ import (
 	"github.com/banzaicloud/pipeline/secret/verify"
 	"github.com/banzaicloud/pipeline/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/goph/emperror"
 	"github.com/pkg/errors"
 )
 
@@ -194,7 +195,8 @@ func AddSecrets(c *gin.Context) {
 }
 
 // UpdateSecrets updates the given secret in Vault
-func UpdateSecrets(c *gin.Context) {
+// If sync is requested, the installed copies of the secret are updated as well.
+func (a *SecretAPI) UpdateSecrets(c *gin.Context) {
 
 	organizationID := auth.GetCurrentOrganization(c.Request).ID
 	log.Debugf("Organization id: %d", organizationID)
@@ -209,6 +211,8 @@ func UpdateSecrets(c *gin.Context) {
 
 	log.Infof("validate value %t", validate)
 
+	sync, _ := strconv.ParseBool(c.DefaultQuery("sync", "false"))
+
 	var createSecretRequest secret.CreateSecretRequest
 	if err := c.ShouldBind(&createSecretRequest); err != nil {
 		log.Errorf("Error during binding CreateSecretRequest: %s", err.Error())
@@ -273,11 +277,20 @@ func UpdateSecrets(c *gin.Context) {
 		return
 	}
 
-	var errorMsg string
+	var errorMessages []string
 	if validationError != nil {
-		errorMsg = validationError.Error()
+		errorMessages = append(errorMessages, validationError.Error())
 	}
 
+	if sync {
+		if err := a.installations.ReinstallSecret(c.Request.Context(), organizationID, s); err != nil {
+			a.errorHandler.Handle(emperror.With(err, "organization", organizationID, "secret", secretID))
+			errorMessages = append(errorMessages, err.Error())
+		}
+	}
+
+	errorMsg := strings.Join(errorMessages, "; ")
+
 	c.JSON(http.StatusOK, secret.CreateSecretResponse{
 		Name:      s.Name,
 		Type:      s.Type,
@@ -291,7 +304,7 @@ func UpdateSecrets(c *gin.Context) {
 
 // ListSecrets returns the user all secrets, if the secret type or tag is filled
 // then a filtered response is returned
-func ListSecrets(c *gin.Context) {
+func (a *SecretAPI) ListSecrets(c *gin.Context) {
 
 	organizationID := auth.GetCurrentOrganization(c.Request).ID
 
@@ -315,18 +328,58 @@ func ListSecrets(c *gin.Context) {
 			Message: "Not supported secret type",
 			Error:   err.Error(),
 		})
-	} else {
-		if secrets, err := secret.RestrictedStore.List(organizationID, &query); err != nil {
-			log.Errorf("Error during listing secrets: %s", err.Error())
-			c.AbortWithStatusJSON(http.StatusBadRequest, common.ErrorResponse{
-				Code:    http.StatusBadRequest,
-				Message: "Error during listing secrets",
+		return
+	}
+
+	secrets, err := a.secrets.List(organizationID, &query)
+	if err != nil {
+		log.Errorf("Error during listing secrets: %s", err.Error())
+		c.AbortWithStatusJSON(http.StatusBadRequest, common.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Error during listing secrets",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if query.Values && !a.authorizeSecretValues(c, secrets) {
+		return
+	}
+
+	c.JSON(http.StatusOK, secrets)
+}
+
+// authorizeSecretValues checks that the user is allowed to read the values of every listed secret.
+// Listing values is authorized the same way as reading the secrets one by one.
+func (a *SecretAPI) authorizeSecretValues(c *gin.Context, secrets []*secret.SecretItemResponse) bool {
+	organization := auth.GetCurrentOrganization(c.Request)
+	user := auth.GetCurrentUser(c.Request)
+
+	for _, secretItem := range secrets {
+		path := fmt.Sprintf("/api/v1/orgs/%d/secrets/%s", organization.ID, secretItem.ID)
+
+		granted, err := a.enforcer.Enforce(organization, user, path, http.MethodGet)
+		if err != nil {
+			a.errorHandler.Handle(emperror.With(err, "organization", organization.ID, "secret", secretItem.ID))
+
+			c.AbortWithStatusJSON(http.StatusInternalServerError, common.ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: "Error during checking permissions",
 				Error:   err.Error(),
 			})
-		} else {
-			c.JSON(http.StatusOK, secrets)
+			return false
+		}
+
+		if !granted {
+			c.AbortWithStatusJSON(http.StatusForbidden, common.ErrorResponse{
+				Code:    http.StatusForbidden,
+				Message: fmt.Sprintf("reading the values of secret %s is not allowed", secretItem.Name),
+			})
+			return false
 		}
 	}
+
+	return true
 }
 
 // GetSecret returns a secret by ID
@@ -349,7 +402,8 @@ func GetSecret(c *gin.Context) {
 }
 
 // DeleteSecrets delete a secret with the given secret id
-func DeleteSecrets(c *gin.Context) {
+// If sync is requested, the installed copies of the secret are removed from the clusters first.
+func (a *SecretAPI) DeleteSecrets(c *gin.Context) {
 	log.Info("Start deleting secrets")
 
 	log.Info("Get organization id from params")
@@ -358,6 +412,8 @@ func DeleteSecrets(c *gin.Context) {
 
 	secretID := getSecretID(c)
 
+	sync, _ := strconv.ParseBool(c.DefaultQuery("sync", "false"))
+
 	log.Infof("Check clusters before delete secret[%s]", secretID)
 	if err := checkClustersBeforeDelete(organizationID, secretID); err != nil {
 		log.Errorf("Cluster found with this secret[%s]: %s", secretID, err.Error())
@@ -366,6 +422,13 @@ func DeleteSecrets(c *gin.Context) {
 			Message: fmt.Sprintf("Cluster found with this secret[%s]", secretID),
 			Error:   err.Error(),
 		})
+	} else if err := a.uninstallSecret(c, organizationID, secretID, sync); err != nil {
+		log.Errorf("Error during uninstalling secret from clusters: %s", err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, common.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Error during uninstalling secret from clusters",
+			Error:   err.Error(),
+		})
 	} else if err := secret.RestrictedStore.Delete(organizationID, secretID); err != nil {
 		log.Errorf("Error during deleting secrets: %s", err.Error())
 		code := http.StatusInternalServerError
@@ -376,11 +439,37 @@ func DeleteSecrets(c *gin.Context) {
 		}
 		c.AbortWithStatusJSON(code, resp)
 	} else {
+		// The installed copies are kept without sync, but they are no longer tracked
+		if err := a.installations.ForgetSecret(organizationID, secretID); err != nil {
+			a.errorHandler.Handle(emperror.With(err, "organization", organizationID, "secret", secretID))
+		}
+
 		log.Info("Delete secrets succeeded")
 		c.Status(http.StatusNoContent)
 	}
 }
 
+// uninstallSecret removes the installed copies of a secret if sync is requested
+func (a *SecretAPI) uninstallSecret(c *gin.Context, organizationID uint, secretID string, sync bool) error {
+	if !sync {
+		return nil
+	}
+
+	// Keep the installed copies of secrets that cannot be deleted
+	secretItem, err := secret.RestrictedStore.Get(organizationID, secretID)
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range secretItem.Tags {
+		if tag == secretTypes.TagBanzaiReadonly {
+			return secret.ReadOnlyError{SecretID: secretID}
+		}
+	}
+
+	return a.installations.UninstallSecret(c.Request.Context(), organizationID, secretID)
+}
+
 // GetSecretTags returns tags of a secret by ID
 func GetSecretTags(c *gin.Context) {
 	organizationID := auth.GetCurrentOrganization(c.Request).ID
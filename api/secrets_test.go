@@ -15,18 +15,30 @@
 package api_test
 
 import (
+	"context"
 	"crypto/sha256"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"sort"
 	"testing"
 	"time"
 
 	"github.com/banzaicloud/pipeline/api"
+	"github.com/banzaicloud/pipeline/auth"
+	intAuth "github.com/banzaicloud/pipeline/internal/auth"
 	clusterTypes "github.com/banzaicloud/pipeline/pkg/cluster"
 	secretTypes "github.com/banzaicloud/pipeline/pkg/secret"
 	"github.com/banzaicloud/pipeline/secret"
 	"github.com/banzaicloud/pipeline/secret/verify"
+	"github.com/gin-gonic/gin"
+	"github.com/goph/emperror"
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	qorauth "github.com/qor/auth"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestIsValidSecretType(t *testing.T) {
@@ -166,6 +178,90 @@ func TestListSecrets(t *testing.T) {
 
 }
 
+type secretListerStub struct {
+	secrets []*secret.SecretItemResponse
+}
+
+func (s *secretListerStub) List(organizationID uint, query *secretTypes.ListSecretsQuery) ([]*secret.SecretItemResponse, error) {
+	return s.secrets, nil
+}
+
+func TestListSecrets_Values(t *testing.T) {
+	db, err := gorm.Open("sqlite3", "file::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.AutoMigrate(&auth.UserOrganization{}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := intAuth.Migrate(db, logrus.New()); err != nil {
+		t.Fatal(err)
+	}
+
+	enforcer := intAuth.NewEnforcer(db)
+	if err := intAuth.NewAccessManager(db, enforcer, "").AddDefaultPolicies(); err != nil {
+		t.Fatal(err)
+	}
+
+	org := &auth.Organization{ID: 1, Name: "org"}
+	admin := &auth.User{ID: 1, Login: "admin"}
+	viewer := &auth.User{ID: 2, Login: "viewer"}
+
+	db.Create(&auth.UserOrganization{OrganizationID: org.ID, UserID: admin.ID, Role: intAuth.RoleAdmin})
+	db.Create(&auth.UserOrganization{OrganizationID: org.ID, UserID: viewer.ID, Role: intAuth.RoleViewer})
+
+	secrets := &secretListerStub{
+		secrets: []*secret.SecretItemResponse{
+			{ID: "abc", Name: "db", Type: secretTypes.PasswordSecretType, Values: map[string]string{"password": "s3cr3t"}},
+		},
+	}
+
+	secretAPI := api.NewSecretAPI(secrets, nil, enforcer, logrus.New(), emperror.NewNoopHandler())
+
+	tests := map[string]struct {
+		user         *auth.User
+		path         string
+		expectedCode int
+	}{
+		"viewer lists secrets": {
+			user:         viewer,
+			path:         "/api/v1/orgs/1/secrets",
+			expectedCode: http.StatusOK,
+		},
+		"viewer lists secret values": {
+			user:         viewer,
+			path:         "/api/v1/orgs/1/secrets?values=true",
+			expectedCode: http.StatusForbidden,
+		},
+		"admin lists secret values": {
+			user:         admin,
+			path:         "/api/v1/orgs/1/secrets?values=true",
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+
+		t.Run(name, func(t *testing.T) {
+			gin.SetMode(gin.ReleaseMode)
+			router := gin.New()
+			router.GET("/api/v1/orgs/:orgid/secrets", secretAPI.ListSecrets)
+
+			ctx := context.WithValue(context.Background(), qorauth.CurrentUser, test.user)
+			ctx = context.WithValue(ctx, auth.CurrentOrganization, org)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, test.path, nil)
+			router.ServeHTTP(w, req.WithContext(ctx))
+
+			assert.Equal(t, test.expectedCode, w.Code)
+		})
+	}
+}
+
 func TestDeleteSecrets(t *testing.T) {
 
 	cases := []struct {
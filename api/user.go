@@ -20,16 +20,20 @@ import (
 	"strconv"
 
 	"github.com/banzaicloud/pipeline/auth"
+	intAuth "github.com/banzaicloud/pipeline/internal/auth"
 	"github.com/banzaicloud/pipeline/pkg/common"
 	"github.com/gin-gonic/gin"
 	"github.com/goph/emperror"
 	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
 type userAccessManager interface {
 	GrantOrganizationAccessToUser(userID string, orgID uint)
 	RevokeOrganizationAccessFromUser(userID string, orgID uint)
+	GetUserRole(userID uint, orgID uint) (string, error)
+	SetUserRole(userID uint, orgID uint, role string) error
 }
 
 // UserAPI implements user functions.
@@ -157,7 +161,7 @@ func (a *UserAPI) GetUsers(c *gin.Context) {
 	}
 }
 
-// AddUser adds a user to an organization, role=admin|member has to be in the body, otherwise member is the default role.
+// AddUser adds a user to an organization, role=admin|member|viewer has to be in the body, otherwise member is the default role.
 func (a *UserAPI) AddUser(c *gin.Context) {
 
 	log.Info("Adding user to organization")
@@ -176,8 +180,8 @@ func (a *UserAPI) AddUser(c *gin.Context) {
 	}
 
 	role := struct {
-		Role string `json:"role" binding:"required,eq=member|eq=admin"`
-	}{Role: "member"}
+		Role string `json:"role" binding:"required,eq=member|eq=admin|eq=viewer"`
+	}{Role: intAuth.RoleMember}
 
 	if c.Request.ContentLength != 0 {
 		err = c.ShouldBindJSON(&role)
@@ -276,6 +280,113 @@ func (a *UserAPI) RemoveUser(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// UserRoleResponse describes the role of a user in an organization.
+type UserRoleResponse struct {
+	UserID         uint   `json:"userId"`
+	OrganizationID uint   `json:"organizationId"`
+	Role           string `json:"role"`
+}
+
+// UpdateUserRoleRequest describes a role change of a user in an organization.
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,eq=member|eq=admin|eq=viewer"`
+}
+
+// GetUserRoles returns the role of a user in the current organization.
+func (a *UserAPI) GetUserRoles(c *gin.Context) {
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	id, ok := a.parseUserID(c)
+	if !ok {
+		return
+	}
+
+	role, err := a.accessManager.GetUserRole(id, organization.ID)
+	if err != nil {
+		a.handleUserRoleError(c, id, err, "failed to get user role")
+		return
+	}
+
+	c.JSON(http.StatusOK, UserRoleResponse{
+		UserID:         id,
+		OrganizationID: organization.ID,
+		Role:           role,
+	})
+}
+
+// UpdateUserRoles changes the role of a user in the current organization.
+func (a *UserAPI) UpdateUserRoles(c *gin.Context) {
+	organization := auth.GetCurrentOrganization(c.Request)
+
+	id, ok := a.parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var request UpdateUserRoleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		message := fmt.Sprintf("error parsing role from request: %s", err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, common.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: message,
+			Error:   message,
+		})
+		return
+	}
+
+	err := a.accessManager.SetUserRole(id, organization.ID, request.Role)
+	if err != nil {
+		a.handleUserRoleError(c, id, err, "failed to update user role")
+		return
+	}
+
+	c.JSON(http.StatusOK, UserRoleResponse{
+		UserID:         id,
+		OrganizationID: organization.ID,
+		Role:           request.Role,
+	})
+}
+
+func (a *UserAPI) parseUserID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		message := fmt.Sprintf("error parsing user id: %s", err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, common.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: message,
+			Error:   message,
+		})
+		return 0, false
+	}
+
+	return uint(id), true
+}
+
+func (a *UserAPI) handleUserRoleError(c *gin.Context, userID uint, err error, message string) {
+	var statusCode int
+
+	switch cause := errors.Cause(err); {
+	case cause == intAuth.ErrInvalidRole:
+		statusCode = http.StatusBadRequest
+		message = cause.Error()
+	case cause == intAuth.ErrLastAdmin:
+		statusCode = http.StatusConflict
+		message = cause.Error()
+	case gorm.IsRecordNotFoundError(cause):
+		statusCode = http.StatusNotFound
+		message = fmt.Sprintf("user not found with id: %d", userID)
+	default:
+		statusCode = http.StatusInternalServerError
+		a.errorHandler.Handle(emperror.Wrap(err, message))
+	}
+
+	c.AbortWithStatusJSON(statusCode, common.ErrorResponse{
+		Code:    statusCode,
+		Message: message,
+		Error:   message,
+	})
+}
+
 type updateUserRequest struct {
 	GitHubToken *string `json:"gitHubToken,omitempty"`
 	GitLabToken *string `json:"gitLabToken,omitempty"`
@@ -193,6 +193,37 @@ func getVNetSubnetID(np *model.AKSNodePoolModel) *string {
 
 // CreateCluster creates a new cluster
 func (c *AKSCluster) CreateCluster() error {
+	if err := c.createManagedCluster(); err != nil {
+		return err
+	}
+
+	if err := c.assignStorageAccountContributorRole(); err != nil {
+		return emperror.Wrap(err, "failed to assign storage account contributor role")
+	}
+	c.log.Info("Role assigned successfully")
+
+	return nil
+}
+
+// infraCreationSteps returns the steps of creating the cluster at AKS.
+func (c *AKSCluster) infraCreationSteps() []clusterInfraStep {
+	return []clusterInfraStep{
+		{
+			name:   aksCreateClusterStep,
+			create: c.createManagedCluster,
+			undo:   c.deleteManagedCluster,
+		},
+		{
+			name: aksAssignStorageAccountContributorRoleStep,
+			create: func() error {
+				return emperror.Wrap(c.assignStorageAccountContributorRole(), "failed to assign storage account contributor role")
+			},
+		},
+	}
+}
+
+// createManagedCluster creates or updates the managed cluster at AKS and waits for its provisioning.
+func (c *AKSCluster) createManagedCluster() error {
 	c.log.Info("Creating cluster...")
 
 	cc, err := c.getCloudConnection()
@@ -267,11 +298,6 @@ func (c *AKSCluster) CreateCluster() error {
 	}
 	c.log.Info("Cluster ready")
 
-	if err = c.assignStorageAccountContributorRole(); err != nil {
-		return emperror.Wrap(err, "failed to assign storage account contributor role")
-	}
-	c.log.Info("Role assigned successfully")
-
 	return nil
 }
 
@@ -513,19 +539,25 @@ func (c *AKSCluster) GetStatus() (*pkgCluster.GetClusterStatusResponse, error) {
 
 // DeleteCluster deletes the cluster from AKS
 func (c *AKSCluster) DeleteCluster() error {
+	c.loadAKSClusterModelFromDB()
+
+	if err := c.deleteManagedCluster(); err != nil {
+		return err
+	}
+	c.log.Info("Delete succeeded")
+	return nil
+}
+
+// deleteManagedCluster deletes the managed cluster from AKS. Deleting a non-existent cluster is not an error.
+func (c *AKSCluster) deleteManagedCluster() error {
 	cc, err := c.getCloudConnection()
 	if err != nil {
 		return emperror.Wrap(err, "failed to get cloud connection")
 	}
 
-	c.loadAKSClusterModelFromDB()
-
 	err = cc.GetManagedClustersClient().DeleteAndWaitForIt(context.TODO(), c.GetResourceGroupName(), c.GetName())
-	if err != nil {
-		return emperror.Wrap(err, "cluster deletion request failed")
-	}
-	c.log.Info("Delete succeeded")
-	return nil
+
+	return emperror.Wrap(err, "cluster deletion request failed")
 }
 
 func getAgentPoolProfileByName(cluster *containerservice.ManagedCluster, name string) *containerservice.ManagedClusterAgentPoolProfile {
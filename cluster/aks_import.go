@@ -0,0 +1,106 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerservice/mgmt/2018-03-31/containerservice"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+
+	"github.com/banzaicloud/pipeline/model"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+)
+
+// discoverAKSCluster looks up an existing AKS cluster and its node pools and returns an unsaved AKSCluster.
+func discoverAKSCluster(importCtx ImportContext) (*AKSCluster, error) {
+	if importCtx.ResourceGroup == "" {
+		return nil, &invalidError{errors.New("resource group is required to import an AKS cluster")}
+	}
+
+	cc, err := getDefaultCloudConnection(importCtx.OrganizationID, importCtx.SecretID)
+	if err != nil {
+		return nil, emperror.Wrap(err, "failed to create cloud connection")
+	}
+
+	managedCluster, err := cc.GetManagedClustersClient().Get(context.TODO(), importCtx.ResourceGroup, importCtx.Name)
+	if managedCluster.Response.Response != nil && managedCluster.StatusCode == http.StatusNotFound {
+		return nil, &invalidError{errors.Errorf("AKS cluster %q not found in resource group %s", importCtx.Name, importCtx.ResourceGroup)}
+	} else if err != nil {
+		return nil, emperror.Wrap(err, "failed to get managed cluster")
+	}
+
+	if managedCluster.ManagedClusterProperties == nil || !isProvisioningSuccessful(&managedCluster) {
+		return nil, &invalidError{errors.New("AKS cluster is not provisioned successfully")}
+	}
+
+	nodePools := aksNodePoolModelsFromCluster(&managedCluster, importCtx.UserID)
+	if len(nodePools) == 0 {
+		return nil, &invalidError{errors.New("no node pools found for AKS cluster")}
+	}
+
+	location := importCtx.Location
+	if managedCluster.Location != nil {
+		location = *managedCluster.Location
+	}
+
+	return &AKSCluster{
+		modelCluster: &model.ClusterModel{
+			Name:           importCtx.Name,
+			Location:       location,
+			Cloud:          pkgCluster.Azure,
+			Distribution:   pkgCluster.AKS,
+			OrganizationId: importCtx.OrganizationID,
+			SecretId:       importCtx.SecretID,
+			CreatedBy:      importCtx.UserID,
+			RbacEnabled:    to.Bool(managedCluster.EnableRBAC),
+			AKS: model.AKSClusterModel{
+				ResourceGroup:     importCtx.ResourceGroup,
+				KubernetesVersion: to.String(managedCluster.KubernetesVersion),
+				NodePools:         nodePools,
+			},
+		},
+		log: log.WithField("cluster", importCtx.Name),
+	}, nil
+}
+
+// aksNodePoolModelsFromCluster builds the node pool models from the agent pool profiles of an AKS cluster.
+func aksNodePoolModelsFromCluster(managedCluster *containerservice.ManagedCluster, userID uint) []*model.AKSNodePoolModel {
+	if managedCluster.ManagedClusterProperties == nil || managedCluster.AgentPoolProfiles == nil {
+		return nil
+	}
+
+	profiles := *managedCluster.AgentPoolProfiles
+
+	nodePools := make([]*model.AKSNodePoolModel, 0, len(profiles))
+	for _, profile := range profiles {
+		count := int(to.Int32(profile.Count))
+
+		nodePools = append(nodePools, &model.AKSNodePoolModel{
+			CreatedBy:        userID,
+			Name:             to.String(profile.Name),
+			Count:            count,
+			NodeMinCount:     count,
+			NodeMaxCount:     count,
+			NodeInstanceType: string(profile.VMSize),
+			VNetSubnetID:     to.String(profile.VnetSubnetID),
+		})
+	}
+
+	return nodePools
+}
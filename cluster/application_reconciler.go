@@ -0,0 +1,439 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/banzaicloud/pipeline/helm"
+	"github.com/banzaicloud/pipeline/internal/application"
+	intCluster "github.com/banzaicloud/pipeline/internal/cluster"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	pkgHelm "github.com/banzaicloud/pipeline/pkg/helm"
+)
+
+const applicationResyncInterval = 5 * time.Minute
+
+// applicationSync is a work item of the application reconciler.
+// Drift is always reported, it is only corrected if apply is set or the application syncs automatically.
+type applicationSync struct {
+	applicationID uint
+	apply         bool
+}
+
+// ApplicationReconciler installs or upgrades the releases of applications on every cluster matching their selector,
+// including clusters created later, and periodically reports the sync status and the drift of every cluster.
+type ApplicationReconciler struct {
+	manager *Manager
+	store   *application.Store
+	labels  clusterLabelLister
+
+	// clusterEvents is the event bus through which cluster created notifications are received
+	clusterEvents clusterEventsSubscriber
+
+	queue workqueue.RateLimitingInterface
+	stop  chan struct{}
+
+	logger       logrus.FieldLogger
+	errorHandler emperror.Handler
+}
+
+// NewApplicationReconciler instantiates a new application reconciler.
+func NewApplicationReconciler(manager *Manager, store *application.Store, labels clusterLabelLister, clusterEvents clusterEventsSubscriber, logger logrus.FieldLogger, errorHandler emperror.Handler) *ApplicationReconciler {
+	return &ApplicationReconciler{
+		manager:       manager,
+		store:         store,
+		labels:        labels,
+		clusterEvents: clusterEvents,
+		queue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "application-reconciler"),
+		stop:          make(chan struct{}),
+		logger:        logger,
+		errorHandler:  errorHandler,
+	}
+}
+
+func (r *ApplicationReconciler) Start() error {
+	r.logger.Info("starting application reconciler")
+
+	// applications have to be installed on matching clusters created later
+	if err := r.clusterEvents.SubscribeAsync(clusterCreatedTopic, r.clusterCreated, false); err != nil {
+		return emperror.Wrap(err, "failed to subscribe to cluster events")
+	}
+
+	go r.runWorker()
+
+	go func() {
+		ticker := time.NewTicker(applicationResyncInterval)
+		defer ticker.Stop()
+
+		r.resync()
+
+		for {
+			select {
+			case <-ticker.C:
+				r.resync()
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (r *ApplicationReconciler) Stop() {
+	r.logger.Info("shutting application reconciler")
+	close(r.stop)
+	r.queue.ShutDown()
+}
+
+// Sync installs or upgrades the release of an application on every matching cluster that is out of sync.
+func (r *ApplicationReconciler) Sync(applicationID uint) {
+	r.enqueue(applicationSync{applicationID: applicationID, apply: true})
+}
+
+// Delete deletes an application and removes its release from every cluster in the background.
+func (r *ApplicationReconciler) Delete(organizationID uint, applicationID uint) error {
+	app, err := r.store.Get(organizationID, applicationID)
+	if err != nil {
+		return err
+	}
+
+	if err := r.store.Delete(organizationID, applicationID); err != nil {
+		return err
+	}
+
+	go func() {
+		for _, status := range app.Clusters {
+			if err := r.deleteRelease(*app, status.ClusterID); err != nil {
+				r.errorHandler.Handle(emperror.With(err, "application", app.Name, "clusterId", status.ClusterID))
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (r *ApplicationReconciler) enqueue(item applicationSync) {
+	if !r.queue.ShuttingDown() {
+		r.queue.Add(item)
+	}
+}
+
+// resync checks every application for drift.
+func (r *ApplicationReconciler) resync() {
+	ids, err := r.store.ListIDs()
+	if err != nil {
+		r.errorHandler.Handle(err)
+
+		return
+	}
+
+	for _, id := range ids {
+		r.enqueue(applicationSync{applicationID: id})
+	}
+}
+
+func (r *ApplicationReconciler) clusterCreated(clusterID uint) {
+	cluster, err := r.manager.GetClusterByIDOnly(context.Background(), clusterID)
+	if err != nil {
+		r.errorHandler.Handle(emperror.WrapWith(err, "failed to retrieve cluster", "clusterId", clusterID))
+
+		return
+	}
+
+	if err := r.ClusterChanged(cluster); err != nil {
+		r.errorHandler.Handle(err)
+	}
+}
+
+// ClusterChanged syncs the applications a cluster matches, or matched before its labels changed.
+func (r *ApplicationReconciler) ClusterChanged(cluster CommonCluster) error {
+	applications, err := r.store.List(cluster.GetOrganizationId())
+	if err != nil {
+		return err
+	}
+
+	targets, err := bulkTargets([]CommonCluster{cluster}, r.labels)
+	if err != nil {
+		return err
+	}
+
+	for _, app := range applications {
+		if app.Selector.Matches(targets[0]) || app.HasCluster(cluster.GetID()) {
+			r.Sync(app.ID)
+		}
+	}
+
+	return nil
+}
+
+// runWorker runs the loop that processes applications taken from the workqueue
+func (r *ApplicationReconciler) runWorker() {
+	// loop until we are told to quit
+	for r.processNextApplication() {
+	}
+}
+
+// processNextApplication takes one application off the queue for processing.
+// It returns false when it's time to quit
+func (r *ApplicationReconciler) processNextApplication() bool {
+	item, quit := r.queue.Get()
+	if quit {
+		return false
+	}
+
+	// tell to the queue that we finished processing the work item
+	defer r.queue.Done(item)
+
+	if err := r.reconcile(item.(applicationSync)); err != nil {
+		// processing the application failed; requeue to be retried later
+		r.errorHandler.Handle(err)
+
+		r.queue.AddRateLimited(item)
+	} else {
+		r.queue.Forget(item)
+	}
+
+	return true
+}
+
+// reconcile syncs an application on every matching cluster and removes its release from clusters no longer matching.
+// Errors of single clusters are recorded in their status, only errors affecting the whole application are returned.
+func (r *ApplicationReconciler) reconcile(item applicationSync) error {
+	app, err := r.store.GetByID(item.applicationID)
+	if err == application.ErrApplicationNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	logger := r.logger.WithFields(logrus.Fields{
+		"organization": app.OrganizationID,
+		"application":  app.Name,
+	})
+
+	clusters, err := r.manager.GetClusters(context.Background(), app.OrganizationID)
+	if err != nil {
+		return emperror.WrapWith(err, "failed to list clusters", "organizationId", app.OrganizationID)
+	}
+
+	targets, err := bulkTargets(clusters, r.labels)
+	if err != nil {
+		return err
+	}
+
+	matching := make(map[uint]bool)
+	for i, cluster := range clusters {
+		if !app.Selector.Matches(targets[i]) {
+			continue
+		}
+
+		matching[cluster.GetID()] = true
+
+		status := r.syncCluster(*app, cluster, item.apply || app.AutoSync)
+		if status.Status == application.SyncStatusFailed {
+			logger.WithField("cluster", cluster.GetName()).Warnf("failed to sync application: %s", status.Message)
+		}
+
+		if err := r.store.SetClusterStatus(app.ID, status); err != nil {
+			return err
+		}
+	}
+
+	for _, status := range app.Clusters {
+		if matching[status.ClusterID] {
+			continue
+		}
+
+		logger.WithField("cluster", status.ClusterName).Info("cluster no longer matches the application selector")
+
+		if err := r.deleteRelease(*app, status.ClusterID); err != nil {
+			return err
+		}
+
+		if err := r.store.DeleteClusterStatus(app.ID, status.ClusterID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// syncCluster compares the release of an application on a cluster with the desired state and,
+// if apply is set, installs or upgrades it when they differ.
+func (r *ApplicationReconciler) syncCluster(app application.Application, cluster CommonCluster, apply bool) (status application.ClusterStatus) {
+	now := time.Now()
+
+	status = application.ClusterStatus{
+		ClusterID:   cluster.GetID(),
+		ClusterName: cluster.GetName(),
+		CheckedAt:   &now,
+	}
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			status.Status = application.SyncStatusFailed
+			status.Message = fmt.Sprintf("internal error: %v", rec)
+		}
+	}()
+
+	clusterStatus, err := cluster.GetStatus()
+	if err != nil {
+		status.Status = application.SyncStatusFailed
+		status.Message = err.Error()
+
+		return
+	}
+
+	if clusterStatus.Status != pkgCluster.Running {
+		status.Status = application.SyncStatusPending
+		status.Message = fmt.Sprintf("cluster is %s", clusterStatus.Status)
+
+		return
+	}
+
+	kubeConfig, err := cluster.GetK8sConfig()
+	if err != nil {
+		status.Status = application.SyncStatusFailed
+		status.Message = err.Error()
+
+		return
+	}
+
+	values := app.ClusterValues(cluster.GetName())
+
+	message, drift, err := applicationDrift(app, values, kubeConfig)
+	if err != nil {
+		status.Status = application.SyncStatusFailed
+		status.Message = err.Error()
+
+		return
+	}
+
+	if message == "" && len(drift) == 0 {
+		status.Status = application.SyncStatusSynced
+
+		return
+	}
+
+	if !apply {
+		status.Status = application.SyncStatusOutOfSync
+		status.Message = message
+		status.Drift = drift
+
+		return
+	}
+
+	err = installOrUpgradeDeployment(cluster, pkgHelm.CreateUpdateDeploymentRequest{
+		Name:        app.Chart,
+		Version:     app.Version,
+		ReleaseName: app.ReleaseName,
+		Namespace:   app.Namespace,
+		Values:      values,
+	})
+	if err != nil {
+		status.Status = application.SyncStatusFailed
+		status.Message = err.Error()
+		status.Drift = drift
+
+		return
+	}
+
+	deployment, err := helm.GetDeployment(app.ReleaseName, kubeConfig)
+	if err != nil {
+		status.Status = application.SyncStatusFailed
+		status.Message = emperror.Wrap(err, "failed to get deployment").Error()
+
+		return
+	}
+
+	status.Status = application.SyncStatusSynced
+	status.Revision = deployment.Version
+	status.SyncedAt = &now
+
+	return
+}
+
+// applicationDrift returns how the release of an application differs from the desired state.
+// Only the values set by the application are compared, chart defaults are not considered drift.
+func applicationDrift(app application.Application, values map[string]interface{}, kubeConfig []byte) (string, []pkgHelm.ValueChange, error) {
+	deployment, err := helm.GetDeployment(app.ReleaseName, kubeConfig)
+	if _, ok := errors.Cause(err).(*helm.DeploymentNotFoundError); ok {
+		return "release is not installed", nil, nil
+	}
+	if err != nil {
+		return "", nil, emperror.Wrap(err, "failed to get deployment")
+	}
+
+	var message string
+	if deployment.ChartName != app.ChartName() || (app.Version != "" && deployment.ChartVersion != app.Version) {
+		message = fmt.Sprintf("chart %s is deployed instead of %s", deployment.Chart, helm.GetVersionedChartName(app.ChartName(), app.Version))
+	}
+
+	return message, valuesDrift(deployment.Values, values), nil
+}
+
+// valuesDrift returns the desired values that differ from the deployed ones.
+// Values referencing secrets are skipped, their deployed value is never the reference itself.
+func valuesDrift(deployed map[string]interface{}, desired map[string]interface{}) []pkgHelm.ValueChange {
+	drift := make([]pkgHelm.ValueChange, 0)
+
+	for _, change := range helm.DiffValues(deployed, desired) {
+		if change.To == nil {
+			continue
+		}
+
+		if value, ok := change.To.(string); ok && HasSecretReference(value) {
+			continue
+		}
+
+		drift = append(drift, change)
+	}
+
+	return drift
+}
+
+// deleteRelease removes the release of an application from a cluster, if the cluster still exists.
+func (r *ApplicationReconciler) deleteRelease(app application.Application, clusterID uint) error {
+	cluster, err := r.manager.GetClusterByIDOnly(context.Background(), clusterID)
+	if intCluster.IsClusterNotFoundError(err) {
+		return nil
+	}
+	if err != nil {
+		return emperror.WrapWith(err, "failed to retrieve cluster", "clusterId", clusterID)
+	}
+
+	kubeConfig, err := cluster.GetK8sConfig()
+	if err != nil {
+		return emperror.WrapWith(err, "failed to get kubeconfig", "clusterId", clusterID)
+	}
+
+	err = helm.DeleteDeployment(app.ReleaseName, kubeConfig)
+	if err != nil && strings.Contains(err.Error(), "not found") {
+		return nil
+	}
+
+	return emperror.WrapWith(err, "failed to delete release", "clusterId", clusterID, "release", app.ReleaseName)
+}
@@ -0,0 +1,51 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	pkgHelm "github.com/banzaicloud/pipeline/pkg/helm"
+)
+
+func TestValuesDrift(t *testing.T) {
+	// deployed values include the chart defaults
+	deployed := map[string]interface{}{
+		"replicaCount": 1.0,
+		"image": map[string]interface{}{
+			"repository": "nginx",
+			"tag":        "1.15",
+		},
+		"service": map[string]interface{}{
+			"type": "ClusterIP",
+		},
+	}
+
+	assert.Empty(t, valuesDrift(deployed, map[string]interface{}{
+		"image": map[string]interface{}{"tag": "1.15"},
+	}))
+
+	assert.Equal(t, []pkgHelm.ValueChange{
+		{Key: "image.tag", From: "1.15", To: "1.16"},
+		{Key: "ingress", To: map[string]interface{}{"enabled": true}},
+		{Key: "replicaCount", From: 1.0, To: 3.0},
+	}, valuesDrift(deployed, map[string]interface{}{
+		"replicaCount": 3.0,
+		"image":        map[string]interface{}{"tag": "1.16"},
+		"ingress":      map[string]interface{}{"enabled": true},
+	}))
+}
@@ -0,0 +1,479 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/gofrs/uuid"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"go.uber.org/cadence/client"
+	k8sHelm "k8s.io/helm/pkg/helm"
+
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/banzaicloud/pipeline/helm"
+	"github.com/banzaicloud/pipeline/internal/clusterbulk"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	pkgHelm "github.com/banzaicloud/pipeline/pkg/helm"
+)
+
+// BulkRunner executes bulk operations on the selected clusters of an organization with bounded concurrency.
+// Cancelling an operation stops clusters that have not been started yet; clusters already being processed finish.
+// Operations may be cancelled on any replica: the runner executing them checks the stored status between clusters.
+type BulkRunner struct {
+	manager *Manager
+	store   *clusterbulk.Store
+	labels  clusterLabelLister
+
+	// owner identifies this process as the executor of the operations it starts
+	owner string
+
+	mu      sync.Mutex
+	cancels map[uint]context.CancelFunc
+
+	logger       logrus.FieldLogger
+	errorHandler emperror.Handler
+}
+
+// NewBulkRunner returns a new BulkRunner instance.
+func NewBulkRunner(manager *Manager, store *clusterbulk.Store, labels clusterLabelLister, logger logrus.FieldLogger, errorHandler emperror.Handler) *BulkRunner {
+	return &BulkRunner{
+		manager:      manager,
+		store:        store,
+		labels:       labels,
+		owner:        uuid.Must(uuid.NewV4()).String(),
+		cancels:      make(map[uint]context.CancelFunc),
+		logger:       logger,
+		errorHandler: errorHandler,
+	}
+}
+
+// Select returns the clusters of the organization matching the selector.
+func (r *BulkRunner) Select(ctx context.Context, organizationID uint, selector clusterbulk.Selector) ([]CommonCluster, error) {
+	if err := selector.Validate(); err != nil {
+		return nil, err
+	}
+
+	clusters, err := r.manager.GetClusters(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := bulkTargets(clusters, r.labels)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []CommonCluster
+	for i, cluster := range clusters {
+		if selector.Matches(targets[i]) {
+			selected = append(selected, cluster)
+		}
+	}
+
+	if len(selected) == 0 {
+		return nil, errors.WithMessage(clusterbulk.ErrInvalidOperation, "no clusters match the selector")
+	}
+
+	return selected, nil
+}
+
+// Start starts the bulk operation on the selected clusters in the background.
+func (r *BulkRunner) Start(organizationID uint, userID uint, request clusterbulk.Request, clusters []CommonCluster) (*clusterbulk.Operation, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	targets, err := bulkTargets(clusters, r.labels)
+	if err != nil {
+		return nil, err
+	}
+
+	operation, err := r.store.Create(organizationID, userID, request, targets)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	r.mu.Lock()
+	r.cancels[operation.ID] = cancel
+	r.mu.Unlock()
+
+	go r.run(ctx, cancel, *operation, clusters)
+
+	return operation, nil
+}
+
+// clusterLabelLister returns the labels of clusters keyed by cluster ID.
+type clusterLabelLister interface {
+	List(clusterIDs []uint) (map[uint]map[string]string, error)
+}
+
+// bulkTargets returns the properties of clusters a selector is matched against, in the order of the clusters.
+func bulkTargets(clusters []CommonCluster, labels clusterLabelLister) ([]clusterbulk.Target, error) {
+	clusterIDs := make([]uint, 0, len(clusters))
+	for _, cluster := range clusters {
+		clusterIDs = append(clusterIDs, cluster.GetID())
+	}
+
+	clusterLabels, err := labels.List(clusterIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]clusterbulk.Target, 0, len(clusters))
+	for _, cluster := range clusters {
+		targets = append(targets, clusterbulk.Target{
+			ClusterID:    cluster.GetID(),
+			ClusterName:  cluster.GetName(),
+			Cloud:        cluster.GetCloud(),
+			Distribution: cluster.GetDistribution(),
+			Labels:       clusterLabels[cluster.GetID()],
+		})
+	}
+
+	return targets, nil
+}
+
+// Cancel cancels a running bulk operation.
+// The operation is stopped right away if it runs in this process, otherwise when its runner notices the cancellation.
+func (r *BulkRunner) Cancel(organizationID uint, operationID uint) (*clusterbulk.Operation, error) {
+	operation, err := r.store.Get(organizationID, operationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if operation.Status.Finished() {
+		return nil, clusterbulk.ErrOperationFinished
+	}
+
+	if err := r.store.SetStatus(operationID, clusterbulk.StatusCancelling); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	if cancel, ok := r.cancels[operationID]; ok {
+		cancel()
+	}
+	r.mu.Unlock()
+
+	return r.store.Get(organizationID, operationID)
+}
+
+func (r *BulkRunner) run(ctx context.Context, cancel context.CancelFunc, operation clusterbulk.Operation, clusters []CommonCluster) {
+	logger := r.logger.WithFields(logrus.Fields{
+		"operation": operation.ID,
+		"action":    operation.Action,
+	})
+
+	defer func() {
+		r.mu.Lock()
+		delete(r.cancels, operation.ID)
+		r.mu.Unlock()
+
+		cancel()
+	}()
+
+	logger.WithField("clusters", len(clusters)).Info("starting cluster bulk operation")
+
+	// without a recorded owner the operation would be interrupted as stale, so it is not executed at all
+	started, err := r.store.Start(operation.ID, r.owner)
+	if err != nil {
+		r.errorHandler.Handle(err)
+		cancel()
+	} else if !started {
+		logger.Info("cluster bulk operation was cancelled before it started")
+		cancel()
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go r.heartbeat(done, cancel, operation.ID)
+
+	sem := make(chan struct{}, operation.Concurrency)
+	var wg sync.WaitGroup
+
+	for _, cluster := range clusters {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+
+		if ctx.Err() == nil && r.isCancelling(operation.ID) {
+			cancel()
+		}
+
+		if ctx.Err() != nil {
+			r.setClusterStatus(operation.ID, cluster.GetID(), clusterbulk.StatusCancelled, "")
+			continue
+		}
+
+		wg.Add(1)
+		go func(cluster CommonCluster) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			r.runCluster(operation, cluster, logger.WithField("cluster", cluster.GetName()))
+		}(cluster)
+	}
+
+	wg.Wait()
+
+	status, err := r.store.Finish(operation.ID)
+	if err != nil {
+		r.errorHandler.Handle(err)
+
+		return
+	}
+
+	logger.WithField("status", status).Info("cluster bulk operation finished")
+}
+
+// heartbeat periodically records that the operation is being executed by this process until done is closed,
+// and cancels the operation when it was cancelled on another replica.
+func (r *BulkRunner) heartbeat(done <-chan struct{}, cancel context.CancelFunc, operationID uint) {
+	ticker := time.NewTicker(clusterbulk.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-done:
+			return
+		}
+
+		if err := r.store.Heartbeat(operationID, r.owner); err != nil {
+			r.errorHandler.Handle(err)
+		}
+
+		if r.isCancelling(operationID) {
+			cancel()
+		}
+	}
+}
+
+// isCancelling returns true if the operation was cancelled, possibly on another replica.
+func (r *BulkRunner) isCancelling(operationID uint) bool {
+	status, err := r.store.GetStatus(operationID)
+	if err != nil {
+		r.errorHandler.Handle(err)
+
+		return false
+	}
+
+	return status == clusterbulk.StatusCancelling
+}
+
+func (r *BulkRunner) runCluster(operation clusterbulk.Operation, cluster CommonCluster, logger logrus.FieldLogger) {
+	r.setClusterStatus(operation.ID, cluster.GetID(), clusterbulk.StatusRunning, "")
+
+	err := r.execute(operation, cluster)
+	if err != nil {
+		logger.WithError(err).Warn("cluster bulk operation action failed")
+
+		r.setClusterStatus(operation.ID, cluster.GetID(), clusterbulk.StatusFailed, err.Error())
+
+		return
+	}
+
+	r.setClusterStatus(operation.ID, cluster.GetID(), clusterbulk.StatusSucceeded, "")
+}
+
+func (r *BulkRunner) setClusterStatus(operationID uint, clusterID uint, status clusterbulk.Status, message string) {
+	if err := r.store.SetClusterStatus(operationID, clusterID, status, message); err != nil {
+		r.errorHandler.Handle(err)
+	}
+}
+
+func (r *BulkRunner) execute(operation clusterbulk.Operation, cluster CommonCluster) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = errors.Errorf("internal error: %v", rec)
+		}
+	}()
+
+	switch operation.Action {
+	case clusterbulk.PostHooksAction:
+		var postHooks pkgCluster.PostHooks
+		if err := decodeBulkParams(operation.Params, &postHooks); err != nil {
+			return err
+		}
+
+		return r.runPostHooks(cluster, postHooks)
+	case clusterbulk.InstallSecretAction:
+		var params clusterbulk.InstallSecretParams
+		if err := decodeBulkParams(operation.Params, &params); err != nil {
+			return err
+		}
+
+		return installBulkSecret(cluster, params)
+	case clusterbulk.DeploymentAction:
+		var params pkgHelm.CreateUpdateDeploymentRequest
+		if err := decodeBulkParams(operation.Params, &params); err != nil {
+			return err
+		}
+
+		return installOrUpgradeDeployment(cluster, params)
+	case clusterbulk.DeleteAction:
+		var params clusterbulk.DeleteParams
+		if err := decodeBulkParams(operation.Params, &params); err != nil {
+			return err
+		}
+
+		if cluster.GetDistribution() == pkgCluster.PKE && cluster.GetCloud() == pkgCluster.Azure {
+			return errors.New("PKE on Azure clusters cannot be deleted by bulk operations")
+		}
+
+		return r.manager.DeleteCluster(context.Background(), cluster, params.Force)
+	}
+
+	return errors.Errorf("unknown action %q", operation.Action)
+}
+
+// runPostHooks runs the posthooks workflow on a cluster and waits for its result.
+func (r *BulkRunner) runPostHooks(cluster CommonCluster, postHooks pkgCluster.PostHooks) error {
+	input := RunPostHooksWorkflowInput{
+		ClusterID: cluster.GetID(),
+		PostHooks: BuildWorkflowPostHookFunctions(postHooks, false),
+	}
+
+	workflowOptions := client.StartWorkflowOptions{
+		TaskList:                     "pipeline",
+		ExecutionStartToCloseTimeout: 2 * time.Hour,
+	}
+
+	exec, err := r.manager.workflowClient.ExecuteWorkflow(context.Background(), workflowOptions, RunPostHooksWorkflowName, input)
+	if err != nil {
+		return emperror.WrapWith(err, "failed to start workflow", "workflowName", RunPostHooksWorkflowName)
+	}
+
+	return emperror.WrapWith(exec.Get(context.Background(), nil), "posthooks workflow failed", "workflowID", exec.GetID())
+}
+
+func installBulkSecret(cluster CommonCluster, params clusterbulk.InstallSecretParams) error {
+	request := InstallSecretRequest{
+		SourceSecretName: params.SourceSecretName,
+		Namespace:        params.Namespace,
+		Spec:             map[string]InstallSecretRequestSpecItem{},
+	}
+
+	for key, spec := range params.Spec {
+		request.Spec[key] = InstallSecretRequestSpecItem{
+			Source:    spec.Source,
+			SourceMap: spec.SourceMap,
+			Value:     spec.Value,
+		}
+	}
+
+	if request.SourceSecretName == "" {
+		request.SourceSecretName = params.Name
+	}
+
+	_, err := InstallSecret(cluster, params.Name, request)
+
+	return err
+}
+
+// installOrUpgradeDeployment upgrades a Helm release if it already exists in the cluster, otherwise installs it.
+func installOrUpgradeDeployment(cluster CommonCluster, request pkgHelm.CreateUpdateDeploymentRequest) error {
+	kubeConfig, err := cluster.GetK8sConfig()
+	if err != nil {
+		return emperror.Wrap(err, "failed to get kubeconfig")
+	}
+
+	org, err := auth.GetOrganizationById(cluster.GetOrganizationId())
+	if err != nil {
+		return emperror.Wrap(err, "failed to get organization")
+	}
+
+	var values []byte
+	if request.Values != nil {
+		values, err = yaml.Marshal(request.Values)
+		if err != nil {
+			return emperror.Wrap(err, "failed to marshal values")
+		}
+	}
+
+	env := helm.GenerateHelmRepoEnv(org.Name)
+
+	deployment, err := helm.GetDeployment(request.ReleaseName, kubeConfig)
+	if _, ok := errors.Cause(err).(*helm.DeploymentNotFoundError); ok {
+		values, err = ResolveDeploymentSecrets(cluster, request.Namespace, values, false, nil)
+		if err != nil {
+			return emperror.Wrap(err, "failed to resolve secret references")
+		}
+
+		options := []k8sHelm.InstallOption{
+			k8sHelm.InstallWait(request.Wait),
+			k8sHelm.ValueOverrides(values),
+		}
+
+		if request.Timeout > 0 {
+			options = append(options, k8sHelm.InstallTimeout(request.Timeout))
+		}
+
+		_, err = helm.CreateDeployment(
+			request.Name,
+			request.Version,
+			request.Package,
+			request.Namespace,
+			request.ReleaseName,
+			false,
+			request.OdPcts,
+			kubeConfig,
+			env,
+			options...,
+		)
+
+		return emperror.Wrap(err, "failed to install deployment")
+	}
+	if err != nil {
+		return emperror.Wrap(err, "failed to get deployment")
+	}
+
+	values, err = ResolveDeploymentSecrets(cluster, deployment.Namespace, values, false, nil)
+	if err != nil {
+		return emperror.Wrap(err, "failed to resolve secret references")
+	}
+
+	_, err = helm.UpgradeDeployment(
+		request.ReleaseName,
+		request.Name,
+		request.Version,
+		request.Package,
+		values,
+		request.ReUseValues,
+		kubeConfig,
+		env,
+	)
+
+	return emperror.Wrap(err, "failed to upgrade deployment")
+}
+
+func decodeBulkParams(raw json.RawMessage, params interface{}) error {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	return errors.Wrap(json.Unmarshal(raw, params), "failed to decode action parameters")
+}
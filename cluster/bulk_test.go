@@ -0,0 +1,138 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/banzaicloud/pipeline/internal/clusterbulk"
+)
+
+type bulkClusterStub struct {
+	CommonCluster
+
+	id uint
+
+	// execute is called when the bulk operation starts working on the cluster
+	execute func()
+}
+
+func (c *bulkClusterStub) GetID() uint {
+	return c.id
+}
+
+func (c *bulkClusterStub) GetName() string {
+	return "test"
+}
+
+func (c *bulkClusterStub) GetCloud() string {
+	return "dummy"
+}
+
+func (c *bulkClusterStub) GetDistribution() string {
+	return "dummy"
+}
+
+func (c *bulkClusterStub) GetK8sConfig() ([]byte, error) {
+	if c.execute != nil {
+		c.execute()
+	}
+
+	return nil, errors.New("no kubeconfig")
+}
+
+func newTestBulkRunner(t *testing.T) (*BulkRunner, *clusterbulk.Store) {
+	db, err := gorm.Open("sqlite3", "file::memory:")
+	require.NoError(t, err)
+
+	require.NoError(t, db.AutoMigrate(&clusterbulk.OperationModel{}, &clusterbulk.OperationClusterModel{}).Error)
+
+	store := clusterbulk.NewStore(db)
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+
+	return NewBulkRunner(nil, store, nil, logger, &errorHandlerStub{}), store
+}
+
+func TestBulkRunner_CancelledOnOtherReplica(t *testing.T) {
+	runner, store := newTestBulkRunner(t)
+
+	request := clusterbulk.Request{
+		Action:      clusterbulk.DeploymentAction,
+		Params:      json.RawMessage(`{"name":"stable/nginx","releaseName":"nginx"}`),
+		Concurrency: 1,
+	}
+	targets := []clusterbulk.Target{{ClusterID: 1}, {ClusterID: 2}, {ClusterID: 3}}
+
+	operation, err := store.Create(1, 10, request, targets)
+	require.NoError(t, err)
+
+	// another replica cancels the operation while the first cluster is being processed
+	clusters := []CommonCluster{
+		&bulkClusterStub{id: 1, execute: func() {
+			require.NoError(t, store.SetStatus(operation.ID, clusterbulk.StatusCancelling))
+		}},
+		&bulkClusterStub{id: 2},
+		&bulkClusterStub{id: 3},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runner.run(ctx, cancel, *operation, clusters)
+
+	operation, err = store.Get(1, operation.ID)
+	require.NoError(t, err)
+
+	assert.Equal(t, clusterbulk.StatusCancelled, operation.Status)
+	assert.Equal(t, clusterbulk.StatusFailed, operation.Clusters[0].Status)
+	assert.Equal(t, clusterbulk.StatusCancelled, operation.Clusters[1].Status)
+	assert.Equal(t, clusterbulk.StatusCancelled, operation.Clusters[2].Status)
+}
+
+func TestBulkRunner_CancelledBeforeStart(t *testing.T) {
+	runner, store := newTestBulkRunner(t)
+
+	request := clusterbulk.Request{
+		Action:      clusterbulk.DeploymentAction,
+		Params:      json.RawMessage(`{"name":"stable/nginx","releaseName":"nginx"}`),
+		Concurrency: 1,
+	}
+
+	operation, err := store.Create(1, 10, request, []clusterbulk.Target{{ClusterID: 1}})
+	require.NoError(t, err)
+	require.NoError(t, store.SetStatus(operation.ID, clusterbulk.StatusCancelling))
+
+	executed := false
+	clusters := []CommonCluster{&bulkClusterStub{id: 1, execute: func() { executed = true }}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runner.run(ctx, cancel, *operation, clusters)
+
+	assert.False(t, executed)
+
+	operation, err = store.Get(1, operation.ID)
+	require.NoError(t, err)
+
+	assert.Equal(t, clusterbulk.StatusCancelled, operation.Status)
+	assert.Equal(t, clusterbulk.StatusCancelled, operation.Clusters[0].Status)
+}
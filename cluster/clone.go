@@ -0,0 +1,364 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"time"
+
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	pipConfig "github.com/banzaicloud/pipeline/config"
+	"github.com/banzaicloud/pipeline/helm"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	pkgClusterAzure "github.com/banzaicloud/pipeline/pkg/cluster/aks"
+	pkgEks "github.com/banzaicloud/pipeline/pkg/cluster/eks"
+	pkgClusterGoogle "github.com/banzaicloud/pipeline/pkg/cluster/gke"
+	"github.com/banzaicloud/pipeline/secret"
+)
+
+// CloneOptions describes the overrides of a cluster clone and what to copy from the source cluster.
+type CloneOptions struct {
+	Name      string
+	Location  string
+	SecretID  string
+	PostHooks pkgCluster.PostHooks
+
+	// Deployments are the release names of the Helm deployments to copy.
+	Deployments []string
+	// AllDeployments copies every Helm deployment outside of the Pipeline system namespace.
+	AllDeployments bool
+	// Secrets are the names of the secrets installed in the source cluster to install in the clone.
+	Secrets []string
+}
+
+// CopiesResources returns true if anything has to be copied into the clone after it has been created.
+func (o CloneOptions) CopiesResources() bool {
+	return o.AllDeployments || len(o.Deployments) > 0 || len(o.Secrets) > 0
+}
+
+// NewCloneRequest reconstructs a create request from a stored cluster and applies the clone overrides.
+// Settings bound to the source location (images, subnets) are only kept when the location is unchanged.
+// The returned warnings list the features of the source cluster that cannot be reproduced.
+func NewCloneRequest(source CommonCluster, options CloneOptions) (*pkgCluster.CreateClusterRequest, []string, error) {
+	location := source.GetLocation()
+	if options.Location != "" {
+		location = options.Location
+	}
+	sameLocation := location == source.GetLocation()
+
+	secretID := source.GetSecretId()
+	if options.SecretID != "" {
+		secretID = options.SecretID
+	}
+
+	request := &pkgCluster.CreateClusterRequest{
+		Name:         options.Name,
+		Location:     location,
+		Cloud:        source.GetCloud(),
+		SecretId:     secretID,
+		Properties:   &pkgCluster.CreateClusterProperties{},
+		ScaleOptions: source.GetScaleOptions(),
+	}
+
+	if ttl := source.GetTTL(); ttl > 0 {
+		request.TtlMinutes = uint(ttl / time.Minute)
+	}
+
+	switch c := source.(type) {
+	case *EKSCluster:
+		request.Properties.CreateClusterEKS = newEKSCloneProperties(c, sameLocation)
+	case *GKECluster:
+		request.Properties.CreateClusterGKE = newGKECloneProperties(c, sameLocation)
+	case *AKSCluster:
+		request.Properties.CreateClusterAKS = newAKSCloneProperties(c, sameLocation)
+	default:
+		return nil, nil, &invalidError{errors.Errorf("cloning is not supported for %s clusters", source.GetDistribution())}
+	}
+
+	postHooks, warnings := clonePostHooks(source, options.PostHooks)
+	request.PostHooks = postHooks
+
+	return request, warnings, nil
+}
+
+func newEKSCloneProperties(c *EKSCluster, sameLocation bool) *pkgEks.CreateClusterEKS {
+	nodePools := make(map[string]*pkgEks.NodePool, len(c.modelCluster.EKS.NodePools))
+	for _, np := range c.modelCluster.EKS.NodePools {
+		nodePool := &pkgEks.NodePool{
+			InstanceType: np.NodeInstanceType,
+			SpotPrice:    np.NodeSpotPrice,
+			Autoscaling:  np.Autoscaling,
+			MinCount:     np.NodeMinCount,
+			MaxCount:     np.NodeMaxCount,
+			Count:        np.Count,
+			Labels:       np.Labels,
+		}
+
+		// AMIs are regional, the default image of the new region is used otherwise
+		if sameLocation {
+			nodePool.Image = np.NodeImage
+		}
+
+		nodePools[np.Name] = nodePool
+	}
+
+	return &pkgEks.CreateClusterEKS{
+		Version:   c.modelCluster.EKS.Version,
+		NodePools: nodePools,
+	}
+}
+
+func newGKECloneProperties(c *GKECluster, sameLocation bool) *pkgClusterGoogle.CreateClusterGKE {
+	nodePools := make(map[string]*pkgClusterGoogle.NodePool, len(c.model.NodePools))
+	for _, np := range c.model.NodePools {
+		nodePools[np.Name] = &pkgClusterGoogle.NodePool{
+			Autoscaling:      np.Autoscaling,
+			MinCount:         np.NodeMinCount,
+			MaxCount:         np.NodeMaxCount,
+			Count:            np.NodeCount,
+			NodeInstanceType: np.NodeInstanceType,
+			Preemptible:      np.Preemptible,
+			Labels:           np.Labels,
+		}
+	}
+
+	properties := &pkgClusterGoogle.CreateClusterGKE{
+		NodeVersion: c.model.NodeVersion,
+		NodePools:   nodePools,
+		Master: &pkgClusterGoogle.Master{
+			Version: c.model.MasterVersion,
+		},
+		ProjectId: c.model.ProjectId,
+	}
+
+	// subnets are regional
+	if sameLocation {
+		properties.Vpc = c.model.Vpc
+		properties.Subnet = c.model.Subnet
+	}
+
+	return properties
+}
+
+func newAKSCloneProperties(c *AKSCluster, sameLocation bool) *pkgClusterAzure.CreateClusterAKS {
+	nodePools := make(map[string]*pkgClusterAzure.NodePoolCreate, len(c.modelCluster.AKS.NodePools))
+	for _, np := range c.modelCluster.AKS.NodePools {
+		nodePool := &pkgClusterAzure.NodePoolCreate{
+			Autoscaling:      np.Autoscaling,
+			MinCount:         np.NodeMinCount,
+			MaxCount:         np.NodeMaxCount,
+			Count:            np.Count,
+			NodeInstanceType: np.NodeInstanceType,
+			Labels:           np.Labels,
+		}
+
+		// virtual networks are regional
+		if sameLocation {
+			nodePool.VNetSubnetID = np.VNetSubnetID
+		}
+
+		nodePools[np.Name] = nodePool
+	}
+
+	return &pkgClusterAzure.CreateClusterAKS{
+		ResourceGroup:     c.modelCluster.AKS.ResourceGroup,
+		KubernetesVersion: c.modelCluster.AKS.KubernetesVersion,
+		NodePools:         nodePools,
+	}
+}
+
+// clonePostHooks returns the posthooks enabling the features of the source cluster merged with the requested ones.
+// Posthook parameters are not stored, so the ones without usable defaults have to be passed in the request.
+func clonePostHooks(source CommonCluster, requested pkgCluster.PostHooks) (pkgCluster.PostHooks, []string) {
+	postHooks := make(pkgCluster.PostHooks)
+	var warnings []string
+
+	if source.GetMonitoring() {
+		postHooks[pkgCluster.InstallMonitoring] = nil
+	}
+
+	if source.GetServiceMesh() {
+		postHooks[pkgCluster.InstallServiceMesh] = nil
+	}
+
+	if source.GetSecurityScan() {
+		postHooks[pkgCluster.InstallAnchoreImageValidator] = nil
+	}
+
+	for name, param := range requested {
+		postHooks[name] = param
+	}
+
+	if _, ok := postHooks[pkgCluster.InstallLogging]; source.GetLogging() && !ok {
+		warnings = append(warnings, "logging is enabled on the source cluster, but it is not enabled on the clone: pass the InstallLogging posthook with its parameters to enable it")
+	}
+
+	return postHooks, warnings
+}
+
+// CopyClusterResources waits for a cloned cluster to be running, then copies the selected
+// Helm deployments and installed secrets of the source cluster into it.
+// Copying continues after a failed item; the errors are returned together.
+func (m *Manager) CopyClusterResources(ctx context.Context, source CommonCluster, targetID uint, options CloneOptions) error {
+	logger := m.getLogger(ctx).WithFields(logrus.Fields{
+		"organization":    source.GetOrganizationId(),
+		"sourceClusterId": source.GetID(),
+		"clusterId":       targetID,
+	})
+
+	target, err := m.waitForRunningCluster(ctx, source.GetOrganizationId(), targetID)
+	if err != nil {
+		return err
+	}
+
+	sourceKubeConfig, err := source.GetK8sConfig()
+	if err != nil {
+		return emperror.Wrap(err, "failed to get source cluster kubeconfig")
+	}
+
+	targetKubeConfig, err := target.GetK8sConfig()
+	if err != nil {
+		return emperror.Wrap(err, "failed to get cluster kubeconfig")
+	}
+
+	errs := emperror.NewMultiErrorBuilder()
+
+	if len(options.Secrets) > 0 {
+		logger.Info("copying secrets")
+
+		for _, err := range copyInstalledSecrets(source, target, options.Secrets) {
+			errs.Add(err)
+		}
+	}
+
+	releaseNames := options.Deployments
+	if options.AllDeployments {
+		releaseNames, err = listCopiableDeployments(sourceKubeConfig)
+		if err != nil {
+			errs.Add(err)
+		}
+	}
+
+	for _, releaseName := range releaseNames {
+		logger.WithField("release", releaseName).Info("copying deployment")
+
+		if err := helm.CopyDeployment(releaseName, sourceKubeConfig, targetKubeConfig); err != nil {
+			errs.Add(emperror.With(err, "release", releaseName))
+		}
+	}
+
+	return emperror.Wrap(errs.ErrOrNil(), "failed to copy cluster resources")
+}
+
+// waitForRunningCluster polls the status of a cluster until it is running.
+func (m *Manager) waitForRunningCluster(ctx context.Context, organizationID uint, clusterID uint) (CommonCluster, error) {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		cluster, err := m.GetClusterByID(ctx, organizationID, clusterID)
+		if err != nil {
+			return nil, err
+		}
+
+		status, err := cluster.GetStatus()
+		if err != nil {
+			return nil, err
+		}
+
+		switch status.Status {
+		case pkgCluster.Running:
+			return cluster, nil
+		case pkgCluster.Error, pkgCluster.Warning:
+			return nil, errors.Errorf("cluster is not running, current status: %s", status.Status)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// listCopiableDeployments lists the deployed Helm releases of a cluster, except the ones installed by Pipeline.
+func listCopiableDeployments(kubeConfig []byte) ([]string, error) {
+	releases, err := helm.ListDeployments(nil, "", kubeConfig)
+	if err != nil {
+		return nil, emperror.Wrap(err, "failed to list deployments")
+	}
+
+	systemNamespace := viper.GetString(pipConfig.PipelineSystemNamespace)
+
+	var releaseNames []string
+	for _, release := range releases.GetReleases() {
+		if release.GetNamespace() == systemNamespace || release.GetInfo().GetStatus().GetCode().String() != "DEPLOYED" {
+			continue
+		}
+
+		releaseNames = append(releaseNames, release.GetName())
+	}
+
+	return releaseNames, nil
+}
+
+// copyInstalledSecrets installs the selected secrets into the target cluster the same way they are installed in the source cluster.
+func copyInstalledSecrets(source CommonCluster, target CommonCluster, secretNames []string) []error {
+	if secretInstallationStore == nil {
+		return []error{errors.New("secret installations are not recorded")}
+	}
+
+	var errs []error
+	for _, secretName := range secretNames {
+		installations, err := secretInstallationStore.List(source.GetOrganizationId(), secret.GenerateSecretIDFromName(secretName))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, inst := range installations {
+			if inst.ClusterID != source.GetID() {
+				continue
+			}
+
+			req := InstallSecretRequest{
+				SourceSecretName: secretName,
+				Namespace:        inst.Namespace,
+			}
+
+			if len(inst.Spec) > 0 {
+				req.Spec = make(map[string]InstallSecretRequestSpecItem, len(inst.Spec))
+				for key, spec := range inst.Spec {
+					req.Spec[key] = InstallSecretRequestSpecItem{
+						Source:    spec.Source,
+						SourceMap: spec.SourceMap,
+						Value:     spec.Value,
+					}
+				}
+			}
+
+			_, err := InstallSecret(target, inst.Name, req)
+			if err != nil && err != ErrKubernetesSecretAlreadyExists {
+				errs = append(errs, emperror.With(err, "secret", secretName, "namespace", inst.Namespace))
+			}
+		}
+	}
+
+	return errs
+}
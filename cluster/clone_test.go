@@ -0,0 +1,206 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/banzaicloud/pipeline/internal/cluster"
+	"github.com/banzaicloud/pipeline/internal/providers/google"
+	"github.com/banzaicloud/pipeline/model"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	pkgClusterAzure "github.com/banzaicloud/pipeline/pkg/cluster/aks"
+	pkgEks "github.com/banzaicloud/pipeline/pkg/cluster/eks"
+	pkgClusterGoogle "github.com/banzaicloud/pipeline/pkg/cluster/gke"
+)
+
+func newEKSCloneSource() *EKSCluster {
+	return CreateEKSClusterFromModel(&model.ClusterModel{
+		ID:           1,
+		Name:         "prod-eu",
+		Location:     "eu-west-1",
+		Cloud:        pkgCluster.Amazon,
+		Distribution: pkgCluster.EKS,
+		SecretId:     "secret",
+		TtlMinutes:   120,
+		Monitoring:   true,
+		EKS: model.EKSClusterModel{
+			Version: "1.12",
+			NodePools: []*model.AmazonNodePoolsModel{
+				{
+					Name:             "pool1",
+					NodeInstanceType: "m4.xlarge",
+					NodeSpotPrice:    "0.2",
+					Autoscaling:      true,
+					NodeMinCount:     1,
+					NodeMaxCount:     3,
+					Count:            2,
+					NodeImage:        "ami-123",
+				},
+			},
+		},
+	})
+}
+
+func TestNewCloneRequest_EKS(t *testing.T) {
+	t.Run("SameLocation", func(t *testing.T) {
+		request, warnings, err := NewCloneRequest(newEKSCloneSource(), CloneOptions{Name: "prod-eu-2"})
+		require.NoError(t, err)
+
+		assert.Empty(t, warnings)
+		assert.Equal(t, "prod-eu-2", request.Name)
+		assert.Equal(t, "eu-west-1", request.Location)
+		assert.Equal(t, pkgCluster.Amazon, request.Cloud)
+		assert.Equal(t, "secret", request.SecretId)
+		assert.Equal(t, uint(120), request.TtlMinutes)
+		assert.Equal(t, &pkgEks.CreateClusterEKS{
+			Version: "1.12",
+			NodePools: map[string]*pkgEks.NodePool{
+				"pool1": {
+					InstanceType: "m4.xlarge",
+					SpotPrice:    "0.2",
+					Autoscaling:  true,
+					MinCount:     1,
+					MaxCount:     3,
+					Count:        2,
+					Image:        "ami-123",
+				},
+			},
+		}, request.Properties.CreateClusterEKS)
+		assert.Equal(t, pkgCluster.PostHooks{pkgCluster.InstallMonitoring: nil}, request.PostHooks)
+	})
+
+	t.Run("Overrides", func(t *testing.T) {
+		request, _, err := NewCloneRequest(newEKSCloneSource(), CloneOptions{
+			Name:     "prod-us",
+			Location: "us-east-1",
+			SecretID: "other-secret",
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "us-east-1", request.Location)
+		assert.Equal(t, "other-secret", request.SecretId)
+		assert.Empty(t, request.Properties.CreateClusterEKS.NodePools["pool1"].Image)
+	})
+}
+
+func TestNewCloneRequest_GKE(t *testing.T) {
+	source := &GKECluster{
+		model: &google.GKEClusterModel{
+			Cluster: cluster.ClusterModel{
+				Name:         "prod-eu",
+				Location:     "europe-west1-b",
+				Cloud:        pkgCluster.Google,
+				Distribution: pkgCluster.GKE,
+				SecretID:     "secret",
+			},
+			MasterVersion: "1.12.7",
+			NodeVersion:   "1.12.7",
+			ProjectId:     "project",
+			Vpc:           "vpc",
+			Subnet:        "subnet",
+			NodePools: []*google.GKENodePoolModel{
+				{Name: "pool1", NodeCount: 2, NodeInstanceType: "n1-standard-2", Preemptible: true},
+			},
+		},
+	}
+
+	request, _, err := NewCloneRequest(source, CloneOptions{Name: "prod-us", Location: "us-central1-a"})
+	require.NoError(t, err)
+
+	assert.Equal(t, &pkgClusterGoogle.CreateClusterGKE{
+		NodeVersion: "1.12.7",
+		NodePools: map[string]*pkgClusterGoogle.NodePool{
+			"pool1": {Count: 2, NodeInstanceType: "n1-standard-2", Preemptible: true},
+		},
+		Master:    &pkgClusterGoogle.Master{Version: "1.12.7"},
+		ProjectId: "project",
+	}, request.Properties.CreateClusterGKE)
+}
+
+func TestNewCloneRequest_AKS(t *testing.T) {
+	source := CreateAKSClusterFromModel(&model.ClusterModel{
+		Name:         "prod-eu",
+		Location:     "westeurope",
+		Cloud:        pkgCluster.Azure,
+		Distribution: pkgCluster.AKS,
+		SecretId:     "secret",
+		AKS: model.AKSClusterModel{
+			ResourceGroup:     "rg",
+			KubernetesVersion: "1.12.7",
+			NodePools: []*model.AKSNodePoolModel{
+				{Name: "pool1", Count: 3, NodeMinCount: 1, NodeMaxCount: 5, NodeInstanceType: "Standard_D2_v2", VNetSubnetID: "subnet"},
+			},
+		},
+	})
+
+	request, _, err := NewCloneRequest(source, CloneOptions{Name: "prod-eu-2"})
+	require.NoError(t, err)
+
+	assert.Equal(t, &pkgClusterAzure.CreateClusterAKS{
+		ResourceGroup:     "rg",
+		KubernetesVersion: "1.12.7",
+		NodePools: map[string]*pkgClusterAzure.NodePoolCreate{
+			"pool1": {Count: 3, MinCount: 1, MaxCount: 5, NodeInstanceType: "Standard_D2_v2", VNetSubnetID: "subnet"},
+		},
+	}, request.Properties.CreateClusterAKS)
+}
+
+func TestNewCloneRequest_Unsupported(t *testing.T) {
+	source := &KubeCluster{modelCluster: &model.ClusterModel{Name: "kube", Distribution: pkgCluster.Unknown}}
+
+	_, _, err := NewCloneRequest(source, CloneOptions{Name: "clone"})
+
+	require.Error(t, err)
+	assert.IsType(t, &invalidError{}, err)
+}
+
+func TestClonePostHooks(t *testing.T) {
+	source := CreateEKSClusterFromModel(&model.ClusterModel{
+		Logging:      true,
+		ServiceMesh:  true,
+		SecurityScan: true,
+	})
+
+	t.Run("LoggingWithoutParams", func(t *testing.T) {
+		postHooks, warnings := clonePostHooks(source, nil)
+
+		assert.Equal(t, pkgCluster.PostHooks{
+			pkgCluster.InstallServiceMesh:           nil,
+			pkgCluster.InstallAnchoreImageValidator: nil,
+		}, postHooks)
+		assert.Len(t, warnings, 1)
+	})
+
+	t.Run("RequestedPostHooks", func(t *testing.T) {
+		loggingParam := pkgCluster.LoggingParam{BucketName: "logs"}
+		serviceMeshParam := map[string]interface{}{"mtls": true}
+
+		postHooks, warnings := clonePostHooks(source, pkgCluster.PostHooks{
+			pkgCluster.InstallLogging:     loggingParam,
+			pkgCluster.InstallServiceMesh: serviceMeshParam,
+		})
+
+		assert.Equal(t, pkgCluster.PostHooks{
+			pkgCluster.InstallLogging:               loggingParam,
+			pkgCluster.InstallServiceMesh:           serviceMeshParam,
+			pkgCluster.InstallAnchoreImageValidator: nil,
+		}, postHooks)
+		assert.Empty(t, warnings)
+	})
+}
@@ -16,6 +16,13 @@ package cluster
 
 import (
 	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/cadence/activity"
+	"go.uber.org/cadence/workflow"
+
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
 )
 
 const UpdateClusterStatusActivityName = "update-cluster-status"
@@ -44,3 +51,54 @@ func (a *UpdateClusterStatusActivity) Execute(ctx context.Context, input UpdateC
 
 	return c.SetStatus(input.Status, input.StatusMessage)
 }
+
+// clusterWorkflowID returns the ID of a cluster workflow. Workflows with well-known IDs can be looked up
+// without storing their IDs, and at most one of them can run for a cluster at a time.
+func clusterWorkflowID(workflowName string, clusterID uint) string {
+	return fmt.Sprintf("%s-%d", workflowName, clusterID)
+}
+
+func setClusterStatus(ctx workflow.Context, clusterID uint, status, statusMessage string) error {
+	return workflow.ExecuteActivity(ctx, UpdateClusterStatusActivityName, UpdateClusterStatusActivityInput{
+		ClusterID:     clusterID,
+		Status:        status,
+		StatusMessage: statusMessage,
+	}).Get(ctx, nil)
+}
+
+func setClusterErrorStatus(ctx workflow.Context, clusterID uint, err error) error {
+	return setClusterStatus(ctx, clusterID, pkgCluster.Error, err.Error())
+}
+
+// heartbeatInterval is the interval of heartbeats recorded by long running activities.
+const heartbeatInterval = 20 * time.Second
+
+// heartbeatWhile runs a blocking function and records activity heartbeats until it returns,
+// so that the loss of the worker running it is detected early.
+// When the activity is cancelled or times out, it still waits for the function to return,
+// so that a retry or a compensation never runs concurrently with it.
+// Functions should stop early when the activity context is done.
+func heartbeatWhile(ctx context.Context, fn func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case err := <-done:
+			return err
+		case <-ctx.Done():
+			if err := <-done; err != nil {
+				return err
+			}
+
+			return ctx.Err()
+		case <-ticker.C:
+			activity.RecordHeartbeat(ctx)
+		}
+	}
+}
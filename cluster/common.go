@@ -27,8 +27,10 @@ import (
 	"github.com/banzaicloud/pipeline/internal/platform/database"
 	"github.com/banzaicloud/pipeline/internal/providers/azure/pke/adapter"
 	pkeAzureAdapter "github.com/banzaicloud/pipeline/internal/providers/azure/pke/driver/commoncluster"
+	internalPke "github.com/banzaicloud/pipeline/internal/providers/pke"
 	"github.com/banzaicloud/pipeline/model"
 	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	"github.com/banzaicloud/pipeline/pkg/cluster/pke"
 	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
 	pkgErrors "github.com/banzaicloud/pipeline/pkg/errors"
 	modelOracle "github.com/banzaicloud/pipeline/pkg/providers/oracle/model"
@@ -36,6 +38,7 @@ import (
 	"github.com/banzaicloud/pipeline/secret"
 	"github.com/banzaicloud/pipeline/utils"
 	"github.com/goph/emperror"
+	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
@@ -472,6 +475,123 @@ func createCommonClusterWithDistributionFromModel(modelCluster *model.ClusterMod
 	}
 }
 
+func getNodePoolsFromCreateRequest(createRequest *pkgCluster.CreateClusterRequest) map[string]*pkgCluster.NodePoolStatus {
+	nodePools := make(map[string]*pkgCluster.NodePoolStatus)
+	properties := createRequest.Properties
+	if properties == nil {
+		return nodePools
+	}
+
+	if properties.CreateClusterPKE != nil {
+		for _, np := range properties.CreateClusterPKE.NodePools {
+			nodePool := &pkgCluster.NodePoolStatus{
+				Autoscaling: np.Autoscaling,
+				Labels:      np.Labels,
+			}
+
+			if np.Provider == pke.NPPAmazon {
+				var providerConfig internalPke.NodePoolProviderConfigAmazon
+				if err := mapstructure.Decode(np.ProviderConfig, &providerConfig); err == nil {
+					nodePool.InstanceType = providerConfig.AutoScalingGroup.InstanceType
+					nodePool.SpotPrice = providerConfig.AutoScalingGroup.SpotPrice
+					nodePool.Count = providerConfig.AutoScalingGroup.Size.Desired
+					nodePool.MinCount = providerConfig.AutoScalingGroup.Size.Min
+					nodePool.MaxCount = providerConfig.AutoScalingGroup.Size.Max
+				}
+			}
+
+			nodePools[np.Name] = nodePool
+		}
+		return nodePools
+	}
+
+	switch createRequest.Cloud {
+	case pkgCluster.Alibaba:
+		if properties.CreateClusterACK != nil {
+			for name, np := range properties.CreateClusterACK.NodePools {
+				if np != nil {
+					nodePools[name] = &pkgCluster.NodePoolStatus{
+						InstanceType: np.InstanceType,
+						Count:        np.MinCount,
+						MinCount:     np.MinCount,
+						MaxCount:     np.MaxCount,
+						Labels:       np.Labels,
+					}
+				}
+			}
+		}
+
+	case pkgCluster.Amazon:
+		if properties.CreateClusterEKS != nil {
+			for name, np := range properties.CreateClusterEKS.NodePools {
+				if np != nil {
+					nodePools[name] = &pkgCluster.NodePoolStatus{
+						Autoscaling:  np.Autoscaling,
+						InstanceType: np.InstanceType,
+						Count:        np.Count,
+						MinCount:     np.MinCount,
+						MaxCount:     np.MaxCount,
+						SpotPrice:    np.SpotPrice,
+						Image:        np.Image,
+						Labels:       np.Labels,
+					}
+				}
+			}
+		}
+
+	case pkgCluster.Azure:
+		if properties.CreateClusterAKS != nil {
+			for name, np := range properties.CreateClusterAKS.NodePools {
+				if np != nil {
+					nodePools[name] = &pkgCluster.NodePoolStatus{
+						Autoscaling:  np.Autoscaling,
+						InstanceType: np.NodeInstanceType,
+						Count:        np.Count,
+						MinCount:     np.MinCount,
+						MaxCount:     np.MaxCount,
+						Labels:       np.Labels,
+					}
+				}
+			}
+		}
+
+	case pkgCluster.Google:
+		if properties.CreateClusterGKE != nil {
+			for name, np := range properties.CreateClusterGKE.NodePools {
+				if np != nil {
+					nodePools[name] = &pkgCluster.NodePoolStatus{
+						Autoscaling:  np.Autoscaling,
+						InstanceType: np.NodeInstanceType,
+						Count:        np.Count,
+						MinCount:     np.MinCount,
+						MaxCount:     np.MaxCount,
+						Preemptible:  np.Preemptible,
+						Labels:       np.Labels,
+					}
+				}
+			}
+		}
+
+	case pkgCluster.Oracle:
+		if properties.CreateClusterOKE != nil {
+			for name, np := range properties.CreateClusterOKE.NodePools {
+				if np != nil {
+					nodePools[name] = &pkgCluster.NodePoolStatus{
+						InstanceType: np.Shape,
+						Count:        int(np.Count),
+						Image:        np.Image,
+						Version:      np.Version,
+						Labels:       np.Labels,
+					}
+				}
+			}
+		}
+
+	}
+
+	return nodePools
+}
+
 func getNodePoolsFromUpdateRequest(updateRequest *pkgCluster.UpdateClusterRequest) map[string]*pkgCluster.NodePoolStatus {
 	nodePools := make(map[string]*pkgCluster.NodePoolStatus)
 	cloudType := updateRequest.Cloud
@@ -0,0 +1,382 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"time"
+
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+	"go.uber.org/cadence"
+	"go.uber.org/cadence/activity"
+	"go.uber.org/cadence/workflow"
+
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	"github.com/banzaicloud/pipeline/secret"
+)
+
+const CreateClusterWorkflowName = "create-cluster"
+
+// PostHooksFailedErrorReason is the reason of the error returned by the cluster creation workflow
+// when the cluster itself has been created, but running its posthooks failed.
+const PostHooksFailedErrorReason = "posthooks-failed"
+
+type CreateClusterWorkflowInput struct {
+	ClusterID uint
+	Cloud     string
+	PostHooks pkgCluster.PostHooks
+}
+
+// CreateClusterWorkflow creates the infrastructure of an EKS, GKE or AKS cluster and runs its posthooks.
+func CreateClusterWorkflow(ctx workflow.Context, input CreateClusterWorkflowInput) error {
+	retryPolicy := &cadence.RetryPolicy{
+		InitialInterval:    time.Second * 3,
+		BackoffCoefficient: 2,
+		ExpirationInterval: time.Minute * 5,
+		MaximumAttempts:    5,
+	}
+	ao := workflow.ActivityOptions{
+		ScheduleToStartTimeout: 10 * time.Minute,
+		StartToCloseTimeout:    10 * time.Minute,
+		WaitForCancellation:    true,
+		RetryPolicy:            retryPolicy,
+	}
+	cwo := workflow.ChildWorkflowOptions{
+		ExecutionStartToCloseTimeout: 2 * time.Hour,
+		TaskStartToCloseTimeout:      5 * time.Minute,
+	}
+	ctx = workflow.WithChildOptions(workflow.WithActivityOptions(ctx, ao), cwo)
+
+	// generate SSH key
+	{
+		activityInput := GenerateClusterSSHKeyActivityInput{
+			ClusterID: input.ClusterID,
+		}
+		if err := workflow.ExecuteActivity(ctx, GenerateClusterSSHKeyActivityName, activityInput).Get(ctx, nil); err != nil {
+			_ = setClusterStatus(ctx, input.ClusterID, pkgCluster.Error, "internal error")
+			return err
+		}
+	}
+
+	// create infrastructure
+	{
+		var err error
+
+		if input.Cloud == pkgCluster.Amazon {
+			wfInput := CreateEKSClusterWorkflowInput{
+				ClusterID: input.ClusterID,
+			}
+			err = workflow.ExecuteChildWorkflow(ctx, CreateEKSClusterWorkflowName, wfInput).Get(ctx, nil)
+		} else {
+			err = createClusterInfra(ctx, input.ClusterID, input.Cloud)
+		}
+
+		if err != nil {
+			_ = setClusterErrorStatus(ctx, input.ClusterID, err)
+			return err
+		}
+	}
+
+	if err := setClusterStatus(ctx, input.ClusterID, pkgCluster.Creating, "running posthooks"); err != nil {
+		return err
+	}
+
+	var postHooks []RunPostHooksWorkflowInputPostHook
+
+	// collect posthooks
+	{
+		activityInput := GetClusterPostHooksActivityInput{
+			ClusterID: input.ClusterID,
+			PostHooks: input.PostHooks,
+		}
+		if err := workflow.ExecuteActivity(ctx, GetClusterPostHooksActivityName, activityInput).Get(ctx, &postHooks); err != nil {
+			_ = setClusterStatus(ctx, input.ClusterID, pkgCluster.Error, "failed to get desired labels")
+			return err
+		}
+	}
+
+	// run posthooks
+	{
+		wfInput := RunPostHooksWorkflowInput{
+			ClusterID: input.ClusterID,
+			PostHooks: postHooks,
+		}
+		if err := workflow.ExecuteChildWorkflow(ctx, RunPostHooksWorkflowName, wfInput).Get(ctx, nil); err != nil {
+			return cadence.NewCustomError(PostHooksFailedErrorReason, err.Error())
+		}
+	}
+
+	return nil
+}
+
+const GenerateClusterSSHKeyActivityName = "generate-cluster-ssh-key"
+
+type GenerateClusterSSHKeyActivityInput struct {
+	ClusterID uint
+}
+
+type GenerateClusterSSHKeyActivity struct {
+	manager *Manager
+}
+
+func NewGenerateClusterSSHKeyActivity(manager *Manager) *GenerateClusterSSHKeyActivity {
+	return &GenerateClusterSSHKeyActivity{
+		manager: manager,
+	}
+}
+
+// Execute generates an SSH key for the cluster and stores it in Vault, unless the cluster already has one or does not need one.
+func (a *GenerateClusterSSHKeyActivity) Execute(ctx context.Context, input GenerateClusterSSHKeyActivityInput) error {
+	cluster, err := a.manager.GetClusterByIDOnly(ctx, input.ClusterID)
+	if err != nil {
+		return err
+	}
+
+	if len(cluster.GetSshSecretId()) != 0 || !cluster.RequiresSshPublicKey() {
+		return nil
+	}
+
+	sshKey, err := secret.GenerateSSHKeyPair()
+	if err != nil {
+		return emperror.Wrap(err, "failed to generate SSH key")
+	}
+
+	sshSecretId, err := secret.StoreSSHKeyPair(sshKey, cluster.GetOrganizationId(), cluster.GetID(), cluster.GetName(), cluster.GetUID())
+	if err != nil {
+		return emperror.Wrap(err, "failed to store SSH key")
+	}
+
+	return emperror.Wrap(cluster.SaveSshSecretId(sshSecretId), "failed to save SSH key secret ID")
+}
+
+// Names of the GKE and AKS cluster creation steps.
+const (
+	gkeCreateClusterStep                       = "gke-create-cluster"
+	aksCreateClusterStep                       = "aks-create-cluster"
+	aksAssignStorageAccountContributorRoleStep = "aks-assign-storage-account-contributor-role"
+)
+
+// clusterInfraCreationStepNames lists the cluster creation steps of the providers in the order of their execution.
+var clusterInfraCreationStepNames = map[string][]string{
+	pkgCluster.Google: {
+		gkeCreateClusterStep,
+	},
+	pkgCluster.Azure: {
+		aksCreateClusterStep,
+		aksAssignStorageAccountContributorRoleStep,
+	},
+}
+
+// clusterInfraStep is an idempotent step of creating the infrastructure of a cluster.
+type clusterInfraStep struct {
+	name   string
+	create func() error
+	// undo reverts the step, it is nil if there is nothing to revert
+	undo func() error
+}
+
+// clusterInfraCreator is implemented by clusters whose infrastructure is created by the cluster creation workflow step by step.
+type clusterInfraCreator interface {
+	CommonCluster
+
+	infraCreationSteps() []clusterInfraStep
+}
+
+// createClusterInfra creates the infrastructure of a GKE or AKS cluster step by step.
+// The steps are idempotent, so they are retried. When a step fails for good,
+// the failed step and the completed ones are reverted in reverse order.
+func createClusterInfra(ctx workflow.Context, clusterID uint, cloud string) error {
+	logger := workflow.GetLogger(ctx).Sugar().With("clusterID", clusterID)
+
+	steps, ok := clusterInfraCreationStepNames[cloud]
+	if !ok {
+		return errors.Errorf("cluster creation is not supported for cloud: %s", cloud)
+	}
+
+	retryPolicy := &cadence.RetryPolicy{
+		InitialInterval:    time.Second * 10,
+		BackoffCoefficient: 2,
+		ExpirationInterval: time.Hour * 2,
+		MaximumAttempts:    3,
+	}
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		ScheduleToStartTimeout: 10 * time.Minute,
+		StartToCloseTimeout:    time.Hour,
+		HeartbeatTimeout:       time.Minute,
+		WaitForCancellation:    true,
+		RetryPolicy:            retryPolicy,
+	})
+
+	var startedSteps []string
+
+	for _, step := range steps {
+		startedSteps = append(startedSteps, step)
+
+		activityInput := CreateClusterInfraStepActivityInput{
+			ClusterID: clusterID,
+			Step:      step,
+		}
+		if err := workflow.ExecuteActivity(ctx, CreateClusterInfraStepActivityName, activityInput).Get(ctx, nil); err != nil {
+			for i := len(startedSteps) - 1; i >= 0; i-- {
+				activityInput := UndoCreateClusterInfraStepActivityInput{
+					ClusterID: clusterID,
+					Step:      startedSteps[i],
+				}
+				if err := workflow.ExecuteActivity(ctx, UndoCreateClusterInfraStepActivityName, activityInput).Get(ctx, nil); err != nil {
+					logger.Errorw("reverting cluster creation step failed", "step", startedSteps[i], "error", err)
+				}
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getClusterInfraStep returns the cluster and its infrastructure creation step with the given name.
+func getClusterInfraStep(ctx context.Context, manager *Manager, clusterID uint, name string) (CommonCluster, clusterInfraStep, error) {
+	cluster, err := manager.GetClusterByIDOnly(ctx, clusterID)
+	if err != nil {
+		return nil, clusterInfraStep{}, err
+	}
+
+	creator, ok := cluster.(clusterInfraCreator)
+	if !ok {
+		return nil, clusterInfraStep{}, errors.Errorf("creating the infrastructure of cluster %d is not supported", clusterID)
+	}
+
+	step, err := findClusterInfraStep(creator.infraCreationSteps(), name)
+
+	return cluster, step, err
+}
+
+// findClusterInfraStep returns the step with the given name.
+func findClusterInfraStep(steps []clusterInfraStep, name string) (clusterInfraStep, error) {
+	for _, step := range steps {
+		if step.name == name {
+			return step, nil
+		}
+	}
+
+	return clusterInfraStep{}, errors.Errorf("unknown cluster creation step: %s", name)
+}
+
+const CreateClusterInfraStepActivityName = "create-cluster-infra-step"
+
+type CreateClusterInfraStepActivityInput struct {
+	ClusterID uint
+	Step      string
+}
+
+type CreateClusterInfraStepActivity struct {
+	manager *Manager
+}
+
+func NewCreateClusterInfraStepActivity(manager *Manager) *CreateClusterInfraStepActivity {
+	return &CreateClusterInfraStepActivity{
+		manager: manager,
+	}
+}
+
+// Execute runs a cluster infrastructure creation step and saves the changes of the cluster made by it.
+func (a *CreateClusterInfraStepActivity) Execute(ctx context.Context, input CreateClusterInfraStepActivityInput) error {
+	cluster, step, err := getClusterInfraStep(ctx, a.manager, input.ClusterID, input.Step)
+	if err != nil {
+		return err
+	}
+
+	info := activity.GetInfo(ctx)
+	a.manager.getLogger(ctx).WithField("attempt", info.Attempt).Infof("running cluster creation step %q", input.Step)
+
+	if err := heartbeatWhile(ctx, step.create); err != nil {
+		return emperror.Wrapf(err, "cluster creation step %q failed", input.Step)
+	}
+
+	return cluster.Persist()
+}
+
+const UndoCreateClusterInfraStepActivityName = "undo-create-cluster-infra-step"
+
+type UndoCreateClusterInfraStepActivityInput struct {
+	ClusterID uint
+	Step      string
+}
+
+type UndoCreateClusterInfraStepActivity struct {
+	manager *Manager
+}
+
+func NewUndoCreateClusterInfraStepActivity(manager *Manager) *UndoCreateClusterInfraStepActivity {
+	return &UndoCreateClusterInfraStepActivity{
+		manager: manager,
+	}
+}
+
+// Execute reverts a cluster infrastructure creation step.
+func (a *UndoCreateClusterInfraStepActivity) Execute(ctx context.Context, input UndoCreateClusterInfraStepActivityInput) error {
+	_, step, err := getClusterInfraStep(ctx, a.manager, input.ClusterID, input.Step)
+	if err != nil {
+		return err
+	}
+
+	if step.undo == nil {
+		return nil
+	}
+
+	return emperror.Wrapf(heartbeatWhile(ctx, step.undo), "failed to revert cluster creation step %q", input.Step)
+}
+
+const GetClusterPostHooksActivityName = "get-cluster-posthooks"
+
+type GetClusterPostHooksActivityInput struct {
+	ClusterID uint
+	PostHooks pkgCluster.PostHooks
+}
+
+type GetClusterPostHooksActivity struct {
+	manager *Manager
+}
+
+func NewGetClusterPostHooksActivity(manager *Manager) *GetClusterPostHooksActivity {
+	return &GetClusterPostHooksActivity{
+		manager: manager,
+	}
+}
+
+// Execute returns the posthooks of a newly created cluster including the node pool labels.
+func (a *GetClusterPostHooksActivity) Execute(ctx context.Context, input GetClusterPostHooksActivityInput) ([]RunPostHooksWorkflowInputPostHook, error) {
+	cluster, err := a.manager.GetClusterByIDOnly(ctx, input.ClusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	labelsMap, err := GetDesiredLabelsForCluster(ctx, cluster, nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	postHooks := input.PostHooks
+	if postHooks == nil {
+		postHooks = make(pkgCluster.PostHooks)
+	}
+
+	postHooks[pkgCluster.SetupNodePoolLabelsSet] = NodePoolLabelParam{
+		Labels: labelsMap,
+	}
+
+	return BuildWorkflowPostHookFunctions(postHooks, true), nil
+}
@@ -0,0 +1,56 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+)
+
+func TestClusterInfraCreationStepNames(t *testing.T) {
+	creators := map[string]clusterInfraCreator{
+		pkgCluster.Google: &GKECluster{},
+		pkgCluster.Azure:  &AKSCluster{},
+	}
+
+	for cloud, creator := range creators {
+		var names []string
+		for _, step := range creator.infraCreationSteps() {
+			names = append(names, step.name)
+		}
+
+		assert.Equal(t, clusterInfraCreationStepNames[cloud], names, cloud)
+	}
+}
+
+func TestFindClusterInfraStep(t *testing.T) {
+	steps := (&AKSCluster{}).infraCreationSteps()
+
+	step, err := findClusterInfraStep(steps, aksCreateClusterStep)
+	require.NoError(t, err)
+	assert.Equal(t, aksCreateClusterStep, step.name)
+	assert.NotNil(t, step.undo)
+
+	step, err = findClusterInfraStep(steps, aksAssignStorageAccountContributorRoleStep)
+	require.NoError(t, err)
+	assert.Nil(t, step.undo)
+
+	_, err = findClusterInfraStep(steps, "unknown")
+	assert.Error(t, err)
+}
@@ -0,0 +1,225 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"time"
+
+	"github.com/goph/emperror"
+	"go.uber.org/cadence"
+	"go.uber.org/cadence/workflow"
+
+	intCluster "github.com/banzaicloud/pipeline/internal/cluster"
+	"github.com/banzaicloud/pipeline/internal/cluster/statestore"
+	intClusterWorkflow "github.com/banzaicloud/pipeline/internal/cluster/workflow"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+)
+
+const DeleteClusterWorkflowName = "delete-cluster"
+
+type DeleteClusterWorkflowInput struct {
+	OrganizationID uint
+	ClusterID      uint
+	ClusterUID     string
+	ClusterName    string
+	Cloud          string
+	K8sSecretID    string
+	Forced         bool
+}
+
+// DeleteClusterWorkflow deletes the Kubernetes resources, the infrastructure and the Pipeline records of an EKS, GKE or AKS cluster.
+func DeleteClusterWorkflow(ctx workflow.Context, input DeleteClusterWorkflowInput) error {
+	logger := workflow.GetLogger(ctx).Sugar().With("clusterID", input.ClusterID)
+
+	retryPolicy := &cadence.RetryPolicy{
+		InitialInterval:    time.Second * 3,
+		BackoffCoefficient: 2,
+		ExpirationInterval: time.Minute * 5,
+		MaximumAttempts:    5,
+	}
+	ao := workflow.ActivityOptions{
+		ScheduleToStartTimeout: 10 * time.Minute,
+		StartToCloseTimeout:    10 * time.Minute,
+		WaitForCancellation:    true,
+		RetryPolicy:            retryPolicy,
+	}
+	cwo := workflow.ChildWorkflowOptions{
+		ExecutionStartToCloseTimeout: 2 * time.Hour,
+		TaskStartToCloseTimeout:      5 * time.Minute,
+	}
+	ctx = workflow.WithChildOptions(workflow.WithActivityOptions(ctx, ao), cwo)
+
+	// delete k8s resources
+	if input.K8sSecretID != "" {
+		wfInput := intClusterWorkflow.DeleteK8sResourcesWorkflowInput{
+			OrganizationID: input.OrganizationID,
+			ClusterName:    input.ClusterName,
+			K8sSecretID:    input.K8sSecretID,
+		}
+		if err := workflow.ExecuteChildWorkflow(ctx, intClusterWorkflow.DeleteK8sResourcesWorkflowName, wfInput).Get(ctx, nil); err != nil {
+			if input.Forced {
+				logger.Errorw("deleting k8s resources failed", "error", err)
+			} else {
+				_ = setClusterErrorStatus(ctx, input.ClusterID, err)
+				return err
+			}
+		}
+	}
+
+	// clean up DNS records
+	{
+		activityInput := intClusterWorkflow.DeleteClusterDNSRecordsActivityInput{
+			OrganizationID: input.OrganizationID,
+			ClusterUID:     input.ClusterUID,
+		}
+		if err := workflow.ExecuteActivity(ctx, intClusterWorkflow.DeleteClusterDNSRecordsActivityName, activityInput).Get(ctx, nil); err != nil {
+			logger.Errorw("deleting cluster DNS records failed", "error", err)
+		}
+	}
+
+	// delete infrastructure
+	{
+		var err error
+
+		if input.Cloud == pkgCluster.Amazon {
+			wfInput := DeleteEKSClusterWorkflowInput{
+				ClusterID: input.ClusterID,
+			}
+			err = workflow.ExecuteChildWorkflow(ctx, DeleteEKSClusterWorkflowName, wfInput).Get(ctx, nil)
+		} else {
+			infraCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+				ScheduleToStartTimeout: 10 * time.Minute,
+				StartToCloseTimeout:    time.Hour,
+				HeartbeatTimeout:       time.Minute,
+				WaitForCancellation:    true,
+				RetryPolicy:            retryPolicy,
+			})
+			activityInput := DeleteClusterInfraActivityInput{
+				ClusterID: input.ClusterID,
+			}
+			err = workflow.ExecuteActivity(infraCtx, DeleteClusterInfraActivityName, activityInput).Get(ctx, nil)
+		}
+
+		if err != nil {
+			if input.Forced {
+				logger.Errorw("deleting cluster from the provider failed", "error", err)
+			} else {
+				_ = setClusterErrorStatus(ctx, input.ClusterID, err)
+				return err
+			}
+		}
+	}
+
+	// delete unused secrets
+	{
+		activityInput := intClusterWorkflow.DeleteUnusedClusterSecretsActivityInput{
+			OrganizationID: input.OrganizationID,
+			ClusterUID:     input.ClusterUID,
+		}
+		if err := workflow.ExecuteActivity(ctx, intClusterWorkflow.DeleteUnusedClusterSecretsActivityName, activityInput).Get(ctx, nil); err != nil {
+			if input.Forced {
+				logger.Errorw("deleting unused cluster secrets failed", "error", err)
+			} else {
+				_ = setClusterErrorStatus(ctx, input.ClusterID, err)
+				return err
+			}
+		}
+	}
+
+	// delete cluster from data store
+	{
+		activityInput := DeleteClusterFromStoreActivityInput{
+			ClusterID: input.ClusterID,
+		}
+		if err := workflow.ExecuteActivity(ctx, DeleteClusterFromStoreActivityName, activityInput).Get(ctx, nil); err != nil {
+			_ = setClusterErrorStatus(ctx, input.ClusterID, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+const DeleteClusterInfraActivityName = "delete-cluster-infra"
+
+type DeleteClusterInfraActivityInput struct {
+	ClusterID uint
+}
+
+type DeleteClusterInfraActivity struct {
+	manager *Manager
+}
+
+func NewDeleteClusterInfraActivity(manager *Manager) *DeleteClusterInfraActivity {
+	return &DeleteClusterInfraActivity{
+		manager: manager,
+	}
+}
+
+// Execute deletes the cluster at the provider.
+func (a *DeleteClusterInfraActivity) Execute(ctx context.Context, input DeleteClusterInfraActivityInput) error {
+	cluster, err := a.manager.GetClusterByIDOnly(ctx, input.ClusterID)
+	if err != nil {
+		return err
+	}
+
+	return heartbeatWhile(ctx, cluster.DeleteCluster)
+}
+
+const DeleteClusterFromStoreActivityName = "delete-cluster-from-store"
+
+type DeleteClusterFromStoreActivityInput struct {
+	ClusterID uint
+}
+
+type DeleteClusterFromStoreActivity struct {
+	manager *Manager
+}
+
+func NewDeleteClusterFromStoreActivity(manager *Manager) *DeleteClusterFromStoreActivity {
+	return &DeleteClusterFromStoreActivity{
+		manager: manager,
+	}
+}
+
+// Execute deletes the secret installation records, the database records and the state store of the cluster.
+func (a *DeleteClusterFromStoreActivity) Execute(ctx context.Context, input DeleteClusterFromStoreActivityInput) error {
+	cluster, err := a.manager.GetClusterByIDOnly(ctx, input.ClusterID)
+	if intCluster.IsClusterNotFoundError(err) {
+		// the cluster has already been deleted by a previous attempt
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	logger := a.manager.getLogger(ctx).WithField("cluster", cluster.GetName())
+
+	if err := deleteSecretInstallations(cluster, logger); err != nil {
+		logger.Error(err)
+	}
+
+	if err := cluster.DeleteFromDatabase(); err != nil {
+		return emperror.Wrap(err, "failed to delete from the database")
+	}
+
+	logger.Info("cleaning cluster's statestore folder")
+	if err := statestore.CleanStateStore(cluster.GetName()); err != nil {
+		return emperror.Wrap(err, "cleaning cluster statestore failed")
+	}
+
+	return nil
+}
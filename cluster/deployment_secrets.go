@@ -0,0 +1,239 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+
+	"github.com/ghodss/yaml"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+
+	"github.com/banzaicloud/pipeline/helm"
+	"github.com/banzaicloud/pipeline/secret"
+)
+
+// secretReferenceRegexp matches the secret references of helm values:
+// {{ secretRef "name" }} installs a Pipeline secret into the namespace of the release and is replaced with its name.
+// Inline {{ secret "name" "key" }} references are matched only to be rejected:
+// their values would be stored in the release and returned by the deployment API.
+var secretReferenceRegexp = regexp.MustCompile(`\{\{\s*(secret|secretRef)\s+"([^"]*)"(?:\s+"([^"]*)")?\s*\}\}`)
+
+// ErrSecretReferenceDenied is returned when the user is not allowed to read a referenced secret.
+var ErrSecretReferenceDenied = errors.New("access to referenced secret denied")
+
+// SecretReferenceAuthorizer checks whether the referenced secret (identified by its ID) may be used.
+type SecretReferenceAuthorizer func(secretID string) error
+
+// HasSecretReference tells whether a value contains a secret reference.
+func HasSecretReference(value string) bool {
+	return secretReferenceRegexp.MatchString(value)
+}
+
+// SecretReferenceNames returns the names of the secrets referenced by a value.
+func SecretReferenceNames(value string) []string {
+	var names []string
+	seen := make(map[string]bool)
+
+	for _, match := range secretReferenceRegexp.FindAllStringSubmatch(value, -1) {
+		if name := match[2]; !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// ResolveDeploymentSecrets replaces the secret references of helm values with the names of the referenced Pipeline secrets
+// of the organization of the cluster, so that credentials are not part of deployment requests.
+// The secrets are installed into the namespace of the release unless dryRun is set.
+// Secrets with a forbidden tag (like the credentials of the cluster) cannot be referenced,
+// and every referenced secret is checked with authorize unless it is nil.
+func ResolveDeploymentSecrets(
+	cluster CommonCluster,
+	namespace string,
+	values []byte,
+	dryRun bool,
+	authorize SecretReferenceAuthorizer,
+) ([]byte, error) {
+	if !HasSecretReference(string(values)) {
+		return values, nil
+	}
+
+	if namespace == "" {
+		namespace = helm.DefaultNamespace
+	}
+
+	resolver := secretReferenceResolver{
+		checkSecret: func(name string) error {
+			secretItem, err := secret.Store.GetByName(cluster.GetOrganizationId(), name)
+			if err == secret.ErrSecretNotExists {
+				return errors.Errorf("secret %q not found", name)
+			} else if err != nil {
+				return emperror.WrapWith(err, "failed to get secret", "secret", name)
+			}
+
+			if err := secret.HasForbiddenTag(secretItem.Tags); err != nil {
+				return errors.Wrapf(err, "secret %q cannot be referenced", name)
+			}
+
+			if authorize != nil {
+				return authorize(secretItem.ID)
+			}
+
+			return nil
+		},
+		installSecret: func(name string) error {
+			if dryRun {
+				return nil
+			}
+
+			request := InstallSecretRequest{
+				SourceSecretName: name,
+				Namespace:        namespace,
+			}
+
+			_, err := InstallSecret(cluster, name, request)
+			if err == ErrKubernetesSecretAlreadyExists {
+				_, err = MergeSecret(cluster, name, request)
+			}
+
+			return emperror.WrapWith(err, "failed to install secret", "secret", name, "namespace", namespace)
+		},
+		installed: make(map[string]bool),
+	}
+
+	return resolver.resolveValues(values)
+}
+
+// secretReferenceResolver resolves the secret references of helm values.
+type secretReferenceResolver struct {
+	checkSecret   func(name string) error
+	installSecret func(name string) error
+
+	// installed records the secrets already checked and installed, every secret is handled once
+	installed map[string]bool
+}
+
+// resolveValues resolves the secret references of YAML encoded helm values.
+func (r *secretReferenceResolver) resolveValues(values []byte) ([]byte, error) {
+	jsonValues, err := yaml.YAMLToJSON(values)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse values")
+	}
+
+	// numbers are kept as they are, large integers would be formatted as floats otherwise
+	decoder := json.NewDecoder(bytes.NewReader(jsonValues))
+	decoder.UseNumber()
+
+	var parsedValues interface{}
+	if err := decoder.Decode(&parsedValues); err != nil {
+		return nil, errors.Wrap(err, "failed to parse values")
+	}
+
+	resolvedValues, err := r.resolve(parsedValues)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonValues, err = json.Marshal(resolvedValues)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal values")
+	}
+
+	return yaml.JSONToYAML(jsonValues)
+}
+
+// resolve returns a copy of a value with every secret reference of its strings resolved.
+func (r *secretReferenceResolver) resolve(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		resolved := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			resolvedItem, err := r.resolve(item)
+			if err != nil {
+				return nil, err
+			}
+
+			resolved[key] = resolvedItem
+		}
+
+		return resolved, nil
+	case []interface{}:
+		resolved := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			resolvedItem, err := r.resolve(item)
+			if err != nil {
+				return nil, err
+			}
+
+			resolved = append(resolved, resolvedItem)
+		}
+
+		return resolved, nil
+	case string:
+		return r.resolveString(v)
+	}
+
+	return value, nil
+}
+
+func (r *secretReferenceResolver) resolveString(value string) (string, error) {
+	var resolveErr error
+
+	resolved := secretReferenceRegexp.ReplaceAllStringFunc(value, func(reference string) string {
+		if resolveErr != nil {
+			return reference
+		}
+
+		match := secretReferenceRegexp.FindStringSubmatch(reference)
+		function, name, key := match[1], match[2], match[3]
+
+		if function == "secret" {
+			resolveErr = errors.Errorf("inline secret values are not supported, reference the secret with {{ secretRef %q }} instead", name)
+
+			return reference
+		}
+
+		if key != "" {
+			resolveErr = errors.Errorf("secret reference %s cannot have a key", reference)
+
+			return reference
+		}
+
+		if !r.installed[name] {
+			if err := r.checkSecret(name); err != nil {
+				resolveErr = err
+
+				return reference
+			}
+
+			if err := r.installSecret(name); err != nil {
+				resolveErr = err
+
+				return reference
+			}
+
+			r.installed[name] = true
+		}
+
+		return name
+	})
+
+	return resolved, resolveErr
+}
@@ -0,0 +1,121 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pkgSecret "github.com/banzaicloud/pipeline/pkg/secret"
+	"github.com/banzaicloud/pipeline/secret"
+)
+
+func newTestSecretReferenceResolver(installed *[]string) *secretReferenceResolver {
+	secrets := map[string][]string{
+		"my-db":      nil,
+		"kubeconfig": {pkgSecret.TagKubeConfig},
+		"denied":     nil,
+	}
+
+	return &secretReferenceResolver{
+		checkSecret: func(name string) error {
+			tags, ok := secrets[name]
+			if !ok {
+				return errors.Errorf("secret %q not found", name)
+			}
+
+			if name == "denied" {
+				return ErrSecretReferenceDenied
+			}
+
+			return secret.HasForbiddenTag(tags)
+		},
+		installSecret: func(name string) error {
+			*installed = append(*installed, name)
+
+			return nil
+		},
+		installed: make(map[string]bool),
+	}
+}
+
+func TestSecretReferenceResolver(t *testing.T) {
+	var installed []string
+	resolver := newTestSecretReferenceResolver(&installed)
+
+	values := []byte(`
+database:
+  existingSecret: '{{ secretRef "my-db" }}'
+  url: 'postgres://db:5432'
+extraEnv:
+- '{{secretRef "my-db"}}'
+maxSize: 9007199254740993
+`)
+
+	resolved, err := resolver.resolveValues(values)
+	require.NoError(t, err)
+
+	assert.Equal(t, `database:
+  existingSecret: my-db
+  url: postgres://db:5432
+extraEnv:
+- my-db
+maxSize: 9007199254740993
+`, string(resolved))
+	assert.Equal(t, []string{"my-db"}, installed)
+}
+
+func TestSecretReferenceResolver_Errors(t *testing.T) {
+	tests := map[string]string{
+		"inline value":     `password: '{{ secret "my-db" "password" }}'`,
+		"missing secret":   `secretName: '{{ secretRef "other" }}'`,
+		"forbidden secret": `secretName: '{{ secretRef "kubeconfig" }}'`,
+		"denied secret":    `secretName: '{{ secretRef "denied" }}'`,
+		"ref with key":     `secretName: '{{ secretRef "my-db" "password" }}'`,
+	}
+
+	for name, values := range tests {
+		values := values
+
+		t.Run(name, func(t *testing.T) {
+			var installed []string
+			resolver := newTestSecretReferenceResolver(&installed)
+
+			_, err := resolver.resolveValues([]byte(values))
+			assert.Error(t, err)
+			assert.Empty(t, installed)
+		})
+	}
+}
+
+func TestHasSecretReference(t *testing.T) {
+	assert.True(t, HasSecretReference(`password: '{{ secret "my-db" "password" }}'`))
+	assert.True(t, HasSecretReference(`existingSecret: '{{ secretRef "my-db" }}'`))
+	assert.False(t, HasSecretReference(`password: '{{ .Values.password }}'`))
+	assert.False(t, HasSecretReference(`password: secret`))
+}
+
+func TestSecretReferenceNames(t *testing.T) {
+	names := SecretReferenceNames(`
+existingSecret: '{{ secretRef "my-db" }}'
+password: '{{ secret "my-db" "password" }}'
+extraEnv: '{{secretRef "other"}}'
+`)
+
+	assert.Equal(t, []string{"my-db", "other"}, names)
+}
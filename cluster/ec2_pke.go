@@ -25,6 +25,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/Masterminds/semver"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudformation"
@@ -572,6 +573,89 @@ func (c *EC2ClusterPKE) DeletePKECluster(ctx context.Context, workflowClient cli
 	return nil
 }
 
+// UpgradePKECluster starts upgrading the cluster to a newer Kubernetes version in the background
+func (c *EC2ClusterPKE) UpgradePKECluster(ctx context.Context, kubernetesVersion string, workflowClient client.Client, externalBaseURL string) error {
+	if c.model.Cluster.Status != pkgCluster.Running {
+		return &invalidError{errors.Errorf("cluster is not running, current status: %s", c.model.Cluster.Status)}
+	}
+
+	if err := validateKubernetesUpgrade(c.model.Kubernetes.Version, kubernetesVersion); err != nil {
+		return &invalidError{err}
+	}
+
+	var nodePools []pkeworkflow.NodePool
+	for _, np := range c.GetNodePools() {
+		nodePools = append(nodePools, pkeworkflow.NodePool{
+			Name:              np.Name,
+			MinCount:          np.MinCount,
+			MaxCount:          np.MaxCount,
+			Count:             np.Count,
+			Autoscaling:       np.Autoscaling,
+			Master:            np.Master,
+			Worker:            np.Worker,
+			InstanceType:      np.InstanceType,
+			AvailabilityZones: np.AvailabilityZones,
+			ImageID:           np.ImageID,
+			SpotPrice:         np.SpotPrice,
+		})
+	}
+
+	input := pkeworkflow.UpgradeClusterWorkflowInput{
+		OrganizationID:      c.GetOrganizationId(),
+		ClusterID:           c.GetID(),
+		ClusterName:         c.GetName(),
+		SecretID:            c.GetSecretId(),
+		Region:              c.GetLocation(),
+		PipelineExternalURL: externalBaseURL,
+		KubernetesVersion:   kubernetesVersion,
+		NodePools:           nodePools,
+	}
+	workflowOptions := client.StartWorkflowOptions{
+		TaskList: "pipeline",
+		// leave enough time for pausing the upgrade
+		ExecutionStartToCloseTimeout: 72 * time.Hour,
+	}
+	exec, err := workflowClient.StartWorkflow(ctx, workflowOptions, pkeworkflow.UpgradeClusterWorkflowName, input)
+	if err != nil {
+		return emperror.Wrap(err, "failed to start upgrade workflow")
+	}
+
+	err = c.SetCurrentWorkflowID(exec.ID)
+	if err != nil {
+		return err
+	}
+
+	return c.SetStatus(pkgCluster.Updating, fmt.Sprintf("upgrading to Kubernetes %s", kubernetesVersion))
+}
+
+// validateKubernetesUpgrade checks that the cluster can be upgraded from the current version to the target one:
+// downgrades and skipping minor versions are not supported.
+func validateKubernetesUpgrade(currentVersion, targetVersion string) error {
+	if currentVersion == "" {
+		currentVersion = defaultPKEVersion
+	}
+
+	current, err := semver.NewVersion(currentVersion)
+	if err != nil {
+		return errors.Wrapf(err, "invalid current Kubernetes version %q", currentVersion)
+	}
+
+	target, err := semver.NewVersion(targetVersion)
+	if err != nil {
+		return errors.Wrapf(err, "invalid Kubernetes version %q", targetVersion)
+	}
+
+	if !target.GreaterThan(current) {
+		return errors.Errorf("Kubernetes version %s is not newer than the current version %s", target, current)
+	}
+
+	if target.Major() != current.Major() || target.Minor() > current.Minor()+1 {
+		return errors.Errorf("Kubernetes can only be upgraded by one minor version at a time (from %s)", current)
+	}
+
+	return nil
+}
+
 func (c *EC2ClusterPKE) DownloadK8sConfig() ([]byte, error) {
 	return nil, pkgError.ErrorFunctionShouldNotBeCalled
 }
@@ -924,6 +1008,18 @@ func (c *EC2ClusterPKE) GetKubernetesVersion() (string, error) {
 	return c.model.Kubernetes.Version, nil
 }
 
+// SetKubernetesVersion saves the Kubernetes version of the cluster
+func (c *EC2ClusterPKE) SetKubernetesVersion(version string) error {
+	c.model.Kubernetes.Version = version
+
+	err := c.db.Save(&c.model.Kubernetes).Error
+	if err != nil {
+		return emperror.WrapWith(err, "failed to save Kubernetes version", "version", version)
+	}
+
+	return nil
+}
+
 // GetNetworkCloudProvider return cloud provider specific network information.
 func (c *EC2ClusterPKE) GetNetworkCloudProvider() (cloudProvider, vpcID string, subnets []string, err error) {
 	cp := c.model.Network.CloudProvider
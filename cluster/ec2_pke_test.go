@@ -0,0 +1,51 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateKubernetesUpgrade(t *testing.T) {
+	tests := []struct {
+		name    string
+		current string
+		target  string
+		valid   bool
+	}{
+		{name: "patch upgrade", current: "1.13.3", target: "1.13.5", valid: true},
+		{name: "minor upgrade", current: "1.13.3", target: "1.14.0", valid: true},
+		{name: "default current version", current: "", target: "1.13.3", valid: true},
+		{name: "same version", current: "1.14.0", target: "1.14.0"},
+		{name: "downgrade", current: "1.14.0", target: "1.13.3"},
+		{name: "skipping a minor version", current: "1.12.2", target: "1.14.0"},
+		{name: "invalid version", current: "1.13.3", target: "latest"},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			err := validateKubernetesUpgrade(test.current, test.target)
+			if test.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
@@ -48,6 +48,7 @@ import (
 	"github.com/spf13/viper"
 	v1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api/v1"
@@ -191,13 +192,65 @@ func (c *EKSCluster) createAWSCredentialsFromSecret() (*credentials.Credentials,
 	return verify.CreateAWSCredentials(clusterSecret.Values), nil
 }
 
+// eksClusterStep is a named group of actions of an EKS cluster creation or deletion
+// which are executed (and reverted) together.
+type eksClusterStep struct {
+	name    string
+	actions []utils.Action
+}
+
+// Names of the EKS cluster creation steps in the order of their execution.
+const (
+	eksCreateVPCAndRolesStep    = "create-vpc-and-roles"
+	eksCreateUserAccessKeyStep  = "create-user-access-key"
+	eksUploadSSHKeyStep         = "upload-ssh-key"
+	eksCreateControlPlaneStep   = "create-control-plane"
+	eksCreateNodePoolStacksStep = "create-node-pool-stacks"
+)
+
+// Names of the EKS cluster deletion steps in the order of their execution.
+const (
+	eksWaitResourceDeletionStep      = "wait-resource-deletion"
+	eksDeleteNodePoolStacksStep      = "delete-node-pool-stacks"
+	eksDeleteControlPlaneStep        = "delete-control-plane"
+	eksDeleteSSHKeyStep              = "delete-ssh-key"
+	eksDeleteUserAccessKeyStep       = "delete-user-access-key"
+	eksDeleteUserAccessKeySecretStep = "delete-user-access-key-secret"
+	eksDeleteVPCAndRolesStep         = "delete-vpc-and-roles"
+)
+
 // CreateCluster creates an EKS cluster with cloudformation templates.
 func (c *EKSCluster) CreateCluster() error {
 	c.log.Info("Start creating EKS cluster")
 
+	creationContext, awsCred, err := c.newCreationContext()
+	if err != nil {
+		return err
+	}
+
+	steps, err := c.creationSteps(creationContext)
+	if err != nil {
+		return err
+	}
+
+	var actions []utils.Action
+	for _, step := range steps {
+		actions = append(actions, step.actions...)
+	}
+
+	_, err = utils.NewActionExecutor(c.log).ExecuteActions(actions, nil, false)
+	if err != nil {
+		return emperror.Wrap(err, "failed to create EKS cluster")
+	}
+
+	return c.finalizeCreation(creationContext, awsCred)
+}
+
+// newCreationContext returns a new EKS cluster creation context populated from the cluster model.
+func (c *EKSCluster) newCreationContext() (*action.EksClusterCreateUpdateContext, *credentials.Credentials, error) {
 	awsCred, err := c.createAWSCredentialsFromSecret()
 	if err != nil {
-		return emperror.Wrap(err, "failed to retrieve AWS credentials from secret")
+		return nil, nil, emperror.Wrap(err, "failed to retrieve AWS credentials from secret")
 	}
 
 	session, err := session.NewSession(&aws.Config{
@@ -205,11 +258,9 @@ func (c *EKSCluster) CreateCluster() error {
 		Credentials: awsCred,
 	})
 	if err != nil {
-		return emperror.Wrap(err, "failed to create AWS session")
+		return nil, nil, emperror.Wrap(err, "failed to create AWS session")
 	}
 
-	// role that controls access to resources for creating an EKS cluster
-	eksStackName := c.generateStackNameForCluster()
 	sshKeyName := c.generateSSHKeyNameForCluster()
 
 	c.modelCluster.RbacEnabled = true
@@ -217,7 +268,7 @@ func (c *EKSCluster) CreateCluster() error {
 	log.Infoln("Getting CloudFormation template for creating node pools for EKS cluster")
 	nodePoolTemplate, err := pkgEks.GetNodePoolTemplate()
 	if err != nil {
-		return emperror.Wrap(err, "failed to get CloudFormation template for node pools")
+		return nil, nil, emperror.Wrap(err, "failed to get CloudFormation template for node pools")
 	}
 
 	creationContext := action.NewEksClusterCreationContext(
@@ -227,11 +278,6 @@ func (c *EKSCluster) CreateCluster() error {
 		nodePoolTemplate,
 	)
 
-	sshSecret, err := c.getSshSecret(c)
-	if err != nil {
-		return emperror.Wrap(err, "failed to get ssh secret")
-	}
-
 	creationContext.VpcID = c.modelCluster.EKS.VpcId
 	creationContext.RouteTableID = c.modelCluster.EKS.RouteTableId
 	for _, subnet := range c.modelCluster.EKS.Subnets {
@@ -243,24 +289,63 @@ func (c *EKSCluster) CreateCluster() error {
 	}
 
 	creationContext.ScaleEnabled = c.GetScaleOptions() != nil && c.GetScaleOptions().Enabled
-	ASGWaitLoopCount := int(viper.GetDuration(config.EksASGFulfillmentTimeout).Seconds() / asgWaitLoopSleepSeconds)
-	headNodePoolName := viper.GetString(config.PipelineHeadNodePoolName)
 
-	actions := []utils.Action{
-		action.NewCreateVPCAndRolesAction(c.log, creationContext, eksStackName),
-		action.NewCreateClusterUserAccessKeyAction(c.log, creationContext),
-		action.NewPersistClusterUserAccessKeyAction(c.log, creationContext, c.GetOrganizationId()),
-		action.NewUploadSSHKeyAction(c.log, creationContext, sshSecret),
-		action.NewGenerateVPCConfigRequestAction(c.log, creationContext, eksStackName, c.GetOrganizationId()),
-		action.NewCreateEksClusterAction(c.log, creationContext, c.modelCluster.EKS.Version),
-		action.NewCreateUpdateNodePoolStackAction(c.log, true, creationContext, ASGWaitLoopCount, asgWaitLoopSleepSeconds*time.Second, headNodePoolName, c.modelCluster.EKS.NodePools...),
-	}
+	return creationContext, awsCred, nil
+}
 
-	_, err = utils.NewActionExecutor(c.log).ExecuteActions(actions, nil, false)
+// creationSteps returns the steps creating the EKS cluster infrastructure in the order of their execution.
+func (c *EKSCluster) creationSteps(creationContext *action.EksClusterCreateUpdateContext) ([]eksClusterStep, error) {
+	// role that controls access to resources for creating an EKS cluster
+	eksStackName := c.generateStackNameForCluster()
+
+	sshSecret, err := c.getSshSecret(c)
 	if err != nil {
-		return emperror.Wrap(err, "failed to create EKS cluster")
+		return nil, emperror.Wrap(err, "failed to get ssh secret")
 	}
 
+	ASGWaitLoopCount := int(viper.GetDuration(config.EksASGFulfillmentTimeout).Seconds() / asgWaitLoopSleepSeconds)
+	headNodePoolName := viper.GetString(config.PipelineHeadNodePoolName)
+
+	return []eksClusterStep{
+		{
+			name: eksCreateVPCAndRolesStep,
+			actions: []utils.Action{
+				action.NewCreateVPCAndRolesAction(c.log, creationContext, eksStackName),
+			},
+		},
+		{
+			name: eksCreateUserAccessKeyStep,
+			actions: []utils.Action{
+				action.NewCreateClusterUserAccessKeyAction(c.log, creationContext, c.GetOrganizationId()),
+				action.NewPersistClusterUserAccessKeyAction(c.log, creationContext, c.GetOrganizationId()),
+			},
+		},
+		{
+			name: eksUploadSSHKeyStep,
+			actions: []utils.Action{
+				action.NewUploadSSHKeyAction(c.log, creationContext, sshSecret),
+			},
+		},
+		{
+			name: eksCreateControlPlaneStep,
+			actions: []utils.Action{
+				action.NewGenerateVPCConfigRequestAction(c.log, creationContext, eksStackName, c.GetOrganizationId()),
+				action.NewCreateEksClusterAction(c.log, creationContext, c.modelCluster.EKS.Version),
+			},
+		},
+		{
+			name: eksCreateNodePoolStacksStep,
+			actions: []utils.Action{
+				action.NewCreateUpdateNodePoolStackAction(c.log, true, creationContext, ASGWaitLoopCount, asgWaitLoopSleepSeconds*time.Second, headNodePoolName, c.modelCluster.EKS.NodePools...),
+			},
+		},
+	}, nil
+}
+
+// finalizeCreation sets up the created EKS cluster for the nodes and the cluster user and persists the cluster.
+func (c *EKSCluster) finalizeCreation(creationContext *action.EksClusterCreateUpdateContext, awsCred *credentials.Credentials) error {
+	var err error
+
 	c.APIEndpoint = aws.StringValue(creationContext.APIEndpoint)
 	c.CertificateAuthorityData, err = base64.StdEncoding.DecodeString(aws.StringValue(creationContext.CertificateAuthorityData))
 
@@ -334,7 +419,7 @@ func (c *EKSCluster) CreateCluster() error {
 		},
 	}
 	_, err = kubeClient.CoreV1().ConfigMaps("kube-system").Create(&awsAuthConfigMap)
-	if err != nil {
+	if err != nil && !k8sapierrors.IsAlreadyExists(err) {
 		return emperror.WrapWith(err, "failed to create config map", "configmap", awsAuthConfigMap.Name)
 	}
 
@@ -389,44 +474,94 @@ func (c *EKSCluster) GetDistribution() string {
 func (c *EKSCluster) DeleteCluster() error {
 	c.log.Info("Start delete EKS cluster")
 
-	awsCred, err := c.createAWSCredentialsFromSecret()
+	deleteContext, err := c.newDeletionContext()
 	if err != nil {
 		return err
 	}
 
+	var actions []utils.Action
+	for _, step := range c.deletionSteps(deleteContext) {
+		actions = append(actions, step.actions...)
+	}
+
+	_, err = utils.NewActionExecutor(c.log).ExecuteActions(actions, nil, false)
+	if err != nil {
+		c.log.Errorln("EKS cluster delete error:", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// newDeletionContext returns a new EKS cluster deletion context.
+func (c *EKSCluster) newDeletionContext() (*action.EksClusterDeletionContext, error) {
+	awsCred, err := c.createAWSCredentialsFromSecret()
+	if err != nil {
+		return nil, err
+	}
+
 	session, err := session.NewSession(&aws.Config{
 		Region:      aws.String(c.modelCluster.Location),
 		Credentials: awsCred,
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	deleteContext := action.NewEksClusterDeleteContext(
+	return action.NewEksClusterDeleteContext(
 		session,
 		c.modelCluster.Name,
-	)
-	var actions []utils.Action
-	actions = append(actions, action.NewWaitResourceDeletionAction(c.log, deleteContext)) // wait for ELBs to be deleted
-
-	nodePoolStackNames := c.getNodepoolStackNamesToDelete(session)
-	deleteNodePoolsAction := action.NewDeleteStacksAction(c.log, deleteContext, nodePoolStackNames...)
-
-	actions = append(actions,
-		deleteNodePoolsAction,
-		action.NewDeleteClusterAction(c.log, deleteContext),
-		action.NewDeleteSSHKeyAction(c.log, deleteContext, c.generateSSHKeyNameForCluster()),
-		action.NewDeleteClusterUserAccessKeyAction(c.log, deleteContext),
-		action.NewDeleteClusterUserAccessKeySecretAction(c.log, deleteContext, c.GetOrganizationId()),
-		action.NewDeleteStacksAction(c.log, deleteContext, c.generateStackNameForCluster()),
-	)
-	_, err = utils.NewActionExecutor(c.log).ExecuteActions(actions, nil, false)
-	if err != nil {
-		c.log.Errorln("EKS cluster delete error:", err.Error())
-		return err
-	}
+	), nil
+}
 
-	return nil
+// deletionSteps returns the steps deleting the EKS cluster infrastructure in the order of their execution.
+func (c *EKSCluster) deletionSteps(deleteContext *action.EksClusterDeletionContext) []eksClusterStep {
+	nodePoolStackNames := c.getNodepoolStackNamesToDelete(deleteContext.Session)
+
+	return []eksClusterStep{
+		{
+			name: eksWaitResourceDeletionStep,
+			actions: []utils.Action{
+				action.NewWaitResourceDeletionAction(c.log, deleteContext), // wait for ELBs to be deleted
+			},
+		},
+		{
+			name: eksDeleteNodePoolStacksStep,
+			actions: []utils.Action{
+				action.NewDeleteStacksAction(c.log, deleteContext, nodePoolStackNames...),
+			},
+		},
+		{
+			name: eksDeleteControlPlaneStep,
+			actions: []utils.Action{
+				action.NewDeleteClusterAction(c.log, deleteContext),
+			},
+		},
+		{
+			name: eksDeleteSSHKeyStep,
+			actions: []utils.Action{
+				action.NewDeleteSSHKeyAction(c.log, deleteContext, c.generateSSHKeyNameForCluster()),
+			},
+		},
+		{
+			name: eksDeleteUserAccessKeyStep,
+			actions: []utils.Action{
+				action.NewDeleteClusterUserAccessKeyAction(c.log, deleteContext),
+			},
+		},
+		{
+			name: eksDeleteUserAccessKeySecretStep,
+			actions: []utils.Action{
+				action.NewDeleteClusterUserAccessKeySecretAction(c.log, deleteContext, c.GetOrganizationId()),
+			},
+		},
+		{
+			name: eksDeleteVPCAndRolesStep,
+			actions: []utils.Action{
+				action.NewDeleteStacksAction(c.log, deleteContext, c.generateStackNameForCluster()),
+			},
+		},
+	}
 }
 
 func (c *EKSCluster) getNodepoolStackNamesToDelete(sess *session.Session) []string {
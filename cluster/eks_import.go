@@ -0,0 +1,283 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+
+	"github.com/banzaicloud/pipeline/model"
+	"github.com/banzaicloud/pipeline/pkg/amazon"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	"github.com/banzaicloud/pipeline/pkg/cluster/eks/action"
+	pkgCloudformation "github.com/banzaicloud/pipeline/pkg/providers/amazon/cloudformation"
+	"github.com/banzaicloud/pipeline/secret"
+	"github.com/banzaicloud/pipeline/secret/verify"
+)
+
+// discoverEKSCluster looks up an existing EKS cluster and its node pools and returns an unsaved EKSCluster.
+// Only clusters created by Pipeline can be imported: node pools are read from Pipeline's CloudFormation stacks,
+// and updating or deleting the cluster relies on the same stacks.
+func discoverEKSCluster(importCtx ImportContext) (*EKSCluster, error) {
+	logger := log.WithField("cluster", importCtx.Name)
+
+	clusterSecret, err := getSecret(importCtx.OrganizationID, importCtx.SecretID)
+	if err != nil {
+		return nil, emperror.Wrap(err, "failed to get cluster secret")
+	}
+
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String(importCtx.Location),
+		Credentials: verify.CreateAWSCredentials(clusterSecret.Values),
+	})
+	if err != nil {
+		return nil, emperror.Wrap(err, "failed to create AWS session")
+	}
+
+	describeClusterOutput, err := eks.New(sess).DescribeCluster(&eks.DescribeClusterInput{
+		Name: aws.String(importCtx.Name),
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == eks.ErrCodeResourceNotFoundException {
+		return nil, &invalidError{errors.Errorf("EKS cluster %q not found in %s", importCtx.Name, importCtx.Location)}
+	} else if err != nil {
+		return nil, emperror.Wrap(err, "failed to describe EKS cluster")
+	}
+
+	eksCluster := describeClusterOutput.Cluster
+	if status := aws.StringValue(eksCluster.Status); status != eks.ClusterStatusActive {
+		return nil, &invalidError{errors.Errorf("EKS cluster is not active, current status: %s", status)}
+	}
+
+	cloudformationSrv := cloudformation.New(sess)
+
+	if err := checkEKSClusterStack(cloudformationSrv, importCtx.Name); err != nil {
+		return nil, err
+	}
+
+	nodePools, err := discoverEKSNodePools(cloudformationSrv, autoscaling.New(sess), importCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	var subnets []*model.EKSSubnetModel
+	if vpcConfig := eksCluster.ResourcesVpcConfig; vpcConfig != nil && len(vpcConfig.SubnetIds) > 0 {
+		subnets, err = discoverEKSSubnets(ec2.New(sess), vpcConfig.SubnetIds)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cluster := &EKSCluster{
+		modelCluster: &model.ClusterModel{
+			Name:           importCtx.Name,
+			Location:       importCtx.Location,
+			Cloud:          pkgCluster.Amazon,
+			Distribution:   pkgCluster.EKS,
+			OrganizationId: importCtx.OrganizationID,
+			SecretId:       importCtx.SecretID,
+			CreatedBy:      importCtx.UserID,
+			RbacEnabled:    true,
+			EKS: model.EKSClusterModel{
+				Version:   aws.StringValue(eksCluster.Version),
+				NodePools: nodePools,
+				Subnets:   subnets,
+			},
+		},
+		log: logger,
+	}
+
+	if eksCluster.ResourcesVpcConfig != nil {
+		cluster.modelCluster.EKS.VpcId = eksCluster.ResourcesVpcConfig.VpcId
+	}
+
+	return cluster, nil
+}
+
+// checkEKSClusterStack makes sure the cluster was created by Pipeline, ie. its CloudFormation stack exists.
+// Clusters without the stack are rejected, because updating and deleting them would fail.
+func checkEKSClusterStack(cloudformationSrv cloudformationiface.CloudFormationAPI, clusterName string) error {
+	stackName := "pipeline-eks-" + clusterName
+
+	_, err := cloudformationSrv.DescribeStacks(&cloudformation.DescribeStacksInput{StackName: aws.String(stackName)})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "ValidationError" {
+		return &invalidError{errors.Errorf(
+			"EKS cluster %q was not created by Pipeline (stack %s not found), only clusters created by Pipeline can be imported",
+			clusterName,
+			stackName,
+		)}
+	} else if err != nil {
+		return emperror.WrapWith(err, "failed to describe cluster stack", "stack", stackName)
+	}
+
+	return nil
+}
+
+// discoverEKSSubnets builds the subnet models of an EKS cluster.
+func discoverEKSSubnets(ec2Srv *ec2.EC2, subnetIDs []*string) ([]*model.EKSSubnetModel, error) {
+	describeSubnetsOutput, err := ec2Srv.DescribeSubnets(&ec2.DescribeSubnetsInput{SubnetIds: subnetIDs})
+	if err != nil {
+		return nil, emperror.Wrap(err, "failed to describe cluster subnets")
+	}
+
+	subnets := make([]*model.EKSSubnetModel, 0, len(describeSubnetsOutput.Subnets))
+	for _, subnet := range describeSubnetsOutput.Subnets {
+		subnets = append(subnets, &model.EKSSubnetModel{
+			SubnetId: subnet.SubnetId,
+			Cidr:     subnet.CidrBlock,
+		})
+	}
+
+	return subnets, nil
+}
+
+// discoverEKSNodePools builds the node pool models from the node pool stacks of an EKS cluster.
+func discoverEKSNodePools(cloudformationSrv *cloudformation.CloudFormation, autoscalingSrv *autoscaling.AutoScaling, importCtx ImportContext) ([]*model.AmazonNodePoolsModel, error) {
+	tags := map[string]string{
+		"pipeline-cluster-name": importCtx.Name,
+		"pipeline-stack-type":   "nodepool",
+	}
+	stackNames, err := pkgCloudformation.GetExistingTaggedStackNames(cloudformationSrv, tags)
+	if err != nil {
+		return nil, emperror.Wrap(err, "failed to list node pool stacks")
+	}
+
+	if len(stackNames) == 0 {
+		return nil, &invalidError{errors.New("no node pools found for EKS cluster")}
+	}
+
+	nodePools := make([]*model.AmazonNodePoolsModel, 0, len(stackNames))
+	for _, stackName := range stackNames {
+		describeStacksOutput, err := cloudformationSrv.DescribeStacks(&cloudformation.DescribeStacksInput{StackName: aws.String(stackName)})
+		if err != nil {
+			return nil, emperror.WrapWith(err, "failed to describe node pool stack", "stack", stackName)
+		}
+
+		nodePool, err := eksNodePoolFromStack(describeStacksOutput.Stacks[0], importCtx.Name, importCtx.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		asg, err := getAutoScalingGroup(cloudformationSrv, autoscalingSrv, stackName)
+		if err != nil {
+			return nil, emperror.WrapWith(err, "failed to get node pool auto scaling group", "stack", stackName)
+		}
+		nodePool.Count = int(aws.Int64Value(asg.DesiredCapacity))
+
+		nodePools = append(nodePools, nodePool)
+	}
+
+	return nodePools, nil
+}
+
+// eksNodePoolFromStack builds a node pool model from the parameters of a node pool stack.
+func eksNodePoolFromStack(stack *cloudformation.Stack, clusterName string, userID uint) (*model.AmazonNodePoolsModel, error) {
+	stackName := aws.StringValue(stack.StackName)
+
+	nodePool := &model.AmazonNodePoolsModel{
+		CreatedBy: userID,
+		Name:      strings.TrimPrefix(stackName, action.GenerateNodePoolStackName(clusterName, "")),
+	}
+
+	for _, param := range stack.Parameters {
+		value := aws.StringValue(param.ParameterValue)
+
+		var err error
+		switch aws.StringValue(param.ParameterKey) {
+		case "NodeGroupName":
+			nodePool.Name = value
+		case "NodeImageId":
+			nodePool.NodeImage = value
+		case "NodeInstanceType":
+			nodePool.NodeInstanceType = value
+		case "NodeSpotPrice":
+			nodePool.NodeSpotPrice = value
+		case "NodeAutoScalingGroupMinSize":
+			nodePool.NodeMinCount, err = strconv.Atoi(value)
+		case "NodeAutoScalingGroupMaxSize":
+			nodePool.NodeMaxCount, err = strconv.Atoi(value)
+		case "NodeAutoScalingInitSize":
+			nodePool.Count, err = strconv.Atoi(value)
+		case "ClusterAutoscalerEnabled":
+			nodePool.Autoscaling, err = strconv.ParseBool(value)
+		}
+		if err != nil {
+			return nil, emperror.WrapWith(err, "invalid node pool stack parameter", "stack", stackName, "parameter", aws.StringValue(param.ParameterKey))
+		}
+	}
+
+	return nodePool, nil
+}
+
+// ensureEKSClusterUserAccessKey makes sure the access key of the cluster's IAM user is stored in the organization.
+// The key is missing when the cluster was created by another Pipeline instance or organization.
+// It must be called on a persisted cluster: a created access key would be left behind otherwise.
+func ensureEKSClusterUserAccessKey(cluster *EKSCluster) error {
+	_, _, err := action.GetClusterUserAccessKeyIdAndSecretVault(cluster.GetOrganizationId(), cluster.GetName())
+	if err == nil {
+		return nil
+	}
+	if errors.Cause(err) != secret.ErrSecretNotExists {
+		return err
+	}
+
+	awsCred, err := cluster.createAWSCredentialsFromSecret()
+	if err != nil {
+		return emperror.Wrap(err, "failed to retrieve AWS credentials from secret")
+	}
+
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String(cluster.GetLocation()),
+		Credentials: awsCred,
+	})
+	if err != nil {
+		return emperror.Wrap(err, "failed to create AWS session")
+	}
+
+	creationContext := action.NewEksClusterCreationContext(sess, cluster.GetName(), "", "")
+
+	iamSvc := iam.New(sess)
+	accessKey, err := amazon.CreateUserAccessKey(iamSvc, aws.String(cluster.GetName()))
+	if err != nil {
+		return emperror.Wrap(err, "failed to create cluster user access key")
+	}
+
+	cluster.log.Info("created access key for the cluster user")
+
+	creationContext.ClusterUserAccessKeyId = aws.StringValue(accessKey.AccessKeyId)
+	creationContext.ClusterUserSecretAccessKey = aws.StringValue(accessKey.SecretAccessKey)
+
+	_, err = action.NewPersistClusterUserAccessKeyAction(cluster.log, creationContext, cluster.GetOrganizationId()).ExecuteAction(nil)
+	if err != nil {
+		if err := amazon.DeleteUserAccessKey(iamSvc, accessKey.UserName, accessKey.AccessKeyId); err != nil {
+			cluster.log.WithError(err).Error("failed to delete cluster user access key")
+		}
+
+		return emperror.Wrap(err, "failed to store cluster user access key")
+	}
+
+	return nil
+}
@@ -0,0 +1,476 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+	"go.uber.org/cadence"
+	"go.uber.org/cadence/activity"
+	"go.uber.org/cadence/workflow"
+
+	"github.com/banzaicloud/pipeline/pkg/cluster/eks/action"
+	"github.com/banzaicloud/pipeline/utils"
+)
+
+const CreateEKSClusterWorkflowName = "eks-create-cluster"
+
+// eksCreationStepNames lists the EKS cluster creation steps in the order of their execution.
+var eksCreationStepNames = []string{
+	eksCreateVPCAndRolesStep,
+	eksCreateUserAccessKeyStep,
+	eksUploadSSHKeyStep,
+	eksCreateControlPlaneStep,
+	eksCreateNodePoolStacksStep,
+}
+
+type CreateEKSClusterWorkflowInput struct {
+	ClusterID uint
+}
+
+// CreateEKSClusterWorkflow creates the infrastructure of an EKS cluster step by step.
+// Creation steps pick up the resources created by their previous attempts, so they are retried.
+// When a step finally fails, it is reverted along with the already completed steps in reverse order.
+func CreateEKSClusterWorkflow(ctx workflow.Context, input CreateEKSClusterWorkflowInput) error {
+	logger := workflow.GetLogger(ctx).Sugar().With("clusterID", input.ClusterID)
+
+	retryPolicy := &cadence.RetryPolicy{
+		InitialInterval:    time.Second * 10,
+		BackoffCoefficient: 2,
+		ExpirationInterval: time.Minute * 10,
+		MaximumAttempts:    5,
+	}
+
+	stepRetryPolicy := &cadence.RetryPolicy{
+		InitialInterval:    time.Second * 30,
+		BackoffCoefficient: 2,
+		ExpirationInterval: 2 * time.Hour,
+		MaximumAttempts:    3,
+	}
+
+	stepCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		ScheduleToStartTimeout: 10 * time.Minute,
+		StartToCloseTimeout:    time.Hour,
+		HeartbeatTimeout:       time.Minute,
+		WaitForCancellation:    true,
+		RetryPolicy:            stepRetryPolicy,
+	})
+	retryCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		ScheduleToStartTimeout: 10 * time.Minute,
+		StartToCloseTimeout:    15 * time.Minute,
+		HeartbeatTimeout:       time.Minute,
+		WaitForCancellation:    true,
+		RetryPolicy:            retryPolicy,
+	})
+
+	var state EKSCreationState
+	var completedSteps []string
+
+	compensate := func(err error) error {
+		for i := len(completedSteps) - 1; i >= 0; i-- {
+			activityInput := EKSUndoCreateClusterStepActivityInput{
+				ClusterID: input.ClusterID,
+				Step:      completedSteps[i],
+				State:     state,
+			}
+			if err := workflow.ExecuteActivity(retryCtx, EKSUndoCreateClusterStepActivityName, activityInput).Get(ctx, nil); err != nil {
+				logger.Errorw("reverting EKS cluster creation step failed", "step", completedSteps[i], "error", err)
+			}
+		}
+
+		return err
+	}
+
+	for _, step := range eksCreationStepNames {
+		activityInput := EKSCreateClusterStepActivityInput{
+			ClusterID: input.ClusterID,
+			Step:      step,
+			State:     state,
+		}
+		err := workflow.ExecuteActivity(stepCtx, EKSCreateClusterStepActivityName, activityInput).Get(ctx, &state)
+
+		// a failed step may have created some of its resources, so it is reverted as well
+		completedSteps = append(completedSteps, step)
+
+		if err != nil {
+			return compensate(err)
+		}
+	}
+
+	{
+		activityInput := EKSFinalizeClusterActivityInput{
+			ClusterID: input.ClusterID,
+			State:     state,
+		}
+		if err := workflow.ExecuteActivity(retryCtx, EKSFinalizeClusterActivityName, activityInput).Get(ctx, nil); err != nil {
+			return compensate(err)
+		}
+	}
+
+	return nil
+}
+
+const DeleteEKSClusterWorkflowName = "eks-delete-cluster"
+
+// eksDeletionStepNames lists the EKS cluster deletion steps in the order of their execution.
+var eksDeletionStepNames = []string{
+	eksWaitResourceDeletionStep,
+	eksDeleteNodePoolStacksStep,
+	eksDeleteControlPlaneStep,
+	eksDeleteSSHKeyStep,
+	eksDeleteUserAccessKeyStep,
+	eksDeleteUserAccessKeySecretStep,
+	eksDeleteVPCAndRolesStep,
+}
+
+type DeleteEKSClusterWorkflowInput struct {
+	ClusterID uint
+}
+
+// DeleteEKSClusterWorkflow deletes the infrastructure of an EKS cluster step by step.
+func DeleteEKSClusterWorkflow(ctx workflow.Context, input DeleteEKSClusterWorkflowInput) error {
+	retryPolicy := &cadence.RetryPolicy{
+		InitialInterval:    time.Second * 10,
+		BackoffCoefficient: 2,
+		ExpirationInterval: time.Minute * 30,
+		MaximumAttempts:    5,
+	}
+	ao := workflow.ActivityOptions{
+		ScheduleToStartTimeout: 10 * time.Minute,
+		StartToCloseTimeout:    time.Hour,
+		HeartbeatTimeout:       time.Minute,
+		WaitForCancellation:    true,
+		RetryPolicy:            retryPolicy,
+	}
+	ctx = workflow.WithActivityOptions(ctx, ao)
+
+	for _, step := range eksDeletionStepNames {
+		activityInput := EKSDeleteClusterStepActivityInput{
+			ClusterID: input.ClusterID,
+			Step:      step,
+		}
+		if err := workflow.ExecuteActivity(ctx, EKSDeleteClusterStepActivityName, activityInput).Get(ctx, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// EKSCreationState contains the serializable part of an EKS cluster creation context
+// passed between the creation steps.
+type EKSCreationState struct {
+	ClusterRoleArn           string
+	NodeInstanceRoleID       *string
+	NodeInstanceRoleArn      string
+	SecurityGroupID          *string
+	NodeSecurityGroupID      *string
+	SubnetBlocks             []*string
+	SubnetIDs                []*string
+	VpcID                    *string
+	VpcCidr                  *string
+	APIEndpoint              *string
+	CertificateAuthorityData *string
+	ClusterUserArn           string
+	ClusterUserAccessKeyId   string
+	RouteTableID             *string
+}
+
+// newEKSCreationState returns the serializable state of a creation context.
+// The cluster user secret access key is left out deliberately: it is persisted in the secret store.
+func newEKSCreationState(creationContext *action.EksClusterCreateUpdateContext) EKSCreationState {
+	return EKSCreationState{
+		ClusterRoleArn:           creationContext.ClusterRoleArn,
+		NodeInstanceRoleID:       creationContext.NodeInstanceRoleID,
+		NodeInstanceRoleArn:      creationContext.NodeInstanceRoleArn,
+		SecurityGroupID:          creationContext.SecurityGroupID,
+		NodeSecurityGroupID:      creationContext.NodeSecurityGroupID,
+		SubnetBlocks:             creationContext.SubnetBlocks,
+		SubnetIDs:                creationContext.SubnetIDs,
+		VpcID:                    creationContext.VpcID,
+		VpcCidr:                  creationContext.VpcCidr,
+		APIEndpoint:              creationContext.APIEndpoint,
+		CertificateAuthorityData: creationContext.CertificateAuthorityData,
+		ClusterUserArn:           creationContext.ClusterUserArn,
+		ClusterUserAccessKeyId:   creationContext.ClusterUserAccessKeyId,
+		RouteTableID:             creationContext.RouteTableID,
+	}
+}
+
+// apply restores the state in a creation context built from the cluster model.
+// Empty fields leave the values of the context intact.
+func (s EKSCreationState) apply(creationContext *action.EksClusterCreateUpdateContext) {
+	setString := func(dst *string, src string) {
+		if src != "" {
+			*dst = src
+		}
+	}
+	setStringPtr := func(dst **string, src *string) {
+		if aws.StringValue(src) != "" {
+			*dst = src
+		}
+	}
+	setStringSlice := func(dst *[]*string, src []*string) {
+		if len(src) > 0 {
+			*dst = src
+		}
+	}
+
+	setString(&creationContext.ClusterRoleArn, s.ClusterRoleArn)
+	setStringPtr(&creationContext.NodeInstanceRoleID, s.NodeInstanceRoleID)
+	setString(&creationContext.NodeInstanceRoleArn, s.NodeInstanceRoleArn)
+	setStringPtr(&creationContext.SecurityGroupID, s.SecurityGroupID)
+	setStringPtr(&creationContext.NodeSecurityGroupID, s.NodeSecurityGroupID)
+	setStringSlice(&creationContext.SubnetBlocks, s.SubnetBlocks)
+	setStringSlice(&creationContext.SubnetIDs, s.SubnetIDs)
+	setStringPtr(&creationContext.VpcID, s.VpcID)
+	setStringPtr(&creationContext.VpcCidr, s.VpcCidr)
+	setStringPtr(&creationContext.APIEndpoint, s.APIEndpoint)
+	setStringPtr(&creationContext.CertificateAuthorityData, s.CertificateAuthorityData)
+	setString(&creationContext.ClusterUserArn, s.ClusterUserArn)
+	setString(&creationContext.ClusterUserAccessKeyId, s.ClusterUserAccessKeyId)
+	setStringPtr(&creationContext.RouteTableID, s.RouteTableID)
+}
+
+// getEKSCluster returns the EKS cluster with the given ID.
+func getEKSCluster(ctx context.Context, manager *Manager, clusterID uint) (*EKSCluster, error) {
+	cluster, err := manager.GetClusterByIDOnly(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	eksCluster, ok := cluster.(*EKSCluster)
+	if !ok {
+		return nil, errors.Errorf("cluster %d is not an EKS cluster", clusterID)
+	}
+
+	return eksCluster, nil
+}
+
+// findEKSClusterStep returns the step with the given name.
+func findEKSClusterStep(steps []eksClusterStep, name string) (eksClusterStep, error) {
+	for _, step := range steps {
+		if step.name == name {
+			return step, nil
+		}
+	}
+
+	return eksClusterStep{}, errors.Errorf("unknown EKS cluster step: %s", name)
+}
+
+// restoreEKSCreationContext rebuilds the creation context of an EKS cluster in the state of the last completed step.
+func restoreEKSCreationContext(ctx context.Context, manager *Manager, clusterID uint, state EKSCreationState) (*EKSCluster, *action.EksClusterCreateUpdateContext, error) {
+	cluster, err := getEKSCluster(ctx, manager, clusterID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	creationContext, _, err := cluster.newCreationContext()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	state.apply(creationContext)
+
+	return cluster, creationContext, nil
+}
+
+const EKSCreateClusterStepActivityName = "eks-create-cluster-step"
+
+type EKSCreateClusterStepActivityInput struct {
+	ClusterID uint
+	Step      string
+	State     EKSCreationState
+}
+
+type EKSCreateClusterStepActivity struct {
+	manager *Manager
+}
+
+func NewEKSCreateClusterStepActivity(manager *Manager) *EKSCreateClusterStepActivity {
+	return &EKSCreateClusterStepActivity{
+		manager: manager,
+	}
+}
+
+// Execute runs the actions of a creation step. The actions look up the resources created by a previous attempt,
+// so a failed step is left as is to be retried or reverted by the workflow.
+func (a *EKSCreateClusterStepActivity) Execute(ctx context.Context, input EKSCreateClusterStepActivityInput) (EKSCreationState, error) {
+	cluster, creationContext, err := restoreEKSCreationContext(ctx, a.manager, input.ClusterID, input.State)
+	if err != nil {
+		return input.State, err
+	}
+
+	creationContext.Context = ctx
+
+	steps, err := cluster.creationSteps(creationContext)
+	if err != nil {
+		return input.State, err
+	}
+
+	step, err := findEKSClusterStep(steps, input.Step)
+	if err != nil {
+		return input.State, err
+	}
+
+	err = heartbeatWhile(ctx, func() error {
+		_, err := utils.NewActionExecutor(cluster.log).ExecuteActions(step.actions, nil, false)
+		return err
+	})
+	if err != nil {
+		return input.State, emperror.Wrapf(err, "EKS cluster creation step %q failed", input.Step)
+	}
+
+	return newEKSCreationState(creationContext), nil
+}
+
+const EKSUndoCreateClusterStepActivityName = "eks-undo-create-cluster-step"
+
+type EKSUndoCreateClusterStepActivityInput struct {
+	ClusterID uint
+	Step      string
+	State     EKSCreationState
+}
+
+type EKSUndoCreateClusterStepActivity struct {
+	manager *Manager
+}
+
+func NewEKSUndoCreateClusterStepActivity(manager *Manager) *EKSUndoCreateClusterStepActivity {
+	return &EKSUndoCreateClusterStepActivity{
+		manager: manager,
+	}
+}
+
+// Execute reverts the actions of a completed creation step in reverse order.
+func (a *EKSUndoCreateClusterStepActivity) Execute(ctx context.Context, input EKSUndoCreateClusterStepActivityInput) error {
+	cluster, creationContext, err := restoreEKSCreationContext(ctx, a.manager, input.ClusterID, input.State)
+	if err != nil {
+		return err
+	}
+
+	steps, err := cluster.creationSteps(creationContext)
+	if err != nil {
+		return err
+	}
+
+	step, err := findEKSClusterStep(steps, input.Step)
+	if err != nil {
+		return err
+	}
+
+	for i := len(step.actions) - 1; i >= 0; i-- {
+		revocableAction, ok := step.actions[i].(utils.RevocableAction)
+		if !ok {
+			continue
+		}
+
+		if err := revocableAction.UndoAction(); err != nil {
+			return emperror.Wrapf(err, "failed to revert %s", revocableAction.GetName())
+		}
+	}
+
+	return nil
+}
+
+const EKSFinalizeClusterActivityName = "eks-finalize-cluster"
+
+type EKSFinalizeClusterActivityInput struct {
+	ClusterID uint
+	State     EKSCreationState
+}
+
+type EKSFinalizeClusterActivity struct {
+	manager *Manager
+}
+
+func NewEKSFinalizeClusterActivity(manager *Manager) *EKSFinalizeClusterActivity {
+	return &EKSFinalizeClusterActivity{
+		manager: manager,
+	}
+}
+
+func (a *EKSFinalizeClusterActivity) Execute(ctx context.Context, input EKSFinalizeClusterActivityInput) error {
+	cluster, err := getEKSCluster(ctx, a.manager, input.ClusterID)
+	if err != nil {
+		return err
+	}
+
+	creationContext, awsCred, err := cluster.newCreationContext()
+	if err != nil {
+		return err
+	}
+
+	input.State.apply(creationContext)
+
+	clusterUserAccessKeyId, clusterUserSecretAccessKey, err := action.GetClusterUserAccessKeyIdAndSecretVault(cluster.GetOrganizationId(), cluster.GetName())
+	if err != nil {
+		return emperror.Wrap(err, "failed to retrieve EKS cluster user access key")
+	}
+
+	creationContext.ClusterUserAccessKeyId = clusterUserAccessKeyId
+	creationContext.ClusterUserSecretAccessKey = clusterUserSecretAccessKey
+
+	return heartbeatWhile(ctx, func() error {
+		return cluster.finalizeCreation(creationContext, awsCred)
+	})
+}
+
+const EKSDeleteClusterStepActivityName = "eks-delete-cluster-step"
+
+type EKSDeleteClusterStepActivityInput struct {
+	ClusterID uint
+	Step      string
+}
+
+type EKSDeleteClusterStepActivity struct {
+	manager *Manager
+}
+
+func NewEKSDeleteClusterStepActivity(manager *Manager) *EKSDeleteClusterStepActivity {
+	return &EKSDeleteClusterStepActivity{
+		manager: manager,
+	}
+}
+
+// Execute runs the actions of a deletion step. Deletion steps are idempotent, so they can be retried safely.
+func (a *EKSDeleteClusterStepActivity) Execute(ctx context.Context, input EKSDeleteClusterStepActivityInput) error {
+	cluster, err := getEKSCluster(ctx, a.manager, input.ClusterID)
+	if err != nil {
+		return err
+	}
+
+	deleteContext, err := cluster.newDeletionContext()
+	if err != nil {
+		return err
+	}
+
+	step, err := findEKSClusterStep(cluster.deletionSteps(deleteContext), input.Step)
+	if err != nil {
+		return err
+	}
+
+	info := activity.GetInfo(ctx)
+	cluster.log.WithField("attempt", info.Attempt).Infof("running EKS cluster deletion step %q", input.Step)
+
+	return heartbeatWhile(ctx, func() error {
+		_, err := utils.NewActionExecutor(cluster.log).ExecuteActions(step.actions, nil, false)
+		return emperror.Wrapf(err, "EKS cluster deletion step %q failed", input.Step)
+	})
+}
@@ -0,0 +1,74 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/banzaicloud/pipeline/pkg/cluster/eks/action"
+)
+
+func TestEKSCreationState(t *testing.T) {
+	t.Run("RoundTrip", func(t *testing.T) {
+		creationContext := action.NewEksClusterCreationContext(nil, "test", "ssh-key", "template")
+		creationContext.VpcID = aws.String("vpc-123")
+		creationContext.SubnetIDs = []*string{aws.String("subnet-1"), aws.String("subnet-2")}
+		creationContext.SecurityGroupID = aws.String("sg-123")
+		creationContext.ClusterUserArn = "arn:aws:iam::123:user/test"
+		creationContext.ClusterUserAccessKeyId = "AKIA"
+		creationContext.ClusterUserSecretAccessKey = "secret"
+
+		state := newEKSCreationState(creationContext)
+
+		restoredContext := action.NewEksClusterCreationContext(nil, "test", "ssh-key", "template")
+		state.apply(restoredContext)
+
+		assert.Equal(t, "vpc-123", aws.StringValue(restoredContext.VpcID))
+		assert.Equal(t, []string{"subnet-1", "subnet-2"}, aws.StringValueSlice(restoredContext.SubnetIDs))
+		assert.Equal(t, "sg-123", aws.StringValue(restoredContext.SecurityGroupID))
+		assert.Equal(t, "arn:aws:iam::123:user/test", restoredContext.ClusterUserArn)
+		assert.Equal(t, "AKIA", restoredContext.ClusterUserAccessKeyId)
+		assert.Empty(t, restoredContext.ClusterUserSecretAccessKey)
+	})
+
+	t.Run("EmptyStateKeepsModelValues", func(t *testing.T) {
+		creationContext := action.NewEksClusterCreationContext(nil, "test", "ssh-key", "template")
+		creationContext.VpcID = aws.String("vpc-123")
+		creationContext.SubnetBlocks = []*string{aws.String("192.168.64.0/20")}
+
+		EKSCreationState{}.apply(creationContext)
+
+		assert.Equal(t, "vpc-123", aws.StringValue(creationContext.VpcID))
+		assert.Equal(t, []string{"192.168.64.0/20"}, aws.StringValueSlice(creationContext.SubnetBlocks))
+	})
+}
+
+func TestFindEKSClusterStep(t *testing.T) {
+	steps := []eksClusterStep{
+		{name: eksCreateVPCAndRolesStep},
+		{name: eksUploadSSHKeyStep},
+	}
+
+	step, err := findEKSClusterStep(steps, eksUploadSSHKeyStep)
+	require.NoError(t, err)
+	assert.Equal(t, eksUploadSSHKeyStep, step.name)
+
+	_, err = findEKSClusterStep(steps, "unknown")
+	assert.Error(t, err)
+}
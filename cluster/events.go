@@ -25,6 +25,23 @@ type clusterEvents interface {
 	// this event is fired regardless of whether the cluster update succeeded or
 	// only partially succeeded (cluster is in warning state)
 	ClusterUpdated(clusterID uint)
+
+	// ClusterCreationFailed event is emitted when a cluster creation workflow fails
+	// before the posthooks could be started.
+	ClusterCreationFailed(orgID uint, clusterID uint, clusterName string, reason string)
+
+	// ClusterPostHooksFailed event is emitted when the posthooks of a newly created cluster fail.
+	ClusterPostHooksFailed(orgID uint, clusterID uint, clusterName string, reason string)
+
+	// ClusterUpdateFailed event is emitted when a cluster update workflow fails.
+	ClusterUpdateFailed(orgID uint, clusterID uint, clusterName string, reason string)
+
+	// ClusterDeletionFailed event is emitted when a cluster deletion workflow fails.
+	ClusterDeletionFailed(orgID uint, clusterID uint, clusterName string, reason string)
+
+	// ClusterTTLExpired event is emitted when a cluster reaches the end of its TTL
+	// right before it gets deleted.
+	ClusterTTLExpired(orgID uint, clusterID uint, clusterName string)
 }
 
 type nopClusterEvents struct {
@@ -43,6 +60,21 @@ func (*nopClusterEvents) ClusterDeleted(orgID uint, clusterName string) {
 func (*nopClusterEvents) ClusterUpdated(clusterID uint) {
 }
 
+func (*nopClusterEvents) ClusterCreationFailed(orgID uint, clusterID uint, clusterName string, reason string) {
+}
+
+func (*nopClusterEvents) ClusterPostHooksFailed(orgID uint, clusterID uint, clusterName string, reason string) {
+}
+
+func (*nopClusterEvents) ClusterUpdateFailed(orgID uint, clusterID uint, clusterName string, reason string) {
+}
+
+func (*nopClusterEvents) ClusterDeletionFailed(orgID uint, clusterID uint, clusterName string, reason string) {
+}
+
+func (*nopClusterEvents) ClusterTTLExpired(orgID uint, clusterID uint, clusterName string) {
+}
+
 type eventBus interface {
 	Publish(topic string, args ...interface{})
 }
@@ -55,6 +87,12 @@ const (
 	clusterCreatedTopic = "cluster_created"
 	clusterDeletedTopic = "cluster_deleted"
 	clusterUpdatedTopic = "cluster_updated"
+
+	clusterCreationFailedTopic  = "cluster_creation_failed"
+	clusterPostHooksFailedTopic = "cluster_posthooks_failed"
+	clusterUpdateFailedTopic    = "cluster_update_failed"
+	clusterDeletionFailedTopic  = "cluster_deletion_failed"
+	clusterTTLExpiredTopic      = "cluster_ttl_expired"
 )
 
 func NewClusterEvents(eb eventBus) *clusterEventBus {
@@ -74,3 +112,23 @@ func (c *clusterEventBus) ClusterDeleted(orgID uint, clusterName string) {
 func (c *clusterEventBus) ClusterUpdated(clusterID uint) {
 	c.eb.Publish(clusterUpdatedTopic, clusterID)
 }
+
+func (c *clusterEventBus) ClusterCreationFailed(orgID uint, clusterID uint, clusterName string, reason string) {
+	c.eb.Publish(clusterCreationFailedTopic, orgID, clusterID, clusterName, reason)
+}
+
+func (c *clusterEventBus) ClusterPostHooksFailed(orgID uint, clusterID uint, clusterName string, reason string) {
+	c.eb.Publish(clusterPostHooksFailedTopic, orgID, clusterID, clusterName, reason)
+}
+
+func (c *clusterEventBus) ClusterUpdateFailed(orgID uint, clusterID uint, clusterName string, reason string) {
+	c.eb.Publish(clusterUpdateFailedTopic, orgID, clusterID, clusterName, reason)
+}
+
+func (c *clusterEventBus) ClusterDeletionFailed(orgID uint, clusterID uint, clusterName string, reason string) {
+	c.eb.Publish(clusterDeletionFailedTopic, orgID, clusterID, clusterName, reason)
+}
+
+func (c *clusterEventBus) ClusterTTLExpired(orgID uint, clusterID uint, clusterName string) {
+	c.eb.Publish(clusterTTLExpiredTopic, orgID, clusterID, clusterName)
+}
@@ -59,8 +59,9 @@ import (
 )
 
 const (
-	statusRunning = "RUNNING"
-	statusDone    = "DONE"
+	statusRunning      = "RUNNING"
+	statusDone         = "DONE"
+	statusProvisioning = "PROVISIONING"
 )
 
 const (
@@ -312,7 +313,12 @@ func (c *GKECluster) CreateCluster() error {
 			return emperror.Wrap(err, "waiting for cluster creation to complete failed")
 		}
 	} else {
-		c.log.Info("Cluster %s already exists.", c.model.Cluster.Name)
+		c.log.Infof("Cluster %s already exists.", c.model.Cluster.Name)
+
+		// a previous attempt may have started the creation
+		if err := waitForClusterProvisioning(svc, cc, c.log); err != nil {
+			return emperror.Wrap(err, "waiting for cluster creation to complete failed")
+		}
 	}
 
 	gkeCluster, err := getClusterGoogle(svc, cc)
@@ -322,6 +328,10 @@ func (c *GKECluster) CreateCluster() error {
 		return errors.New(be.Message)
 	}
 
+	if gkeCluster.Status != statusRunning {
+		return errors.Errorf("cluster is in %s state: %s", gkeCluster.Status, gkeCluster.StatusMessage)
+	}
+
 	c.googleCluster = gkeCluster
 
 	c.updateCurrentVersions(gkeCluster)
@@ -433,6 +443,16 @@ func (c *GKECluster) DeleteCluster() error {
 		return pkgErrors.ErrorNilCluster
 	}
 
+	if err := c.deleteGoogleCluster(); err != nil {
+		return err
+	}
+	c.log.Info("Delete succeeded")
+	return nil
+
+}
+
+// deleteGoogleCluster deletes the cluster from GKE. Deleting a non-existent cluster is not an error.
+func (c *GKECluster) deleteGoogleCluster() error {
 	if c.model.ProjectId == "" {
 		// if there's no projectid saved with the cluster, take it from the secret
 		secretItem, err := c.GetSecretWithValidation()
@@ -453,9 +473,19 @@ func (c *GKECluster) DeleteCluster() error {
 		// TODO status code !?
 		return errors.New(be.Message)
 	}
-	c.log.Info("Delete succeeded")
+
 	return nil
+}
 
+// infraCreationSteps returns the steps of creating the cluster at GKE.
+func (c *GKECluster) infraCreationSteps() []clusterInfraStep {
+	return []clusterInfraStep{
+		{
+			name:   gkeCreateClusterStep,
+			create: c.CreateCluster,
+			undo:   c.deleteGoogleCluster,
+		},
+	}
 }
 
 // waitForResourcesDelete waits until the Kubernetes destroys all the resources which it had created
@@ -1988,6 +2018,23 @@ func waitForOperation(getter operationInfoer, operationName string, logger logru
 	return nil
 }
 
+// waitForClusterProvisioning waits until a cluster leaves the provisioning state.
+func waitForClusterProvisioning(svc *gke.Service, cc googleCluster, logger logrus.FieldLogger) error {
+	for {
+		cluster, err := getClusterGoogle(svc, cc)
+		if err != nil {
+			return emperror.Wrap(err, "retrieving cluster failed")
+		}
+
+		logger.Infof("Cluster status: %s", cluster.Status)
+		if cluster.Status != statusProvisioning {
+			return nil
+		}
+
+		time.Sleep(time.Second * 5)
+	}
+}
+
 // ListNodeNames returns node names to label them
 func (c *GKECluster) ListNodeNames() (nodeNames pkgCommon.NodeNames, err error) {
 	// nodes are labeled in create request
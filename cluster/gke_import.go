@@ -0,0 +1,118 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"net/http"
+
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+	gke "google.golang.org/api/container/v1"
+	"google.golang.org/api/googleapi"
+
+	pipConfig "github.com/banzaicloud/pipeline/config"
+	"github.com/banzaicloud/pipeline/internal/cluster"
+	"github.com/banzaicloud/pipeline/internal/providers/google"
+)
+
+// discoverGKECluster looks up an existing GKE cluster and its node pools and returns an unsaved GKECluster.
+func discoverGKECluster(importCtx ImportContext) (*GKECluster, error) {
+	repository, err := NewDBGKEClusterRepository(pipConfig.DB())
+	if err != nil {
+		return nil, emperror.Wrap(err, "failed to create GKE cluster repository")
+	}
+
+	c := &GKECluster{
+		repository: repository,
+		model: &google.GKEClusterModel{
+			Cluster: cluster.ClusterModel{
+				Name:           importCtx.Name,
+				Location:       importCtx.Location,
+				OrganizationID: importCtx.OrganizationID,
+				SecretID:       importCtx.SecretID,
+				Cloud:          google.Provider,
+				Distribution:   google.ClusterDistributionGKE,
+				CreatedBy:      importCtx.UserID,
+			},
+		},
+		log: log.WithField("cluster", importCtx.Name),
+	}
+
+	googleCluster, err := c.GetGoogleCluster()
+	if googleErr, ok := errors.Cause(err).(*googleapi.Error); ok && googleErr.Code == http.StatusNotFound {
+		return nil, &invalidError{errors.Errorf("GKE cluster %q not found in %s", importCtx.Name, importCtx.Location)}
+	} else if err != nil {
+		return nil, emperror.Wrap(err, "failed to get GKE cluster")
+	}
+
+	if googleCluster.Status != statusRunning {
+		return nil, &invalidError{errors.Errorf("GKE cluster is not running, current status: %s", googleCluster.Status)}
+	}
+
+	if len(googleCluster.NodePools) == 0 {
+		return nil, &invalidError{errors.New("no node pools found for GKE cluster")}
+	}
+
+	c.model.ProjectId, err = c.getProjectId()
+	if err != nil {
+		return nil, err
+	}
+
+	c.model.Region, err = c.getRegionByZone(c.model.ProjectId, importCtx.Location)
+	if err != nil {
+		return nil, err
+	}
+
+	c.model.MasterVersion = googleCluster.CurrentMasterVersion
+	c.model.NodeVersion = googleCluster.CurrentNodeVersion
+	c.model.Vpc = googleCluster.Network
+	c.model.Subnet = googleCluster.Subnetwork
+	c.model.NodePools = gkeNodePoolModelsFromCluster(googleCluster, importCtx.UserID)
+	c.model.Cluster.RbacEnabled = googleCluster.LegacyAbac == nil || !googleCluster.LegacyAbac.Enabled
+
+	return c, nil
+}
+
+// gkeNodePoolModelsFromCluster builds the node pool models of a GKE cluster.
+func gkeNodePoolModelsFromCluster(googleCluster *gke.Cluster, userID uint) []*google.GKENodePoolModel {
+	nodePools := make([]*google.GKENodePoolModel, 0, len(googleCluster.NodePools))
+	for _, np := range googleCluster.NodePools {
+		if np == nil {
+			continue
+		}
+
+		nodePool := &google.GKENodePoolModel{
+			CreatedBy: userID,
+			Name:      np.Name,
+			// Google API doesn't expose the current node count for a node pool
+			NodeCount: int(np.InitialNodeCount),
+		}
+
+		if np.Config != nil {
+			nodePool.NodeInstanceType = np.Config.MachineType
+			nodePool.Preemptible = np.Config.Preemptible
+		}
+
+		if np.Autoscaling != nil {
+			nodePool.Autoscaling = np.Autoscaling.Enabled
+			nodePool.NodeMinCount = int(np.Autoscaling.MinNodeCount)
+			nodePool.NodeMaxCount = int(np.Autoscaling.MaxNodeCount)
+		}
+
+		nodePools = append(nodePools, nodePool)
+	}
+
+	return nodePools
+}
@@ -41,6 +41,7 @@ type clusterRepository interface {
 	FindOneByID(organizationID uint, clusterID uint) (*model.ClusterModel, error)
 	FindOneByName(organizationID uint, clusterName string) (*model.ClusterModel, error)
 	FindBySecret(organizationID uint, secretID string) ([]*model.ClusterModel, error)
+	Touch(clusterID uint) error
 }
 
 type secretValidator interface {
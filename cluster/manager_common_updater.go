@@ -94,6 +94,32 @@ func (c *commonUpdater) Validate(ctx context.Context) error {
 
 // Prepare implements the clusterUpdater interface.
 func (c *commonUpdater) Prepare(ctx context.Context) (CommonCluster, error) {
+	if err := c.checkRequest(); err != nil {
+		return nil, err
+	}
+
+	if err := c.cluster.SetStatus(cluster.Updating, cluster.UpdatingMessage); err != nil {
+		return nil, err
+	}
+	return c.cluster, c.cluster.Persist()
+}
+
+// Plan implements the clusterUpdatePlanner interface.
+func (c *commonUpdater) Plan(ctx context.Context) (*UpdatePlan, error) {
+	if err := c.checkRequest(); err != nil {
+		return nil, err
+	}
+
+	status, err := c.cluster.GetStatus()
+	if err != nil {
+		return nil, emperror.Wrap(err, "could not get cluster status")
+	}
+
+	return newUpdatePlan(c.request, status, c.scaleOptionsChanged, c.ttlChanged, c.clusterPropertiesChanged), nil
+}
+
+// checkRequest adds the defaults to the update request, validates it and determines what has to be updated.
+func (c *commonUpdater) checkRequest() error {
 	c.cluster.AddDefaultsToUpdate(c.request)
 
 	c.scaleOptionsChanged = isDifferent(c.request.ScaleOptions, c.cluster.GetScaleOptions()) == nil
@@ -103,7 +129,7 @@ func (c *commonUpdater) Prepare(ctx context.Context) (CommonCluster, error) {
 	if err := c.cluster.CheckEqualityToUpdate(c.request); err != nil {
 		c.clusterPropertiesChanged = false
 		if !c.scaleOptionsChanged && !c.ttlChanged {
-			return nil, &commonUpdateValidationError{
+			return &commonUpdateValidationError{
 				msg:            err.Error(),
 				invalidRequest: true,
 			}
@@ -111,16 +137,13 @@ func (c *commonUpdater) Prepare(ctx context.Context) (CommonCluster, error) {
 	}
 
 	if err := c.request.Validate(); err != nil {
-		return nil, &commonUpdateValidationError{
+		return &commonUpdateValidationError{
 			msg:            err.Error(),
 			invalidRequest: true,
 		}
 	}
 
-	if err := c.cluster.SetStatus(cluster.Updating, cluster.UpdatingMessage); err != nil {
-		return nil, err
-	}
-	return c.cluster, c.cluster.Persist()
+	return nil
 }
 
 // Update implements the clusterUpdater interface.
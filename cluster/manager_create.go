@@ -23,8 +23,10 @@ import (
 	"github.com/goph/emperror"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"go.uber.org/cadence"
 	"go.uber.org/cadence/client"
 
+	"github.com/banzaicloud/pipeline/internal/cluster/metrics"
 	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
 	secretTypes "github.com/banzaicloud/pipeline/pkg/secret"
 	"github.com/banzaicloud/pipeline/secret"
@@ -67,34 +69,10 @@ func (m *Manager) CreateCluster(ctx context.Context, creationCtx CreationContext
 		"cluster":      creationCtx.Name,
 	})
 
-	if err := m.assertNotExists(creationCtx); err != nil {
+	if err := m.validateCreation(ctx, &creationCtx, creator, logger); err != nil {
 		return nil, err
 	}
 
-	logger.Debug("validating secret")
-	if len(creationCtx.SecretIDs) > 0 {
-		var err error
-		for _, secretID := range creationCtx.SecretIDs {
-			err = m.secrets.ValidateSecretType(creationCtx.OrganizationID, secretID, creationCtx.Provider)
-			if err == nil {
-				creationCtx.SecretID = secretID
-				break
-			}
-		}
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		if err := m.secrets.ValidateSecretType(creationCtx.OrganizationID, creationCtx.SecretID, creationCtx.Provider); err != nil {
-			return nil, err
-		}
-	}
-
-	logger.Debug("validating creation context")
-	if err := creator.Validate(ctx); err != nil {
-		return nil, errors.Wrap(&invalidError{err}, "validation failed")
-	}
-
 	logger.Debug("preparing cluster creation")
 	cluster, err := creator.Prepare(ctx)
 	if err != nil {
@@ -134,12 +112,27 @@ func (m *Manager) CreateCluster(ctx context.Context, creationCtx CreationContext
 
 	errorHandler := m.getClusterErrorHandler(ctx, cluster)
 
+	if isWorkflowCluster(cluster) {
+		if err := m.createClusterWithWorkflow(ctx, cluster, creationCtx.PostHooks, timer, errorHandler, logger); err != nil {
+			return nil, err
+		}
+
+		return cluster, nil
+	}
+
 	go func() {
 		defer emperror.HandleRecover(errorHandler.WithStatus(pkgCluster.Error, "internal error while creating cluster"))
+		defer m.startOperationHeartbeat(cluster)()
 
 		ctx = context.WithValue(ctx, ExternalBaseURLKey, creationCtx.ExternalBaseURL)
 		err := m.createCluster(ctx, cluster, creator, creationCtx.PostHooks, logger)
 		if err != nil {
+			if perr, ok := err.(postHooksError); ok {
+				m.events.ClusterPostHooksFailed(cluster.GetOrganizationId(), cluster.GetID(), cluster.GetName(), perr.Error())
+			} else {
+				m.events.ClusterCreationFailed(cluster.GetOrganizationId(), cluster.GetID(), cluster.GetName(), err.Error())
+			}
+
 			errorHandler.Handle(err)
 			return
 		}
@@ -149,6 +142,113 @@ func (m *Manager) CreateCluster(ctx context.Context, creationCtx CreationContext
 	return cluster, nil
 }
 
+// isWorkflowCluster returns true if the cluster is created and deleted by Cadence workflows.
+func isWorkflowCluster(cluster CommonCluster) bool {
+	switch cluster.(type) {
+	case *EKSCluster, *GKECluster, *AKSCluster:
+		return true
+	default:
+		return false
+	}
+}
+
+// createClusterWithWorkflow starts the cluster creation workflow and reports its result in the background.
+func (m *Manager) createClusterWithWorkflow(
+	ctx context.Context,
+	cluster CommonCluster,
+	postHooks pkgCluster.PostHooks,
+	timer metrics.DurationMetricTimer,
+	errorHandler clusterErrorHandler,
+	logger logrus.FieldLogger,
+) error {
+	input := CreateClusterWorkflowInput{
+		ClusterID: cluster.GetID(),
+		Cloud:     cluster.GetCloud(),
+		PostHooks: postHooks,
+	}
+
+	workflowOptions := client.StartWorkflowOptions{
+		ID:                           clusterWorkflowID(CreateClusterWorkflowName, cluster.GetID()),
+		TaskList:                     "pipeline",
+		ExecutionStartToCloseTimeout: 3 * time.Hour,
+	}
+
+	exec, err := m.workflowClient.ExecuteWorkflow(ctx, workflowOptions, CreateClusterWorkflowName, input)
+	if err != nil {
+		_ = cluster.SetStatus(pkgCluster.Error, "failed to start cluster creation")
+
+		return emperror.WrapWith(err, "failed to start workflow", "workflowName", CreateClusterWorkflowName)
+	}
+
+	logger = logger.WithFields(logrus.Fields{
+		"workflowName":  CreateClusterWorkflowName,
+		"workflowID":    exec.GetID(),
+		"workflowRunID": exec.GetRunID(),
+	})
+
+	logger.Info("workflow started successfully")
+
+	go func() {
+		defer emperror.HandleRecover(errorHandler.WithStatus(pkgCluster.Error, "internal error while creating cluster"))
+
+		err := exec.Get(context.Background(), nil)
+		if err != nil {
+			if cerr, ok := err.(*cadence.CustomError); ok && cerr.Reason() == PostHooksFailedErrorReason {
+				var message string
+				_ = cerr.Details(&message)
+
+				m.events.ClusterPostHooksFailed(cluster.GetOrganizationId(), cluster.GetID(), cluster.GetName(), message)
+			} else {
+				m.events.ClusterCreationFailed(cluster.GetOrganizationId(), cluster.GetID(), cluster.GetName(), err.Error())
+			}
+
+			errorHandler.Handle(err)
+			return
+		}
+
+		logger.Info("workflow finished successfully")
+
+		timer.RecordDuration()
+		m.events.ClusterCreated(cluster.GetID())
+	}()
+
+	return nil
+}
+
+// validateCreation runs every check of a cluster creation that does not change anything.
+// The first valid secret of the context is selected as the cluster's secret.
+func (m *Manager) validateCreation(ctx context.Context, creationCtx *CreationContext, creator clusterCreator, logger logrus.FieldLogger) error {
+	if err := m.assertNotExists(*creationCtx); err != nil {
+		return err
+	}
+
+	logger.Debug("validating secret")
+	if len(creationCtx.SecretIDs) > 0 {
+		var err error
+		for _, secretID := range creationCtx.SecretIDs {
+			err = m.secrets.ValidateSecretType(creationCtx.OrganizationID, secretID, creationCtx.Provider)
+			if err == nil {
+				creationCtx.SecretID = secretID
+				break
+			}
+		}
+		if err != nil {
+			return err
+		}
+	} else {
+		if err := m.secrets.ValidateSecretType(creationCtx.OrganizationID, creationCtx.SecretID, creationCtx.Provider); err != nil {
+			return err
+		}
+	}
+
+	logger.Debug("validating creation context")
+	if err := creator.Validate(ctx); err != nil {
+		return errors.Wrap(&invalidError{err}, "validation failed")
+	}
+
+	return nil
+}
+
 func (m *Manager) assertNotExists(ctx CreationContext) error {
 	exists, err := m.clusters.Exists(ctx.OrganizationID, ctx.Name)
 	if err != nil {
@@ -162,6 +262,16 @@ func (m *Manager) assertNotExists(ctx CreationContext) error {
 	return nil
 }
 
+// postHooksError is returned by createCluster when the cluster itself has been created, but running its posthooks failed.
+type postHooksError struct {
+	error
+}
+
+// Cause returns the underlying error.
+func (e postHooksError) Cause() error {
+	return e.error
+}
+
 // createCluster creates the cluster blockingly given an initially validated context
 // updates cluster status, but the caller logs the returned error
 func (m *Manager) createCluster(
@@ -244,7 +354,7 @@ func (m *Manager) createCluster(
 
 	err = exec.Get(ctx, nil)
 	if err != nil {
-		return emperror.Wrap(err, "running posthooks failed")
+		return postHooksError{emperror.Wrap(err, "running posthooks failed")}
 	}
 
 	logger.WithFields(logrus.Fields{
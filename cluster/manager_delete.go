@@ -16,10 +16,12 @@ package cluster
 
 import (
 	"context"
+	"time"
 
 	"github.com/banzaicloud/pipeline/helm"
 	intClusterDNS "github.com/banzaicloud/pipeline/internal/cluster/dns"
 	intClusterK8s "github.com/banzaicloud/pipeline/internal/cluster/kubernetes"
+	"github.com/banzaicloud/pipeline/internal/cluster/metrics"
 	"github.com/banzaicloud/pipeline/internal/cluster/statestore"
 	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
 	"github.com/banzaicloud/pipeline/secret"
@@ -38,15 +40,94 @@ func (m *Manager) DeleteCluster(ctx context.Context, cluster CommonCluster, forc
 
 	errorHandler := m.getClusterErrorHandler(ctx, cluster)
 
+	if isWorkflowCluster(cluster) {
+		return m.deleteClusterWithWorkflow(ctx, cluster, force, timer, errorHandler)
+	}
+
 	go func() {
 		defer emperror.HandleRecover(errorHandler.WithStatus(pkgCluster.Error, "internal error while deleting cluster"))
+		defer m.startOperationHeartbeat(cluster)()
 
 		err := m.deleteCluster(context.Background(), cluster, force)
 		if err != nil {
+			m.events.ClusterDeletionFailed(cluster.GetOrganizationId(), cluster.GetID(), cluster.GetName(), err.Error())
+
+			errorHandler.Handle(err)
+			return
+		}
+		timer.RecordDuration()
+	}()
+
+	return nil
+}
+
+// deleteClusterWithWorkflow starts the cluster deletion workflow and reports its result in the background.
+func (m *Manager) deleteClusterWithWorkflow(
+	ctx context.Context,
+	cluster CommonCluster,
+	force bool,
+	timer metrics.DurationMetricTimer,
+	errorHandler clusterErrorHandler,
+) error {
+	logger := m.getLogger(ctx).WithFields(logrus.Fields{
+		"organization": cluster.GetOrganizationId(),
+		"cluster":      cluster.GetName(),
+		"force":        force,
+	})
+
+	if err := cluster.SetStatus(pkgCluster.Deleting, pkgCluster.DeletingMessage); err != nil {
+		return emperror.WrapWith(err, "cluster status update failed", "cluster_id", cluster.GetID())
+	}
+
+	input := DeleteClusterWorkflowInput{
+		OrganizationID: cluster.GetOrganizationId(),
+		ClusterID:      cluster.GetID(),
+		ClusterUID:     cluster.GetUID(),
+		ClusterName:    cluster.GetName(),
+		Cloud:          cluster.GetCloud(),
+		K8sSecretID:    cluster.GetConfigSecretId(),
+		Forced:         force,
+	}
+
+	workflowOptions := client.StartWorkflowOptions{
+		ID:                           clusterWorkflowID(DeleteClusterWorkflowName, cluster.GetID()),
+		TaskList:                     "pipeline",
+		ExecutionStartToCloseTimeout: 3 * time.Hour,
+	}
+
+	exec, err := m.workflowClient.ExecuteWorkflow(ctx, workflowOptions, DeleteClusterWorkflowName, input)
+	if err != nil {
+		_ = cluster.SetStatus(pkgCluster.Error, "failed to start cluster deletion")
+
+		return emperror.WrapWith(err, "failed to start workflow", "workflowName", DeleteClusterWorkflowName)
+	}
+
+	logger = logger.WithFields(logrus.Fields{
+		"workflowName":  DeleteClusterWorkflowName,
+		"workflowID":    exec.GetID(),
+		"workflowRunID": exec.GetRunID(),
+	})
+
+	logger.Info("workflow started successfully")
+
+	go func() {
+		defer emperror.HandleRecover(errorHandler.WithStatus(pkgCluster.Error, "internal error while deleting cluster"))
+
+		err := exec.Get(context.Background(), nil)
+		if err != nil {
+			m.events.ClusterDeletionFailed(cluster.GetOrganizationId(), cluster.GetID(), cluster.GetName(), err.Error())
+
 			errorHandler.Handle(err)
 			return
 		}
+
+		logger.Info("cluster deleted successfully")
+
+		// delete from proxy from kubeProxyCache if any
+		m.DeleteKubeProxy(cluster)
+
 		timer.RecordDuration()
+		m.events.ClusterDeleted(cluster.GetOrganizationId(), cluster.GetName())
 	}()
 
 	return nil
@@ -111,6 +192,19 @@ func deleteUnusedSecrets(cluster CommonCluster, logger *logrus.Entry) error {
 	return nil
 }
 
+func deleteSecretInstallations(cluster CommonCluster, logger *logrus.Entry) error {
+	if secretInstallationStore == nil {
+		return nil
+	}
+
+	logger.Info("deleting secret installation records of the cluster")
+	if err := secretInstallationStore.DeleteByCluster(cluster.GetID()); err != nil {
+		return emperror.Wrap(err, "deleting secret installation records failed")
+	}
+
+	return nil
+}
+
 func (m *Manager) deleteCluster(ctx context.Context, cluster CommonCluster, force bool) error {
 	logger := m.getLogger(ctx).WithFields(logrus.Fields{
 		"organization": cluster.GetOrganizationId(),
@@ -233,6 +327,11 @@ func (m *Manager) deleteCluster(ctx context.Context, cluster CommonCluster, forc
 		logger.Error(err)
 	}
 
+	err = deleteSecretInstallations(cluster, logger)
+	if err != nil {
+		logger.Error(err)
+	}
+
 	// delete cluster from database
 	orgID := cluster.GetOrganizationId()
 	deleteName := cluster.GetName()
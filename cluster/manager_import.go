@@ -0,0 +1,185 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"time"
+
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"go.uber.org/cadence/client"
+
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+)
+
+// ImportContext represents the data necessary to import an existing cluster.
+type ImportContext struct {
+	OrganizationID uint
+	UserID         uint
+	Name           string
+	Provider       string
+	Location       string
+	SecretID       string
+
+	// ResourceGroup is the resource group of an AKS cluster.
+	ResourceGroup string
+}
+
+// ImportingMessage is the status message of a cluster while it is being imported.
+const ImportingMessage = "Cluster is being imported"
+
+// ImportPostHookFunctions are the posthook functions run after a cluster is imported.
+// Only the ones needed to manage the cluster are run: imported clusters keep their own addons.
+// nolint: gochecknoglobals
+var ImportPostHookFunctions = []string{
+	pkgCluster.StoreKubeConfig,
+	pkgCluster.SetupPrivileges,
+	pkgCluster.CreatePipelineNamespacePostHook,
+	pkgCluster.InstallHelmPostHook,
+	pkgCluster.InstallNodePoolLabelSetOperator,
+	pkgCluster.SetupNodePoolLabelsSet,
+}
+
+// ImportCluster discovers an existing cluster and its node pools in the cloud
+// and registers them as a managed cluster. The posthooks are run in the background.
+func (m *Manager) ImportCluster(ctx context.Context, importCtx ImportContext) (CommonCluster, error) {
+	logger := m.getLogger(ctx).WithFields(logrus.Fields{
+		"organization": importCtx.OrganizationID,
+		"user":         importCtx.UserID,
+		"cluster":      importCtx.Name,
+		"provider":     importCtx.Provider,
+	})
+
+	if err := m.assertNotExists(CreationContext{OrganizationID: importCtx.OrganizationID, Name: importCtx.Name}); err != nil {
+		return nil, err
+	}
+
+	logger.Debug("validating secret")
+	if err := m.secrets.ValidateSecretType(importCtx.OrganizationID, importCtx.SecretID, importCtx.Provider); err != nil {
+		return nil, err
+	}
+
+	logger.Info("discovering cluster")
+	cluster, err := discoverCluster(importCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cluster.Persist(); err != nil {
+		return nil, err
+	}
+
+	// credentials are created in the cloud only for persisted clusters, so that they are not left behind
+	if eksCluster, ok := cluster.(*EKSCluster); ok {
+		if err := ensureEKSClusterUserAccessKey(eksCluster); err != nil {
+			if err := cluster.DeleteFromDatabase(); err != nil {
+				logger.WithError(err).Error("failed to delete imported cluster")
+			}
+
+			return nil, err
+		}
+	}
+
+	if err := cluster.SetStatus(pkgCluster.Creating, ImportingMessage); err != nil {
+		return nil, err
+	}
+
+	m.clusterTotalMetric.WithLabelValues(cluster.GetCloud(), cluster.GetLocation()).Inc()
+
+	logger = logger.WithField("clusterId", cluster.GetID())
+	logger.Info("cluster imported, running posthooks")
+
+	errorHandler := m.getClusterErrorHandler(ctx, cluster)
+
+	go func() {
+		defer emperror.HandleRecover(errorHandler.WithStatus(pkgCluster.Error, "internal error while importing cluster"))
+		defer m.startOperationHeartbeat(cluster)()
+
+		if err := m.runImportPostHooks(ctx, cluster, logger); err != nil {
+			m.events.ClusterPostHooksFailed(cluster.GetOrganizationId(), cluster.GetID(), cluster.GetName(), err.Error())
+
+			errorHandler.Handle(err)
+			return
+		}
+
+		m.events.ClusterCreated(cluster.GetID())
+	}()
+
+	return cluster, nil
+}
+
+// discoverCluster returns an unsaved cluster built from the provider's view of an existing cluster.
+func discoverCluster(importCtx ImportContext) (CommonCluster, error) {
+	switch importCtx.Provider {
+	case pkgCluster.Amazon:
+		return discoverEKSCluster(importCtx)
+	case pkgCluster.Google:
+		return discoverGKECluster(importCtx)
+	case pkgCluster.Azure:
+		return discoverAKSCluster(importCtx)
+	default:
+		return nil, &invalidError{errors.Errorf("importing clusters is not supported for cloud: %s", importCtx.Provider)}
+	}
+}
+
+// runImportPostHooks runs the import posthooks in a workflow and waits for it to finish.
+// The workflow sets the cluster status to running when all posthooks succeeded.
+func (m *Manager) runImportPostHooks(ctx context.Context, cluster CommonCluster, logger logrus.FieldLogger) error {
+	labelsMap, err := GetDesiredLabelsForCluster(ctx, cluster, nil, false)
+	if err != nil {
+		_ = cluster.SetStatus(pkgCluster.Error, "failed to get desired labels")
+
+		return err
+	}
+
+	postHooks := make(pkgCluster.PostHooks, len(ImportPostHookFunctions))
+	for _, postHookName := range ImportPostHookFunctions {
+		postHooks[postHookName] = nil
+	}
+	postHooks[pkgCluster.SetupNodePoolLabelsSet] = NodePoolLabelParam{
+		Labels: labelsMap,
+	}
+
+	input := RunPostHooksWorkflowInput{
+		ClusterID: cluster.GetID(),
+		PostHooks: BuildWorkflowPostHookFunctions(postHooks, false),
+	}
+
+	workflowOptions := client.StartWorkflowOptions{
+		TaskList:                     "pipeline",
+		ExecutionStartToCloseTimeout: 1 * time.Hour,
+	}
+
+	exec, err := m.workflowClient.ExecuteWorkflow(ctx, workflowOptions, RunPostHooksWorkflowName, input)
+	if err != nil {
+		_ = cluster.SetStatus(pkgCluster.Error, "failed to run posthooks")
+
+		return emperror.WrapWith(err, "failed to start workflow", "workflowName", RunPostHooksWorkflowName)
+	}
+
+	logger.WithFields(logrus.Fields{
+		"workflowName":  RunPostHooksWorkflowName,
+		"workflowID":    exec.GetID(),
+		"workflowRunID": exec.GetRunID(),
+	}).Info("workflow started successfully")
+
+	if err := exec.Get(ctx, nil); err != nil {
+		return emperror.Wrap(err, "running posthooks failed")
+	}
+
+	return nil
+}
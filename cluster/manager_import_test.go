@@ -0,0 +1,203 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/containerservice/mgmt/2018-03-31/containerservice"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gke "google.golang.org/api/container/v1"
+
+	"github.com/banzaicloud/pipeline/internal/providers/google"
+	"github.com/banzaicloud/pipeline/model"
+)
+
+func TestDiscoverCluster_UnsupportedProvider(t *testing.T) {
+	_, err := discoverCluster(ImportContext{Name: "test", Provider: "dummy"})
+
+	require.Error(t, err)
+	assert.IsType(t, &invalidError{}, err)
+}
+
+func TestEKSNodePoolFromStack(t *testing.T) {
+	param := func(key, value string) *cloudformation.Parameter {
+		return &cloudformation.Parameter{ParameterKey: aws.String(key), ParameterValue: aws.String(value)}
+	}
+
+	t.Run("Parameters", func(t *testing.T) {
+		stack := &cloudformation.Stack{
+			StackName: aws.String("pipeline-eks-nodepool-test-pool1"),
+			Parameters: []*cloudformation.Parameter{
+				param("NodeGroupName", "pool1"),
+				param("NodeImageId", "ami-123"),
+				param("NodeInstanceType", "m4.xlarge"),
+				param("NodeSpotPrice", "0.2"),
+				param("NodeAutoScalingGroupMinSize", "1"),
+				param("NodeAutoScalingGroupMaxSize", "3"),
+				param("NodeAutoScalingInitSize", "2"),
+				param("ClusterAutoscalerEnabled", "true"),
+				param("KeyName", "pipeline-eks-ssh-test"),
+			},
+		}
+
+		nodePool, err := eksNodePoolFromStack(stack, "test", 1)
+		require.NoError(t, err)
+
+		assert.Equal(t, &model.AmazonNodePoolsModel{
+			CreatedBy:        1,
+			Name:             "pool1",
+			NodeSpotPrice:    "0.2",
+			Autoscaling:      true,
+			NodeMinCount:     1,
+			NodeMaxCount:     3,
+			Count:            2,
+			NodeImage:        "ami-123",
+			NodeInstanceType: "m4.xlarge",
+		}, nodePool)
+	})
+
+	t.Run("NameFromStackName", func(t *testing.T) {
+		stack := &cloudformation.Stack{StackName: aws.String("pipeline-eks-nodepool-test-pool2")}
+
+		nodePool, err := eksNodePoolFromStack(stack, "test", 1)
+		require.NoError(t, err)
+
+		assert.Equal(t, "pool2", nodePool.Name)
+	})
+
+	t.Run("InvalidParameter", func(t *testing.T) {
+		stack := &cloudformation.Stack{
+			StackName:  aws.String("pipeline-eks-nodepool-test-pool1"),
+			Parameters: []*cloudformation.Parameter{param("NodeAutoScalingGroupMinSize", "one")},
+		}
+
+		_, err := eksNodePoolFromStack(stack, "test", 1)
+		require.Error(t, err)
+	})
+}
+
+type describeStacksCloudFormation struct {
+	cloudformationiface.CloudFormationAPI
+
+	stacks map[string]*cloudformation.Stack
+}
+
+func (c *describeStacksCloudFormation) DescribeStacks(input *cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error) {
+	stack, ok := c.stacks[aws.StringValue(input.StackName)]
+	if !ok {
+		return nil, awserr.New("ValidationError", "Stack does not exist", nil)
+	}
+
+	return &cloudformation.DescribeStacksOutput{Stacks: []*cloudformation.Stack{stack}}, nil
+}
+
+func TestCheckEKSClusterStack(t *testing.T) {
+	cloudformationSrv := &describeStacksCloudFormation{
+		stacks: map[string]*cloudformation.Stack{
+			"pipeline-eks-test": {StackName: aws.String("pipeline-eks-test")},
+		},
+	}
+
+	t.Run("CreatedByPipeline", func(t *testing.T) {
+		assert.NoError(t, checkEKSClusterStack(cloudformationSrv, "test"))
+	})
+
+	t.Run("CreatedOutsidePipeline", func(t *testing.T) {
+		err := checkEKSClusterStack(cloudformationSrv, "other")
+
+		require.Error(t, err)
+		assert.IsType(t, &invalidError{}, err)
+		assert.Contains(t, err.Error(), "pipeline-eks-other")
+	})
+}
+
+func TestGKENodePoolModelsFromCluster(t *testing.T) {
+	googleCluster := &gke.Cluster{
+		NodePools: []*gke.NodePool{
+			{
+				Name:             "pool1",
+				InitialNodeCount: 2,
+				Config:           &gke.NodeConfig{MachineType: "n1-standard-2", Preemptible: true},
+				Autoscaling:      &gke.NodePoolAutoscaling{Enabled: true, MinNodeCount: 1, MaxNodeCount: 4},
+			},
+			{
+				Name:             "pool2",
+				InitialNodeCount: 1,
+			},
+		},
+	}
+
+	nodePools := gkeNodePoolModelsFromCluster(googleCluster, 1)
+
+	assert.Equal(t, []*google.GKENodePoolModel{
+		{
+			CreatedBy:        1,
+			Name:             "pool1",
+			Autoscaling:      true,
+			Preemptible:      true,
+			NodeMinCount:     1,
+			NodeMaxCount:     4,
+			NodeCount:        2,
+			NodeInstanceType: "n1-standard-2",
+		},
+		{
+			CreatedBy: 1,
+			Name:      "pool2",
+			NodeCount: 1,
+		},
+	}, nodePools)
+}
+
+func TestAKSNodePoolModelsFromCluster(t *testing.T) {
+	t.Run("AgentPoolProfiles", func(t *testing.T) {
+		managedCluster := &containerservice.ManagedCluster{
+			ManagedClusterProperties: &containerservice.ManagedClusterProperties{
+				AgentPoolProfiles: &[]containerservice.ManagedClusterAgentPoolProfile{
+					{
+						Name:         to.StringPtr("pool1"),
+						Count:        to.Int32Ptr(3),
+						VMSize:       containerservice.StandardD2V2,
+						VnetSubnetID: to.StringPtr("subnet-1"),
+					},
+				},
+			},
+		}
+
+		nodePools := aksNodePoolModelsFromCluster(managedCluster, 1)
+
+		assert.Equal(t, []*model.AKSNodePoolModel{
+			{
+				CreatedBy:        1,
+				Name:             "pool1",
+				NodeMinCount:     3,
+				NodeMaxCount:     3,
+				Count:            3,
+				NodeInstanceType: "Standard_D2_v2",
+				VNetSubnetID:     "subnet-1",
+			},
+		}, nodePools)
+	})
+
+	t.Run("NoProperties", func(t *testing.T) {
+		assert.Empty(t, aksNodePoolModelsFromCluster(&containerservice.ManagedCluster{}, 1))
+	})
+}
@@ -0,0 +1,315 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// NodePoolChange describes how a node pool is affected by a cluster create or update.
+type NodePoolChange string
+
+// Node pool changes
+const (
+	NodePoolAdded     NodePoolChange = "add"
+	NodePoolRemoved   NodePoolChange = "remove"
+	NodePoolResized   NodePoolChange = "resize"
+	NodePoolUpdated   NodePoolChange = "update"
+	NodePoolUnchanged NodePoolChange = "none"
+)
+
+// NodePoolPlan describes the planned change of a node pool.
+type NodePoolPlan struct {
+	Name          string         `json:"name"`
+	Change        NodePoolChange `json:"change"`
+	InstanceType  string         `json:"instanceType,omitempty"`
+	Count         int            `json:"count"`
+	PreviousCount int            `json:"previousCount,omitempty"`
+	MinCount      int            `json:"minCount,omitempty"`
+	MaxCount      int            `json:"maxCount,omitempty"`
+	Autoscaling   bool           `json:"autoscaling"`
+	Spot          bool           `json:"spot"`
+}
+
+// CreationPlan describes what a cluster creation would do.
+type CreationPlan struct {
+	Request      *pkgCluster.CreateClusterRequest `json:"request,omitempty"`
+	Cloud        string                           `json:"cloud"`
+	Distribution string                           `json:"distribution"`
+	Location     string                           `json:"location"`
+	NodePools    []NodePoolPlan                   `json:"nodePools"`
+	PostHooks    []string                         `json:"postHooks"`
+	Warnings     []string                         `json:"warnings,omitempty"`
+}
+
+// UpdatePlan describes what a cluster update would do.
+type UpdatePlan struct {
+	Request             *pkgCluster.UpdateClusterRequest `json:"request"`
+	NodePools           []NodePoolPlan                   `json:"nodePools"`
+	ScaleOptionsChanged bool                             `json:"scaleOptionsChanged"`
+	TTLChanged          bool                             `json:"ttlChanged"`
+	PostHooks           []string                         `json:"postHooks"`
+	Warnings            []string                         `json:"warnings,omitempty"`
+}
+
+type clusterUpdatePlanner interface {
+	// Validate validates the cluster update context.
+	Validate(ctx context.Context) error
+
+	// Plan returns what the update would do without changing anything.
+	Plan(ctx context.Context) (*UpdatePlan, error)
+}
+
+// PlanClusterCreation runs every validation of a cluster creation and returns what would be created.
+// Nothing is persisted and no provider calls are made other than the ones needed for validation.
+func (m *Manager) PlanClusterCreation(
+	ctx context.Context,
+	creationCtx CreationContext,
+	creator clusterCreator,
+	request *pkgCluster.CreateClusterRequest,
+	cluster CommonCluster,
+) (*CreationPlan, error) {
+	logger := m.getLogger(ctx).WithFields(logrus.Fields{
+		"organization": creationCtx.OrganizationID,
+		"user":         creationCtx.UserID,
+		"cluster":      creationCtx.Name,
+	})
+
+	if err := m.validateCreation(ctx, &creationCtx, creator, logger); err != nil {
+		return nil, err
+	}
+
+	logger.Debug("planning cluster creation")
+
+	return newCreationPlan(request, cluster, creationCtx.PostHooks), nil
+}
+
+// PlanClusterUpdate runs every validation of a cluster update and returns what would be changed.
+// Nothing is persisted and the cluster status is left untouched.
+func (m *Manager) PlanClusterUpdate(ctx context.Context, updateCtx UpdateContext, planner clusterUpdatePlanner) (*UpdatePlan, error) {
+	logger := m.getLogger(ctx).WithFields(logrus.Fields{
+		"organization": updateCtx.OrganizationID,
+		"user":         updateCtx.UserID,
+		"cluster":      updateCtx.ClusterID,
+	})
+
+	logger.Debug("validating update context")
+
+	if err := planner.Validate(ctx); err != nil {
+		return nil, errors.WithMessage(err, "cluster update validation failed")
+	}
+
+	logger.Debug("planning cluster update")
+
+	plan, err := planner.Plan(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "could not plan cluster update")
+	}
+
+	return plan, nil
+}
+
+// NewTypedCreationPlan describes the creation of a cluster requested with a typed (v2) create request.
+func NewTypedCreationPlan(cloud string, distribution string, location string, nodePools []NodePoolPlan, postHooks pkgCluster.PostHooks) *CreationPlan {
+	plan := &CreationPlan{
+		Cloud:        cloud,
+		Distribution: distribution,
+		Location:     location,
+		NodePools:    nodePools,
+	}
+
+	plan.planPostHooks(postHooks)
+
+	if len(plan.NodePools) == 0 {
+		plan.Warnings = append(plan.Warnings, "no node pools found in the request")
+	}
+
+	return plan
+}
+
+// planPostHooks lists the posthooks the creation workflow would run.
+func (plan *CreationPlan) planPostHooks(postHooks pkgCluster.PostHooks) {
+	// BuildWorkflowPostHookFunctions removes the base posthooks from the map, so it gets a copy
+	workflowPostHooks := make(pkgCluster.PostHooks, len(postHooks))
+	for name, param := range postHooks {
+		if _, ok := HookMap[name]; !ok {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("unknown posthook %q will be skipped", name))
+		}
+
+		workflowPostHooks[name] = param
+	}
+
+	for _, postHook := range BuildWorkflowPostHookFunctions(workflowPostHooks, true) {
+		plan.PostHooks = append(plan.PostHooks, postHook.Name)
+	}
+}
+
+func newCreationPlan(request *pkgCluster.CreateClusterRequest, cluster CommonCluster, postHooks pkgCluster.PostHooks) *CreationPlan {
+	plan := &CreationPlan{
+		Request:      request,
+		Cloud:        cluster.GetCloud(),
+		Distribution: cluster.GetDistribution(),
+		Location:     cluster.GetLocation(),
+		NodePools:    planNodePools(nil, getNodePoolsFromCreateRequest(request)),
+	}
+
+	plan.planPostHooks(postHooks)
+
+	if len(plan.NodePools) == 0 && plan.Cloud != pkgCluster.Kubernetes && plan.Cloud != pkgCluster.Dummy {
+		plan.Warnings = append(plan.Warnings, "no node pools found in the request")
+	}
+
+	if request.TtlMinutes > 0 {
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("the cluster will be deleted automatically %s after it has been started", time.Duration(request.TtlMinutes)*time.Minute))
+	}
+
+	sort.Strings(plan.Warnings)
+
+	return plan
+}
+
+func newUpdatePlan(
+	request *pkgCluster.UpdateClusterRequest,
+	status *pkgCluster.GetClusterStatusResponse,
+	scaleOptionsChanged bool,
+	ttlChanged bool,
+	clusterPropertiesChanged bool,
+) *UpdatePlan {
+	plan := &UpdatePlan{
+		Request:             request,
+		NodePools:           planNodePools(status.NodePools, nil),
+		ScaleOptionsChanged: scaleOptionsChanged,
+		TTLChanged:          ttlChanged,
+		PostHooks:           []string{},
+	}
+
+	if clusterPropertiesChanged {
+		if nodePools := getNodePoolsFromUpdateRequest(request); len(nodePools) > 0 {
+			plan.NodePools = planNodePools(status.NodePools, nodePools)
+		}
+	}
+
+	if clusterPropertiesChanged || scaleOptionsChanged || ttlChanged {
+		plan.PostHooks = []string{
+			pkgCluster.SetupNodePoolLabelsSet,
+			pkgCluster.InstallClusterAutoscalerPostHook,
+			pkgCluster.LabelNodesWithNodePoolName,
+		}
+	}
+
+	for _, nodePool := range plan.NodePools {
+		if nodePool.Change == NodePoolRemoved {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("node pool %q will be removed together with its %d node(s)", nodePool.Name, nodePool.PreviousCount))
+		}
+	}
+
+	if status.Status == pkgCluster.Warning {
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("cluster is in %s state: %s", pkgCluster.Warning, status.StatusMessage))
+	}
+
+	if ttlChanged && request.TtlMinutes > 0 {
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("the cluster will be deleted automatically %s after it has been started", time.Duration(request.TtlMinutes)*time.Minute))
+	}
+
+	return plan
+}
+
+// planNodePools compares the current node pools of a cluster with the desired ones.
+// When no desired node pools are given, every current node pool is left unchanged.
+func planNodePools(current map[string]*pkgCluster.NodePoolStatus, desired map[string]*pkgCluster.NodePoolStatus) []NodePoolPlan {
+	plans := make([]NodePoolPlan, 0, len(current)+len(desired))
+
+	for name, nodePool := range desired {
+		if nodePool == nil {
+			continue
+		}
+
+		plan := newNodePoolPlan(name, nodePool)
+
+		previous, ok := current[name]
+		switch {
+		case !ok || previous == nil:
+			plan.Change = NodePoolAdded
+
+		case previous.Count != nodePool.Count:
+			plan.Change = NodePoolResized
+			plan.PreviousCount = previous.Count
+
+		case (nodePool.InstanceType != "" && previous.InstanceType != nodePool.InstanceType) ||
+			previous.MinCount != nodePool.MinCount ||
+			previous.MaxCount != nodePool.MaxCount ||
+			previous.Autoscaling != nodePool.Autoscaling:
+			plan.Change = NodePoolUpdated
+			plan.PreviousCount = previous.Count
+
+		default:
+			plan.Change = NodePoolUnchanged
+			plan.PreviousCount = previous.Count
+		}
+
+		if plan.InstanceType == "" && ok && previous != nil {
+			plan.InstanceType = previous.InstanceType
+		}
+
+		plans = append(plans, plan)
+	}
+
+	for name, nodePool := range current {
+		if nodePool == nil {
+			continue
+		}
+
+		if _, ok := desired[name]; ok {
+			continue
+		}
+
+		plan := newNodePoolPlan(name, nodePool)
+		plan.PreviousCount = nodePool.Count
+
+		if len(desired) == 0 {
+			plan.Change = NodePoolUnchanged
+		} else {
+			plan.Change = NodePoolRemoved
+			plan.Count = 0
+		}
+
+		plans = append(plans, plan)
+	}
+
+	sort.Slice(plans, func(i, j int) bool {
+		return plans[i].Name < plans[j].Name
+	})
+
+	return plans
+}
+
+func newNodePoolPlan(name string, nodePool *pkgCluster.NodePoolStatus) NodePoolPlan {
+	return NodePoolPlan{
+		Name:         name,
+		InstanceType: nodePool.InstanceType,
+		Count:        nodePool.Count,
+		MinCount:     nodePool.MinCount,
+		MaxCount:     nodePool.MaxCount,
+		Autoscaling:  nodePool.Autoscaling,
+		Spot:         nodePool.Preemptible || (nodePool.SpotPrice != "" && nodePool.SpotPrice != "0"),
+	}
+}
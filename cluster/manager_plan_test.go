@@ -0,0 +1,127 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	"github.com/banzaicloud/pipeline/pkg/cluster/eks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanNodePools(t *testing.T) {
+	current := map[string]*pkgCluster.NodePoolStatus{
+		"kept":    {InstanceType: "m5.large", Count: 2, MinCount: 1, MaxCount: 3},
+		"resized": {InstanceType: "m5.large", Count: 2, MinCount: 1, MaxCount: 3},
+		"updated": {InstanceType: "m5.large", Count: 2, MinCount: 1, MaxCount: 3},
+		"removed": {InstanceType: "m5.large", Count: 4},
+	}
+	desired := map[string]*pkgCluster.NodePoolStatus{
+		"added":   {InstanceType: "c5.xlarge", Count: 1, SpotPrice: "0.2"},
+		"kept":    {InstanceType: "m5.large", Count: 2, MinCount: 1, MaxCount: 3},
+		"resized": {InstanceType: "m5.large", Count: 3, MinCount: 1, MaxCount: 3},
+		"updated": {Count: 2, MinCount: 1, MaxCount: 5},
+	}
+
+	plans := planNodePools(current, desired)
+
+	assert.Equal(t, []NodePoolPlan{
+		{Name: "added", Change: NodePoolAdded, InstanceType: "c5.xlarge", Count: 1, Spot: true},
+		{Name: "kept", Change: NodePoolUnchanged, InstanceType: "m5.large", Count: 2, PreviousCount: 2, MinCount: 1, MaxCount: 3},
+		{Name: "removed", Change: NodePoolRemoved, InstanceType: "m5.large", Count: 0, PreviousCount: 4},
+		{Name: "resized", Change: NodePoolResized, InstanceType: "m5.large", Count: 3, PreviousCount: 2, MinCount: 1, MaxCount: 3},
+		{Name: "updated", Change: NodePoolUpdated, InstanceType: "m5.large", Count: 2, PreviousCount: 2, MinCount: 1, MaxCount: 5},
+	}, plans)
+
+	// Without desired node pools nothing changes
+	for _, plan := range planNodePools(current, nil) {
+		assert.Equal(t, NodePoolUnchanged, plan.Change, plan.Name)
+	}
+}
+
+func TestNewCreationPlan(t *testing.T) {
+	request := &pkgCluster.CreateClusterRequest{
+		Name:     "test",
+		Location: "eu-west-1",
+		Cloud:    pkgCluster.Amazon,
+		SecretId: "secret",
+		PostHooks: pkgCluster.PostHooks{
+			pkgCluster.InstallLogging: nil,
+			"UnknownPostHook":         nil,
+		},
+		TtlMinutes: 60,
+		Properties: &pkgCluster.CreateClusterProperties{
+			CreateClusterEKS: &eks.CreateClusterEKS{
+				Version: "1.12",
+				Vpc:     &eks.ClusterVPC{},
+				NodePools: map[string]*eks.NodePool{
+					"pool1": {InstanceType: "m5.large", Count: 2, MinCount: 1, MaxCount: 3, SpotPrice: "0.1", Autoscaling: true},
+				},
+			},
+		},
+	}
+
+	cluster, err := CreateEKSClusterFromRequest(request, 1, 1)
+	require.NoError(t, err)
+
+	plan := newCreationPlan(request, cluster, request.PostHooks)
+
+	assert.Equal(t, pkgCluster.Amazon, plan.Cloud)
+	assert.Equal(t, pkgCluster.EKS, plan.Distribution)
+	assert.Equal(t, "eu-west-1", plan.Location)
+	assert.Equal(t, []NodePoolPlan{
+		{Name: "pool1", Change: NodePoolAdded, InstanceType: "m5.large", Count: 2, MinCount: 1, MaxCount: 3, Autoscaling: true, Spot: true},
+	}, plan.NodePools)
+
+	assert.Equal(t, BasePostHookFunctions, plan.PostHooks[:len(BasePostHookFunctions)])
+	assert.Equal(t, pkgCluster.InstallLogging, plan.PostHooks[len(BasePostHookFunctions)])
+	assert.Len(t, plan.PostHooks, len(BasePostHookFunctions)+1)
+
+	assert.Len(t, plan.Warnings, 2)
+	assert.Contains(t, plan.Warnings[1], "UnknownPostHook")
+
+	// The posthooks of the request are left intact
+	assert.Len(t, request.PostHooks, 2)
+}
+
+func TestNewTypedCreationPlan(t *testing.T) {
+	nodePools := []NodePoolPlan{
+		{Name: "master", Change: NodePoolAdded, InstanceType: "Standard_D2s_v3", Count: 1},
+	}
+	postHooks := pkgCluster.PostHooks{
+		pkgCluster.InstallLogging: nil,
+		"UnknownPostHook":         nil,
+	}
+
+	plan := NewTypedCreationPlan(pkgCluster.Azure, pkgCluster.PKE, "westeurope", nodePools, postHooks)
+
+	assert.Nil(t, plan.Request)
+	assert.Equal(t, pkgCluster.Azure, plan.Cloud)
+	assert.Equal(t, pkgCluster.PKE, plan.Distribution)
+	assert.Equal(t, "westeurope", plan.Location)
+	assert.Equal(t, nodePools, plan.NodePools)
+
+	assert.Equal(t, pkgCluster.InstallLogging, plan.PostHooks[len(plan.PostHooks)-1])
+	assert.Len(t, plan.PostHooks, len(BasePostHookFunctions)+1)
+
+	assert.Len(t, plan.Warnings, 1)
+	assert.Contains(t, plan.Warnings[0], "UnknownPostHook")
+
+	// Without node pools the plan warns about them
+	plan = NewTypedCreationPlan(pkgCluster.Azure, pkgCluster.PKE, "westeurope", nil, nil)
+	assert.Equal(t, []string{"no node pools found in the request"}, plan.Warnings)
+}
@@ -77,9 +77,12 @@ func (m *Manager) UpdateCluster(ctx context.Context, updateCtx UpdateContext, up
 
 	go func() {
 		defer emperror.HandleRecover(errorHandler.WithStatus(pkgCluster.Warning, "internal error while updating cluster"))
+		defer m.startOperationHeartbeat(cluster)()
 
 		err := m.updateCluster(ctx, updateCtx, cluster, updater)
 		if err != nil {
+			m.events.ClusterUpdateFailed(cluster.GetOrganizationId(), cluster.GetID(), cluster.GetName(), err.Error())
+
 			errorHandler.Handle(err)
 			return
 		}
@@ -0,0 +1,138 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+
+	intCluster "github.com/banzaicloud/pipeline/internal/cluster"
+	"github.com/banzaicloud/pipeline/internal/secret/installation"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	"github.com/banzaicloud/pipeline/pkg/k8sclient"
+	"github.com/banzaicloud/pipeline/secret"
+	"github.com/pkg/errors"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SecretInstallationStore records where secrets are installed.
+type SecretInstallationStore interface {
+	Save(organizationID uint, installation installation.Installation) error
+	List(organizationID uint, secretID string) ([]installation.Installation, error)
+	DeleteByCluster(clusterID uint) error
+}
+
+// secretInstallationStore records the secrets installed by the functions of this package.
+// nolint: gochecknoglobals
+var secretInstallationStore SecretInstallationStore
+
+// SetSecretInstallationStore sets the store used to record secret installations.
+// Secret installations are not recorded until it is set.
+func SetSecretInstallationStore(store SecretInstallationStore) {
+	secretInstallationStore = store
+}
+
+type clusterByIDGetter interface {
+	GetClusterByID(ctx context.Context, organizationID uint, clusterID uint) (CommonCluster, error)
+}
+
+// SecretInstallationClient updates and removes installed secrets in the clusters.
+type SecretInstallationClient struct {
+	clusters clusterByIDGetter
+}
+
+// NewSecretInstallationClient returns a new SecretInstallationClient instance.
+func NewSecretInstallationClient(clusters clusterByIDGetter) *SecretInstallationClient {
+	return &SecretInstallationClient{
+		clusters: clusters,
+	}
+}
+
+// UpdateSecret updates an installed secret with the current values of the source secret.
+func (c *SecretInstallationClient) UpdateSecret(ctx context.Context, organizationID uint, inst installation.Installation, secretItem *secret.SecretItemResponse) error {
+	kubeConfig, err := c.getK8sConfig(ctx, organizationID, inst.ClusterID)
+	if err != nil {
+		return err
+	}
+
+	req := InstallSecretRequest{
+		SourceSecretName: secretItem.Name,
+		Namespace:        inst.Namespace,
+	}
+
+	if len(inst.Spec) > 0 {
+		req.Spec = make(map[string]InstallSecretRequestSpecItem, len(inst.Spec))
+
+		for key, spec := range inst.Spec {
+			req.Spec[key] = InstallSecretRequestSpecItem{
+				Source:    spec.Source,
+				SourceMap: spec.SourceMap,
+				Value:     spec.Value,
+			}
+		}
+	}
+
+	_, err = MergeSecretByK8SConfig(kubeConfig, organizationID, inst.ClusterID, inst.Name, req)
+	if err == ErrKubernetesSecretNotFound {
+		return installation.ErrNotInstalled
+	}
+
+	return err
+}
+
+// DeleteSecret deletes an installed secret from the cluster.
+func (c *SecretInstallationClient) DeleteSecret(ctx context.Context, organizationID uint, inst installation.Installation) error {
+	kubeConfig, err := c.getK8sConfig(ctx, organizationID, inst.ClusterID)
+	if err != nil {
+		return err
+	}
+
+	client, err := k8sclient.NewClientFromKubeConfig(kubeConfig)
+	if err != nil {
+		return errors.Wrap(err, "failed to create kubernetes client")
+	}
+
+	err = client.CoreV1().Secrets(inst.Namespace).Delete(inst.Name, &metav1.DeleteOptions{})
+	if k8sapierrors.IsNotFound(err) {
+		return installation.ErrNotInstalled
+	}
+
+	return errors.Wrap(err, "failed to delete kubernetes secret")
+}
+
+func (c *SecretInstallationClient) getK8sConfig(ctx context.Context, organizationID uint, clusterID uint) ([]byte, error) {
+	cluster, err := c.clusters.GetClusterByID(ctx, organizationID, clusterID)
+	if intCluster.IsClusterNotFoundError(err) {
+		return nil, installation.ErrNotInstalled
+	} else if err != nil {
+		return nil, err
+	}
+
+	status, err := cluster.GetStatus()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster status")
+	}
+
+	if status.Status != pkgCluster.Running {
+		return nil, errors.Errorf("cluster is not running: %s", status.Status)
+	}
+
+	kubeConfig, err := cluster.GetK8sConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get k8s config")
+	}
+
+	return kubeConfig, nil
+}
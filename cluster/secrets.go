@@ -18,6 +18,7 @@ import (
 	stderrors "errors"
 
 	intSecret "github.com/banzaicloud/pipeline/internal/secret"
+	"github.com/banzaicloud/pipeline/internal/secret/installation"
 	"github.com/banzaicloud/pipeline/pkg/k8sclient"
 	"github.com/banzaicloud/pipeline/pkg/k8sutil"
 	secretTypes "github.com/banzaicloud/pipeline/pkg/secret"
@@ -39,11 +40,19 @@ func InstallSecrets(cc CommonCluster, query *secretTypes.ListSecretsQuery, names
 		return nil, err
 	}
 
-	return InstallSecretsByK8SConfig(kubeConfig, cc.GetOrganizationId(), query, namespace)
+	return InstallSecretsByK8SConfig(kubeConfig, cc.GetOrganizationId(), cc.GetID(), query, namespace)
 }
 
 // InstallSecretsByK8SConfig is the same as InstallSecrets but use this if you already have a K8S config at hand.
-func InstallSecretsByK8SConfig(kubeConfig []byte, orgID uint, query *secretTypes.ListSecretsQuery, namespace string) ([]secretTypes.K8SSourceMeta, error) {
+func InstallSecretsByK8SConfig(kubeConfig []byte, orgID uint, clusterID uint, query *secretTypes.ListSecretsQuery, namespace string) ([]secretTypes.K8SSourceMeta, error) {
+	secretSources, installations, err := installSecretsByK8SConfig(kubeConfig, orgID, query, namespace)
+	recordSecretInstallations(orgID, clusterID, installations)
+
+	return secretSources, err
+}
+
+// installSecretsByK8SConfig installs the secrets and returns the installations that succeeded, even in case of an error.
+func installSecretsByK8SConfig(kubeConfig []byte, orgID uint, query *secretTypes.ListSecretsQuery, namespace string) ([]secretTypes.K8SSourceMeta, []installation.Installation, error) {
 
 	// Values are always needed in this case
 	query.Values = true
@@ -51,22 +60,23 @@ func InstallSecretsByK8SConfig(kubeConfig []byte, orgID uint, query *secretTypes
 	clusterClient, err := k8sclient.NewClientFromKubeConfig(kubeConfig)
 	if err != nil {
 		log.Errorf("Error during building k8s client: %s", err.Error())
-		return nil, err
+		return nil, nil, err
 	}
 
 	secrets, err := secret.Store.List(orgID, query)
 	if err != nil {
 		log.Errorf("Error during listing secrets: %s", err.Error())
-		return nil, err
+		return nil, nil, err
 	}
 
 	clusterSecretList, err := clusterClient.CoreV1().Secrets(namespace).List(metav1.ListOptions{})
 	if err != nil {
 		log.Errorf("Error during getting k8s secrets of the cluster: %s", err.Error())
-		return nil, err
+		return nil, nil, err
 	}
 
 	var secretSources []secretTypes.K8SSourceMeta
+	var installations []installation.Installation
 
 	for _, s := range secrets {
 		k8sSecret := v1.Secret{
@@ -89,13 +99,13 @@ func InstallSecretsByK8SConfig(kubeConfig []byte, orgID uint, query *secretTypes
 		}
 		client, err := k8sclient.NewClientFromKubeConfig(kubeConfig)
 		if err != nil {
-			return nil, errors.WithMessage(err, "failed to create client for namespace creation")
+			return nil, installations, errors.WithMessage(err, "failed to create client for namespace creation")
 		}
 
 		err = k8sutil.EnsureNamespace(client, namespace)
 		if err != nil {
 			log.Errorf("Error checking namespace: %s", err.Error())
-			return nil, err
+			return nil, installations, err
 		}
 
 		kubeSecretRequest := intSecret.KubeSecretRequest{
@@ -106,7 +116,7 @@ func InstallSecretsByK8SConfig(kubeConfig []byte, orgID uint, query *secretTypes
 
 		newK8sSecret, err := intSecret.CreateKubeSecret(kubeSecretRequest)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to create k8s secret")
+			return nil, installations, errors.Wrap(err, "failed to create k8s secret")
 		}
 
 		if create {
@@ -122,13 +132,18 @@ func InstallSecretsByK8SConfig(kubeConfig []byte, orgID uint, query *secretTypes
 
 		if err != nil {
 			log.Errorf("Error during creating k8s secret: %s", err.Error())
-			return nil, err
+			return nil, installations, err
 		}
 
 		secretSources = append(secretSources, s.K8SSourceMeta())
+		installations = append(installations, installation.Installation{
+			SecretID:  s.ID,
+			Namespace: namespace,
+			Name:      s.Name,
+		})
 	}
 
-	return secretSources, nil
+	return secretSources, installations, nil
 }
 
 type InstallSecretRequest struct {
@@ -155,11 +170,11 @@ func InstallSecret(cc CommonCluster, secretName string, req InstallSecretRequest
 		return nil, errors.Wrap(err, "failed to get k8s config")
 	}
 
-	return InstallSecretByK8SConfig(kubeConfig, cc.GetOrganizationId(), secretName, req)
+	return InstallSecretByK8SConfig(kubeConfig, cc.GetOrganizationId(), cc.GetID(), secretName, req)
 }
 
 // InstallSecretByK8SConfig is the same as InstallSecret but use this if you already have a K8S config at hand.
-func InstallSecretByK8SConfig(kubeConfig []byte, orgID uint, secretName string, req InstallSecretRequest) (*secretTypes.K8SSourceMeta, error) {
+func InstallSecretByK8SConfig(kubeConfig []byte, orgID uint, clusterID uint, secretName string, req InstallSecretRequest) (*secretTypes.K8SSourceMeta, error) {
 	clusterClient, err := k8sclient.NewClientFromKubeConfig(kubeConfig)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create kubernetes client")
@@ -214,6 +229,10 @@ func InstallSecretByK8SConfig(kubeConfig []byte, orgID uint, secretName string,
 		return nil, emperror.Wrap(err, "failed to create secret")
 	}
 
+	if req.SourceSecretName != "" {
+		recordSecretInstallations(orgID, clusterID, []installation.Installation{newSecretInstallation(secretName, req)})
+	}
+
 	return &sourceMeta, nil
 }
 
@@ -225,11 +244,11 @@ func MergeSecret(cc CommonCluster, secretName string, req InstallSecretRequest)
 		return nil, errors.Wrap(err, "failed to get k8s config")
 	}
 
-	return MergeSecretByK8SConfig(kubeConfig, cc.GetOrganizationId(), secretName, req)
+	return MergeSecretByK8SConfig(kubeConfig, cc.GetOrganizationId(), cc.GetID(), secretName, req)
 }
 
 // MergeSecretByK8SConfig is the same as MergeSecret but use this if you already have a K8S config at hand.
-func MergeSecretByK8SConfig(kubeConfig []byte, orgID uint, secretName string, req InstallSecretRequest) (*secretTypes.K8SSourceMeta, error) {
+func MergeSecretByK8SConfig(kubeConfig []byte, orgID uint, clusterID uint, secretName string, req InstallSecretRequest) (*secretTypes.K8SSourceMeta, error) {
 	clusterClient, err := k8sclient.NewClientFromKubeConfig(kubeConfig)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create kubernetes client")
@@ -295,5 +314,54 @@ func MergeSecretByK8SConfig(kubeConfig []byte, orgID uint, secretName string, re
 		return nil, emperror.Wrap(err, "failed to update secret")
 	}
 
+	if req.SourceSecretName != "" {
+		recordSecretInstallations(orgID, clusterID, []installation.Installation{newSecretInstallation(secretName, req)})
+	}
+
 	return &sourceMeta, nil
 }
+
+func newSecretInstallation(secretName string, req InstallSecretRequest) installation.Installation {
+	inst := installation.Installation{
+		SecretID:  secret.GenerateSecretIDFromName(req.SourceSecretName),
+		Namespace: req.Namespace,
+		Name:      secretName,
+	}
+
+	if len(req.Spec) > 0 {
+		inst.Spec = make(map[string]installation.SpecItem, len(req.Spec))
+
+		for key, spec := range req.Spec {
+			inst.Spec[key] = installation.SpecItem{
+				Source:    spec.Source,
+				SourceMap: spec.SourceMap,
+				Value:     spec.Value,
+			}
+		}
+	}
+
+	return inst
+}
+
+// recordSecretInstallations records where secrets were installed so that later changes can be synced to the cluster.
+// The secrets are already installed at this point, so failures are only reported.
+func recordSecretInstallations(orgID uint, clusterID uint, installations []installation.Installation) {
+	if secretInstallationStore == nil {
+		return
+	}
+
+	for _, inst := range installations {
+		inst.ClusterID = clusterID
+
+		if err := secretInstallationStore.Save(orgID, inst); err != nil {
+			errorHandler.Handle(emperror.With(
+				err,
+				"organization", orgID,
+				"cluster", clusterID,
+				"secret", inst.SecretID,
+				"namespace", inst.Namespace,
+				"name", inst.Name,
+			))
+		}
+	}
+}
@@ -0,0 +1,273 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/banzaicloud/pipeline/internal/clustersleep"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	"github.com/goph/emperror"
+	"github.com/sirupsen/logrus"
+)
+
+type sleepError struct {
+	msg string
+
+	invalidRequest     bool
+	preconditionFailed bool
+}
+
+func (e *sleepError) Error() string {
+	return e.msg
+}
+
+func (e *sleepError) IsInvalid() bool {
+	return e.invalidRequest
+}
+
+func (e *sleepError) PreconditionFailed() bool {
+	return e.preconditionFailed
+}
+
+// CanSleep returns true if the node pools of the cluster can be scaled down through the UpdateNodePools path.
+// ACK and OKE clusters accept node pool updates without scaling anything, so they cannot sleep.
+func CanSleep(cluster CommonCluster) bool {
+	switch cluster.(type) {
+	case *EKSCluster, *GKECluster, *AKSCluster:
+		return true
+	default:
+		return false
+	}
+}
+
+// Sleeper puts clusters to sleep by scaling their node pools down and wakes them up by restoring the recorded sizes.
+type Sleeper struct {
+	manager *Manager
+	store   *clustersleep.Store
+	logger  logrus.FieldLogger
+}
+
+// NewSleeper returns a new Sleeper instance.
+func NewSleeper(manager *Manager, store *clustersleep.Store, logger logrus.FieldLogger) *Sleeper {
+	return &Sleeper{
+		manager: manager,
+		store:   store,
+		logger:  logger,
+	}
+}
+
+// Sleep scales every node pool of the cluster to zero (or to their minimum size) and records the current sizes.
+// The returned warnings list the node pools that could not be scaled to zero.
+func (s *Sleeper) Sleep(ctx context.Context, cluster CommonCluster, userID uint, scaleToMinimum bool) ([]string, error) {
+	if !CanSleep(cluster) {
+		return nil, &sleepError{
+			msg:            fmt.Sprintf("%s clusters cannot be put to sleep", cluster.GetCloud()),
+			invalidRequest: true,
+		}
+	}
+
+	status, err := cluster.GetStatus()
+	if err != nil {
+		return nil, emperror.Wrap(err, "could not get cluster status")
+	}
+
+	if status.Status != pkgCluster.Running && status.Status != pkgCluster.Warning {
+		return nil, emperror.With(
+			&sleepError{
+				msg:                fmt.Sprintf("cluster is not in %s or %s state", pkgCluster.Running, pkgCluster.Warning),
+				preconditionFailed: true,
+			},
+			"status", status.Status,
+		)
+	}
+
+	// a previous sleep attempt may have already scaled some of the node pools down
+	sizes, err := s.store.RecordedNodePoolSizes(cluster.GetID())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sizes) == 0 {
+		sizes = make(map[string]int, len(status.NodePools))
+		for name, nodePool := range status.NodePools {
+			sizes[name] = nodePool.Count
+		}
+
+		if err := s.store.RecordNodePoolSizes(cluster.GetID(), sizes); err != nil {
+			return nil, err
+		}
+	}
+
+	request := &pkgCluster.UpdateNodePoolsRequest{
+		NodePools: make(map[string]*pkgCluster.NodePoolData, len(status.NodePools)),
+	}
+	var warnings []string
+	for name, nodePool := range status.NodePools {
+		count, warning := sleepNodePoolCount(cluster.GetCloud(), name, nodePool, scaleToMinimum)
+		if warning != "" {
+			warnings = append(warnings, warning)
+		}
+
+		request.NodePools[name] = &pkgCluster.NodePoolData{Count: count}
+	}
+	sort.Strings(warnings)
+
+	return warnings, s.updateNodePools(ctx, cluster, userID, request, pkgCluster.Sleeping, pkgCluster.SleepingMessage, nil)
+}
+
+// aksMinNodePoolCount is the smallest size AKS accepts for a node pool.
+const aksMinNodePoolCount = 1
+
+// sleepNodePoolCount returns the size a node pool is scaled to when the cluster is put to sleep.
+// EKS node pools cannot be scaled below the minimum size of their auto scaling group,
+// AKS node pools cannot be scaled below one node,
+// and autoscaled node pools would be scaled back up by the autoscaler,
+// so these are scaled to their minimum size with a warning.
+func sleepNodePoolCount(cloud string, name string, nodePool *pkgCluster.NodePoolStatus, scaleToMinimum bool) (int, string) {
+	minCount := 0
+	if nodePool.Autoscaling || cloud == pkgCluster.Amazon {
+		minCount = nodePool.MinCount
+	}
+	if cloud == pkgCluster.Azure && minCount < aksMinNodePoolCount {
+		minCount = aksMinNodePoolCount
+	}
+
+	if scaleToMinimum {
+		if nodePool.MinCount > minCount {
+			return nodePool.MinCount, ""
+		}
+
+		return minCount, ""
+	}
+
+	if minCount > 0 {
+		return minCount, fmt.Sprintf("node pool %s cannot be scaled to zero, it is scaled to its minimum size (%d)", name, minCount)
+	}
+
+	return 0, ""
+}
+
+// Wake restores the node pool sizes recorded when the cluster was put to sleep.
+// A cluster left in a running or warning state by a failed sleep can be woken up as long as sizes were recorded.
+func (s *Sleeper) Wake(ctx context.Context, cluster CommonCluster, userID uint) error {
+	status, err := cluster.GetStatus()
+	if err != nil {
+		return emperror.Wrap(err, "could not get cluster status")
+	}
+
+	sizes, err := s.store.RecordedNodePoolSizes(cluster.GetID())
+	if err != nil {
+		return err
+	}
+
+	if !canWake(status.Status, len(sizes) > 0) {
+		return emperror.With(
+			&sleepError{
+				msg:                fmt.Sprintf("cluster is not in %s state and has no recorded node pool sizes", pkgCluster.Sleeping),
+				preconditionFailed: true,
+			},
+			"status", status.Status,
+		)
+	}
+
+	request := &pkgCluster.UpdateNodePoolsRequest{
+		NodePools: make(map[string]*pkgCluster.NodePoolData, len(sizes)),
+	}
+	for name, count := range sizes {
+		if !cluster.NodePoolExists(name) {
+			continue
+		}
+
+		request.NodePools[name] = &pkgCluster.NodePoolData{Count: count}
+	}
+
+	clusterID := cluster.GetID()
+
+	return s.updateNodePools(ctx, cluster, userID, request, pkgCluster.Running, pkgCluster.RunningMessage, func() error {
+		return s.store.ClearNodePoolSizes(clusterID)
+	})
+}
+
+// canWake tells whether a cluster in the given status can be woken up.
+func canWake(status string, hasRecordedSizes bool) bool {
+	switch status {
+	case pkgCluster.Sleeping:
+		return true
+	case pkgCluster.Running, pkgCluster.Warning:
+		return hasRecordedSizes
+	default:
+		return false
+	}
+}
+
+func (s *Sleeper) updateNodePools(
+	ctx context.Context,
+	cluster CommonCluster,
+	userID uint,
+	request *pkgCluster.UpdateNodePoolsRequest,
+	status string,
+	statusMessage string,
+	onSuccess func() error,
+) error {
+	logger := s.logger.WithFields(logrus.Fields{
+		"organization": cluster.GetOrganizationId(),
+		"cluster":      cluster.GetID(),
+		"status":       status,
+	})
+
+	if err := cluster.SetStatus(pkgCluster.Updating, pkgCluster.UpdatingMessage); err != nil {
+		return emperror.Wrap(err, "could not update cluster status")
+	}
+
+	errorHandler := s.manager.getClusterErrorHandler(ctx, cluster)
+
+	go func() {
+		defer emperror.HandleRecover(errorHandler.WithStatus(pkgCluster.Warning, "internal error while scaling node pools"))
+		defer s.manager.startOperationHeartbeat(cluster)()
+
+		logger.Info("scaling node pools")
+
+		if err := cluster.UpdateNodePools(request, userID); err != nil {
+			if setErr := cluster.SetStatus(pkgCluster.Warning, err.Error()); setErr != nil {
+				logger.Error(setErr.Error())
+			}
+			s.manager.events.ClusterUpdated(cluster.GetID())
+
+			errorHandler.Handle(emperror.Wrap(err, "error scaling node pools"))
+
+			return
+		}
+
+		if err := cluster.SetStatus(status, statusMessage); err != nil {
+			errorHandler.Handle(emperror.Wrap(err, "could not update cluster status"))
+
+			return
+		}
+		s.manager.events.ClusterUpdated(cluster.GetID())
+
+		if onSuccess != nil {
+			if err := onSuccess(); err != nil {
+				errorHandler.Handle(err)
+			}
+		}
+
+		logger.Info("node pools scaled successfully")
+	}()
+
+	return nil
+}
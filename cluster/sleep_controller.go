@@ -0,0 +1,152 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"time"
+
+	intCluster "github.com/banzaicloud/pipeline/internal/cluster"
+	"github.com/banzaicloud/pipeline/internal/clustersleep"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const sleepControllerInterval = time.Minute
+
+// SleepController periodically checks cluster sleep schedules and puts clusters to sleep or wakes them up when due.
+type SleepController struct {
+	sleeper *Sleeper
+	store   *clustersleep.Store
+
+	stop chan struct{}
+
+	logger       logrus.FieldLogger
+	errorHandler emperror.Handler
+}
+
+// NewSleepController instantiates a new cluster sleep controller.
+func NewSleepController(sleeper *Sleeper, store *clustersleep.Store, logger logrus.FieldLogger, errorHandler emperror.Handler) *SleepController {
+	return &SleepController{
+		sleeper:      sleeper,
+		store:        store,
+		stop:         make(chan struct{}),
+		logger:       logger,
+		errorHandler: errorHandler,
+	}
+}
+
+func (c *SleepController) Start() {
+	c.logger.Info("starting cluster sleep controller")
+
+	go func() {
+		ticker := time.NewTicker(sleepControllerInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				c.run(time.Now())
+			case <-c.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (c *SleepController) Stop() {
+	c.logger.Info("shutting cluster sleep controller")
+	close(c.stop)
+}
+
+func (c *SleepController) run(now time.Time) {
+	schedules, err := c.store.ListSchedules()
+	if err != nil {
+		c.errorHandler.Handle(err)
+
+		return
+	}
+
+	for _, scheduled := range schedules {
+		action := scheduled.Schedule.DueAction(now)
+		if action == clustersleep.NoAction {
+			continue
+		}
+
+		// other instances run the same schedules, the action is executed by the one claiming it
+		claimed, err := c.store.ClaimSchedule(scheduled, now)
+		if err != nil {
+			c.errorHandler.Handle(err)
+
+			continue
+		}
+
+		if !claimed {
+			continue
+		}
+
+		if err := c.execute(scheduled, action); isSleepPreconditionFailed(err) {
+			c.logger.WithField("cluster", scheduled.ClusterID).Infof("skipping scheduled %s: %s", action, err)
+		} else if err != nil {
+			c.errorHandler.Handle(emperror.With(err, "clusterId", scheduled.ClusterID, "action", action))
+		}
+	}
+}
+
+func (c *SleepController) execute(scheduled clustersleep.ScheduledCluster, action clustersleep.Action) error {
+	logger := c.logger.WithFields(logrus.Fields{
+		"cluster": scheduled.ClusterID,
+		"action":  action,
+	})
+
+	cluster, err := c.sleeper.manager.GetClusterByIDOnly(context.Background(), scheduled.ClusterID)
+	if intCluster.IsClusterNotFoundError(err) {
+		logger.Info("cluster not found, removing sleep schedule")
+
+		return c.store.DeleteByCluster(scheduled.ClusterID)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !CanSleep(cluster) {
+		logger.Warnf("%s clusters cannot be put to sleep, skipping scheduled action", cluster.GetCloud())
+
+		return nil
+	}
+
+	logger.Info("executing scheduled cluster sleep action")
+
+	switch action {
+	case clustersleep.SleepAction:
+		warnings, err := c.sleeper.Sleep(context.Background(), cluster, 0, scheduled.Schedule.ScaleToMinimum)
+		for _, warning := range warnings {
+			logger.Warn(warning)
+		}
+
+		return err
+	case clustersleep.WakeAction:
+		return c.sleeper.Wake(context.Background(), cluster, 0)
+	}
+
+	return nil
+}
+
+func isSleepPreconditionFailed(err error) bool {
+	e, ok := errors.Cause(err).(*sleepError)
+
+	return ok && e.PreconditionFailed()
+}
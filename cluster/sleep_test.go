@@ -0,0 +1,111 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+)
+
+func TestSleepNodePoolCount(t *testing.T) {
+	tests := map[string]struct {
+		cloud          string
+		nodePool       pkgCluster.NodePoolStatus
+		scaleToMinimum bool
+		expectedCount  int
+		expectWarning  bool
+	}{
+		"fixed size": {
+			cloud:         pkgCluster.Google,
+			nodePool:      pkgCluster.NodePoolStatus{Count: 3},
+			expectedCount: 0,
+		},
+		"scale to minimum": {
+			cloud:          pkgCluster.Google,
+			nodePool:       pkgCluster.NodePoolStatus{Count: 3, MinCount: 1, MaxCount: 5, Autoscaling: true},
+			scaleToMinimum: true,
+			expectedCount:  1,
+		},
+		"autoscaling": {
+			cloud:         pkgCluster.Azure,
+			nodePool:      pkgCluster.NodePoolStatus{Count: 3, MinCount: 2, MaxCount: 5, Autoscaling: true},
+			expectedCount: 2,
+			expectWarning: true,
+		},
+		"autoscaling with zero minimum": {
+			cloud:         pkgCluster.Google,
+			nodePool:      pkgCluster.NodePoolStatus{Count: 3, MaxCount: 5, Autoscaling: true},
+			expectedCount: 0,
+		},
+		"aks fixed size": {
+			cloud:         pkgCluster.Azure,
+			nodePool:      pkgCluster.NodePoolStatus{Count: 3},
+			expectedCount: 1,
+			expectWarning: true,
+		},
+		"aks scale to minimum": {
+			cloud:          pkgCluster.Azure,
+			nodePool:       pkgCluster.NodePoolStatus{Count: 3},
+			scaleToMinimum: true,
+			expectedCount:  1,
+		},
+		"eks auto scaling group minimum": {
+			cloud:         pkgCluster.Amazon,
+			nodePool:      pkgCluster.NodePoolStatus{Count: 3, MinCount: 1, MaxCount: 3},
+			expectedCount: 1,
+			expectWarning: true,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			count, warning := sleepNodePoolCount(test.cloud, "pool1", &test.nodePool, test.scaleToMinimum)
+
+			assert.Equal(t, test.expectedCount, count)
+			if test.expectWarning {
+				assert.Contains(t, warning, "pool1")
+			} else {
+				assert.Empty(t, warning)
+			}
+		})
+	}
+}
+
+func TestCanSleep(t *testing.T) {
+	assert.True(t, CanSleep(&EKSCluster{}))
+	assert.True(t, CanSleep(&GKECluster{}))
+	assert.True(t, CanSleep(&AKSCluster{}))
+	assert.False(t, CanSleep(&ACKCluster{}))
+	assert.False(t, CanSleep(&OKECluster{}))
+	assert.False(t, CanSleep(&DummyCluster{}))
+}
+
+func TestCanWake(t *testing.T) {
+	assert.True(t, canWake(pkgCluster.Sleeping, false))
+	assert.True(t, canWake(pkgCluster.Sleeping, true))
+
+	// a failed sleep leaves the cluster in warning state with some node pools already scaled down
+	assert.True(t, canWake(pkgCluster.Warning, true))
+	assert.True(t, canWake(pkgCluster.Running, true))
+
+	assert.False(t, canWake(pkgCluster.Warning, false))
+	assert.False(t, canWake(pkgCluster.Running, false))
+	assert.False(t, canWake(pkgCluster.Updating, true))
+}
@@ -0,0 +1,273 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/goph/emperror"
+	"github.com/sirupsen/logrus"
+	"go.uber.org/cadence/.gen/go/shared"
+	"go.uber.org/cadence/client"
+
+	"github.com/banzaicloud/pipeline/model"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+)
+
+const (
+	// operationHeartbeatInterval is how often a cluster operation running in a Pipeline process
+	// marks its cluster as being worked on.
+	operationHeartbeatInterval = time.Minute
+
+	// operationHeartbeatTimeout is the time after which a cluster operation without a heartbeat
+	// is considered to be interrupted.
+	operationHeartbeatTimeout = 5 * operationHeartbeatInterval
+
+	statusReconcilerInterval = 5 * time.Minute
+)
+
+// StatusReconciler periodically moves clusters left in a transitional status (creating, updating, deleting)
+// by an interrupted operation to a final status based on the state reported by their provider.
+type StatusReconciler struct {
+	clusters       clusterRepository
+	workflowClient client.Client
+	getCluster     func(clusterModel *model.ClusterModel) (CommonCluster, error)
+
+	stop chan struct{}
+
+	logger       logrus.FieldLogger
+	errorHandler emperror.Handler
+}
+
+// NewStatusReconciler returns a new cluster status reconciler.
+func NewStatusReconciler(manager *Manager, logger logrus.FieldLogger, errorHandler emperror.Handler) *StatusReconciler {
+	return &StatusReconciler{
+		clusters:       manager.clusters,
+		workflowClient: manager.workflowClient,
+		getCluster:     GetCommonClusterFromModel,
+		stop:           make(chan struct{}),
+		logger:         logger,
+		errorHandler:   errorHandler,
+	}
+}
+
+// Start reconciles cluster statuses right away and then periodically in the background.
+func (r *StatusReconciler) Start() {
+	r.logger.Info("starting cluster status reconciler")
+
+	go func() {
+		ticker := time.NewTicker(statusReconcilerInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := r.Reconcile(context.Background()); err != nil {
+				r.errorHandler.Handle(err)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (r *StatusReconciler) Stop() {
+	r.logger.Info("shutting cluster status reconciler")
+	close(r.stop)
+}
+
+// Reconcile checks every cluster in a transitional status.
+// Clusters with neither an open Cadence workflow nor a recent operation heartbeat
+// are moved to running, warning or error status depending on whether the provider reports them ready.
+func (r *StatusReconciler) Reconcile(ctx context.Context) error {
+	r.logger.Debug("reconciling clusters in transitional status")
+
+	clusterModels, err := r.clusters.All()
+	if err != nil {
+		return emperror.Wrap(err, "retrieving clusters failed")
+	}
+
+	now := time.Now()
+
+	for _, clusterModel := range clusterModels {
+		if !isTransitionalStatus(clusterModel.Status) {
+			continue
+		}
+
+		logger := r.logger.WithFields(logrus.Fields{
+			"organization": clusterModel.OrganizationId,
+			"cluster":      clusterModel.Name,
+			"clusterID":    clusterModel.ID,
+			"status":       clusterModel.Status,
+		})
+
+		cluster, err := r.getCluster(clusterModel)
+		if err != nil {
+			r.errorHandler.Handle(emperror.WrapWith(err, "could not get cluster from model", "clusterID", clusterModel.ID))
+			continue
+		}
+
+		if err := r.reconcileCluster(ctx, cluster, clusterModel, now, logger); err != nil {
+			r.errorHandler.Handle(emperror.WrapWith(err, "failed to reconcile cluster status", "clusterID", clusterModel.ID))
+		}
+	}
+
+	return nil
+}
+
+func (r *StatusReconciler) reconcileCluster(
+	ctx context.Context,
+	cluster CommonCluster,
+	clusterModel *model.ClusterModel,
+	now time.Time,
+	logger logrus.FieldLogger,
+) error {
+	running, err := r.hasRunningWorkflow(ctx, cluster)
+	if err != nil {
+		return err
+	}
+
+	if running {
+		logger.Debug("cluster workflow is in progress")
+
+		return nil
+	}
+
+	// Operations running in a Pipeline process keep the cluster fresh with a heartbeat
+	if !isStaleStatus(clusterModel.UpdatedAt, now) {
+		logger.Debug("cluster operation is in progress")
+
+		return nil
+	}
+
+	logger.Info("checking provider state of interrupted cluster operation")
+
+	ready, err := cluster.IsReady()
+
+	newStatus, statusMessage := reconciledStatus(clusterModel.Status, ready, err)
+
+	logger.WithField("newStatus", newStatus).Info("moving cluster out of transitional status")
+
+	return cluster.SetStatus(newStatus, statusMessage)
+}
+
+// hasRunningWorkflow returns true if an open Cadence workflow is operating on the cluster.
+func (r *StatusReconciler) hasRunningWorkflow(ctx context.Context, cluster CommonCluster) (bool, error) {
+	if r.workflowClient == nil {
+		return false, nil
+	}
+
+	var workflowIDs []string
+
+	if c, ok := cluster.(interface{ GetCurrentWorkflowID() string }); ok && c.GetCurrentWorkflowID() != "" {
+		workflowIDs = append(workflowIDs, c.GetCurrentWorkflowID())
+	}
+
+	if isWorkflowCluster(cluster) {
+		workflowIDs = append(workflowIDs,
+			clusterWorkflowID(CreateClusterWorkflowName, cluster.GetID()),
+			clusterWorkflowID(DeleteClusterWorkflowName, cluster.GetID()),
+		)
+	}
+
+	for _, workflowID := range workflowIDs {
+		desc, err := r.workflowClient.DescribeWorkflowExecution(ctx, workflowID, "")
+		if _, ok := err.(*shared.EntityNotExistsError); ok {
+			continue
+		}
+		if err != nil {
+			return false, emperror.WrapWith(err, "failed to describe workflow", "workflowID", workflowID)
+		}
+
+		if info := desc.WorkflowExecutionInfo; info != nil && info.CloseStatus == nil {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func isTransitionalStatus(status string) bool {
+	switch status {
+	case pkgCluster.Creating, pkgCluster.Updating, pkgCluster.Deleting:
+		return true
+	default:
+		return false
+	}
+}
+
+// isStaleStatus returns true if a cluster has not changed for longer than the operation heartbeat timeout.
+func isStaleStatus(updatedAt time.Time, now time.Time) bool {
+	return now.Sub(updatedAt) > operationHeartbeatTimeout
+}
+
+// startOperationHeartbeat periodically touches a cluster while an operation is running in this process,
+// so that no status reconciler considers the operation interrupted. The returned function stops the heartbeat.
+func (m *Manager) startOperationHeartbeat(cluster CommonCluster) func() {
+	stop := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(operationHeartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := m.clusters.Touch(cluster.GetID()); err != nil {
+					m.errorHandler.Handle(emperror.WrapWith(err, "failed to record cluster operation heartbeat", "clusterID", cluster.GetID()))
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return func() { close(stop) }
+}
+
+// reconciledStatus returns the status and status message of a cluster whose operation has been interrupted
+// based on the readiness reported by the provider.
+func reconciledStatus(status string, ready bool, readyErr error) (string, string) {
+	var operation string
+	switch status {
+	case pkgCluster.Creating:
+		operation = "creation"
+	case pkgCluster.Updating:
+		operation = "update"
+	case pkgCluster.Deleting:
+		operation = "deletion"
+	}
+
+	if readyErr != nil {
+		return pkgCluster.Error, fmt.Sprintf("cluster %s was interrupted, failed to get cluster state: %s", operation, readyErr.Error())
+	}
+
+	if !ready {
+		return pkgCluster.Error, fmt.Sprintf("cluster %s was interrupted, cluster is not ready", operation)
+	}
+
+	switch status {
+	case pkgCluster.Updating:
+		return pkgCluster.Running, "cluster update was interrupted, cluster is running but may not reflect every requested change"
+	case pkgCluster.Deleting:
+		return pkgCluster.Warning, "cluster deletion was interrupted, cluster is still running"
+	default:
+		return pkgCluster.Warning, "cluster creation was interrupted, cluster is running but its setup may be incomplete"
+	}
+}
@@ -0,0 +1,265 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/cadence/.gen/go/shared"
+	"go.uber.org/cadence/mocks"
+
+	"github.com/banzaicloud/pipeline/model"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+)
+
+type reconcilerClusterRepositoryStub struct {
+	clusterRepository
+
+	clusters []*model.ClusterModel
+}
+
+func (r *reconcilerClusterRepositoryStub) All() ([]*model.ClusterModel, error) {
+	return r.clusters, nil
+}
+
+type reconcilerClusterStub struct {
+	CommonCluster
+
+	id         uint
+	workflowID string
+	ready      bool
+
+	status        string
+	statusMessage string
+}
+
+func (c *reconcilerClusterStub) GetID() uint {
+	return c.id
+}
+
+func (c *reconcilerClusterStub) GetCurrentWorkflowID() string {
+	return c.workflowID
+}
+
+func (c *reconcilerClusterStub) IsReady() (bool, error) {
+	return c.ready, nil
+}
+
+func (c *reconcilerClusterStub) SetStatus(status, statusMessage string) error {
+	c.status = status
+	c.statusMessage = statusMessage
+
+	return nil
+}
+
+type errorHandlerStub struct {
+	errors []error
+}
+
+func (h *errorHandlerStub) Handle(err error) {
+	h.errors = append(h.errors, err)
+}
+
+func TestStatusReconciler_Reconcile(t *testing.T) {
+	now := time.Now()
+	stale := now.Add(-operationHeartbeatTimeout - time.Minute)
+
+	clusterModels := []*model.ClusterModel{
+		{ID: 1, Status: pkgCluster.Creating, UpdatedAt: stale},
+		{ID: 2, Status: pkgCluster.Updating, UpdatedAt: now},
+		{ID: 3, Status: pkgCluster.Deleting, UpdatedAt: stale},
+		{ID: 4, Status: pkgCluster.Running, UpdatedAt: stale},
+	}
+
+	clusters := map[uint]*reconcilerClusterStub{
+		1: {id: 1, ready: true},
+		2: {id: 2, ready: true},
+		3: {id: 3, ready: true, workflowID: "running-workflow"},
+		4: {id: 4, ready: true},
+	}
+
+	workflowClient := new(mocks.Client)
+	workflowClient.On("DescribeWorkflowExecution", mock.Anything, "running-workflow", "").Return(
+		&shared.DescribeWorkflowExecutionResponse{WorkflowExecutionInfo: &shared.WorkflowExecutionInfo{}},
+		nil,
+	)
+
+	errorHandler := new(errorHandlerStub)
+
+	reconciler := &StatusReconciler{
+		clusters:       &reconcilerClusterRepositoryStub{clusters: clusterModels},
+		workflowClient: workflowClient,
+		getCluster: func(clusterModel *model.ClusterModel) (CommonCluster, error) {
+			return clusters[clusterModel.ID], nil
+		},
+		logger:       logrus.New(),
+		errorHandler: errorHandler,
+	}
+
+	err := reconciler.Reconcile(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, errorHandler.errors)
+
+	assert.Equal(t, pkgCluster.Warning, clusters[1].status, "interrupted operation should be reconciled")
+	assert.Empty(t, clusters[2].status, "operation with a recent heartbeat should be left alone")
+	assert.Empty(t, clusters[3].status, "operation with a running workflow should be left alone")
+	assert.Empty(t, clusters[4].status, "cluster in a final status should be left alone")
+
+	workflowClient.AssertExpectations(t)
+}
+
+func TestStatusReconciler_hasRunningWorkflow(t *testing.T) {
+	closed := shared.WorkflowExecutionCloseStatusFailed
+
+	tests := map[string]struct {
+		response *shared.DescribeWorkflowExecutionResponse
+		err      error
+		running  bool
+		hasError bool
+	}{
+		"open workflow": {
+			response: &shared.DescribeWorkflowExecutionResponse{WorkflowExecutionInfo: &shared.WorkflowExecutionInfo{}},
+			running:  true,
+		},
+		"closed workflow": {
+			response: &shared.DescribeWorkflowExecutionResponse{WorkflowExecutionInfo: &shared.WorkflowExecutionInfo{CloseStatus: &closed}},
+		},
+		"unknown workflow": {
+			err: &shared.EntityNotExistsError{},
+		},
+		"cadence error": {
+			err:      errors.New("connection refused"),
+			hasError: true,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			workflowClient := new(mocks.Client)
+			workflowClient.On("DescribeWorkflowExecution", mock.Anything, "workflow", "").Return(test.response, test.err)
+
+			reconciler := &StatusReconciler{workflowClient: workflowClient}
+
+			running, err := reconciler.hasRunningWorkflow(context.Background(), &reconcilerClusterStub{workflowID: "workflow"})
+			if test.hasError {
+				require.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.running, running)
+		})
+	}
+
+	t.Run("workflow cluster", func(t *testing.T) {
+		workflowClient := new(mocks.Client)
+		workflowClient.On("DescribeWorkflowExecution", mock.Anything, clusterWorkflowID(CreateClusterWorkflowName, 1), "").Return(
+			nil,
+			&shared.EntityNotExistsError{},
+		)
+		workflowClient.On("DescribeWorkflowExecution", mock.Anything, clusterWorkflowID(DeleteClusterWorkflowName, 1), "").Return(
+			&shared.DescribeWorkflowExecutionResponse{WorkflowExecutionInfo: &shared.WorkflowExecutionInfo{}},
+			nil,
+		)
+
+		reconciler := &StatusReconciler{workflowClient: workflowClient}
+
+		running, err := reconciler.hasRunningWorkflow(context.Background(), &EKSCluster{modelCluster: &model.ClusterModel{ID: 1}})
+		require.NoError(t, err)
+		assert.True(t, running)
+
+		workflowClient.AssertExpectations(t)
+	})
+}
+
+func TestReconciledStatus(t *testing.T) {
+	tests := map[string]struct {
+		status         string
+		ready          bool
+		readyErr       error
+		expectedStatus string
+	}{
+		"creating ready": {
+			status:         pkgCluster.Creating,
+			ready:          true,
+			expectedStatus: pkgCluster.Warning,
+		},
+		"creating not ready": {
+			status:         pkgCluster.Creating,
+			expectedStatus: pkgCluster.Error,
+		},
+		"updating ready": {
+			status:         pkgCluster.Updating,
+			ready:          true,
+			expectedStatus: pkgCluster.Running,
+		},
+		"updating provider error": {
+			status:         pkgCluster.Updating,
+			readyErr:       errors.New("cluster not found"),
+			expectedStatus: pkgCluster.Error,
+		},
+		"deleting ready": {
+			status:         pkgCluster.Deleting,
+			ready:          true,
+			expectedStatus: pkgCluster.Warning,
+		},
+		"deleting provider error": {
+			status:         pkgCluster.Deleting,
+			readyErr:       errors.New("cluster not found"),
+			expectedStatus: pkgCluster.Error,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			status, statusMessage := reconciledStatus(test.status, test.ready, test.readyErr)
+
+			assert.Equal(t, test.expectedStatus, status)
+			assert.NotEmpty(t, statusMessage)
+
+			if test.readyErr != nil {
+				assert.Contains(t, statusMessage, test.readyErr.Error())
+			}
+		})
+	}
+}
+
+func TestIsTransitionalStatus(t *testing.T) {
+	assert.True(t, isTransitionalStatus(pkgCluster.Creating))
+	assert.True(t, isTransitionalStatus(pkgCluster.Updating))
+	assert.True(t, isTransitionalStatus(pkgCluster.Deleting))
+	assert.False(t, isTransitionalStatus(pkgCluster.Running))
+	assert.False(t, isTransitionalStatus(pkgCluster.Error))
+}
+
+func TestIsStaleStatus(t *testing.T) {
+	now := time.Now()
+
+	assert.False(t, isStaleStatus(now.Add(-time.Minute), now))
+	assert.False(t, isStaleStatus(now.Add(-operationHeartbeatTimeout+time.Minute), now))
+	assert.True(t, isStaleStatus(now.Add(-operationHeartbeatTimeout-time.Minute), now))
+}
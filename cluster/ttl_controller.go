@@ -173,6 +173,8 @@ func (c *TTLController) handleCluster(clusterID uint) error {
 	if c.isClusterEndOfLife(clusterStartedAt, ttl) {
 		log.Info("deleting cluster as it has reached end of life")
 
+		c.manager.events.ClusterTTLExpired(cluster.GetOrganizationId(), cluster.GetID(), cluster.GetName())
+
 		err = c.manager.DeleteCluster(context.Background(), cluster, false)
 		if err != nil {
 			return emperror.WrapWith(err, "failed to initiate cluster deletion", "clusterID", clusterID)
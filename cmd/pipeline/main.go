@@ -39,6 +39,7 @@ import (
 	"github.com/banzaicloud/pipeline/cluster"
 	"github.com/banzaicloud/pipeline/config"
 	"github.com/banzaicloud/pipeline/dns"
+	"github.com/banzaicloud/pipeline/internal/application"
 	arkClusterManager "github.com/banzaicloud/pipeline/internal/ark/clustermanager"
 	arkEvents "github.com/banzaicloud/pipeline/internal/ark/events"
 	arkSync "github.com/banzaicloud/pipeline/internal/ark/sync"
@@ -49,6 +50,12 @@ import (
 	"github.com/banzaicloud/pipeline/internal/cluster/clustersecret"
 	"github.com/banzaicloud/pipeline/internal/cluster/clustersecret/clustersecretadapter"
 	prometheusMetrics "github.com/banzaicloud/pipeline/internal/cluster/metrics/adapters/prometheus"
+	"github.com/banzaicloud/pipeline/internal/clusterbulk"
+	"github.com/banzaicloud/pipeline/internal/clusterlabel"
+	"github.com/banzaicloud/pipeline/internal/clusterprofile"
+	"github.com/banzaicloud/pipeline/internal/clustersleep"
+	"github.com/banzaicloud/pipeline/internal/clustertemplate"
+	"github.com/banzaicloud/pipeline/internal/cost"
 	"github.com/banzaicloud/pipeline/internal/dashboard"
 	"github.com/banzaicloud/pipeline/internal/monitor"
 	"github.com/banzaicloud/pipeline/internal/notification"
@@ -58,6 +65,8 @@ import (
 	platformlog "github.com/banzaicloud/pipeline/internal/platform/log"
 	azurePKEAdapter "github.com/banzaicloud/pipeline/internal/providers/azure/pke/adapter"
 	azurePKEDriver "github.com/banzaicloud/pipeline/internal/providers/azure/pke/driver"
+	"github.com/banzaicloud/pipeline/internal/secret/installation"
+	"github.com/banzaicloud/pipeline/internal/secret/rotation"
 	anchore "github.com/banzaicloud/pipeline/internal/security"
 	"github.com/banzaicloud/pipeline/model/defaults"
 	"github.com/banzaicloud/pipeline/pkg/k8sclient"
@@ -112,11 +121,15 @@ func main() {
 		logger.Panic(err.Error())
 	}
 
+	err = secret.InitStore(db)
+	if err != nil {
+		logger.Panic(err.Error())
+	}
+
 	basePath := viper.GetString("pipeline.basepath")
 
 	enforcer := intAuth.NewEnforcer(db)
-	accessManager := intAuth.NewAccessManager(enforcer, basePath)
-	accessManager.AddDefaultPolicies()
+	accessManager := intAuth.NewAccessManager(db, enforcer, basePath)
 
 	orgImporter := auth.NewOrgImporter(db, accessManager, config.EventBus)
 	tokenHandler := auth.NewTokenHandler(accessManager)
@@ -133,11 +146,27 @@ func main() {
 		}
 	}
 
+	err = accessManager.AddDefaultPolicies()
+	if err != nil {
+		panic(err)
+	}
+
 	err = defaults.SetDefaultValues()
 	if err != nil {
 		panic(err)
 	}
 
+	// Profiles saved before organization scoped profiles are kept as global ones
+	clusterProfiles := clusterprofile.NewProfiles(db, defaults.GetDefaultProfileName())
+	legacyProfiles, err := defaults.GetAllProfileResponses()
+	if err != nil {
+		panic(err)
+	}
+	err = clusterProfiles.EnsureGlobalProfiles(append(legacyProfiles, clusterprofile.DefaultProfiles(defaults.GetDefaultProfileName())...))
+	if err != nil {
+		panic(err)
+	}
+
 	// External DNS service
 	dnsSvc, err := dns.GetExternalDnsServiceClient()
 	if err != nil {
@@ -194,9 +223,16 @@ func main() {
 		errorHandler.Handle(emperror.Wrap(err, "Failed to configure Cadence client"))
 	}
 
+	secretInstallationStore := installation.NewStore(db)
+	cluster.SetSecretInstallationStore(secretInstallationStore)
+
 	clusterManager := cluster.NewManager(clusters, secretValidator, clusterEvents, statusChangeDurationMetric, clusterTotalMetric, workflowClient, log, errorHandler)
 	clusterGetter := common.NewClusterGetter(clusterManager, logger, errorHandler)
 
+	clusterStatusReconciler := cluster.NewStatusReconciler(clusterManager, log.WithField("subsystem", "status-reconciler"), errorHandler)
+	defer clusterStatusReconciler.Stop()
+	clusterStatusReconciler.Start()
+
 	clusterTTLController := cluster.NewTTLController(clusterManager, clusterEventBus, log.WithField("subsystem", "ttl-controller"), errorHandler)
 	defer clusterTTLController.Stop()
 	err = clusterTTLController.Start()
@@ -204,6 +240,31 @@ func main() {
 		logger.Panic(err)
 	}
 
+	clusterSleepStore := clustersleep.NewStore(db)
+	clusterSleeper := cluster.NewSleeper(clusterManager, clusterSleepStore, log.WithField("subsystem", "cluster-sleeper"))
+
+	clusterSleepController := cluster.NewSleepController(clusterSleeper, clusterSleepStore, log.WithField("subsystem", "sleep-controller"), errorHandler)
+	defer clusterSleepController.Stop()
+	clusterSleepController.Start()
+
+	clusterLabelStore := clusterlabel.NewStore(db)
+
+	clusterBulkStore := clusterbulk.NewStore(db)
+
+	clusterBulkReconciler := clusterbulk.NewReconciler(clusterBulkStore, log.WithField("subsystem", "cluster-bulk-reconciler"), errorHandler)
+	defer clusterBulkReconciler.Stop()
+	clusterBulkReconciler.Start()
+
+	clusterBulkRunner := cluster.NewBulkRunner(clusterManager, clusterBulkStore, clusterLabelStore, log.WithField("subsystem", "cluster-bulk-runner"), errorHandler)
+
+	applicationStore := application.NewStore(db)
+	applicationReconciler := cluster.NewApplicationReconciler(clusterManager, applicationStore, clusterLabelStore, clusterEventBus, log.WithField("subsystem", "application-reconciler"), errorHandler)
+	defer applicationReconciler.Stop()
+	err = applicationReconciler.Start()
+	if err != nil {
+		logger.Panic(err)
+	}
+
 	if viper.GetBool(config.MonitorEnabled) {
 		client, err := k8sclient.NewInClusterClient()
 		if err != nil {
@@ -232,6 +293,43 @@ func main() {
 		}
 	}
 
+	secretInstallationManager := installation.NewManager(
+		secretInstallationStore,
+		cluster.NewSecretInstallationClient(clusterManager),
+		log.WithField("subsystem", "secret-installation"),
+	)
+
+	secretRotator := rotation.NewRotator(
+		db,
+		secret.RestrictedStore,
+		secretInstallationManager,
+		log.WithField("subsystem", "secret-rotator"),
+		errorHandler,
+	)
+	if viper.GetBool(config.SecretRotationEnabled) {
+		go secretRotator.Run(context.Background(), viper.GetDuration(config.SecretRotationCheckInterval))
+	}
+
+	notifier := notification.NewNotifier(
+		db,
+		notification.SMTPConfig{
+			Host:     viper.GetString(config.NotificationSMTPHost),
+			Port:     viper.GetInt(config.NotificationSMTPPort),
+			Username: viper.GetString(config.NotificationSMTPUsername),
+			Password: viper.GetString(config.NotificationSMTPPassword),
+			From:     viper.GetString(config.NotificationSMTPFrom),
+		},
+		viper.GetInt(config.NotificationDeliveryMaxAttempts),
+		viper.GetDuration(config.NotificationDeliveryRetryInterval),
+		log.WithField("subsystem", "notification"),
+		errorHandler,
+	)
+	err = notification.NewSubscriber(notifier, clusters, errorHandler).Register(clusterEventBus)
+	if err != nil {
+		logger.Panic(err)
+	}
+	go notifier.Run(context.Background(), viper.GetDuration(config.NotificationDeliveryRetryInterval))
+
 	if viper.GetBool(config.SpotMetricsEnabled) {
 		go monitor.NewSpotMetricsExporter(context.Background(), clusterManager, log.WithField("subsystem", "spot-metrics-exporter")).Run(viper.GetDuration(config.SpotMetricsCollectionInterval))
 	}
@@ -255,7 +353,16 @@ func main() {
 			workflowClient,
 		),
 	}
-	clusterAPI := api.NewClusterAPI(clusterManager, clusterGetter, workflowClient, log, errorHandler, externalBaseURL, clusterCreators, clusterDeleters)
+	clusterUpdaters := api.ClusterUpdaters{
+		PKEOnAzure: azurePKEDriver.MakeAzurePKEClusterUpdater(
+			log,
+			azurePKEAdapter.NewGORMAzurePKEClusterStore(db),
+			workflowClient,
+			externalBaseURL,
+		),
+	}
+	costEstimator := cost.NewEstimator(cost.NewCloudinfoPriceSource(viper.GetString(config.CloudInfoEndPoint), viper.GetDuration(config.CostPriceCacheTTL)))
+	clusterAPI := api.NewClusterAPI(clusterManager, clusterGetter, workflowClient, log, errorHandler, externalBaseURL, clusterCreators, clusterDeleters, clusterUpdaters, costEstimator, clusterProfiles)
 
 	nplsApi := api.NewNodepoolManagerAPI(clusterGetter, log, errorHandler)
 
@@ -288,6 +395,18 @@ func main() {
 		router.Use(audit.LogWriter(skipPaths, viper.GetStringSlice("audit.headers"), db, log))
 	}
 
+	auditEvents := audit.NewEvents(db)
+	if retention := viper.GetDuration(config.AuditRetention); retention > 0 {
+		go audit.RunRetention(
+			context.Background(),
+			auditEvents,
+			retention,
+			viper.GetDuration(config.AuditRetentionCheckInterval),
+			log.WithField("subsystem", "audit-retention"),
+			errorHandler,
+		)
+	}
+
 	router.GET("/", api.RedirectRoot)
 
 	base := router.Group(basePath)
@@ -309,6 +428,18 @@ func main() {
 	organizationAPI := api.NewOrganizationAPI(orgImporter)
 	userAPI := api.NewUserAPI(accessManager, db, log, errorHandler)
 	networkAPI := api.NewNetworkAPI(log)
+	deploymentAPI := api.NewDeploymentAPI(enforcer)
+	secretAPI := api.NewSecretAPI(secret.RestrictedStore, secretInstallationManager, enforcer, log, errorHandler)
+	secretRotationAPI := api.NewSecretRotationAPI(secretRotator, log, errorHandler)
+	notificationChannelAPI := api.NewNotificationChannelAPI(notification.NewChannels(db), notifier, log, errorHandler)
+	auditAPI := api.NewAuditAPI(auditEvents, log, errorHandler)
+	clusterCostAPI := api.NewClusterCostAPI(clusterManager, clusterGetter, costEstimator, log, errorHandler)
+	clusterSleepAPI := api.NewClusterSleepAPI(clusterSleepStore, clusterSleeper, clusterGetter, log, errorHandler)
+	clusterLabelAPI := api.NewClusterLabelAPI(clusterLabelStore, applicationReconciler, clusterGetter, log, errorHandler)
+	clusterBulkAPI := api.NewClusterBulkAPI(clusterBulkRunner, clusterBulkStore, enforcer, log, errorHandler)
+	applicationAPI := api.NewApplicationAPI(applicationReconciler, applicationStore, log, errorHandler)
+	clusterProfileAPI := api.NewClusterProfileAPI(clusterProfiles, log, errorHandler)
+	clusterTemplateAPI := api.NewClusterTemplateAPI(clustertemplate.NewTemplates(db), clusterAPI, log, errorHandler)
 
 	scmProvider := viper.GetString("cicd.scm")
 	var scmToken string
@@ -378,9 +509,20 @@ func main() {
 			orgs.POST("/:orgid/clusters", clusterAPI.CreateCluster)
 			//v1.GET("/status", api.Status)
 			orgs.GET("/:orgid/clusters", clusterAPI.GetClusters)
+			orgs.POST("/:orgid/clusters/:id", clusterAPI.ImportCluster)
 			orgs.GET("/:orgid/clusters/:id", clusterAPI.GetCluster)
 			orgs.GET("/:orgid/clusters/:id/pods", api.GetPodDetails)
 			orgs.GET("/:orgid/clusters/:id/bootstrap", clusterAPI.GetBootstrapInfo)
+			orgs.GET("/:orgid/clusters/:id/cost", clusterCostAPI.GetClusterCost)
+			orgs.GET("/:orgid/clusters/:id/sleepschedule", clusterSleepAPI.GetSleepSchedule)
+			orgs.PUT("/:orgid/clusters/:id/sleepschedule", clusterSleepAPI.SetSleepSchedule)
+			orgs.DELETE("/:orgid/clusters/:id/sleepschedule", clusterSleepAPI.DeleteSleepSchedule)
+			orgs.GET("/:orgid/clusters/:id/labels", clusterLabelAPI.GetLabels)
+			orgs.PUT("/:orgid/clusters/:id/labels", clusterLabelAPI.SetLabels)
+			orgs.POST("/:orgid/clusters/:id/clone", clusterAPI.CloneCluster)
+			orgs.POST("/:orgid/clusters/:id/sleep", clusterSleepAPI.SleepCluster)
+			orgs.POST("/:orgid/clusters/:id/wake", clusterSleepAPI.WakeCluster)
+			orgs.GET("/:orgid/cost", clusterCostAPI.GetOrganizationCost)
 			orgs.PUT("/:orgid/clusters/:id", clusterAPI.UpdateCluster)
 
 			orgs.PUT("/:orgid/clusters/:id/posthooks", clusterAPI.ReRunPostHooks)
@@ -394,17 +536,20 @@ func main() {
 			orgs.GET("/:orgid/clusters/:id/apiendpoint", api.GetApiEndpoint)
 			orgs.GET("/:orgid/clusters/:id/nodes", api.GetClusterNodes)
 			orgs.GET("/:orgid/clusters/:id/endpoints", api.ListEndpoints)
-			orgs.GET("/:orgid/clusters/:id/secrets", api.ListClusterSecrets)
+			orgs.GET("/:orgid/clusters/:id/secrets", secretAPI.ListClusterSecrets)
 			orgs.GET("/:orgid/clusters/:id/deployments", api.ListDeployments)
-			orgs.POST("/:orgid/clusters/:id/deployments", api.CreateDeployment)
+			orgs.POST("/:orgid/clusters/:id/deployments", deploymentAPI.CreateDeployment)
 			orgs.GET("/:orgid/clusters/:id/deployments/:name", api.GetDeployment)
 			orgs.GET("/:orgid/clusters/:id/deployments/:name/resources", api.GetDeploymentResources)
+			orgs.GET("/:orgid/clusters/:id/deployments/:name/history", api.GetDeploymentHistory)
+			orgs.POST("/:orgid/clusters/:id/deployments/:name/rollback", api.RollbackDeployment)
+			orgs.POST("/:orgid/clusters/:id/deployments/:name/diff", deploymentAPI.DiffDeployment)
 			orgs.GET("/:orgid/clusters/:id/hpa", api.GetHpaResource)
 			orgs.PUT("/:orgid/clusters/:id/hpa", api.PutHpaResource)
 			orgs.DELETE("/:orgid/clusters/:id/hpa", api.DeleteHpaResource)
 			orgs.HEAD("/:orgid/clusters/:id/deployments", api.GetTillerStatus)
 			orgs.DELETE("/:orgid/clusters/:id/deployments/:name", api.DeleteDeployment)
-			orgs.PUT("/:orgid/clusters/:id/deployments/:name", api.UpgradeDeployment)
+			orgs.PUT("/:orgid/clusters/:id/deployments/:name", deploymentAPI.UpgradeDeployment)
 			orgs.HEAD("/:orgid/clusters/:id/deployments/:name", api.HelmDeploymentStatus)
 			orgs.POST("/:orgid/clusters/:id/helminit", api.InitHelmOnCluster)
 
@@ -475,16 +620,53 @@ func main() {
 			orgs.DELETE("/:orgid/helm/repos/:name", api.HelmReposDelete)
 			orgs.GET("/:orgid/helm/charts", api.HelmCharts)
 			orgs.GET("/:orgid/helm/chart/:reponame/:name", api.HelmChart)
-			orgs.GET("/:orgid/profiles/cluster/:distribution", api.GetClusterProfiles)
-			orgs.POST("/:orgid/profiles/cluster", api.AddClusterProfile)
-			orgs.PUT("/:orgid/profiles/cluster", api.UpdateClusterProfile)
-			orgs.DELETE("/:orgid/profiles/cluster/:distribution/:name", api.DeleteClusterProfile)
-			orgs.GET("/:orgid/secrets", api.ListSecrets)
+			orgs.GET("/:orgid/profiles/cluster/:distribution", clusterProfileAPI.GetClusterProfiles)
+			orgs.POST("/:orgid/profiles/cluster", clusterProfileAPI.AddClusterProfile)
+			orgs.PUT("/:orgid/profiles/cluster", clusterProfileAPI.UpdateClusterProfile)
+			orgs.DELETE("/:orgid/profiles/cluster/:distribution/:name", clusterProfileAPI.DeleteClusterProfile)
+
+			orgs.GET("/:orgid/clustertemplates", clusterTemplateAPI.ListTemplates)
+			orgs.POST("/:orgid/clustertemplates", clusterTemplateAPI.CreateTemplate)
+			orgs.GET("/:orgid/clustertemplates/:name", clusterTemplateAPI.GetTemplate)
+			orgs.PUT("/:orgid/clustertemplates/:name", clusterTemplateAPI.UpdateTemplate)
+			orgs.DELETE("/:orgid/clustertemplates/:name", clusterTemplateAPI.DeleteTemplate)
+			orgs.GET("/:orgid/clustertemplates/:name/versions", clusterTemplateAPI.ListTemplateVersions)
+			orgs.POST("/:orgid/clustertemplates/:name/instantiate", clusterTemplateAPI.InstantiateTemplate)
+			orgs.GET("/:orgid/clusteroperations", clusterBulkAPI.ListOperations)
+			orgs.POST("/:orgid/clusteroperations", clusterBulkAPI.StartOperation)
+			orgs.GET("/:orgid/clusteroperations/:id", clusterBulkAPI.GetOperation)
+			orgs.POST("/:orgid/clusteroperations/:id/cancel", clusterBulkAPI.CancelOperation)
+			orgs.GET("/:orgid/applications", applicationAPI.ListApplications)
+			orgs.POST("/:orgid/applications", applicationAPI.CreateApplication)
+			orgs.GET("/:orgid/applications/:id", applicationAPI.GetApplication)
+			orgs.PUT("/:orgid/applications/:id", applicationAPI.UpdateApplication)
+			orgs.DELETE("/:orgid/applications/:id", applicationAPI.DeleteApplication)
+			orgs.POST("/:orgid/applications/:id/sync", applicationAPI.SyncApplication)
+			orgs.GET("/:orgid/audit", auditAPI.ListEvents)
+			orgs.GET("/:orgid/audit/export", auditAPI.ExportEvents)
+
+			orgs.GET("/:orgid/notifications/channels", notificationChannelAPI.ListChannels)
+			orgs.POST("/:orgid/notifications/channels", notificationChannelAPI.CreateChannel)
+			orgs.GET("/:orgid/notifications/channels/:channelId", notificationChannelAPI.GetChannel)
+			orgs.PUT("/:orgid/notifications/channels/:channelId", notificationChannelAPI.UpdateChannel)
+			orgs.DELETE("/:orgid/notifications/channels/:channelId", notificationChannelAPI.DeleteChannel)
+			orgs.POST("/:orgid/notifications/channels/:channelId/test", notificationChannelAPI.TestChannel)
+			orgs.GET("/:orgid/notifications/deliveries", notificationChannelAPI.ListDeliveries)
+
+			orgs.GET("/:orgid/secrets", secretAPI.ListSecrets)
 			orgs.GET("/:orgid/secrets/:id", api.GetSecret)
 			orgs.POST("/:orgid/secrets", api.AddSecrets)
-			orgs.PUT("/:orgid/secrets/:id", api.UpdateSecrets)
-			orgs.DELETE("/:orgid/secrets/:id", api.DeleteSecrets)
+			orgs.PUT("/:orgid/secrets/:id", secretAPI.UpdateSecrets)
+			orgs.DELETE("/:orgid/secrets/:id", secretAPI.DeleteSecrets)
 			orgs.GET("/:orgid/secrets/:id/validate", api.ValidateSecret)
+			orgs.GET("/:orgid/secrets/:id/installations", secretAPI.ListSecretInstallations)
+			orgs.GET("/:orgid/secrets/:id/versions", api.ListSecretVersions)
+			orgs.GET("/:orgid/secrets/:id/versions/:version", api.GetSecretVersion)
+			orgs.POST("/:orgid/secrets/:id/versions/:version/restore", secretAPI.RestoreSecretVersion)
+			orgs.GET("/:orgid/secrets/:id/rotation", secretRotationAPI.GetRotationPolicy)
+			orgs.PUT("/:orgid/secrets/:id/rotation", secretRotationAPI.SetRotationPolicy)
+			orgs.DELETE("/:orgid/secrets/:id/rotation", secretRotationAPI.DeleteRotationPolicy)
+			orgs.POST("/:orgid/secrets/:id/rotate", secretRotationAPI.RotateSecret)
 			orgs.GET("/:orgid/secrets/:id/tags", api.GetSecretTags)
 			orgs.PUT("/:orgid/secrets/:id/tags/*tag", api.AddSecretTag)
 			orgs.DELETE("/:orgid/secrets/:id/tags/*tag", api.DeleteSecretTag)
@@ -492,6 +674,8 @@ func main() {
 			orgs.GET("/:orgid/users/:id", userAPI.GetUsers)
 			orgs.POST("/:orgid/users/:id", userAPI.AddUser)
 			orgs.DELETE("/:orgid/users/:id", userAPI.RemoveUser)
+			orgs.GET("/:orgid/users/:id/roles", userAPI.GetUserRoles)
+			orgs.PUT("/:orgid/users/:id/roles", userAPI.UpdateUserRoles)
 
 			orgs.GET("/:orgid/buckets", api.ListAllBuckets)
 			orgs.POST("/:orgid/buckets", api.CreateBucket)
@@ -545,6 +729,7 @@ func main() {
 			viper.GetDuration(config.ARKBucketSyncInterval),
 			viper.GetDuration(config.ARKRestoreSyncInterval),
 			viper.GetDuration(config.ARKBackupSyncInterval),
+			arkEvents.NewBackupEvents(clusterEventBus),
 		)
 	}
 
@@ -17,13 +17,24 @@ package main
 import (
 	"github.com/banzaicloud/pipeline/auth"
 	route53model "github.com/banzaicloud/pipeline/dns/route53/model"
+	"github.com/banzaicloud/pipeline/helm"
+	"github.com/banzaicloud/pipeline/internal/application"
 	"github.com/banzaicloud/pipeline/internal/ark"
 	"github.com/banzaicloud/pipeline/internal/audit"
+	intAuth "github.com/banzaicloud/pipeline/internal/auth"
 	"github.com/banzaicloud/pipeline/internal/cluster"
+	"github.com/banzaicloud/pipeline/internal/clusterbulk"
+	"github.com/banzaicloud/pipeline/internal/clusterlabel"
+	"github.com/banzaicloud/pipeline/internal/clusterprofile"
+	"github.com/banzaicloud/pipeline/internal/clustersleep"
+	"github.com/banzaicloud/pipeline/internal/clustertemplate"
 	"github.com/banzaicloud/pipeline/internal/notification"
 	"github.com/banzaicloud/pipeline/internal/providers"
+	"github.com/banzaicloud/pipeline/internal/secret/installation"
+	"github.com/banzaicloud/pipeline/internal/secret/rotation"
 	"github.com/banzaicloud/pipeline/model"
 	"github.com/banzaicloud/pipeline/model/defaults"
+	"github.com/banzaicloud/pipeline/secret"
 	"github.com/banzaicloud/pipeline/spotguide"
 	"github.com/jinzhu/gorm"
 	"github.com/sirupsen/logrus"
@@ -39,6 +50,10 @@ func Migrate(db *gorm.DB, logger logrus.FieldLogger) error {
 		return err
 	}
 
+	if err := intAuth.Migrate(db, logger); err != nil {
+		return err
+	}
+
 	if err := defaults.Migrate(db, logger); err != nil {
 		return err
 	}
@@ -67,9 +82,49 @@ func Migrate(db *gorm.DB, logger logrus.FieldLogger) error {
 		return err
 	}
 
+	if err := secret.Migrate(db, logger); err != nil {
+		return err
+	}
+
+	if err := rotation.Migrate(db, logger); err != nil {
+		return err
+	}
+
+	if err := installation.Migrate(db, logger); err != nil {
+		return err
+	}
+
 	if err := notification.Migrate(db, logger); err != nil {
 		return err
 	}
 
+	if err := clustertemplate.Migrate(db, logger); err != nil {
+		return err
+	}
+
+	if err := clusterprofile.Migrate(db, logger); err != nil {
+		return err
+	}
+
+	if err := clustersleep.Migrate(db, logger); err != nil {
+		return err
+	}
+
+	if err := clusterbulk.Migrate(db, logger); err != nil {
+		return err
+	}
+
+	if err := clusterlabel.Migrate(db, logger); err != nil {
+		return err
+	}
+
+	if err := helm.Migrate(db, logger); err != nil {
+		return err
+	}
+
+	if err := application.Migrate(db, logger); err != nil {
+		return err
+	}
+
 	return nil
 }
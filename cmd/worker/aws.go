@@ -26,6 +26,7 @@ func registerAwsWorkflows(clusters *pkeworkflowadapter.ClusterManagerAdapter, to
 	workflow.RegisterWithOptions(pkeworkflow.CreateClusterWorkflow, workflow.RegisterOptions{Name: pkeworkflow.CreateClusterWorkflowName})
 	workflow.RegisterWithOptions(pkeworkflow.DeleteClusterWorkflow, workflow.RegisterOptions{Name: pkeworkflow.DeleteClusterWorkflowName})
 	workflow.RegisterWithOptions(pkeworkflow.UpdateClusterWorkflow, workflow.RegisterOptions{Name: pkeworkflow.UpdateClusterWorkflowName})
+	workflow.RegisterWithOptions(pkeworkflow.UpgradeClusterWorkflow, workflow.RegisterOptions{Name: pkeworkflow.UpgradeClusterWorkflowName})
 
 	awsClientFactory := pkeworkflow.NewAWSClientFactory(pkeworkflowadapter.NewSecretStore(secret.Store))
 
@@ -80,4 +81,28 @@ func registerAwsWorkflows(clusters *pkeworkflowadapter.ClusterManagerAdapter, to
 	deleteSshKeyPairActivity := pkeworkflow.NewDeleteSSHKeyPairActivity(clusters)
 	activity.RegisterWithOptions(deleteSshKeyPairActivity.Execute, activity.RegisterOptions{Name: pkeworkflow.DeleteSSHKeyPairActivityName})
 
+	upgradeMasterActivity := pkeworkflow.NewUpgradeMasterActivity(clusters)
+	activity.RegisterWithOptions(upgradeMasterActivity.Execute, activity.RegisterOptions{Name: pkeworkflow.UpgradeMasterActivityName})
+
+	setKubernetesVersionActivity := pkeworkflow.NewSetKubernetesVersionActivity(clusters)
+	activity.RegisterWithOptions(setKubernetesVersionActivity.Execute, activity.RegisterOptions{Name: pkeworkflow.SetKubernetesVersionActivityName})
+
+	updatePoolImageActivity := pkeworkflow.NewUpdatePoolImageActivity(clusters, tokenGenerator)
+	activity.RegisterWithOptions(updatePoolImageActivity.Execute, activity.RegisterOptions{Name: pkeworkflow.UpdatePoolImageActivityName})
+
+	listPoolInstancesActivity := pkeworkflow.NewListPoolInstancesActivity(clusters)
+	activity.RegisterWithOptions(listPoolInstancesActivity.Execute, activity.RegisterOptions{Name: pkeworkflow.ListPoolInstancesActivityName})
+
+	drainNodeActivity := pkeworkflow.NewDrainNodeActivity(clusters)
+	activity.RegisterWithOptions(drainNodeActivity.Execute, activity.RegisterOptions{Name: pkeworkflow.DrainNodeActivityName})
+
+	terminatePoolInstanceActivity := pkeworkflow.NewTerminatePoolInstanceActivity(awsClientFactory)
+	activity.RegisterWithOptions(terminatePoolInstanceActivity.Execute, activity.RegisterOptions{Name: pkeworkflow.TerminatePoolInstanceActivityName})
+
+	waitForPoolNodesActivity := pkeworkflow.NewWaitForPoolNodesActivity(clusters)
+	activity.RegisterWithOptions(waitForPoolNodesActivity.Execute, activity.RegisterOptions{Name: pkeworkflow.WaitForPoolNodesActivityName})
+
+	uncordonPoolNodesActivity := pkeworkflow.NewUncordonPoolNodesActivity(clusters)
+	activity.RegisterWithOptions(uncordonPoolNodesActivity.Execute, activity.RegisterOptions{Name: pkeworkflow.UncordonPoolNodesActivityName})
+
 }
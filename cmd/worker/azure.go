@@ -30,6 +30,7 @@ func registerAzureWorkflows(secretStore pkeworkflow.SecretStore, tokenGenerator
 	workflow.RegisterWithOptions(azurepkeworkflow.CreateInfrastructureWorkflow, workflow.RegisterOptions{Name: azurepkeworkflow.CreateInfraWorkflowName})
 	workflow.RegisterWithOptions(azurepkeworkflow.DeleteClusterWorkflow, workflow.RegisterOptions{Name: azurepkeworkflow.DeleteClusterWorkflowName})
 	workflow.RegisterWithOptions(azurepkeworkflow.DeleteInfrastructureWorkflow, workflow.RegisterOptions{Name: azurepkeworkflow.DeleteInfraWorkflowName})
+	workflow.RegisterWithOptions(azurepkeworkflow.UpdateClusterWorkflow, workflow.RegisterOptions{Name: azurepkeworkflow.UpdateClusterWorkflowName})
 
 	azureClientFactory := azurepkeworkflow.NewAzureClientFactory(secretStore)
 
@@ -76,6 +77,22 @@ func registerAzureWorkflows(secretStore pkeworkflow.SecretStore, tokenGenerator
 	deleteClusterFromStoreActivity := azurepkeworkflow.MakeDeleteClusterFromStoreActivity(store)
 	activity.RegisterWithOptions(deleteClusterFromStoreActivity.Execute, activity.RegisterOptions{Name: azurepkeworkflow.DeleteClusterFromStoreActivityName})
 
+	// update cluster activities
+	collectUpdateClusterProvidersActivity := azurepkeworkflow.MakeCollectUpdateClusterProvidersActivity(azureClientFactory)
+	activity.RegisterWithOptions(collectUpdateClusterProvidersActivity.Execute, activity.RegisterOptions{Name: azurepkeworkflow.CollectUpdateClusterProvidersActivityName})
+
+	updateVMSSActivity := azurepkeworkflow.MakeUpdateVMSSActivity(azureClientFactory)
+	activity.RegisterWithOptions(updateVMSSActivity.Execute, activity.RegisterOptions{Name: azurepkeworkflow.UpdateVMSSActivityName})
+
+	createNodePoolInStoreActivity := azurepkeworkflow.MakeCreateNodePoolInStoreActivity(store)
+	activity.RegisterWithOptions(createNodePoolInStoreActivity.Execute, activity.RegisterOptions{Name: azurepkeworkflow.CreateNodePoolInStoreActivityName})
+
+	deleteNodePoolFromStoreActivity := azurepkeworkflow.MakeDeleteNodePoolFromStoreActivity(store)
+	activity.RegisterWithOptions(deleteNodePoolFromStoreActivity.Execute, activity.RegisterOptions{Name: azurepkeworkflow.DeleteNodePoolFromStoreActivityName})
+
+	setNodePoolSizesActivity := azurepkeworkflow.MakeSetNodePoolSizesActivity(store)
+	activity.RegisterWithOptions(setNodePoolSizesActivity.Execute, activity.RegisterOptions{Name: azurepkeworkflow.SetNodePoolSizesActivityName})
+
 	setClusterStatusActivity := azurepkeworkflow.MakeSetClusterStatusActivity(store)
 	activity.RegisterWithOptions(setClusterStatusActivity.Execute, activity.RegisterOptions{Name: azurepkeworkflow.SetClusterStatusActivityName})
 }
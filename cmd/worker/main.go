@@ -50,6 +50,7 @@ import (
 	"github.com/banzaicloud/pipeline/internal/providers/pke/pkeworkflow"
 	"github.com/banzaicloud/pipeline/internal/providers/pke/pkeworkflow/pkeworkflowadapter"
 	intSecret "github.com/banzaicloud/pipeline/internal/secret"
+	"github.com/banzaicloud/pipeline/internal/secret/installation"
 	"github.com/banzaicloud/pipeline/secret"
 )
 
@@ -123,6 +124,11 @@ func main() {
 			emperror.Panic(err)
 		}
 
+		err = secret.InitStore(db)
+		emperror.Panic(errors.Wrap(err, "failed to initialize secret store"))
+
+		cluster.SetSecretInstallationStore(installation.NewStore(db))
+
 		clusterManager := cluster.NewManager(
 			intCluster.NewClusters(db),
 			nil,
@@ -134,7 +140,7 @@ func main() {
 			errorHandler,
 		)
 		enforcer := intAuth.NewEnforcer(db)
-		accessManager := intAuth.NewAccessManager(enforcer, config.Pipeline.BasePath)
+		accessManager := intAuth.NewAccessManager(db, enforcer, config.Pipeline.BasePath)
 		tokenGenerator := pkeworkflowadapter.NewTokenGenerator(auth.NewTokenHandler(accessManager))
 		auth.Init(nil, accessManager, nil)
 		auth.InitTokenStore()
@@ -178,6 +184,42 @@ func main() {
 		updateClusterStatusActivity := cluster.NewUpdateClusterStatusActivity(clusterManager)
 		activity.RegisterWithOptions(updateClusterStatusActivity.Execute, activity.RegisterOptions{Name: cluster.UpdateClusterStatusActivityName})
 
+		// Register EKS, GKE and AKS cluster workflows and activities
+		workflow.RegisterWithOptions(cluster.CreateClusterWorkflow, workflow.RegisterOptions{Name: cluster.CreateClusterWorkflowName})
+		workflow.RegisterWithOptions(cluster.DeleteClusterWorkflow, workflow.RegisterOptions{Name: cluster.DeleteClusterWorkflowName})
+		workflow.RegisterWithOptions(cluster.CreateEKSClusterWorkflow, workflow.RegisterOptions{Name: cluster.CreateEKSClusterWorkflowName})
+		workflow.RegisterWithOptions(cluster.DeleteEKSClusterWorkflow, workflow.RegisterOptions{Name: cluster.DeleteEKSClusterWorkflowName})
+
+		generateClusterSSHKeyActivity := cluster.NewGenerateClusterSSHKeyActivity(clusterManager)
+		activity.RegisterWithOptions(generateClusterSSHKeyActivity.Execute, activity.RegisterOptions{Name: cluster.GenerateClusterSSHKeyActivityName})
+
+		createClusterInfraStepActivity := cluster.NewCreateClusterInfraStepActivity(clusterManager)
+		activity.RegisterWithOptions(createClusterInfraStepActivity.Execute, activity.RegisterOptions{Name: cluster.CreateClusterInfraStepActivityName})
+
+		undoCreateClusterInfraStepActivity := cluster.NewUndoCreateClusterInfraStepActivity(clusterManager)
+		activity.RegisterWithOptions(undoCreateClusterInfraStepActivity.Execute, activity.RegisterOptions{Name: cluster.UndoCreateClusterInfraStepActivityName})
+
+		getClusterPostHooksActivity := cluster.NewGetClusterPostHooksActivity(clusterManager)
+		activity.RegisterWithOptions(getClusterPostHooksActivity.Execute, activity.RegisterOptions{Name: cluster.GetClusterPostHooksActivityName})
+
+		deleteClusterInfraActivity := cluster.NewDeleteClusterInfraActivity(clusterManager)
+		activity.RegisterWithOptions(deleteClusterInfraActivity.Execute, activity.RegisterOptions{Name: cluster.DeleteClusterInfraActivityName})
+
+		deleteClusterFromStoreActivity := cluster.NewDeleteClusterFromStoreActivity(clusterManager)
+		activity.RegisterWithOptions(deleteClusterFromStoreActivity.Execute, activity.RegisterOptions{Name: cluster.DeleteClusterFromStoreActivityName})
+
+		eksCreateClusterStepActivity := cluster.NewEKSCreateClusterStepActivity(clusterManager)
+		activity.RegisterWithOptions(eksCreateClusterStepActivity.Execute, activity.RegisterOptions{Name: cluster.EKSCreateClusterStepActivityName})
+
+		eksUndoCreateClusterStepActivity := cluster.NewEKSUndoCreateClusterStepActivity(clusterManager)
+		activity.RegisterWithOptions(eksUndoCreateClusterStepActivity.Execute, activity.RegisterOptions{Name: cluster.EKSUndoCreateClusterStepActivityName})
+
+		eksFinalizeClusterActivity := cluster.NewEKSFinalizeClusterActivity(clusterManager)
+		activity.RegisterWithOptions(eksFinalizeClusterActivity.Execute, activity.RegisterOptions{Name: cluster.EKSFinalizeClusterActivityName})
+
+		eksDeleteClusterStepActivity := cluster.NewEKSDeleteClusterStepActivity(clusterManager)
+		activity.RegisterWithOptions(eksDeleteClusterStepActivity.Execute, activity.RegisterOptions{Name: cluster.EKSDeleteClusterStepActivityName})
+
 		deleteUnusedClusterSecretsActivity := intClusterWorkflow.MakeDeleteUnusedClusterSecretsActivity(secret.Store)
 		activity.RegisterWithOptions(deleteUnusedClusterSecretsActivity.Execute, activity.RegisterOptions{Name: intClusterWorkflow.DeleteUnusedClusterSecretsActivityName})
 
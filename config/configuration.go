@@ -155,6 +155,31 @@ const (
 	// Default regions config keys to initialize clients
 	AmazonInitializeRegionKey  = "amazon.defaultApiRegion"
 	AlibabaInitializeRegionKey = "alibaba.defaultApiRegion"
+
+	// Secret store backend: vault or database
+	SecretStoreBackend = "secret.backend"
+	// File containing the base64 encoded 256 bit key used to encrypt secrets in the database backend
+	SecretStoreDatabaseKeyFile = "secret.database.keyFile"
+
+	// Secret rotation
+	SecretRotationEnabled       = "secret.rotation.enabled"
+	SecretRotationCheckInterval = "secret.rotation.checkInterval"
+
+	// Cloudinfo prices used for cost estimation are cached for this long
+	CostPriceCacheTTL = "cost.priceCacheTTL"
+
+	// Audit event retention
+	AuditRetention              = "audit.retention"
+	AuditRetentionCheckInterval = "audit.retentionCheckInterval"
+
+	// Notification delivery
+	NotificationDeliveryMaxAttempts   = "notification.delivery.maxAttempts"
+	NotificationDeliveryRetryInterval = "notification.delivery.retryInterval"
+	NotificationSMTPHost              = "notification.smtp.host"
+	NotificationSMTPPort              = "notification.smtp.port"
+	NotificationSMTPUsername          = "notification.smtp.username"
+	NotificationSMTPPassword          = "notification.smtp.password"
+	NotificationSMTPFrom              = "notification.smtp.from"
 )
 
 //Init initializes the configurations
@@ -218,7 +243,17 @@ func init() {
 	viper.SetDefault("audit.enabled", true)
 	viper.SetDefault("audit.headers", []string{"secretId"})
 	viper.SetDefault("audit.skippaths", []string{"/auth/github/callback", "/pipeline/api"})
+	viper.SetDefault(AuditRetention, "0")
+	viper.SetDefault(CostPriceCacheTTL, "1h")
+	viper.SetDefault(AuditRetentionCheckInterval, "1h")
 	viper.SetDefault("tls.validity", "8760h") // 1 year
+	viper.SetDefault(SecretStoreBackend, "vault")
+	viper.SetDefault(SecretRotationEnabled, true)
+	viper.SetDefault(SecretRotationCheckInterval, "10m")
+	viper.SetDefault(NotificationDeliveryMaxAttempts, 5)
+	viper.SetDefault(NotificationDeliveryRetryInterval, "1m")
+	viper.SetDefault(NotificationSMTPPort, 587)
+	viper.SetDefault(NotificationSMTPFrom, "pipeline@example.org")
 	viper.SetDefault(DNSBaseDomain, "example.org")
 	viper.SetDefault(DNSGcIntervalMinute, 1)
 	viper.SetDefault(DNSExternalDnsChartVersion, "1.6.2")
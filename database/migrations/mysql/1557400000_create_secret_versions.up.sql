@@ -0,0 +1,10 @@
+CREATE TABLE `secret_versions` (
+  `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+  `organization_id` int(10) unsigned NOT NULL,
+  `secret_id` varchar(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `version` int(11) NOT NULL,
+  `data` mediumtext COLLATE utf8mb4_unicode_ci NOT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_secret_versions_org_secret_version` (`organization_id`,`secret_id`,`version`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
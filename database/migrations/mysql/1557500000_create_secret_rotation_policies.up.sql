@@ -0,0 +1,16 @@
+CREATE TABLE `secret_rotation_policies` (
+  `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+  `organization_id` int(10) unsigned NOT NULL,
+  `secret_id` varchar(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `interval` varchar(32) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `renew_before` varchar(32) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `password_format` varchar(64) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `last_rotated_at` timestamp NULL DEFAULT NULL,
+  `next_rotation_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `last_error` text COLLATE utf8mb4_unicode_ci,
+  `created_at` timestamp NULL DEFAULT NULL,
+  `updated_at` timestamp NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_secret_rotation_policies_org_secret` (`organization_id`,`secret_id`),
+  KEY `idx_secret_rotation_policies_next_rotation_at` (`next_rotation_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
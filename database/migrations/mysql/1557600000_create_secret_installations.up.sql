@@ -0,0 +1,14 @@
+CREATE TABLE `secret_installations` (
+  `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+  `organization_id` int(10) unsigned NOT NULL,
+  `secret_id` varchar(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `cluster_id` int(10) unsigned NOT NULL,
+  `namespace` varchar(63) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `name` varchar(253) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `spec` text COLLATE utf8mb4_unicode_ci,
+  `created_at` timestamp NULL DEFAULT NULL,
+  `updated_at` timestamp NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_secret_installations_unique` (`organization_id`,`secret_id`,`cluster_id`,`namespace`,`name`),
+  KEY `idx_secret_installations_cluster_id` (`cluster_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
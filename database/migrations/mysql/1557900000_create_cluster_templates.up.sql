@@ -0,0 +1,13 @@
+CREATE TABLE `cluster_templates` (
+  `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+  `organization_id` int(10) unsigned NOT NULL,
+  `name` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `version` int(10) unsigned NOT NULL,
+  `description` text COLLATE utf8mb4_unicode_ci,
+  `variables` text COLLATE utf8mb4_unicode_ci,
+  `template` text COLLATE utf8mb4_unicode_ci,
+  `created_by` int(10) unsigned DEFAULT NULL,
+  `created_at` timestamp NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_cluster_templates_org_name_version` (`organization_id`,`name`,`version`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
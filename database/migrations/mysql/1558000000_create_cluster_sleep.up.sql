@@ -0,0 +1,16 @@
+CREATE TABLE `cluster_sleep` (
+  `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+  `cluster_id` int(10) unsigned NOT NULL,
+  `sleep_cron` varchar(255) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `wake_cron` varchar(255) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `timezone` varchar(255) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `scale_to_minimum` tinyint(1) DEFAULT NULL,
+  `next_sleep_at` timestamp NULL DEFAULT NULL,
+  `next_wake_at` timestamp NULL DEFAULT NULL,
+  `node_pool_sizes` text COLLATE utf8mb4_unicode_ci,
+  `created_by` int(10) unsigned DEFAULT NULL,
+  `created_at` timestamp NULL DEFAULT NULL,
+  `updated_at` timestamp NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_cluster_sleep_cluster_id` (`cluster_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
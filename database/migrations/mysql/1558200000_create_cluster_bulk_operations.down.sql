@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `cluster_bulk_operation_clusters`;
+DROP TABLE IF EXISTS `cluster_bulk_operations`;
@@ -0,0 +1,33 @@
+CREATE TABLE `applications` (
+  `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+  `organization_id` int(10) unsigned NOT NULL,
+  `name` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `release_name` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `chart` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `version` varchar(255) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `namespace` varchar(255) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `values` text COLLATE utf8mb4_unicode_ci,
+  `selector` text COLLATE utf8mb4_unicode_ci,
+  `overrides` text COLLATE utf8mb4_unicode_ci,
+  `auto_sync` tinyint(1) DEFAULT NULL,
+  `created_by` int(10) unsigned DEFAULT NULL,
+  `created_at` timestamp NULL DEFAULT NULL,
+  `updated_at` timestamp NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_applications_org_name` (`organization_id`,`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `application_clusters` (
+  `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+  `application_id` int(10) unsigned NOT NULL,
+  `cluster_id` int(10) unsigned NOT NULL,
+  `cluster_name` varchar(255) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `status` varchar(255) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `message` text COLLATE utf8mb4_unicode_ci,
+  `drift` text COLLATE utf8mb4_unicode_ci,
+  `revision` int(11) DEFAULT NULL,
+  `synced_at` timestamp NULL DEFAULT NULL,
+  `checked_at` timestamp NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_application_clusters_application_cluster` (`application_id`,`cluster_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
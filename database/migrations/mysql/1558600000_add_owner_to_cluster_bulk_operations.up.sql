@@ -0,0 +1,2 @@
+ALTER TABLE `cluster_bulk_operations` ADD COLUMN `owner` varchar(255) COLLATE utf8mb4_unicode_ci DEFAULT NULL;
+ALTER TABLE `cluster_bulk_operations` ADD COLUMN `heartbeat_at` timestamp NULL DEFAULT NULL;
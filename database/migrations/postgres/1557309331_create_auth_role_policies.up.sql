@@ -0,0 +1,13 @@
+CREATE TABLE "auth_role_policies" (
+  "id" serial,
+  "organization_id" integer NOT NULL DEFAULT 0,
+  "role" varchar(32) NOT NULL,
+  "path" varchar(255) NOT NULL,
+  "method" varchar(10) NOT NULL,
+  "effect" varchar(5) NOT NULL,
+  PRIMARY KEY ("id")
+);
+
+CREATE UNIQUE INDEX idx_auth_role_policies_unique ON "auth_role_policies"(
+  organization_id, "role", "path", "method", "effect"
+);
@@ -0,0 +1,19 @@
+CREATE TABLE "secret_rotation_policies" (
+  "id" serial,
+  "organization_id" integer NOT NULL,
+  "secret_id" varchar(64) NOT NULL,
+  "interval" varchar(32) NOT NULL,
+  "renew_before" varchar(32),
+  "password_format" varchar(64),
+  "last_rotated_at" timestamp with time zone,
+  "next_rotation_at" timestamp with time zone NOT NULL,
+  "last_error" text,
+  "created_at" timestamp with time zone,
+  "updated_at" timestamp with time zone,
+  PRIMARY KEY ("id")
+);
+
+CREATE UNIQUE INDEX idx_secret_rotation_policies_org_secret ON "secret_rotation_policies"(
+  organization_id, "secret_id"
+);
+CREATE INDEX idx_secret_rotation_policies_next_rotation_at ON "secret_rotation_policies"(next_rotation_at);
@@ -0,0 +1,17 @@
+CREATE TABLE "secret_installations" (
+  "id" serial,
+  "organization_id" integer NOT NULL,
+  "secret_id" varchar(64) NOT NULL,
+  "cluster_id" integer NOT NULL,
+  "namespace" varchar(63) NOT NULL,
+  "name" varchar(253) NOT NULL,
+  "spec" text,
+  "created_at" timestamp with time zone,
+  "updated_at" timestamp with time zone,
+  PRIMARY KEY ("id")
+);
+
+CREATE UNIQUE INDEX idx_secret_installations_unique ON "secret_installations"(
+  organization_id, "secret_id", cluster_id, "namespace", "name"
+);
+CREATE INDEX idx_secret_installations_cluster_id ON "secret_installations"(cluster_id);
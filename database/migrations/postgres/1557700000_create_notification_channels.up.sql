@@ -0,0 +1,36 @@
+CREATE TABLE "notification_channels" (
+  "id" serial,
+  "organization_id" integer NOT NULL,
+  "name" varchar(255) NOT NULL,
+  "type" varchar(255) NOT NULL,
+  "url" text,
+  "secret" varchar(255),
+  "recipients" text,
+  "events" text,
+  "enabled" boolean NOT NULL,
+  "created_at" timestamp with time zone,
+  "updated_at" timestamp with time zone,
+  PRIMARY KEY ("id")
+);
+
+CREATE UNIQUE INDEX idx_notification_channels_org_name ON "notification_channels"(organization_id, "name");
+
+CREATE TABLE "notification_deliveries" (
+  "id" serial,
+  "organization_id" integer NOT NULL,
+  "channel_id" integer NOT NULL,
+  "event" varchar(255) NOT NULL,
+  "payload" text,
+  "status" varchar(255) NOT NULL,
+  "attempts" integer NOT NULL,
+  "last_error" text,
+  "next_attempt_at" timestamp with time zone,
+  "delivered_at" timestamp with time zone,
+  "created_at" timestamp with time zone,
+  "updated_at" timestamp with time zone,
+  PRIMARY KEY ("id")
+);
+
+CREATE INDEX idx_notification_deliveries_org_id ON "notification_deliveries"(organization_id);
+CREATE INDEX idx_notification_deliveries_channel_id ON "notification_deliveries"(channel_id);
+CREATE INDEX idx_notification_deliveries_status_next_attempt ON "notification_deliveries"("status", next_attempt_at);
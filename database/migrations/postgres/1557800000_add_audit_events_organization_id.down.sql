@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_audit_events_organization_id;
+ALTER TABLE "audit_events" DROP COLUMN IF EXISTS "organization_id";
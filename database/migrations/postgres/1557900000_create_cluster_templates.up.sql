@@ -0,0 +1,14 @@
+CREATE TABLE "cluster_templates" (
+  "id" serial,
+  "organization_id" integer NOT NULL,
+  "name" varchar(255) NOT NULL,
+  "version" integer NOT NULL,
+  "description" text,
+  "variables" text,
+  "template" text,
+  "created_by" integer,
+  "created_at" timestamp with time zone,
+  PRIMARY KEY ("id")
+);
+
+CREATE UNIQUE INDEX idx_cluster_templates_org_name_version ON "cluster_templates"(organization_id, "name", "version");
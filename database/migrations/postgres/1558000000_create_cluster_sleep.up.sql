@@ -0,0 +1,17 @@
+CREATE TABLE "cluster_sleep" (
+  "id" serial,
+  "cluster_id" integer NOT NULL,
+  "sleep_cron" varchar(255),
+  "wake_cron" varchar(255),
+  "timezone" varchar(255),
+  "scale_to_minimum" boolean,
+  "next_sleep_at" timestamp with time zone,
+  "next_wake_at" timestamp with time zone,
+  "node_pool_sizes" text,
+  "created_by" integer,
+  "created_at" timestamp with time zone,
+  "updated_at" timestamp with time zone,
+  PRIMARY KEY ("id")
+);
+
+CREATE UNIQUE INDEX idx_cluster_sleep_cluster_id ON "cluster_sleep"(cluster_id);
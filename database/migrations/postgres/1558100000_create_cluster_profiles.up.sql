@@ -0,0 +1,15 @@
+CREATE TABLE "cluster_profiles" (
+  "id" serial,
+  "organization_id" integer NOT NULL,
+  "type" varchar(255) NOT NULL,
+  "name" varchar(255) NOT NULL,
+  "location" varchar(255),
+  "ttl_minutes" integer NOT NULL DEFAULT 0,
+  "properties" text,
+  "created_by" integer,
+  "created_at" timestamp with time zone,
+  "updated_at" timestamp with time zone,
+  PRIMARY KEY ("id")
+);
+
+CREATE UNIQUE INDEX idx_cluster_profiles_org_type_name ON "cluster_profiles"(organization_id, type, name);
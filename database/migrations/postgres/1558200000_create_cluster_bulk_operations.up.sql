@@ -0,0 +1,30 @@
+CREATE TABLE "cluster_bulk_operations" (
+  "id" serial,
+  "organization_id" integer NOT NULL,
+  "action" varchar(255) NOT NULL,
+  "selector" text,
+  "params" text,
+  "concurrency" integer,
+  "status" varchar(255),
+  "created_by" integer,
+  "created_at" timestamp with time zone,
+  "updated_at" timestamp with time zone,
+  "finished_at" timestamp with time zone,
+  PRIMARY KEY ("id")
+);
+
+CREATE INDEX idx_cluster_bulk_operations_organization_id ON "cluster_bulk_operations"(organization_id);
+
+CREATE TABLE "cluster_bulk_operation_clusters" (
+  "id" serial,
+  "operation_id" integer NOT NULL,
+  "cluster_id" integer,
+  "cluster_name" varchar(255),
+  "status" varchar(255),
+  "error" text,
+  "started_at" timestamp with time zone,
+  "finished_at" timestamp with time zone,
+  PRIMARY KEY ("id")
+);
+
+CREATE INDEX idx_cluster_bulk_operation_clusters_operation_id ON "cluster_bulk_operation_clusters"(operation_id);
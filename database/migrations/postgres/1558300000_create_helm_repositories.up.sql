@@ -0,0 +1,14 @@
+CREATE TABLE "helm_repositories" (
+  "id" serial,
+  "organization_id" integer NOT NULL,
+  "name" varchar(255) NOT NULL,
+  "url" varchar(255) NOT NULL,
+  "secret_id" varchar(255),
+  "created_at" timestamp with time zone,
+  "updated_at" timestamp with time zone,
+  "deleted_at" timestamp with time zone,
+  PRIMARY KEY ("id")
+);
+
+CREATE UNIQUE INDEX idx_helm_repositories_org_name ON "helm_repositories"(organization_id, name);
+CREATE INDEX idx_helm_repositories_deleted_at ON "helm_repositories"(deleted_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "application_clusters";
+DROP TABLE IF EXISTS "applications";
@@ -0,0 +1,35 @@
+CREATE TABLE "applications" (
+  "id" serial,
+  "organization_id" integer NOT NULL,
+  "name" varchar(255) NOT NULL,
+  "release_name" varchar(255) NOT NULL,
+  "chart" varchar(255) NOT NULL,
+  "version" varchar(255),
+  "namespace" varchar(255),
+  "values" text,
+  "selector" text,
+  "overrides" text,
+  "auto_sync" boolean,
+  "created_by" integer,
+  "created_at" timestamp with time zone,
+  "updated_at" timestamp with time zone,
+  PRIMARY KEY ("id")
+);
+
+CREATE UNIQUE INDEX idx_applications_org_name ON "applications"(organization_id, name);
+
+CREATE TABLE "application_clusters" (
+  "id" serial,
+  "application_id" integer NOT NULL,
+  "cluster_id" integer NOT NULL,
+  "cluster_name" varchar(255),
+  "status" varchar(255),
+  "message" text,
+  "drift" text,
+  "revision" integer,
+  "synced_at" timestamp with time zone,
+  "checked_at" timestamp with time zone,
+  PRIMARY KEY ("id")
+);
+
+CREATE UNIQUE INDEX idx_application_clusters_application_cluster ON "application_clusters"(application_id, cluster_id);
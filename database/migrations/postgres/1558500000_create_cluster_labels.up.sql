@@ -0,0 +1,11 @@
+CREATE TABLE "cluster_labels" (
+  "id" serial,
+  "cluster_id" integer NOT NULL,
+  "name" varchar(255) NOT NULL,
+  "value" varchar(255),
+  "created_at" timestamp with time zone,
+  "updated_at" timestamp with time zone,
+  PRIMARY KEY ("id")
+);
+
+CREATE UNIQUE INDEX idx_cluster_labels_cluster_id_name ON "cluster_labels"(cluster_id, name);
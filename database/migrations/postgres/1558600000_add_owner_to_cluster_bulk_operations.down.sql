@@ -0,0 +1,2 @@
+ALTER TABLE "cluster_bulk_operations" DROP COLUMN IF EXISTS "heartbeat_at";
+ALTER TABLE "cluster_bulk_operations" DROP COLUMN IF EXISTS "owner";
@@ -0,0 +1,2 @@
+ALTER TABLE "cluster_bulk_operations" ADD COLUMN "owner" varchar(255);
+ALTER TABLE "cluster_bulk_operations" ADD COLUMN "heartbeat_at" timestamp with time zone;
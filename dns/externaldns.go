@@ -18,10 +18,10 @@ import (
 	"sync"
 	"time"
 
+	"github.com/banzaicloud/bank-vaults/pkg/vault"
 	"github.com/banzaicloud/pipeline/config"
 	"github.com/banzaicloud/pipeline/dns/route53"
 	secretTypes "github.com/banzaicloud/pipeline/pkg/secret"
-	"github.com/banzaicloud/pipeline/secret"
 	"github.com/gofrs/uuid"
 	"github.com/spf13/cast"
 	"github.com/spf13/viper"
@@ -78,7 +78,14 @@ func newExternalDnsServiceClientInstance() {
 	// vault kv put secret/banzaicloud/aws AWS_REGION=... AWS_ACCESS_KEY_ID=... AWS_SECRET_ACCESS_KEY=...
 	awsCredentialsPath := viper.GetString(config.AwsCredentialPath)
 
-	secret, err := secret.Store.Logical.Read(awsCredentialsPath)
+	vaultClient, err := vault.NewClient("pipeline")
+	if err != nil {
+		log.Errorf("Failed to create Vault client: %s", err.Error())
+		errCreate = err
+		return
+	}
+
+	secret, err := vaultClient.Vault().Logical().Read(awsCredentialsPath)
 	if err != nil {
 		log.Errorf("Failed to read AWS credentials from Vault: %s", err.Error())
 		errCreate = err
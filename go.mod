@@ -79,6 +79,7 @@ require (
 	github.com/patrickmn/go-cache v2.1.0+incompatible
 	github.com/pelletier/go-toml v1.2.0
 	github.com/pkg/errors v0.8.1
+	github.com/pmezard/go-difflib v1.0.0
 	github.com/prashantv/protectmem v0.0.0-20171002184600-e20412882b3a // indirect
 	github.com/prometheus/client_golang v0.9.2
 	github.com/prometheus/common v0.0.0-20181126121408-4724e9255275
@@ -91,7 +92,7 @@ require (
 	github.com/qor/render v0.0.0-20171201033449-63566e46f01b // indirect
 	github.com/qor/responder v0.0.0-20160314063933-ecae0be66c1a // indirect
 	github.com/qor/session v0.0.0-20170907035918-8206b0adab70
-	github.com/robfig/cron v0.0.0-20180505203441-b41be1df6967 // indirect
+	github.com/robfig/cron v0.0.0-20180505203441-b41be1df6967
 	github.com/russross/blackfriday v1.5.1 // indirect
 	github.com/samuel/go-thrift v0.0.0-20160419172024-e9042807f4f5 // indirect
 	github.com/sirupsen/logrus v1.3.0
@@ -28,6 +28,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"regexp"
+	"sort"
 	"strings"
 	"text/template"
 	"time"
@@ -37,10 +38,12 @@ import (
 	"github.com/banzaicloud/pipeline/pkg/common"
 	pkgHelm "github.com/banzaicloud/pipeline/pkg/helm"
 	"github.com/banzaicloud/pipeline/pkg/k8sclient"
+	"github.com/ghodss/yaml"
 	"github.com/goph/emperror"
 	"github.com/microcosm-cc/bluemonday"
 	"github.com/patrickmn/go-cache"
 	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cast"
 	"github.com/spf13/viper"
@@ -49,7 +52,6 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/helm/pkg/chartutil"
-	"k8s.io/helm/pkg/getter"
 	"k8s.io/helm/pkg/helm"
 	helm_env "k8s.io/helm/pkg/helm/environment"
 	"k8s.io/helm/pkg/proto/hapi/chart"
@@ -80,6 +82,9 @@ var DefaultInstallOptions = []helm.InstallOption{
 	helm.InstallDryRun(false),
 }
 
+// defaultRollbackTimeout is the timeout of a rollback in seconds, the same as the default of an install
+const defaultRollbackTimeout = 300
+
 // DeploymentNotFoundError is returned when a Helm related operation is executed on
 // a deployment (helm release) that doesn't exists
 type DeploymentNotFoundError struct {
@@ -622,6 +627,424 @@ func GetDeploymentByVersion(releaseName string, kubeConfig []byte, version int32
 	}, nil
 }
 
+// GetDeploymentHistory returns the revisions of a helm deployment, the latest one first.
+func GetDeploymentHistory(releaseName string, kubeConfig []byte, max int32) ([]pkgHelm.DeploymentRevision, error) {
+	helmClient, err := pkgHelm.NewClient(kubeConfig, log)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create Helm client")
+	}
+	defer helmClient.Close()
+
+	history, err := helmClient.ReleaseHistory(releaseName, helm.WithMaxHistory(max))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil, &DeploymentNotFoundError{HelmError: err}
+		}
+		return nil, errors.Wrap(err, "failed to get release history")
+	}
+
+	revisions := make([]pkgHelm.DeploymentRevision, 0, len(history.GetReleases()))
+	for _, rel := range history.GetReleases() {
+		metadata := rel.GetChart().GetMetadata()
+
+		revisions = append(revisions, pkgHelm.DeploymentRevision{
+			Revision:     rel.GetVersion(),
+			Chart:        GetVersionedChartName(metadata.GetName(), metadata.GetVersion()),
+			ChartName:    metadata.GetName(),
+			ChartVersion: metadata.GetVersion(),
+			AppVersion:   metadata.GetAppVersion(),
+			Status:       rel.GetInfo().GetStatus().GetCode().String(),
+			Description:  rel.GetInfo().GetDescription(),
+			CreatedAt:    time.Unix(rel.GetInfo().GetFirstDeployed().GetSeconds(), 0),
+			Updated:      time.Unix(rel.GetInfo().GetLastDeployed().GetSeconds(), 0),
+		})
+	}
+
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision > revisions[j].Revision
+	})
+
+	return revisions, nil
+}
+
+// RollbackDeployment rolls a helm deployment back to a previous revision.
+// The response contains the changes of the user supplied values between the current and the target revision.
+func RollbackDeployment(releaseName string, kubeConfig []byte, request pkgHelm.RollbackDeploymentRequest) (*pkgHelm.RollbackDeploymentResponse, error) {
+	helmClient, err := pkgHelm.NewClient(kubeConfig, log)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create Helm client")
+	}
+	defer helmClient.Close()
+
+	current, err := helmClient.ReleaseContent(releaseName)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil, &DeploymentNotFoundError{HelmError: err}
+		}
+		return nil, errors.Wrap(err, "failed to get current release")
+	}
+
+	target, err := helmClient.ReleaseContent(releaseName, helm.ContentReleaseVersion(request.Revision))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil, &DeploymentNotFoundError{HelmError: err}
+		}
+		return nil, errors.Wrapf(err, "failed to get revision %d", request.Revision)
+	}
+
+	currentValues, err := chartutil.ReadValues([]byte(current.GetRelease().GetConfig().GetRaw()))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse current values")
+	}
+
+	targetValues, err := chartutil.ReadValues([]byte(target.GetRelease().GetConfig().GetRaw()))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse target values")
+	}
+
+	timeout := request.Timeout
+	if timeout == 0 {
+		timeout = defaultRollbackTimeout
+	}
+
+	rollback, err := helmClient.RollbackRelease(
+		releaseName,
+		helm.RollbackVersion(request.Revision),
+		helm.RollbackWait(request.Wait),
+		helm.RollbackTimeout(timeout),
+		helm.RollbackForce(request.Force),
+		helm.RollbackRecreate(request.Recreate),
+		helm.RollbackDryRun(request.DryRun),
+		helm.RollbackDescription(fmt.Sprintf("Rollback to %d", request.Revision)),
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to roll back to revision %d", request.Revision)
+	}
+
+	return &pkgHelm.RollbackDeploymentResponse{
+		ReleaseName:  releaseName,
+		Revision:     rollback.GetRelease().GetVersion(),
+		RolledBackTo: request.Revision,
+		Status:       rollback.GetRelease().GetInfo().GetStatus().GetCode().String(),
+		ValuesDiff:   DiffValues(currentValues.AsMap(), targetValues.AsMap()),
+	}, nil
+}
+
+// DiffValues returns the changed values between two sets of helm values.
+// Nested values are keyed by their dot separated path, lists are compared as a whole.
+func DiffValues(from map[string]interface{}, to map[string]interface{}) []pkgHelm.ValueChange {
+	changes := make([]pkgHelm.ValueChange, 0)
+
+	diffValues("", from, to, &changes)
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+
+	return changes
+}
+
+func diffValues(prefix string, from map[string]interface{}, to map[string]interface{}, changes *[]pkgHelm.ValueChange) {
+	keys := make(map[string]bool)
+	for key := range from {
+		keys[key] = true
+	}
+	for key := range to {
+		keys[key] = true
+	}
+
+	for key := range keys {
+		path := prefix + key
+		fromValue, toValue := from[key], to[key]
+
+		fromMap, fromIsMap := fromValue.(map[string]interface{})
+		toMap, toIsMap := toValue.(map[string]interface{})
+
+		switch {
+		case fromIsMap && toIsMap:
+			diffValues(path+".", fromMap, toMap, changes)
+		case !reflect.DeepEqual(fromValue, toValue):
+			*changes = append(*changes, pkgHelm.ValueChange{
+				Key:  path,
+				From: fromValue,
+				To:   toValue,
+			})
+		}
+	}
+}
+
+// DiffDeployment renders an upgrade of a helm deployment without applying it and returns how the resources
+// and the merged values (chart defaults and user supplied values) of the deployed release would change.
+func DiffDeployment(releaseName, chartName, chartVersion string, chartPackage []byte, values []byte, reuseValues bool, kubeConfig []byte, env helm_env.EnvSettings) (*pkgHelm.DeploymentDiffResponse, error) {
+	chartRequested, err := getRequestedChart(releaseName, chartName, chartVersion, chartPackage, env)
+	if err != nil {
+		return nil, fmt.Errorf("error loading chart: %v", err)
+	}
+
+	helmClient, err := pkgHelm.NewClient(kubeConfig, log)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create Helm client")
+	}
+	defer helmClient.Close()
+
+	current, err := helmClient.ReleaseContent(releaseName)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil, &DeploymentNotFoundError{HelmError: err}
+		}
+		return nil, errors.Wrap(err, "failed to get current release")
+	}
+
+	upgrade, err := helmClient.UpdateReleaseFromChart(
+		releaseName,
+		chartRequested,
+		helm.UpdateValueOverrides(values),
+		helm.UpgradeDryRun(true),
+		helm.ReuseValues(reuseValues),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to render upgrade")
+	}
+
+	currentRelease, upgradeRelease := current.GetRelease(), upgrade.GetRelease()
+
+	currentValues, err := chartutil.CoalesceValues(currentRelease.GetChart(), currentRelease.GetConfig())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to merge current values")
+	}
+
+	upgradeValues, err := chartutil.CoalesceValues(upgradeRelease.GetChart(), upgradeRelease.GetConfig())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to merge upgrade values")
+	}
+
+	resources, err := DiffManifests(currentRelease.GetManifest(), upgradeRelease.GetManifest())
+	if err != nil {
+		return nil, err
+	}
+
+	return &pkgHelm.DeploymentDiffResponse{
+		ReleaseName: releaseName,
+		Revision:    currentRelease.GetVersion(),
+		Chart:       GetVersionedChartName(currentRelease.GetChart().GetMetadata().GetName(), currentRelease.GetChart().GetMetadata().GetVersion()),
+		NewChart:    GetVersionedChartName(chartRequested.GetMetadata().GetName(), chartRequested.GetMetadata().GetVersion()),
+		Resources:   resources,
+		ValuesDiff:  DiffValues(currentValues.AsMap(), upgradeValues.AsMap()),
+	}, nil
+}
+
+// manifestResource is a K8s resource of a release manifest
+type manifestResource struct {
+	kind      string
+	name      string
+	namespace string
+	content   string
+
+	// object is the parsed content of Secret resources, which is redacted in diffs
+	object map[string]interface{}
+}
+
+func (r manifestResource) key() string {
+	return fmt.Sprintf("%s/%s/%s", r.namespace, r.kind, r.name)
+}
+
+// manifestSeparatorRegexp matches the lines separating the documents of a release manifest.
+var manifestSeparatorRegexp = regexp.MustCompile(`(?m)^---[ \t]*$`)
+
+// Placeholders of the values of Secret resources in diffs.
+const (
+	redactedSecretValue        = "(redacted)"
+	redactedChangedSecretValue = "(redacted, changed)"
+)
+
+// parseManifestResources splits a release manifest into resources.
+// Documents that cannot be parsed are skipped the same way as ParseReleaseManifest does.
+func parseManifestResources(manifest string) map[string]manifestResource {
+	resources := make(map[string]manifestResource)
+
+	for _, object := range manifestSeparatorRegexp.Split(manifest, -1) {
+		var header struct {
+			Kind     string `json:"kind"`
+			Metadata struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+		}
+
+		if err := yaml.Unmarshal([]byte(object), &header); err != nil {
+			log.Warnf("Error while decoding YAML object. Err was: %s", err)
+			continue
+		}
+
+		// empty documents and templates rendering comments only
+		if header.Kind == "" {
+			continue
+		}
+
+		resource := manifestResource{
+			kind:      header.Kind,
+			name:      header.Metadata.Name,
+			namespace: header.Metadata.Namespace,
+			content:   strings.TrimSpace(object) + "\n",
+		}
+
+		if resource.kind == "Secret" {
+			if err := yaml.Unmarshal([]byte(object), &resource.object); err != nil {
+				log.Warnf("Error while decoding YAML object. Err was: %s", err)
+				continue
+			}
+		}
+
+		resources[resource.key()] = resource
+	}
+
+	return resources
+}
+
+// redactSecret returns the content of a Secret resource with the values of its data replaced by placeholders.
+// Values differing from the ones of the previous version of the secret are marked as changed.
+func redactSecret(secret map[string]interface{}, previous map[string]interface{}) (string, error) {
+	redacted := make(map[string]interface{}, len(secret))
+	for key, value := range secret {
+		redacted[key] = value
+	}
+
+	for _, field := range []string{"data", "stringData"} {
+		values, ok := secret[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		previousValues, _ := previous[field].(map[string]interface{})
+
+		redactedValues := make(map[string]interface{}, len(values))
+		for key, value := range values {
+			redactedValues[key] = redactedSecretValue
+
+			if previousValue, ok := previousValues[key]; ok && !reflect.DeepEqual(value, previousValue) {
+				redactedValues[key] = redactedChangedSecretValue
+			}
+		}
+
+		redacted[field] = redactedValues
+	}
+
+	content, err := yaml.Marshal(redacted)
+
+	return string(content), errors.Wrap(err, "failed to marshal secret")
+}
+
+// DiffManifests returns the unified diff of every added, removed or modified resource between two release manifests.
+// The values of Secret resources are redacted.
+func DiffManifests(from string, to string) ([]pkgHelm.ResourceDiff, error) {
+	fromResources := parseManifestResources(from)
+	toResources := parseManifestResources(to)
+
+	keys := make([]string, 0, len(fromResources)+len(toResources))
+	for key := range fromResources {
+		keys = append(keys, key)
+	}
+	for key := range toResources {
+		if _, ok := fromResources[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	diffs := make([]pkgHelm.ResourceDiff, 0)
+	for _, key := range keys {
+		fromResource, inFrom := fromResources[key]
+		toResource, inTo := toResources[key]
+
+		resource, change := fromResource, pkgHelm.ResourceModified
+		switch {
+		case !inFrom:
+			resource, change = toResource, pkgHelm.ResourceAdded
+		case !inTo:
+			change = pkgHelm.ResourceRemoved
+		case fromResource.content == toResource.content:
+			continue
+		}
+
+		fromContent, toContent := fromResource.content, toResource.content
+		if resource.kind == "Secret" {
+			var err error
+
+			if inFrom {
+				if fromContent, err = redactSecret(fromResource.object, nil); err != nil {
+					return nil, err
+				}
+			}
+
+			if inTo {
+				if toContent, err = redactSecret(toResource.object, fromResource.object); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(fromContent),
+			B:        difflib.SplitLines(toContent),
+			FromFile: "current",
+			ToFile:   "upgrade",
+			Context:  3,
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to diff %s", key)
+		}
+
+		diffs = append(diffs, pkgHelm.ResourceDiff{
+			Kind:      resource.kind,
+			Name:      resource.name,
+			Namespace: resource.namespace,
+			Change:    change,
+			Diff:      diff,
+		})
+	}
+
+	return diffs, nil
+}
+
+// CopyDeployment installs a release of a cluster into another cluster
+// with the same chart package, namespace and user supplied values.
+func CopyDeployment(releaseName string, sourceKubeConfig []byte, targetKubeConfig []byte) error {
+	sourceClient, err := pkgHelm.NewClient(sourceKubeConfig, log)
+	if err != nil {
+		return errors.Wrap(err, "failed to create source Helm client")
+	}
+	defer sourceClient.Close()
+
+	releaseContent, err := sourceClient.ReleaseContent(releaseName)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return &DeploymentNotFoundError{HelmError: err}
+		}
+		return errors.Wrap(err, "failed to get release")
+	}
+
+	rel := releaseContent.GetRelease()
+
+	targetClient, err := pkgHelm.NewClient(targetKubeConfig, log)
+	if err != nil {
+		return errors.Wrap(err, "failed to create Helm client")
+	}
+	defer targetClient.Close()
+
+	installOptions := append(
+		DefaultInstallOptions,
+		helm.ReleaseName(rel.GetName()),
+		helm.ValueOverrides([]byte(rel.GetConfig().GetRaw())),
+	)
+
+	_, err = targetClient.InstallReleaseFromChart(rel.GetChart(), rel.GetNamespace(), installOptions...)
+	if err != nil {
+		return errors.Wrap(err, "failed to install release")
+	}
+
+	return nil
+}
+
 // GetDeploymentStatus retrieves the status of the passed in release name.
 // returns with an error if the release is not found or another error occurs
 // in case of error the status is filled with information to classify the error cause
@@ -713,172 +1136,187 @@ func mergeValues(dest map[string]interface{}, src map[string]interface{}) map[st
 	return dest
 }
 
-// ReposGet returns repo
-func ReposGet(env helm_env.EnvSettings) ([]*repo.Entry, error) {
+// ReposGet returns the helm repositories of an organization
+func ReposGet(orgName string) ([]pkgHelm.Repository, error) {
+	env := GenerateHelmRepoEnv(orgName)
+	store := defaultRepositoryStore()
 
-	repoPath := env.Home.RepositoryFile()
-	log.Debugf("Helm repo path: %s", repoPath)
+	organizationID, err := store.organizationID(orgName)
+	if err != nil {
+		return nil, err
+	}
 
-	f, err := repo.LoadRepositoriesFile(repoPath)
+	models, err := store.list(organizationID)
 	if err != nil {
 		return nil, err
 	}
-	if len(f.Repositories) == 0 {
-		return make([]*repo.Entry, 0), nil
+
+	repositories := make([]pkgHelm.Repository, 0, len(models))
+	for _, model := range models {
+		repositories = append(repositories, pkgHelm.Repository{
+			Name:     model.Name,
+			URL:      model.URL,
+			Cache:    env.Home.CacheIndex(model.Name),
+			SecretID: model.SecretID,
+		})
 	}
 
-	return f.Repositories, nil
+	return repositories, nil
 }
 
-// ReposAdd adds repo(s)
-func ReposAdd(env helm_env.EnvSettings, Hrepo *repo.Entry) (bool, error) {
-	repoFile := env.Home.RepositoryFile()
-	var f *repo.RepoFile
-	if _, err := os.Stat(repoFile); err != nil {
-		log.Infof("Creating %s", repoFile)
-		f = repo.NewRepoFile()
-	} else {
-		f, err = repo.LoadRepositoriesFile(repoFile)
-		if err != nil {
-			return false, errors.Wrap(err, "Cannot create a new ChartRepo")
-		}
-		log.Debugf("Profile file %q loaded.", repoFile)
+// ReposAdd adds a helm repository to an organization, returns false if it already exists
+func ReposAdd(orgName string, request pkgHelm.RepositoryRequest) (bool, error) {
+	if request.Name == "" || request.URL == "" {
+		return false, errors.WithMessage(ErrInvalidRepository, "name and url are required")
 	}
 
-	for _, n := range f.Repositories {
-		log.Debugf("repo: %s", n.Name)
-		if n.Name == Hrepo.Name {
-			return false, nil
-		}
+	env := GenerateHelmRepoEnv(orgName)
+	store := defaultRepositoryStore()
+
+	organizationID, err := store.organizationID(orgName)
+	if err != nil {
+		return false, err
 	}
 
-	c := repo.Entry{
-		Name:  Hrepo.Name,
-		URL:   Hrepo.URL,
-		Cache: env.Home.CacheIndex(Hrepo.Name),
+	if _, err := store.get(organizationID, request.Name); err == nil {
+		return false, nil
+	} else if err != ErrRepoNotFound {
+		return false, err
 	}
-	r, err := repo.NewChartRepository(&c, getter.All(env))
-	if err != nil {
-		return false, errors.Wrap(err, "Cannot create a new ChartRepo")
+
+	model := RepositoryModel{
+		OrganizationID: organizationID,
+		Name:           request.Name,
+		URL:            request.URL,
+		SecretID:       request.SecretID,
 	}
-	log.Debugf("New repo added: %s", Hrepo.Name)
 
-	errIdx := r.DownloadIndexFile("")
-	if errIdx != nil {
-		return false, errors.Wrap(errIdx, "Repo index download failed")
+	if err := verifyRepository(env, model); err != nil {
+		return false, err
 	}
-	f.Add(&c)
-	if errW := f.WriteFile(repoFile, 0644); errW != nil {
-		return false, errors.Wrap(errW, "Cannot write helm repo profile file")
+
+	if err := store.create(&model); err != nil {
+		return false, err
 	}
-	return true, nil
+	log.Debugf("New repo added: %s", request.Name)
+
+	return true, syncRepositories(store, env, orgName)
 }
 
-// ReposDelete deletes repo(s)
-func ReposDelete(env helm_env.EnvSettings, repoName string) error {
-	repoFile := env.Home.RepositoryFile()
-	log.Debugf("Repo File: %s", repoFile)
+// ReposDelete deletes a helm repository of an organization
+func ReposDelete(orgName string, repoName string) error {
+	env := GenerateHelmRepoEnv(orgName)
+	store := defaultRepositoryStore()
 
-	r, err := repo.LoadRepositoriesFile(repoFile)
+	organizationID, err := store.organizationID(orgName)
 	if err != nil {
 		return err
 	}
 
-	if !r.Remove(repoName) {
-		return ErrRepoNotFound
-	}
-	if err := r.WriteFile(repoFile, 0644); err != nil {
+	model, err := store.get(organizationID, repoName)
+	if err != nil {
 		return err
 	}
 
-	if _, err := os.Stat(env.Home.CacheIndex(repoName)); err == nil {
-		err = os.Remove(env.Home.CacheIndex(repoName))
-		if err != nil {
-			return err
-		}
+	if err := store.delete(model); err != nil {
+		return err
 	}
-	return nil
 
-}
+	removeLocalRepository(env, repoName)
 
-// ReposModify modifies repo(s)
-func ReposModify(env helm_env.EnvSettings, repoName string, newRepo *repo.Entry) error {
+	return syncRepositories(store, env, orgName)
+}
 
-	log.Debug("ReposModify")
-	repoFile := env.Home.RepositoryFile()
-	log.Debugf("Repo File: %s", repoFile)
-	log.Debugf("New repo content: %#v", newRepo)
+// ReposModify modifies a helm repository of an organization, empty fields of the request are left unchanged
+func ReposModify(orgName string, repoName string, request pkgHelm.RepositoryRequest) error {
+	env := GenerateHelmRepoEnv(orgName)
+	store := defaultRepositoryStore()
 
-	f, err := repo.LoadRepositoriesFile(repoFile)
+	organizationID, err := store.organizationID(orgName)
 	if err != nil {
 		return err
 	}
 
-	if !f.Has(repoName) {
-		return ErrRepoNotFound
+	model, err := store.get(organizationID, repoName)
+	if err != nil {
+		return err
 	}
 
-	var formerRepo *repo.Entry
-	repos := f.Repositories
-	for _, r := range repos {
-		if r.Name == repoName {
-			formerRepo = r
+	if request.Name != "" && request.Name != model.Name {
+		if _, err := store.get(organizationID, request.Name); err == nil {
+			return errors.WithMessage(ErrInvalidRepository, fmt.Sprintf("repository %q already exists", request.Name))
 		}
+
+		model.Name = request.Name
 	}
 
-	if formerRepo != nil {
-		if len(newRepo.Name) == 0 {
-			newRepo.Name = formerRepo.Name
-			log.Infof("new repo name field is empty, replaced with: %s", formerRepo.Name)
-		}
+	if request.URL != "" {
+		model.URL = request.URL
+	}
 
-		if len(newRepo.URL) == 0 {
-			newRepo.URL = formerRepo.URL
-			log.Infof("new repo url field is empty, replaced with: %s", formerRepo.URL)
-		}
+	if request.SecretID != "" {
+		model.SecretID = request.SecretID
+	}
 
-		if len(newRepo.Cache) == 0 {
-			newRepo.Cache = formerRepo.Cache
-			log.Infof("new repo cache field is empty, replaced with: %s", formerRepo.Cache)
-		}
+	if err := verifyRepository(env, *model); err != nil {
+		return err
 	}
 
-	f.Update(newRepo)
+	if err := store.update(model); err != nil {
+		return err
+	}
 
-	if errW := f.WriteFile(repoFile, 0644); errW != nil {
-		return errors.Wrap(errW, "Cannot write helm repo profile file")
+	if model.Name != repoName {
+		removeLocalRepository(env, repoName)
 	}
-	return nil
+
+	return syncRepositories(store, env, orgName)
 }
 
-// ReposUpdate updates a repo(s)
-func ReposUpdate(env helm_env.EnvSettings, repoName string) error {
+// ReposUpdate downloads the index of a helm repository and makes every Pipeline instance refresh it
+func ReposUpdate(orgName string, repoName string) error {
+	env := GenerateHelmRepoEnv(orgName)
+	store := defaultRepositoryStore()
 
-	repoFile := env.Home.RepositoryFile()
-	log.Debugf("Repo File: %s", repoFile)
+	organizationID, err := store.organizationID(orgName)
+	if err != nil {
+		return err
+	}
 
-	f, err := repo.LoadRepositoriesFile(repoFile)
+	model, err := store.get(organizationID, repoName)
+	if err != nil {
+		return err
+	}
 
+	entry, err := repositoryEntry(env, organizationID, *model)
 	if err != nil {
-		return errors.Wrap(err, "Load ChartRepo")
+		return err
 	}
 
-	for _, cfg := range f.Repositories {
-		if cfg.Name == repoName {
-			c, err := repo.NewChartRepository(cfg, getter.All(env))
-			if err != nil {
-				return errors.Wrap(err, "Cannot get ChartRepo")
-			}
-			errIdx := c.DownloadIndexFile("")
-			if errIdx != nil {
-				return errors.Wrap(errIdx, "Repo index download failed")
-			}
-			return nil
+	if err := downloadIndex(env, entry); err != nil {
+		return err
+	}
 
-		}
+	return store.touch(model)
+}
+
+// verifyRepository checks that the index of a repository can be downloaded with its credentials
+func verifyRepository(env helm_env.EnvSettings, model RepositoryModel) error {
+	entry, err := repositoryEntry(env, model.OrganizationID, model)
+	if err != nil {
+		return err
 	}
 
-	return ErrRepoNotFound
+	return errors.WithMessage(downloadIndex(env, entry), ErrInvalidRepository.Error())
+}
+
+// removeLocalRepository removes the cached index and the certificates of a repository
+func removeLocalRepository(env helm_env.EnvSettings, repoName string) {
+	for _, path := range []string{env.Home.CacheIndex(repoName), filepath.Join(env.Home.Repository(), "certs", repoName)} {
+		if err := os.RemoveAll(path); err != nil {
+			log.Warnf("failed to remove %s: %s", path, err.Error())
+		}
+	}
 }
 
 // ChartList describe a chart list
@@ -18,9 +18,13 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pkgHelm "github.com/banzaicloud/pipeline/pkg/helm"
 )
 
 func TestDownloadFile_TooBig(t *testing.T) {
@@ -32,3 +36,195 @@ func TestDownloadFile_TooBig(t *testing.T) {
 	_, err := DownloadFile(ts.URL)
 	assert.EqualError(t, err, "chart data is too big")
 }
+
+func TestDiffValues(t *testing.T) {
+	from := map[string]interface{}{
+		"replicaCount": 3,
+		"image": map[string]interface{}{
+			"repository": "nginx",
+			"tag":        "1.15",
+		},
+		"ingress": map[string]interface{}{
+			"enabled": true,
+			"hosts":   []interface{}{"a.example.com"},
+		},
+		"debug": true,
+	}
+
+	to := map[string]interface{}{
+		"replicaCount": 3,
+		"image": map[string]interface{}{
+			"repository": "nginx",
+			"tag":        "1.14",
+		},
+		"ingress": map[string]interface{}{
+			"enabled": true,
+			"hosts":   []interface{}{"a.example.com", "b.example.com"},
+		},
+		"resources": map[string]interface{}{
+			"limits": map[string]interface{}{"cpu": "100m"},
+		},
+	}
+
+	assert.Equal(t, []pkgHelm.ValueChange{
+		{Key: "debug", From: true},
+		{Key: "image.tag", From: "1.15", To: "1.14"},
+		{Key: "ingress.hosts", From: []interface{}{"a.example.com"}, To: []interface{}{"a.example.com", "b.example.com"}},
+		{Key: "resources", To: map[string]interface{}{"limits": map[string]interface{}{"cpu": "100m"}}},
+	}, DiffValues(from, to))
+
+	assert.Empty(t, DiffValues(from, from))
+}
+
+func TestDiffManifests(t *testing.T) {
+	from := `
+---
+# Source: app/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+spec:
+  ports:
+  - port: 80
+---
+# Source: app/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 1
+---
+# Source: app/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app
+  namespace: apps
+`
+
+	to := `
+---
+# Source: app/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+spec:
+  ports:
+  - port: 80
+---
+# Source: app/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 2
+---
+# Source: app/templates/ingress.yaml
+apiVersion: extensions/v1beta1
+kind: Ingress
+metadata:
+  name: app
+`
+
+	diffs, err := DiffManifests(from, to)
+	require.NoError(t, err)
+	require.Len(t, diffs, 3)
+
+	assert.Equal(t, "Deployment", diffs[0].Kind)
+	assert.Equal(t, pkgHelm.ResourceModified, diffs[0].Change)
+	assert.Contains(t, diffs[0].Diff, "-  replicas: 1\n+  replicas: 2\n")
+
+	assert.Equal(t, "Ingress", diffs[1].Kind)
+	assert.Equal(t, pkgHelm.ResourceAdded, diffs[1].Change)
+	assert.Contains(t, diffs[1].Diff, "+kind: Ingress\n")
+
+	assert.Equal(t, "ConfigMap", diffs[2].Kind)
+	assert.Equal(t, "apps", diffs[2].Namespace)
+	assert.Equal(t, pkgHelm.ResourceRemoved, diffs[2].Change)
+	assert.Contains(t, diffs[2].Diff, "-kind: ConfigMap\n")
+
+	diffs, err = DiffManifests(from, from)
+	require.NoError(t, err)
+	assert.Empty(t, diffs)
+}
+
+func TestDiffManifests_Separators(t *testing.T) {
+	from := `
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app
+data:
+  banner: "--- welcome ---"
+  script: |
+    echo start
+    ---
+    echo end
+---
+this is: [not valid yaml
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+`
+
+	to := strings.Replace(from, "echo end", "echo done", 1)
+
+	diffs, err := DiffManifests(from, to)
+	require.NoError(t, err)
+	require.Len(t, diffs, 1)
+
+	assert.Equal(t, "ConfigMap", diffs[0].Kind)
+	assert.Equal(t, pkgHelm.ResourceModified, diffs[0].Change)
+	assert.Contains(t, diffs[0].Diff, "-    echo end\n+    echo done\n")
+}
+
+func TestDiffManifests_Secrets(t *testing.T) {
+	from := `
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: app
+data:
+  password: c2VjcmV0
+  username: YWRtaW4=
+`
+
+	to := `
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: app
+data:
+  password: bmV3LXNlY3JldA==
+  username: YWRtaW4=
+stringData:
+  token: plain-token
+`
+
+	diffs, err := DiffManifests(from, to)
+	require.NoError(t, err)
+	require.Len(t, diffs, 1)
+
+	diff := diffs[0].Diff
+	assert.Equal(t, "Secret", diffs[0].Kind)
+	assert.Contains(t, diff, "-  password: (redacted)\n+  password: (redacted, changed)\n")
+	assert.Contains(t, diff, "+  token: (redacted)\n")
+	for _, value := range []string{"c2VjcmV0", "YWRtaW4=", "bmV3LXNlY3JldA==", "plain-token"} {
+		assert.NotContains(t, diff, value)
+	}
+
+	diffs, err = DiffManifests("", to)
+	require.NoError(t, err)
+	require.Len(t, diffs, 1)
+	assert.Equal(t, pkgHelm.ResourceAdded, diffs[0].Change)
+	assert.NotContains(t, diffs[0].Diff, "bmV3LXNlY3JldA==")
+}
@@ -40,7 +40,6 @@ import (
 	"k8s.io/helm/pkg/getter"
 	helmEnv "k8s.io/helm/pkg/helm/environment"
 	"k8s.io/helm/pkg/helm/helmpath"
-	"k8s.io/helm/pkg/repo"
 )
 
 //PreInstall create's serviceAccount and AccountRoleBinding
@@ -193,7 +192,15 @@ func GenerateHelmRepoEnv(orgName string) (env helmEnv.EnvSettings) {
 	// check local helm
 	if _, err := os.Stat(helmPath); os.IsNotExist(err) {
 		log.Infof("Helm directories [%s] not exists", helmPath)
-		InstallLocalHelm(env)
+		if err := InstallLocalHelm(env); err != nil {
+			log.Errorf("Helm install failed: %s", err.Error())
+			return
+		}
+	}
+
+	// repositories are stored in the database, the local files only serve as a cache for helm
+	if err := syncRepositories(defaultRepositoryStore(), env, orgName); err != nil {
+		log.Errorf("failed to sync helm repositories of organization %q: %s", orgName, err.Error())
 	}
 
 	return
@@ -262,36 +269,6 @@ func EnsureDirectories(env helmEnv.EnvSettings) error {
 	return nil
 }
 
-func ensureDefaultRepos(env helmEnv.EnvSettings) error {
-
-	stableRepositoryURL := viper.GetString("helm.stableRepositoryURL")
-	banzaiRepositoryURL := viper.GetString("helm.banzaiRepositoryURL")
-
-	log.Infof("Setting up default helm repos.")
-
-	_, err := ReposAdd(
-		env,
-		&repo.Entry{
-			Name:  phelm.StableRepository,
-			URL:   stableRepositoryURL,
-			Cache: env.Home.CacheIndex(phelm.StableRepository),
-		})
-	if err != nil {
-		return errors.Wrapf(err, "cannot init repo: %s", phelm.StableRepository)
-	}
-	_, err = ReposAdd(
-		env,
-		&repo.Entry{
-			Name:  phelm.BanzaiRepository,
-			URL:   banzaiRepositoryURL,
-			Cache: env.Home.CacheIndex(phelm.BanzaiRepository),
-		})
-	if err != nil {
-		return errors.Wrapf(err, "cannot init repo: %s", phelm.BanzaiRepository)
-	}
-	return nil
-}
-
 // InstallLocalHelm install helm into the given path
 func InstallLocalHelm(env helmEnv.EnvSettings) error {
 	if err := InstallHelmClient(env); err != nil {
@@ -299,9 +276,6 @@ func InstallLocalHelm(env helmEnv.EnvSettings) error {
 	}
 	log.Info("Helm client install succeeded")
 
-	if err := ensureDefaultRepos(env); err != nil {
-		return errors.Wrap(err, "Setting up default repos failed!")
-	}
 	return nil
 }
 
@@ -0,0 +1,328 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helm
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/banzaicloud/pipeline/config"
+	pkgHelm "github.com/banzaicloud/pipeline/pkg/helm"
+	secretTypes "github.com/banzaicloud/pipeline/pkg/secret"
+	"github.com/banzaicloud/pipeline/secret"
+	"github.com/goph/emperror"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"k8s.io/helm/pkg/getter"
+	helm_env "k8s.io/helm/pkg/helm/environment"
+	"k8s.io/helm/pkg/repo"
+)
+
+// TableName constants
+const (
+	repositoryTableName = "helm_repositories"
+)
+
+// ErrInvalidRepository is returned when a helm repository cannot be used.
+var ErrInvalidRepository = errors.New("invalid helm repository")
+
+// RepositoryModel stores a helm chart repository of an organization.
+// Deleted repositories are kept (soft deleted) to tell apart organizations without repositories
+// from organizations whose repositories have never been set up.
+type RepositoryModel struct {
+	ID             uint   `gorm:"primary_key"`
+	OrganizationID uint   `gorm:"not null;unique_index:idx_helm_repositories_org_name"`
+	Name           string `gorm:"not null;unique_index:idx_helm_repositories_org_name"`
+	URL            string `gorm:"not null"`
+	SecretID       string
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time `sql:"index"`
+}
+
+// TableName changes the default table name.
+func (RepositoryModel) TableName() string {
+	return repositoryTableName
+}
+
+// organization is the part of the organization model needed to look organizations up by name.
+type organization struct {
+	ID   uint
+	Name string
+}
+
+// TableName changes the default table name.
+func (organization) TableName() string {
+	return "organizations"
+}
+
+// repositoryStore persists the helm repositories of organizations.
+type repositoryStore struct {
+	db *gorm.DB
+}
+
+func newRepositoryStore(db *gorm.DB) *repositoryStore {
+	return &repositoryStore{
+		db: db,
+	}
+}
+
+func (s *repositoryStore) organizationID(orgName string) (uint, error) {
+	var org organization
+
+	if err := s.db.Where(organization{Name: orgName}).First(&org).Error; err != nil {
+		return 0, emperror.WrapWith(err, "failed to get organization", "organization", orgName)
+	}
+
+	return org.ID, nil
+}
+
+// initialized tells whether the repositories of an organization have been set up, even if all of them were deleted since.
+func (s *repositoryStore) initialized(organizationID uint) (bool, error) {
+	var count int
+
+	err := s.db.Unscoped().Model(&RepositoryModel{}).Where("organization_id = ?", organizationID).Count(&count).Error
+	if err != nil {
+		return false, emperror.WrapWith(err, "failed to count helm repositories", "organizationId", organizationID)
+	}
+
+	return count > 0, nil
+}
+
+func (s *repositoryStore) list(organizationID uint) ([]RepositoryModel, error) {
+	var models []RepositoryModel
+
+	err := s.db.Where(RepositoryModel{OrganizationID: organizationID}).Order("name").Find(&models).Error
+
+	return models, emperror.WrapWith(err, "failed to list helm repositories", "organizationId", organizationID)
+}
+
+func (s *repositoryStore) get(organizationID uint, name string) (*RepositoryModel, error) {
+	var model RepositoryModel
+
+	err := s.db.Where(RepositoryModel{OrganizationID: organizationID, Name: name}).First(&model).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, ErrRepoNotFound
+	}
+	if err != nil {
+		return nil, emperror.WrapWith(err, "failed to get helm repository", "organizationId", organizationID, "repository", name)
+	}
+
+	return &model, nil
+}
+
+func (s *repositoryStore) create(model *RepositoryModel) error {
+	// a deleted repository with the same name would violate the unique index
+	err := s.db.Unscoped().
+		Where("organization_id = ? AND name = ? AND deleted_at IS NOT NULL", model.OrganizationID, model.Name).
+		Delete(&RepositoryModel{}).Error
+	if err != nil {
+		return emperror.WrapWith(err, "failed to purge deleted helm repository", "repository", model.Name)
+	}
+
+	return emperror.WrapWith(s.db.Create(model).Error, "failed to create helm repository", "repository", model.Name)
+}
+
+func (s *repositoryStore) update(model *RepositoryModel) error {
+	return emperror.WrapWith(s.db.Save(model).Error, "failed to update helm repository", "repository", model.Name)
+}
+
+func (s *repositoryStore) delete(model *RepositoryModel) error {
+	return emperror.WrapWith(s.db.Delete(model).Error, "failed to delete helm repository", "repository", model.Name)
+}
+
+// touch marks the index of a repository outdated on every Pipeline instance.
+func (s *repositoryStore) touch(model *RepositoryModel) error {
+	err := s.db.Model(model).Update("updated_at", time.Now()).Error
+
+	return emperror.WrapWith(err, "failed to update helm repository", "repository", model.Name)
+}
+
+// repositorySyncLock serializes writing the local helm repository files.
+// nolint: gochecknoglobals
+var repositorySyncLock sync.Mutex
+
+// syncRepositories materializes the repositories of an organization stored in the database as a local helm
+// repository file, including the credentials of private repositories, and downloads outdated index files.
+func syncRepositories(store *repositoryStore, env helm_env.EnvSettings, orgName string) error {
+	organizationID, err := store.organizationID(orgName)
+	if err != nil {
+		return err
+	}
+
+	repositorySyncLock.Lock()
+	defer repositorySyncLock.Unlock()
+
+	if err := seedRepositories(store, env, organizationID); err != nil {
+		return err
+	}
+
+	models, err := store.list(organizationID)
+	if err != nil {
+		return err
+	}
+
+	repoFile := repo.NewRepoFile()
+	for _, model := range models {
+		entry, err := repositoryEntry(env, organizationID, model)
+		if err != nil {
+			log.Errorf("skipping helm repository %q: %s", model.Name, err.Error())
+			continue
+		}
+
+		if indexOutdated(entry.Cache, model.UpdatedAt) {
+			if err := downloadIndex(env, entry); err != nil {
+				log.Errorf("failed to download index of helm repository %q: %s", model.Name, err.Error())
+			}
+		}
+
+		repoFile.Add(entry)
+	}
+
+	// the file may contain credentials
+	return errors.Wrap(repoFile.WriteFile(env.Home.RepositoryFile(), 0600), "failed to write helm repository file")
+}
+
+// seedRepositories sets up the repositories of an organization when it is used for the first time.
+// Repositories of a local repository file written by earlier Pipeline versions are imported,
+// otherwise the default repositories are added.
+func seedRepositories(store *repositoryStore, env helm_env.EnvSettings, organizationID uint) error {
+	initialized, err := store.initialized(organizationID)
+	if err != nil || initialized {
+		return err
+	}
+
+	repositories := defaultRepositories()
+
+	if localFile, err := repo.LoadRepositoriesFile(env.Home.RepositoryFile()); err == nil && len(localFile.Repositories) > 0 {
+		repositories = nil
+		for _, entry := range localFile.Repositories {
+			repositories = append(repositories, RepositoryModel{Name: entry.Name, URL: entry.URL})
+		}
+	}
+
+	for _, model := range repositories {
+		model.OrganizationID = organizationID
+
+		if err := store.create(&model); err != nil {
+			// another Pipeline instance may have seeded the repositories at the same time
+			if _, getErr := store.get(organizationID, model.Name); getErr == nil {
+				continue
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+func defaultRepositories() []RepositoryModel {
+	return []RepositoryModel{
+		{
+			Name: pkgHelm.StableRepository,
+			URL:  viper.GetString("helm.stableRepositoryURL"),
+		},
+		{
+			Name: pkgHelm.BanzaiRepository,
+			URL:  viper.GetString("helm.banzaiRepositoryURL"),
+		},
+	}
+}
+
+// repositoryEntry returns the helm repository file entry of a repository.
+func repositoryEntry(env helm_env.EnvSettings, organizationID uint, model RepositoryModel) (*repo.Entry, error) {
+	entry := &repo.Entry{
+		Name:  model.Name,
+		URL:   model.URL,
+		Cache: env.Home.CacheIndex(model.Name),
+	}
+
+	if model.SecretID == "" {
+		return entry, nil
+	}
+
+	repoSecret, err := secret.Store.Get(organizationID, model.SecretID)
+	if err != nil {
+		return nil, emperror.WrapWith(err, "failed to get repository secret", "secretId", model.SecretID)
+	}
+
+	switch repoSecret.Type {
+	case secretTypes.PasswordSecretType:
+		entry.Username = repoSecret.Values[secretTypes.Username]
+		entry.Password = repoSecret.Values[secretTypes.Password]
+	case secretTypes.TLSSecretType:
+		certDir := filepath.Join(env.Home.Repository(), "certs", model.Name)
+		if err := os.MkdirAll(certDir, 0700); err != nil {
+			return nil, errors.Wrap(err, "failed to create certificate directory")
+		}
+
+		files := []struct {
+			key   string
+			file  string
+			field *string
+		}{
+			{key: secretTypes.CACert, file: "ca.crt", field: &entry.CAFile},
+			{key: secretTypes.ClientCert, file: "client.crt", field: &entry.CertFile},
+			{key: secretTypes.ClientKey, file: "client.key", field: &entry.KeyFile},
+		}
+
+		for _, f := range files {
+			value := repoSecret.Values[f.key]
+			if value == "" {
+				continue
+			}
+
+			path := filepath.Join(certDir, f.file)
+			if err := ioutil.WriteFile(path, []byte(value), 0600); err != nil {
+				return nil, errors.Wrapf(err, "failed to write %s", f.file)
+			}
+
+			*f.field = path
+		}
+	default:
+		return nil, errors.WithMessage(ErrInvalidRepository, "repository secrets must be of password or tls type")
+	}
+
+	return entry, nil
+}
+
+// indexOutdated tells whether a cached index file is missing or older than the last change of its repository.
+func indexOutdated(cacheFile string, updatedAt time.Time) bool {
+	info, err := os.Stat(cacheFile)
+	if err != nil {
+		return true
+	}
+
+	return info.ModTime().Before(updatedAt)
+}
+
+func downloadIndex(env helm_env.EnvSettings, entry *repo.Entry) error {
+	chartRepository, err := repo.NewChartRepository(entry, getter.All(env))
+	if err != nil {
+		return errors.Wrap(err, "Cannot create a new ChartRepo")
+	}
+
+	return errors.Wrap(chartRepository.DownloadIndexFile(""), "Repo index download failed")
+}
+
+func defaultRepositoryStore() *repositoryStore {
+	return newRepositoryStore(config.DB())
+}
@@ -0,0 +1,164 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helm
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pkgHelm "github.com/banzaicloud/pipeline/pkg/helm"
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	helm_env "k8s.io/helm/pkg/helm/environment"
+	"k8s.io/helm/pkg/repo"
+)
+
+func newTestRepositoryStore(t *testing.T) *repositoryStore {
+	db, err := gorm.Open("sqlite3", "file::memory:")
+	require.NoError(t, err)
+
+	require.NoError(t, db.AutoMigrate(&organization{}, &RepositoryModel{}).Error)
+	require.NoError(t, db.Create(&organization{ID: 1, Name: "example"}).Error)
+
+	return newRepositoryStore(db)
+}
+
+func newTestEnv(t *testing.T) (helm_env.EnvSettings, func()) {
+	dir, err := ioutil.TempDir("", "helm")
+	require.NoError(t, err)
+
+	env := CreateEnvSettings(dir)
+	require.NoError(t, EnsureDirectories(env))
+
+	return env, func() { os.RemoveAll(dir) }
+}
+
+func TestRepositoryStore(t *testing.T) {
+	store := newTestRepositoryStore(t)
+
+	organizationID, err := store.organizationID("example")
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), organizationID)
+
+	_, err = store.organizationID("unknown")
+	assert.Error(t, err)
+
+	initialized, err := store.initialized(organizationID)
+	require.NoError(t, err)
+	assert.False(t, initialized)
+
+	model := RepositoryModel{OrganizationID: organizationID, Name: "private", URL: "https://charts.example.com", SecretID: "secret"}
+	require.NoError(t, store.create(&model))
+	require.NoError(t, store.create(&RepositoryModel{OrganizationID: organizationID, Name: "other", URL: "https://other.example.com"}))
+
+	models, err := store.list(organizationID)
+	require.NoError(t, err)
+	require.Len(t, models, 2)
+	assert.Equal(t, "other", models[0].Name)
+	assert.Equal(t, "private", models[1].Name)
+
+	stored, err := store.get(organizationID, "private")
+	require.NoError(t, err)
+	assert.Equal(t, "https://charts.example.com", stored.URL)
+	assert.Equal(t, "secret", stored.SecretID)
+
+	stored.URL = "https://new.example.com"
+	require.NoError(t, store.update(stored))
+
+	stored, err = store.get(organizationID, "private")
+	require.NoError(t, err)
+	assert.Equal(t, "https://new.example.com", stored.URL)
+
+	require.NoError(t, store.delete(stored))
+
+	_, err = store.get(organizationID, "private")
+	assert.Equal(t, ErrRepoNotFound, err)
+
+	// a deleted repository can be added again
+	require.NoError(t, store.create(&RepositoryModel{OrganizationID: organizationID, Name: "private", URL: "https://charts.example.com"}))
+
+	stored, err = store.get(organizationID, "private")
+	require.NoError(t, err)
+	require.NoError(t, store.delete(stored))
+
+	other, err := store.get(organizationID, "other")
+	require.NoError(t, err)
+	require.NoError(t, store.delete(other))
+
+	initialized, err = store.initialized(organizationID)
+	require.NoError(t, err)
+	assert.True(t, initialized, "organizations with deleted repositories must be kept initialized")
+}
+
+func TestSeedRepositories_Defaults(t *testing.T) {
+	store := newTestRepositoryStore(t)
+	env, cleanup := newTestEnv(t)
+	defer cleanup()
+
+	require.NoError(t, seedRepositories(store, env, 1))
+
+	models, err := store.list(1)
+	require.NoError(t, err)
+	require.Len(t, models, 2)
+	assert.Equal(t, pkgHelm.BanzaiRepository, models[0].Name)
+	assert.Equal(t, pkgHelm.StableRepository, models[1].Name)
+
+	// seeding again is a no-op, even if every repository is deleted
+	for i := range models {
+		require.NoError(t, store.delete(&models[i]))
+	}
+	require.NoError(t, seedRepositories(store, env, 1))
+
+	models, err = store.list(1)
+	require.NoError(t, err)
+	assert.Empty(t, models)
+}
+
+func TestSeedRepositories_LocalFile(t *testing.T) {
+	store := newTestRepositoryStore(t)
+	env, cleanup := newTestEnv(t)
+	defer cleanup()
+
+	repoFile := repo.NewRepoFile()
+	repoFile.Add(&repo.Entry{Name: "legacy", URL: "https://legacy.example.com"})
+	require.NoError(t, repoFile.WriteFile(env.Home.RepositoryFile(), 0644))
+
+	require.NoError(t, seedRepositories(store, env, 1))
+
+	models, err := store.list(1)
+	require.NoError(t, err)
+	require.Len(t, models, 1)
+	assert.Equal(t, "legacy", models[0].Name)
+	assert.Equal(t, "https://legacy.example.com", models[0].URL)
+}
+
+func TestIndexOutdated(t *testing.T) {
+	env, cleanup := newTestEnv(t)
+	defer cleanup()
+
+	cacheFile := filepath.Join(env.Home.Cache(), "example-index.yaml")
+
+	assert.True(t, indexOutdated(cacheFile, time.Now()))
+
+	require.NoError(t, ioutil.WriteFile(cacheFile, []byte("apiVersion: v1"), 0644))
+
+	assert.False(t, indexOutdated(cacheFile, time.Now().Add(-time.Hour)))
+	assert.True(t, indexOutdated(cacheFile, time.Now().Add(time.Hour)))
+}
@@ -0,0 +1,154 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package application
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/banzaicloud/pipeline/internal/clusterbulk"
+	pkgHelm "github.com/banzaicloud/pipeline/pkg/helm"
+)
+
+// ErrApplicationNotFound is returned when an application cannot be found.
+var ErrApplicationNotFound = errors.New("application not found")
+
+// ErrApplicationAlreadyExists is returned when an application with the same name already exists.
+var ErrApplicationAlreadyExists = errors.New("application already exists")
+
+// ErrInvalidApplication is returned when an application request is invalid.
+var ErrInvalidApplication = errors.New("invalid application")
+
+// SyncStatus is the sync status of an application on a cluster.
+type SyncStatus string
+
+// Application sync statuses
+const (
+	SyncStatusPending   SyncStatus = "PENDING"
+	SyncStatusSynced    SyncStatus = "SYNCED"
+	SyncStatusOutOfSync SyncStatus = "OUT_OF_SYNC"
+	SyncStatusFailed    SyncStatus = "FAILED"
+)
+
+// Request describes an application create/update request.
+// Overrides are keyed by cluster name and merged into the values of the application on the given cluster.
+type Request struct {
+	Name        string                            `json:"name" binding:"required"`
+	ReleaseName string                            `json:"releaseName,omitempty"`
+	Chart       string                            `json:"chart" binding:"required"`
+	Version     string                            `json:"version,omitempty"`
+	Namespace   string                            `json:"namespace,omitempty"`
+	Values      map[string]interface{}            `json:"values,omitempty"`
+	Selector    clusterbulk.Selector              `json:"selector"`
+	Overrides   map[string]map[string]interface{} `json:"overrides,omitempty"`
+	AutoSync    bool                              `json:"autoSync,omitempty"`
+}
+
+// Validate checks the chart and the cluster selector of the application.
+func (r Request) Validate() error {
+	if !strings.Contains(r.Chart, "/") {
+		return errors.WithMessage(ErrInvalidApplication, "chart must be in repository/name format")
+	}
+
+	if r.Selector.Empty() {
+		return errors.WithMessage(ErrInvalidApplication, "at least one selector criterion is required")
+	}
+
+	for _, name := range r.Selector.Names {
+		if _, err := path.Match(name, ""); err != nil {
+			return errors.WithMessage(ErrInvalidApplication, fmt.Sprintf("invalid name pattern %q", name))
+		}
+	}
+
+	return nil
+}
+
+// Application is a chart deployed with the same values on every cluster matching its selector.
+type Application struct {
+	ID             uint                              `json:"id"`
+	OrganizationID uint                              `json:"-"`
+	Name           string                            `json:"name"`
+	ReleaseName    string                            `json:"releaseName"`
+	Chart          string                            `json:"chart"`
+	Version        string                            `json:"version,omitempty"`
+	Namespace      string                            `json:"namespace,omitempty"`
+	Values         map[string]interface{}            `json:"values,omitempty"`
+	Selector       clusterbulk.Selector              `json:"selector"`
+	Overrides      map[string]map[string]interface{} `json:"overrides,omitempty"`
+	AutoSync       bool                              `json:"autoSync"`
+	Clusters       []ClusterStatus                   `json:"clusters"`
+	CreatedBy      uint                              `json:"createdBy,omitempty"`
+	CreatedAt      time.Time                         `json:"createdAt"`
+	UpdatedAt      time.Time                         `json:"updatedAt"`
+}
+
+// ChartName returns the name of the chart without its repository.
+func (a Application) ChartName() string {
+	return path.Base(a.Chart)
+}
+
+// ClusterValues returns the values of the application on a cluster, including the overrides of the cluster.
+func (a Application) ClusterValues(clusterName string) map[string]interface{} {
+	return mergeValues(a.Values, a.Overrides[clusterName])
+}
+
+// HasCluster tells whether the application has a status on the cluster, ie. it has been synced to it.
+func (a Application) HasCluster(clusterID uint) bool {
+	for _, status := range a.Clusters {
+		if status.ClusterID == clusterID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ClusterStatus is the sync status of an application on a cluster.
+// Drift lists the values of the application that differ from the ones of the deployed release.
+type ClusterStatus struct {
+	ClusterID   uint                  `json:"clusterId"`
+	ClusterName string                `json:"clusterName"`
+	Status      SyncStatus            `json:"status"`
+	Message     string                `json:"message,omitempty"`
+	Drift       []pkgHelm.ValueChange `json:"drift,omitempty"`
+	Revision    int32                 `json:"revision,omitempty"`
+	SyncedAt    *time.Time            `json:"syncedAt,omitempty"`
+	CheckedAt   *time.Time            `json:"checkedAt,omitempty"`
+}
+
+// mergeValues merges src into a copy of dest, nested maps are merged recursively.
+func mergeValues(dest map[string]interface{}, src map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(dest)+len(src))
+	for key, value := range dest {
+		merged[key] = value
+	}
+
+	for key, value := range src {
+		srcMap, srcIsMap := value.(map[string]interface{})
+		destMap, destIsMap := merged[key].(map[string]interface{})
+
+		if srcIsMap && destIsMap {
+			merged[key] = mergeValues(destMap, srcMap)
+		} else {
+			merged[key] = value
+		}
+	}
+
+	return merged
+}
@@ -0,0 +1,70 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package application
+
+import (
+	"time"
+)
+
+// TableName constants
+const (
+	applicationTableName        = "applications"
+	applicationClusterTableName = "application_clusters"
+)
+
+// ApplicationModel is the database model of a multi-cluster application.
+type ApplicationModel struct {
+	ID             uint   `gorm:"primary_key"`
+	OrganizationID uint   `gorm:"not null;unique_index:idx_applications_org_name"`
+	Name           string `gorm:"not null;unique_index:idx_applications_org_name"`
+	ReleaseName    string `gorm:"not null"`
+	Chart          string `gorm:"not null"`
+	Version        string
+	Namespace      string
+	Values         string `sql:"type:text;"`
+	Selector       string `sql:"type:text;"`
+	Overrides      string `sql:"type:text;"`
+	AutoSync       bool
+	Clusters       []ClusterModel `gorm:"foreignkey:ApplicationID"`
+	CreatedBy      uint
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TableName changes the default table name.
+func (ApplicationModel) TableName() string {
+	return applicationTableName
+}
+
+// ClusterModel is the database model of the sync status of an application on a cluster.
+type ClusterModel struct {
+	ID            uint `gorm:"primary_key"`
+	ApplicationID uint `gorm:"not null;unique_index:idx_application_clusters_application_cluster"`
+	ClusterID     uint `gorm:"not null;unique_index:idx_application_clusters_application_cluster"`
+	ClusterName   string
+	Status        string
+	Message       string `sql:"type:text;"`
+	Drift         string `sql:"type:text;"`
+	Revision      int32
+
+	SyncedAt  *time.Time
+	CheckedAt *time.Time
+}
+
+// TableName changes the default table name.
+func (ClusterModel) TableName() string {
+	return applicationClusterTableName
+}
@@ -0,0 +1,327 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package application
+
+import (
+	"encoding/json"
+
+	"github.com/goph/emperror"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// Store persists applications and their sync status on the selected clusters.
+type Store struct {
+	db *gorm.DB
+}
+
+// NewStore returns a new Store instance.
+func NewStore(db *gorm.DB) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+// Create stores a new application.
+func (s *Store) Create(organizationID uint, userID uint, request Request) (*Application, error) {
+	if err := s.checkName(organizationID, 0, request.Name); err != nil {
+		return nil, err
+	}
+
+	model := ApplicationModel{
+		OrganizationID: organizationID,
+		ReleaseName:    request.ReleaseName,
+		CreatedBy:      userID,
+	}
+
+	if model.ReleaseName == "" {
+		model.ReleaseName = request.Name
+	}
+
+	if err := applyRequest(&model, request); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Create(&model).Error; err != nil {
+		return nil, emperror.WrapWith(err, "failed to create application", "organizationId", organizationID, "application", request.Name)
+	}
+
+	return applicationFromModel(model)
+}
+
+// Get returns an application of an organization.
+func (s *Store) Get(organizationID uint, applicationID uint) (*Application, error) {
+	model, err := s.get(ApplicationModel{ID: applicationID, OrganizationID: organizationID})
+	if err != nil {
+		return nil, err
+	}
+
+	return applicationFromModel(*model)
+}
+
+// GetByID returns an application regardless of its organization.
+func (s *Store) GetByID(applicationID uint) (*Application, error) {
+	model, err := s.get(ApplicationModel{ID: applicationID})
+	if err != nil {
+		return nil, err
+	}
+
+	return applicationFromModel(*model)
+}
+
+func (s *Store) get(query ApplicationModel) (*ApplicationModel, error) {
+	var model ApplicationModel
+
+	err := s.db.Preload("Clusters", func(db *gorm.DB) *gorm.DB {
+		return db.Order("cluster_name")
+	}).Where(query).First(&model).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, ErrApplicationNotFound
+	}
+	if err != nil {
+		return nil, emperror.WrapWith(err, "failed to get application", "applicationId", query.ID)
+	}
+
+	return &model, nil
+}
+
+// List returns the applications of an organization.
+func (s *Store) List(organizationID uint) ([]Application, error) {
+	var models []ApplicationModel
+
+	err := s.db.Preload("Clusters", func(db *gorm.DB) *gorm.DB {
+		return db.Order("cluster_name")
+	}).Where(ApplicationModel{OrganizationID: organizationID}).Order("name").Find(&models).Error
+	if err != nil {
+		return nil, emperror.WrapWith(err, "failed to list applications", "organizationId", organizationID)
+	}
+
+	applications := make([]Application, 0, len(models))
+	for _, model := range models {
+		application, err := applicationFromModel(model)
+		if err != nil {
+			return nil, err
+		}
+
+		applications = append(applications, *application)
+	}
+
+	return applications, nil
+}
+
+// ListIDs returns the IDs of every application.
+func (s *Store) ListIDs() ([]uint, error) {
+	var ids []uint
+
+	err := s.db.Model(&ApplicationModel{}).Pluck("id", &ids).Error
+
+	return ids, emperror.Wrap(err, "failed to list applications")
+}
+
+// Update changes the chart, the values and the cluster selector of an application.
+// The release name and the namespace of the deployed releases cannot be changed.
+func (s *Store) Update(organizationID uint, applicationID uint, request Request) (*Application, error) {
+	model, err := s.get(ApplicationModel{ID: applicationID, OrganizationID: organizationID})
+	if err != nil {
+		return nil, err
+	}
+
+	if request.ReleaseName != "" && request.ReleaseName != model.ReleaseName {
+		return nil, errors.WithMessage(ErrInvalidApplication, "the release name cannot be changed")
+	}
+
+	if request.Namespace != model.Namespace {
+		return nil, errors.WithMessage(ErrInvalidApplication, "the namespace cannot be changed")
+	}
+
+	if err := s.checkName(organizationID, applicationID, request.Name); err != nil {
+		return nil, err
+	}
+
+	if err := applyRequest(model, request); err != nil {
+		return nil, err
+	}
+
+	clusters := model.Clusters
+	model.Clusters = nil
+
+	if err := s.db.Save(model).Error; err != nil {
+		return nil, emperror.WrapWith(err, "failed to update application", "applicationId", applicationID)
+	}
+
+	model.Clusters = clusters
+
+	return applicationFromModel(*model)
+}
+
+// Delete deletes an application and its cluster statuses.
+func (s *Store) Delete(organizationID uint, applicationID uint) error {
+	model, err := s.get(ApplicationModel{ID: applicationID, OrganizationID: organizationID})
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.Where(ClusterModel{ApplicationID: model.ID}).Delete(&ClusterModel{}).Error; err != nil {
+		return emperror.WrapWith(err, "failed to delete application cluster statuses", "applicationId", applicationID)
+	}
+
+	return emperror.WrapWith(s.db.Delete(model).Error, "failed to delete application", "applicationId", applicationID)
+}
+
+// SetClusterStatus records the sync status of an application on a cluster.
+func (s *Store) SetClusterStatus(applicationID uint, status ClusterStatus) error {
+	var model ClusterModel
+
+	err := s.db.Where(ClusterModel{ApplicationID: applicationID, ClusterID: status.ClusterID}).
+		FirstOrInit(&model).Error
+	if err != nil {
+		return emperror.WrapWith(err, "failed to get application cluster status", "applicationId", applicationID, "clusterId", status.ClusterID)
+	}
+
+	drift, err := json.Marshal(status.Drift)
+	if err != nil {
+		return emperror.Wrap(err, "failed to marshal drift")
+	}
+
+	model.ClusterName = status.ClusterName
+	model.Status = string(status.Status)
+	model.Message = status.Message
+	model.Drift = string(drift)
+	model.CheckedAt = status.CheckedAt
+
+	// keep the last successful sync when the application is out of sync or failed
+	if status.Revision != 0 {
+		model.Revision = status.Revision
+	}
+	if status.SyncedAt != nil {
+		model.SyncedAt = status.SyncedAt
+	}
+
+	err = s.db.Save(&model).Error
+
+	return emperror.WrapWith(err, "failed to save application cluster status", "applicationId", applicationID, "clusterId", status.ClusterID)
+}
+
+// DeleteClusterStatus removes the sync status of an application on a cluster.
+func (s *Store) DeleteClusterStatus(applicationID uint, clusterID uint) error {
+	err := s.db.Where(ClusterModel{ApplicationID: applicationID, ClusterID: clusterID}).Delete(&ClusterModel{}).Error
+
+	return emperror.WrapWith(err, "failed to delete application cluster status", "applicationId", applicationID, "clusterId", clusterID)
+}
+
+// checkName checks that no other application of the organization has the given name.
+func (s *Store) checkName(organizationID uint, applicationID uint, name string) error {
+	var count int
+
+	err := s.db.Model(&ApplicationModel{}).
+		Where("organization_id = ? AND name = ? AND id <> ?", organizationID, name, applicationID).
+		Count(&count).Error
+	if err != nil {
+		return emperror.WrapWith(err, "failed to check application name", "application", name)
+	}
+
+	if count > 0 {
+		return ErrApplicationAlreadyExists
+	}
+
+	return nil
+}
+
+func applyRequest(model *ApplicationModel, request Request) error {
+	values, err := json.Marshal(request.Values)
+	if err != nil {
+		return emperror.Wrap(err, "failed to marshal values")
+	}
+
+	selector, err := json.Marshal(request.Selector)
+	if err != nil {
+		return emperror.Wrap(err, "failed to marshal selector")
+	}
+
+	overrides, err := json.Marshal(request.Overrides)
+	if err != nil {
+		return emperror.Wrap(err, "failed to marshal overrides")
+	}
+
+	model.Name = request.Name
+	model.Chart = request.Chart
+	model.Version = request.Version
+	model.Namespace = request.Namespace
+	model.Values = string(values)
+	model.Selector = string(selector)
+	model.Overrides = string(overrides)
+	model.AutoSync = request.AutoSync
+
+	return nil
+}
+
+func applicationFromModel(model ApplicationModel) (*Application, error) {
+	application := Application{
+		ID:             model.ID,
+		OrganizationID: model.OrganizationID,
+		Name:           model.Name,
+		ReleaseName:    model.ReleaseName,
+		Chart:          model.Chart,
+		Version:        model.Version,
+		Namespace:      model.Namespace,
+		AutoSync:       model.AutoSync,
+		Clusters:       make([]ClusterStatus, 0, len(model.Clusters)),
+		CreatedBy:      model.CreatedBy,
+		CreatedAt:      model.CreatedAt,
+		UpdatedAt:      model.UpdatedAt,
+	}
+
+	fields := []struct {
+		name  string
+		raw   string
+		value interface{}
+	}{
+		{name: "values", raw: model.Values, value: &application.Values},
+		{name: "selector", raw: model.Selector, value: &application.Selector},
+		{name: "overrides", raw: model.Overrides, value: &application.Overrides},
+	}
+
+	for _, field := range fields {
+		if field.raw == "" {
+			continue
+		}
+
+		if err := json.Unmarshal([]byte(field.raw), field.value); err != nil {
+			return nil, emperror.WrapWith(err, "failed to unmarshal "+field.name, "applicationId", model.ID)
+		}
+	}
+
+	for _, cluster := range model.Clusters {
+		status := ClusterStatus{
+			ClusterID:   cluster.ClusterID,
+			ClusterName: cluster.ClusterName,
+			Status:      SyncStatus(cluster.Status),
+			Message:     cluster.Message,
+			Revision:    cluster.Revision,
+			SyncedAt:    cluster.SyncedAt,
+			CheckedAt:   cluster.CheckedAt,
+		}
+
+		if cluster.Drift != "" {
+			if err := json.Unmarshal([]byte(cluster.Drift), &status.Drift); err != nil {
+				return nil, emperror.WrapWith(err, "failed to unmarshal drift", "applicationId", model.ID, "clusterId", cluster.ClusterID)
+			}
+		}
+
+		application.Clusters = append(application.Clusters, status)
+	}
+
+	return &application, nil
+}
@@ -0,0 +1,219 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package application
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/banzaicloud/pipeline/internal/clusterbulk"
+	pkgHelm "github.com/banzaicloud/pipeline/pkg/helm"
+)
+
+func newTestStore(t *testing.T) *Store {
+	db, err := gorm.Open("sqlite3", "file::memory:")
+	require.NoError(t, err)
+
+	require.NoError(t, db.AutoMigrate(&ApplicationModel{}, &ClusterModel{}).Error)
+
+	return NewStore(db)
+}
+
+func newTestRequest() Request {
+	return Request{
+		Name:      "monitoring",
+		Chart:     "stable/prometheus",
+		Version:   "8.11.0",
+		Namespace: "monitoring",
+		Values: map[string]interface{}{
+			"server": map[string]interface{}{
+				"replicaCount": 1.0,
+				"retention":    "15d",
+			},
+		},
+		Selector: clusterbulk.Selector{Cloud: "amazon", Names: []string{"prod-*"}},
+		Overrides: map[string]map[string]interface{}{
+			"prod-eu": {"server": map[string]interface{}{"replicaCount": 3.0}},
+		},
+	}
+}
+
+func TestRequest_Validate(t *testing.T) {
+	tests := map[string]struct {
+		modify func(r *Request)
+		valid  bool
+	}{
+		"valid":              {modify: func(r *Request) {}, valid: true},
+		"chart without repo": {modify: func(r *Request) { r.Chart = "prometheus" }},
+		"empty selector":     {modify: func(r *Request) { r.Selector = clusterbulk.Selector{} }},
+		"invalid pattern":    {modify: func(r *Request) { r.Selector.Names = []string{"prod-["} }},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			request := newTestRequest()
+			test.modify(&request)
+
+			err := request.Validate()
+			if test.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, ErrInvalidApplication, errors.Cause(err))
+			}
+		})
+	}
+}
+
+func TestApplication_ClusterValues(t *testing.T) {
+	application := Application{
+		Chart:     "stable/prometheus",
+		Values:    newTestRequest().Values,
+		Overrides: newTestRequest().Overrides,
+	}
+
+	assert.Equal(t, "prometheus", application.ChartName())
+
+	assert.Equal(t, map[string]interface{}{
+		"server": map[string]interface{}{
+			"replicaCount": 3.0,
+			"retention":    "15d",
+		},
+	}, application.ClusterValues("prod-eu"))
+
+	assert.Equal(t, application.Values, application.ClusterValues("prod-us"))
+
+	// overrides must not change the values of the application
+	assert.Equal(t, 1.0, application.Values["server"].(map[string]interface{})["replicaCount"])
+}
+
+func TestStore(t *testing.T) {
+	store := newTestStore(t)
+
+	created, err := store.Create(1, 2, newTestRequest())
+	require.NoError(t, err)
+	assert.Equal(t, "monitoring", created.ReleaseName)
+	assert.Equal(t, uint(2), created.CreatedBy)
+
+	_, err = store.Create(1, 2, newTestRequest())
+	assert.Equal(t, ErrApplicationAlreadyExists, err)
+
+	// names are unique per organization
+	_, err = store.Create(3, 2, newTestRequest())
+	require.NoError(t, err)
+
+	application, err := store.Get(1, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, newTestRequest().Values, application.Values)
+	assert.Equal(t, newTestRequest().Selector, application.Selector)
+	assert.Equal(t, newTestRequest().Overrides, application.Overrides)
+	assert.Empty(t, application.Clusters)
+
+	_, err = store.Get(3, created.ID)
+	assert.Equal(t, ErrApplicationNotFound, err)
+
+	applications, err := store.List(1)
+	require.NoError(t, err)
+	assert.Len(t, applications, 1)
+
+	ids, err := store.ListIDs()
+	require.NoError(t, err)
+	assert.Len(t, ids, 2)
+
+	request := newTestRequest()
+	request.Version = "8.12.0"
+	updated, err := store.Update(1, created.ID, request)
+	require.NoError(t, err)
+	assert.Equal(t, "8.12.0", updated.Version)
+
+	request.Namespace = "other"
+	_, err = store.Update(1, created.ID, request)
+	assert.Equal(t, ErrInvalidApplication, errors.Cause(err))
+
+	request = newTestRequest()
+	request.ReleaseName = "other"
+	_, err = store.Update(1, created.ID, request)
+	assert.Equal(t, ErrInvalidApplication, errors.Cause(err))
+
+	require.NoError(t, store.Delete(1, created.ID))
+
+	_, err = store.GetByID(created.ID)
+	assert.Equal(t, ErrApplicationNotFound, err)
+}
+
+func TestStore_ClusterStatus(t *testing.T) {
+	store := newTestStore(t)
+
+	created, err := store.Create(1, 2, newTestRequest())
+	require.NoError(t, err)
+
+	now := time.Now()
+
+	require.NoError(t, store.SetClusterStatus(created.ID, ClusterStatus{
+		ClusterID:   5,
+		ClusterName: "prod-eu",
+		Status:      SyncStatusSynced,
+		Revision:    2,
+		SyncedAt:    &now,
+		CheckedAt:   &now,
+	}))
+	require.NoError(t, store.SetClusterStatus(created.ID, ClusterStatus{
+		ClusterID:   6,
+		ClusterName: "prod-us",
+		Status:      SyncStatusPending,
+		Message:     "cluster is not running",
+		CheckedAt:   &now,
+	}))
+
+	// drift keeps the last successful sync
+	drift := []pkgHelm.ValueChange{{Key: "server.retention", From: "30d", To: "15d"}}
+	require.NoError(t, store.SetClusterStatus(created.ID, ClusterStatus{
+		ClusterID:   5,
+		ClusterName: "prod-eu",
+		Status:      SyncStatusOutOfSync,
+		Drift:       drift,
+		CheckedAt:   &now,
+	}))
+
+	application, err := store.GetByID(created.ID)
+	require.NoError(t, err)
+	require.Len(t, application.Clusters, 2)
+
+	assert.Equal(t, "prod-eu", application.Clusters[0].ClusterName)
+	assert.Equal(t, SyncStatusOutOfSync, application.Clusters[0].Status)
+	assert.Equal(t, drift, application.Clusters[0].Drift)
+	assert.Equal(t, int32(2), application.Clusters[0].Revision)
+	assert.NotNil(t, application.Clusters[0].SyncedAt)
+
+	assert.Equal(t, SyncStatusPending, application.Clusters[1].Status)
+	assert.Equal(t, "cluster is not running", application.Clusters[1].Message)
+	assert.Empty(t, application.Clusters[1].Drift)
+
+	require.NoError(t, store.DeleteClusterStatus(created.ID, 6))
+
+	application, err = store.GetByID(created.ID)
+	require.NoError(t, err)
+	assert.Len(t, application.Clusters, 1)
+
+	// updating an application keeps the statuses of its clusters
+	application, err = store.Update(1, created.ID, newTestRequest())
+	require.NoError(t, err)
+	assert.Len(t, application.Clusters, 1)
+}
@@ -0,0 +1,39 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+type eventPublisher interface {
+	Publish(topic string, args ...interface{})
+}
+
+type backupEventBus struct {
+	eb eventPublisher
+}
+
+const (
+	backupFailedTopic = "backup_failed"
+)
+
+// NewBackupEvents gives back a new backupEventBus
+func NewBackupEvents(eb eventPublisher) *backupEventBus {
+	return &backupEventBus{
+		eb: eb,
+	}
+}
+
+// BackupFailed publishes a backupFailedTopic event
+func (b *backupEventBus) BackupFailed(orgID uint, clusterID uint, backupName string, reason string) {
+	b.eb.Publish(backupFailedTopic, orgID, clusterID, backupName, reason)
+}
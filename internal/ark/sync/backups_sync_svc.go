@@ -16,8 +16,11 @@ package sync
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	"github.com/goph/emperror"
+	arkAPI "github.com/heptio/ark/pkg/apis/ark/v1"
 	"github.com/jinzhu/gorm"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -29,11 +32,22 @@ import (
 	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
 )
 
+// backupEvents is used to report backups that failed since the previous sync
+type backupEvents interface {
+	BackupFailed(orgID uint, clusterID uint, backupName string, reason string)
+}
+
+type nopBackupEvents struct{}
+
+func (nopBackupEvents) BackupFailed(orgID uint, clusterID uint, backupName string, reason string) {
+}
+
 // BackupsSyncService is for syncing backups between Pipeline DB and ARK for an Org
 type BackupsSyncService struct {
 	org    *auth.Organization
 	db     *gorm.DB
 	logger logrus.FieldLogger
+	events backupEvents
 
 	backupsSvc *ark.BackupsService
 	bucketsSvc *ark.BucketsService
@@ -46,6 +60,7 @@ func NewBackupsSyncService(org *auth.Organization, db *gorm.DB, logger logrus.Fi
 		org:    org,
 		db:     db,
 		logger: logger,
+		events: nopBackupEvents{},
 	}
 
 	s.backupsSvc = ark.BackupsServiceFactory(s.org, s.db, s.logger)
@@ -156,13 +171,30 @@ func (s *BackupsSyncService) SyncBackupsForCluster(cluster api.Cluster) error {
 			log.WithField("count", req.NodeCount).Debug("node count found")
 		}
 
-		_, err = s.backupsSvc.Persist(req)
+		persisted, err := s.backupsSvc.Persist(req)
 		if err != nil {
 			return emperror.Wrap(err, "could not persist backup")
 		}
 
+		if isBackupFailed(persisted.Status) && (persitedBackup == nil || !isBackupFailed(persitedBackup.Status)) {
+			reason := persisted.StatusMessage
+			if len(backup.Status.ValidationErrors) > 0 {
+				reason = strings.Join(backup.Status.ValidationErrors, "; ")
+			}
+			if reason == "" {
+				reason = fmt.Sprintf("backup finished in %s phase", persisted.Status)
+			}
+
+			s.events.BackupFailed(s.org.ID, persisted.ClusterID, persisted.Name, reason)
+		}
+
 		log.Debug("backup synced")
 	}
 
 	return nil
 }
+
+// isBackupFailed returns true if the given backup phase is a failure phase
+func isBackupFailed(phase string) bool {
+	return phase == string(arkAPI.BackupPhaseFailed) || phase == string(arkAPI.BackupPhaseFailedValidation)
+}
@@ -32,6 +32,7 @@ func RunSyncServices(
 	logger logrus.FieldLogger,
 	errorHandler emperror.Handler,
 	bucketSyncInterval, restoreSyncInterval, backupSyncInterval time.Duration,
+	events backupEvents,
 ) {
 	if bucketSyncInterval.Seconds() < 1 {
 		logger.WithField("interval", bucketSyncInterval.Seconds()).Error("invalid bucket sync interval")
@@ -57,6 +58,7 @@ func RunSyncServices(
 		bucketSyncInterval,
 		restoreSyncInterval,
 		backupSyncInterval,
+		events,
 	)
 
 	svc.Run(context, db, logger)
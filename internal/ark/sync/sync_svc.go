@@ -32,6 +32,7 @@ type Service struct {
 	bucketSyncInterval  time.Duration
 	restoreSyncInterval time.Duration
 	backupSyncInterval  time.Duration
+	events              backupEvents
 }
 
 // NewSyncService creates and initializes a Service
@@ -40,13 +41,19 @@ func NewSyncService(
 	BucketSyncInterval time.Duration,
 	RestoreSyncInterval time.Duration,
 	BackupSyncInterval time.Duration,
+	Events backupEvents,
 ) *Service {
 
+	if Events == nil {
+		Events = nopBackupEvents{}
+	}
+
 	return &Service{
 		clusterManager:      ClusterManager,
 		bucketSyncInterval:  BucketSyncInterval,
 		restoreSyncInterval: RestoreSyncInterval,
 		backupSyncInterval:  BackupSyncInterval,
+		events:              Events,
 	}
 }
 
@@ -208,6 +215,7 @@ func (s *Service) syncBackups(db *gorm.DB, logger logrus.FieldLogger) error {
 		log := logger.WithField("orgID", org.ID).WithField("orgName", org.Name)
 		log.Debug("syncing backups")
 		syncer := NewBackupsSyncService(org, db, log)
+		syncer.events = s.events
 		err := syncer.SyncBackups(s.clusterManager)
 		if err != nil {
 			log.Error(err)
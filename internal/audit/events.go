@@ -0,0 +1,239 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/goph/emperror"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// MaxPageSize is the maximum number of events returned in a single page.
+const MaxPageSize = 1000
+
+// ErrInvalidFilter is returned when an event filter cannot be applied.
+var ErrInvalidFilter = errors.New("invalid audit event filter")
+
+// EventFilter narrows down the audit events of an organization.
+type EventFilter struct {
+	// UserID only matches events of this user when not zero.
+	UserID uint
+
+	// From and To limit the time range of events (both inclusive).
+	From *time.Time
+	To   *time.Time
+
+	// Method only matches events with this HTTP method when not empty.
+	Method string
+
+	// PathPrefix only matches events whose path starts with this prefix when not empty.
+	PathPrefix string
+
+	// Status matches an exact response status code (eg. "404") or a status class (eg. "5xx").
+	Status string
+}
+
+// Event is the API representation of an AuditEvent.
+type Event struct {
+	ID            uint            `json:"id"`
+	Time          time.Time       `json:"time"`
+	CorrelationID string          `json:"correlationId,omitempty"`
+	ClientIP      string          `json:"clientIp,omitempty"`
+	UserAgent     string          `json:"userAgent,omitempty"`
+	UserID        uint            `json:"userId,omitempty"`
+	Method        string          `json:"method"`
+	Path          string          `json:"path"`
+	StatusCode    int             `json:"statusCode"`
+	ResponseTime  int             `json:"responseTime"`
+	ResponseSize  int             `json:"responseSize"`
+	Body          json.RawMessage `json:"body,omitempty"`
+	Headers       json.RawMessage `json:"headers,omitempty"`
+	Errors        json.RawMessage `json:"errors,omitempty"`
+}
+
+// Events reads and purges the stored audit events.
+type Events struct {
+	db *gorm.DB
+}
+
+// NewEvents returns a new Events instance.
+func NewEvents(db *gorm.DB) *Events {
+	return &Events{
+		db: db,
+	}
+}
+
+// List returns a page of events of an organization matching the filter, newest first.
+// The cursor is the ID of the last event of the previous page (zero for the first page).
+// The returned next cursor is zero when there are no more events.
+func (e *Events) List(organizationID uint, filter EventFilter, cursor uint, limit int) ([]Event, uint, error) {
+	if limit < 1 || limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+
+	query, err := e.query(organizationID, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if cursor != 0 {
+		query = query.Where("id < ?", cursor)
+	}
+
+	var models []AuditEvent
+
+	// Fetch one more event to find out if there is a next page
+	err = query.Order("id desc").Limit(limit + 1).Find(&models).Error
+	if err != nil {
+		return nil, 0, emperror.WrapWith(err, "failed to list audit events", "organizationId", organizationID)
+	}
+
+	var next uint
+	if len(models) > limit {
+		models = models[:limit]
+		next = models[limit-1].ID
+	}
+
+	events := make([]Event, 0, len(models))
+	for _, model := range models {
+		events = append(events, eventFromModel(model))
+	}
+
+	return events, next, nil
+}
+
+// Each calls fn for every event of an organization matching the filter, newest first.
+// Events are read in pages, so that exports do not need to hold every event in memory.
+func (e *Events) Each(organizationID uint, filter EventFilter, fn func(Event) error) error {
+	var cursor uint
+
+	for {
+		events, next, err := e.List(organizationID, filter, cursor, MaxPageSize)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			if err := fn(event); err != nil {
+				return err
+			}
+		}
+
+		if next == 0 {
+			return nil
+		}
+
+		cursor = next
+	}
+}
+
+// Purge deletes every event older than the given time and returns the number of deleted events.
+func (e *Events) Purge(before time.Time) (int64, error) {
+	result := e.db.Where("time < ?", before).Delete(&AuditEvent{})
+	if result.Error != nil {
+		return 0, emperror.Wrap(result.Error, "failed to purge audit events")
+	}
+
+	return result.RowsAffected, nil
+}
+
+func (e *Events) query(organizationID uint, filter EventFilter) (*gorm.DB, error) {
+	query := e.db.Model(&AuditEvent{}).Where("organization_id = ?", organizationID)
+
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+
+	if filter.From != nil {
+		query = query.Where("time >= ?", *filter.From)
+	}
+
+	if filter.To != nil {
+		query = query.Where("time <= ?", *filter.To)
+	}
+
+	if filter.Method != "" {
+		query = query.Where("method = ?", strings.ToUpper(filter.Method))
+	}
+
+	if filter.PathPrefix != "" {
+		// substr is used instead of LIKE, so that the prefix does not need escaping in every SQL dialect
+		query = query.Where("substr(path, 1, ?) = ?", utf8.RuneCountInString(filter.PathPrefix), filter.PathPrefix)
+	}
+
+	if filter.Status != "" {
+		min, max, err := parseStatusFilter(filter.Status)
+		if err != nil {
+			return nil, err
+		}
+
+		query = query.Where("status_code BETWEEN ? AND ?", min, max)
+	}
+
+	return query, nil
+}
+
+// parseStatusFilter returns the status code range described by an exact status code or a status class.
+func parseStatusFilter(status string) (int, int, error) {
+	if len(status) == 3 && strings.HasSuffix(strings.ToLower(status), "xx") {
+		class, err := strconv.Atoi(status[:1])
+		if err == nil && class >= 1 && class <= 5 {
+			return class * 100, class*100 + 99, nil
+		}
+	}
+
+	code, err := strconv.Atoi(status)
+	if err != nil || code < 100 || code > 599 {
+		return 0, 0, errors.WithMessage(ErrInvalidFilter, "status must be a status code or a status class like 5xx")
+	}
+
+	return code, code, nil
+}
+
+func eventFromModel(model AuditEvent) Event {
+	event := Event{
+		ID:            model.ID,
+		Time:          model.Time,
+		CorrelationID: model.CorrelationID,
+		ClientIP:      model.ClientIP,
+		UserAgent:     model.UserAgent,
+		UserID:        model.UserID,
+		Method:        model.Method,
+		Path:          model.Path,
+		StatusCode:    model.StatusCode,
+		ResponseTime:  model.ResponseTime,
+		ResponseSize:  model.ResponseSize,
+	}
+
+	if model.Body != nil {
+		event.Body = json.RawMessage(*model.Body)
+	}
+
+	if model.Headers != "" {
+		event.Headers = json.RawMessage(model.Headers)
+	}
+
+	if model.Errors != nil {
+		event.Errors = json.RawMessage(*model.Errors)
+	}
+
+	return event
+}
@@ -0,0 +1,128 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEvents(t *testing.T) (*gorm.DB, *Events) {
+	db, err := gorm.Open("sqlite3", "file::memory:")
+	require.NoError(t, err)
+
+	require.NoError(t, db.AutoMigrate(&AuditEvent{}).Error)
+
+	now := time.Now()
+	body := `{"name":"demo"}`
+	fixtures := []AuditEvent{
+		{Time: now.Add(-72 * time.Hour), OrganizationID: 1, UserID: 1, Method: "GET", Path: "/api/v1/orgs/1/clusters", StatusCode: 200, Headers: "{}"},
+		{Time: now.Add(-48 * time.Hour), OrganizationID: 1, UserID: 2, Method: "POST", Path: "/api/v1/orgs/1/clusters", StatusCode: 201, Headers: "{}", Body: &body},
+		{Time: now.Add(-24 * time.Hour), OrganizationID: 1, UserID: 1, Method: "DELETE", Path: "/api/v1/orgs/1/secrets/abc", StatusCode: 404, Headers: "{}"},
+		{Time: now.Add(-1 * time.Hour), OrganizationID: 1, UserID: 2, Method: "PUT", Path: "/api/v1/orgs/1/secrets/a_c", StatusCode: 500, Headers: "{}"},
+		{Time: now, OrganizationID: 2, UserID: 3, Method: "GET", Path: "/api/v1/orgs/2/clusters", StatusCode: 200, Headers: "{}"},
+	}
+
+	for i := range fixtures {
+		require.NoError(t, db.Create(&fixtures[i]).Error)
+	}
+
+	return db, NewEvents(db)
+}
+
+func TestEvents_List(t *testing.T) {
+	db, events := newTestEvents(t)
+	defer db.Close()
+
+	from := time.Now().Add(-50 * time.Hour)
+	to := time.Now().Add(-12 * time.Hour)
+
+	tests := []struct {
+		name     string
+		filter   EventFilter
+		expected []string
+	}{
+		{name: "organization", filter: EventFilter{}, expected: []string{"PUT", "DELETE", "POST", "GET"}},
+		{name: "user", filter: EventFilter{UserID: 2}, expected: []string{"PUT", "POST"}},
+		{name: "time range", filter: EventFilter{From: &from, To: &to}, expected: []string{"DELETE", "POST"}},
+		{name: "method", filter: EventFilter{Method: "post"}, expected: []string{"POST"}},
+		{name: "path prefix", filter: EventFilter{PathPrefix: "/api/v1/orgs/1/secrets/a_"}, expected: []string{"PUT"}},
+		{name: "status code", filter: EventFilter{Status: "404"}, expected: []string{"DELETE"}},
+		{name: "status class", filter: EventFilter{Status: "2xx"}, expected: []string{"POST", "GET"}},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			list, next, err := events.List(1, test.filter, 0, 10)
+			require.NoError(t, err)
+			assert.Zero(t, next)
+
+			methods := make([]string, 0, len(list))
+			for _, event := range list {
+				methods = append(methods, event.Method)
+			}
+
+			assert.Equal(t, test.expected, methods)
+		})
+	}
+
+	_, _, err := events.List(1, EventFilter{Status: "6xx"}, 0, 10)
+	assert.Equal(t, ErrInvalidFilter, errors.Cause(err))
+}
+
+func TestEvents_Pagination(t *testing.T) {
+	db, events := newTestEvents(t)
+	defer db.Close()
+
+	page, next, err := events.List(1, EventFilter{}, 0, 3)
+	require.NoError(t, err)
+	require.Len(t, page, 3)
+	require.NotZero(t, next)
+
+	page, next, err = events.List(1, EventFilter{}, next, 3)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Zero(t, next)
+	assert.Equal(t, "GET", page[0].Method)
+
+	var all []Event
+	require.NoError(t, events.Each(1, EventFilter{}, func(event Event) error {
+		all = append(all, event)
+		return nil
+	}))
+	assert.Len(t, all, 4)
+	assert.JSONEq(t, `{"name":"demo"}`, string(all[2].Body))
+}
+
+func TestEvents_Purge(t *testing.T) {
+	db, events := newTestEvents(t)
+	defer db.Close()
+
+	purged, err := events.Purge(time.Now().Add(-36 * time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), purged)
+
+	list, _, err := events.List(1, EventFilter{}, 0, 10)
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+}
@@ -178,11 +178,17 @@ func LogWriter(
 			userID = user.ID
 		}
 
+		var organizationID uint
+		if organization := auth.GetCurrentOrganization(c.Request); organization != nil {
+			organizationID = organization.ID
+		}
+
 		responseEvent := AuditEvent{
-			UserID:       userID,
-			StatusCode:   c.Writer.Status(),
-			ResponseSize: c.Writer.Size(),
-			ResponseTime: int(time.Since(start).Nanoseconds() / 1000 / 1000), // ms
+			UserID:         userID,
+			OrganizationID: organizationID,
+			StatusCode:     c.Writer.Status(),
+			ResponseSize:   c.Writer.Size(),
+			ResponseTime:   int(time.Since(start).Nanoseconds() / 1000 / 1000), // ms
 		}
 
 		if c.IsAborted() {
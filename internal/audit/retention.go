@@ -0,0 +1,55 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/goph/emperror"
+	"github.com/sirupsen/logrus"
+)
+
+// RunRetention periodically purges the audit events older than the retention period until the context is cancelled.
+func RunRetention(
+	ctx context.Context,
+	events *Events,
+	retention time.Duration,
+	interval time.Duration,
+	logger logrus.FieldLogger,
+	errorHandler emperror.Handler,
+) {
+	logger = logger.WithField("retention", retention.String())
+	logger.WithField("interval", interval.String()).Info("starting audit event retention")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := events.Purge(time.Now().Add(-retention))
+		if err != nil {
+			errorHandler.Handle(err)
+		} else if purged > 0 {
+			logger.WithField("count", purged).Info("purged expired audit events")
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			logger.Debug("stopping audit event retention")
+			return
+		}
+	}
+}
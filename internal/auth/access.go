@@ -14,47 +14,142 @@
 
 package auth
 
+import (
+	"github.com/banzaicloud/pipeline/auth"
+	"github.com/goph/emperror"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
 // AccessManager is responsible for managing authorization rules.
-// NOTE:
-// Currently this implementation is a dummy one, and is a placeholder for a future implementation.
-// It hasn't been removed to mark the places where it should been called.
+// Users get access to an organization through their membership, the role stored in the membership
+// selects the policies (persisted in the database) that are checked by the Enforcer.
 type AccessManager struct {
+	db       *gorm.DB
 	enforcer Enforcer
 	basePath string
 }
 
 // NewAccessManager returns a new AccessManager instance.
-func NewAccessManager(enforcer Enforcer, basePath string) *AccessManager {
+func NewAccessManager(db *gorm.DB, enforcer Enforcer, basePath string) *AccessManager {
 	return &AccessManager{
+		db:       db,
 		enforcer: enforcer,
 		basePath: basePath,
 	}
 }
 
-// AddDefaultPolicies adds default policy rules to the underlying access manager.
-func (m *AccessManager) AddDefaultPolicies() {
+// AddDefaultPolicies adds default policy rules for the built-in roles if they don't exist yet.
+func (m *AccessManager) AddDefaultPolicies() error {
+	for _, policy := range defaultPolicies() {
+		policy := policy
+
+		err := m.db.Where(&policy).FirstOrCreate(&policy).Error
+		if err != nil {
+			return emperror.WrapWith(err, "failed to add default policy", "role", policy.Role, "path", policy.Path, "method", policy.Method)
+		}
+	}
+
+	return nil
 }
 
 // GrantDefaultAccessToUser adds all the default non-org-specific role to a user.
+// Non-org-specific resources are available to every authenticated user, so there is nothing to grant.
 func (m *AccessManager) GrantDefaultAccessToUser(userID string) {
 }
 
 // GrantDefaultAccessToVirtualUser adds org list role to a virtual user.
+// Non-org-specific resources are available to every authenticated user, so there is nothing to grant.
 func (m *AccessManager) GrantDefaultAccessToVirtualUser(userID string) {
 }
 
 // AddOrganizationPolicies creates an organization role, by adding the default (*) org policies for the given organization.
+// Default policies are global (they apply to every organization), so there is nothing to add.
 func (m *AccessManager) AddOrganizationPolicies(orgID uint) {
 }
 
 // GrantOrganizationAccessToUser adds a user to an organization by adding the associated organization role.
+// The role is stored in the membership itself, so there is nothing to grant.
 func (m *AccessManager) GrantOrganizationAccessToUser(userID string, orgID uint) {
 }
 
 // RevokeOrganizationAccessFromUser removes a user from an organization by removing the associated organization role.
+// The role is removed together with the membership, so there is nothing to revoke.
 func (m *AccessManager) RevokeOrganizationAccessFromUser(userID string, orgID uint) {
 }
 
 // RevokeAllAccessFromUser removes all roles for a given user.
+// Roles are removed together with the memberships, so there is nothing to revoke.
 func (m *AccessManager) RevokeAllAccessFromUser(userID string) {
 }
+
+// GetUserRole returns the role of a user in an organization.
+func (m *AccessManager) GetUserRole(userID uint, orgID uint) (string, error) {
+	membership, err := m.getMembership(m.db, userID, orgID)
+	if err != nil {
+		return "", err
+	}
+
+	return membership.Role, nil
+}
+
+// SetUserRole changes the role of a user in an organization.
+// The last admin of an organization cannot be demoted.
+func (m *AccessManager) SetUserRole(userID uint, orgID uint, role string) error {
+	if !IsValidRole(role) {
+		return errors.WithStack(ErrInvalidRole)
+	}
+
+	tx := m.db.Begin()
+	if err := tx.Error; err != nil {
+		return emperror.Wrap(err, "failed to start transaction")
+	}
+
+	membership, err := m.getMembership(tx, userID, orgID)
+	if err != nil {
+		tx.Rollback()
+
+		return err
+	}
+
+	if membership.Role == RoleAdmin && role != RoleAdmin {
+		var admins int
+
+		err := tx.Model(&auth.UserOrganization{}).
+			Where(&auth.UserOrganization{OrganizationID: orgID, Role: RoleAdmin}).
+			Count(&admins).Error
+		if err != nil {
+			tx.Rollback()
+
+			return emperror.Wrap(err, "failed to count organization admins")
+		}
+
+		if admins <= 1 {
+			tx.Rollback()
+
+			return errors.WithStack(ErrLastAdmin)
+		}
+	}
+
+	err = tx.Model(&auth.UserOrganization{}).
+		Where(&auth.UserOrganization{UserID: userID, OrganizationID: orgID}).
+		Update("role", role).Error
+	if err != nil {
+		tx.Rollback()
+
+		return emperror.WrapWith(err, "failed to update user role", "userId", userID, "orgId", orgID)
+	}
+
+	return emperror.Wrap(tx.Commit().Error, "failed to commit transaction")
+}
+
+func (m *AccessManager) getMembership(db *gorm.DB, userID uint, orgID uint) (*auth.UserOrganization, error) {
+	var membership auth.UserOrganization
+
+	err := db.Where(&auth.UserOrganization{UserID: userID, OrganizationID: orgID}).First(&membership).Error
+	if err != nil {
+		return nil, emperror.WrapWith(err, "failed to get user membership", "userId", userID, "orgId", orgID)
+	}
+
+	return &membership, nil
+}
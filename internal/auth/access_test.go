@@ -21,6 +21,7 @@ import (
 	"github.com/banzaicloud/pipeline/auth"
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -43,21 +44,39 @@ func newUser(t *testing.T, db *gorm.DB, id uint, login string) *auth.User {
 }
 
 func addUserToOrg(t *testing.T, db *gorm.DB, user *auth.User, org *auth.Organization) {
+	addUserToOrgWithRole(t, db, user, org, "")
+}
+
+func addUserToOrgWithRole(t *testing.T, db *gorm.DB, user *auth.User, org *auth.Organization, role string) {
 	db.AutoMigrate(auth.UserOrganization{})
-	if err := db.FirstOrCreate(&auth.UserOrganization{OrganizationID: org.ID, UserID: user.ID}).Error; err != nil {
+	membership := auth.UserOrganization{OrganizationID: org.ID, UserID: user.ID, Role: role}
+	if err := db.Where(membership).FirstOrCreate(&membership).Error; err != nil {
 		t.Fatal(err)
 	}
 }
 
-func TestAccessManager_DefaultPolicies(t *testing.T) {
+func newAccessManager(t *testing.T) (*gorm.DB, Enforcer, *AccessManager) {
 	db, err := gorm.Open("sqlite3", "file::memory:")
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	if err := db.AutoMigrate(&RolePolicy{}).Error; err != nil {
+		t.Fatal(err)
+	}
+
 	enforcer := NewEnforcer(db)
-	accessManager := NewAccessManager(enforcer, "")
+	accessManager := NewAccessManager(db, enforcer, "")
 
-	accessManager.AddDefaultPolicies()
+	if err := accessManager.AddDefaultPolicies(); err != nil {
+		t.Fatal(err)
+	}
+
+	return db, enforcer, accessManager
+}
+
+func TestAccessManager_DefaultPolicies(t *testing.T) {
+	db, enforcer, accessManager := newAccessManager(t)
 
 	accessManager.GrantDefaultAccessToUser("user")
 	accessManager.GrantDefaultAccessToVirtualUser("userVirtual")
@@ -153,3 +172,115 @@ func TestAccessManager_DefaultPolicies(t *testing.T) {
 		})
 	}
 }
+
+func TestAccessManager_RolePolicies(t *testing.T) {
+	db, enforcer, _ := newAccessManager(t)
+
+	org := newOrg(t, db, 10, "roles")
+	admin := newUser(t, db, 10, "admin")
+	member := newUser(t, db, 11, "member")
+	viewer := newUser(t, db, 12, "viewer")
+
+	addUserToOrgWithRole(t, db, admin, org, RoleAdmin)
+	addUserToOrgWithRole(t, db, member, org, RoleMember)
+	addUserToOrgWithRole(t, db, viewer, org, RoleViewer)
+
+	tests := []struct {
+		user           *auth.User
+		path           string
+		method         string
+		expectedResult bool
+	}{
+		{user: admin, path: "/api/v1/orgs/10/clusters/1", method: http.MethodDelete, expectedResult: true},
+		{user: admin, path: "/api/v1/orgs/10/users/11/roles", method: http.MethodPut, expectedResult: true},
+		{user: member, path: "/api/v1/orgs/10/clusters", method: http.MethodPost, expectedResult: true},
+		{user: member, path: "/api/v1/orgs/10/clusters/1", method: http.MethodPut, expectedResult: true},
+		{user: member, path: "/api/v1/orgs/10/clusters/1", method: http.MethodDelete, expectedResult: false},
+		{user: member, path: "/api/v1/orgs/10/clusters/1/deployments/dep", method: http.MethodDelete, expectedResult: true},
+		{user: member, path: "/api/v1/orgs/10/users/12", method: http.MethodPost, expectedResult: false},
+		{user: member, path: "/api/v1/orgs/10/users/12/roles", method: http.MethodPut, expectedResult: false},
+		{user: member, path: "/api/v1/orgs/10/users", method: http.MethodGet, expectedResult: true},
+		{user: viewer, path: "/api/v1/orgs/10/clusters/1", method: http.MethodGet, expectedResult: true},
+		{user: viewer, path: "/api/v1/orgs/10/clusters/1", method: http.MethodHead, expectedResult: true},
+		{user: viewer, path: "/api/v1/orgs/10/clusters", method: http.MethodPost, expectedResult: false},
+		{user: viewer, path: "/api/v1/orgs/10/secrets/abc", method: http.MethodGet, expectedResult: false},
+		{user: viewer, path: "/api/v1/orgs/10/secrets/abc/versions", method: http.MethodGet, expectedResult: true},
+		{user: viewer, path: "/api/v1/orgs/10/secrets/abc/versions/2", method: http.MethodGet, expectedResult: false},
+		{user: viewer, path: "/api/v1/orgs/10/clusters/1/config", method: http.MethodGet, expectedResult: false},
+		{user: viewer, path: "/api/v1/orgs/10/clusters/1/proxy/api/v1/secrets", method: http.MethodGet, expectedResult: false},
+		{user: viewer, path: "/api/v1/orgs/10/clusters/1/deployments/dep", method: http.MethodGet, expectedResult: false},
+		{user: viewer, path: "/api/v1/orgs/10/clusters/1/deployments/dep", method: http.MethodHead, expectedResult: true},
+		{user: viewer, path: "/api/v1/orgs/10/clusters/1/deployments/dep/history", method: http.MethodGet, expectedResult: true},
+		{user: member, path: "/api/v1/orgs/10/clusters/1/deployments/dep", method: http.MethodGet, expectedResult: true},
+		{user: admin, path: "/api/v1/orgs/10/audit/export", method: http.MethodGet, expectedResult: true},
+		{user: member, path: "/api/v1/orgs/10/audit", method: http.MethodGet, expectedResult: false},
+		{user: viewer, path: "/api/v1/orgs/10/audit/export", method: http.MethodGet, expectedResult: false},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.user.Login+" "+test.method+" "+test.path, func(t *testing.T) {
+			granted, err := enforcer.Enforce(org, test.user, test.path, test.method)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+
+			assert.Equal(t, test.expectedResult, granted)
+		})
+	}
+}
+
+func TestAccessManager_SetUserRole(t *testing.T) {
+	db, _, accessManager := newAccessManager(t)
+
+	org := newOrg(t, db, 20, "setrole")
+	admin := newUser(t, db, 20, "admin2")
+	member := newUser(t, db, 21, "member2")
+
+	addUserToOrgWithRole(t, db, admin, org, RoleAdmin)
+	addUserToOrgWithRole(t, db, member, org, RoleMember)
+
+	err := accessManager.SetUserRole(member.ID, org.ID, "superuser")
+	assert.Equal(t, ErrInvalidRole, errors.Cause(err))
+
+	err = accessManager.SetUserRole(admin.ID, org.ID, RoleViewer)
+	assert.Equal(t, ErrLastAdmin, errors.Cause(err))
+
+	err = accessManager.SetUserRole(member.ID, org.ID, RoleAdmin)
+	assert.NoError(t, err)
+
+	err = accessManager.SetUserRole(admin.ID, org.ID, RoleViewer)
+	assert.NoError(t, err)
+
+	role, err := accessManager.GetUserRole(admin.ID, org.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, RoleViewer, role)
+
+	_, err = accessManager.GetUserRole(99, org.ID)
+	assert.True(t, gorm.IsRecordNotFoundError(errors.Cause(err)))
+}
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		path     string
+		expected bool
+	}{
+		{pattern: "*", path: "/api/v1/orgs/1", expected: true},
+		{pattern: "/api/v1/orgs/:orgid", path: "/api/v1/orgs/1", expected: true},
+		{pattern: "/api/v1/orgs/:orgid", path: "/api/v1/orgs/1/clusters", expected: false},
+		{pattern: "/api/v1/orgs/:orgid/clusters/:id", path: "/api/v1/orgs/1/clusters", expected: false},
+		{pattern: "/api/v1/orgs/:orgid/users/*", path: "/api/v1/orgs/1/users/2/roles", expected: true},
+		{pattern: "/api/v1/orgs/:orgid/users/*", path: "/api/v1/orgs/1/users", expected: false},
+		{pattern: "/api/v1/orgs/:orgid/users/*", path: "/api/v1/orgs/1/secrets/2", expected: false},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.pattern+" "+test.path, func(t *testing.T) {
+			assert.Equal(t, test.expected, matchPath(test.pattern, test.path))
+		})
+	}
+}
@@ -55,8 +55,9 @@ func (e *basicEnforcer) Enforce(org *auth.Organization, user *auth.User, path, m
 		return org.Name == orgName, nil
 	}
 
-	err := e.db.Model(user).Where(org).Related(org, "Organizations").Error
+	var membership auth.UserOrganization
 
+	err := e.db.Where(&auth.UserOrganization{UserID: user.ID, OrganizationID: org.ID}).First(&membership).Error
 	if err != nil {
 		if gorm.IsRecordNotFoundError(err) {
 			return false, nil
@@ -64,7 +65,14 @@ func (e *basicEnforcer) Enforce(org *auth.Organization, user *auth.User, path, m
 		return false, emperror.Wrap(err, "failed to query user's organizations from db")
 	}
 
-	return true, nil
+	var policies []RolePolicy
+
+	err = e.db.Where("role = ? AND organization_id IN (?)", membership.Role, []uint{0, org.ID}).Find(&policies).Error
+	if err != nil {
+		return false, emperror.WrapWith(err, "failed to query role policies from db", "role", membership.Role)
+	}
+
+	return evaluatePolicies(policies, path, method), nil
 }
 
 // NewEnforcer returns a new enforcer.
@@ -68,15 +68,10 @@ func (m *middleware) CheckPermission(r *http.Request) (bool, error) {
 		return false, nil
 	}
 
-	granted, err := m.enforcer.Enforce(org, user, path, method)
-	if err != nil {
-		return granted, err
+	// Policies are defined without the base path
+	if m.basePath != "" && strings.HasPrefix(path, fmt.Sprintf("%s/", m.basePath)) {
+		path = strings.TrimPrefix(path, m.basePath)
 	}
 
-	// Try checking the permission without a base path
-	if !granted && m.basePath != "" && strings.HasPrefix(path, fmt.Sprintf("%s/", m.basePath)) {
-		granted, err = m.enforcer.Enforce(org, user, strings.TrimPrefix(path, m.basePath), method)
-	}
-
-	return granted, err
+	return m.enforcer.Enforce(org, user, path, method)
 }
@@ -0,0 +1,181 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Organization roles
+const (
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+	RoleViewer = "viewer"
+)
+
+// Policy effects
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+// PolicyWildcard matches any path or method in a policy rule.
+const PolicyWildcard = "*"
+
+// TableName constants
+const (
+	rolePolicyTableName = "auth_role_policies"
+)
+
+// ErrInvalidRole is returned when an unknown role is being assigned to a user.
+var ErrInvalidRole = errors.New("invalid role")
+
+// ErrLastAdmin is returned when the last admin of an organization would lose the admin role.
+var ErrLastAdmin = errors.New("organization must have at least one admin")
+
+// Roles returns the list of known organization roles.
+func Roles() []string {
+	return []string{RoleAdmin, RoleMember, RoleViewer}
+}
+
+// IsValidRole checks whether the given role is a known organization role.
+func IsValidRole(role string) bool {
+	for _, r := range Roles() {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RolePolicy is a single authorization rule for a role.
+// Rules with a zero OrganizationID apply to every organization.
+type RolePolicy struct {
+	ID             uint   `gorm:"primary_key"`
+	OrganizationID uint   `gorm:"not null;default:0;unique_index:idx_auth_role_policies_unique"`
+	Role           string `gorm:"size:32;not null;unique_index:idx_auth_role_policies_unique"`
+	Path           string `gorm:"size:255;not null;unique_index:idx_auth_role_policies_unique"`
+	Method         string `gorm:"size:10;not null;unique_index:idx_auth_role_policies_unique"`
+	Effect         string `gorm:"size:5;not null;unique_index:idx_auth_role_policies_unique"`
+}
+
+// TableName specifies a database table name for the model.
+func (RolePolicy) TableName() string {
+	return rolePolicyTableName
+}
+
+// Matches checks whether the policy applies to the path and method.
+func (p RolePolicy) Matches(path, method string) bool {
+	if p.Method != PolicyWildcard && !strings.EqualFold(p.Method, method) {
+		return false
+	}
+
+	return matchPath(p.Path, path)
+}
+
+// matchPath matches a path against a pattern.
+// A ":param" segment matches exactly one path segment,
+// a trailing "*" segment matches one or more path segments.
+func matchPath(pattern, path string) bool {
+	if pattern == PolicyWildcard {
+		return true
+	}
+
+	patternSegments := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+
+	for i, segment := range patternSegments {
+		if segment == PolicyWildcard && i == len(patternSegments)-1 {
+			return len(pathSegments) > i
+		}
+
+		if i >= len(pathSegments) {
+			return false
+		}
+
+		if strings.HasPrefix(segment, ":") {
+			continue
+		}
+
+		if segment != pathSegments[i] {
+			return false
+		}
+	}
+
+	return len(patternSegments) == len(pathSegments)
+}
+
+// evaluatePolicies decides access based on a set of policies: deny rules always take precedence.
+func evaluatePolicies(policies []RolePolicy, path, method string) bool {
+	allowed := false
+
+	for _, policy := range policies {
+		if !policy.Matches(path, method) {
+			continue
+		}
+
+		if policy.Effect == EffectDeny {
+			return false
+		}
+
+		allowed = true
+	}
+
+	return allowed
+}
+
+// defaultPolicies returns the global policies for the built-in roles.
+func defaultPolicies() []RolePolicy {
+	policies := []RolePolicy{
+		// Admins can do anything within their organization
+		{Role: RoleAdmin, Path: PolicyWildcard, Method: PolicyWildcard, Effect: EffectAllow},
+
+		// Members can manage resources, but cannot delete clusters, the organization, manage users or read the audit log
+		{Role: RoleMember, Path: PolicyWildcard, Method: PolicyWildcard, Effect: EffectAllow},
+		{Role: RoleMember, Path: "/api/v1/orgs/:orgid", Method: http.MethodDelete, Effect: EffectDeny},
+		{Role: RoleMember, Path: "/api/v1/orgs/:orgid/clusters/:id", Method: http.MethodDelete, Effect: EffectDeny},
+		{Role: RoleMember, Path: "/api/v1/orgs/:orgid/audit", Method: PolicyWildcard, Effect: EffectDeny},
+		{Role: RoleMember, Path: "/api/v1/orgs/:orgid/audit/*", Method: PolicyWildcard, Effect: EffectDeny},
+
+		// Viewers have read-only access without access to credentials
+		{Role: RoleViewer, Path: PolicyWildcard, Method: http.MethodGet, Effect: EffectAllow},
+		{Role: RoleViewer, Path: PolicyWildcard, Method: http.MethodHead, Effect: EffectAllow},
+		{Role: RoleViewer, Path: "/api/v1/orgs/:orgid/secrets/:id", Method: http.MethodGet, Effect: EffectDeny},
+		{Role: RoleViewer, Path: "/api/v1/orgs/:orgid/secrets/:id/versions/:version", Method: http.MethodGet, Effect: EffectDeny},
+		{Role: RoleViewer, Path: "/api/v1/orgs/:orgid/clusters/:id/config", Method: http.MethodGet, Effect: EffectDeny},
+		{Role: RoleViewer, Path: "/api/v1/orgs/:orgid/clusters/:id/proxy/*", Method: PolicyWildcard, Effect: EffectDeny},
+		// Deployment values often hold credentials
+		{Role: RoleViewer, Path: "/api/v1/orgs/:orgid/clusters/:id/deployments/:name", Method: http.MethodGet, Effect: EffectDeny},
+		{Role: RoleViewer, Path: "/api/v1/orgs/:orgid/notifications/channels", Method: http.MethodGet, Effect: EffectDeny},
+		{Role: RoleViewer, Path: "/api/v1/orgs/:orgid/notifications/channels/:channelId", Method: http.MethodGet, Effect: EffectDeny},
+		{Role: RoleViewer, Path: "/api/v1/orgs/:orgid/audit", Method: PolicyWildcard, Effect: EffectDeny},
+		{Role: RoleViewer, Path: "/api/v1/orgs/:orgid/audit/*", Method: PolicyWildcard, Effect: EffectDeny},
+	}
+
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodDelete} {
+		policies = append(policies, RolePolicy{
+			Role:   RoleMember,
+			Path:   "/api/v1/orgs/:orgid/users/*",
+			Method: method,
+			Effect: EffectDeny,
+		})
+	}
+
+	return policies
+}
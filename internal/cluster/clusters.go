@@ -15,6 +15,8 @@
 package cluster
 
 import (
+	"time"
+
 	"github.com/banzaicloud/pipeline/model"
 	"github.com/goph/emperror"
 	"github.com/jinzhu/gorm"
@@ -161,6 +163,16 @@ func (c *Clusters) FindBySecret(organizationID uint, secretID string) ([]*model.
 	return clusters, nil
 }
 
+// Touch sets the last update time of a cluster to the current time.
+func (c *Clusters) Touch(clusterID uint) error {
+	err := c.db.Model(&model.ClusterModel{}).Where("id = ?", clusterID).UpdateColumn("updated_at", time.Now()).Error
+	if err != nil {
+		return emperror.WrapWith(err, "could not touch cluster", "clusterID", clusterID)
+	}
+
+	return nil
+}
+
 // GetConfigSecretIDByClusterID returns the kubeconfig's secretID stored in DB
 func (c *Clusters) GetConfigSecretIDByClusterID(organizationID uint, clusterID uint) (string, error) {
 	cluster := model.ClusterModel{ID: clusterID}
@@ -0,0 +1,69 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterbulk
+
+import (
+	"time"
+)
+
+// TableName constants
+const (
+	operationTableName        = "cluster_bulk_operations"
+	operationClusterTableName = "cluster_bulk_operation_clusters"
+)
+
+// OperationModel is the database model of a bulk cluster operation.
+type OperationModel struct {
+	ID             uint   `gorm:"primary_key"`
+	OrganizationID uint   `gorm:"not null;index:idx_cluster_bulk_operations_organization_id"`
+	Action         string `gorm:"not null"`
+	Selector       string `sql:"type:text;"`
+	Params         string `sql:"type:text;"`
+	Concurrency    int
+	Status         string
+	Clusters       []OperationClusterModel `gorm:"foreignkey:OperationID"`
+	CreatedBy      uint
+
+	// Owner identifies the Pipeline process executing the operation
+	Owner       string
+	HeartbeatAt *time.Time
+
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	FinishedAt *time.Time
+}
+
+// TableName changes the default table name.
+func (OperationModel) TableName() string {
+	return operationTableName
+}
+
+// OperationClusterModel is the database model of a single cluster targeted by a bulk operation.
+type OperationClusterModel struct {
+	ID          uint `gorm:"primary_key"`
+	OperationID uint `gorm:"not null;index:idx_cluster_bulk_operation_clusters_operation_id"`
+	ClusterID   uint
+	ClusterName string
+	Status      string
+	Error       string `sql:"type:text;"`
+
+	StartedAt  *time.Time
+	FinishedAt *time.Time
+}
+
+// TableName changes the default table name.
+func (OperationClusterModel) TableName() string {
+	return operationClusterTableName
+}
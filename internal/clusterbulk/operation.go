@@ -0,0 +1,277 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterbulk
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	pkgHelm "github.com/banzaicloud/pipeline/pkg/helm"
+)
+
+// ErrOperationNotFound is returned when a bulk operation cannot be found.
+var ErrOperationNotFound = errors.New("cluster bulk operation not found")
+
+// ErrInvalidOperation is returned when a bulk operation request is invalid.
+var ErrInvalidOperation = errors.New("invalid cluster bulk operation")
+
+// ErrOperationFinished is returned when a finished bulk operation is cancelled.
+var ErrOperationFinished = errors.New("cluster bulk operation is already finished")
+
+// Action is an action executed on every selected cluster.
+type Action string
+
+// Bulk operation actions
+const (
+	PostHooksAction     Action = "posthooks"
+	InstallSecretAction Action = "install_secret"
+	DeploymentAction    Action = "deployment"
+	DeleteAction        Action = "delete"
+)
+
+// Status is the status of a bulk operation or of one of its clusters.
+type Status string
+
+// Bulk operation statuses
+const (
+	StatusPending    Status = "PENDING"
+	StatusRunning    Status = "RUNNING"
+	StatusSucceeded  Status = "SUCCEEDED"
+	StatusFailed     Status = "FAILED"
+	StatusCancelling Status = "CANCELLING"
+	StatusCancelled  Status = "CANCELLED"
+)
+
+// Finished tells whether the status is final.
+func (s Status) Finished() bool {
+	return s == StatusSucceeded || s == StatusFailed || s == StatusCancelled
+}
+
+// Concurrency limits
+const (
+	DefaultConcurrency = 5
+	MaxConcurrency     = 20
+)
+
+// Selector selects the clusters of an organization a bulk operation is executed on.
+// Every non-empty criterion has to match; names may contain shell patterns (eg. "prod-*")
+// and selected clusters have to carry every label with the given value.
+type Selector struct {
+	ClusterIDs   []uint            `json:"clusterIds,omitempty"`
+	Names        []string          `json:"names,omitempty"`
+	Cloud        string            `json:"cloud,omitempty"`
+	Distribution string            `json:"distribution,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+}
+
+// Empty tells whether the selector has no criteria.
+func (s Selector) Empty() bool {
+	return len(s.ClusterIDs) == 0 && len(s.Names) == 0 && s.Cloud == "" && s.Distribution == "" && len(s.Labels) == 0
+}
+
+// Validate checks the name patterns of the selector.
+func (s Selector) Validate() error {
+	if s.Empty() {
+		return errors.WithMessage(ErrInvalidOperation, "at least one selector criterion is required")
+	}
+
+	for _, name := range s.Names {
+		if _, err := path.Match(name, ""); err != nil {
+			return errors.WithMessage(ErrInvalidOperation, fmt.Sprintf("invalid name pattern %q", name))
+		}
+	}
+
+	return nil
+}
+
+// Matches tells whether a cluster is selected.
+func (s Selector) Matches(cluster Target) bool {
+	if len(s.ClusterIDs) > 0 && !containsID(s.ClusterIDs, cluster.ClusterID) {
+		return false
+	}
+
+	if len(s.Names) > 0 && !matchesName(s.Names, cluster.ClusterName) {
+		return false
+	}
+
+	if s.Cloud != "" && s.Cloud != cluster.Cloud {
+		return false
+	}
+
+	if s.Distribution != "" && s.Distribution != cluster.Distribution {
+		return false
+	}
+
+	for name, value := range s.Labels {
+		if clusterValue, ok := cluster.Labels[name]; !ok || clusterValue != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+func containsID(ids []uint, id uint) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchesName(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Target is a cluster a bulk operation can be executed on.
+type Target struct {
+	ClusterID    uint
+	ClusterName  string
+	Cloud        string
+	Distribution string
+	Labels       map[string]string
+}
+
+// Request describes a bulk operation to be started.
+type Request struct {
+	Selector    Selector        `json:"selector"`
+	Action      Action          `json:"action" binding:"required"`
+	Params      json.RawMessage `json:"params,omitempty"`
+	Concurrency int             `json:"concurrency,omitempty"`
+}
+
+// Validate checks the selector, the action and its parameters.
+func (r Request) Validate() error {
+	if err := r.Selector.Validate(); err != nil {
+		return err
+	}
+
+	if r.Concurrency < 0 || r.Concurrency > MaxConcurrency {
+		return errors.WithMessage(ErrInvalidOperation, fmt.Sprintf("concurrency must be between 1 and %d", MaxConcurrency))
+	}
+
+	switch r.Action {
+	case PostHooksAction:
+		var params pkgCluster.PostHooks
+
+		return decodeParams(r.Params, &params)
+	case InstallSecretAction:
+		var params InstallSecretParams
+		if err := decodeParams(r.Params, &params); err != nil {
+			return err
+		}
+
+		if params.Name == "" {
+			return errors.WithMessage(ErrInvalidOperation, "secret name is required")
+		}
+	case DeploymentAction:
+		var params pkgHelm.CreateUpdateDeploymentRequest
+		if err := decodeParams(r.Params, &params); err != nil {
+			return err
+		}
+
+		if params.Name == "" || params.ReleaseName == "" {
+			return errors.WithMessage(ErrInvalidOperation, "chart name and release name are required")
+		}
+	case DeleteAction:
+		var params DeleteParams
+
+		return decodeParams(r.Params, &params)
+	default:
+		return errors.WithMessage(ErrInvalidOperation, fmt.Sprintf("unknown action %q", r.Action))
+	}
+
+	return nil
+}
+
+func decodeParams(raw json.RawMessage, params interface{}) error {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(raw, params); err != nil {
+		return errors.WithMessage(ErrInvalidOperation, "invalid action parameters: "+err.Error())
+	}
+
+	return nil
+}
+
+// InstallSecretParams are the parameters of the install secret action.
+type InstallSecretParams struct {
+	Name             string                           `json:"name"`
+	SourceSecretName string                           `json:"sourceSecretName,omitempty"`
+	Namespace        string                           `json:"namespace,omitempty"`
+	Spec             map[string]InstallSecretSpecItem `json:"spec,omitempty"`
+}
+
+// InstallSecretSpecItem describes a key of an installed secret.
+type InstallSecretSpecItem struct {
+	Source    string            `json:"source,omitempty"`
+	SourceMap map[string]string `json:"sourceMap,omitempty"`
+	Value     string            `json:"value,omitempty"`
+}
+
+// DeleteParams are the parameters of the delete action.
+type DeleteParams struct {
+	Force bool `json:"force,omitempty"`
+}
+
+// Operation is a bulk operation with the progress of every selected cluster.
+type Operation struct {
+	ID          uint               `json:"id"`
+	Action      Action             `json:"action"`
+	Selector    Selector           `json:"selector"`
+	Params      json.RawMessage    `json:"params,omitempty"`
+	Concurrency int                `json:"concurrency"`
+	Status      Status             `json:"status"`
+	Progress    Progress           `json:"progress"`
+	Clusters    []OperationCluster `json:"clusters"`
+	CreatedBy   uint               `json:"createdBy,omitempty"`
+	CreatedAt   time.Time          `json:"createdAt"`
+	FinishedAt  *time.Time         `json:"finishedAt,omitempty"`
+}
+
+// Progress summarizes the statuses of the clusters of a bulk operation.
+type Progress struct {
+	Total     int `json:"total"`
+	Pending   int `json:"pending"`
+	Running   int `json:"running"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	Cancelled int `json:"cancelled"`
+}
+
+// OperationCluster is the state of a single cluster of a bulk operation.
+type OperationCluster struct {
+	ClusterID   uint       `json:"clusterId"`
+	ClusterName string     `json:"clusterName"`
+	Status      Status     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	StartedAt   *time.Time `json:"startedAt,omitempty"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+}
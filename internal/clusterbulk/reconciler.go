@@ -0,0 +1,94 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterbulk
+
+import (
+	"time"
+
+	"github.com/goph/emperror"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// HeartbeatInterval is how often the process executing a bulk operation records a heartbeat.
+	HeartbeatInterval = time.Minute
+
+	// heartbeatTimeout is the time after which an operation without a heartbeat is considered to be interrupted.
+	heartbeatTimeout = 5 * HeartbeatInterval
+
+	reconcilerInterval = 5 * time.Minute
+)
+
+// Reconciler periodically fails bulk operations left unfinished by a stopped Pipeline process.
+// Operations are judged by their heartbeat, so operations executed by other running replicas are left alone.
+type Reconciler struct {
+	store *Store
+
+	stop chan struct{}
+
+	logger       logrus.FieldLogger
+	errorHandler emperror.Handler
+}
+
+// NewReconciler returns a new Reconciler instance.
+func NewReconciler(store *Store, logger logrus.FieldLogger, errorHandler emperror.Handler) *Reconciler {
+	return &Reconciler{
+		store:        store,
+		stop:         make(chan struct{}),
+		logger:       logger,
+		errorHandler: errorHandler,
+	}
+}
+
+// Start reconciles bulk operations right away and then periodically in the background.
+func (r *Reconciler) Start() {
+	r.logger.Info("starting cluster bulk operation reconciler")
+
+	go func() {
+		ticker := time.NewTicker(reconcilerInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := r.Reconcile(time.Now()); err != nil {
+				r.errorHandler.Handle(err)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (r *Reconciler) Stop() {
+	r.logger.Info("shutting cluster bulk operation reconciler")
+	close(r.stop)
+}
+
+// Reconcile marks the unfinished operations without a recent heartbeat failed.
+func (r *Reconciler) Reconcile(now time.Time) error {
+	interrupted, err := r.store.InterruptStale(now.Add(-heartbeatTimeout))
+	if err != nil {
+		return err
+	}
+
+	if interrupted > 0 {
+		r.logger.WithField("operations", interrupted).Info("interrupted stale cluster bulk operations")
+	}
+
+	return nil
+}
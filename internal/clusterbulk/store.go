@@ -0,0 +1,340 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterbulk
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/goph/emperror"
+	"github.com/jinzhu/gorm"
+)
+
+// interruptedMessage is recorded for clusters whose operation was interrupted by a crash or restart.
+const interruptedMessage = "operation interrupted: the Pipeline instance executing it stopped"
+
+// unfinishedStatuses are the statuses of operations and clusters still waiting for or under execution.
+// nolint: gochecknoglobals
+var unfinishedStatuses = []string{string(StatusPending), string(StatusRunning), string(StatusCancelling)}
+
+// Store persists bulk operations and the progress of their clusters.
+type Store struct {
+	db *gorm.DB
+}
+
+// NewStore returns a new Store instance.
+func NewStore(db *gorm.DB) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+// Create stores a new pending bulk operation for the given clusters.
+func (s *Store) Create(organizationID uint, userID uint, request Request, targets []Target) (*Operation, error) {
+	selector, err := json.Marshal(request.Selector)
+	if err != nil {
+		return nil, emperror.Wrap(err, "failed to marshal selector")
+	}
+
+	concurrency := request.Concurrency
+	if concurrency == 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	model := OperationModel{
+		OrganizationID: organizationID,
+		Action:         string(request.Action),
+		Selector:       string(selector),
+		Params:         string(request.Params),
+		Concurrency:    concurrency,
+		Status:         string(StatusPending),
+		CreatedBy:      userID,
+	}
+
+	for _, target := range targets {
+		model.Clusters = append(model.Clusters, OperationClusterModel{
+			ClusterID:   target.ClusterID,
+			ClusterName: target.ClusterName,
+			Status:      string(StatusPending),
+		})
+	}
+
+	if err := s.db.Create(&model).Error; err != nil {
+		return nil, emperror.WrapWith(err, "failed to create cluster bulk operation", "organizationId", organizationID)
+	}
+
+	return operationFromModel(model)
+}
+
+// Get returns a bulk operation of an organization.
+func (s *Store) Get(organizationID uint, operationID uint) (*Operation, error) {
+	var model OperationModel
+
+	err := s.db.Preload("Clusters").
+		Where(OperationModel{ID: operationID, OrganizationID: organizationID}).
+		First(&model).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, ErrOperationNotFound
+	}
+	if err != nil {
+		return nil, emperror.WrapWith(err, "failed to get cluster bulk operation", "operationId", operationID)
+	}
+
+	return operationFromModel(model)
+}
+
+// List returns the bulk operations of an organization, the latest one first.
+func (s *Store) List(organizationID uint) ([]Operation, error) {
+	var models []OperationModel
+
+	err := s.db.Preload("Clusters").
+		Where(OperationModel{OrganizationID: organizationID}).
+		Order("id desc").
+		Find(&models).Error
+	if err != nil {
+		return nil, emperror.WrapWith(err, "failed to list cluster bulk operations", "organizationId", organizationID)
+	}
+
+	operations := make([]Operation, 0, len(models))
+	for _, model := range models {
+		operation, err := operationFromModel(model)
+		if err != nil {
+			return nil, err
+		}
+
+		operations = append(operations, *operation)
+	}
+
+	return operations, nil
+}
+
+// SetStatus updates the status of a bulk operation.
+func (s *Store) SetStatus(operationID uint, status Status) error {
+	fields := map[string]interface{}{
+		"status": string(status),
+	}
+
+	if status.Finished() {
+		fields["finished_at"] = time.Now()
+	}
+
+	err := s.db.Model(&OperationModel{ID: operationID}).Updates(fields).Error
+
+	return emperror.WrapWith(err, "failed to update cluster bulk operation", "operationId", operationID)
+}
+
+// GetStatus returns the current status of a bulk operation.
+func (s *Store) GetStatus(operationID uint) (Status, error) {
+	var model OperationModel
+
+	err := s.db.Select("status").Where("id = ?", operationID).First(&model).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return "", ErrOperationNotFound
+	}
+	if err != nil {
+		return "", emperror.WrapWith(err, "failed to get cluster bulk operation status", "operationId", operationID)
+	}
+
+	return Status(model.Status), nil
+}
+
+// Start marks a pending bulk operation running and records the process executing it.
+// It returns false if the operation is not pending anymore, eg. because it was cancelled in the meantime.
+func (s *Store) Start(operationID uint, owner string) (bool, error) {
+	result := s.db.Model(&OperationModel{}).
+		Where("id = ? AND status = ?", operationID, string(StatusPending)).
+		Updates(map[string]interface{}{
+			"status":       string(StatusRunning),
+			"owner":        owner,
+			"heartbeat_at": time.Now(),
+		})
+	if result.Error != nil {
+		return false, emperror.WrapWith(result.Error, "failed to start cluster bulk operation", "operationId", operationID)
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// Heartbeat records that the owner is still executing the bulk operation.
+func (s *Store) Heartbeat(operationID uint, owner string) error {
+	err := s.db.Model(&OperationModel{}).
+		Where("id = ? AND owner = ?", operationID, owner).
+		UpdateColumn("heartbeat_at", time.Now()).Error
+
+	return emperror.WrapWith(err, "failed to record cluster bulk operation heartbeat", "operationId", operationID)
+}
+
+// SetClusterStatus updates the status of a cluster of a bulk operation.
+// A non-empty message is recorded as the error of the cluster.
+func (s *Store) SetClusterStatus(operationID uint, clusterID uint, status Status, message string) error {
+	fields := map[string]interface{}{
+		"status": string(status),
+		"error":  message,
+	}
+
+	now := time.Now()
+	if status == StatusRunning {
+		fields["started_at"] = now
+	} else if status.Finished() {
+		fields["finished_at"] = now
+	}
+
+	err := s.db.Model(&OperationClusterModel{}).
+		Where("operation_id = ? AND cluster_id = ?", operationID, clusterID).
+		Updates(fields).Error
+
+	return emperror.WrapWith(err, "failed to update cluster of bulk operation", "operationId", operationID, "clusterId", clusterID)
+}
+
+// Finish sets the final status of a bulk operation based on the statuses of its clusters.
+func (s *Store) Finish(operationID uint) (Status, error) {
+	var clusters []OperationClusterModel
+
+	if err := s.db.Where("operation_id = ?", operationID).Find(&clusters).Error; err != nil {
+		return "", emperror.WrapWith(err, "failed to get clusters of bulk operation", "operationId", operationID)
+	}
+
+	status := StatusSucceeded
+	for _, cluster := range clusters {
+		switch Status(cluster.Status) {
+		case StatusCancelled:
+			status = StatusCancelled
+		case StatusFailed:
+			if status != StatusCancelled {
+				status = StatusFailed
+			}
+		}
+	}
+
+	return status, s.SetStatus(operationID, status)
+}
+
+// InterruptStale marks unfinished bulk operations without a heartbeat since the given time
+// and their unfinished clusters failed, and returns the number of interrupted operations.
+// Operations are executed in memory, so they cannot be continued after the executing process stopped.
+// Operations that never started are judged by their creation time.
+func (s *Store) InterruptStale(heartbeatBefore time.Time) (int, error) {
+	var models []OperationModel
+
+	err := s.db.
+		Where("status IN (?)", unfinishedStatuses).
+		Where("heartbeat_at < ? OR (heartbeat_at IS NULL AND created_at < ?)", heartbeatBefore, heartbeatBefore).
+		Find(&models).Error
+	if err != nil {
+		return 0, emperror.Wrap(err, "failed to list stale cluster bulk operations")
+	}
+
+	var interrupted int
+	for _, model := range models {
+		ok, err := s.interrupt(model)
+		if err != nil {
+			return interrupted, err
+		}
+
+		if ok {
+			interrupted++
+		}
+	}
+
+	return interrupted, nil
+}
+
+// interrupt marks a stale bulk operation failed unless its owner recorded a heartbeat in the meantime.
+func (s *Store) interrupt(model OperationModel) (bool, error) {
+	now := time.Now()
+
+	query := s.db.Model(&OperationModel{}).Where("id = ? AND status IN (?)", model.ID, unfinishedStatuses)
+	if model.HeartbeatAt == nil {
+		query = query.Where("heartbeat_at IS NULL")
+	} else {
+		query = query.Where("heartbeat_at = ?", *model.HeartbeatAt)
+	}
+
+	result := query.Updates(map[string]interface{}{
+		"status":      string(StatusFailed),
+		"finished_at": now,
+	})
+	if result.Error != nil {
+		return false, emperror.WrapWith(result.Error, "failed to interrupt cluster bulk operation", "operationId", model.ID)
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	err := s.db.Model(&OperationClusterModel{}).
+		Where("operation_id = ? AND status IN (?)", model.ID, unfinishedStatuses).
+		Updates(map[string]interface{}{
+			"status":      string(StatusFailed),
+			"error":       interruptedMessage,
+			"finished_at": now,
+		}).Error
+	if err != nil {
+		return false, emperror.WrapWith(err, "failed to interrupt clusters of bulk operation", "operationId", model.ID)
+	}
+
+	return true, nil
+}
+
+func operationFromModel(model OperationModel) (*Operation, error) {
+	operation := Operation{
+		ID:          model.ID,
+		Action:      Action(model.Action),
+		Concurrency: model.Concurrency,
+		Status:      Status(model.Status),
+		Clusters:    make([]OperationCluster, 0, len(model.Clusters)),
+		CreatedBy:   model.CreatedBy,
+		CreatedAt:   model.CreatedAt,
+		FinishedAt:  model.FinishedAt,
+	}
+
+	if model.Selector != "" {
+		if err := json.Unmarshal([]byte(model.Selector), &operation.Selector); err != nil {
+			return nil, emperror.WrapWith(err, "failed to unmarshal selector", "operationId", model.ID)
+		}
+	}
+
+	if model.Params != "" {
+		operation.Params = json.RawMessage(model.Params)
+	}
+
+	for _, cluster := range model.Clusters {
+		status := Status(cluster.Status)
+
+		operation.Clusters = append(operation.Clusters, OperationCluster{
+			ClusterID:   cluster.ClusterID,
+			ClusterName: cluster.ClusterName,
+			Status:      status,
+			Error:       cluster.Error,
+			StartedAt:   cluster.StartedAt,
+			FinishedAt:  cluster.FinishedAt,
+		})
+
+		operation.Progress.Total++
+		switch status {
+		case StatusPending:
+			operation.Progress.Pending++
+		case StatusRunning:
+			operation.Progress.Running++
+		case StatusSucceeded:
+			operation.Progress.Succeeded++
+		case StatusFailed:
+			operation.Progress.Failed++
+		case StatusCancelled:
+			operation.Progress.Cancelled++
+		}
+	}
+
+	return &operation, nil
+}
@@ -0,0 +1,317 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterbulk
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(t *testing.T) *Store {
+	db, err := gorm.Open("sqlite3", "file::memory:")
+	require.NoError(t, err)
+
+	require.NoError(t, db.AutoMigrate(&OperationModel{}, &OperationClusterModel{}).Error)
+
+	return NewStore(db)
+}
+
+func TestSelector_Matches(t *testing.T) {
+	target := Target{ClusterID: 3, ClusterName: "prod-eu", Cloud: "amazon", Distribution: "eks", Labels: map[string]string{"env": "prod", "team": "web"}}
+
+	tests := map[string]struct {
+		selector Selector
+		matches  bool
+	}{
+		"id":                 {selector: Selector{ClusterIDs: []uint{1, 3}}, matches: true},
+		"other id":           {selector: Selector{ClusterIDs: []uint{1, 2}}},
+		"name pattern":       {selector: Selector{Names: []string{"dev-*", "prod-*"}}, matches: true},
+		"other name":         {selector: Selector{Names: []string{"prod"}}},
+		"cloud":              {selector: Selector{Cloud: "amazon"}, matches: true},
+		"cloud and name":     {selector: Selector{Cloud: "amazon", Names: []string{"prod-*"}}, matches: true},
+		"other cloud":        {selector: Selector{Cloud: "google", Names: []string{"prod-*"}}},
+		"distribution":       {selector: Selector{Distribution: "eks"}, matches: true},
+		"other distribution": {selector: Selector{Cloud: "amazon", Distribution: "pke"}},
+		"labels":             {selector: Selector{Labels: map[string]string{"env": "prod", "team": "web"}}, matches: true},
+		"labels and cloud":   {selector: Selector{Cloud: "amazon", Labels: map[string]string{"env": "prod"}}, matches: true},
+		"other label value":  {selector: Selector{Labels: map[string]string{"env": "dev"}}},
+		"missing label":      {selector: Selector{Labels: map[string]string{"env": "prod", "tier": "db"}}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.matches, test.selector.Matches(target))
+		})
+	}
+}
+
+func TestRequest_Validate(t *testing.T) {
+	selector := Selector{Cloud: "amazon"}
+
+	tests := map[string]struct {
+		request Request
+		valid   bool
+	}{
+		"posthooks": {
+			request: Request{Selector: selector, Action: PostHooksAction, Params: json.RawMessage(`{"InstallMonitoring": null}`)},
+			valid:   true,
+		},
+		"install secret": {
+			request: Request{Selector: selector, Action: InstallSecretAction, Params: json.RawMessage(`{"name": "registry"}`)},
+			valid:   true,
+		},
+		"deployment": {
+			request: Request{Selector: selector, Action: DeploymentAction, Params: json.RawMessage(`{"name": "stable/nginx", "releaseName": "nginx"}`)},
+			valid:   true,
+		},
+		"delete": {
+			request: Request{Selector: selector, Action: DeleteAction, Concurrency: MaxConcurrency},
+			valid:   true,
+		},
+		"empty selector": {
+			request: Request{Action: DeleteAction},
+		},
+		"invalid name pattern": {
+			request: Request{Selector: Selector{Names: []string{"prod-["}}, Action: DeleteAction},
+		},
+		"unknown action": {
+			request: Request{Selector: selector, Action: "restart"},
+		},
+		"missing secret name": {
+			request: Request{Selector: selector, Action: InstallSecretAction, Params: json.RawMessage(`{}`)},
+		},
+		"missing release name": {
+			request: Request{Selector: selector, Action: DeploymentAction, Params: json.RawMessage(`{"name": "stable/nginx"}`)},
+		},
+		"invalid params": {
+			request: Request{Selector: selector, Action: DeleteAction, Params: json.RawMessage(`{"force": "yes"}`)},
+		},
+		"too much concurrency": {
+			request: Request{Selector: selector, Action: DeleteAction, Concurrency: MaxConcurrency + 1},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := test.request.Validate()
+
+			if test.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, ErrInvalidOperation, errors.Cause(err))
+			}
+		})
+	}
+}
+
+func TestStore(t *testing.T) {
+	store := newTestStore(t)
+
+	request := Request{
+		Selector: Selector{Names: []string{"prod-*"}},
+		Action:   InstallSecretAction,
+		Params:   json.RawMessage(`{"name":"registry"}`),
+	}
+	targets := []Target{
+		{ClusterID: 1, ClusterName: "prod-eu"},
+		{ClusterID: 2, ClusterName: "prod-us"},
+		{ClusterID: 3, ClusterName: "prod-ap"},
+	}
+
+	operation, err := store.Create(1, 10, request, targets)
+	require.NoError(t, err)
+
+	assert.Equal(t, StatusPending, operation.Status)
+	assert.Equal(t, DefaultConcurrency, operation.Concurrency)
+	assert.Equal(t, Progress{Total: 3, Pending: 3}, operation.Progress)
+
+	_, err = store.Get(2, operation.ID)
+	assert.Equal(t, ErrOperationNotFound, err)
+
+	require.NoError(t, store.SetStatus(operation.ID, StatusRunning))
+	require.NoError(t, store.SetClusterStatus(operation.ID, 1, StatusSucceeded, ""))
+	require.NoError(t, store.SetClusterStatus(operation.ID, 2, StatusFailed, "secret not found"))
+	require.NoError(t, store.SetClusterStatus(operation.ID, 3, StatusRunning, ""))
+
+	operation, err = store.Get(1, operation.ID)
+	require.NoError(t, err)
+
+	assert.Equal(t, StatusRunning, operation.Status)
+	assert.Equal(t, request.Selector, operation.Selector)
+	assert.JSONEq(t, string(request.Params), string(operation.Params))
+	assert.Equal(t, Progress{Total: 3, Running: 1, Succeeded: 1, Failed: 1}, operation.Progress)
+	assert.Equal(t, "secret not found", operation.Clusters[1].Error)
+	assert.NotNil(t, operation.Clusters[2].StartedAt)
+	assert.Nil(t, operation.Clusters[2].FinishedAt)
+
+	require.NoError(t, store.SetClusterStatus(operation.ID, 3, StatusSucceeded, ""))
+
+	status, err := store.Finish(operation.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailed, status)
+
+	operations, err := store.List(1)
+	require.NoError(t, err)
+	require.Len(t, operations, 1)
+	assert.Equal(t, StatusFailed, operations[0].Status)
+	assert.NotNil(t, operations[0].FinishedAt)
+}
+
+func TestStore_Finish(t *testing.T) {
+	tests := map[string]struct {
+		statuses []Status
+		status   Status
+	}{
+		"succeeded": {statuses: []Status{StatusSucceeded, StatusSucceeded}, status: StatusSucceeded},
+		"failed":    {statuses: []Status{StatusSucceeded, StatusFailed}, status: StatusFailed},
+		"cancelled": {statuses: []Status{StatusFailed, StatusCancelled}, status: StatusCancelled},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			store := newTestStore(t)
+
+			var targets []Target
+			for i := range test.statuses {
+				targets = append(targets, Target{ClusterID: uint(i + 1)})
+			}
+
+			operation, err := store.Create(1, 10, Request{Action: DeleteAction}, targets)
+			require.NoError(t, err)
+
+			for i, status := range test.statuses {
+				require.NoError(t, store.SetClusterStatus(operation.ID, uint(i+1), status, ""))
+			}
+
+			status, err := store.Finish(operation.ID)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.status, status)
+		})
+	}
+}
+
+func TestStore_Start(t *testing.T) {
+	store := newTestStore(t)
+
+	operation, err := store.Create(1, 10, Request{Action: DeleteAction}, []Target{{ClusterID: 1}})
+	require.NoError(t, err)
+
+	started, err := store.Start(operation.ID, "replica-1")
+	require.NoError(t, err)
+	assert.True(t, started)
+
+	status, err := store.GetStatus(operation.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusRunning, status)
+
+	started, err = store.Start(operation.ID, "replica-2")
+	require.NoError(t, err)
+	assert.False(t, started)
+
+	cancelled, err := store.Create(1, 10, Request{Action: DeleteAction}, []Target{{ClusterID: 1}})
+	require.NoError(t, err)
+	require.NoError(t, store.SetStatus(cancelled.ID, StatusCancelling))
+
+	started, err = store.Start(cancelled.ID, "replica-1")
+	require.NoError(t, err)
+	assert.False(t, started)
+
+	status, err = store.GetStatus(cancelled.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusCancelling, status)
+
+	_, err = store.GetStatus(100)
+	assert.Equal(t, ErrOperationNotFound, err)
+}
+
+func TestStore_InterruptStale(t *testing.T) {
+	store := newTestStore(t)
+
+	targets := []Target{{ClusterID: 1}, {ClusterID: 2}}
+	now := time.Now()
+
+	setHeartbeat := func(operationID uint, heartbeatAt time.Time) {
+		err := store.db.Model(&OperationModel{}).Where("id = ?", operationID).UpdateColumn("heartbeat_at", heartbeatAt).Error
+		require.NoError(t, err)
+	}
+
+	finished, err := store.Create(1, 10, Request{Action: DeleteAction}, targets)
+	require.NoError(t, err)
+	require.NoError(t, store.SetClusterStatus(finished.ID, 1, StatusSucceeded, ""))
+	require.NoError(t, store.SetClusterStatus(finished.ID, 2, StatusSucceeded, ""))
+	_, err = store.Finish(finished.ID)
+	require.NoError(t, err)
+	setHeartbeat(finished.ID, now.Add(-time.Hour))
+
+	stale, err := store.Create(1, 10, Request{Action: DeleteAction}, targets)
+	require.NoError(t, err)
+	_, err = store.Start(stale.ID, "replica-1")
+	require.NoError(t, err)
+	require.NoError(t, store.SetClusterStatus(stale.ID, 1, StatusSucceeded, ""))
+	require.NoError(t, store.SetClusterStatus(stale.ID, 2, StatusRunning, ""))
+	setHeartbeat(stale.ID, now.Add(-time.Hour))
+
+	alive, err := store.Create(1, 10, Request{Action: DeleteAction}, targets)
+	require.NoError(t, err)
+	_, err = store.Start(alive.ID, "replica-2")
+	require.NoError(t, err)
+	require.NoError(t, store.SetClusterStatus(alive.ID, 1, StatusRunning, ""))
+	require.NoError(t, store.Heartbeat(alive.ID, "replica-2"))
+
+	pending, err := store.Create(1, 10, Request{Action: DeleteAction}, targets)
+	require.NoError(t, err)
+
+	interrupted, err := store.InterruptStale(now.Add(-10 * time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, interrupted)
+
+	operation, err := store.Get(1, finished.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusSucceeded, operation.Status)
+
+	operation, err = store.Get(1, stale.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailed, operation.Status)
+	assert.Equal(t, StatusSucceeded, operation.Clusters[0].Status)
+	assert.Equal(t, StatusFailed, operation.Clusters[1].Status)
+	assert.Equal(t, interruptedMessage, operation.Clusters[1].Error)
+
+	operation, err = store.Get(1, alive.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusRunning, operation.Status)
+	assert.Equal(t, StatusRunning, operation.Clusters[0].Status)
+
+	operation, err = store.Get(1, pending.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusPending, operation.Status)
+
+	// operations that were never started are judged by their creation time
+	interrupted, err = store.InterruptStale(now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 2, interrupted)
+
+	operation, err = store.Get(1, pending.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailed, operation.Status)
+	assert.Equal(t, StatusFailed, operation.Clusters[0].Status)
+}
@@ -0,0 +1,40 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterlabel
+
+import (
+	"time"
+)
+
+// TableName constants
+const (
+	labelTableName = "cluster_labels"
+)
+
+// LabelModel is a label of a cluster used to select it in applications and bulk operations.
+type LabelModel struct {
+	ID        uint   `gorm:"primary_key"`
+	ClusterID uint   `gorm:"not null;unique_index:idx_cluster_labels_cluster_id_name"`
+	Name      string `gorm:"not null;unique_index:idx_cluster_labels_cluster_id_name"`
+	Value     string
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TableName changes the default table name.
+func (LabelModel) TableName() string {
+	return labelTableName
+}
@@ -0,0 +1,127 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterlabel
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/goph/emperror"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ErrInvalidLabels is returned when cluster labels are invalid.
+var ErrInvalidLabels = errors.New("invalid cluster labels")
+
+// Validate checks whether the labels are valid Kubernetes labels.
+func Validate(labels map[string]string) error {
+	for name, value := range labels {
+		if errs := validation.IsQualifiedName(name); len(errs) > 0 {
+			return errors.WithMessage(ErrInvalidLabels, fmt.Sprintf("invalid label name %q: %s", name, strings.Join(errs, ", ")))
+		}
+
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return errors.WithMessage(ErrInvalidLabels, fmt.Sprintf("invalid value of label %q: %s", name, strings.Join(errs, ", ")))
+		}
+	}
+
+	return nil
+}
+
+// Store manages the labels of clusters.
+type Store struct {
+	db *gorm.DB
+}
+
+// NewStore returns a new Store instance.
+func NewStore(db *gorm.DB) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+// Get returns the labels of a cluster.
+func (s *Store) Get(clusterID uint) (map[string]string, error) {
+	labels, err := s.List([]uint{clusterID})
+	if err != nil {
+		return nil, err
+	}
+
+	if labels[clusterID] == nil {
+		return map[string]string{}, nil
+	}
+
+	return labels[clusterID], nil
+}
+
+// List returns the labels of the given clusters keyed by cluster ID. Clusters without labels are left out.
+func (s *Store) List(clusterIDs []uint) (map[uint]map[string]string, error) {
+	labels := make(map[uint]map[string]string)
+
+	if len(clusterIDs) == 0 {
+		return labels, nil
+	}
+
+	var models []LabelModel
+	if err := s.db.Where("cluster_id IN (?)", clusterIDs).Find(&models).Error; err != nil {
+		return nil, emperror.Wrap(err, "failed to list cluster labels")
+	}
+
+	for _, model := range models {
+		if labels[model.ClusterID] == nil {
+			labels[model.ClusterID] = make(map[string]string)
+		}
+
+		labels[model.ClusterID][model.Name] = model.Value
+	}
+
+	return labels, nil
+}
+
+// Set validates the labels and replaces the labels of a cluster with them.
+func (s *Store) Set(clusterID uint, labels map[string]string) error {
+	if err := Validate(labels); err != nil {
+		return err
+	}
+
+	tx := s.db.Begin()
+	if err := tx.Error; err != nil {
+		return emperror.Wrap(err, "failed to start transaction")
+	}
+
+	if err := tx.Where(&LabelModel{ClusterID: clusterID}).Delete(&LabelModel{}).Error; err != nil {
+		tx.Rollback()
+
+		return emperror.WrapWith(err, "failed to delete cluster labels", "clusterId", clusterID)
+	}
+
+	for name, value := range labels {
+		model := LabelModel{
+			ClusterID: clusterID,
+			Name:      name,
+			Value:     value,
+		}
+
+		if err := tx.Create(&model).Error; err != nil {
+			tx.Rollback()
+
+			return emperror.WrapWith(err, "failed to create cluster label", "clusterId", clusterID, "label", name)
+		}
+	}
+
+	return emperror.WrapWith(tx.Commit().Error, "failed to save cluster labels", "clusterId", clusterID)
+}
@@ -0,0 +1,90 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterlabel
+
+import (
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(t *testing.T) *Store {
+	db, err := gorm.Open("sqlite3", "file::memory:")
+	require.NoError(t, err)
+
+	require.NoError(t, db.AutoMigrate(&LabelModel{}).Error)
+
+	return NewStore(db)
+}
+
+func TestValidate(t *testing.T) {
+	tests := map[string]struct {
+		labels map[string]string
+		valid  bool
+	}{
+		"valid":         {labels: map[string]string{"env": "prod", "example.com/team": "web"}, valid: true},
+		"empty value":   {labels: map[string]string{"env": ""}, valid: true},
+		"invalid name":  {labels: map[string]string{"env prod": "true"}},
+		"invalid value": {labels: map[string]string{"env": "prod/eu"}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := Validate(test.labels)
+
+			if test.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, ErrInvalidLabels, errors.Cause(err))
+			}
+		})
+	}
+}
+
+func TestStore(t *testing.T) {
+	store := newTestStore(t)
+
+	labels, err := store.Get(1)
+	require.NoError(t, err)
+	assert.Empty(t, labels)
+
+	require.NoError(t, store.Set(1, map[string]string{"env": "prod", "team": "web"}))
+	require.NoError(t, store.Set(2, map[string]string{"env": "dev"}))
+
+	// labels are replaced
+	require.NoError(t, store.Set(1, map[string]string{"env": "staging"}))
+
+	labels, err = store.Get(1)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "staging"}, labels)
+
+	all, err := store.List([]uint{1, 2, 3})
+	require.NoError(t, err)
+	assert.Equal(t, map[uint]map[string]string{
+		1: {"env": "staging"},
+		2: {"env": "dev"},
+	}, all)
+
+	err = store.Set(2, map[string]string{"env": "prod/eu"})
+	assert.Equal(t, ErrInvalidLabels, errors.Cause(err))
+
+	labels, err = store.Get(2)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "dev"}, labels)
+}
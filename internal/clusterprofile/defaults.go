@@ -0,0 +1,123 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterprofile
+
+import (
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	"github.com/banzaicloud/pipeline/pkg/cluster/ack"
+)
+
+const (
+	defaultNodePoolName      = "pool1"
+	defaultPKEKubernetes     = "1.13.3"
+	defaultACKRegion         = "eu-central-1"
+	defaultACKZone           = "eu-central-1a"
+	defaultAzureLocation     = "westeurope"
+	defaultNodePoolMinCount  = 1
+	defaultNodePoolMaxCount  = 2
+	defaultPKEInstanceType   = "c5.large"
+	defaultAzureInstanceType = "Standard_B2s"
+)
+
+// DefaultProfiles returns the built-in global default profiles of the distributions
+// that had no profile support before organization scoped profiles.
+func DefaultProfiles(name string) []pkgCluster.ClusterProfileResponse {
+	return []pkgCluster.ClusterProfileResponse{
+		{
+			Name:         name,
+			Location:     defaultACKRegion,
+			Cloud:        pkgCluster.Alibaba,
+			Distribution: pkgCluster.ACK,
+			Properties: &pkgCluster.ClusterProfileProperties{
+				ACK: &ack.ClusterProfileACK{
+					RegionID: defaultACKRegion,
+					ZoneID:   defaultACKZone,
+					NodePools: map[string]*ack.NodePool{
+						defaultNodePoolName: {
+							InstanceType: ack.DefaultWorkerInstanceType,
+							MinCount:     defaultNodePoolMinCount,
+							MaxCount:     defaultNodePoolMaxCount,
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:         name,
+			Cloud:        pkgCluster.Amazon,
+			Distribution: pkgCluster.PKE,
+			Properties: &pkgCluster.ClusterProfileProperties{
+				PKE: map[string]interface{}{
+					"kubernetes": map[string]interface{}{
+						"version": defaultPKEKubernetes,
+						"rbac":    map[string]interface{}{"enabled": true},
+					},
+					"cri": map[string]interface{}{
+						"runtime": "containerd",
+					},
+					"nodepools": []interface{}{
+						map[string]interface{}{
+							"name":     defaultNodePoolName,
+							"roles":    []interface{}{"master", "worker"},
+							"provider": "amazon",
+							"providerConfig": map[string]interface{}{
+								"autoScalingGroup": map[string]interface{}{
+									"instanceType": defaultPKEInstanceType,
+									"size": map[string]interface{}{
+										"min": defaultNodePoolMinCount,
+										"max": defaultNodePoolMinCount,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:         name,
+			Location:     defaultAzureLocation,
+			Cloud:        pkgCluster.Azure,
+			Distribution: pkgCluster.PKE,
+			Properties: &pkgCluster.ClusterProfileProperties{
+				PKEOnAzure: map[string]interface{}{
+					"kubernetes": map[string]interface{}{
+						"version": defaultPKEKubernetes,
+						"rbac":    true,
+					},
+					"nodepools": []interface{}{
+						map[string]interface{}{
+							"name":         "master",
+							"roles":        []interface{}{"master"},
+							"instanceType": defaultAzureInstanceType,
+							"count":        1,
+							"minCount":     1,
+							"maxCount":     1,
+						},
+						map[string]interface{}{
+							"name":         defaultNodePoolName,
+							"roles":        []interface{}{"worker"},
+							"instanceType": defaultAzureInstanceType,
+							"autoscaling":  true,
+							"count":        defaultNodePoolMinCount,
+							"minCount":     defaultNodePoolMinCount,
+							"maxCount":     defaultNodePoolMaxCount,
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterprofile
+
+import (
+	"time"
+)
+
+// TableName constants
+const (
+	profileTableName = "cluster_profiles"
+)
+
+// ProfileModel is a cluster profile of an organization.
+// Profiles with zero organization ID are global and readable by every organization.
+type ProfileModel struct {
+	ID             uint   `gorm:"primary_key"`
+	OrganizationID uint   `gorm:"not null;unique_index:idx_cluster_profiles_org_type_name"`
+	Type           string `gorm:"not null;unique_index:idx_cluster_profiles_org_type_name"`
+	Name           string `gorm:"not null;unique_index:idx_cluster_profiles_org_type_name"`
+	Location       string
+	TtlMinutes     uint   `gorm:"not null;default:0"`
+	Properties     string `sql:"type:text;"`
+	CreatedBy      uint
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TableName changes the default table name.
+func (ProfileModel) TableName() string {
+	return profileTableName
+}
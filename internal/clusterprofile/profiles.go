@@ -0,0 +1,521 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterprofile
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	"github.com/goph/emperror"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// Profile types
+const (
+	TypeACK        = "ack"
+	TypeEKS        = "eks"
+	TypeAKS        = "aks"
+	TypeGKE        = "gke"
+	TypeOKE        = "oke"
+	TypePKE        = "pke"
+	TypePKEOnAzure = "pke-on-azure"
+)
+
+// ErrProfileNotFound is returned when a profile cannot be found.
+var ErrProfileNotFound = errors.New("cluster profile not found")
+
+// ErrProfileAlreadyExists is returned when an organization already has a profile with the same name.
+var ErrProfileAlreadyExists = errors.New("cluster profile already exists")
+
+// ErrInvalidProfile is returned when a profile is invalid.
+var ErrInvalidProfile = errors.New("invalid cluster profile")
+
+// ErrGlobalProfile is returned when a global profile is about to be modified by an organization.
+var ErrGlobalProfile = errors.New("global cluster profiles cannot be modified")
+
+type profileType struct {
+	cloud         string
+	distribution  string
+	propertiesKey string
+}
+
+var profileTypes = map[string]profileType{
+	TypeACK:        {cloud: pkgCluster.Alibaba, distribution: pkgCluster.ACK, propertiesKey: "ack"},
+	TypeEKS:        {cloud: pkgCluster.Amazon, distribution: pkgCluster.EKS, propertiesKey: "eks"},
+	TypeAKS:        {cloud: pkgCluster.Azure, distribution: pkgCluster.AKS, propertiesKey: "aks"},
+	TypeGKE:        {cloud: pkgCluster.Google, distribution: pkgCluster.GKE, propertiesKey: "gke"},
+	TypeOKE:        {cloud: pkgCluster.Oracle, distribution: pkgCluster.OKE, propertiesKey: "oke"},
+	TypePKE:        {cloud: pkgCluster.Amazon, distribution: pkgCluster.PKE, propertiesKey: "pke"},
+	TypePKEOnAzure: {cloud: pkgCluster.Azure, distribution: pkgCluster.PKE, propertiesKey: "pkeOnAzure"},
+}
+
+// ProfileType returns the profile type of a cloud and distribution.
+// When no distribution is given, the managed Kubernetes distribution of the cloud is assumed.
+func ProfileType(cloud string, distribution string) (string, error) {
+	for name, t := range profileTypes {
+		if t.cloud != cloud {
+			continue
+		}
+
+		if distribution == t.distribution || (distribution == "" && t.distribution != pkgCluster.PKE) {
+			return name, nil
+		}
+	}
+
+	return "", errors.WithMessage(ErrInvalidProfile, fmt.Sprintf("unsupported cloud and distribution: %s/%s", cloud, distribution))
+}
+
+// Profiles manages the cluster profiles of organizations.
+//
+// Every profile inherits the fields it leaves empty from the global default profile of its type.
+// Global profiles are readable by every organization, which can override them with own profiles of the same name.
+type Profiles struct {
+	db          *gorm.DB
+	defaultName string
+}
+
+// NewProfiles returns a new Profiles instance.
+func NewProfiles(db *gorm.DB, defaultName string) *Profiles {
+	return &Profiles{
+		db:          db,
+		defaultName: defaultName,
+	}
+}
+
+type profile struct {
+	OrganizationID uint
+	Type           string
+	Name           string
+	Location       string
+	TtlMinutes     uint
+	Properties     map[string]interface{}
+}
+
+// List returns the profiles of a type visible to an organization.
+func (p *Profiles) List(organizationID uint, typ string) ([]pkgCluster.ClusterProfileResponse, error) {
+	if _, ok := profileTypes[typ]; !ok {
+		return nil, errors.WithMessage(ErrInvalidProfile, fmt.Sprintf("unsupported profile type: %s", typ))
+	}
+
+	var models []ProfileModel
+
+	err := p.db.
+		Where("organization_id IN (?) AND type = ?", []uint{0, organizationID}, typ).
+		Order("organization_id").
+		Find(&models).Error
+	if err != nil {
+		return nil, emperror.WrapWith(err, "failed to list cluster profiles", "organization", organizationID, "type", typ)
+	}
+
+	profiles := make(map[string]profile, len(models))
+	for _, model := range models {
+		prof, err := profileFromModel(model)
+		if err != nil {
+			return nil, err
+		}
+
+		// organization profiles come last and override global ones
+		profiles[model.Name] = prof
+	}
+
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	defaultProfile, hasDefault := profiles[p.defaultName]
+	if hasDefault && defaultProfile.OrganizationID != 0 {
+		hasDefault = false
+	}
+
+	responses := make([]pkgCluster.ClusterProfileResponse, 0, len(names))
+	for _, name := range names {
+		prof := profiles[name]
+		if hasDefault {
+			prof = inherit(defaultProfile, prof)
+		}
+
+		response, err := prof.response()
+		if err != nil {
+			return nil, err
+		}
+
+		responses = append(responses, *response)
+	}
+
+	return responses, nil
+}
+
+// Get returns a profile visible to an organization with its inherited fields filled in.
+func (p *Profiles) Get(organizationID uint, typ string, name string) (*pkgCluster.ClusterProfileResponse, error) {
+	prof, err := p.find(organizationID, typ, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if prof.Name != p.defaultName {
+		defaultProfile, err := p.find(0, typ, p.defaultName)
+		if err != nil && errors.Cause(err) != ErrProfileNotFound {
+			return nil, err
+		}
+
+		if err == nil {
+			*prof = inherit(*defaultProfile, *prof)
+		}
+	}
+
+	return prof.response()
+}
+
+// Create creates a new profile for an organization.
+func (p *Profiles) Create(organizationID uint, userID uint, request pkgCluster.ClusterProfileRequest) error {
+	prof, err := p.profileFromRequest(organizationID, request)
+	if err != nil {
+		return err
+	}
+
+	existing, err := p.findModel(organizationID, prof.Type, prof.Name)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return ErrProfileAlreadyExists
+	}
+
+	model, err := prof.model()
+	if err != nil {
+		return err
+	}
+	model.CreatedBy = userID
+
+	err = p.db.Create(&model).Error
+
+	return emperror.WrapWith(err, "failed to create cluster profile", "organization", organizationID, "name", prof.Name)
+}
+
+// Update overwrites the fields of an organization's profile set in the request.
+func (p *Profiles) Update(organizationID uint, request pkgCluster.ClusterProfileRequest) error {
+	update, err := p.profileFromRequest(organizationID, request)
+	if err != nil {
+		return err
+	}
+
+	model, err := p.findOwnModel(organizationID, update.Type, update.Name)
+	if err != nil {
+		return err
+	}
+
+	prof, err := profileFromModel(*model)
+	if err != nil {
+		return err
+	}
+
+	updated, err := inherit(prof, update).model()
+	if err != nil {
+		return err
+	}
+
+	model.Location = updated.Location
+	model.TtlMinutes = updated.TtlMinutes
+	model.Properties = updated.Properties
+
+	err = p.db.Save(model).Error
+
+	return emperror.WrapWith(err, "failed to update cluster profile", "organization", organizationID, "name", update.Name)
+}
+
+// Delete deletes a profile of an organization.
+func (p *Profiles) Delete(organizationID uint, typ string, name string) error {
+	model, err := p.findOwnModel(organizationID, typ, name)
+	if err != nil {
+		return err
+	}
+
+	err = p.db.Delete(model).Error
+
+	return emperror.WrapWith(err, "failed to delete cluster profile", "organization", organizationID, "name", name)
+}
+
+// EnsureGlobalProfiles saves the given profiles as global ones unless a global profile with the same name exists.
+func (p *Profiles) EnsureGlobalProfiles(profiles []pkgCluster.ClusterProfileResponse) error {
+	for _, response := range profiles {
+		typ, err := ProfileType(response.Cloud, response.Distribution)
+		if err != nil {
+			return err
+		}
+
+		existing, err := p.findModel(0, typ, response.Name)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			continue
+		}
+
+		properties, err := propertiesOf(typ, response.Properties)
+		if err != nil {
+			return err
+		}
+
+		model, err := profile{
+			Type:       typ,
+			Name:       response.Name,
+			Location:   response.Location,
+			TtlMinutes: response.TtlMinutes,
+			Properties: properties,
+		}.model()
+		if err != nil {
+			return err
+		}
+
+		if err := p.db.Create(&model).Error; err != nil {
+			return emperror.WrapWith(err, "failed to create global cluster profile", "type", typ, "name", response.Name)
+		}
+	}
+
+	return nil
+}
+
+func (p *Profiles) profileFromRequest(organizationID uint, request pkgCluster.ClusterProfileRequest) (profile, error) {
+	if request.Name == p.defaultName {
+		return profile{}, errors.WithMessage(ErrInvalidProfile, fmt.Sprintf("the profile name %q is reserved", p.defaultName))
+	}
+
+	typ, err := ProfileType(request.Cloud, request.Distribution)
+	if err != nil {
+		return profile{}, err
+	}
+
+	properties, err := propertiesOf(typ, request.Properties)
+	if err != nil {
+		return profile{}, err
+	}
+
+	return profile{
+		OrganizationID: organizationID,
+		Type:           typ,
+		Name:           request.Name,
+		Location:       request.Location,
+		TtlMinutes:     request.TtlMinutes,
+		Properties:     properties,
+	}, nil
+}
+
+// find returns the profile of an organization or the global one with the same name.
+func (p *Profiles) find(organizationID uint, typ string, name string) (*profile, error) {
+	if _, ok := profileTypes[typ]; !ok {
+		return nil, errors.WithMessage(ErrInvalidProfile, fmt.Sprintf("unsupported profile type: %s", typ))
+	}
+
+	model, err := p.findModel(organizationID, typ, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if model == nil && organizationID != 0 {
+		model, err = p.findModel(0, typ, name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if model == nil {
+		return nil, ErrProfileNotFound
+	}
+
+	prof, err := profileFromModel(*model)
+	if err != nil {
+		return nil, err
+	}
+
+	return &prof, nil
+}
+
+// findOwnModel returns a profile owned by an organization.
+func (p *Profiles) findOwnModel(organizationID uint, typ string, name string) (*ProfileModel, error) {
+	if _, ok := profileTypes[typ]; !ok {
+		return nil, errors.WithMessage(ErrInvalidProfile, fmt.Sprintf("unsupported profile type: %s", typ))
+	}
+
+	model, err := p.findModel(organizationID, typ, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if model != nil {
+		return model, nil
+	}
+
+	global, err := p.findModel(0, typ, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if global != nil {
+		return nil, ErrGlobalProfile
+	}
+
+	return nil, ErrProfileNotFound
+}
+
+func (p *Profiles) findModel(organizationID uint, typ string, name string) (*ProfileModel, error) {
+	var model ProfileModel
+
+	err := p.db.
+		Where("organization_id = ? AND type = ? AND name = ?", organizationID, typ, name).
+		First(&model).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, emperror.WrapWith(err, "failed to get cluster profile", "organization", organizationID, "type", typ, "name", name)
+	}
+
+	return &model, nil
+}
+
+// propertiesOf returns the distribution specific properties of a profile as a generic object.
+func propertiesOf(typ string, properties *pkgCluster.ClusterProfileProperties) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+
+	if properties == nil {
+		return result, nil
+	}
+
+	// TODO (colin): remove this after we deleted the deprecated 'acsk' property from cluster profiles
+	if properties.ACSK != nil && properties.ACK == nil {
+		properties.ACK = properties.ACSK
+	}
+
+	raw, err := json.Marshal(properties)
+	if err != nil {
+		return nil, emperror.Wrap(err, "failed to marshal profile properties")
+	}
+
+	var all map[string]interface{}
+	if err := json.Unmarshal(raw, &all); err != nil {
+		return nil, emperror.Wrap(err, "failed to unmarshal profile properties")
+	}
+
+	if value, ok := all[profileTypes[typ].propertiesKey].(map[string]interface{}); ok {
+		result = value
+	}
+
+	return result, nil
+}
+
+func profileFromModel(model ProfileModel) (profile, error) {
+	prof := profile{
+		OrganizationID: model.OrganizationID,
+		Type:           model.Type,
+		Name:           model.Name,
+		Location:       model.Location,
+		TtlMinutes:     model.TtlMinutes,
+		Properties:     make(map[string]interface{}),
+	}
+
+	if model.Properties != "" {
+		if err := json.Unmarshal([]byte(model.Properties), &prof.Properties); err != nil {
+			return prof, emperror.WrapWith(err, "failed to unmarshal profile properties", "profile", model.Name)
+		}
+	}
+
+	return prof, nil
+}
+
+func (prof profile) model() (ProfileModel, error) {
+	properties, err := json.Marshal(prof.Properties)
+	if err != nil {
+		return ProfileModel{}, emperror.Wrap(err, "failed to marshal profile properties")
+	}
+
+	return ProfileModel{
+		OrganizationID: prof.OrganizationID,
+		Type:           prof.Type,
+		Name:           prof.Name,
+		Location:       prof.Location,
+		TtlMinutes:     prof.TtlMinutes,
+		Properties:     string(properties),
+	}, nil
+}
+
+func (prof profile) response() (*pkgCluster.ClusterProfileResponse, error) {
+	t := profileTypes[prof.Type]
+
+	raw, err := json.Marshal(map[string]interface{}{t.propertiesKey: prof.Properties})
+	if err != nil {
+		return nil, emperror.Wrap(err, "failed to marshal profile properties")
+	}
+
+	var properties pkgCluster.ClusterProfileProperties
+	if err := json.Unmarshal(raw, &properties); err != nil {
+		return nil, emperror.WrapWith(err, "failed to unmarshal profile properties", "profile", prof.Name)
+	}
+
+	return &pkgCluster.ClusterProfileResponse{
+		Name:         prof.Name,
+		Location:     prof.Location,
+		Cloud:        t.cloud,
+		Distribution: t.distribution,
+		Global:       prof.OrganizationID == 0,
+		TtlMinutes:   prof.TtlMinutes,
+		Properties:   &properties,
+	}, nil
+}
+
+// inherit returns the child profile with its empty fields filled in from the parent.
+// Properties are inherited one by one: a property set in the child (eg. the node pools) replaces the parent's one.
+func inherit(parent profile, child profile) profile {
+	result := child
+
+	if result.Location == "" {
+		result.Location = parent.Location
+	}
+
+	if result.TtlMinutes == 0 {
+		result.TtlMinutes = parent.TtlMinutes
+	}
+
+	result.Properties = make(map[string]interface{}, len(parent.Properties)+len(child.Properties))
+	for key, value := range parent.Properties {
+		result.Properties[key] = value
+	}
+	for key, value := range child.Properties {
+		if !isEmpty(value) {
+			result.Properties[key] = value
+		}
+	}
+
+	return result
+}
+
+func isEmpty(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	default:
+		return reflect.DeepEqual(value, reflect.Zero(v.Type()).Interface())
+	}
+}
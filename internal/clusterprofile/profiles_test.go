@@ -0,0 +1,207 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterprofile
+
+import (
+	"testing"
+
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	"github.com/banzaicloud/pipeline/pkg/cluster/eks"
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestProfiles(t *testing.T) *Profiles {
+	db, err := gorm.Open("sqlite3", "file::memory:")
+	require.NoError(t, err)
+
+	require.NoError(t, db.AutoMigrate(&ProfileModel{}).Error)
+
+	profiles := NewProfiles(db, "default")
+
+	require.NoError(t, profiles.EnsureGlobalProfiles([]pkgCluster.ClusterProfileResponse{
+		{
+			Name:         "default",
+			Location:     "us-west-2",
+			Cloud:        pkgCluster.Amazon,
+			Distribution: pkgCluster.EKS,
+			TtlMinutes:   60,
+			Properties: &pkgCluster.ClusterProfileProperties{
+				EKS: &eks.ClusterProfileEKS{
+					Version: "1.12",
+					NodePools: map[string]*eks.NodePool{
+						"pool1": {InstanceType: "m4.xlarge", Count: 1},
+					},
+				},
+			},
+		},
+		{
+			Name:         "large",
+			Location:     "eu-west-1",
+			Cloud:        pkgCluster.Amazon,
+			Distribution: pkgCluster.EKS,
+			Properties: &pkgCluster.ClusterProfileProperties{
+				EKS: &eks.ClusterProfileEKS{
+					NodePools: map[string]*eks.NodePool{
+						"pool1": {InstanceType: "m4.4xlarge", Count: 3},
+					},
+				},
+			},
+		},
+	}))
+
+	return profiles
+}
+
+func TestProfileType(t *testing.T) {
+	tests := []struct {
+		cloud        string
+		distribution string
+		profileType  string
+	}{
+		{pkgCluster.Alibaba, "", TypeACK},
+		{pkgCluster.Amazon, "", TypeEKS},
+		{pkgCluster.Amazon, pkgCluster.EKS, TypeEKS},
+		{pkgCluster.Amazon, pkgCluster.PKE, TypePKE},
+		{pkgCluster.Azure, "", TypeAKS},
+		{pkgCluster.Azure, pkgCluster.PKE, TypePKEOnAzure},
+		{pkgCluster.Google, "", TypeGKE},
+		{pkgCluster.Oracle, "", TypeOKE},
+	}
+
+	for _, test := range tests {
+		profileType, err := ProfileType(test.cloud, test.distribution)
+		require.NoError(t, err)
+		assert.Equal(t, test.profileType, profileType)
+	}
+
+	_, err := ProfileType(pkgCluster.Google, pkgCluster.PKE)
+	assert.Equal(t, ErrInvalidProfile, errors.Cause(err))
+}
+
+func TestProfiles_Inheritance(t *testing.T) {
+	profiles := newTestProfiles(t)
+
+	profile, err := profiles.Get(1, TypeEKS, "large")
+	require.NoError(t, err)
+
+	assert.True(t, profile.Global)
+	assert.Equal(t, "eu-west-1", profile.Location)
+	assert.Equal(t, uint(60), profile.TtlMinutes)
+	assert.Equal(t, "1.12", profile.Properties.EKS.Version)
+	assert.Equal(t, "m4.4xlarge", profile.Properties.EKS.NodePools["pool1"].InstanceType)
+}
+
+func TestProfiles_OrganizationScope(t *testing.T) {
+	profiles := newTestProfiles(t)
+
+	request := pkgCluster.ClusterProfileRequest{
+		Name:  "large",
+		Cloud: pkgCluster.Amazon,
+		Properties: &pkgCluster.ClusterProfileProperties{
+			EKS: &eks.ClusterProfileEKS{
+				NodePools: map[string]*eks.NodePool{
+					"pool1": {InstanceType: "m5.4xlarge", Count: 5},
+				},
+			},
+		},
+	}
+	require.NoError(t, profiles.Create(1, 1, request))
+
+	err := profiles.Create(1, 1, request)
+	assert.Equal(t, ErrProfileAlreadyExists, err)
+
+	// the organization's profile overrides the global one
+	profile, err := profiles.Get(1, TypeEKS, "large")
+	require.NoError(t, err)
+	assert.False(t, profile.Global)
+	assert.Equal(t, "us-west-2", profile.Location)
+	assert.Equal(t, "m5.4xlarge", profile.Properties.EKS.NodePools["pool1"].InstanceType)
+
+	// other organizations still see the global profile
+	profile, err = profiles.Get(2, TypeEKS, "large")
+	require.NoError(t, err)
+	assert.True(t, profile.Global)
+	assert.Equal(t, "m4.4xlarge", profile.Properties.EKS.NodePools["pool1"].InstanceType)
+
+	list, err := profiles.List(1, TypeEKS)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, "default", list[0].Name)
+	assert.Equal(t, "large", list[1].Name)
+	assert.False(t, list[1].Global)
+
+	list, err = profiles.List(2, TypeEKS)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.True(t, list[1].Global)
+
+	request.Location = "eu-central-1"
+	request.Properties = &pkgCluster.ClusterProfileProperties{}
+	require.NoError(t, profiles.Update(1, request))
+
+	profile, err = profiles.Get(1, TypeEKS, "large")
+	require.NoError(t, err)
+	assert.Equal(t, "eu-central-1", profile.Location)
+	assert.Equal(t, "m5.4xlarge", profile.Properties.EKS.NodePools["pool1"].InstanceType)
+
+	require.NoError(t, profiles.Delete(1, TypeEKS, "large"))
+
+	profile, err = profiles.Get(1, TypeEKS, "large")
+	require.NoError(t, err)
+	assert.True(t, profile.Global)
+}
+
+func TestProfiles_GlobalProfilesAreReadOnly(t *testing.T) {
+	profiles := newTestProfiles(t)
+
+	err := profiles.Update(1, pkgCluster.ClusterProfileRequest{Name: "large", Cloud: pkgCluster.Amazon})
+	assert.Equal(t, ErrGlobalProfile, err)
+
+	err = profiles.Delete(1, TypeEKS, "large")
+	assert.Equal(t, ErrGlobalProfile, err)
+
+	err = profiles.Delete(1, TypeEKS, "missing")
+	assert.Equal(t, ErrProfileNotFound, err)
+
+	err = profiles.Create(1, 1, pkgCluster.ClusterProfileRequest{Name: "default", Cloud: pkgCluster.Amazon})
+	assert.Equal(t, ErrInvalidProfile, errors.Cause(err))
+}
+
+func TestDefaultProfiles_PKECreateClusterRequest(t *testing.T) {
+	var profile pkgCluster.ClusterProfileResponse
+	for _, p := range DefaultProfiles("default") {
+		if p.Cloud == pkgCluster.Amazon && p.Distribution == pkgCluster.PKE {
+			profile = p
+		}
+	}
+	require.NotNil(t, profile.Properties)
+
+	request, err := profile.CreateClusterRequest(&pkgCluster.CreateClusterRequest{
+		Name:         "pke",
+		Location:     "eu-west-1",
+		Cloud:        pkgCluster.Amazon,
+		Distribution: pkgCluster.PKE,
+		Properties:   &pkgCluster.CreateClusterProperties{},
+	})
+	require.NoError(t, err)
+
+	// The default PKE profile has no location, the location of the request is kept
+	assert.Equal(t, "eu-west-1", request.Location)
+	assert.NotNil(t, request.Properties.CreateClusterPKE)
+}
@@ -0,0 +1,46 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clustersleep
+
+import (
+	"time"
+)
+
+// TableName constants
+const (
+	sleepTableName = "cluster_sleep"
+)
+
+// SleepModel stores the sleep schedule of a cluster and the node pool sizes recorded when it was put to sleep.
+type SleepModel struct {
+	ID             uint `gorm:"primary_key"`
+	ClusterID      uint `gorm:"not null;unique_index:idx_cluster_sleep_cluster_id"`
+	SleepCron      string
+	WakeCron       string
+	Timezone       string
+	ScaleToMinimum bool
+	NextSleepAt    *time.Time
+	NextWakeAt     *time.Time
+	NodePoolSizes  string `sql:"type:text;"`
+	CreatedBy      uint
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TableName changes the default table name.
+func (SleepModel) TableName() string {
+	return sleepTableName
+}
@@ -0,0 +1,362 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clustersleep
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/goph/emperror"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/robfig/cron"
+)
+
+// ErrScheduleNotFound is returned when a cluster has no sleep schedule.
+var ErrScheduleNotFound = errors.New("cluster sleep schedule not found")
+
+// ErrInvalidSchedule is returned when a sleep schedule is invalid.
+var ErrInvalidSchedule = errors.New("invalid cluster sleep schedule")
+
+// Schedule describes when a cluster is put to sleep and woken up.
+// Both times are standard cron expressions evaluated in the given timezone (UTC by default).
+type Schedule struct {
+	Sleep          string     `json:"sleep"`
+	Wake           string     `json:"wake"`
+	Timezone       string     `json:"timezone,omitempty"`
+	ScaleToMinimum bool       `json:"scaleToMinimum,omitempty"`
+	NextSleepAt    *time.Time `json:"nextSleepAt,omitempty"`
+	NextWakeAt     *time.Time `json:"nextWakeAt,omitempty"`
+}
+
+// Action is a scheduled cluster sleep action.
+type Action string
+
+// Scheduled actions
+const (
+	NoAction    Action = ""
+	SleepAction Action = "sleep"
+	WakeAction  Action = "wake"
+)
+
+// ScheduledCluster is a cluster with a sleep schedule.
+type ScheduledCluster struct {
+	ClusterID uint
+	Schedule  Schedule
+}
+
+// Validate checks the cron expressions and the timezone of the schedule.
+func (s Schedule) Validate() error {
+	if s.Sleep == "" || s.Wake == "" {
+		return errors.WithMessage(ErrInvalidSchedule, "both sleep and wake times are required")
+	}
+
+	if _, err := s.location(); err != nil {
+		return errors.WithMessage(ErrInvalidSchedule, err.Error())
+	}
+
+	if _, err := cron.ParseStandard(s.Sleep); err != nil {
+		return errors.WithMessage(ErrInvalidSchedule, "invalid sleep time: "+err.Error())
+	}
+
+	if _, err := cron.ParseStandard(s.Wake); err != nil {
+		return errors.WithMessage(ErrInvalidSchedule, "invalid wake time: "+err.Error())
+	}
+
+	return nil
+}
+
+func (s Schedule) location() (*time.Location, error) {
+	if s.Timezone == "" {
+		return time.UTC, nil
+	}
+
+	return time.LoadLocation(s.Timezone)
+}
+
+// next returns the first time after the given one matching a cron expression of the schedule.
+func (s Schedule) next(spec string, after time.Time) (time.Time, error) {
+	loc, err := s.location()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return schedule.Next(after.In(loc)).UTC(), nil
+}
+
+// DueAction returns the action to be taken at the given time. When both the sleep and the wake time have passed,
+// the later one is returned as it determines the state the cluster should be in.
+func (s Schedule) DueAction(now time.Time) Action {
+	sleepDue := s.NextSleepAt != nil && !s.NextSleepAt.After(now)
+	wakeDue := s.NextWakeAt != nil && !s.NextWakeAt.After(now)
+
+	switch {
+	case sleepDue && wakeDue:
+		if s.NextSleepAt.After(*s.NextWakeAt) {
+			return SleepAction
+		}
+
+		return WakeAction
+	case sleepDue:
+		return SleepAction
+	case wakeDue:
+		return WakeAction
+	default:
+		return NoAction
+	}
+}
+
+// Store manages the sleep schedules and the recorded node pool sizes of clusters.
+type Store struct {
+	db *gorm.DB
+}
+
+// NewStore returns a new Store instance.
+func NewStore(db *gorm.DB) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+// GetSchedule returns the sleep schedule of a cluster.
+func (s *Store) GetSchedule(clusterID uint) (*Schedule, error) {
+	model, err := s.find(clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	if model == nil || model.SleepCron == "" {
+		return nil, ErrScheduleNotFound
+	}
+
+	schedule := scheduleFromModel(*model)
+
+	return &schedule, nil
+}
+
+// SetSchedule validates and stores the sleep schedule of a cluster.
+func (s *Store) SetSchedule(clusterID uint, userID uint, schedule Schedule) (*Schedule, error) {
+	if err := schedule.Validate(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	nextSleepAt, err := schedule.next(schedule.Sleep, now)
+	if err != nil {
+		return nil, err
+	}
+
+	nextWakeAt, err := schedule.next(schedule.Wake, now)
+	if err != nil {
+		return nil, err
+	}
+
+	model, err := s.find(clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	if model == nil {
+		model = &SleepModel{
+			ClusterID: clusterID,
+		}
+	}
+
+	model.SleepCron = schedule.Sleep
+	model.WakeCron = schedule.Wake
+	model.Timezone = schedule.Timezone
+	model.ScaleToMinimum = schedule.ScaleToMinimum
+	model.NextSleepAt = &nextSleepAt
+	model.NextWakeAt = &nextWakeAt
+	model.CreatedBy = userID
+
+	if err := s.db.Save(model).Error; err != nil {
+		return nil, emperror.WrapWith(err, "failed to save cluster sleep schedule", "clusterId", clusterID)
+	}
+
+	result := scheduleFromModel(*model)
+
+	return &result, nil
+}
+
+// DeleteSchedule removes the sleep schedule of a cluster. Recorded node pool sizes are kept.
+func (s *Store) DeleteSchedule(clusterID uint) error {
+	model, err := s.find(clusterID)
+	if err != nil {
+		return err
+	}
+
+	if model == nil || model.SleepCron == "" {
+		return ErrScheduleNotFound
+	}
+
+	model.SleepCron = ""
+	model.WakeCron = ""
+	model.Timezone = ""
+	model.ScaleToMinimum = false
+	model.NextSleepAt = nil
+	model.NextWakeAt = nil
+
+	return emperror.WrapWith(s.db.Save(model).Error, "failed to delete cluster sleep schedule", "clusterId", clusterID)
+}
+
+// ListSchedules returns every cluster with a sleep schedule.
+func (s *Store) ListSchedules() ([]ScheduledCluster, error) {
+	var models []SleepModel
+
+	if err := s.db.Where("sleep_cron <> ''").Find(&models).Error; err != nil {
+		return nil, emperror.Wrap(err, "failed to list cluster sleep schedules")
+	}
+
+	clusters := make([]ScheduledCluster, 0, len(models))
+	for _, model := range models {
+		clusters = append(clusters, ScheduledCluster{
+			ClusterID: model.ClusterID,
+			Schedule:  scheduleFromModel(model),
+		})
+	}
+
+	return clusters, nil
+}
+
+// ClaimSchedule moves the passed sleep and wake times of a listed schedule to their next occurrence.
+// The update only succeeds if the schedule has not changed since it was listed,
+// so only one of the concurrently running controllers gets to execute the due action.
+func (s *Store) ClaimSchedule(scheduled ScheduledCluster, now time.Time) (bool, error) {
+	schedule := scheduled.Schedule
+	updates := make(map[string]interface{}, 2)
+
+	if schedule.NextSleepAt == nil || !schedule.NextSleepAt.After(now) {
+		nextSleepAt, err := schedule.next(schedule.Sleep, now)
+		if err != nil {
+			return false, err
+		}
+		updates["next_sleep_at"] = nextSleepAt
+	}
+
+	if schedule.NextWakeAt == nil || !schedule.NextWakeAt.After(now) {
+		nextWakeAt, err := schedule.next(schedule.Wake, now)
+		if err != nil {
+			return false, err
+		}
+		updates["next_wake_at"] = nextWakeAt
+	}
+
+	query := s.db.Model(&SleepModel{}).Where("cluster_id = ?", scheduled.ClusterID)
+	query = whereTime(query, "next_sleep_at", schedule.NextSleepAt)
+	query = whereTime(query, "next_wake_at", schedule.NextWakeAt)
+
+	result := query.Updates(updates)
+	if result.Error != nil {
+		return false, emperror.WrapWith(result.Error, "failed to claim cluster sleep schedule", "clusterId", scheduled.ClusterID)
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+func whereTime(query *gorm.DB, column string, value *time.Time) *gorm.DB {
+	if value == nil {
+		return query.Where(column + " IS NULL")
+	}
+
+	return query.Where(column+" = ?", *value)
+}
+
+// RecordNodePoolSizes stores the node pool sizes of a cluster to be restored when it wakes up.
+func (s *Store) RecordNodePoolSizes(clusterID uint, sizes map[string]int) error {
+	raw, err := json.Marshal(sizes)
+	if err != nil {
+		return emperror.Wrap(err, "failed to marshal node pool sizes")
+	}
+
+	model, err := s.find(clusterID)
+	if err != nil {
+		return err
+	}
+
+	if model == nil {
+		model = &SleepModel{
+			ClusterID: clusterID,
+		}
+	}
+
+	model.NodePoolSizes = string(raw)
+
+	return emperror.WrapWith(s.db.Save(model).Error, "failed to record node pool sizes", "clusterId", clusterID)
+}
+
+// RecordedNodePoolSizes returns the node pool sizes recorded when the cluster was put to sleep.
+func (s *Store) RecordedNodePoolSizes(clusterID uint) (map[string]int, error) {
+	model, err := s.find(clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	if model == nil || model.NodePoolSizes == "" {
+		return nil, nil
+	}
+
+	var sizes map[string]int
+	if err := json.Unmarshal([]byte(model.NodePoolSizes), &sizes); err != nil {
+		return nil, emperror.WrapWith(err, "failed to unmarshal node pool sizes", "clusterId", clusterID)
+	}
+
+	return sizes, nil
+}
+
+// ClearNodePoolSizes removes the recorded node pool sizes of a cluster.
+func (s *Store) ClearNodePoolSizes(clusterID uint) error {
+	err := s.db.Model(&SleepModel{}).Where("cluster_id = ?", clusterID).Update("node_pool_sizes", "").Error
+
+	return emperror.WrapWith(err, "failed to clear node pool sizes", "clusterId", clusterID)
+}
+
+// DeleteByCluster removes every sleep record of a cluster.
+func (s *Store) DeleteByCluster(clusterID uint) error {
+	err := s.db.Where("cluster_id = ?", clusterID).Delete(&SleepModel{}).Error
+
+	return emperror.WrapWith(err, "failed to delete cluster sleep records", "clusterId", clusterID)
+}
+
+func (s *Store) find(clusterID uint) (*SleepModel, error) {
+	var model SleepModel
+
+	err := s.db.Where("cluster_id = ?", clusterID).First(&model).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, emperror.WrapWith(err, "failed to get cluster sleep record", "clusterId", clusterID)
+	}
+
+	return &model, nil
+}
+
+func scheduleFromModel(model SleepModel) Schedule {
+	return Schedule{
+		Sleep:          model.SleepCron,
+		Wake:           model.WakeCron,
+		Timezone:       model.Timezone,
+		ScaleToMinimum: model.ScaleToMinimum,
+		NextSleepAt:    model.NextSleepAt,
+		NextWakeAt:     model.NextWakeAt,
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clustersleep
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(t *testing.T) *Store {
+	db, err := gorm.Open("sqlite3", "file::memory:")
+	require.NoError(t, err)
+
+	require.NoError(t, db.AutoMigrate(&SleepModel{}).Error)
+
+	return NewStore(db)
+}
+
+func TestSchedule_Validate(t *testing.T) {
+	tests := map[string]struct {
+		schedule Schedule
+		valid    bool
+	}{
+		"valid": {
+			schedule: Schedule{Sleep: "0 20 * * 1-5", Wake: "0 7 * * 1-5", Timezone: "Europe/Budapest"},
+			valid:    true,
+		},
+		"missing wake": {
+			schedule: Schedule{Sleep: "0 20 * * *"},
+		},
+		"invalid cron": {
+			schedule: Schedule{Sleep: "every evening", Wake: "0 7 * * *"},
+		},
+		"invalid timezone": {
+			schedule: Schedule{Sleep: "0 20 * * *", Wake: "0 7 * * *", Timezone: "Mars/Olympus"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := test.schedule.Validate()
+
+			if test.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, ErrInvalidSchedule, errors.Cause(err))
+			}
+		})
+	}
+}
+
+func TestSchedule_DueAction(t *testing.T) {
+	now := time.Date(2019, 5, 20, 12, 0, 0, 0, time.UTC)
+	before := func(d time.Duration) *time.Time { t := now.Add(-d); return &t }
+	after := func(d time.Duration) *time.Time { t := now.Add(d); return &t }
+
+	tests := map[string]struct {
+		schedule Schedule
+		action   Action
+	}{
+		"nothing due":          {Schedule{NextSleepAt: after(time.Hour), NextWakeAt: after(2 * time.Hour)}, NoAction},
+		"sleep due":            {Schedule{NextSleepAt: before(time.Minute), NextWakeAt: after(time.Hour)}, SleepAction},
+		"wake due":             {Schedule{NextSleepAt: after(time.Hour), NextWakeAt: before(time.Minute)}, WakeAction},
+		"both due, sleep last": {Schedule{NextSleepAt: before(time.Minute), NextWakeAt: before(time.Hour)}, SleepAction},
+		"both due, wake last":  {Schedule{NextSleepAt: before(time.Hour), NextWakeAt: before(time.Minute)}, WakeAction},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.action, test.schedule.DueAction(now))
+		})
+	}
+}
+
+func TestStore_Schedule(t *testing.T) {
+	store := newTestStore(t)
+
+	_, err := store.GetSchedule(1)
+	assert.Equal(t, ErrScheduleNotFound, err)
+
+	_, err = store.SetSchedule(1, 1, Schedule{Sleep: "invalid", Wake: "0 7 * * *"})
+	assert.Equal(t, ErrInvalidSchedule, errors.Cause(err))
+
+	schedule, err := store.SetSchedule(1, 1, Schedule{Sleep: "0 20 * * *", Wake: "0 7 * * *", Timezone: "Europe/Budapest"})
+	require.NoError(t, err)
+	require.NotNil(t, schedule.NextSleepAt)
+	require.NotNil(t, schedule.NextWakeAt)
+	assert.True(t, schedule.NextSleepAt.After(time.Now()))
+	assert.Equal(t, NoAction, schedule.DueAction(time.Now()))
+
+	schedules, err := store.ListSchedules()
+	require.NoError(t, err)
+	require.Len(t, schedules, 1)
+	assert.Equal(t, uint(1), schedules[0].ClusterID)
+
+	nextSleepAt := *schedule.NextSleepAt
+	claimed, err := store.ClaimSchedule(schedules[0], nextSleepAt)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	// The schedule changed since it was listed, so another controller cannot claim it again
+	claimed, err = store.ClaimSchedule(schedules[0], nextSleepAt)
+	require.NoError(t, err)
+	assert.False(t, claimed)
+
+	schedule, err = store.GetSchedule(1)
+	require.NoError(t, err)
+	assert.Equal(t, nextSleepAt.Add(24*time.Hour).Unix(), schedule.NextSleepAt.Unix())
+
+	require.NoError(t, store.DeleteSchedule(1))
+
+	_, err = store.GetSchedule(1)
+	assert.Equal(t, ErrScheduleNotFound, err)
+
+	schedules, err = store.ListSchedules()
+	require.NoError(t, err)
+	assert.Empty(t, schedules)
+}
+
+func TestStore_NodePoolSizes(t *testing.T) {
+	store := newTestStore(t)
+
+	sizes, err := store.RecordedNodePoolSizes(1)
+	require.NoError(t, err)
+	assert.Nil(t, sizes)
+
+	require.NoError(t, store.RecordNodePoolSizes(1, map[string]int{"pool1": 3, "pool2": 1}))
+
+	sizes, err = store.RecordedNodePoolSizes(1)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"pool1": 3, "pool2": 1}, sizes)
+
+	_, err = store.GetSchedule(1)
+	assert.Equal(t, ErrScheduleNotFound, err)
+
+	require.NoError(t, store.ClearNodePoolSizes(1))
+
+	sizes, err = store.RecordedNodePoolSizes(1)
+	require.NoError(t, err)
+	assert.Nil(t, sizes)
+}
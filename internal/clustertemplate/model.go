@@ -0,0 +1,43 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clustertemplate
+
+import (
+	"time"
+)
+
+// TableName constants
+const (
+	templateTableName = "cluster_templates"
+)
+
+// TemplateModel is a single version of a cluster template.
+type TemplateModel struct {
+	ID             uint   `gorm:"primary_key"`
+	OrganizationID uint   `gorm:"not null;unique_index:idx_cluster_templates_org_name_version"`
+	Name           string `gorm:"not null;unique_index:idx_cluster_templates_org_name_version"`
+	Version        uint   `gorm:"not null;unique_index:idx_cluster_templates_org_name_version"`
+	Description    string `sql:"type:text;"`
+	Variables      string `sql:"type:text;"`
+	Template       string `sql:"type:text;"`
+	CreatedBy      uint
+
+	CreatedAt time.Time
+}
+
+// TableName changes the default table name.
+func (TemplateModel) TableName() string {
+	return templateTableName
+}
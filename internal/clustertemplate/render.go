@@ -0,0 +1,300 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clustertemplate
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// Variable types
+const (
+	TypeString  = "string"
+	TypeInteger = "integer"
+	TypeNumber  = "number"
+	TypeBoolean = "boolean"
+)
+
+// ErrInvalidVariables is returned when the variables given for rendering a template are invalid.
+var ErrInvalidVariables = errors.New("invalid template variables")
+
+// nolint: gochecknoglobals
+var (
+	variableNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+	// placeholderRegexp matches strings that consist of a single variable reference.
+	// Such strings are replaced by the typed value of the variable instead of its string representation.
+	placeholderRegexp = regexp.MustCompile(`^\{\{\s*\.([a-zA-Z_][a-zA-Z0-9_]*)\s*\}\}$`)
+)
+
+// Variable is a parameter of a cluster template.
+// Variables without a default value are required.
+type Variable struct {
+	Name        string        `json:"name"`
+	Type        string        `json:"type"`
+	Description string        `json:"description,omitempty"`
+	Default     interface{}   `json:"default,omitempty"`
+	Allowed     []interface{} `json:"allowed,omitempty"`
+}
+
+// Required returns true if the variable has no default value.
+func (v Variable) Required() bool {
+	return v.Default == nil
+}
+
+// validate checks the variable definition.
+func (v Variable) validate() error {
+	if !variableNameRegexp.MatchString(v.Name) {
+		return errors.Errorf("invalid variable name %q", v.Name)
+	}
+
+	switch v.Type {
+	case TypeString, TypeInteger, TypeNumber, TypeBoolean:
+	default:
+		return errors.Errorf("variable %q: type must be one of %s, %s, %s or %s", v.Name, TypeString, TypeInteger, TypeNumber, TypeBoolean)
+	}
+
+	for _, allowed := range v.Allowed {
+		if _, err := convertValue(v.Type, allowed); err != nil {
+			return errors.WithMessagef(err, "variable %q: invalid allowed value", v.Name)
+		}
+	}
+
+	if v.Default != nil {
+		if _, err := v.value(v.Default); err != nil {
+			return errors.WithMessagef(err, "variable %q: invalid default value", v.Name)
+		}
+	}
+
+	return nil
+}
+
+// value converts a value to the type of the variable and checks it against the allowed values.
+func (v Variable) value(value interface{}) (interface{}, error) {
+	converted, err := convertValue(v.Type, value)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(v.Allowed) == 0 {
+		return converted, nil
+	}
+
+	for _, allowed := range v.Allowed {
+		allowed, _ := convertValue(v.Type, allowed)
+		if reflect.DeepEqual(allowed, converted) {
+			return converted, nil
+		}
+	}
+
+	return nil, errors.Errorf("value %v is not allowed", value)
+}
+
+// sample returns a valid value of the variable used for validating templates.
+func (v Variable) sample() interface{} {
+	if v.Default != nil {
+		value, _ := v.value(v.Default)
+		return value
+	}
+
+	if len(v.Allowed) > 0 {
+		value, _ := convertValue(v.Type, v.Allowed[0])
+		return value
+	}
+
+	switch v.Type {
+	case TypeInteger:
+		return int64(0)
+	case TypeNumber:
+		return float64(0)
+	case TypeBoolean:
+		return false
+	default:
+		return ""
+	}
+}
+
+func convertValue(typ string, value interface{}) (interface{}, error) {
+	switch typ {
+	case TypeString:
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+
+	case TypeInteger:
+		switch n := value.(type) {
+		case int:
+			return int64(n), nil
+		case int64:
+			return n, nil
+		case float64:
+			if n == math.Trunc(n) {
+				return int64(n), nil
+			}
+		}
+
+	case TypeNumber:
+		switch n := value.(type) {
+		case int:
+			return float64(n), nil
+		case int64:
+			return float64(n), nil
+		case float64:
+			return n, nil
+		}
+
+	case TypeBoolean:
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+	}
+
+	return nil, errors.Errorf("%v is not a valid %s", value, typ)
+}
+
+// resolveValues checks the given values against the variable definitions and fills in the defaults.
+func resolveValues(variables []Variable, values map[string]interface{}) (map[string]interface{}, error) {
+	resolved := make(map[string]interface{}, len(variables))
+	declared := make(map[string]bool, len(variables))
+
+	for _, variable := range variables {
+		declared[variable.Name] = true
+
+		value, ok := values[variable.Name]
+		if !ok || value == nil {
+			if variable.Required() {
+				return nil, errors.WithMessagef(ErrInvalidVariables, "missing required variable %q", variable.Name)
+			}
+
+			value = variable.Default
+		}
+
+		converted, err := variable.value(value)
+		if err != nil {
+			return nil, errors.WithMessagef(ErrInvalidVariables, "variable %q: %s", variable.Name, err.Error())
+		}
+
+		resolved[variable.Name] = converted
+	}
+
+	for name := range values {
+		if !declared[name] {
+			return nil, errors.WithMessagef(ErrInvalidVariables, "unknown variable %q", name)
+		}
+	}
+
+	return resolved, nil
+}
+
+// render substitutes the variables in every string of a decoded JSON document (including object keys).
+// A string consisting of a single variable reference is replaced by the typed value of the variable.
+func render(node interface{}, values map[string]interface{}) (interface{}, error) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		rendered := make(map[string]interface{}, len(n))
+		for key, value := range n {
+			renderedKey, err := renderString(key, values)
+			if err != nil {
+				return nil, err
+			}
+
+			renderedValue, err := render(value, values)
+			if err != nil {
+				return nil, err
+			}
+
+			rendered[renderedKey] = renderedValue
+		}
+
+		return rendered, nil
+
+	case []interface{}:
+		rendered := make([]interface{}, 0, len(n))
+		for _, value := range n {
+			renderedValue, err := render(value, values)
+			if err != nil {
+				return nil, err
+			}
+
+			rendered = append(rendered, renderedValue)
+		}
+
+		return rendered, nil
+
+	case string:
+		if match := placeholderRegexp.FindStringSubmatch(n); match != nil {
+			value, ok := values[match[1]]
+			if !ok {
+				return nil, errors.Errorf("undeclared variable %q", match[1])
+			}
+
+			return value, nil
+		}
+
+		return renderString(n, values)
+
+	default:
+		return node, nil
+	}
+}
+
+func renderString(s string, values map[string]interface{}) (string, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+
+	tmpl, err := template.New("").Option("missingkey=error").Parse(s)
+	if err != nil {
+		return "", errors.WithMessagef(err, "invalid template string %q", s)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, values); err != nil {
+		return "", errors.WithMessagef(err, "failed to render template string %q", s)
+	}
+
+	return buf.String(), nil
+}
+
+// checkTemplate renders the template with sample values to make sure that it only refers to declared variables.
+func checkTemplate(variables []Variable, tmpl map[string]interface{}) error {
+	declared := make(map[string]bool, len(variables))
+	samples := make(map[string]interface{}, len(variables))
+
+	for _, variable := range variables {
+		if err := variable.validate(); err != nil {
+			return err
+		}
+
+		if declared[variable.Name] {
+			return errors.Errorf("duplicate variable %q", variable.Name)
+		}
+
+		declared[variable.Name] = true
+		samples[variable.Name] = variable.sample()
+	}
+
+	if _, err := render(tmpl, samples); err != nil {
+		return err
+	}
+
+	return nil
+}
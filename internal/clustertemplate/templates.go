@@ -0,0 +1,264 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clustertemplate
+
+import (
+	"encoding/json"
+	"regexp"
+	"time"
+
+	"github.com/goph/emperror"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// ErrTemplateNotFound is returned when a cluster template cannot be found.
+var ErrTemplateNotFound = errors.New("cluster template not found")
+
+// ErrTemplateAlreadyExists is returned when a cluster template with the same name already exists.
+var ErrTemplateAlreadyExists = errors.New("cluster template already exists")
+
+// ErrInvalidTemplate is returned when a cluster template definition is invalid.
+var ErrInvalidTemplate = errors.New("invalid cluster template")
+
+// nolint: gochecknoglobals
+var templateNameRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// Template is a version of a parameterized cluster create request of an organization.
+type Template struct {
+	Name        string                 `json:"name"`
+	Version     uint                   `json:"version"`
+	Description string                 `json:"description,omitempty"`
+	Variables   []Variable             `json:"variables,omitempty"`
+	Template    map[string]interface{} `json:"template"`
+	CreatedBy   uint                   `json:"createdBy"`
+	CreatedAt   time.Time              `json:"createdAt"`
+}
+
+// Templates manages the cluster templates of organizations.
+type Templates struct {
+	db *gorm.DB
+}
+
+// NewTemplates returns a new Templates instance.
+func NewTemplates(db *gorm.DB) *Templates {
+	return &Templates{
+		db: db,
+	}
+}
+
+// List returns the latest version of every cluster template of an organization.
+func (t *Templates) List(organizationID uint) ([]Template, error) {
+	var models []TemplateModel
+
+	err := t.db.
+		Where("organization_id = ?", organizationID).
+		Where("version = (SELECT MAX(t.version) FROM cluster_templates t WHERE t.organization_id = cluster_templates.organization_id AND t.name = cluster_templates.name)").
+		Order("name").
+		Find(&models).Error
+	if err != nil {
+		return nil, emperror.WrapWith(err, "failed to list cluster templates", "organizationId", organizationID)
+	}
+
+	return templatesFromModels(models)
+}
+
+// Versions returns every version of a cluster template, the latest first.
+func (t *Templates) Versions(organizationID uint, name string) ([]Template, error) {
+	var models []TemplateModel
+
+	err := t.db.Where("organization_id = ? AND name = ?", organizationID, name).Order("version DESC").Find(&models).Error
+	if err != nil {
+		return nil, emperror.WrapWith(err, "failed to list cluster template versions", "organizationId", organizationID, "name", name)
+	}
+
+	if len(models) == 0 {
+		return nil, ErrTemplateNotFound
+	}
+
+	return templatesFromModels(models)
+}
+
+// Get returns a version of a cluster template. A zero version returns the latest one.
+func (t *Templates) Get(organizationID uint, name string, version uint) (*Template, error) {
+	model, err := t.find(organizationID, name, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return templateFromModel(*model)
+}
+
+// Create validates and stores the first version of a new cluster template.
+func (t *Templates) Create(organizationID uint, userID uint, template Template) (*Template, error) {
+	if !templateNameRegexp.MatchString(template.Name) {
+		return nil, errors.WithMessage(ErrInvalidTemplate, "name must consist of lower case alphanumeric characters or '-'")
+	}
+
+	_, err := t.find(organizationID, template.Name, 0)
+	if err == nil {
+		return nil, ErrTemplateAlreadyExists
+	} else if err != ErrTemplateNotFound {
+		return nil, err
+	}
+
+	template.Version = 1
+
+	return t.save(organizationID, userID, template)
+}
+
+// Update validates and stores a new version of an existing cluster template.
+func (t *Templates) Update(organizationID uint, userID uint, template Template) (*Template, error) {
+	latest, err := t.find(organizationID, template.Name, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	template.Version = latest.Version + 1
+
+	return t.save(organizationID, userID, template)
+}
+
+// Delete deletes every version of a cluster template.
+func (t *Templates) Delete(organizationID uint, name string) error {
+	if _, err := t.find(organizationID, name, 0); err != nil {
+		return err
+	}
+
+	err := t.db.Where("organization_id = ? AND name = ?", organizationID, name).Delete(&TemplateModel{}).Error
+	if err != nil {
+		return emperror.WrapWith(err, "failed to delete cluster template", "organizationId", organizationID, "name", name)
+	}
+
+	return nil
+}
+
+// Render renders a cluster create request from a version of a cluster template.
+// Variables missing from values are set to their default value.
+func (t *Templates) Render(organizationID uint, name string, version uint, values map[string]interface{}) (map[string]interface{}, error) {
+	template, err := t.Get(organizationID, name, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return template.Render(values)
+}
+
+// Render renders a cluster create request from the template.
+// Variables missing from values are set to their default value.
+func (t Template) Render(values map[string]interface{}) (map[string]interface{}, error) {
+	resolved, err := resolveValues(t.Variables, values)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered, err := render(t.Template, resolved)
+	if err != nil {
+		return nil, errors.WithMessage(ErrInvalidVariables, err.Error())
+	}
+
+	return rendered.(map[string]interface{}), nil
+}
+
+func (t *Templates) save(organizationID uint, userID uint, template Template) (*Template, error) {
+	if len(template.Template) == 0 {
+		return nil, errors.WithMessage(ErrInvalidTemplate, "template is required")
+	}
+
+	if err := checkTemplate(template.Variables, template.Template); err != nil {
+		return nil, errors.WithMessage(ErrInvalidTemplate, err.Error())
+	}
+
+	variables, err := json.Marshal(template.Variables)
+	if err != nil {
+		return nil, emperror.Wrap(err, "failed to marshal template variables")
+	}
+
+	body, err := json.Marshal(template.Template)
+	if err != nil {
+		return nil, emperror.Wrap(err, "failed to marshal template")
+	}
+
+	model := TemplateModel{
+		OrganizationID: organizationID,
+		Name:           template.Name,
+		Version:        template.Version,
+		Description:    template.Description,
+		Variables:      string(variables),
+		Template:       string(body),
+		CreatedBy:      userID,
+	}
+
+	err = t.db.Create(&model).Error
+	if err != nil {
+		return nil, emperror.WrapWith(err, "failed to save cluster template", "organizationId", organizationID, "name", template.Name, "version", template.Version)
+	}
+
+	return templateFromModel(model)
+}
+
+func (t *Templates) find(organizationID uint, name string, version uint) (*TemplateModel, error) {
+	var model TemplateModel
+
+	query := t.db.Where("organization_id = ? AND name = ?", organizationID, name)
+	if version > 0 {
+		query = query.Where("version = ?", version)
+	}
+
+	err := query.Order("version DESC").First(&model).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, ErrTemplateNotFound
+	} else if err != nil {
+		return nil, emperror.WrapWith(err, "failed to get cluster template", "organizationId", organizationID, "name", name, "version", version)
+	}
+
+	return &model, nil
+}
+
+func templatesFromModels(models []TemplateModel) ([]Template, error) {
+	templates := make([]Template, 0, len(models))
+	for _, model := range models {
+		template, err := templateFromModel(model)
+		if err != nil {
+			return nil, err
+		}
+
+		templates = append(templates, *template)
+	}
+
+	return templates, nil
+}
+
+func templateFromModel(model TemplateModel) (*Template, error) {
+	template := Template{
+		Name:        model.Name,
+		Version:     model.Version,
+		Description: model.Description,
+		CreatedBy:   model.CreatedBy,
+		CreatedAt:   model.CreatedAt,
+	}
+
+	if model.Variables != "" {
+		if err := json.Unmarshal([]byte(model.Variables), &template.Variables); err != nil {
+			return nil, emperror.WrapWith(err, "failed to unmarshal template variables", "name", model.Name, "version", model.Version)
+		}
+	}
+
+	if err := json.Unmarshal([]byte(model.Template), &template.Template); err != nil {
+		return nil, emperror.WrapWith(err, "failed to unmarshal template", "name", model.Name, "version", model.Version)
+	}
+
+	return &template, nil
+}
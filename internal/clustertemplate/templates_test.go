@@ -0,0 +1,188 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clustertemplate
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTemplates(t *testing.T) (*gorm.DB, *Templates) {
+	db, err := gorm.Open("sqlite3", "file::memory:")
+	require.NoError(t, err)
+
+	require.NoError(t, db.AutoMigrate(&TemplateModel{}).Error)
+
+	return db, NewTemplates(db)
+}
+
+func decode(t *testing.T, s string) map[string]interface{} {
+	var v map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(s), &v))
+
+	return v
+}
+
+func newEKSTemplate(t *testing.T) Template {
+	return Template{
+		Name: "eks",
+		Variables: []Variable{
+			{Name: "name", Type: TypeString},
+			{Name: "region", Type: TypeString, Default: "eu-west-1", Allowed: []interface{}{"eu-west-1", "us-east-1"}},
+			{Name: "count", Type: TypeInteger, Default: float64(2)},
+			{Name: "spot", Type: TypeBoolean, Default: false},
+		},
+		Template: decode(t, `{
+			"name": "{{ .name }}",
+			"location": "{{ .region }}",
+			"cloud": "amazon",
+			"secretName": "aws",
+			"properties": {
+				"eks": {
+					"nodePools": {
+						"{{ .name }}-pool": {"instanceType": "m5.large", "count": "{{ .count }}", "minCount": 1, "maxCount": "{{ .count }}"}
+					}
+				}
+			},
+			"postHooks": {"spot": "{{ .spot }}", "description": "cluster {{ .name }} in {{ .region }}"}
+		}`),
+	}
+}
+
+func TestTemplates_CreateUpdate(t *testing.T) {
+	db, templates := newTestTemplates(t)
+	defer db.Close()
+
+	invalid := []struct {
+		name     string
+		template Template
+	}{
+		{name: "invalid name", template: Template{Name: "Invalid_Name", Template: decode(t, `{"name": "x"}`)}},
+		{name: "empty template", template: Template{Name: "empty"}},
+		{name: "invalid variable type", template: Template{Name: "type", Variables: []Variable{{Name: "v", Type: "list"}}, Template: decode(t, `{"name": "x"}`)}},
+		{name: "invalid default", template: Template{Name: "default", Variables: []Variable{{Name: "v", Type: TypeInteger, Default: "x"}}, Template: decode(t, `{"name": "x"}`)}},
+		{name: "default not allowed", template: Template{Name: "allowed", Variables: []Variable{{Name: "v", Type: TypeString, Default: "c", Allowed: []interface{}{"a", "b"}}}, Template: decode(t, `{"name": "x"}`)}},
+		{name: "undeclared variable", template: Template{Name: "undeclared", Template: decode(t, `{"name": "{{ .name }}"}`)}},
+		{name: "undeclared variable in string", template: Template{Name: "undeclared2", Template: decode(t, `{"name": "cluster-{{ .name }}"}`)}},
+		{name: "syntax error", template: Template{Name: "syntax", Template: decode(t, `{"name": "cluster-{{ .name "}`)}},
+	}
+
+	for _, test := range invalid {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			_, err := templates.Create(1, 1, test.template)
+			assert.Equal(t, ErrInvalidTemplate, errors.Cause(err))
+		})
+	}
+
+	created, err := templates.Create(1, 1, newEKSTemplate(t))
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), created.Version)
+
+	_, err = templates.Create(1, 1, newEKSTemplate(t))
+	assert.Equal(t, ErrTemplateAlreadyExists, err)
+
+	update := newEKSTemplate(t)
+	update.Description = "second version"
+	updated, err := templates.Update(1, 2, update)
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), updated.Version)
+	assert.Equal(t, uint(2), updated.CreatedBy)
+
+	_, err = templates.Create(2, 1, newEKSTemplate(t))
+	require.NoError(t, err)
+
+	list, err := templates.List(1)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "second version", list[0].Description)
+
+	versions, err := templates.Versions(1, "eks")
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, uint(2), versions[0].Version)
+
+	first, err := templates.Get(1, "eks", 1)
+	require.NoError(t, err)
+	assert.Empty(t, first.Description)
+
+	_, err = templates.Update(1, 1, Template{Name: "missing", Template: decode(t, `{"name": "x"}`)})
+	assert.Equal(t, ErrTemplateNotFound, err)
+
+	require.NoError(t, templates.Delete(1, "eks"))
+
+	_, err = templates.Get(1, "eks", 0)
+	assert.Equal(t, ErrTemplateNotFound, err)
+
+	_, err = templates.Get(2, "eks", 0)
+	assert.NoError(t, err)
+}
+
+func TestTemplate_Render(t *testing.T) {
+	template := newEKSTemplate(t)
+
+	rendered, err := template.Render(map[string]interface{}{"name": "test", "count": float64(3)})
+	require.NoError(t, err)
+
+	assert.Equal(t, decode(t, `{
+		"name": "test",
+		"location": "eu-west-1",
+		"cloud": "amazon",
+		"secretName": "aws",
+		"properties": {
+			"eks": {
+				"nodePools": {
+					"test-pool": {"instanceType": "m5.large", "count": 3, "minCount": 1, "maxCount": 3}
+				}
+			}
+		},
+		"postHooks": {"spot": false, "description": "cluster test in eu-west-1"}
+	}`), normalize(t, rendered))
+
+	invalid := []struct {
+		name   string
+		values map[string]interface{}
+	}{
+		{name: "missing required", values: map[string]interface{}{}},
+		{name: "unknown variable", values: map[string]interface{}{"name": "test", "size": "large"}},
+		{name: "not allowed", values: map[string]interface{}{"name": "test", "region": "ap-south-1"}},
+		{name: "invalid integer", values: map[string]interface{}{"name": "test", "count": 1.5}},
+		{name: "invalid type", values: map[string]interface{}{"name": "test", "spot": "yes"}},
+	}
+
+	for _, test := range invalid {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			_, err := template.Render(test.values)
+			assert.Equal(t, ErrInvalidVariables, errors.Cause(err))
+		})
+	}
+}
+
+// normalize converts typed values (eg. int64) to their decoded JSON form.
+func normalize(t *testing.T, v map[string]interface{}) map[string]interface{} {
+	body, err := json.Marshal(v)
+	require.NoError(t, err)
+
+	return decode(t, string(body))
+}
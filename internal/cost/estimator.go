@@ -0,0 +1,225 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cost
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	"github.com/pkg/errors"
+)
+
+// HoursPerMonth is the average number of hours in a month used for monthly estimates.
+const HoursPerMonth = 730
+
+// Currency of the estimated costs (Cloudinfo prices are in USD).
+const Currency = "USD"
+
+// NodePool describes the nodes of a node pool relevant to its cost.
+type NodePool struct {
+	Name         string
+	InstanceType string
+	Count        int
+	Spot         bool
+}
+
+// NodePoolCost is the estimated cost of a node pool.
+type NodePoolCost struct {
+	Name         string  `json:"name"`
+	InstanceType string  `json:"instanceType"`
+	Count        int     `json:"count"`
+	Spot         bool    `json:"spot"`
+	NodePrice    float64 `json:"nodePrice"`
+	HourlyCost   float64 `json:"hourlyCost"`
+}
+
+// Estimate is the estimated cost of a set of node pools.
+type Estimate struct {
+	Currency    string         `json:"currency"`
+	HourlyCost  float64        `json:"hourlyCost"`
+	MonthlyCost float64        `json:"monthlyCost"`
+	NodePools   []NodePoolCost `json:"nodePools"`
+	Warnings    []string       `json:"warnings,omitempty"`
+}
+
+// ClusterCost is the estimated cost of a cluster.
+type ClusterCost struct {
+	ClusterID       uint      `json:"clusterId"`
+	ClusterName     string    `json:"clusterName"`
+	Cloud           string    `json:"cloud"`
+	Distribution    string    `json:"distribution"`
+	Region          string    `json:"region"`
+	Status          string    `json:"status"`
+	MonthToDateCost float64   `json:"monthToDateCost"`
+	Since           time.Time `json:"since"`
+	Estimate
+}
+
+// OrganizationCost is the estimated cost of every cluster of an organization.
+type OrganizationCost struct {
+	Currency        string        `json:"currency"`
+	HourlyCost      float64       `json:"hourlyCost"`
+	MonthlyCost     float64       `json:"monthlyCost"`
+	MonthToDateCost float64       `json:"monthToDateCost"`
+	Clusters        []ClusterCost `json:"clusters"`
+}
+
+// Estimator estimates cluster costs from instance type prices.
+type Estimator struct {
+	prices PriceSource
+}
+
+// NewEstimator returns a new Estimator.
+func NewEstimator(prices PriceSource) *Estimator {
+	return &Estimator{
+		prices: prices,
+	}
+}
+
+// EstimateNodePools estimates the cost of the given node pools.
+// Node pools without price information are reported as warnings and do not count into the estimate.
+func (e *Estimator) EstimateNodePools(ctx context.Context, cloud string, service string, region string, nodePools []NodePool) (*Estimate, error) {
+	estimate := &Estimate{
+		Currency:  Currency,
+		NodePools: make([]NodePoolCost, 0, len(nodePools)),
+	}
+
+	for _, nodePool := range nodePools {
+		nodePoolCost := NodePoolCost{
+			Name:         nodePool.Name,
+			InstanceType: nodePool.InstanceType,
+			Count:        nodePool.Count,
+			Spot:         nodePool.Spot,
+		}
+
+		price, err := e.prices.GetPrice(ctx, cloud, service, region, nodePool.InstanceType)
+		if errors.Cause(err) == ErrPriceNotFound {
+			estimate.Warnings = append(estimate.Warnings, fmt.Sprintf("no price found for instance type %q of node pool %q", nodePool.InstanceType, nodePool.Name))
+		} else if err != nil {
+			return nil, err
+		}
+
+		nodePoolCost.NodePrice = price.OnDemand
+		if nodePool.Spot {
+			if price.Spot > 0 {
+				nodePoolCost.NodePrice = price.Spot
+			} else if err == nil {
+				estimate.Warnings = append(estimate.Warnings, fmt.Sprintf("no spot price found for instance type %q of node pool %q, using the on-demand price", nodePool.InstanceType, nodePool.Name))
+			}
+		}
+
+		nodePoolCost.HourlyCost = round(nodePoolCost.NodePrice * float64(nodePool.Count))
+		estimate.HourlyCost += nodePoolCost.HourlyCost
+		estimate.NodePools = append(estimate.NodePools, nodePoolCost)
+	}
+
+	estimate.HourlyCost = round(estimate.HourlyCost)
+	estimate.MonthlyCost = round(estimate.HourlyCost * HoursPerMonth)
+
+	return estimate, nil
+}
+
+// EstimateCluster estimates the cost of a cluster based on its current node pools.
+// The month-to-date cost assumes that the current node pools have been running since
+// the beginning of the month or since the cluster has been started, whichever is later.
+func (e *Estimator) EstimateCluster(ctx context.Context, status *pkgCluster.GetClusterStatusResponse, now time.Time) (*ClusterCost, error) {
+	region := status.Region
+	if region == "" {
+		region = status.Location
+	}
+
+	nodePools := make([]NodePool, 0, len(status.NodePools))
+	for name, nodePool := range status.NodePools {
+		if nodePool == nil {
+			continue
+		}
+
+		nodePools = append(nodePools, NodePool{
+			Name:         name,
+			InstanceType: nodePool.InstanceType,
+			Count:        nodePool.Count,
+			Spot:         isSpotNodePool(nodePool),
+		})
+	}
+
+	sort.Slice(nodePools, func(i, j int) bool {
+		return nodePools[i].Name < nodePools[j].Name
+	})
+
+	estimate, err := e.EstimateNodePools(ctx, status.Cloud, status.Distribution, region, nodePools)
+	if err != nil {
+		return nil, err
+	}
+
+	since := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	started := status.CreatedAt
+	if status.StartedAt != nil {
+		started = *status.StartedAt
+	}
+	if started.After(since) {
+		since = started
+	}
+
+	var monthToDate float64
+	if now.After(since) {
+		monthToDate = round(estimate.HourlyCost * now.Sub(since).Hours())
+	}
+
+	return &ClusterCost{
+		ClusterID:       status.ResourceID,
+		ClusterName:     status.Name,
+		Cloud:           status.Cloud,
+		Distribution:    status.Distribution,
+		Region:          region,
+		Status:          status.Status,
+		MonthToDateCost: monthToDate,
+		Since:           since,
+		Estimate:        *estimate,
+	}, nil
+}
+
+// Rollup sums the costs of the clusters of an organization.
+func Rollup(clusters []ClusterCost) *OrganizationCost {
+	cost := &OrganizationCost{
+		Currency: Currency,
+		Clusters: clusters,
+	}
+
+	for _, cluster := range clusters {
+		cost.HourlyCost += cluster.HourlyCost
+		cost.MonthlyCost += cluster.MonthlyCost
+		cost.MonthToDateCost += cluster.MonthToDateCost
+	}
+
+	cost.HourlyCost = round(cost.HourlyCost)
+	cost.MonthlyCost = round(cost.MonthlyCost)
+	cost.MonthToDateCost = round(cost.MonthToDateCost)
+
+	return cost
+}
+
+// isSpotNodePool returns true if the nodes of the pool are spot (Amazon) or preemptible (Google) instances.
+func isSpotNodePool(nodePool *pkgCluster.NodePoolStatus) bool {
+	return nodePool.Preemptible || (nodePool.SpotPrice != "" && nodePool.SpotPrice != "0")
+}
+
+// round rounds a cost to 4 decimal places to avoid floating point noise in responses.
+func round(value float64) float64 {
+	return math.Round(value*10000) / 10000
+}
@@ -0,0 +1,132 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cost
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/banzaicloud/pipeline/.gen/cloudinfo"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCloudinfoStub(t *testing.T, requests *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+
+		if r.URL.Path != "/providers/amazon/services/eks/regions/eu-west-1/products" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(cloudinfo.ProductDetailsResponse{
+			Products: []cloudinfo.ProductDetails{
+				{
+					Type:          "m5.large",
+					OnDemandPrice: 0.1,
+					SpotPrice: []cloudinfo.ZonePrice{
+						{Zone: "eu-west-1a", Price: 0.03},
+						{Zone: "eu-west-1b", Price: 0.05},
+					},
+				},
+				{Type: "c5.xlarge", OnDemandPrice: 0.2},
+			},
+		}))
+	}))
+}
+
+func TestEstimator_EstimateNodePools(t *testing.T) {
+	var requests int
+	server := newCloudinfoStub(t, &requests)
+	defer server.Close()
+
+	estimator := NewEstimator(NewCloudinfoPriceSource(server.URL, time.Hour))
+
+	estimate, err := estimator.EstimateNodePools(context.Background(), "amazon", "eks", "eu-west-1", []NodePool{
+		{Name: "ondemand", InstanceType: "m5.large", Count: 3},
+		{Name: "spot", InstanceType: "m5.large", Count: 2, Spot: true},
+		{Name: "nospot", InstanceType: "c5.xlarge", Count: 1, Spot: true},
+		{Name: "unknown", InstanceType: "x1.huge", Count: 5},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, requests, "prices should be cached per region")
+	assert.Equal(t, Currency, estimate.Currency)
+	require.Len(t, estimate.NodePools, 4)
+	assert.Equal(t, 0.3, estimate.NodePools[0].HourlyCost)
+	assert.Equal(t, 0.04, estimate.NodePools[1].NodePrice)
+	assert.Equal(t, 0.08, estimate.NodePools[1].HourlyCost)
+	assert.Equal(t, 0.2, estimate.NodePools[2].HourlyCost)
+	assert.Zero(t, estimate.NodePools[3].HourlyCost)
+	assert.Equal(t, 0.58, estimate.HourlyCost)
+	assert.Equal(t, 423.4, estimate.MonthlyCost)
+	assert.Len(t, estimate.Warnings, 2)
+
+	_, err = estimator.EstimateNodePools(context.Background(), "amazon", "eks", "us-east-1", []NodePool{{Name: "pool", InstanceType: "m5.large", Count: 1}})
+	assert.Error(t, err)
+}
+
+func TestEstimator_EstimateCluster(t *testing.T) {
+	var requests int
+	server := newCloudinfoStub(t, &requests)
+	defer server.Close()
+
+	estimator := NewEstimator(NewCloudinfoPriceSource(server.URL, time.Hour))
+
+	now := time.Date(2019, time.May, 10, 12, 0, 0, 0, time.UTC)
+	startedAt := time.Date(2019, time.May, 10, 2, 0, 0, 0, time.UTC)
+
+	status := &pkgCluster.GetClusterStatusResponse{
+		Name:         "demo",
+		Status:       pkgCluster.Running,
+		Cloud:        "amazon",
+		Distribution: "eks",
+		Location:     "eu-west-1",
+		ResourceID:   1,
+		NodePools: map[string]*pkgCluster.NodePoolStatus{
+			"pool2": {InstanceType: "m5.large", Count: 1, SpotPrice: "0.05"},
+			"pool1": {InstanceType: "m5.large", Count: 2},
+		},
+		CreatorBaseFields: pkgCommon.CreatorBaseFields{CreatedAt: time.Date(2019, time.April, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	clusterCost, err := estimator.EstimateCluster(context.Background(), status, now)
+	require.NoError(t, err)
+	assert.Equal(t, "eu-west-1", clusterCost.Region)
+	assert.Equal(t, "pool1", clusterCost.NodePools[0].Name)
+	assert.True(t, clusterCost.NodePools[1].Spot)
+	assert.Equal(t, 0.24, clusterCost.HourlyCost)
+	assert.Equal(t, time.Date(2019, time.May, 1, 0, 0, 0, 0, time.UTC), clusterCost.Since)
+	assert.Equal(t, 0.24*(9*24+12), clusterCost.MonthToDateCost)
+
+	status.StartedAt = &startedAt
+	restarted, err := estimator.EstimateCluster(context.Background(), status, now)
+	require.NoError(t, err)
+	assert.Equal(t, startedAt, restarted.Since)
+	assert.Equal(t, 2.4, restarted.MonthToDateCost)
+
+	rollup := Rollup([]ClusterCost{*clusterCost, *restarted})
+	assert.Equal(t, 0.48, rollup.HourlyCost)
+	assert.Equal(t, round(0.24*(9*24+12)+2.4), rollup.MonthToDateCost)
+	assert.Len(t, rollup.Clusters, 2)
+}
@@ -0,0 +1,132 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cost
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/banzaicloud/pipeline/.gen/cloudinfo"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+)
+
+// ErrPriceNotFound is returned when there is no price information for an instance type.
+var ErrPriceNotFound = errors.New("price not found")
+
+// Price holds the hourly prices of an instance type.
+type Price struct {
+	OnDemand float64
+
+	// Spot is the average spot (or preemptible) price across the availability zones,
+	// zero if the provider has no such offering for the instance type.
+	Spot float64
+}
+
+// PriceSource provides the hourly prices of instance types.
+type PriceSource interface {
+	GetPrice(ctx context.Context, cloud string, service string, region string, instanceType string) (Price, error)
+}
+
+type priceKey struct {
+	cloud   string
+	service string
+	region  string
+}
+
+type priceCacheEntry struct {
+	prices    map[string]Price
+	fetchedAt time.Time
+}
+
+// CloudinfoPriceSource reads instance type prices from Cloudinfo and caches them per region.
+type CloudinfoPriceSource struct {
+	client *cloudinfo.APIClient
+	ttl    time.Duration
+
+	mu    sync.Mutex
+	cache map[priceKey]priceCacheEntry
+}
+
+// NewCloudinfoPriceSource returns a new CloudinfoPriceSource.
+func NewCloudinfoPriceSource(basePath string, ttl time.Duration) *CloudinfoPriceSource {
+	return &CloudinfoPriceSource{
+		client: cloudinfo.NewAPIClient(&cloudinfo.Configuration{
+			BasePath:      basePath,
+			DefaultHeader: make(map[string]string),
+			UserAgent:     "Pipeline/go",
+		}),
+		ttl:   ttl,
+		cache: make(map[priceKey]priceCacheEntry),
+	}
+}
+
+// GetPrice returns the hourly prices of an instance type.
+func (s *CloudinfoPriceSource) GetPrice(ctx context.Context, cloud string, service string, region string, instanceType string) (Price, error) {
+	key := priceKey{cloud: cloud, service: service, region: region}
+
+	s.mu.Lock()
+	entry, ok := s.cache[key]
+	s.mu.Unlock()
+
+	// Cloudinfo is called without holding the lock, so a slow response does not block
+	// cached lookups; concurrent misses may fetch the same region more than once.
+	if !ok || time.Since(entry.fetchedAt) > s.ttl {
+		response, _, err := s.client.ProductsApi.GetProducts(ctx, cloud, service, region)
+		if err != nil {
+			return Price{}, emperror.WrapWith(err, "failed to get products from Cloudinfo", "cloud", cloud, "service", service, "region", region)
+		}
+
+		entry = priceCacheEntry{
+			prices:    make(map[string]Price, len(response.Products)),
+			fetchedAt: time.Now(),
+		}
+
+		for _, product := range response.Products {
+			entry.prices[product.Type] = productPrice(product)
+		}
+
+		s.mu.Lock()
+		s.cache[key] = entry
+		s.mu.Unlock()
+	}
+
+	price, ok := entry.prices[instanceType]
+	if !ok {
+		return Price{}, emperror.With(ErrPriceNotFound, "cloud", cloud, "service", service, "region", region, "instanceType", instanceType)
+	}
+
+	return price, nil
+}
+
+func productPrice(product cloudinfo.ProductDetails) Price {
+	price := Price{OnDemand: product.OnDemandPrice}
+
+	var sum float64
+	var count int
+	for _, zonePrice := range product.SpotPrice {
+		if zonePrice.Price > 0 {
+			sum += zonePrice.Price
+			count++
+		}
+	}
+
+	if count > 0 {
+		price.Spot = sum / float64(count)
+	}
+
+	return price
+}
@@ -18,8 +18,8 @@ import (
 	"path/filepath"
 	"sync"
 
+	"github.com/banzaicloud/bank-vaults/pkg/vault"
 	"github.com/banzaicloud/pipeline/pkg/crypto/cert"
-	"github.com/banzaicloud/pipeline/secret"
 	"github.com/spf13/viper"
 )
 
@@ -40,7 +40,12 @@ func newCertGenerator() *cert.Generator {
 		)
 
 	case "vault":
-		caLoader = cert.NewVaultCALoader(secret.Store.Logical, viper.GetString("cert.path"))
+		vaultClient, err := vault.NewClient("pipeline")
+		if err != nil {
+			panic(err)
+		}
+
+		caLoader = cert.NewVaultCALoader(vaultClient.Vault().Logical(), viper.GetString("cert.path"))
 	}
 
 	generator := cert.NewGenerator(cert.NewCACache(caLoader))
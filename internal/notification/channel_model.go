@@ -0,0 +1,66 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notification
+
+import (
+	"time"
+)
+
+// TableName constants
+const (
+	channelTableName  = "notification_channels"
+	deliveryTableName = "notification_deliveries"
+)
+
+// ChannelModel is the persisted form of a notification channel.
+type ChannelModel struct {
+	ID             uint   `gorm:"primary_key"`
+	OrganizationID uint   `gorm:"unique_index:idx_notification_channels_org_name;not null"`
+	Name           string `gorm:"unique_index:idx_notification_channels_org_name;not null"`
+	Type           string `gorm:"not null"`
+	URL            string `sql:"type:text"`
+	Secret         string
+	Recipients     string `sql:"type:text"`
+	Events         string `sql:"type:text"`
+	Enabled        bool   `gorm:"not null"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// TableName changes the default table name.
+func (ChannelModel) TableName() string {
+	return channelTableName
+}
+
+// DeliveryModel is a single attempt (or series of attempts) to deliver an event through a channel.
+type DeliveryModel struct {
+	ID             uint       `gorm:"primary_key"`
+	OrganizationID uint       `gorm:"index:idx_notification_deliveries_org_id;not null"`
+	ChannelID      uint       `gorm:"index:idx_notification_deliveries_channel_id;not null"`
+	Event          string     `gorm:"not null"`
+	Payload        string     `sql:"type:text"`
+	Status         string     `gorm:"index:idx_notification_deliveries_status_next_attempt;not null"`
+	Attempts       int        `gorm:"not null"`
+	LastError      string     `sql:"type:text"`
+	NextAttemptAt  *time.Time `gorm:"index:idx_notification_deliveries_status_next_attempt"`
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// TableName changes the default table name.
+func (DeliveryModel) TableName() string {
+	return deliveryTableName
+}
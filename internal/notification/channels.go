@@ -0,0 +1,258 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notification
+
+import (
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/goph/emperror"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// Channel types
+const (
+	ChannelWebhook = "webhook"
+	ChannelSlack   = "slack"
+	ChannelEmail   = "email"
+)
+
+// ErrChannelNotFound is returned when a notification channel cannot be found.
+var ErrChannelNotFound = errors.New("notification channel not found")
+
+// ErrInvalidChannel is returned when a notification channel definition is invalid.
+var ErrInvalidChannel = errors.New("invalid notification channel")
+
+// Channel describes a notification channel of an organization.
+type Channel struct {
+	ID         uint      `json:"id"`
+	Name       string    `json:"name" binding:"required"`
+	Type       string    `json:"type" binding:"required"`
+	URL        string    `json:"url,omitempty"`
+	Secret     string    `json:"secret,omitempty"`
+	Recipients []string  `json:"recipients,omitempty"`
+	Events     []string  `json:"events,omitempty"`
+	Enabled    bool      `json:"enabled"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// Channels manages the notification channels of organizations.
+type Channels struct {
+	db *gorm.DB
+}
+
+// NewChannels returns a new Channels instance.
+func NewChannels(db *gorm.DB) *Channels {
+	return &Channels{
+		db: db,
+	}
+}
+
+// List returns the notification channels of an organization.
+func (c *Channels) List(organizationID uint) ([]Channel, error) {
+	var models []ChannelModel
+
+	err := c.db.Where("organization_id = ?", organizationID).Order("id").Find(&models).Error
+	if err != nil {
+		return nil, emperror.WrapWith(err, "failed to list notification channels", "organizationId", organizationID)
+	}
+
+	channels := make([]Channel, 0, len(models))
+	for _, model := range models {
+		channels = append(channels, channelFromModel(model))
+	}
+
+	return channels, nil
+}
+
+// Get returns a notification channel of an organization.
+func (c *Channels) Get(organizationID uint, channelID uint) (*Channel, error) {
+	model, err := c.find(organizationID, channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	channel := channelFromModel(*model)
+
+	return &channel, nil
+}
+
+// Create validates and stores a new notification channel.
+func (c *Channels) Create(organizationID uint, channel Channel) (*Channel, error) {
+	if err := validateChannel(channel); err != nil {
+		return nil, err
+	}
+
+	model := ChannelModel{OrganizationID: organizationID}
+	channelToModel(channel, &model)
+
+	err := c.db.Create(&model).Error
+	if err != nil {
+		return nil, emperror.WrapWith(err, "failed to create notification channel", "organizationId", organizationID, "name", channel.Name)
+	}
+
+	created := channelFromModel(model)
+
+	return &created, nil
+}
+
+// Update validates and replaces an existing notification channel.
+// An empty secret leaves the current secret of the channel unchanged.
+func (c *Channels) Update(organizationID uint, channelID uint, channel Channel) (*Channel, error) {
+	model, err := c.find(organizationID, channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if channel.Secret == "" {
+		channel.Secret = model.Secret
+	}
+
+	if err := validateChannel(channel); err != nil {
+		return nil, err
+	}
+
+	channelToModel(channel, model)
+
+	err = c.db.Save(model).Error
+	if err != nil {
+		return nil, emperror.WrapWith(err, "failed to update notification channel", "organizationId", organizationID, "channelId", channelID)
+	}
+
+	updated := channelFromModel(*model)
+
+	return &updated, nil
+}
+
+// Delete deletes a notification channel along with its delivery log.
+func (c *Channels) Delete(organizationID uint, channelID uint) error {
+	model, err := c.find(organizationID, channelID)
+	if err != nil {
+		return err
+	}
+
+	err = c.db.Where("channel_id = ?", model.ID).Delete(&DeliveryModel{}).Error
+	if err != nil {
+		return emperror.WrapWith(err, "failed to delete notification deliveries", "organizationId", organizationID, "channelId", channelID)
+	}
+
+	err = c.db.Delete(model).Error
+	if err != nil {
+		return emperror.WrapWith(err, "failed to delete notification channel", "organizationId", organizationID, "channelId", channelID)
+	}
+
+	return nil
+}
+
+func (c *Channels) find(organizationID uint, channelID uint) (*ChannelModel, error) {
+	var model ChannelModel
+
+	err := c.db.Where("organization_id = ? AND id = ?", organizationID, channelID).First(&model).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, ErrChannelNotFound
+	} else if err != nil {
+		return nil, emperror.WrapWith(err, "failed to get notification channel", "organizationId", organizationID, "channelId", channelID)
+	}
+
+	return &model, nil
+}
+
+func validateChannel(channel Channel) error {
+	if strings.TrimSpace(channel.Name) == "" {
+		return errors.WithMessage(ErrInvalidChannel, "name is required")
+	}
+
+	switch channel.Type {
+	case ChannelWebhook, ChannelSlack:
+		u, err := url.Parse(channel.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.WithMessage(ErrInvalidChannel, "a valid http(s) url is required")
+		}
+
+	case ChannelEmail:
+		if len(channel.Recipients) == 0 {
+			return errors.WithMessage(ErrInvalidChannel, "at least one recipient is required")
+		}
+
+		for _, recipient := range channel.Recipients {
+			if _, err := mail.ParseAddress(recipient); err != nil {
+				return errors.WithMessage(ErrInvalidChannel, "invalid recipient: "+recipient)
+			}
+		}
+
+	default:
+		return errors.WithMessage(ErrInvalidChannel, "type must be one of webhook, slack or email")
+	}
+
+	for _, event := range channel.Events {
+		if !IsValidEventType(event) {
+			return errors.WithMessage(ErrInvalidChannel, "unknown event: "+event)
+		}
+	}
+
+	return nil
+}
+
+// subscribed returns true if the channel should receive the given event type.
+func (m ChannelModel) subscribed(eventType string) bool {
+	if m.Events == "" || eventType == EventTest {
+		return true
+	}
+
+	for _, event := range strings.Split(m.Events, ",") {
+		if event == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+func channelToModel(channel Channel, model *ChannelModel) {
+	model.Name = channel.Name
+	model.Type = channel.Type
+	model.URL = channel.URL
+	model.Secret = channel.Secret
+	model.Recipients = strings.Join(channel.Recipients, ",")
+	model.Events = strings.Join(channel.Events, ",")
+	model.Enabled = channel.Enabled
+}
+
+// channelFromModel converts a model to a Channel, the secret is never exposed.
+func channelFromModel(model ChannelModel) Channel {
+	channel := Channel{
+		ID:        model.ID,
+		Name:      model.Name,
+		Type:      model.Type,
+		URL:       model.URL,
+		Enabled:   model.Enabled,
+		CreatedAt: model.CreatedAt,
+		UpdatedAt: model.UpdatedAt,
+	}
+
+	if model.Recipients != "" {
+		channel.Recipients = strings.Split(model.Recipients, ",")
+	}
+
+	if model.Events != "" {
+		channel.Events = strings.Split(model.Events, ",")
+	}
+
+	return channel
+}
@@ -0,0 +1,123 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notification
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Event types notification channels can subscribe to.
+const (
+	EventClusterCreated         = "cluster.created"
+	EventClusterCreateFailed    = "cluster.create_failed"
+	EventClusterPostHooksFailed = "cluster.posthooks_failed"
+	EventClusterUpdated         = "cluster.updated"
+	EventClusterUpdateFailed    = "cluster.update_failed"
+	EventClusterDeleted         = "cluster.deleted"
+	EventClusterDeleteFailed    = "cluster.delete_failed"
+	EventClusterTTLExpired      = "cluster.ttl_expired"
+	EventBackupFailed           = "backup.failed"
+
+	// EventTest is sent when testing a channel, channels cannot subscribe to it.
+	EventTest = "test"
+)
+
+// nolint: gochecknoglobals
+var eventTitles = map[string]string{
+	EventClusterCreated:         "Cluster created",
+	EventClusterCreateFailed:    "Cluster creation failed",
+	EventClusterPostHooksFailed: "Cluster posthooks failed",
+	EventClusterUpdated:         "Cluster updated",
+	EventClusterUpdateFailed:    "Cluster update failed",
+	EventClusterDeleted:         "Cluster deleted",
+	EventClusterDeleteFailed:    "Cluster deletion failed",
+	EventClusterTTLExpired:      "Cluster TTL expired",
+	EventBackupFailed:           "Cluster backup failed",
+	EventTest:                   "Test notification",
+}
+
+// EventTypes returns the event types notification channels can subscribe to.
+func EventTypes() []string {
+	return []string{
+		EventClusterCreated,
+		EventClusterCreateFailed,
+		EventClusterPostHooksFailed,
+		EventClusterUpdated,
+		EventClusterUpdateFailed,
+		EventClusterDeleted,
+		EventClusterDeleteFailed,
+		EventClusterTTLExpired,
+		EventBackupFailed,
+	}
+}
+
+// IsValidEventType returns true if channels can subscribe to the given event type.
+func IsValidEventType(eventType string) bool {
+	for _, t := range EventTypes() {
+		if t == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Event is the payload sent through notification channels.
+type Event struct {
+	Type           string    `json:"type"`
+	OrganizationID uint      `json:"organizationId"`
+	ClusterID      uint      `json:"clusterId,omitempty"`
+	ClusterName    string    `json:"clusterName,omitempty"`
+	BackupName     string    `json:"backupName,omitempty"`
+	Message        string    `json:"message,omitempty"`
+	Time           time.Time `json:"time"`
+}
+
+// Title returns a short human readable description of the event type.
+func (e Event) Title() string {
+	if title, ok := eventTitles[e.Type]; ok {
+		return title
+	}
+
+	return e.Type
+}
+
+// Summary returns a single line human readable description of the event.
+func (e Event) Summary() string {
+	var details []string
+
+	if e.ClusterName != "" {
+		details = append(details, fmt.Sprintf("cluster %q", e.ClusterName))
+	} else if e.ClusterID != 0 {
+		details = append(details, fmt.Sprintf("cluster #%d", e.ClusterID))
+	}
+
+	if e.BackupName != "" {
+		details = append(details, fmt.Sprintf("backup %q", e.BackupName))
+	}
+
+	summary := e.Title()
+	if len(details) > 0 {
+		summary += " (" + strings.Join(details, ", ") + ")"
+	}
+
+	if e.Message != "" {
+		summary += ": " + e.Message
+	}
+
+	return summary
+}
@@ -26,6 +26,8 @@ import (
 func Migrate(db *gorm.DB, logger logrus.FieldLogger) error {
 	tables := []interface{}{
 		&NotificationModel{},
+		&ChannelModel{},
+		&DeliveryModel{},
 	}
 
 	var tableNames string
@@ -0,0 +1,340 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/goph/emperror"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// deliveryClaimTimeout is how long a delivery attempt is reserved for the Pipeline instance making it.
+// The delivery is retried by any instance after that, in case the attempt has been interrupted.
+const deliveryClaimTimeout = 5 * time.Minute
+
+// Delivery describes the delivery of an event through a notification channel.
+type Delivery struct {
+	ID            uint       `json:"id"`
+	ChannelID     uint       `json:"channelId"`
+	Event         string     `json:"event"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"lastError,omitempty"`
+	NextAttemptAt *time.Time `json:"nextAttemptAt,omitempty"`
+	DeliveredAt   *time.Time `json:"deliveredAt,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+}
+
+// Notifier sends events through the notification channels of organizations
+// and retries failed deliveries with exponential backoff.
+type Notifier struct {
+	db            *gorm.DB
+	senders       map[string]sender
+	maxAttempts   int
+	retryInterval time.Duration
+	logger        logrus.FieldLogger
+	errorHandler  emperror.Handler
+}
+
+// NewNotifier returns a new Notifier.
+func NewNotifier(
+	db *gorm.DB,
+	smtpConfig SMTPConfig,
+	maxAttempts int,
+	retryInterval time.Duration,
+	logger logrus.FieldLogger,
+	errorHandler emperror.Handler,
+) *Notifier {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	return &Notifier{
+		db: db,
+		senders: map[string]sender{
+			ChannelWebhook: &webhookSender{client: client},
+			ChannelSlack:   &slackSender{client: client},
+			ChannelEmail:   &emailSender{config: smtpConfig},
+		},
+		maxAttempts:   maxAttempts,
+		retryInterval: retryInterval,
+		logger:        logger,
+		errorHandler:  errorHandler,
+	}
+}
+
+// Notify records a delivery for every enabled channel of the organization subscribed to the event
+// and makes a first delivery attempt right away.
+func (n *Notifier) Notify(ctx context.Context, event Event) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	var channels []ChannelModel
+
+	err := n.db.Where("organization_id = ? AND enabled = ?", event.OrganizationID, true).Find(&channels).Error
+	if err != nil {
+		return emperror.WrapWith(err, "failed to list notification channels", "organizationId", event.OrganizationID)
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return emperror.Wrap(err, "failed to marshal event")
+	}
+
+	for _, channel := range channels {
+		if !channel.subscribed(event.Type) {
+			continue
+		}
+
+		// The first attempt is claimed by this instance
+		claimedUntil := time.Now().Add(deliveryClaimTimeout)
+		delivery := DeliveryModel{
+			OrganizationID: event.OrganizationID,
+			ChannelID:      channel.ID,
+			Event:          event.Type,
+			Payload:        string(payload),
+			Status:         DeliveryPending,
+			NextAttemptAt:  &claimedUntil,
+		}
+
+		err := n.db.Create(&delivery).Error
+		if err != nil {
+			n.errorHandler.Handle(emperror.WrapWith(err, "failed to create notification delivery", "channelId", channel.ID))
+			continue
+		}
+
+		n.deliver(ctx, &delivery, channel, event)
+	}
+
+	return nil
+}
+
+// Test sends a test event through a channel without recording a delivery.
+func (n *Notifier) Test(ctx context.Context, organizationID uint, channelID uint) error {
+	channel, err := NewChannels(n.db).find(organizationID, channelID)
+	if err != nil {
+		return err
+	}
+
+	return n.send(ctx, *channel, Event{
+		Type:           EventTest,
+		OrganizationID: organizationID,
+		Message:        "this is a test notification from Pipeline",
+		Time:           time.Now(),
+	})
+}
+
+// ListDeliveries returns the latest deliveries of an organization, optionally filtered by channel.
+func (n *Notifier) ListDeliveries(organizationID uint, channelID uint, limit int) ([]Delivery, error) {
+	var models []DeliveryModel
+
+	query := n.db.Where("organization_id = ?", organizationID)
+	if channelID != 0 {
+		query = query.Where("channel_id = ?", channelID)
+	}
+
+	err := query.Order("id desc").Limit(limit).Find(&models).Error
+	if err != nil {
+		return nil, emperror.WrapWith(err, "failed to list notification deliveries", "organizationId", organizationID)
+	}
+
+	deliveries := make([]Delivery, 0, len(models))
+	for _, model := range models {
+		deliveries = append(deliveries, Delivery{
+			ID:            model.ID,
+			ChannelID:     model.ChannelID,
+			Event:         model.Event,
+			Status:        model.Status,
+			Attempts:      model.Attempts,
+			LastError:     model.LastError,
+			NextAttemptAt: model.NextAttemptAt,
+			DeliveredAt:   model.DeliveredAt,
+			CreatedAt:     model.CreatedAt,
+		})
+	}
+
+	return deliveries, nil
+}
+
+// Run retries pending deliveries periodically until the context is cancelled.
+func (n *Notifier) Run(ctx context.Context, interval time.Duration) {
+	n.logger.WithField("interval", interval.String()).Info("starting notification delivery retries")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := n.RetryPending(ctx); err != nil {
+				n.errorHandler.Handle(err)
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// RetryPending makes a new attempt to send every pending delivery that is due.
+func (n *Notifier) RetryPending(ctx context.Context) error {
+	var deliveries []DeliveryModel
+
+	err := n.db.Where("status = ? AND next_attempt_at <= ?", DeliveryPending, time.Now()).Order("id").Find(&deliveries).Error
+	if err != nil {
+		return emperror.Wrap(err, "failed to list pending notification deliveries")
+	}
+
+	for i := range deliveries {
+		delivery := &deliveries[i]
+
+		claimed, err := n.claim(delivery)
+		if err != nil {
+			return err
+		}
+
+		if !claimed {
+			continue
+		}
+
+		channel, err := NewChannels(n.db).find(delivery.OrganizationID, delivery.ChannelID)
+		if err == ErrChannelNotFound {
+			n.finish(delivery, DeliveryFailed, err.Error())
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		var event Event
+		if err := json.Unmarshal([]byte(delivery.Payload), &event); err != nil {
+			n.finish(delivery, DeliveryFailed, "invalid payload: "+err.Error())
+			continue
+		}
+
+		n.deliver(ctx, delivery, *channel, event)
+	}
+
+	return nil
+}
+
+// claim reserves a due delivery for an attempt made by this instance.
+// It returns false if another instance has claimed the delivery in the meantime.
+func (n *Notifier) claim(delivery *DeliveryModel) (bool, error) {
+	now := time.Now()
+	claimedUntil := now.Add(deliveryClaimTimeout)
+
+	result := n.db.Model(&DeliveryModel{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", delivery.ID, DeliveryPending, now).
+		Update("next_attempt_at", claimedUntil)
+	if result.Error != nil {
+		return false, emperror.WrapWith(result.Error, "failed to claim notification delivery", "deliveryId", delivery.ID)
+	}
+
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	delivery.NextAttemptAt = &claimedUntil
+
+	return true, nil
+}
+
+func (n *Notifier) deliver(ctx context.Context, delivery *DeliveryModel, channel ChannelModel, event Event) {
+	logger := n.logger.WithFields(logrus.Fields{
+		"organization": delivery.OrganizationID,
+		"channel":      channel.ID,
+		"delivery":     delivery.ID,
+		"event":        delivery.Event,
+	})
+
+	delivery.Attempts++
+
+	err := n.send(ctx, channel, event)
+	if err == nil {
+		logger.Debug("notification delivered")
+		n.finish(delivery, DeliveryDelivered, "")
+
+		return
+	}
+
+	logger.WithField("attempts", delivery.Attempts).Warn(err.Error())
+
+	if delivery.Attempts >= n.maxAttempts {
+		n.finish(delivery, DeliveryFailed, err.Error())
+
+		return
+	}
+
+	next := time.Now().Add(n.backoff(delivery.Attempts))
+	delivery.LastError = err.Error()
+	delivery.NextAttemptAt = &next
+
+	n.save(delivery)
+}
+
+func (n *Notifier) send(ctx context.Context, channel ChannelModel, event Event) error {
+	s, ok := n.senders[channel.Type]
+	if !ok {
+		return errors.Errorf("unsupported channel type: %s", channel.Type)
+	}
+
+	return s.Send(ctx, channel, event)
+}
+
+func (n *Notifier) finish(delivery *DeliveryModel, status string, lastError string) {
+	delivery.Status = status
+	delivery.LastError = lastError
+	delivery.NextAttemptAt = nil
+
+	if status == DeliveryDelivered {
+		now := time.Now()
+		delivery.DeliveredAt = &now
+	}
+
+	n.save(delivery)
+}
+
+func (n *Notifier) save(delivery *DeliveryModel) {
+	err := n.db.Save(delivery).Error
+	if err != nil {
+		n.errorHandler.Handle(emperror.WrapWith(err, "failed to save notification delivery", "deliveryId", delivery.ID))
+	}
+}
+
+// backoff returns the delay before the next attempt: the retry interval doubled after every failed attempt.
+func (n *Notifier) backoff(attempts int) time.Duration {
+	delay := n.retryInterval
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+	}
+
+	return delay
+}
@@ -0,0 +1,254 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/goph/emperror"
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/pkg/errors"
+	logrustest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open("sqlite3", "file::memory:")
+	require.NoError(t, err)
+
+	require.NoError(t, db.AutoMigrate(&ChannelModel{}, &DeliveryModel{}).Error)
+
+	return db
+}
+
+func newTestNotifier(db *gorm.DB, maxAttempts int) *Notifier {
+	logger, _ := logrustest.NewNullLogger()
+
+	return NewNotifier(db, SMTPConfig{}, maxAttempts, time.Minute, logger, emperror.NewNoopHandler())
+}
+
+func TestChannels(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	channels := NewChannels(db)
+
+	tests := []struct {
+		name    string
+		channel Channel
+	}{
+		{name: "missing name", channel: Channel{Type: ChannelSlack, URL: "https://hooks.slack.com/x"}},
+		{name: "unknown type", channel: Channel{Name: "c", Type: "pager", URL: "https://example.com"}},
+		{name: "invalid url", channel: Channel{Name: "c", Type: ChannelWebhook, URL: "ftp://example.com"}},
+		{name: "missing recipients", channel: Channel{Name: "c", Type: ChannelEmail}},
+		{name: "invalid recipient", channel: Channel{Name: "c", Type: ChannelEmail, Recipients: []string{"nobody"}}},
+		{name: "unknown event", channel: Channel{Name: "c", Type: ChannelSlack, URL: "https://hooks.slack.com/x", Events: []string{"cluster.exploded"}}},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			_, err := channels.Create(1, test.channel)
+			assert.Equal(t, ErrInvalidChannel, errors.Cause(err))
+		})
+	}
+
+	created, err := channels.Create(1, Channel{
+		Name:    "ops",
+		Type:    ChannelWebhook,
+		URL:     "https://example.com/hook",
+		Secret:  "s3cr3t",
+		Events:  []string{EventClusterCreateFailed, EventBackupFailed},
+		Enabled: true,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, created.Secret)
+	assert.Equal(t, []string{EventClusterCreateFailed, EventBackupFailed}, created.Events)
+
+	_, err = channels.Get(2, created.ID)
+	assert.Equal(t, ErrChannelNotFound, err)
+
+	updated, err := channels.Update(1, created.ID, Channel{Name: "ops", Type: ChannelWebhook, URL: "https://example.com/new"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/new", updated.URL)
+	assert.Empty(t, updated.Events)
+
+	var model ChannelModel
+	require.NoError(t, db.First(&model, created.ID).Error)
+	assert.Equal(t, "s3cr3t", model.Secret, "an empty secret should keep the current one")
+
+	list, err := channels.List(1)
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+
+	require.NoError(t, channels.Delete(1, created.ID))
+	assert.Equal(t, ErrChannelNotFound, channels.Delete(1, created.ID))
+}
+
+func TestNotifier_Notify(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	var received []Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		if r.Header.Get(SignatureHeader) != Sign("s3cr3t", body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var event Event
+		require.NoError(t, json.Unmarshal(body, &event))
+		assert.Equal(t, event.Type, r.Header.Get(EventHeader))
+		received = append(received, event)
+	}))
+	defer server.Close()
+
+	channels := NewChannels(db)
+	webhook, err := channels.Create(1, Channel{Name: "hook", Type: ChannelWebhook, URL: server.URL, Secret: "s3cr3t", Enabled: true})
+	require.NoError(t, err)
+	_, err = channels.Create(1, Channel{Name: "backups", Type: ChannelWebhook, URL: server.URL, Secret: "s3cr3t", Events: []string{EventBackupFailed}, Enabled: true})
+	require.NoError(t, err)
+	_, err = channels.Create(1, Channel{Name: "disabled", Type: ChannelWebhook, URL: server.URL, Secret: "s3cr3t"})
+	require.NoError(t, err)
+	_, err = channels.Create(2, Channel{Name: "other org", Type: ChannelWebhook, URL: server.URL, Secret: "s3cr3t", Enabled: true})
+	require.NoError(t, err)
+
+	notifier := newTestNotifier(db, 3)
+
+	err = notifier.Notify(context.Background(), Event{Type: EventClusterCreated, OrganizationID: 1, ClusterID: 5, ClusterName: "demo"})
+	require.NoError(t, err)
+
+	require.Len(t, received, 1)
+	assert.Equal(t, "demo", received[0].ClusterName)
+	assert.False(t, received[0].Time.IsZero())
+
+	deliveries, err := notifier.ListDeliveries(1, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, webhook.ID, deliveries[0].ChannelID)
+	assert.Equal(t, DeliveryDelivered, deliveries[0].Status)
+	assert.Equal(t, 1, deliveries[0].Attempts)
+	assert.NotNil(t, deliveries[0].DeliveredAt)
+
+	require.NoError(t, notifier.Test(context.Background(), 1, webhook.ID))
+	assert.Len(t, received, 2)
+}
+
+func TestNotifier_RetryPending(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	fail := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	channel, err := NewChannels(db).Create(1, Channel{Name: "slack", Type: ChannelSlack, URL: server.URL, Enabled: true})
+	require.NoError(t, err)
+
+	notifier := newTestNotifier(db, 3)
+
+	require.NoError(t, notifier.Notify(context.Background(), Event{Type: EventClusterDeleteFailed, OrganizationID: 1, ClusterName: "demo"}))
+
+	var delivery DeliveryModel
+	require.NoError(t, db.First(&delivery).Error)
+	assert.Equal(t, DeliveryPending, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Contains(t, delivery.LastError, "503")
+	require.NotNil(t, delivery.NextAttemptAt)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), *delivery.NextAttemptAt, 5*time.Second)
+
+	// Not due yet
+	require.NoError(t, notifier.RetryPending(context.Background()))
+	require.NoError(t, db.First(&delivery).Error)
+	assert.Equal(t, 1, delivery.Attempts)
+
+	require.NoError(t, db.Model(&DeliveryModel{}).Update("next_attempt_at", time.Now().Add(-time.Second)).Error)
+	require.NoError(t, notifier.RetryPending(context.Background()))
+	require.NoError(t, db.First(&delivery).Error)
+	assert.Equal(t, 2, delivery.Attempts)
+	assert.WithinDuration(t, time.Now().Add(2*time.Minute), *delivery.NextAttemptAt, 5*time.Second)
+
+	require.NoError(t, db.Model(&DeliveryModel{}).Update("next_attempt_at", time.Now().Add(-time.Second)).Error)
+	require.NoError(t, notifier.RetryPending(context.Background()))
+	require.NoError(t, db.First(&delivery).Error)
+	assert.Equal(t, 3, delivery.Attempts)
+	assert.Equal(t, DeliveryFailed, delivery.Status)
+	assert.Nil(t, delivery.NextAttemptAt)
+
+	// A recovered endpoint receives new events
+	fail = false
+	require.NoError(t, notifier.Notify(context.Background(), Event{Type: EventClusterDeleted, OrganizationID: 1, ClusterName: "demo"}))
+
+	deliveries, err := notifier.ListDeliveries(1, channel.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	assert.Equal(t, DeliveryDelivered, deliveries[0].Status)
+	assert.Equal(t, DeliveryFailed, deliveries[1].Status)
+}
+
+func TestNotifier_RetryPending_Claimed(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	_, err := NewChannels(db).Create(1, Channel{Name: "webhook", Type: ChannelWebhook, URL: server.URL, Enabled: true})
+	require.NoError(t, err)
+
+	payload, err := json.Marshal(Event{Type: EventClusterDeleted, OrganizationID: 1, ClusterName: "demo"})
+	require.NoError(t, err)
+
+	due := time.Now().Add(-time.Second)
+	delivery := DeliveryModel{OrganizationID: 1, ChannelID: 1, Event: EventClusterDeleted, Payload: string(payload), Status: DeliveryPending, NextAttemptAt: &due}
+	require.NoError(t, db.Create(&delivery).Error)
+
+	// Another instance has listed the same due delivery, but this one claims it first
+	claimed, err := newTestNotifier(db, 3).claim(&DeliveryModel{ID: delivery.ID})
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	require.NoError(t, newTestNotifier(db, 3).RetryPending(context.Background()))
+	assert.Equal(t, 0, requests)
+
+	claimed, err = newTestNotifier(db, 3).claim(&DeliveryModel{ID: delivery.ID})
+	require.NoError(t, err)
+	assert.False(t, claimed)
+}
+
+func TestEvent_Summary(t *testing.T) {
+	event := Event{Type: EventBackupFailed, ClusterName: "demo", BackupName: "daily", Message: "bucket not found"}
+
+	assert.Equal(t, `Cluster backup failed (cluster "demo", backup "daily"): bucket not found`, event.Summary())
+}
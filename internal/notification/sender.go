@@ -0,0 +1,152 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+)
+
+// Headers sent along with webhook notifications.
+const (
+	EventHeader     = "X-Pipeline-Event"
+	SignatureHeader = "X-Pipeline-Signature"
+)
+
+// SMTPConfig holds the configuration of the SMTP server used by email channels.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// sender delivers an event through a specific type of channel.
+type sender interface {
+	Send(ctx context.Context, channel ChannelModel, event Event) error
+}
+
+// Sign returns the signature of a webhook payload: the hex encoded HMAC-SHA256 of the body, prefixed with "sha256=".
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+type webhookSender struct {
+	client *http.Client
+}
+
+func (s *webhookSender) Send(ctx context.Context, channel ChannelModel, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return emperror.Wrap(err, "failed to marshal event")
+	}
+
+	headers := map[string]string{EventHeader: event.Type}
+	if channel.Secret != "" {
+		headers[SignatureHeader] = Sign(channel.Secret, body)
+	}
+
+	return postJSON(ctx, s.client, channel.URL, body, headers)
+}
+
+type slackSender struct {
+	client *http.Client
+}
+
+func (s *slackSender) Send(ctx context.Context, channel ChannelModel, event Event) error {
+	body, err := json.Marshal(map[string]string{
+		"text": fmt.Sprintf("[Pipeline] %s", event.Summary()),
+	})
+	if err != nil {
+		return emperror.Wrap(err, "failed to marshal slack message")
+	}
+
+	return postJSON(ctx, s.client, channel.URL, body, nil)
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return emperror.Wrap(err, "failed to create request")
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return emperror.Wrap(err, "failed to send request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("unexpected response status: %s", resp.Status)
+	}
+
+	return nil
+}
+
+type emailSender struct {
+	config SMTPConfig
+}
+
+func (s *emailSender) Send(ctx context.Context, channel ChannelModel, event Event) error {
+	if s.config.Host == "" {
+		return errors.New("SMTP server is not configured")
+	}
+
+	recipients := strings.Split(channel.Recipients, ",")
+	subject := fmt.Sprintf("[Pipeline] %s", event.Title())
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", s.config.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(recipients, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprint(&msg, "Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	fmt.Fprintf(&msg, "%s\r\n\r\nOrganization: %d\r\nTime: %s\r\n", event.Summary(), event.OrganizationID, event.Time)
+
+	var auth smtp.Auth
+	if s.config.Username != "" {
+		auth = smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)
+	}
+
+	addr := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+
+	err := smtp.SendMail(addr, auth, s.config.From, recipients, msg.Bytes())
+	if err != nil {
+		return emperror.Wrap(err, "failed to send email")
+	}
+
+	return nil
+}
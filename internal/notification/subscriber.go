@@ -0,0 +1,135 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notification
+
+import (
+	"context"
+
+	"github.com/goph/emperror"
+
+	"github.com/banzaicloud/pipeline/model"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+)
+
+// Event bus topics published by the cluster manager and the backup sync service.
+const (
+	clusterCreatedTopic         = "cluster_created"
+	clusterDeletedTopic         = "cluster_deleted"
+	clusterUpdatedTopic         = "cluster_updated"
+	clusterCreationFailedTopic  = "cluster_creation_failed"
+	clusterPostHooksFailedTopic = "cluster_posthooks_failed"
+	clusterUpdateFailedTopic    = "cluster_update_failed"
+	clusterDeletionFailedTopic  = "cluster_deletion_failed"
+	clusterTTLExpiredTopic      = "cluster_ttl_expired"
+	backupFailedTopic           = "backup_failed"
+)
+
+type eventBus interface {
+	SubscribeAsync(topic string, fn interface{}, transactional bool) error
+}
+
+// clusterFinder is used to look up clusters of events that only carry the cluster ID.
+type clusterFinder interface {
+	FindOneByID(organizationID uint, clusterID uint) (*model.ClusterModel, error)
+}
+
+// Subscriber turns cluster and backup events into notifications.
+type Subscriber struct {
+	notifier     *Notifier
+	clusters     clusterFinder
+	errorHandler emperror.Handler
+}
+
+// NewSubscriber returns a new Subscriber.
+func NewSubscriber(notifier *Notifier, clusters clusterFinder, errorHandler emperror.Handler) *Subscriber {
+	return &Subscriber{
+		notifier:     notifier,
+		clusters:     clusters,
+		errorHandler: errorHandler,
+	}
+}
+
+// Register subscribes to the relevant topics of the event bus.
+func (s *Subscriber) Register(eb eventBus) error {
+	subscriptions := map[string]interface{}{
+		clusterCreatedTopic: func(clusterID uint) {
+			s.clusterEvent(EventClusterCreated, clusterID)
+		},
+		clusterUpdatedTopic: func(clusterID uint) {
+			s.clusterEvent(EventClusterUpdated, clusterID)
+		},
+		clusterDeletedTopic: func(orgID uint, clusterName string) {
+			s.notify(Event{Type: EventClusterDeleted, OrganizationID: orgID, ClusterName: clusterName})
+		},
+		clusterCreationFailedTopic: func(orgID uint, clusterID uint, clusterName string, reason string) {
+			s.notify(Event{Type: EventClusterCreateFailed, OrganizationID: orgID, ClusterID: clusterID, ClusterName: clusterName, Message: reason})
+		},
+		clusterPostHooksFailedTopic: func(orgID uint, clusterID uint, clusterName string, reason string) {
+			s.notify(Event{Type: EventClusterPostHooksFailed, OrganizationID: orgID, ClusterID: clusterID, ClusterName: clusterName, Message: reason})
+		},
+		clusterUpdateFailedTopic: func(orgID uint, clusterID uint, clusterName string, reason string) {
+			s.notify(Event{Type: EventClusterUpdateFailed, OrganizationID: orgID, ClusterID: clusterID, ClusterName: clusterName, Message: reason})
+		},
+		clusterDeletionFailedTopic: func(orgID uint, clusterID uint, clusterName string, reason string) {
+			s.notify(Event{Type: EventClusterDeleteFailed, OrganizationID: orgID, ClusterID: clusterID, ClusterName: clusterName, Message: reason})
+		},
+		clusterTTLExpiredTopic: func(orgID uint, clusterID uint, clusterName string) {
+			s.notify(Event{Type: EventClusterTTLExpired, OrganizationID: orgID, ClusterID: clusterID, ClusterName: clusterName})
+		},
+		backupFailedTopic: func(orgID uint, clusterID uint, backupName string, reason string) {
+			event := Event{Type: EventBackupFailed, OrganizationID: orgID, ClusterID: clusterID, BackupName: backupName, Message: reason}
+			if cluster, err := s.clusters.FindOneByID(orgID, clusterID); err == nil {
+				event.ClusterName = cluster.Name
+			}
+
+			s.notify(event)
+		},
+	}
+
+	for topic, fn := range subscriptions {
+		if err := eb.SubscribeAsync(topic, fn, false); err != nil {
+			return emperror.WrapWith(err, "failed to subscribe to topic", "topic", topic)
+		}
+	}
+
+	return nil
+}
+
+// clusterEvent sends a notification about an event that only carries the cluster ID.
+// Update events of clusters that are not running are skipped: those are reported as update failures.
+func (s *Subscriber) clusterEvent(eventType string, clusterID uint) {
+	cluster, err := s.clusters.FindOneByID(0, clusterID)
+	if err != nil {
+		s.errorHandler.Handle(emperror.WrapWith(err, "failed to find cluster for notification", "clusterId", clusterID, "event", eventType))
+		return
+	}
+
+	if eventType == EventClusterUpdated && cluster.Status != pkgCluster.Running {
+		return
+	}
+
+	s.notify(Event{
+		Type:           eventType,
+		OrganizationID: cluster.OrganizationId,
+		ClusterID:      cluster.ID,
+		ClusterName:    cluster.Name,
+	})
+}
+
+func (s *Subscriber) notify(event Event) {
+	if err := s.notifier.Notify(context.Background(), event); err != nil {
+		s.errorHandler.Handle(emperror.WrapWith(err, "failed to send notification", "event", event.Type))
+	}
+}
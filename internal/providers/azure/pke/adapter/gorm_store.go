@@ -18,6 +18,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/banzaicloud/pipeline/internal/cluster"
 	"github.com/banzaicloud/pipeline/internal/providers/azure/pke"
@@ -231,7 +232,7 @@ func (s gormAzurePKEClusterStore) GetByID(clusterID uint) (cluster pke.PKEOnAzur
 	model := gormAzurePKEClusterModel{
 		ClusterID: clusterID,
 	}
-	if err = emperror.Wrap(s.db.Preload("Cluster").Preload("NodePools").Where(&model).First(&model).Error, "failed to load model from database"); err != nil {
+	if err = emperror.Wrap(s.db.Preload("Cluster").Preload("Cluster.ScaleOptions").Preload("NodePools").Where(&model).First(&model).Error, "failed to load model from database"); err != nil {
 		return
 	}
 	fillClusterFromAzurePKEClusterModel(&cluster, model)
@@ -360,6 +361,117 @@ func (s gormAzurePKEClusterStore) SetFeature(clusterID uint, feature string, sta
 	return emperror.Wrapf(s.db.Model(&model).Updates(fields).Error, "failed to update %q feature state", feature)
 }
 
+func (s gormAzurePKEClusterStore) SetScaleOptions(clusterID uint, scaleOptions pkgCluster.ScaleOptions) error {
+	if clusterID == 0 {
+		return errors.New("cluster ID cannot be 0")
+	}
+
+	model := model.ScaleOptions{
+		ClusterID: clusterID,
+	}
+	if err := emperror.Wrap(s.db.Where(&model).FirstOrInit(&model).Error, "failed to load scale options model"); err != nil {
+		return err
+	}
+
+	model.Enabled = scaleOptions.Enabled
+	model.DesiredCpu = scaleOptions.DesiredCpu
+	model.DesiredMem = scaleOptions.DesiredMem
+	model.DesiredGpu = scaleOptions.DesiredGpu
+	model.OnDemandPct = scaleOptions.OnDemandPct
+	model.Excludes = strings.Join(scaleOptions.Excludes, cluster.InstanceTypeSeparator)
+	model.KeepDesiredCapacity = scaleOptions.KeepDesiredCapacity
+
+	return emperror.Wrap(s.db.Save(&model).Error, "failed to save scale options model")
+}
+
+func (s gormAzurePKEClusterStore) SetTTL(clusterID uint, ttl time.Duration) error {
+	if clusterID == 0 {
+		return errors.New("cluster ID cannot be 0")
+	}
+
+	model := cluster.ClusterModel{
+		ID: clusterID,
+	}
+
+	fields := map[string]interface{}{
+		"TtlMinutes": uint(ttl.Minutes()),
+	}
+
+	return emperror.Wrap(s.db.Model(&model).Updates(fields).Error, "failed to update cluster model")
+}
+
+func (s gormAzurePKEClusterStore) CreateNodePool(clusterID uint, nodePool pke.NodePool) error {
+	if clusterID == 0 {
+		return errors.New("cluster ID cannot be 0")
+	}
+
+	clusterModel := gormAzurePKEClusterModel{
+		ClusterID: clusterID,
+	}
+	if err := emperror.Wrap(s.db.Where(&clusterModel).First(&clusterModel).Error, "failed to load PKE-on-Azure cluster model"); err != nil {
+		return err
+	}
+
+	model := gormAzurePKENodePoolModel{
+		Autoscaling:  nodePool.Autoscaling,
+		ClusterID:    clusterModel.ID,
+		CreatedBy:    nodePool.CreatedBy,
+		DesiredCount: nodePool.DesiredCount,
+		InstanceType: nodePool.InstanceType,
+		Max:          nodePool.Max,
+		Min:          nodePool.Min,
+		Name:         nodePool.Name,
+		Roles:        strings.Join(nodePool.Roles, GORMRoleSeparator),
+		SubnetName:   nodePool.Subnet.Name,
+		Zones:        strings.Join(nodePool.Zones, GORMZoneSeparator),
+	}
+
+	return emperror.Wrap(s.db.Create(&model).Error, "failed to create node pool model")
+}
+
+func (s gormAzurePKEClusterStore) DeleteNodePool(clusterID uint, nodePoolName string) error {
+	model, err := s.getNodePoolModel(clusterID, nodePoolName)
+	if err != nil {
+		return err
+	}
+
+	return emperror.Wrap(s.db.Delete(&model).Error, "failed to soft-delete node pool model")
+}
+
+func (s gormAzurePKEClusterStore) SetNodePoolSizes(clusterID uint, nodePoolName string, min, max, desiredCount uint, autoscaling bool) error {
+	model, err := s.getNodePoolModel(clusterID, nodePoolName)
+	if err != nil {
+		return err
+	}
+
+	fields := map[string]interface{}{
+		"Autoscaling":  autoscaling,
+		"DesiredCount": desiredCount,
+		"Max":          max,
+		"Min":          min,
+	}
+
+	return emperror.Wrap(s.db.Model(&model).Updates(fields).Error, "failed to update node pool model")
+}
+
+func (s gormAzurePKEClusterStore) getNodePoolModel(clusterID uint, nodePoolName string) (model gormAzurePKENodePoolModel, err error) {
+	if clusterID == 0 {
+		return model, errors.New("cluster ID cannot be 0")
+	}
+
+	clusterModel := gormAzurePKEClusterModel{
+		ClusterID: clusterID,
+	}
+	if err = emperror.Wrap(s.db.Where(&clusterModel).First(&clusterModel).Error, "failed to load PKE-on-Azure cluster model"); err != nil {
+		return
+	}
+
+	model.ClusterID = clusterModel.ID
+	model.Name = nodePoolName
+	err = emperror.WrapWith(s.db.Where(&model).First(&model).Error, "failed to load node pool model", "nodePool", nodePoolName)
+	return
+}
+
 // Migrate executes the table migrations for the provider.
 func Migrate(db *gorm.DB, logger logrus.FieldLogger) error {
 	tables := []interface{}{
@@ -19,6 +19,9 @@ import (
 
 	"github.com/banzaicloud/pipeline/internal/cluster"
 	"github.com/banzaicloud/pipeline/internal/providers/azure/pke"
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	logrustest "github.com/sirupsen/logrus/hooks/test"
 	"gotest.tools/assert"
 )
 
@@ -42,3 +45,71 @@ func TestFillClusterFromClusterModel(t *testing.T) {
 		})
 	}
 }
+
+func TestGORMAzurePKEClusterStore_NodePools(t *testing.T) {
+	db, err := gorm.Open("sqlite3", "file::memory:")
+	assert.NilError(t, err)
+	defer db.Close()
+
+	logger, _ := logrustest.NewNullLogger()
+	assert.NilError(t, cluster.Migrate(db, logger))
+	assert.NilError(t, Migrate(db, logger))
+
+	store := NewGORMAzurePKEClusterStore(db)
+
+	cl, err := store.Create(pke.CreateParams{
+		Name:           "test-cluster",
+		OrganizationID: 1,
+		NodePools: []pke.NodePool{
+			{Name: "master", DesiredCount: 1, Max: 1, Min: 1, Roles: []string{"master"}, Subnet: pke.Subnetwork{Name: "subnet-0"}},
+			{Name: "worker", DesiredCount: 1, Max: 2, Min: 1, Roles: []string{"worker"}, Subnet: pke.Subnetwork{Name: "subnet-1"}},
+		},
+	})
+	assert.NilError(t, err)
+
+	err = store.CreateNodePool(cl.ID, pke.NodePool{
+		Name:         "pool2",
+		DesiredCount: 3,
+		InstanceType: "Standard_B2s",
+		Max:          5,
+		Min:          2,
+		Roles:        []string{"worker"},
+		Subnet:       pke.Subnetwork{Name: "subnet-1"},
+		Zones:        []string{"1", "2"},
+	})
+	assert.NilError(t, err)
+
+	assert.NilError(t, store.SetNodePoolSizes(cl.ID, "worker", 2, 4, 3, true))
+	assert.NilError(t, store.DeleteNodePool(cl.ID, "master"))
+	assert.Assert(t, store.DeleteNodePool(cl.ID, "missing") != nil)
+
+	cl, err = store.GetByID(cl.ID)
+	assert.NilError(t, err)
+	assert.Equal(t, len(cl.NodePools), 2)
+
+	nodePools := make(map[string]pke.NodePool, len(cl.NodePools))
+	for _, np := range cl.NodePools {
+		nodePools[np.Name] = np
+	}
+
+	assert.DeepEqual(t, pke.NodePool{
+		Autoscaling:  true,
+		DesiredCount: 3,
+		Max:          4,
+		Min:          2,
+		Name:         "worker",
+		Roles:        []string{"worker"},
+		Subnet:       pke.Subnetwork{Name: "subnet-1"},
+		Zones:        []string{""},
+	}, nodePools["worker"])
+	assert.DeepEqual(t, pke.NodePool{
+		DesiredCount: 3,
+		InstanceType: "Standard_B2s",
+		Max:          5,
+		Min:          2,
+		Name:         "pool2",
+		Roles:        []string{"worker"},
+		Subnet:       pke.Subnetwork{Name: "subnet-1"},
+		Zones:        []string{"1", "2"},
+	}, nodePools["pool2"])
+}
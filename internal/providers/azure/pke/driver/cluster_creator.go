@@ -110,6 +110,13 @@ type AzurePKEClusterCreationParams struct {
 	SSHSecretID    string
 }
 
+// Prepare validates the cluster creation parameters and fills in their defaults without creating anything
+func (cc AzurePKEClusterCreator) Prepare(ctx context.Context, params AzurePKEClusterCreationParams) (AzurePKEClusterCreationParams, error) {
+	err := cc.paramsPreparer.Prepare(ctx, &params)
+
+	return params, err
+}
+
 // Create
 func (cc AzurePKEClusterCreator) Create(ctx context.Context, params AzurePKEClusterCreationParams) (cl pke.PKEOnAzureCluster, err error) {
 	if err = cc.paramsPreparer.Prepare(ctx, &params); err != nil {
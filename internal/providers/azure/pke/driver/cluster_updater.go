@@ -0,0 +1,354 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package driver
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/banzaicloud/pipeline/cluster"
+	"github.com/banzaicloud/pipeline/internal/providers/azure/pke"
+	"github.com/banzaicloud/pipeline/internal/providers/azure/pke/driver/commoncluster"
+	"github.com/banzaicloud/pipeline/internal/providers/azure/pke/workflow"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	pkgSecret "github.com/banzaicloud/pipeline/pkg/secret"
+	"github.com/banzaicloud/pipeline/secret"
+	"github.com/gofrs/uuid"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"go.uber.org/cadence/client"
+)
+
+const WorkerRole = "worker"
+
+func MakeAzurePKEClusterUpdater(logger logrus.FieldLogger, store pke.AzurePKEClusterStore, workflowClient client.Client, pipelineExternalURL string) AzurePKEClusterUpdater {
+	return AzurePKEClusterUpdater{
+		logger:              logger,
+		store:               store,
+		workflowClient:      workflowClient,
+		pipelineExternalURL: pipelineExternalURL,
+	}
+}
+
+// AzurePKEClusterUpdater updates the node pools of existing PKE-on-Azure clusters
+type AzurePKEClusterUpdater struct {
+	logger              logrus.FieldLogger
+	store               pke.AzurePKEClusterStore
+	workflowClient      client.Client
+	pipelineExternalURL string
+}
+
+// AzurePKEClusterUpdateParams defines parameters for PKE-on-Azure cluster update
+type AzurePKEClusterUpdateParams struct {
+	ClusterID uint
+	UpdatedBy uint
+	NodePools []NodePool
+}
+
+// Update adds, removes and resizes the node pools of a cluster and waits for the update workflow to finish
+func (cu AzurePKEClusterUpdater) Update(ctx context.Context, params AzurePKEClusterUpdateParams) error {
+	cl, err := cu.store.GetByID(params.ClusterID)
+	if err != nil {
+		return err
+	}
+
+	changes, err := getNodePoolChanges(cl, params.NodePools, params.UpdatedBy)
+	if err != nil {
+		return err
+	}
+
+	input := workflow.UpdateClusterWorkflowInput{
+		OrganizationID:      cl.OrganizationID,
+		SecretID:            cl.SecretID,
+		ClusterID:           cl.ID,
+		ClusterName:         cl.Name,
+		ResourceGroupName:   cl.ResourceGroup.Name,
+		PublicIPAddressName: cl.Name + "-pip-in",
+		VirtualNetworkName:  cl.VirtualNetwork.Name,
+		NodePoolsToDelete:   changes.toDelete,
+		NodePoolsToUpdate:   changes.toUpdate,
+	}
+
+	if len(changes.toCreate) > 0 {
+		input.NodePoolsToCreate, err = cu.getNodePoolsToCreate(cl, changes.toCreate)
+		if err != nil {
+			return err
+		}
+	}
+
+	workflowOptions := client.StartWorkflowOptions{
+		TaskList:                     "pipeline",
+		ExecutionStartToCloseTimeout: 40 * time.Minute,
+	}
+
+	exec, err := cu.workflowClient.ExecuteWorkflow(ctx, workflowOptions, workflow.UpdateClusterWorkflowName, input)
+	if err != nil {
+		return emperror.Wrap(err, "failed to start cluster update workflow")
+	}
+
+	if err := cu.store.SetActiveWorkflowID(cl.ID, exec.GetID()); err != nil {
+		cu.logger.WithField("clusterID", cl.ID).WithField("workflowID", exec.GetID()).Error("failed to set active workflow ID", err)
+	}
+
+	return emperror.Wrap(exec.Get(ctx, nil), "cluster update workflow failed")
+}
+
+func (cu AzurePKEClusterUpdater) getNodePoolsToCreate(cl pke.PKEOnAzureCluster, nodePools []pke.NodePool) ([]workflow.NodePoolToCreate, error) {
+	sshKeyPair, err := getSSHKeyPair(cl.OrganizationID, cl.SSHSecretID)
+	if err != nil {
+		return nil, emperror.Wrap(err, "failed to get SSH key pair")
+	}
+
+	sir, err := secret.Store.Get(cl.OrganizationID, cl.SecretID)
+	if err != nil {
+		return nil, emperror.Wrap(err, "failed to get cluster secret")
+	}
+	tenantID := sir.GetValue(pkgSecret.AzureTenantID)
+
+	out := make([]workflow.NodePoolToCreate, len(nodePools))
+	for i, np := range nodePools {
+		vmssName := pke.GetVMSSName(cl.Name, np.Name)
+		out[i] = workflow.NodePoolToCreate{
+			NodePool: np,
+			RoleAssignment: workflow.RoleAssignmentTemplate{
+				Name:     uuid.Must(uuid.NewV1()).String(),
+				VMSSName: vmssName,
+				RoleName: "Contributor",
+			},
+			ScaleSet: workflow.VirtualMachineScaleSetTemplate{
+				AdminUsername: "azureuser",
+				Image: workflow.Image{
+					Offer:     "CentOS-CI",
+					Publisher: "OpenLogic",
+					SKU:       "7-CI",
+					Version:   "7.6.20190306",
+				},
+				InstanceCount: np.DesiredCount,
+				InstanceType:  np.InstanceType,
+				Location:      cl.Location,
+				Name:          vmssName,
+				SSHPublicKey:  sshKeyPair.PublicKeyData,
+				SubnetName:    np.Subnet.Name,
+				UserDataScriptParams: map[string]string{
+					"ClusterID":             strconv.FormatUint(uint64(cl.ID), 10),
+					"ClusterName":           cl.Name,
+					"InfraCIDR":             "<not yet set>",
+					"LoadBalancerSKU":       "standard",
+					"NodePoolName":          np.Name,
+					"NSGName":               cl.Name + "-worker-nsg",
+					"OrgID":                 strconv.FormatUint(uint64(cl.OrganizationID), 10),
+					"PipelineURL":           cu.pipelineExternalURL,
+					"PipelineToken":         "<not yet set>",
+					"PKEVersion":            pkeVersion,
+					"KubernetesVersion":     cl.Kubernetes.Version,
+					"PublicAddress":         "<not yet set>",
+					"RouteTableName":        cl.Name + "-route-table",
+					"SubnetName":            np.Subnet.Name,
+					"TenantID":              tenantID,
+					"VnetName":              cl.VirtualNetwork.Name,
+					"VnetResourceGroupName": cl.ResourceGroup.Name,
+				},
+				UserDataScriptTemplate: workerUserDataScriptTemplate,
+				Zones:                  np.Zones,
+			},
+		}
+	}
+
+	return out, nil
+}
+
+type nodePoolChanges struct {
+	toCreate []pke.NodePool
+	toDelete []workflow.NodePoolToDelete
+	toUpdate []workflow.NodePoolToUpdate
+}
+
+// getNodePoolChanges compares the current node pools of a cluster to the desired ones.
+// New node pools are created as workers in the subnet and zones of the first existing worker node pool
+// (or the master node pool, if there are no workers).
+func getNodePoolChanges(cl pke.PKEOnAzureCluster, nodePools []NodePool, createdBy uint) (changes nodePoolChanges, err error) {
+	desired := make(map[string]NodePool, len(nodePools))
+	for _, np := range nodePools {
+		if np.Count < 0 || np.Min < 0 || np.Max < 0 {
+			return changes, validationErrorf("node pool [%s] sizes cannot be negative", np.Name)
+		}
+		if np.Autoscaling && (np.Min > np.Max || np.Count < np.Min || np.Count > np.Max) {
+			return changes, validationErrorf("node pool [%s] count must be between min and max count", np.Name)
+		}
+		desired[np.Name] = np
+	}
+
+	var template *pke.NodePool
+	for i, current := range cl.NodePools {
+		isMaster := NodePool{Roles: current.Roles}.hasRole(MasterRole)
+		if template == nil && !isMaster {
+			template = &cl.NodePools[i]
+		}
+
+		np, ok := desired[current.Name]
+		if !ok {
+			if isMaster {
+				return changes, validationErrorf("master node pool [%s] cannot be removed", current.Name)
+			}
+
+			changes.toDelete = append(changes.toDelete, workflow.NodePoolToDelete{
+				Name:     current.Name,
+				VMSSName: pke.GetVMSSName(cl.Name, current.Name),
+			})
+			continue
+		}
+		delete(desired, current.Name)
+
+		if np.InstanceType != "" && np.InstanceType != current.InstanceType {
+			return changes, validationErrorf("instance type of node pool [%s] cannot be changed", current.Name)
+		}
+
+		update := workflow.NodePoolToUpdate{
+			Name:         current.Name,
+			VMSSName:     pke.GetVMSSName(cl.Name, current.Name),
+			Autoscaling:  np.Autoscaling,
+			DesiredCount: uint(np.Count),
+			Max:          uint(np.Max),
+			Min:          uint(np.Min),
+			Resize:       uint(np.Count) != current.DesiredCount,
+		}
+		if !update.Resize && update.Autoscaling == current.Autoscaling && update.Max == current.Max && update.Min == current.Min {
+			continue
+		}
+		if isMaster {
+			return changes, validationErrorf("master node pool [%s] cannot be changed", current.Name)
+		}
+
+		changes.toUpdate = append(changes.toUpdate, update)
+	}
+
+	if template == nil && len(cl.NodePools) > 0 {
+		template = &cl.NodePools[0]
+	}
+
+	names := make([]string, 0, len(desired))
+	for name := range desired {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		np := desired[name]
+		if np.InstanceType == "" {
+			return changes, validationErrorf("instance type of new node pool [%s] must be specified", name)
+		}
+		if template == nil {
+			return changes, errors.New("there is no node pool to take the subnet of new node pools from")
+		}
+
+		changes.toCreate = append(changes.toCreate, pke.NodePool{
+			Autoscaling:  np.Autoscaling,
+			CreatedBy:    createdBy,
+			DesiredCount: uint(np.Count),
+			InstanceType: np.InstanceType,
+			Max:          uint(np.Max),
+			Min:          uint(np.Min),
+			Name:         name,
+			Roles:        []string{WorkerRole},
+			Subnet:       template.Subnet,
+			Zones:        template.Zones,
+		})
+	}
+
+	return changes, nil
+}
+
+// UpdatableCluster returns a common cluster which updates its node pools with the PKE-on-Azure update workflow
+// when it is passed to a common cluster updater.
+func (cu AzurePKEClusterUpdater) UpdatableCluster(commonCluster cluster.CommonCluster, userID uint) (cluster.CommonCluster, error) {
+	azureCluster, ok := commonCluster.(*commoncluster.AzurePkeCluster)
+	if !ok {
+		return nil, errors.Errorf("cluster %d is not a PKE-on-Azure cluster", commonCluster.GetID())
+	}
+
+	return updatableCluster{
+		AzurePkeCluster: azureCluster,
+		updater:         cu,
+		userID:          userID,
+	}, nil
+}
+
+type updatableCluster struct {
+	*commoncluster.AzurePkeCluster
+
+	updater AzurePKEClusterUpdater
+	userID  uint
+}
+
+func (c updatableCluster) UpdatePKECluster(ctx context.Context, request *pkgCluster.UpdateClusterRequest, _ client.Client, _ string) error {
+	if request.PKE == nil {
+		return nil
+	}
+
+	params := AzurePKEClusterUpdateParams{
+		ClusterID: c.GetID(),
+		UpdatedBy: c.userID,
+	}
+	for name, np := range request.PKE.NodePools {
+		params.NodePools = append(params.NodePools, NodePool{
+			Name:         name,
+			InstanceType: np.InstanceType,
+			Autoscaling:  np.Autoscaling,
+			Count:        np.Count,
+			Min:          np.MinCount,
+			Max:          np.MaxCount,
+		})
+	}
+
+	if err := c.updater.Update(ctx, params); err != nil {
+		return err
+	}
+
+	// reload the cluster, so that the following update steps (eg. autoscaler deployment) see the new node pools
+	updated, err := commoncluster.MakeCommonClusterGetter(secret.Store, c.updater.store).GetByID(c.GetID())
+	if err != nil {
+		return emperror.Wrap(err, "failed to reload cluster")
+	}
+	*c.AzurePkeCluster = *updated
+
+	return nil
+}
+
+// UpdateNodePools resizes the node pools in the request with the PKE-on-Azure update workflow, leaving the others intact.
+func (c updatableCluster) UpdateNodePools(request *pkgCluster.UpdateNodePoolsRequest, userID uint) error {
+	params := AzurePKEClusterUpdateParams{
+		ClusterID: c.GetID(),
+		UpdatedBy: userID,
+	}
+	for _, np := range c.GetPKEOnAzureCluster().NodePools {
+		nodePool := NodePool{
+			Name:         np.Name,
+			InstanceType: np.InstanceType,
+			Autoscaling:  np.Autoscaling,
+			Count:        int(np.DesiredCount),
+			Min:          int(np.Min),
+			Max:          int(np.Max),
+		}
+		if data, ok := request.NodePools[np.Name]; ok && data != nil {
+			nodePool.Count = data.Count
+		}
+		params.NodePools = append(params.NodePools, nodePool)
+	}
+
+	return c.updater.Update(context.Background(), params)
+}
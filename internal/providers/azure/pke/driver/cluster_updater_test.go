@@ -0,0 +1,94 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package driver
+
+import (
+	"testing"
+
+	"github.com/banzaicloud/pipeline/internal/providers/azure/pke"
+	"github.com/banzaicloud/pipeline/internal/providers/azure/pke/workflow"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetNodePoolChanges(t *testing.T) {
+	cl := pke.PKEOnAzureCluster{
+		NodePools: []pke.NodePool{
+			{Name: "master", DesiredCount: 1, Min: 1, Max: 1, InstanceType: "Standard_B2s", Roles: []string{MasterRole}, Subnet: pke.Subnetwork{Name: "subnet-0"}},
+			{Name: "pool1", DesiredCount: 2, Min: 1, Max: 3, InstanceType: "Standard_B2s", Roles: []string{WorkerRole}, Subnet: pke.Subnetwork{Name: "subnet-1"}, Zones: []string{"1"}},
+			{Name: "pool2", DesiredCount: 1, Min: 1, Max: 1, InstanceType: "Standard_B2s", Roles: []string{WorkerRole}, Subnet: pke.Subnetwork{Name: "subnet-1"}},
+		},
+	}
+	cl.Name = "test"
+
+	master := NodePool{Name: "master", Count: 1, Min: 1, Max: 1}
+
+	t.Run("add, remove and resize", func(t *testing.T) {
+		changes, err := getNodePoolChanges(cl, []NodePool{
+			master,
+			{Name: "pool1", Count: 3, Min: 1, Max: 3},
+			{Name: "pool3", InstanceType: "Standard_D2s_v3", Count: 2, Min: 2, Max: 4, Autoscaling: true},
+		}, 42)
+		require.NoError(t, err)
+
+		assert.Equal(t, []workflow.NodePoolToDelete{{Name: "pool2", VMSSName: "test-pool2"}}, changes.toDelete)
+		assert.Equal(t, []workflow.NodePoolToUpdate{{Name: "pool1", VMSSName: "test-pool1", DesiredCount: 3, Min: 1, Max: 3, Resize: true}}, changes.toUpdate)
+		assert.Equal(t, []pke.NodePool{
+			{
+				Autoscaling:  true,
+				CreatedBy:    42,
+				DesiredCount: 2,
+				InstanceType: "Standard_D2s_v3",
+				Max:          4,
+				Min:          2,
+				Name:         "pool3",
+				Roles:        []string{WorkerRole},
+				Subnet:       pke.Subnetwork{Name: "subnet-1"},
+				Zones:        []string{"1"},
+			},
+		}, changes.toCreate)
+	})
+
+	t.Run("autoscaling limits only", func(t *testing.T) {
+		changes, err := getNodePoolChanges(cl, []NodePool{
+			master,
+			{Name: "pool1", Count: 2, Min: 1, Max: 5, Autoscaling: true},
+			{Name: "pool2", Count: 1, Min: 1, Max: 1},
+		}, 42)
+		require.NoError(t, err)
+
+		assert.Empty(t, changes.toCreate)
+		assert.Empty(t, changes.toDelete)
+		assert.Equal(t, []workflow.NodePoolToUpdate{{Name: "pool1", VMSSName: "test-pool1", Autoscaling: true, DesiredCount: 2, Min: 1, Max: 5}}, changes.toUpdate)
+	})
+
+	invalidRequests := map[string][]NodePool{
+		"master removed":        {{Name: "pool1", Count: 2, Min: 1, Max: 3}},
+		"master resized":        {{Name: "master", Count: 3, Min: 1, Max: 3}},
+		"instance type changed": {master, {Name: "pool1", InstanceType: "Standard_D2s_v3", Count: 2, Min: 1, Max: 3}},
+		"missing instance type": {master, {Name: "pool3", Count: 1, Min: 1, Max: 1}},
+		"count out of range":    {master, {Name: "pool1", Count: 5, Min: 1, Max: 3, Autoscaling: true}},
+		"negative count":        {master, {Name: "pool1", Count: -1}},
+	}
+	for name, nodePools := range invalidRequests {
+		nodePools := nodePools
+
+		t.Run(name, func(t *testing.T) {
+			_, err := getNodePoolChanges(cl, nodePools, 42)
+			require.Error(t, err)
+			assert.IsType(t, validationError{}, err)
+		})
+	}
+}
@@ -14,22 +14,38 @@
 package commoncluster
 
 import (
+	"context"
 	"crypto/sha256"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/pem"
 	"fmt"
+	"reflect"
+	"strings"
 	"time"
 
+	"github.com/Azure/go-autorest/autorest/azure"
 	"github.com/banzaicloud/pipeline/auth"
 	"github.com/banzaicloud/pipeline/internal/providers/azure/pke"
 	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	pkgPKE "github.com/banzaicloud/pipeline/pkg/cluster/pke"
 	pkgCommon "github.com/banzaicloud/pipeline/pkg/common"
+	pkgErrors "github.com/banzaicloud/pipeline/pkg/errors"
+	pkgAzure "github.com/banzaicloud/pipeline/pkg/providers/azure"
 	pkgSecret "github.com/banzaicloud/pipeline/pkg/secret"
 	"github.com/banzaicloud/pipeline/secret"
 	"github.com/goph/emperror"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+var log = logrus.WithField("provider", pke.PKEOnAzure)
+
+// Kubernetes service and pod network ranges used by the PKE install scripts
+const (
+	serviceCIDR = "10.10.0.0/16"
+	podCIDR     = "10.20.0.0/16"
 )
 
 type AzurePkeCluster struct {
@@ -114,8 +130,9 @@ func (a *AzurePkeCluster) GetSshSecretId() string {
 	return a.model.SSHSecretID
 }
 
-func (a *AzurePkeCluster) SaveSshSecretId(string) error {
-	panic("TODO")
+func (a *AzurePkeCluster) SaveSshSecretId(sshSecretID string) error {
+	a.model.SSHSecretID = sshSecretID
+	return a.store.SetSSHSecretID(a.model.ID, sshSecretID)
 }
 
 func (a *AzurePkeCluster) SaveConfigSecretId(secretID string) error {
@@ -131,48 +148,96 @@ func (a *AzurePkeCluster) GetSecretWithValidation() (*secret.SecretItemResponse,
 	return a.secrets.Get(a.model.OrganizationID, a.model.SecretID)
 }
 
+// Persist is a no-op: every change of the cluster is saved in the store immediately
 func (a *AzurePkeCluster) Persist() error {
-	panic("not implemented") // TODO?
+	return nil
 }
 
 func (a *AzurePkeCluster) DeleteFromDatabase() error {
-	panic("not implemented") // TODO?
+	return a.store.Delete(a.model.ID)
 }
 
+// CreateCluster is not supported: clusters are created by the PKE-on-Azure create cluster workflow
 func (a *AzurePkeCluster) CreateCluster() error {
-	panic("not implemented")
+	return errors.New("PKE-on-Azure clusters can only be created by the create cluster workflow")
 }
 
+// ValidateCreationFields is not supported: creation parameters are validated by the PKE-on-Azure cluster creator
 func (a *AzurePkeCluster) ValidateCreationFields(r *pkgCluster.CreateClusterRequest) error {
-	panic("not implemented")
+	return errors.New("PKE-on-Azure cluster creation parameters are validated by the cluster creator")
 }
 
+// UpdateCluster is not supported: node pools are updated by the PKE-on-Azure update cluster workflow
 func (a *AzurePkeCluster) UpdateCluster(*pkgCluster.UpdateClusterRequest, uint) error {
-	panic("not implemented")
+	return errors.New("PKE-on-Azure clusters can only be updated by the update cluster workflow")
 }
 
+// UpdateNodePools is not supported: node pools are updated by the PKE-on-Azure update cluster workflow
 func (a *AzurePkeCluster) UpdateNodePools(*pkgCluster.UpdateNodePoolsRequest, uint) error {
-	panic("not implemented")
+	return errors.New("PKE-on-Azure node pools can only be updated by the update cluster workflow")
 }
 
-func (a *AzurePkeCluster) CheckEqualityToUpdate(*pkgCluster.UpdateClusterRequest) error {
-	panic("not implemented")
+// CheckEqualityToUpdate validates the update request
+func (a *AzurePkeCluster) CheckEqualityToUpdate(r *pkgCluster.UpdateClusterRequest) error {
+	if r.PKE != nil && reflect.DeepEqual(r.PKE.NodePools, a.getUpdateNodePools()) {
+		return pkgErrors.ErrorNotDifferentInterfaces
+	}
+
+	return nil
 }
 
-func (a *AzurePkeCluster) AddDefaultsToUpdate(*pkgCluster.UpdateClusterRequest) {
-	panic("not implemented")
+// AddDefaultsToUpdate adds defaults to update request
+func (a *AzurePkeCluster) AddDefaultsToUpdate(r *pkgCluster.UpdateClusterRequest) {
+	current := a.getUpdateNodePools()
+
+	// keep the current node pools if none are specified
+	if r.PKE == nil {
+		r.PKE = &pkgPKE.UpdateClusterPKE{
+			NodePools: current,
+		}
+		return
+	}
+
+	for name, np := range r.PKE.NodePools {
+		if currentNp, ok := current[name]; ok && np.InstanceType == "" {
+			np.InstanceType = currentNp.InstanceType
+			r.PKE.NodePools[name] = np
+		}
+	}
 }
 
+func (a *AzurePkeCluster) getUpdateNodePools() pkgPKE.UpdateNodePools {
+	nodePools := make(pkgPKE.UpdateNodePools, len(a.model.NodePools))
+	for _, np := range a.model.NodePools {
+		nodePools[np.Name] = pkgPKE.UpdateNodePool{
+			InstanceType: np.InstanceType,
+			Autoscaling:  np.Autoscaling,
+			MinCount:     int(np.Min),
+			MaxCount:     int(np.Max),
+			Count:        int(np.DesiredCount),
+		}
+	}
+	return nodePools
+}
+
+// DeleteCluster is not supported: clusters are deleted by the PKE-on-Azure delete cluster workflow
 func (a *AzurePkeCluster) DeleteCluster() error {
-	panic("not implemented")
+	return errors.New("PKE-on-Azure clusters can only be deleted by the delete cluster workflow")
 }
 
 func (a *AzurePkeCluster) GetScaleOptions() *pkgCluster.ScaleOptions {
-	return nil // TODO
+	scaleOptions := a.model.ScaleOptions
+	return &scaleOptions
 }
 
-func (a *AzurePkeCluster) SetScaleOptions(*pkgCluster.ScaleOptions) {
-	panic("TODO")
+func (a *AzurePkeCluster) SetScaleOptions(scaleOptions *pkgCluster.ScaleOptions) {
+	if scaleOptions == nil {
+		return
+	}
+	a.model.ScaleOptions = *scaleOptions
+	if err := a.store.SetScaleOptions(a.model.ID, *scaleOptions); err != nil {
+		log.Errorf("failed to save scale options of cluster %d: %s", a.model.ID, err.Error())
+	}
 }
 
 func (a *AzurePkeCluster) GetTTL() time.Duration {
@@ -181,11 +246,14 @@ func (a *AzurePkeCluster) GetTTL() time.Duration {
 
 func (a *AzurePkeCluster) SetTTL(t time.Duration) {
 	a.model.TtlMinutes = uint(t.Minutes())
-	// TODO: persist
+	if err := a.store.SetTTL(a.model.ID, t); err != nil {
+		log.Errorf("failed to save TTL of cluster %d: %s", a.model.ID, err.Error())
+	}
 }
 
+// DownloadK8sConfig returns the Kubernetes config stored by the master node, as it cannot be downloaded from the provider
 func (a *AzurePkeCluster) DownloadK8sConfig() ([]byte, error) {
-	panic("not implemented")
+	return a.GetK8sConfig()
 }
 
 func (a *AzurePkeCluster) GetAPIEndpoint() (string, error) {
@@ -198,7 +266,10 @@ func (a *AzurePkeCluster) GetAPIEndpoint() (string, error) {
 }
 
 func (a *AzurePkeCluster) GetK8sIpv4Cidrs() (*pkgCluster.Ipv4Cidrs, error) {
-	panic("TODO")
+	return &pkgCluster.Ipv4Cidrs{
+		ServiceClusterIPRanges: []string{serviceCIDR},
+		PodIPRanges:            []string{podCIDR},
+	}, nil
 }
 
 func (a *AzurePkeCluster) GetK8sConfig() ([]byte, error) {
@@ -229,7 +300,7 @@ func (a *AzurePkeCluster) NeedAdminRights() bool {
 }
 
 func (a *AzurePkeCluster) GetKubernetesUserName() (string, error) {
-	panic("TODO")
+	return "", nil
 }
 
 func (a *AzurePkeCluster) GetStatus() (*pkgCluster.GetClusterStatusResponse, error) {
@@ -271,9 +342,36 @@ func (a *AzurePkeCluster) IsReady() (bool, error) {
 	return true, nil
 }
 
-func (a *AzurePkeCluster) ListNodeNames() (nodeNames pkgCommon.NodeNames, err error) {
-	// nodes are labeled in create request
-	return
+// ListNodeNames returns the names of the nodes grouped by node pools, based on the computer names of the scale set instances
+func (a *AzurePkeCluster) ListNodeNames() (pkgCommon.NodeNames, error) {
+	sir, err := a.secrets.Get(a.model.OrganizationID, a.model.SecretID)
+	if err != nil {
+		return nil, emperror.Wrap(err, "failed to get cluster secret")
+	}
+
+	cc, err := pkgAzure.NewCloudConnection(&azure.PublicCloud, pkgAzure.NewCredentials(sir.Values))
+	if err != nil {
+		return nil, emperror.Wrap(err, "failed to create cloud connection")
+	}
+
+	client := cc.GetVirtualMachineScaleSetVMsClient()
+
+	nodeNames := make(pkgCommon.NodeNames, len(a.model.NodePools))
+	for _, np := range a.model.NodePools {
+		vms, err := client.ListAll(context.TODO(), a.model.ResourceGroup.Name, pke.GetVMSSName(a.model.Name, np.Name))
+		if err != nil {
+			return nil, emperror.WrapWith(err, "failed to list scale set instances", "nodePool", np.Name)
+		}
+
+		nodeNames[np.Name] = []string{}
+		for _, vm := range vms {
+			if vm.VirtualMachineScaleSetVMProperties != nil && vm.OsProfile != nil && vm.OsProfile.ComputerName != nil {
+				nodeNames[np.Name] = append(nodeNames[np.Name], strings.ToLower(*vm.OsProfile.ComputerName))
+			}
+		}
+	}
+
+	return nodeNames, nil
 }
 
 func (a *AzurePkeCluster) NodePoolExists(nodePoolName string) bool {
@@ -291,7 +389,7 @@ func (a *AzurePkeCluster) GetSecurityScan() bool {
 
 func (a *AzurePkeCluster) SetSecurityScan(scan bool) {
 	a.model.SecurityScan = scan
-	a.store.SetFeature(a.model.ID, "SecurityScan", scan)
+	a.setFeature("SecurityScan", scan)
 }
 
 func (a *AzurePkeCluster) GetLogging() bool {
@@ -300,7 +398,7 @@ func (a *AzurePkeCluster) GetLogging() bool {
 
 func (a *AzurePkeCluster) SetLogging(l bool) {
 	a.model.Logging = l
-	a.store.SetFeature(a.model.ID, "Logging", l)
+	a.setFeature("Logging", l)
 }
 
 func (a *AzurePkeCluster) GetMonitoring() bool {
@@ -309,7 +407,7 @@ func (a *AzurePkeCluster) GetMonitoring() bool {
 
 func (a *AzurePkeCluster) SetMonitoring(m bool) {
 	a.model.Monitoring = m
-	a.store.SetFeature(a.model.ID, "Monitoring", m)
+	a.setFeature("Monitoring", m)
 }
 
 func (a *AzurePkeCluster) GetServiceMesh() bool {
@@ -318,8 +416,13 @@ func (a *AzurePkeCluster) GetServiceMesh() bool {
 
 func (a *AzurePkeCluster) SetServiceMesh(m bool) {
 	a.model.ServiceMesh = m
-	a.store.SetFeature(a.model.ID, "ServiceMesh", m)
+	a.setFeature("ServiceMesh", m)
+}
 
+func (a *AzurePkeCluster) setFeature(feature string, state bool) {
+	if err := a.store.SetFeature(a.model.ID, feature, state); err != nil {
+		log.Errorf("failed to save %s feature state of cluster %d: %s", feature, a.model.ID, err.Error())
+	}
 }
 
 func (a *AzurePkeCluster) SetStatus(status string, statusMessage string) error {
@@ -0,0 +1,250 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commoncluster
+
+import (
+	"testing"
+	"time"
+
+	intCluster "github.com/banzaicloud/pipeline/internal/cluster"
+	"github.com/banzaicloud/pipeline/internal/providers/azure/pke"
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	pkgPKE "github.com/banzaicloud/pipeline/pkg/cluster/pke"
+	pkgErrors "github.com/banzaicloud/pipeline/pkg/errors"
+	"github.com/banzaicloud/pipeline/secret"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClusterStore struct {
+	clusters map[uint]pke.PKEOnAzureCluster
+}
+
+func (s *fakeClusterStore) get(clusterID uint) (pke.PKEOnAzureCluster, error) {
+	cl, ok := s.clusters[clusterID]
+	if !ok {
+		return cl, errors.Errorf("cluster %d not found", clusterID)
+	}
+	return cl, nil
+}
+
+func (s *fakeClusterStore) update(clusterID uint, fn func(cl *pke.PKEOnAzureCluster)) error {
+	cl, err := s.get(clusterID)
+	if err != nil {
+		return err
+	}
+	fn(&cl)
+	s.clusters[clusterID] = cl
+	return nil
+}
+
+func (s *fakeClusterStore) Create(params pke.CreateParams) (pke.PKEOnAzureCluster, error) {
+	return pke.PKEOnAzureCluster{}, errors.New("not implemented")
+}
+
+func (s *fakeClusterStore) Delete(clusterID uint) error {
+	if _, err := s.get(clusterID); err != nil {
+		return err
+	}
+	delete(s.clusters, clusterID)
+	return nil
+}
+
+func (s *fakeClusterStore) GetByID(clusterID uint) (pke.PKEOnAzureCluster, error) {
+	return s.get(clusterID)
+}
+
+func (s *fakeClusterStore) SetStatus(clusterID uint, status, message string) error {
+	return s.update(clusterID, func(cl *pke.PKEOnAzureCluster) {
+		cl.Status = status
+		cl.StatusMessage = message
+	})
+}
+
+func (s *fakeClusterStore) SetActiveWorkflowID(clusterID uint, workflowID string) error {
+	return s.update(clusterID, func(cl *pke.PKEOnAzureCluster) { cl.ActiveWorkflowID = workflowID })
+}
+
+func (s *fakeClusterStore) SetConfigSecretID(clusterID uint, secretID string) error {
+	return s.update(clusterID, func(cl *pke.PKEOnAzureCluster) { cl.K8sSecretID = secretID })
+}
+
+func (s *fakeClusterStore) SetSSHSecretID(clusterID uint, sshSecretID string) error {
+	return s.update(clusterID, func(cl *pke.PKEOnAzureCluster) { cl.SSHSecretID = sshSecretID })
+}
+
+func (s *fakeClusterStore) SetFeature(clusterID uint, feature string, state bool) error {
+	return s.update(clusterID, func(cl *pke.PKEOnAzureCluster) {
+		switch feature {
+		case "SecurityScan":
+			cl.SecurityScan = state
+		case "Logging":
+			cl.Logging = state
+		case "Monitoring":
+			cl.Monitoring = state
+		case "ServiceMesh":
+			cl.ServiceMesh = state
+		}
+	})
+}
+
+func (s *fakeClusterStore) SetScaleOptions(clusterID uint, scaleOptions pkgCluster.ScaleOptions) error {
+	return s.update(clusterID, func(cl *pke.PKEOnAzureCluster) { cl.ScaleOptions = scaleOptions })
+}
+
+func (s *fakeClusterStore) SetTTL(clusterID uint, ttl time.Duration) error {
+	return s.update(clusterID, func(cl *pke.PKEOnAzureCluster) { cl.TtlMinutes = uint(ttl.Minutes()) })
+}
+
+func (s *fakeClusterStore) CreateNodePool(clusterID uint, nodePool pke.NodePool) error {
+	return s.update(clusterID, func(cl *pke.PKEOnAzureCluster) { cl.NodePools = append(cl.NodePools, nodePool) })
+}
+
+func (s *fakeClusterStore) DeleteNodePool(clusterID uint, nodePoolName string) error {
+	return s.update(clusterID, func(cl *pke.PKEOnAzureCluster) {
+		for i, np := range cl.NodePools {
+			if np.Name == nodePoolName {
+				cl.NodePools = append(cl.NodePools[:i], cl.NodePools[i+1:]...)
+				return
+			}
+		}
+	})
+}
+
+func (s *fakeClusterStore) SetNodePoolSizes(clusterID uint, nodePoolName string, min, max, desiredCount uint, autoscaling bool) error {
+	return s.update(clusterID, func(cl *pke.PKEOnAzureCluster) {
+		for i := range cl.NodePools {
+			if cl.NodePools[i].Name == nodePoolName {
+				cl.NodePools[i].Min = min
+				cl.NodePools[i].Max = max
+				cl.NodePools[i].DesiredCount = desiredCount
+				cl.NodePools[i].Autoscaling = autoscaling
+			}
+		}
+	})
+}
+
+type fakeSecretStore struct{}
+
+func (fakeSecretStore) Get(organizationID uint, secretID string) (*secret.SecretItemResponse, error) {
+	return nil, secret.ErrSecretNotExists
+}
+
+func (fakeSecretStore) GetByName(organizationID uint, secretName string) (*secret.SecretItemResponse, error) {
+	return nil, secret.ErrSecretNotExists
+}
+
+func newTestCluster(t *testing.T) (*fakeClusterStore, *AzurePkeCluster) {
+	store := &fakeClusterStore{
+		clusters: map[uint]pke.PKEOnAzureCluster{
+			1: {
+				ClusterBase: intCluster.ClusterBase{
+					ID:             1,
+					Name:           "test-cluster",
+					OrganizationID: 1,
+				},
+				NodePools: []pke.NodePool{
+					{Name: "master", InstanceType: "Standard_B2s", Roles: []string{"master"}, DesiredCount: 1, Min: 1, Max: 1},
+					{Name: "pool1", InstanceType: "Standard_B2s", Roles: []string{"worker"}, Autoscaling: true, DesiredCount: 2, Min: 1, Max: 3},
+				},
+			},
+		},
+	}
+
+	cl, err := MakeCommonClusterGetter(fakeSecretStore{}, store).GetByID(1)
+	require.NoError(t, err)
+
+	return store, cl
+}
+
+func TestAzurePkeCluster_Setters(t *testing.T) {
+	store, cl := newTestCluster(t)
+
+	require.NoError(t, cl.SaveSshSecretId("ssh-secret"))
+	assert.Equal(t, "ssh-secret", cl.GetSshSecretId())
+	assert.Equal(t, "ssh-secret", store.clusters[1].SSHSecretID)
+
+	cl.SetTTL(30 * time.Minute)
+	assert.Equal(t, 30*time.Minute, cl.GetTTL())
+	assert.Equal(t, uint(30), store.clusters[1].TtlMinutes)
+
+	cl.SetSecurityScan(true)
+	cl.SetLogging(true)
+	cl.SetMonitoring(true)
+	cl.SetServiceMesh(true)
+	assert.True(t, cl.GetSecurityScan() && cl.GetLogging() && cl.GetMonitoring() && cl.GetServiceMesh())
+	assert.True(t, store.clusters[1].SecurityScan && store.clusters[1].Logging && store.clusters[1].Monitoring && store.clusters[1].ServiceMesh)
+
+	require.NoError(t, cl.Persist())
+}
+
+func TestAzurePkeCluster_ScaleOptions(t *testing.T) {
+	store, cl := newTestCluster(t)
+
+	assert.False(t, cl.GetScaleOptions().Enabled)
+
+	cl.SetScaleOptions(nil)
+	assert.False(t, store.clusters[1].ScaleOptions.Enabled)
+
+	scaleOptions := pkgCluster.ScaleOptions{Enabled: true, DesiredCpu: 4, Excludes: []string{"pool1"}}
+	cl.SetScaleOptions(&scaleOptions)
+	assert.Equal(t, scaleOptions, *cl.GetScaleOptions())
+	assert.Equal(t, scaleOptions, store.clusters[1].ScaleOptions)
+
+	// modifying the returned options does not change the cluster
+	cl.GetScaleOptions().Enabled = false
+	assert.True(t, cl.GetScaleOptions().Enabled)
+}
+
+func TestAzurePkeCluster_NodePools(t *testing.T) {
+	_, cl := newTestCluster(t)
+
+	assert.True(t, cl.NodePoolExists("pool1"))
+	assert.False(t, cl.NodePoolExists("pool2"))
+
+	request := &pkgCluster.UpdateClusterRequest{}
+	cl.AddDefaultsToUpdate(request)
+	require.NotNil(t, request.PKE)
+	assert.Equal(t, pkgPKE.UpdateNodePools{
+		"master": {InstanceType: "Standard_B2s", Count: 1, MinCount: 1, MaxCount: 1},
+		"pool1":  {InstanceType: "Standard_B2s", Autoscaling: true, Count: 2, MinCount: 1, MaxCount: 3},
+	}, request.PKE.NodePools)
+	assert.Equal(t, pkgErrors.ErrorNotDifferentInterfaces, cl.CheckEqualityToUpdate(request))
+
+	request.PKE.NodePools["pool2"] = pkgPKE.UpdateNodePool{Count: 1}
+	cl.AddDefaultsToUpdate(request)
+	assert.Empty(t, request.PKE.NodePools["pool2"].InstanceType)
+	assert.NoError(t, cl.CheckEqualityToUpdate(request))
+}
+
+func TestAzurePkeCluster_Kubernetes(t *testing.T) {
+	_, cl := newTestCluster(t)
+
+	cidrs, err := cl.GetK8sIpv4Cidrs()
+	require.NoError(t, err)
+	assert.Equal(t, []string{serviceCIDR}, cidrs.ServiceClusterIPRanges)
+	assert.Equal(t, []string{podCIDR}, cidrs.PodIPRanges)
+
+	_, err = cl.ListNodeNames()
+	assert.Equal(t, secret.ErrSecretNotExists, errors.Cause(err))
+}
+
+func TestAzurePkeCluster_DeleteFromDatabase(t *testing.T) {
+	store, cl := newTestCluster(t)
+
+	require.NoError(t, cl.DeleteFromDatabase())
+	assert.Empty(t, store.clusters)
+}
@@ -15,6 +15,8 @@
 package pke
 
 import (
+	"time"
+
 	intCluster "github.com/banzaicloud/pipeline/internal/cluster"
 	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
 )
@@ -45,4 +47,9 @@ type AzurePKEClusterStore interface {
 	SetConfigSecretID(clusterID uint, secretID string) error
 	SetSSHSecretID(clusterID uint, sshSecretID string) error
 	SetFeature(clusterID uint, feature string, state bool) error
+	SetScaleOptions(clusterID uint, scaleOptions pkgCluster.ScaleOptions) error
+	SetTTL(clusterID uint, ttl time.Duration) error
+	CreateNodePool(clusterID uint, nodePool NodePool) error
+	DeleteNodePool(clusterID uint, nodePoolName string) error
+	SetNodePoolSizes(clusterID uint, nodePoolName string, min, max, desiredCount uint, autoscaling bool) error
 }
@@ -0,0 +1,100 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workflow
+
+import (
+	"context"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/goph/emperror"
+	"go.uber.org/cadence/activity"
+)
+
+// CollectUpdateClusterProvidersActivityName is the default registration name of the activity
+const CollectUpdateClusterProvidersActivityName = "pke-azure-collect-update-cluster-providers"
+
+// CollectUpdateClusterProvidersActivity represents an activity for collecting the details of existing cluster resources
+// needed to create new virtual machine scale sets
+type CollectUpdateClusterProvidersActivity struct {
+	azureClientFactory *AzureClientFactory
+}
+
+// MakeCollectUpdateClusterProvidersActivity returns a new CollectUpdateClusterProvidersActivity
+func MakeCollectUpdateClusterProvidersActivity(azureClientFactory *AzureClientFactory) CollectUpdateClusterProvidersActivity {
+	return CollectUpdateClusterProvidersActivity{
+		azureClientFactory: azureClientFactory,
+	}
+}
+
+// CollectUpdateClusterProvidersActivityInput represents the input needed for executing a CollectUpdateClusterProvidersActivity
+type CollectUpdateClusterProvidersActivityInput struct {
+	OrganizationID      uint
+	SecretID            string
+	ClusterName         string
+	ResourceGroupName   string
+	PublicIPAddressName string
+	VirtualNetworkName  string
+	SubnetNames         []string
+}
+
+// CollectUpdateClusterProvidersActivityOutput contains the details of existing cluster resources
+type CollectUpdateClusterProvidersActivityOutput struct {
+	PublicIPAddress string
+	SubnetCIDRs     map[string]string
+	SubnetIDs       map[string]string
+}
+
+// Execute performs the activity
+func (a CollectUpdateClusterProvidersActivity) Execute(ctx context.Context, input CollectUpdateClusterProvidersActivityInput) (output CollectUpdateClusterProvidersActivityOutput, err error) {
+	logger := activity.GetLogger(ctx).Sugar().With(
+		"organization", input.OrganizationID,
+		"cluster", input.ClusterName,
+		"secret", input.SecretID,
+		"resourceGroup", input.ResourceGroupName,
+	)
+
+	logger.Info("collect existing cluster resources")
+
+	cc, err := a.azureClientFactory.New(input.OrganizationID, input.SecretID)
+	if err = emperror.Wrap(err, "failed to create cloud connection"); err != nil {
+		return
+	}
+
+	publicIP, err := cc.GetPublicIPAddressesClient().Get(ctx, input.ResourceGroupName, input.PublicIPAddressName, "")
+	if err = emperror.WrapWith(err, "failed to get public ip address", "resourceGroup", input.ResourceGroupName, "publicIPAddressName", input.PublicIPAddressName); err != nil {
+		return
+	}
+	if publicIP.PublicIPAddressPropertiesFormat != nil {
+		output.PublicIPAddress = to.String(publicIP.IPAddress)
+	}
+
+	output.SubnetCIDRs = make(map[string]string, len(input.SubnetNames))
+	output.SubnetIDs = make(map[string]string, len(input.SubnetNames))
+
+	client := cc.GetSubnetsClient()
+	for _, subnetName := range input.SubnetNames {
+		subnet, err := client.Get(ctx, input.ResourceGroupName, input.VirtualNetworkName, subnetName, "")
+		if err != nil {
+			return output, emperror.WrapWith(err, "failed to get subnet", "resourceGroup", input.ResourceGroupName, "vnetName", input.VirtualNetworkName, "subnetName", subnetName)
+		}
+
+		output.SubnetIDs[subnetName] = to.String(subnet.ID)
+		if subnet.SubnetPropertiesFormat != nil {
+			output.SubnetCIDRs[subnetName] = to.String(subnet.AddressPrefix)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,42 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workflow
+
+import (
+	"context"
+
+	"github.com/banzaicloud/pipeline/internal/providers/azure/pke"
+)
+
+const CreateNodePoolInStoreActivityName = "pke-azure-create-node-pool-in-store"
+
+type CreateNodePoolInStoreActivity struct {
+	store pke.AzurePKEClusterStore
+}
+
+func MakeCreateNodePoolInStoreActivity(store pke.AzurePKEClusterStore) CreateNodePoolInStoreActivity {
+	return CreateNodePoolInStoreActivity{
+		store: store,
+	}
+}
+
+type CreateNodePoolInStoreActivityInput struct {
+	ClusterID uint
+	NodePool  pke.NodePool
+}
+
+func (a CreateNodePoolInStoreActivity) Execute(ctx context.Context, input CreateNodePoolInStoreActivityInput) error {
+	return a.store.CreateNodePool(input.ClusterID, input.NodePool)
+}
@@ -0,0 +1,46 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workflow
+
+import (
+	"context"
+
+	"github.com/banzaicloud/pipeline/internal/providers/azure/pke"
+)
+
+const SetNodePoolSizesActivityName = "pke-azure-set-node-pool-sizes"
+
+type SetNodePoolSizesActivity struct {
+	store pke.AzurePKEClusterStore
+}
+
+func MakeSetNodePoolSizesActivity(store pke.AzurePKEClusterStore) SetNodePoolSizesActivity {
+	return SetNodePoolSizesActivity{
+		store: store,
+	}
+}
+
+type SetNodePoolSizesActivityInput struct {
+	ClusterID    uint
+	NodePoolName string
+	Autoscaling  bool
+	DesiredCount uint
+	Max          uint
+	Min          uint
+}
+
+func (a SetNodePoolSizesActivity) Execute(ctx context.Context, input SetNodePoolSizesActivityInput) error {
+	return a.store.SetNodePoolSizes(input.ClusterID, input.NodePoolName, input.Min, input.Max, input.DesiredCount, input.Autoscaling)
+}
@@ -0,0 +1,264 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workflow
+
+import (
+	"time"
+
+	"github.com/banzaicloud/pipeline/internal/providers/azure/pke"
+	"github.com/goph/emperror"
+	"go.uber.org/cadence/workflow"
+)
+
+const UpdateClusterWorkflowName = "pke-azure-update-cluster"
+
+// UpdateClusterWorkflowInput
+type UpdateClusterWorkflowInput struct {
+	OrganizationID      uint
+	SecretID            string
+	ClusterID           uint
+	ClusterName         string
+	ResourceGroupName   string
+	PublicIPAddressName string
+	VirtualNetworkName  string
+
+	NodePoolsToCreate []NodePoolToCreate
+	NodePoolsToDelete []NodePoolToDelete
+	NodePoolsToUpdate []NodePoolToUpdate
+}
+
+// NodePoolToCreate describes a node pool and the virtual machine scale set backing it
+type NodePoolToCreate struct {
+	NodePool       pke.NodePool
+	RoleAssignment RoleAssignmentTemplate
+	ScaleSet       VirtualMachineScaleSetTemplate
+}
+
+// NodePoolToDelete describes a node pool and the virtual machine scale set backing it
+type NodePoolToDelete struct {
+	Name     string
+	VMSSName string
+}
+
+// NodePoolToUpdate describes the new sizes of a node pool
+type NodePoolToUpdate struct {
+	Name         string
+	VMSSName     string
+	Autoscaling  bool
+	DesiredCount uint
+	Max          uint
+	Min          uint
+
+	// Resize is true if the capacity of the virtual machine scale set has to be changed
+	Resize bool
+}
+
+func UpdateClusterWorkflow(ctx workflow.Context, input UpdateClusterWorkflowInput) error {
+	ao := workflow.ActivityOptions{
+		ScheduleToStartTimeout: 5 * time.Minute,
+		StartToCloseTimeout:    10 * time.Minute,
+		ScheduleToCloseTimeout: 15 * time.Minute,
+		WaitForCancellation:    true,
+	}
+	ctx = workflow.WithActivityOptions(ctx, ao)
+
+	if err := deleteNodePools(ctx, input); err != nil {
+		setClusterErrorStatus(ctx, input.ClusterID, err)
+		return err
+	}
+
+	if err := updateNodePools(ctx, input); err != nil {
+		setClusterErrorStatus(ctx, input.ClusterID, err)
+		return err
+	}
+
+	if err := createNodePools(ctx, input); err != nil {
+		setClusterErrorStatus(ctx, input.ClusterID, err)
+		return err
+	}
+
+	return nil
+}
+
+func deleteNodePools(ctx workflow.Context, input UpdateClusterWorkflowInput) error {
+	futures := make(map[string]workflow.Future, len(input.NodePoolsToDelete))
+	for _, np := range input.NodePoolsToDelete {
+		activityInput := DeleteVMSSActivityInput{
+			OrganizationID:    input.OrganizationID,
+			SecretID:          input.SecretID,
+			ClusterName:       input.ClusterName,
+			ResourceGroupName: input.ResourceGroupName,
+			VMSSName:          np.VMSSName,
+		}
+		futures[np.Name] = workflow.ExecuteActivity(ctx, DeleteVMSSActivityName, activityInput)
+	}
+
+	for name, future := range futures {
+		if err := future.Get(ctx, nil); err != nil {
+			return emperror.Wrapf(err, "deleting scale set of node pool %q", name)
+		}
+
+		activityInput := DeleteNodePoolFromStoreActivityInput{
+			ClusterID:    input.ClusterID,
+			NodePoolName: name,
+		}
+		if err := workflow.ExecuteActivity(ctx, DeleteNodePoolFromStoreActivityName, activityInput).Get(ctx, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func updateNodePools(ctx workflow.Context, input UpdateClusterWorkflowInput) error {
+	futures := make(map[string]workflow.Future, len(input.NodePoolsToUpdate))
+	for _, np := range input.NodePoolsToUpdate {
+		if !np.Resize {
+			continue
+		}
+
+		activityInput := UpdateVMSSActivityInput{
+			OrganizationID:    input.OrganizationID,
+			SecretID:          input.SecretID,
+			ClusterName:       input.ClusterName,
+			ResourceGroupName: input.ResourceGroupName,
+			VMSSName:          np.VMSSName,
+			InstanceCount:     int64(np.DesiredCount),
+		}
+		futures[np.Name] = workflow.ExecuteActivity(ctx, UpdateVMSSActivityName, activityInput)
+	}
+
+	for _, np := range input.NodePoolsToUpdate {
+		if future, ok := futures[np.Name]; ok {
+			if err := future.Get(ctx, nil); err != nil {
+				return emperror.Wrapf(err, "resizing scale set of node pool %q", np.Name)
+			}
+		}
+
+		activityInput := SetNodePoolSizesActivityInput{
+			ClusterID:    input.ClusterID,
+			NodePoolName: np.Name,
+			Autoscaling:  np.Autoscaling,
+			DesiredCount: np.DesiredCount,
+			Max:          np.Max,
+			Min:          np.Min,
+		}
+		if err := workflow.ExecuteActivity(ctx, SetNodePoolSizesActivityName, activityInput).Get(ctx, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func createNodePools(ctx workflow.Context, input UpdateClusterWorkflowInput) error {
+	if len(input.NodePoolsToCreate) == 0 {
+		return nil
+	}
+
+	// Collect the details of the existing network resources
+	var providersOutput CollectUpdateClusterProvidersActivityOutput
+	{
+		subnetNames := make([]string, 0, len(input.NodePoolsToCreate))
+		for _, np := range input.NodePoolsToCreate {
+			subnetNames = append(subnetNames, np.ScaleSet.SubnetName)
+		}
+
+		activityInput := CollectUpdateClusterProvidersActivityInput{
+			OrganizationID:      input.OrganizationID,
+			SecretID:            input.SecretID,
+			ClusterName:         input.ClusterName,
+			ResourceGroupName:   input.ResourceGroupName,
+			PublicIPAddressName: input.PublicIPAddressName,
+			VirtualNetworkName:  input.VirtualNetworkName,
+			SubnetNames:         subnetNames,
+		}
+		if err := workflow.ExecuteActivity(ctx, CollectUpdateClusterProvidersActivityName, activityInput).Get(ctx, &providersOutput); err != nil {
+			return err
+		}
+	}
+
+	// Create scale sets
+	createVMSSActivityOutputs := make(map[string]CreateVMSSActivityOutput)
+	{
+		factory := VirtualMachineScaleSetsFactory{
+			Templates: make([]VirtualMachineScaleSetTemplate, len(input.NodePoolsToCreate)),
+		}
+		for i, np := range input.NodePoolsToCreate {
+			np.ScaleSet.UserDataScriptParams["InfraCIDR"] = providersOutput.SubnetCIDRs[np.ScaleSet.SubnetName]
+			factory.Templates[i] = np.ScaleSet
+		}
+
+		scaleSets := factory.Make(
+			backendAddressPoolIDProvider(CreateLoadBalancerActivityOutput{}),
+			inboundNATPoolIDProvider(CreateLoadBalancerActivityOutput{}),
+			publicIPAddressIPAddressProvider(CreatePublicIPActivityOutput{PublicIPAddress: providersOutput.PublicIPAddress}),
+			mapSecurityGroupIDProvider(nil),
+			subnetIDProvider(CreateVnetActivityOutput{SubnetIDs: providersOutput.SubnetIDs}),
+		)
+		futures := make(map[string]workflow.Future, len(scaleSets))
+		for _, vmss := range scaleSets {
+			activityInput := CreateVMSSActivityInput{
+				OrganizationID:    input.OrganizationID,
+				SecretID:          input.SecretID,
+				ClusterID:         input.ClusterID,
+				ClusterName:       input.ClusterName,
+				ResourceGroupName: input.ResourceGroupName,
+				ScaleSet:          vmss,
+			}
+			futures[vmss.Name] = workflow.ExecuteActivity(ctx, CreateVMSSActivityName, activityInput)
+		}
+
+		for name, future := range futures {
+			var activityOutput CreateVMSSActivityOutput
+			if err := future.Get(ctx, &activityOutput); err != nil {
+				return emperror.Wrapf(err, "creating scaling set %q", name)
+			}
+			createVMSSActivityOutputs[name] = activityOutput
+		}
+	}
+
+	// Create role assignments and save node pools
+	for _, np := range input.NodePoolsToCreate {
+		{
+			activityInput := AssignRoleActivityInput{
+				OrganizationID:    input.OrganizationID,
+				SecretID:          input.SecretID,
+				ClusterName:       input.ClusterName,
+				ResourceGroupName: input.ResourceGroupName,
+				RoleAssignment: RoleAssignment{
+					Name:        np.RoleAssignment.Name,
+					PrincipalID: createVMSSActivityOutputs[np.RoleAssignment.VMSSName].PrincipalID,
+					RoleName:    np.RoleAssignment.RoleName,
+				},
+			}
+			if err := workflow.ExecuteActivity(ctx, AssignRoleActivityName, activityInput).Get(ctx, nil); err != nil {
+				return err
+			}
+		}
+
+		{
+			activityInput := CreateNodePoolInStoreActivityInput{
+				ClusterID: input.ClusterID,
+				NodePool:  np.NodePool,
+			}
+			if err := workflow.ExecuteActivity(ctx, CreateNodePoolInStoreActivityName, activityInput).Get(ctx, nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
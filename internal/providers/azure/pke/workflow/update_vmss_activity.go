@@ -0,0 +1,108 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workflow
+
+import (
+	"context"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-10-01/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+	"go.uber.org/cadence/activity"
+)
+
+// UpdateVMSSActivityName is the default registration name of the activity
+const UpdateVMSSActivityName = "pke-azure-update-vmss"
+
+// UpdateVMSSActivity represents an activity for changing the capacity of an Azure virtual machine scale set
+type UpdateVMSSActivity struct {
+	azureClientFactory *AzureClientFactory
+}
+
+// MakeUpdateVMSSActivity returns a new UpdateVMSSActivity
+func MakeUpdateVMSSActivity(azureClientFactory *AzureClientFactory) UpdateVMSSActivity {
+	return UpdateVMSSActivity{
+		azureClientFactory: azureClientFactory,
+	}
+}
+
+// UpdateVMSSActivityInput represents the input needed for executing an UpdateVMSSActivity
+type UpdateVMSSActivityInput struct {
+	OrganizationID    uint
+	SecretID          string
+	ClusterName       string
+	ResourceGroupName string
+	VMSSName          string
+	InstanceCount     int64
+}
+
+// Execute performs the activity
+func (a UpdateVMSSActivity) Execute(ctx context.Context, input UpdateVMSSActivityInput) (err error) {
+	logger := activity.GetLogger(ctx).Sugar().With(
+		"organization", input.OrganizationID,
+		"cluster", input.ClusterName,
+		"secret", input.SecretID,
+		"resourceGroup", input.ResourceGroupName,
+		"vmssName", input.VMSSName,
+	)
+
+	keyvals := []interface{}{
+		"resourceGroup", input.ResourceGroupName,
+		"vmssName", input.VMSSName,
+	}
+
+	logger.Infof("update virtual machine scale set capacity to %d", input.InstanceCount)
+
+	cc, err := a.azureClientFactory.New(input.OrganizationID, input.SecretID)
+	if err = emperror.Wrap(err, "failed to create cloud connection"); err != nil {
+		return
+	}
+
+	client := cc.GetVirtualMachineScaleSetsClient()
+
+	vmss, err := client.Get(ctx, input.ResourceGroupName, input.VMSSName)
+	if err = emperror.WrapWith(err, "failed to get virtual machine scale set details", keyvals...); err != nil {
+		return
+	}
+
+	if !HasOwnedTag(input.ClusterName, to.StringMap(vmss.Tags)) {
+		return emperror.With(errors.New("virtual machine scale set is not owned by cluster"), keyvals...)
+	}
+
+	params := compute.VirtualMachineScaleSetUpdate{
+		Sku: &compute.Sku{
+			Capacity: to.Int64Ptr(input.InstanceCount),
+		},
+	}
+
+	logger.Debug("sending request to update virtual machine scale set")
+
+	future, err := client.Update(ctx, input.ResourceGroupName, input.VMSSName, params)
+	if err = emperror.WrapWith(err, "sending request to update virtual machine scale set failed", keyvals...); err != nil {
+		return
+	}
+
+	logger.Debug("waiting for the completion of update virtual machine scale set operation")
+
+	err = future.WaitForCompletionRef(ctx, client.Client)
+	if err = emperror.WrapWith(err, "waiting for the completion of update virtual machine scale set operation failed", keyvals...); err != nil {
+		return
+	}
+
+	logger.Debug("virtual machine scale set update completed")
+
+	return
+}
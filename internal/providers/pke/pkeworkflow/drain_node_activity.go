@@ -0,0 +1,125 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkeworkflow
+
+import (
+	"context"
+	"time"
+
+	"github.com/goph/emperror"
+	"go.uber.org/cadence/activity"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+const DrainNodeActivityName = "pke-drain-node-activity"
+
+// DrainNodeActivity cordons a node and evicts the pods running on it, except the ones managed by daemon sets and mirror pods
+type DrainNodeActivity struct {
+	clusters Clusters
+}
+
+func NewDrainNodeActivity(clusters Clusters) *DrainNodeActivity {
+	return &DrainNodeActivity{
+		clusters: clusters,
+	}
+}
+
+type DrainNodeActivityInput struct {
+	ClusterID uint
+	NodeName  string
+}
+
+func (a *DrainNodeActivity) Execute(ctx context.Context, input DrainNodeActivityInput) error {
+	logger := activity.GetLogger(ctx).Sugar().With("clusterID", input.ClusterID, "node", input.NodeName)
+
+	client, err := getClusterK8sClient(ctx, a.clusters, input.ClusterID)
+	if err != nil {
+		return err
+	}
+
+	node, err := client.CoreV1().Nodes().Get(input.NodeName, metav1.GetOptions{})
+	if k8sNotFound(err) {
+		logger.Info("node not found, skipping drain")
+		return nil
+	} else if err != nil {
+		return emperror.Wrap(err, "failed to get node")
+	}
+
+	if !node.Spec.Unschedulable {
+		node.Spec.Unschedulable = true
+		if _, err := client.CoreV1().Nodes().Update(node); err != nil {
+			return emperror.Wrap(err, "failed to cordon node")
+		}
+	}
+
+	listOptions := metav1.ListOptions{FieldSelector: fields.OneTermEqualSelector("spec.nodeName", input.NodeName).String()}
+
+	return pollUntil(ctx, 5*time.Second, func() (bool, error) {
+		pods, err := client.CoreV1().Pods(metav1.NamespaceAll).List(listOptions)
+		if err != nil {
+			return false, emperror.Wrap(err, "failed to list pods")
+		}
+
+		pods.Items = getEvictablePods(pods.Items)
+		for _, pod := range pods.Items {
+			if pod.DeletionTimestamp != nil {
+				continue
+			}
+
+			err := client.PolicyV1beta1().Evictions(pod.Namespace).Evict(&policyv1beta1.Eviction{
+				ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+			})
+			// evictions violating a disruption budget are retried in the next round
+			if err != nil && !k8sNotFound(err) && !k8sErrors.IsTooManyRequests(err) {
+				return false, emperror.WrapWith(err, "failed to evict pod", "namespace", pod.Namespace, "pod", pod.Name)
+			}
+		}
+
+		return len(pods.Items) == 0, nil
+	})
+}
+
+// getEvictablePods filters out the pods which are not affected by draining a node
+func getEvictablePods(pods []corev1.Pod) []corev1.Pod {
+	var evictable []corev1.Pod
+
+	for _, pod := range pods {
+		if _, ok := pod.Annotations[corev1.MirrorPodAnnotationKey]; ok {
+			continue
+		}
+
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+
+		ownedByDaemonSet := false
+		for _, owner := range pod.OwnerReferences {
+			if owner.Kind == "DaemonSet" {
+				ownedByDaemonSet = true
+			}
+		}
+		if ownedByDaemonSet {
+			continue
+		}
+
+		evictable = append(evictable, pod)
+	}
+
+	return evictable
+}
@@ -0,0 +1,59 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkeworkflow
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+)
+
+const ListPoolInstancesActivityName = "pke-list-pool-instances-activity"
+
+type ListPoolInstancesActivity struct {
+	clusters Clusters
+}
+
+func NewListPoolInstancesActivity(clusters Clusters) *ListPoolInstancesActivity {
+	return &ListPoolInstancesActivity{
+		clusters: clusters,
+	}
+}
+
+type ListPoolInstancesActivityInput struct {
+	ClusterID uint
+	Pool      NodePool
+}
+
+func (a *ListPoolInstancesActivity) Execute(ctx context.Context, input ListPoolInstancesActivityInput) ([]PoolInstance, error) {
+	cluster, err := a.clusters.GetCluster(ctx, input.ClusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	awsCluster, ok := cluster.(AWSCluster)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("can't get AWS client for %t", cluster))
+	}
+
+	client, err := awsCluster.GetAWSClient()
+	if err != nil {
+		return nil, emperror.Wrap(err, "failed to connect to AWS")
+	}
+
+	return listPoolInstances(client, cluster.GetName(), input.Pool.Name)
+}
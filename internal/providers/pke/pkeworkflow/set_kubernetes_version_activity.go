@@ -0,0 +1,53 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkeworkflow
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+const SetKubernetesVersionActivityName = "pke-set-kubernetes-version-activity"
+
+type SetKubernetesVersionActivity struct {
+	clusters Clusters
+}
+
+func NewSetKubernetesVersionActivity(clusters Clusters) *SetKubernetesVersionActivity {
+	return &SetKubernetesVersionActivity{
+		clusters: clusters,
+	}
+}
+
+type SetKubernetesVersionActivityInput struct {
+	ClusterID         uint
+	KubernetesVersion string
+}
+
+func (a *SetKubernetesVersionActivity) Execute(ctx context.Context, input SetKubernetesVersionActivityInput) error {
+	cluster, err := a.clusters.GetCluster(ctx, input.ClusterID)
+	if err != nil {
+		return err
+	}
+
+	awsCluster, ok := cluster.(AWSCluster)
+	if !ok {
+		return errors.New(fmt.Sprintf("can't set Kubernetes version for %t", cluster))
+	}
+
+	return awsCluster.SetKubernetesVersion(input.KubernetesVersion)
+}
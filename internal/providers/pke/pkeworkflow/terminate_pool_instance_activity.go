@@ -0,0 +1,56 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkeworkflow
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/goph/emperror"
+)
+
+const TerminatePoolInstanceActivityName = "pke-terminate-pool-instance-activity"
+
+// TerminatePoolInstanceActivity terminates an instance of a node pool, which is replaced by the AutoScalingGroup
+// using the current launch configuration of the pool
+type TerminatePoolInstanceActivity struct {
+	awsClientFactory *AWSClientFactory
+}
+
+func NewTerminatePoolInstanceActivity(awsClientFactory *AWSClientFactory) *TerminatePoolInstanceActivity {
+	return &TerminatePoolInstanceActivity{
+		awsClientFactory: awsClientFactory,
+	}
+}
+
+type TerminatePoolInstanceActivityInput struct {
+	AWSActivityInput
+	InstanceID string
+}
+
+func (a *TerminatePoolInstanceActivity) Execute(ctx context.Context, input TerminatePoolInstanceActivityInput) error {
+	client, err := a.awsClientFactory.New(input.OrganizationID, input.SecretID, input.Region)
+	if err != nil {
+		return err
+	}
+
+	_, err = autoscaling.New(client).TerminateInstanceInAutoScalingGroup(&autoscaling.TerminateInstanceInAutoScalingGroupInput{
+		InstanceId:                     aws.String(input.InstanceID),
+		ShouldDecrementDesiredCapacity: aws.Bool(false),
+	})
+
+	return emperror.WrapWith(err, "failed to terminate instance", "instance", input.InstanceID)
+}
@@ -0,0 +1,89 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkeworkflow
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const UncordonPoolNodesActivityName = "pke-uncordon-pool-nodes-activity"
+
+// UncordonPoolNodesActivity makes the nodes of a node pool schedulable again after an aborted upgrade
+type UncordonPoolNodesActivity struct {
+	clusters Clusters
+}
+
+func NewUncordonPoolNodesActivity(clusters Clusters) *UncordonPoolNodesActivity {
+	return &UncordonPoolNodesActivity{
+		clusters: clusters,
+	}
+}
+
+type UncordonPoolNodesActivityInput struct {
+	ClusterID uint
+	Pool      NodePool
+}
+
+func (a *UncordonPoolNodesActivity) Execute(ctx context.Context, input UncordonPoolNodesActivityInput) error {
+	cluster, err := a.clusters.GetCluster(ctx, input.ClusterID)
+	if err != nil {
+		return err
+	}
+
+	awsCluster, ok := cluster.(AWSCluster)
+	if !ok {
+		return errors.New(fmt.Sprintf("can't get AWS client for %t", cluster))
+	}
+
+	awsClient, err := awsCluster.GetAWSClient()
+	if err != nil {
+		return emperror.Wrap(err, "failed to connect to AWS")
+	}
+
+	instances, err := listPoolInstances(awsClient, cluster.GetName(), input.Pool.Name)
+	if err != nil {
+		return err
+	}
+
+	client, err := getClusterK8sClient(ctx, a.clusters, input.ClusterID)
+	if err != nil {
+		return err
+	}
+
+	for _, instance := range instances {
+		node, err := client.CoreV1().Nodes().Get(instance.NodeName, metav1.GetOptions{})
+		if k8sNotFound(err) {
+			continue
+		} else if err != nil {
+			return emperror.WrapWith(err, "failed to get node", "node", instance.NodeName)
+		}
+
+		if !node.Spec.Unschedulable {
+			continue
+		}
+
+		node.Spec.Unschedulable = false
+		if _, err := client.CoreV1().Nodes().Update(node); err != nil {
+			return emperror.WrapWith(err, "failed to uncordon node", "node", instance.NodeName)
+		}
+	}
+
+	return nil
+}
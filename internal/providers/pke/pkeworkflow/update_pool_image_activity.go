@@ -0,0 +1,149 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkeworkflow
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+)
+
+const UpdatePoolImageActivityName = "pke-update-pool-image-activity"
+
+// UpdatePoolImageActivity changes the image and the bootstrap command of a worker node pool by updating its launch configuration.
+// Running instances are not affected, only the ones launched after the update.
+type UpdatePoolImageActivity struct {
+	clusters       Clusters
+	tokenGenerator TokenGenerator
+}
+
+func NewUpdatePoolImageActivity(clusters Clusters, tokenGenerator TokenGenerator) *UpdatePoolImageActivity {
+	return &UpdatePoolImageActivity{
+		clusters:       clusters,
+		tokenGenerator: tokenGenerator,
+	}
+}
+
+type UpdatePoolImageActivityInput struct {
+	ClusterID       uint
+	Pool            NodePool
+	ExternalBaseUrl string
+
+	// ImageID and PkeCommand are generated for the current Kubernetes version of the cluster when empty
+	ImageID    string
+	PkeCommand string
+}
+
+// UpdatePoolImageActivityOutput contains the previous image and bootstrap command of the pool, which can be used for rollback
+type UpdatePoolImageActivityOutput struct {
+	ImageID    string
+	PkeCommand string
+}
+
+func (a *UpdatePoolImageActivity) Execute(ctx context.Context, input UpdatePoolImageActivityInput) (UpdatePoolImageActivityOutput, error) {
+	var output UpdatePoolImageActivityOutput
+
+	cluster, err := a.clusters.GetCluster(ctx, input.ClusterID)
+	if err != nil {
+		return output, err
+	}
+
+	awsCluster, ok := cluster.(AWSCluster)
+	if !ok {
+		return output, errors.New(fmt.Sprintf("can't get AWS client for %t", cluster))
+	}
+
+	imageID := input.ImageID
+	pkeCommand := input.PkeCommand
+
+	if imageID == "" {
+		ver, err := awsCluster.GetKubernetesVersion()
+		if err != nil {
+			return output, emperror.Wrap(err, "can't get Kubernetes version")
+		}
+
+		imageID = getDefaultImageID(cluster.GetLocation(), ver)
+		if input.Pool.ImageID != "" {
+			imageID = input.Pool.ImageID
+		}
+	}
+
+	if pkeCommand == "" {
+		_, signedToken, err := a.tokenGenerator.GenerateClusterToken(cluster.GetOrganizationId(), cluster.GetID())
+		if err != nil {
+			return output, emperror.Wrap(err, "can't generate Pipeline token")
+		}
+
+		pkeCommand, err = awsCluster.GetBootstrapCommand(input.Pool.Name, input.ExternalBaseUrl, signedToken)
+		if err != nil {
+			return output, emperror.Wrap(err, "failed to fetch bootstrap command")
+		}
+	}
+
+	client, err := awsCluster.GetAWSClient()
+	if err != nil {
+		return output, emperror.Wrap(err, "failed to connect to AWS")
+	}
+
+	cfClient := cloudformation.New(client)
+
+	stackName := getPoolStackName(cluster.GetName(), input.Pool.Name)
+
+	stacks, err := cfClient.DescribeStacks(&cloudformation.DescribeStacksInput{StackName: aws.String(stackName)})
+	if err != nil {
+		return output, emperror.WrapWith(err, "failed to describe stack", "stack", stackName)
+	}
+	if len(stacks.Stacks) == 0 {
+		return output, errors.Errorf("stack %q not found", stackName)
+	}
+
+	var params []*cloudformation.Parameter
+	for _, p := range stacks.Stacks[0].Parameters {
+		switch aws.StringValue(p.ParameterKey) {
+		case "ImageId":
+			output.ImageID = aws.StringValue(p.ParameterValue)
+			params = append(params, &cloudformation.Parameter{ParameterKey: p.ParameterKey, ParameterValue: aws.String(imageID)})
+		case "PkeCommand":
+			output.PkeCommand = aws.StringValue(p.ParameterValue)
+			params = append(params, &cloudformation.Parameter{ParameterKey: p.ParameterKey, ParameterValue: aws.String(pkeCommand)})
+		default:
+			params = append(params, &cloudformation.Parameter{ParameterKey: p.ParameterKey, UsePreviousValue: aws.Bool(true)})
+		}
+	}
+
+	_, err = cfClient.UpdateStack(&cloudformation.UpdateStackInput{
+		StackName:           aws.String(stackName),
+		UsePreviousTemplate: aws.Bool(true),
+		Parameters:          params,
+	})
+	if err, ok := err.(awserr.Error); ok && err.Code() == "ValidationError" && strings.Contains(err.Message(), "No updates are to be performed") {
+		return output, nil
+	} else if err != nil {
+		return output, emperror.WrapWith(err, "failed to update stack", "stack", stackName)
+	}
+
+	err = cfClient.WaitUntilStackUpdateCompleteWithContext(ctx, &cloudformation.DescribeStacksInput{StackName: aws.String(stackName)})
+	if err != nil {
+		return output, emperror.WrapWith(err, "error waiting for stack update", "stack", stackName)
+	}
+
+	return output, nil
+}
@@ -0,0 +1,203 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkeworkflow
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/banzaicloud/pipeline/pkg/k8sclient"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+	"go.uber.org/cadence/activity"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+func getClusterK8sClient(ctx context.Context, clusters Clusters, clusterID uint) (*kubernetes.Clientset, error) {
+	cluster, err := clusters.GetCluster(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	kubeConfig, err := cluster.GetK8sConfig()
+	if err != nil {
+		return nil, emperror.Wrap(err, "failed to get Kubernetes config")
+	}
+
+	client, err := k8sclient.NewClientFromKubeConfig(kubeConfig)
+	return client, emperror.Wrap(err, "failed to create Kubernetes client")
+}
+
+func k8sNotFound(err error) bool {
+	return k8sErrors.IsNotFound(err)
+}
+
+// isNodeUpToDate returns true when the node is ready and its kubelet runs the given Kubernetes version
+func isNodeUpToDate(node corev1.Node, kubernetesVersion string) bool {
+	return isNodeReady(node) && strings.TrimPrefix(node.Status.NodeInfo.KubeletVersion, "v") == strings.TrimPrefix(kubernetesVersion, "v")
+}
+
+func isNodeReady(node corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// pollUntil calls the condition periodically until it is satisfied or fails, recording heartbeats for the running activity.
+// Transient API errors (like the API server being restarted by an upgrade) do not fail the polling, the condition is retried instead.
+func pollUntil(ctx context.Context, interval time.Duration, condition func() (bool, error)) error {
+	for {
+		done, err := condition()
+		if isTransientAPIError(err) {
+			activity.GetLogger(ctx).Sugar().Infof("retrying after transient API error: %s", err)
+
+			done, err = false, nil
+		}
+		if err != nil || done {
+			return err
+		}
+
+		activity.RecordHeartbeat(ctx)
+
+		select {
+		case <-ctx.Done():
+			return emperror.Wrap(ctx.Err(), "polling stopped")
+		case <-time.After(interval):
+		}
+	}
+}
+
+// isTransientAPIError returns true if the Kubernetes API server could not be reached or was temporarily unable to serve a request
+func isTransientAPIError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	err = errors.Cause(err)
+
+	switch err.(type) {
+	case *url.Error, net.Error:
+		return true
+	}
+
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
+	}
+
+	return k8sErrors.IsServiceUnavailable(err) ||
+		k8sErrors.IsServerTimeout(err) ||
+		k8sErrors.IsTimeout(err) ||
+		k8sErrors.IsTooManyRequests(err) ||
+		k8sErrors.IsInternalError(err)
+}
+
+// PoolInstance is an EC2 instance of a node pool
+type PoolInstance struct {
+	InstanceID string
+	NodeName   string
+	UpToDate   bool
+}
+
+func getPoolStackName(clusterName, poolName string) string {
+	return fmt.Sprintf("pke-pool-%s-worker-%s", clusterName, poolName)
+}
+
+// listPoolInstances returns the instances of a worker node pool, marking the ones launched with the current launch configuration of the pool
+func listPoolInstances(client *session.Session, clusterName, poolName string) ([]PoolInstance, error) {
+	stackName := getPoolStackName(clusterName, poolName)
+
+	stacks, err := cloudformation.New(client).DescribeStacks(&cloudformation.DescribeStacksInput{StackName: aws.String(stackName)})
+	if err != nil {
+		return nil, emperror.WrapWith(err, "failed to describe stack", "stack", stackName)
+	}
+	if len(stacks.Stacks) == 0 {
+		return nil, errors.Errorf("stack %q not found", stackName)
+	}
+
+	var asgName string
+	for _, o := range stacks.Stacks[0].Outputs {
+		if aws.StringValue(o.OutputKey) == "AutoScalingGroupId" {
+			asgName = aws.StringValue(o.OutputValue)
+		}
+	}
+	if asgName == "" {
+		return nil, errors.Errorf("can't find AutoScalingGroup for pool %q", poolName)
+	}
+
+	groups, err := autoscaling.New(client).DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: aws.StringSlice([]string{asgName}),
+	})
+	if err != nil {
+		return nil, emperror.WrapWith(err, "failed to describe AutoScalingGroup", "autoScalingGroup", asgName)
+	}
+	if len(groups.AutoScalingGroups) == 0 {
+		return nil, errors.Errorf("AutoScalingGroup %q not found", asgName)
+	}
+
+	group := groups.AutoScalingGroups[0]
+	launchConfiguration := aws.StringValue(group.LaunchConfigurationName)
+
+	var instances []PoolInstance
+	var instanceIDs []*string
+	for _, instance := range group.Instances {
+		if aws.StringValue(instance.LifecycleState) != autoscaling.LifecycleStateInService {
+			continue
+		}
+
+		instances = append(instances, PoolInstance{
+			InstanceID: aws.StringValue(instance.InstanceId),
+			UpToDate:   aws.StringValue(instance.LaunchConfigurationName) == launchConfiguration,
+		})
+		instanceIDs = append(instanceIDs, instance.InstanceId)
+	}
+
+	if len(instanceIDs) == 0 {
+		return instances, nil
+	}
+
+	// Kubernetes nodes are named after the private DNS names of the instances by the AWS cloud provider
+	reservations, err := ec2.New(client).DescribeInstances(&ec2.DescribeInstancesInput{InstanceIds: instanceIDs})
+	if err != nil {
+		return nil, emperror.Wrap(err, "failed to describe instances")
+	}
+
+	nodeNames := map[string]string{}
+	for _, reservation := range reservations.Reservations {
+		for _, instance := range reservation.Instances {
+			nodeNames[aws.StringValue(instance.InstanceId)] = aws.StringValue(instance.PrivateDnsName)
+		}
+	}
+
+	for i := range instances {
+		instances[i].NodeName = nodeNames[instances[i].InstanceID]
+	}
+
+	return instances, nil
+}
@@ -0,0 +1,262 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkeworkflow
+
+import (
+	"fmt"
+	"time"
+
+	pkgCluster "github.com/banzaicloud/pipeline/pkg/cluster"
+	"github.com/goph/emperror"
+	"go.uber.org/cadence/workflow"
+)
+
+const UpgradeClusterWorkflowName = "pke-upgrade-cluster"
+
+// UpgradeClusterControlSignalName is the name of the signal which can be used to pause and resume an upgrade
+const UpgradeClusterControlSignalName = "upgrade-control"
+
+// Commands accepted by the upgrade control signal
+const (
+	PauseUpgradeCommand  = "pause"
+	ResumeUpgradeCommand = "resume"
+)
+
+type UpgradeClusterWorkflowInput struct {
+	OrganizationID      uint
+	ClusterID           uint
+	ClusterName         string
+	SecretID            string
+	Region              string
+	PipelineExternalURL string
+	KubernetesVersion   string
+	NodePools           []NodePool
+}
+
+// UpgradeClusterWorkflow upgrades the Kubernetes version of a cluster.
+// Master nodes are upgraded first, then the instances of the worker node pools are replaced one by one.
+// When a node pool fails to become ready, its launch configuration is rolled back and the upgrade is aborted.
+func UpgradeClusterWorkflow(ctx workflow.Context, input UpgradeClusterWorkflowInput) error {
+	ao := workflow.ActivityOptions{
+		ScheduleToStartTimeout: 5 * time.Minute,
+		StartToCloseTimeout:    10 * time.Minute,
+		ScheduleToCloseTimeout: 15 * time.Minute,
+		WaitForCancellation:    true,
+	}
+
+	ctx = workflow.WithActivityOptions(ctx, ao)
+
+	longRunningCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		ScheduleToStartTimeout: 5 * time.Minute,
+		StartToCloseTimeout:    30 * time.Minute,
+		ScheduleToCloseTimeout: 35 * time.Minute,
+		HeartbeatTimeout:       time.Minute,
+		WaitForCancellation:    true,
+	})
+
+	awsActivityInput := AWSActivityInput{
+		OrganizationID: input.OrganizationID,
+		SecretID:       input.SecretID,
+		Region:         input.Region,
+	}
+
+	setStatus := func(status, message string) error {
+		return workflow.ExecuteActivity(ctx, UpdateClusterStatusActivityName, UpdateClusterStatusActivityInput{
+			ClusterID:     input.ClusterID,
+			Status:        status,
+			StatusMessage: message,
+		}).Get(ctx, nil)
+	}
+
+	fail := func(err error) error {
+		if serr := setStatus(pkgCluster.Error, err.Error()); serr != nil {
+			workflow.GetLogger(ctx).Sugar().Errorf("failed to set cluster status: %s", serr.Error())
+		}
+		return err
+	}
+
+	controlChan := workflow.GetSignalChannel(ctx, UpgradeClusterControlSignalName)
+	paused := false
+
+	// waitIfPaused blocks the upgrade between two steps while it is paused
+	waitIfPaused := func(progress string) error {
+		var command string
+		for controlChan.ReceiveAsync(&command) {
+			paused = command == PauseUpgradeCommand
+		}
+
+		if !paused {
+			return setStatus(pkgCluster.Updating, progress)
+		}
+
+		if err := setStatus(pkgCluster.Updating, "upgrade paused before: "+progress); err != nil {
+			return err
+		}
+
+		for paused {
+			controlChan.Receive(ctx, &command)
+			paused = command == PauseUpgradeCommand
+		}
+
+		return setStatus(pkgCluster.Updating, progress)
+	}
+
+	// Upgrade masters
+	{
+		if err := setStatus(pkgCluster.Updating, fmt.Sprintf("upgrading master nodes to Kubernetes %s", input.KubernetesVersion)); err != nil {
+			return err
+		}
+
+		activityInput := UpgradeMasterActivityInput{
+			ClusterID:         input.ClusterID,
+			KubernetesVersion: input.KubernetesVersion,
+		}
+		if err := workflow.ExecuteActivity(longRunningCtx, UpgradeMasterActivityName, activityInput).Get(ctx, nil); err != nil {
+			return fail(emperror.Wrap(err, "failed to upgrade master nodes"))
+		}
+
+		// new nodes are bootstrapped with the saved version from now on
+		versionInput := SetKubernetesVersionActivityInput{
+			ClusterID:         input.ClusterID,
+			KubernetesVersion: input.KubernetesVersion,
+		}
+		if err := workflow.ExecuteActivity(ctx, SetKubernetesVersionActivityName, versionInput).Get(ctx, nil); err != nil {
+			return fail(err)
+		}
+	}
+
+	// Upgrade worker node pools
+	var workerPools []NodePool
+	for _, np := range input.NodePools {
+		if !np.Master && np.Name != "master" {
+			workerPools = append(workerPools, np)
+		}
+	}
+
+	for i, np := range workerPools {
+		poolProgress := fmt.Sprintf("upgrading node pool %s (%d/%d)", np.Name, i+1, len(workerPools))
+
+		if err := waitIfPaused(poolProgress); err != nil {
+			return err
+		}
+
+		var previous UpdatePoolImageActivityOutput
+		imageInput := UpdatePoolImageActivityInput{
+			ClusterID:       input.ClusterID,
+			Pool:            np,
+			ExternalBaseUrl: input.PipelineExternalURL,
+		}
+		if err := workflow.ExecuteActivity(longRunningCtx, UpdatePoolImageActivityName, imageInput).Get(ctx, &previous); err != nil {
+			return fail(emperror.Wrapf(err, "failed to update launch configuration of node pool %q", np.Name))
+		}
+
+		var instances []PoolInstance
+		listInput := ListPoolInstancesActivityInput{
+			ClusterID: input.ClusterID,
+			Pool:      np,
+		}
+		if err := workflow.ExecuteActivity(ctx, ListPoolInstancesActivityName, listInput).Get(ctx, &instances); err != nil {
+			return fail(err)
+		}
+
+		upToDate := 0
+		for _, instance := range instances {
+			if instance.UpToDate {
+				upToDate++
+			}
+		}
+
+		for _, instance := range instances {
+			if instance.UpToDate {
+				continue
+			}
+
+			if err := waitIfPaused(fmt.Sprintf("%s: replacing node %s", poolProgress, instance.NodeName)); err != nil {
+				return err
+			}
+
+			err := replacePoolInstance(ctx, longRunningCtx, input, awsActivityInput, np, instance, upToDate+1)
+			if err != nil {
+				rollbackPool(ctx, longRunningCtx, input.ClusterID, np, previous)
+
+				return fail(emperror.Wrapf(err, "upgrade aborted: node pool %q failed to become ready", np.Name))
+			}
+
+			upToDate++
+		}
+	}
+
+	return setStatus(pkgCluster.Running, pkgCluster.RunningMessage)
+}
+
+func replacePoolInstance(
+	ctx workflow.Context,
+	longRunningCtx workflow.Context,
+	input UpgradeClusterWorkflowInput,
+	awsActivityInput AWSActivityInput,
+	np NodePool,
+	instance PoolInstance,
+	expectedCount int,
+) error {
+	if instance.NodeName != "" {
+		drainInput := DrainNodeActivityInput{
+			ClusterID: input.ClusterID,
+			NodeName:  instance.NodeName,
+		}
+		if err := workflow.ExecuteActivity(longRunningCtx, DrainNodeActivityName, drainInput).Get(ctx, nil); err != nil {
+			return err
+		}
+	}
+
+	terminateInput := TerminatePoolInstanceActivityInput{
+		AWSActivityInput: awsActivityInput,
+		InstanceID:       instance.InstanceID,
+	}
+	if err := workflow.ExecuteActivity(ctx, TerminatePoolInstanceActivityName, terminateInput).Get(ctx, nil); err != nil {
+		return err
+	}
+
+	waitInput := WaitForPoolNodesActivityInput{
+		ClusterID:         input.ClusterID,
+		Pool:              np,
+		KubernetesVersion: input.KubernetesVersion,
+		Count:             expectedCount,
+	}
+
+	return workflow.ExecuteActivity(longRunningCtx, WaitForPoolNodesActivityName, waitInput).Get(ctx, nil)
+}
+
+// rollbackPool restores the previous launch configuration of a node pool and makes its remaining nodes schedulable again
+func rollbackPool(ctx workflow.Context, longRunningCtx workflow.Context, clusterID uint, np NodePool, previous UpdatePoolImageActivityOutput) {
+	logger := workflow.GetLogger(ctx).Sugar().With("clusterID", clusterID, "nodePool", np.Name)
+
+	imageInput := UpdatePoolImageActivityInput{
+		ClusterID:  clusterID,
+		Pool:       np,
+		ImageID:    previous.ImageID,
+		PkeCommand: previous.PkeCommand,
+	}
+	if err := workflow.ExecuteActivity(longRunningCtx, UpdatePoolImageActivityName, imageInput).Get(ctx, nil); err != nil {
+		logger.Errorf("failed to roll back launch configuration: %s", err.Error())
+	}
+
+	uncordonInput := UncordonPoolNodesActivityInput{
+		ClusterID: clusterID,
+		Pool:      np,
+	}
+	if err := workflow.ExecuteActivity(ctx, UncordonPoolNodesActivityName, uncordonInput).Get(ctx, nil); err != nil {
+		logger.Errorf("failed to uncordon nodes: %s", err.Error())
+	}
+}
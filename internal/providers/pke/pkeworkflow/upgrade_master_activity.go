@@ -0,0 +1,164 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkeworkflow
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+	"go.uber.org/cadence/activity"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const UpgradeMasterActivityName = "pke-upgrade-master-activity"
+
+// upgradePollInterval is the interval of checking the upgrade job and the upgraded node
+// nolint: gochecknoglobals
+var upgradePollInterval = 10 * time.Second
+
+// upgradeJobImage is used to enter the host namespaces of a master node and run the PKE installer there
+const upgradeJobImage = "alpine:3.9"
+
+// UpgradeMasterActivity upgrades the Kubernetes control plane on the master nodes one by one
+// by running "pke upgrade master" on the nodes in a privileged job.
+type UpgradeMasterActivity struct {
+	clusters Clusters
+}
+
+func NewUpgradeMasterActivity(clusters Clusters) *UpgradeMasterActivity {
+	return &UpgradeMasterActivity{
+		clusters: clusters,
+	}
+}
+
+type UpgradeMasterActivityInput struct {
+	ClusterID         uint
+	KubernetesVersion string
+}
+
+func (a *UpgradeMasterActivity) Execute(ctx context.Context, input UpgradeMasterActivityInput) error {
+	logger := activity.GetLogger(ctx).Sugar().With("clusterID", input.ClusterID)
+
+	client, err := getClusterK8sClient(ctx, a.clusters, input.ClusterID)
+	if err != nil {
+		return err
+	}
+
+	nodes, err := client.CoreV1().Nodes().List(metav1.ListOptions{LabelSelector: masterKey})
+	if err != nil {
+		return emperror.Wrap(err, "failed to list master nodes")
+	}
+	if len(nodes.Items) == 0 {
+		return errors.New("no master nodes found")
+	}
+
+	for _, node := range nodes.Items {
+		if isNodeUpToDate(node, input.KubernetesVersion) {
+			logger.With("node", node.Name).Info("master node is already upgraded")
+			continue
+		}
+
+		if err := upgradeMasterNode(ctx, client, node.Name, input.KubernetesVersion); err != nil {
+			return emperror.WrapWith(err, "failed to upgrade master node", "node", node.Name)
+		}
+
+		logger.With("node", node.Name).Info("master node upgraded")
+	}
+
+	return nil
+}
+
+func upgradeMasterNode(ctx context.Context, client kubernetes.Interface, nodeName, kubernetesVersion string) error {
+	privileged := true
+	backoffLimit := int32(0)
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("pke-upgrade-%s", nodeName),
+			Namespace: metav1.NamespaceSystem,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					NodeName:      nodeName,
+					HostPID:       true,
+					RestartPolicy: corev1.RestartPolicyNever,
+					Tolerations:   []corev1.Toleration{{Operator: corev1.TolerationOpExists}},
+					Containers: []corev1.Container{
+						{
+							Name:  "pke-upgrade",
+							Image: upgradeJobImage,
+							Command: []string{
+								"nsenter", "-t", "1", "-m", "-u", "-i", "-n", "-p", "--",
+								"/usr/local/bin/pke", "upgrade", "master", "--kubernetes-version=" + kubernetesVersion,
+							},
+							SecurityContext: &corev1.SecurityContext{Privileged: &privileged},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	jobs := client.BatchV1().Jobs(job.Namespace)
+
+	// remove the job of a previous (failed) attempt
+	propagation := metav1.DeletePropagationForeground
+	err := jobs.Delete(job.Name, &metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil && !k8sNotFound(err) {
+		return emperror.Wrap(err, "failed to delete previous upgrade job")
+	}
+
+	err = pollUntil(ctx, 5*time.Second, func() (bool, error) {
+		_, err := jobs.Get(job.Name, metav1.GetOptions{})
+		return k8sNotFound(err), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := jobs.Create(job); err != nil {
+		return emperror.Wrap(err, "failed to create upgrade job")
+	}
+
+	err = pollUntil(ctx, upgradePollInterval, func() (bool, error) {
+		job, err := jobs.Get(job.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, emperror.Wrap(err, "failed to get upgrade job")
+		}
+		if job.Status.Failed > 0 {
+			return false, errors.New("upgrade job failed")
+		}
+		return job.Status.Succeeded > 0, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return pollUntil(ctx, upgradePollInterval, func() (bool, error) {
+		node, err := client.CoreV1().Nodes().Get(nodeName, metav1.GetOptions{})
+		if err != nil {
+			return false, emperror.Wrap(err, "failed to get node")
+		}
+		return isNodeUpToDate(*node, kubernetesVersion), nil
+	})
+}
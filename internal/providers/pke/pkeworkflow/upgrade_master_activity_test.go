@@ -0,0 +1,136 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkeworkflow
+
+import (
+	"context"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/cadence/testsuite"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8sTesting "k8s.io/client-go/testing"
+)
+
+func TestUpgradeMasterNode_APIServerRestart(t *testing.T) {
+	defaultPollInterval := upgradePollInterval
+	upgradePollInterval = time.Millisecond
+	defer func() { upgradePollInterval = defaultPollInterval }()
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "master-0"},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+			NodeInfo:   corev1.NodeSystemInfo{KubeletVersion: "v1.14.0"},
+		},
+	}
+	client := fake.NewSimpleClientset(node)
+
+	connectionRefused := &url.Error{
+		Op:  "Get",
+		URL: "https://master-0:6443",
+		Err: syscall.ECONNREFUSED,
+	}
+
+	// the API server is unreachable while the job restarts it, then it responds with errors until it is fully up
+	var job *batchv1.Job
+	client.PrependReactor("create", "jobs", func(action k8sTesting.Action) (bool, runtime.Object, error) {
+		job = action.(k8sTesting.CreateAction).GetObject().(*batchv1.Job)
+		return false, nil, nil
+	})
+
+	var jobGets int
+	client.PrependReactor("get", "jobs", func(action k8sTesting.Action) (bool, runtime.Object, error) {
+		if job == nil {
+			return false, nil, nil
+		}
+
+		jobGets++
+		switch jobGets {
+		case 1, 2:
+			return true, nil, connectionRefused
+		case 3:
+			return true, nil, k8sErrors.NewServiceUnavailable("the server is currently unable to handle the request")
+		}
+
+		job.Status.Succeeded = 1
+		return true, job, nil
+	})
+
+	var nodeGets int
+	client.PrependReactor("get", "nodes", func(action k8sTesting.Action) (bool, runtime.Object, error) {
+		nodeGets++
+		if nodeGets == 1 {
+			return true, nil, k8sErrors.NewInternalError(connectionRefused)
+		}
+		return false, nil, nil
+	})
+
+	var testSuite testsuite.WorkflowTestSuite
+	env := testSuite.NewTestActivityEnvironment()
+
+	_, err := env.ExecuteLocalActivity(func(ctx context.Context) error {
+		return upgradeMasterNode(ctx, client, node.Name, "1.14.0")
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, jobGets)
+	assert.Equal(t, 2, nodeGets)
+}
+
+func TestUpgradeMasterNode_JobFailed(t *testing.T) {
+	client := fake.NewSimpleClientset()
+
+	var job *batchv1.Job
+	client.PrependReactor("create", "jobs", func(action k8sTesting.Action) (bool, runtime.Object, error) {
+		job = action.(k8sTesting.CreateAction).GetObject().(*batchv1.Job)
+		return false, nil, nil
+	})
+	client.PrependReactor("get", "jobs", func(action k8sTesting.Action) (bool, runtime.Object, error) {
+		if job == nil {
+			return false, nil, nil
+		}
+
+		job.Status.Failed = 1
+		return true, job, nil
+	})
+
+	var testSuite testsuite.WorkflowTestSuite
+	env := testSuite.NewTestActivityEnvironment()
+
+	_, err := env.ExecuteLocalActivity(func(ctx context.Context) error {
+		return upgradeMasterNode(ctx, client, "master-0", "1.14.0")
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "upgrade job failed")
+}
+
+func TestIsTransientAPIError(t *testing.T) {
+	assert.False(t, isTransientAPIError(nil))
+	assert.True(t, isTransientAPIError(&url.Error{Op: "Get", URL: "https://master-0:6443", Err: syscall.ECONNREFUSED}))
+	assert.True(t, isTransientAPIError(k8sErrors.NewServiceUnavailable("restarting")))
+	assert.True(t, isTransientAPIError(k8sErrors.NewTimeoutError("timeout", 1)))
+	assert.False(t, isTransientAPIError(k8sErrors.NewForbidden(batchv1.Resource("jobs"), "pke-upgrade-master-0", nil)))
+	assert.False(t, isTransientAPIError(k8sErrors.NewNotFound(batchv1.Resource("jobs"), "pke-upgrade-master-0")))
+}
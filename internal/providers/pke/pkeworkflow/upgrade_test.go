@@ -0,0 +1,57 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkeworkflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIsNodeUpToDate(t *testing.T) {
+	node := func(ready corev1.ConditionStatus, version string) corev1.Node {
+		return corev1.Node{
+			Status: corev1.NodeStatus{
+				Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: ready}},
+				NodeInfo:   corev1.NodeSystemInfo{KubeletVersion: version},
+			},
+		}
+	}
+
+	assert.True(t, isNodeUpToDate(node(corev1.ConditionTrue, "v1.14.0"), "1.14.0"))
+	assert.True(t, isNodeUpToDate(node(corev1.ConditionTrue, "v1.14.0"), "v1.14.0"))
+	assert.False(t, isNodeUpToDate(node(corev1.ConditionFalse, "v1.14.0"), "1.14.0"))
+	assert.False(t, isNodeUpToDate(node(corev1.ConditionTrue, "v1.13.3"), "1.14.0"))
+	assert.False(t, isNodeUpToDate(corev1.Node{}, "1.14.0"))
+}
+
+func TestGetEvictablePods(t *testing.T) {
+	pods := []corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "app"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "mirror", Annotations: map[string]string{corev1.MirrorPodAnnotationKey: "x"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "daemon", OwnerReferences: []metav1.OwnerReference{{Kind: "DaemonSet"}}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "replica", OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet"}}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "completed"}, Status: corev1.PodStatus{Phase: corev1.PodSucceeded}},
+	}
+
+	var names []string
+	for _, pod := range getEvictablePods(pods) {
+		names = append(names, pod.Name)
+	}
+
+	assert.Equal(t, []string{"app", "replica"}, names)
+}
@@ -0,0 +1,94 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkeworkflow
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const WaitForPoolNodesActivityName = "pke-wait-for-pool-nodes-activity"
+
+// WaitForPoolNodesActivity waits until the given number of up-to-date instances of a node pool join the cluster as ready nodes
+type WaitForPoolNodesActivity struct {
+	clusters Clusters
+}
+
+func NewWaitForPoolNodesActivity(clusters Clusters) *WaitForPoolNodesActivity {
+	return &WaitForPoolNodesActivity{
+		clusters: clusters,
+	}
+}
+
+type WaitForPoolNodesActivityInput struct {
+	ClusterID         uint
+	Pool              NodePool
+	KubernetesVersion string
+	Count             int
+}
+
+func (a *WaitForPoolNodesActivity) Execute(ctx context.Context, input WaitForPoolNodesActivityInput) error {
+	cluster, err := a.clusters.GetCluster(ctx, input.ClusterID)
+	if err != nil {
+		return err
+	}
+
+	awsCluster, ok := cluster.(AWSCluster)
+	if !ok {
+		return errors.New(fmt.Sprintf("can't get AWS client for %t", cluster))
+	}
+
+	awsClient, err := awsCluster.GetAWSClient()
+	if err != nil {
+		return emperror.Wrap(err, "failed to connect to AWS")
+	}
+
+	client, err := getClusterK8sClient(ctx, a.clusters, input.ClusterID)
+	if err != nil {
+		return err
+	}
+
+	return pollUntil(ctx, 15*time.Second, func() (bool, error) {
+		instances, err := listPoolInstances(awsClient, cluster.GetName(), input.Pool.Name)
+		if err != nil {
+			return false, err
+		}
+
+		ready := 0
+		for _, instance := range instances {
+			if !instance.UpToDate || instance.NodeName == "" {
+				continue
+			}
+
+			node, err := client.CoreV1().Nodes().Get(instance.NodeName, metav1.GetOptions{})
+			if k8sNotFound(err) {
+				continue
+			} else if err != nil {
+				return false, emperror.WrapWith(err, "failed to get node", "node", instance.NodeName)
+			}
+
+			if isNodeUpToDate(*node, input.KubernetesVersion) {
+				ready++
+			}
+		}
+
+		return ready >= input.Count, nil
+	})
+}
@@ -0,0 +1,134 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package installation
+
+import (
+	"context"
+	"strings"
+
+	"github.com/banzaicloud/pipeline/secret"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrNotInstalled is returned by a ClusterClient when the cluster or the installed Kubernetes secret no longer exists.
+// nolint: gochecknoglobals
+var ErrNotInstalled = errors.New("secret is no longer installed")
+
+// ClusterClient updates and removes installed secrets in Kubernetes clusters.
+type ClusterClient interface {
+	UpdateSecret(ctx context.Context, organizationID uint, installation Installation, secretItem *secret.SecretItemResponse) error
+	DeleteSecret(ctx context.Context, organizationID uint, installation Installation) error
+}
+
+// Manager keeps the installed copies of secrets in sync with the secret store.
+type Manager struct {
+	store    *Store
+	clusters ClusterClient
+	logger   logrus.FieldLogger
+}
+
+// NewManager returns a new Manager instance.
+func NewManager(store *Store, clusters ClusterClient, logger logrus.FieldLogger) *Manager {
+	return &Manager{
+		store:    store,
+		clusters: clusters,
+		logger:   logger,
+	}
+}
+
+// ListInstallations returns the installations of a secret.
+func (m *Manager) ListInstallations(organizationID uint, secretID string) ([]Installation, error) {
+	return m.store.List(organizationID, secretID)
+}
+
+// ReinstallSecret updates every installed copy of a secret with its current values.
+func (m *Manager) ReinstallSecret(ctx context.Context, organizationID uint, secretItem *secret.SecretItemResponse) error {
+	return m.forEachInstallation(organizationID, secretItem.ID, func(installation Installation) error {
+		return m.clusters.UpdateSecret(ctx, organizationID, installation, secretItem)
+	})
+}
+
+// UninstallSecret removes every installed copy of a secret from the clusters.
+func (m *Manager) UninstallSecret(ctx context.Context, organizationID uint, secretID string) error {
+	return m.forEachInstallation(organizationID, secretID, func(installation Installation) error {
+		err := m.clusters.DeleteSecret(ctx, organizationID, installation)
+		if err != nil {
+			return err
+		}
+
+		return m.store.Delete(organizationID, installation.ID)
+	})
+}
+
+// ForgetSecret deletes the installation records of a deleted secret, the installed copies are kept in the clusters.
+func (m *Manager) ForgetSecret(organizationID uint, secretID string) error {
+	return m.store.DeleteBySecret(organizationID, secretID)
+}
+
+// forEachInstallation calls fn for every installation of a secret.
+// Installations that no longer exist are deleted, other errors are collected and returned together.
+func (m *Manager) forEachInstallation(organizationID uint, secretID string, fn func(installation Installation) error) error {
+	installations, err := m.store.List(organizationID, secretID)
+	if err != nil {
+		return err
+	}
+
+	errs := emperror.NewMultiErrorBuilder()
+	errs.SingleWrapMode = emperror.ReturnSingle
+
+	var messages []string
+
+	for _, installation := range installations {
+		logger := m.logger.WithFields(logrus.Fields{
+			"organization": organizationID,
+			"secret":       secretID,
+			"cluster":      installation.ClusterID,
+			"namespace":    installation.Namespace,
+			"name":         installation.Name,
+		})
+
+		err := fn(installation)
+		if errors.Cause(err) == ErrNotInstalled {
+			logger.Info("secret is no longer installed, deleting installation record")
+
+			if err := m.store.Delete(organizationID, installation.ID); err != nil {
+				errs.Add(err)
+				messages = append(messages, err.Error())
+			}
+
+			continue
+		} else if err != nil {
+			err = emperror.With(
+				errors.Wrapf(err, "failed to sync secret installation in cluster %d", installation.ClusterID),
+				"cluster", installation.ClusterID,
+				"namespace", installation.Namespace,
+				"name", installation.Name,
+			)
+
+			errs.Add(err)
+			messages = append(messages, err.Error())
+
+			continue
+		}
+
+		logger.Debug("secret installation synced")
+	}
+
+	errs.Message = strings.Join(messages, "; ")
+
+	return errs.ErrOrNil()
+}
@@ -0,0 +1,177 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package installation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/banzaicloud/pipeline/secret"
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/pkg/errors"
+	logrustest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type clusterClientStub struct {
+	errors  map[uint]error
+	updated []uint
+	deleted []uint
+}
+
+func (c *clusterClientStub) UpdateSecret(ctx context.Context, organizationID uint, installation Installation, secretItem *secret.SecretItemResponse) error {
+	if err := c.errors[installation.ClusterID]; err != nil {
+		return err
+	}
+
+	c.updated = append(c.updated, installation.ClusterID)
+
+	return nil
+}
+
+func (c *clusterClientStub) DeleteSecret(ctx context.Context, organizationID uint, installation Installation) error {
+	if err := c.errors[installation.ClusterID]; err != nil {
+		return err
+	}
+
+	c.deleted = append(c.deleted, installation.ClusterID)
+
+	return nil
+}
+
+func newTestManager(t *testing.T) (*gorm.DB, *Store, *clusterClientStub, *Manager) {
+	db, err := gorm.Open("sqlite3", "file::memory:")
+	require.NoError(t, err)
+
+	require.NoError(t, db.AutoMigrate(&InstallationModel{}).Error)
+
+	store := NewStore(db)
+	clusters := &clusterClientStub{errors: map[uint]error{}}
+	logger, _ := logrustest.NewNullLogger()
+
+	return db, store, clusters, NewManager(store, clusters, logger)
+}
+
+func TestStore(t *testing.T) {
+	db, store, _, _ := newTestManager(t)
+	defer db.Close()
+
+	spec := map[string]SpecItem{
+		"password": {Source: "password"},
+	}
+
+	require.NoError(t, store.Save(1, Installation{SecretID: "secret", ClusterID: 1, Namespace: "default", Name: "db"}))
+	require.NoError(t, store.Save(1, Installation{SecretID: "secret", ClusterID: 1, Namespace: "default", Name: "db", Spec: spec}))
+	require.NoError(t, store.Save(1, Installation{SecretID: "secret", ClusterID: 2, Namespace: "default", Name: "db"}))
+	require.NoError(t, store.Save(2, Installation{SecretID: "secret", ClusterID: 3, Namespace: "default", Name: "db"}))
+
+	installations, err := store.List(1, "secret")
+	require.NoError(t, err)
+	require.Len(t, installations, 2)
+
+	assert.Equal(t, uint(1), installations[0].ClusterID)
+	assert.Equal(t, spec, installations[0].Spec)
+	assert.Equal(t, uint(2), installations[1].ClusterID)
+	assert.Nil(t, installations[1].Spec)
+
+	require.NoError(t, store.DeleteByCluster(1))
+
+	installations, err = store.List(1, "secret")
+	require.NoError(t, err)
+	require.Len(t, installations, 1)
+
+	require.NoError(t, store.Delete(1, installations[0].ID))
+
+	installations, err = store.List(1, "secret")
+	require.NoError(t, err)
+	assert.Empty(t, installations)
+
+	installations, err = store.List(2, "secret")
+	require.NoError(t, err)
+	assert.Len(t, installations, 1)
+}
+
+func TestManager_ReinstallSecret(t *testing.T) {
+	db, store, clusters, manager := newTestManager(t)
+	defer db.Close()
+
+	for clusterID := uint(1); clusterID <= 3; clusterID++ {
+		require.NoError(t, store.Save(1, Installation{SecretID: "secret", ClusterID: clusterID, Namespace: "default", Name: "db"}))
+	}
+
+	clusters.errors[2] = ErrNotInstalled
+	clusters.errors[3] = errors.New("cluster is not running")
+
+	err := manager.ReinstallSecret(context.Background(), 1, &secret.SecretItemResponse{ID: "secret", Name: "db"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cluster is not running")
+
+	assert.Equal(t, []uint{1}, clusters.updated)
+
+	// The installation that no longer exists is forgotten, the failed one is kept
+	installations, err := manager.ListInstallations(1, "secret")
+	require.NoError(t, err)
+	require.Len(t, installations, 2)
+	assert.Equal(t, uint(1), installations[0].ClusterID)
+	assert.Equal(t, uint(3), installations[1].ClusterID)
+}
+
+func TestManager_UninstallSecret(t *testing.T) {
+	db, store, clusters, manager := newTestManager(t)
+	defer db.Close()
+
+	for clusterID := uint(1); clusterID <= 2; clusterID++ {
+		require.NoError(t, store.Save(1, Installation{SecretID: "secret", ClusterID: clusterID, Namespace: "default", Name: "db"}))
+	}
+
+	clusters.errors[2] = errors.New("cluster is not running")
+
+	require.Error(t, manager.UninstallSecret(context.Background(), 1, "secret"))
+	assert.Equal(t, []uint{1}, clusters.deleted)
+
+	installations, err := manager.ListInstallations(1, "secret")
+	require.NoError(t, err)
+	require.Len(t, installations, 1)
+	assert.Equal(t, uint(2), installations[0].ClusterID)
+
+	delete(clusters.errors, 2)
+
+	require.NoError(t, manager.UninstallSecret(context.Background(), 1, "secret"))
+
+	installations, err = manager.ListInstallations(1, "secret")
+	require.NoError(t, err)
+	assert.Empty(t, installations)
+}
+
+func TestManager_ForgetSecret(t *testing.T) {
+	db, store, clusters, manager := newTestManager(t)
+	defer db.Close()
+
+	require.NoError(t, store.Save(1, Installation{SecretID: "secret", ClusterID: 1, Namespace: "default", Name: "db"}))
+	require.NoError(t, store.Save(1, Installation{SecretID: "other", ClusterID: 1, Namespace: "default", Name: "other"}))
+
+	require.NoError(t, manager.ForgetSecret(1, "secret"))
+	assert.Empty(t, clusters.deleted)
+
+	installations, err := manager.ListInstallations(1, "secret")
+	require.NoError(t, err)
+	assert.Empty(t, installations)
+
+	installations, err = manager.ListInstallations(1, "other")
+	require.NoError(t, err)
+	assert.Len(t, installations, 1)
+}
@@ -0,0 +1,43 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package installation
+
+import (
+	"time"
+)
+
+// TableName constants
+const (
+	installationTableName = "secret_installations"
+)
+
+// InstallationModel records a Pipeline secret installed into a Kubernetes cluster.
+type InstallationModel struct {
+	ID             uint   `gorm:"primary_key"`
+	OrganizationID uint   `gorm:"not null;unique_index:idx_secret_installations_unique"`
+	SecretID       string `gorm:"size:64;not null;unique_index:idx_secret_installations_unique"`
+	ClusterID      uint   `gorm:"not null;unique_index:idx_secret_installations_unique;index:idx_secret_installations_cluster_id"`
+	Namespace      string `gorm:"size:63;not null;unique_index:idx_secret_installations_unique"`
+	Name           string `gorm:"size:253;not null;unique_index:idx_secret_installations_unique"`
+	Spec           string `sql:"type:text;"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TableName changes the default table name.
+func (InstallationModel) TableName() string {
+	return installationTableName
+}
@@ -0,0 +1,147 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package installation
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// Installation describes a Pipeline secret installed into a Kubernetes cluster.
+type Installation struct {
+	ID        uint                `json:"id"`
+	SecretID  string              `json:"secretId"`
+	ClusterID uint                `json:"clusterId"`
+	Namespace string              `json:"namespace"`
+	Name      string              `json:"name"`
+	Spec      map[string]SpecItem `json:"spec,omitempty"`
+	CreatedAt time.Time           `json:"createdAt"`
+	UpdatedAt time.Time           `json:"updatedAt"`
+}
+
+// SpecItem describes how a key of the installed Kubernetes secret was populated.
+type SpecItem struct {
+	Source    string            `json:"source,omitempty"`
+	SourceMap map[string]string `json:"sourceMap,omitempty"`
+	Value     string            `json:"value,omitempty"`
+}
+
+// Store persists secret installation records.
+type Store struct {
+	db *gorm.DB
+}
+
+// NewStore returns a new Store instance.
+func NewStore(db *gorm.DB) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+// Save creates or updates the record of a secret installation.
+func (s *Store) Save(organizationID uint, installation Installation) error {
+	var spec string
+	if len(installation.Spec) > 0 {
+		rawSpec, err := json.Marshal(installation.Spec)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal secret installation spec")
+		}
+
+		spec = string(rawSpec)
+	}
+
+	var model InstallationModel
+
+	err := s.db.Where(
+		"organization_id = ? AND secret_id = ? AND cluster_id = ? AND namespace = ? AND name = ?",
+		organizationID, installation.SecretID, installation.ClusterID, installation.Namespace, installation.Name,
+	).First(&model).Error
+	if gorm.IsRecordNotFoundError(err) {
+		model = InstallationModel{
+			OrganizationID: organizationID,
+			SecretID:       installation.SecretID,
+			ClusterID:      installation.ClusterID,
+			Namespace:      installation.Namespace,
+			Name:           installation.Name,
+		}
+	} else if err != nil {
+		return errors.Wrap(err, "failed to get secret installation")
+	}
+
+	model.Spec = spec
+
+	return errors.Wrap(s.db.Save(&model).Error, "failed to save secret installation")
+}
+
+// List returns the installations of a secret.
+func (s *Store) List(organizationID uint, secretID string) ([]Installation, error) {
+	var models []InstallationModel
+
+	err := s.db.
+		Where(&InstallationModel{OrganizationID: organizationID, SecretID: secretID}).
+		Order("cluster_id, namespace, name").
+		Find(&models).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list secret installations")
+	}
+
+	installations := make([]Installation, 0, len(models))
+
+	for _, model := range models {
+		installation := Installation{
+			ID:        model.ID,
+			SecretID:  model.SecretID,
+			ClusterID: model.ClusterID,
+			Namespace: model.Namespace,
+			Name:      model.Name,
+			CreatedAt: model.CreatedAt,
+			UpdatedAt: model.UpdatedAt,
+		}
+
+		if model.Spec != "" {
+			if err := json.Unmarshal([]byte(model.Spec), &installation.Spec); err != nil {
+				return nil, errors.Wrapf(err, "failed to unmarshal spec of secret installation %d", model.ID)
+			}
+		}
+
+		installations = append(installations, installation)
+	}
+
+	return installations, nil
+}
+
+// Delete deletes the record of a secret installation.
+func (s *Store) Delete(organizationID uint, id uint) error {
+	err := s.db.Where("organization_id = ? AND id = ?", organizationID, id).Delete(&InstallationModel{}).Error
+
+	return errors.Wrap(err, "failed to delete secret installation")
+}
+
+// DeleteBySecret deletes the installation records of a secret.
+func (s *Store) DeleteBySecret(organizationID uint, secretID string) error {
+	err := s.db.Where("organization_id = ? AND secret_id = ?", organizationID, secretID).Delete(&InstallationModel{}).Error
+
+	return errors.Wrap(err, "failed to delete secret installations of secret")
+}
+
+// DeleteByCluster deletes the installation records of a cluster.
+func (s *Store) DeleteByCluster(clusterID uint) error {
+	err := s.db.Where("cluster_id = ?", clusterID).Delete(&InstallationModel{}).Error
+
+	return errors.Wrap(err, "failed to delete secret installations of cluster")
+}
@@ -0,0 +1,45 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rotation
+
+import (
+	"time"
+)
+
+// TableName constants
+const (
+	policyTableName = "secret_rotation_policies"
+)
+
+// PolicyModel is the rotation policy of a secret.
+type PolicyModel struct {
+	ID             uint   `gorm:"primary_key"`
+	OrganizationID uint   `gorm:"not null;unique_index:idx_secret_rotation_policies_org_secret"`
+	SecretID       string `gorm:"size:64;not null;unique_index:idx_secret_rotation_policies_org_secret"`
+	Interval       string `gorm:"size:32;not null"`
+	RenewBefore    string `gorm:"size:32"`
+	PasswordFormat string `gorm:"size:64"`
+	LastRotatedAt  *time.Time
+	NextRotationAt time.Time `gorm:"not null;index:idx_secret_rotation_policies_next_rotation_at"`
+	LastError      string    `sql:"type:text;"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TableName changes the default table name.
+func (PolicyModel) TableName() string {
+	return policyTableName
+}
@@ -0,0 +1,432 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rotation
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	secretTypes "github.com/banzaicloud/pipeline/pkg/secret"
+	"github.com/banzaicloud/pipeline/secret"
+	"github.com/goph/emperror"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// RotatorUser is recorded as the author of rotated secret versions
+const RotatorUser = "pipeline-secret-rotator"
+
+// DefaultRenewBefore is the time before certificate expiry when TLS secrets get rotated
+const DefaultRenewBefore = 7 * 24 * time.Hour
+
+// rotationClaimTimeout is how long a due rotation is reserved for the Pipeline instance making it.
+// The rotation is retried by any instance after that, in case it has failed or been interrupted.
+const rotationClaimTimeout = 10 * time.Minute
+
+// ErrPolicyNotFound is returned when a secret has no rotation policy.
+// nolint: gochecknoglobals
+var ErrPolicyNotFound = errors.New("rotation policy not found")
+
+// ErrInvalidPolicy is returned when a rotation policy is invalid.
+// nolint: gochecknoglobals
+var ErrInvalidPolicy = errors.New("invalid rotation policy")
+
+// Policy describes when and how the values of a secret are regenerated.
+type Policy struct {
+	// Interval between two rotations, eg. 720h
+	Interval string `json:"interval" binding:"required"`
+
+	// RenewBefore rotates TLS secrets this long before their certificates expire
+	RenewBefore string `json:"renewBefore,omitempty"`
+
+	// PasswordFormat is the method,length format of regenerated passwords
+	PasswordFormat string `json:"passwordFormat,omitempty"`
+}
+
+// PolicyResponse describes the rotation policy and state of a secret.
+type PolicyResponse struct {
+	Policy
+
+	SecretID       string     `json:"secretId"`
+	LastRotatedAt  *time.Time `json:"lastRotatedAt,omitempty"`
+	NextRotationAt time.Time  `json:"nextRotationAt"`
+	LastError      string     `json:"lastError,omitempty"`
+}
+
+// SecretStore is the subset of the secret store used by the rotator.
+type SecretStore interface {
+	Get(organizationID uint, secretID string) (*secret.SecretItemResponse, error)
+	Update(organizationID uint, secretID string, request *secret.CreateSecretRequest) error
+}
+
+// SecretInstaller updates the installed copies of a secret in the clusters of an organization.
+type SecretInstaller interface {
+	ReinstallSecret(ctx context.Context, organizationID uint, secretItem *secret.SecretItemResponse) error
+}
+
+// Rotator regenerates secret values according to their rotation policies.
+type Rotator struct {
+	db           *gorm.DB
+	secrets      SecretStore
+	installer    SecretInstaller
+	logger       logrus.FieldLogger
+	errorHandler emperror.Handler
+}
+
+// NewRotator returns a new Rotator instance.
+func NewRotator(
+	db *gorm.DB,
+	secrets SecretStore,
+	installer SecretInstaller,
+	logger logrus.FieldLogger,
+	errorHandler emperror.Handler,
+) *Rotator {
+	return &Rotator{
+		db:           db,
+		secrets:      secrets,
+		installer:    installer,
+		logger:       logger,
+		errorHandler: errorHandler,
+	}
+}
+
+// GetPolicy returns the rotation policy of a secret.
+func (r *Rotator) GetPolicy(organizationID uint, secretID string) (*PolicyResponse, error) {
+	model, err := r.getPolicyModel(organizationID, secretID)
+	if err != nil {
+		return nil, err
+	}
+
+	return policyResponse(model), nil
+}
+
+// SetPolicy creates or updates the rotation policy of a secret.
+func (r *Rotator) SetPolicy(organizationID uint, secretID string, policy Policy) (*PolicyResponse, error) {
+	if err := validatePolicy(policy); err != nil {
+		return nil, err
+	}
+
+	item, err := r.secrets.Get(organizationID, secretID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !secret.IsRotatableType(item.Type) {
+		return nil, errors.Wrapf(secret.ErrSecretNotRotatable, "unsupported secret type: %s", item.Type)
+	}
+
+	model, err := r.getPolicyModel(organizationID, secretID)
+	if err == ErrPolicyNotFound {
+		model = &PolicyModel{
+			OrganizationID: organizationID,
+			SecretID:       secretID,
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	model.Interval = policy.Interval
+	model.RenewBefore = policy.RenewBefore
+	model.PasswordFormat = policy.PasswordFormat
+
+	from := time.Now()
+	if model.LastRotatedAt != nil {
+		from = *model.LastRotatedAt
+	}
+	model.NextRotationAt = nextRotation(model, item, from, false)
+
+	if err := r.db.Save(model).Error; err != nil {
+		return nil, errors.Wrap(err, "failed to save rotation policy")
+	}
+
+	return policyResponse(model), nil
+}
+
+// DeletePolicy deletes the rotation policy of a secret.
+func (r *Rotator) DeletePolicy(organizationID uint, secretID string) error {
+	model, err := r.getPolicyModel(organizationID, secretID)
+	if err != nil {
+		return err
+	}
+
+	return errors.Wrap(r.db.Delete(model).Error, "failed to delete rotation policy")
+}
+
+// Rotate regenerates the values of a secret immediately and updates its installations.
+func (r *Rotator) Rotate(ctx context.Context, organizationID uint, secretID string) (*secret.SecretItemResponse, error) {
+	model, err := r.getPolicyModel(organizationID, secretID)
+	if err == ErrPolicyNotFound {
+		return r.rotate(ctx, organizationID, secretID, "")
+	} else if err != nil {
+		return nil, err
+	}
+
+	return r.rotatePolicy(ctx, model)
+}
+
+// RotateDue rotates every secret whose rotation is due.
+// Every policy is claimed before the rotation, so that a secret is rotated by only one Pipeline instance.
+func (r *Rotator) RotateDue(ctx context.Context) error {
+	var models []PolicyModel
+
+	err := r.db.Where("next_rotation_at <= ?", time.Now()).Find(&models).Error
+	if err != nil {
+		return errors.Wrap(err, "failed to find due rotation policies")
+	}
+
+	for i := range models {
+		model := &models[i]
+
+		claimed, err := r.claim(model)
+		if err != nil {
+			return err
+		}
+
+		if !claimed {
+			continue
+		}
+
+		logger := r.logger.WithFields(logrus.Fields{
+			"organization": model.OrganizationID,
+			"secret":       model.SecretID,
+		})
+
+		logger.Info("rotating secret")
+
+		_, err = r.rotatePolicy(ctx, model)
+		if errors.Cause(err) == secret.ErrSecretNotExists {
+			logger.Info("secret no longer exists, deleting rotation policy")
+
+			if err := r.db.Delete(model).Error; err != nil {
+				r.errorHandler.Handle(emperror.With(
+					errors.Wrap(err, "failed to delete rotation policy"),
+					"organization", model.OrganizationID,
+					"secret", model.SecretID,
+				))
+			}
+
+			continue
+		} else if err != nil {
+			r.errorHandler.Handle(emperror.With(
+				errors.Wrap(err, "failed to rotate secret"),
+				"organization", model.OrganizationID,
+				"secret", model.SecretID,
+			))
+
+			continue
+		}
+
+		logger.Info("secret rotated")
+	}
+
+	return nil
+}
+
+// Run rotates the due secrets periodically until the context is cancelled.
+func (r *Rotator) Run(ctx context.Context, interval time.Duration) {
+	r.logger.WithField("interval", interval.String()).Info("starting secret rotator")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.RotateDue(ctx); err != nil {
+			r.errorHandler.Handle(err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			r.logger.Debug("stopping secret rotator")
+			return
+		}
+	}
+}
+
+// claim reserves a due rotation for this instance.
+// It returns false if another instance has claimed the rotation in the meantime.
+func (r *Rotator) claim(model *PolicyModel) (bool, error) {
+	now := time.Now()
+	claimedUntil := now.Add(rotationClaimTimeout)
+
+	result := r.db.Model(&PolicyModel{}).
+		Where("id = ? AND next_rotation_at <= ?", model.ID, now).
+		Update("next_rotation_at", claimedUntil)
+	if result.Error != nil {
+		return false, emperror.With(
+			errors.Wrap(result.Error, "failed to claim rotation policy"),
+			"organization", model.OrganizationID,
+			"secret", model.SecretID,
+		)
+	}
+
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	model.NextRotationAt = claimedUntil
+
+	return true, nil
+}
+
+func (r *Rotator) rotatePolicy(ctx context.Context, model *PolicyModel) (*secret.SecretItemResponse, error) {
+	item, err := r.rotate(ctx, model.OrganizationID, model.SecretID, model.PasswordFormat)
+	if item == nil {
+		// Nothing has changed, retry when the rotation is due again
+		if err != nil && errors.Cause(err) != secret.ErrSecretNotExists {
+			model.LastError = err.Error()
+			if saveErr := r.db.Save(model).Error; saveErr != nil {
+				r.errorHandler.Handle(errors.Wrap(saveErr, "failed to save rotation policy"))
+			}
+		}
+
+		return nil, err
+	}
+
+	now := time.Now()
+	model.LastRotatedAt = &now
+	model.NextRotationAt = nextRotation(model, item, now, true)
+	model.LastError = ""
+	if err != nil {
+		model.LastError = err.Error()
+	}
+
+	if saveErr := r.db.Save(model).Error; saveErr != nil {
+		return item, errors.Wrap(saveErr, "failed to save rotation policy")
+	}
+
+	return item, err
+}
+
+// rotate regenerates the secret values. The returned secret is not nil if the secret itself was rotated.
+func (r *Rotator) rotate(ctx context.Context, organizationID uint, secretID string, passwordFormat string) (*secret.SecretItemResponse, error) {
+	item, err := r.secrets.Get(organizationID, secretID)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := secret.NewRotationRequest(item, passwordFormat)
+	if err != nil {
+		return nil, err
+	}
+	request.UpdatedBy = RotatorUser
+
+	if err := r.secrets.Update(organizationID, secretID, request); err != nil {
+		return nil, errors.Wrap(err, "failed to update secret")
+	}
+
+	rotated, err := r.secrets.Get(organizationID, secretID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get rotated secret")
+	}
+
+	if err := r.installer.ReinstallSecret(ctx, organizationID, rotated); err != nil {
+		return rotated, errors.Wrap(err, "failed to reinstall rotated secret")
+	}
+
+	return rotated, nil
+}
+
+func (r *Rotator) getPolicyModel(organizationID uint, secretID string) (*PolicyModel, error) {
+	var model PolicyModel
+
+	err := r.db.Where(&PolicyModel{OrganizationID: organizationID, SecretID: secretID}).First(&model).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, ErrPolicyNotFound
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to get rotation policy")
+	}
+
+	return &model, nil
+}
+
+func validatePolicy(policy Policy) error {
+	interval, err := time.ParseDuration(policy.Interval)
+	if err != nil || interval <= 0 {
+		return errors.Wrap(ErrInvalidPolicy, "interval must be a positive duration")
+	}
+
+	if policy.RenewBefore != "" {
+		renewBefore, err := time.ParseDuration(policy.RenewBefore)
+		if err != nil || renewBefore <= 0 {
+			return errors.Wrap(ErrInvalidPolicy, "renewBefore must be a positive duration")
+		}
+	}
+
+	if policy.PasswordFormat != "" {
+		methodAndLength := strings.Split(policy.PasswordFormat, ",")
+		if len(methodAndLength) != 2 {
+			return errors.Wrap(ErrInvalidPolicy, "passwordFormat must be in method,length format")
+		}
+
+		if length, err := strconv.Atoi(methodAndLength[1]); err != nil || length <= 0 {
+			return errors.Wrap(ErrInvalidPolicy, "passwordFormat length must be a positive integer")
+		}
+	}
+
+	return nil
+}
+
+// nextRotation calculates the time of the next rotation: after the interval elapsed or
+// before the certificates of a TLS secret expire, whichever comes first.
+func nextRotation(model *PolicyModel, item *secret.SecretItemResponse, from time.Time, rotated bool) time.Time {
+	// the interval is validated when the policy is saved
+	interval, _ := time.ParseDuration(model.Interval)
+	next := from.Add(interval)
+
+	if item.Type != secretTypes.TLSSecretType {
+		return next
+	}
+
+	expiry, ok := secret.CertificateExpiry(item)
+	if !ok {
+		return next
+	}
+
+	renewBefore := DefaultRenewBefore
+	if model.RenewBefore != "" {
+		renewBefore, _ = time.ParseDuration(model.RenewBefore)
+	}
+
+	renewAt := expiry.Add(-renewBefore)
+
+	// A freshly rotated certificate that is valid for less than renewBefore would be rotated in a loop
+	if rotated && !renewAt.After(from) {
+		return next
+	}
+
+	if renewAt.Before(next) {
+		return renewAt
+	}
+
+	return next
+}
+
+func policyResponse(model *PolicyModel) *PolicyResponse {
+	return &PolicyResponse{
+		Policy: Policy{
+			Interval:       model.Interval,
+			RenewBefore:    model.RenewBefore,
+			PasswordFormat: model.PasswordFormat,
+		},
+		SecretID:       model.SecretID,
+		LastRotatedAt:  model.LastRotatedAt,
+		NextRotationAt: model.NextRotationAt,
+		LastError:      model.LastError,
+	}
+}
@@ -0,0 +1,267 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rotation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	secretTypes "github.com/banzaicloud/pipeline/pkg/secret"
+	"github.com/banzaicloud/pipeline/secret"
+	"github.com/goph/emperror"
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/pkg/errors"
+	logrustest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type secretStoreStub struct {
+	secrets map[string]*secret.SecretItemResponse
+}
+
+func (s *secretStoreStub) Get(organizationID uint, secretID string) (*secret.SecretItemResponse, error) {
+	item, ok := s.secrets[secretID]
+	if !ok {
+		return nil, secret.ErrSecretNotExists
+	}
+
+	return item, nil
+}
+
+func (s *secretStoreStub) Update(organizationID uint, secretID string, request *secret.CreateSecretRequest) error {
+	item, ok := s.secrets[secretID]
+	if !ok || request.Version == nil || *request.Version != item.Version {
+		return errors.New("check-and-set parameter did not match the current version")
+	}
+
+	s.secrets[secretID] = &secret.SecretItemResponse{
+		ID:        secretID,
+		Name:      request.Name,
+		Type:      request.Type,
+		Values:    request.Values,
+		Tags:      request.Tags,
+		Version:   item.Version + 1,
+		UpdatedBy: request.UpdatedBy,
+	}
+
+	return nil
+}
+
+type secretInstallerStub struct {
+	installed []string
+}
+
+func (i *secretInstallerStub) ReinstallSecret(ctx context.Context, organizationID uint, secretItem *secret.SecretItemResponse) error {
+	i.installed = append(i.installed, secretItem.Values[secretTypes.Password])
+
+	return nil
+}
+
+func newTestRotator(t *testing.T) (*gorm.DB, *secretStoreStub, *secretInstallerStub, *Rotator) {
+	db, err := gorm.Open("sqlite3", "file::memory:")
+	require.NoError(t, err)
+
+	require.NoError(t, db.AutoMigrate(&PolicyModel{}).Error)
+
+	store := &secretStoreStub{
+		secrets: map[string]*secret.SecretItemResponse{
+			"password": {
+				ID:      "password",
+				Name:    "password",
+				Type:    secretTypes.PasswordSecretType,
+				Values:  map[string]string{secretTypes.Username: "user", secretTypes.Password: "initial"},
+				Version: 1,
+			},
+			"generic": {
+				ID:      "generic",
+				Name:    "generic",
+				Type:    secretTypes.GenericSecret,
+				Values:  map[string]string{"key": "value"},
+				Version: 1,
+			},
+		},
+	}
+	installer := &secretInstallerStub{}
+	logger, _ := logrustest.NewNullLogger()
+
+	rotator := NewRotator(db, store, installer, logger, emperror.NewNoopHandler())
+
+	return db, store, installer, rotator
+}
+
+func TestRotator_SetPolicy(t *testing.T) {
+	db, _, _, rotator := newTestRotator(t)
+	defer db.Close()
+
+	tests := []struct {
+		name     string
+		secretID string
+		policy   Policy
+		err      error
+	}{
+		{name: "invalid interval", secretID: "password", policy: Policy{Interval: "soon"}, err: ErrInvalidPolicy},
+		{name: "negative interval", secretID: "password", policy: Policy{Interval: "-1h"}, err: ErrInvalidPolicy},
+		{name: "invalid renewBefore", secretID: "password", policy: Policy{Interval: "1h", RenewBefore: "x"}, err: ErrInvalidPolicy},
+		{name: "invalid password format", secretID: "password", policy: Policy{Interval: "1h", PasswordFormat: "randAlphaNum"}, err: ErrInvalidPolicy},
+		{name: "missing secret", secretID: "missing", policy: Policy{Interval: "1h"}, err: secret.ErrSecretNotExists},
+		{name: "not rotatable", secretID: "generic", policy: Policy{Interval: "1h"}, err: secret.ErrSecretNotRotatable},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			_, err := rotator.SetPolicy(1, test.secretID, test.policy)
+			assert.Equal(t, test.err, errors.Cause(err))
+		})
+	}
+
+	policy, err := rotator.SetPolicy(1, "password", Policy{Interval: "24h", PasswordFormat: "randAlphaNum,20"})
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), policy.NextRotationAt, time.Minute)
+
+	policy, err = rotator.GetPolicy(1, "password")
+	require.NoError(t, err)
+	assert.Equal(t, "randAlphaNum,20", policy.PasswordFormat)
+
+	require.NoError(t, rotator.DeletePolicy(1, "password"))
+
+	_, err = rotator.GetPolicy(1, "password")
+	assert.Equal(t, ErrPolicyNotFound, err)
+}
+
+func TestRotator_RotateDue(t *testing.T) {
+	db, store, installer, rotator := newTestRotator(t)
+	defer db.Close()
+
+	_, err := rotator.SetPolicy(1, "password", Policy{Interval: "24h", PasswordFormat: "randAlphaNum,20"})
+	require.NoError(t, err)
+
+	// Nothing is due yet
+	require.NoError(t, rotator.RotateDue(context.Background()))
+	assert.Equal(t, 1, store.secrets["password"].Version)
+
+	require.NoError(t, db.Model(&PolicyModel{}).Update("next_rotation_at", time.Now().Add(-time.Minute)).Error)
+
+	require.NoError(t, rotator.RotateDue(context.Background()))
+
+	item := store.secrets["password"]
+	assert.Equal(t, 2, item.Version)
+	assert.Equal(t, RotatorUser, item.UpdatedBy)
+	assert.Equal(t, "user", item.Values[secretTypes.Username])
+	assert.Len(t, item.Values[secretTypes.Password], 20)
+	assert.Equal(t, []string{item.Values[secretTypes.Password]}, installer.installed)
+
+	policy, err := rotator.GetPolicy(1, "password")
+	require.NoError(t, err)
+	require.NotNil(t, policy.LastRotatedAt)
+	assert.True(t, policy.NextRotationAt.After(time.Now()))
+	assert.Empty(t, policy.LastError)
+
+	// Policies of deleted secrets are removed
+	delete(store.secrets, "password")
+	require.NoError(t, db.Model(&PolicyModel{}).Update("next_rotation_at", time.Now().Add(-time.Minute)).Error)
+	require.NoError(t, rotator.RotateDue(context.Background()))
+
+	_, err = rotator.GetPolicy(1, "password")
+	assert.Equal(t, ErrPolicyNotFound, err)
+}
+
+func TestRotator_RotateDue_Claimed(t *testing.T) {
+	db, store, installer, rotator := newTestRotator(t)
+	defer db.Close()
+
+	_, err := rotator.SetPolicy(1, "password", Policy{Interval: "24h"})
+	require.NoError(t, err)
+
+	require.NoError(t, db.Model(&PolicyModel{}).Update("next_rotation_at", time.Now().Add(-time.Minute)).Error)
+
+	var model PolicyModel
+	require.NoError(t, db.First(&model).Error)
+
+	// Another instance has listed the same due policy, but this one claims it first
+	claimed, err := rotator.claim(&model)
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	require.NoError(t, rotator.RotateDue(context.Background()))
+	assert.Equal(t, 1, store.secrets["password"].Version)
+	assert.Empty(t, installer.installed)
+
+	claimed, err = rotator.claim(&model)
+	require.NoError(t, err)
+	assert.False(t, claimed)
+}
+
+func TestRotator_Rotate(t *testing.T) {
+	db, store, installer, rotator := newTestRotator(t)
+	defer db.Close()
+
+	item, err := rotator.Rotate(context.Background(), 1, "password")
+	require.NoError(t, err)
+	assert.Equal(t, 2, item.Version)
+	assert.NotEqual(t, "initial", store.secrets["password"].Values[secretTypes.Password])
+	assert.Len(t, installer.installed, 1)
+
+	_, err = rotator.Rotate(context.Background(), 1, "generic")
+	assert.Equal(t, secret.ErrSecretNotRotatable, errors.Cause(err))
+}
+
+func TestNextRotation(t *testing.T) {
+	now := time.Now()
+	expiry := now.Add(10 * 24 * time.Hour)
+
+	tlsItem := &secret.SecretItemResponse{
+		Name: "tls",
+		Type: secretTypes.TLSSecretType,
+		Values: map[string]string{
+			secretTypes.TLSHosts:    "example.com",
+			secretTypes.TLSValidity: "240h",
+		},
+		Version: 1,
+	}
+
+	rotationRequest, err := secret.NewRotationRequest(tlsItem, "")
+	require.NoError(t, err)
+	tlsItem.Values = rotationRequest.Values
+
+	certExpiry, ok := secret.CertificateExpiry(tlsItem)
+	require.True(t, ok)
+	assert.WithinDuration(t, expiry, certExpiry, time.Minute)
+
+	tests := []struct {
+		name     string
+		model    PolicyModel
+		rotated  bool
+		expected time.Time
+	}{
+		{name: "interval first", model: PolicyModel{Interval: "24h"}, expected: now.Add(24 * time.Hour)},
+		{name: "default renew before expiry", model: PolicyModel{Interval: "720h"}, expected: expiry.Add(-DefaultRenewBefore)},
+		{name: "custom renew before expiry", model: PolicyModel{Interval: "720h", RenewBefore: "48h"}, expected: expiry.Add(-48 * time.Hour)},
+		{name: "expiry already close", model: PolicyModel{Interval: "720h", RenewBefore: "480h"}, expected: expiry.Add(-480 * time.Hour)},
+		{name: "short validity after rotation", model: PolicyModel{Interval: "720h", RenewBefore: "480h"}, rotated: true, expected: now.Add(720 * time.Hour)},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			assert.WithinDuration(t, test.expected, nextRotation(&test.model, tlsItem, now, test.rotated), time.Minute)
+		})
+	}
+}
@@ -172,6 +172,27 @@ func GetAllProfiles(distribution string) ([]ClusterProfile, error) {
 
 }
 
+// GetAllProfileResponses returns the saved profiles of every distribution with profile tables.
+func GetAllProfileResponses() ([]pkgCluster.ClusterProfileResponse, error) {
+	var responses []pkgCluster.ClusterProfileResponse
+
+	for _, distribution := range []string{pkgCluster.EKS, pkgCluster.AKS, pkgCluster.GKE, pkgCluster.OKE} {
+		profiles, err := GetAllProfiles(distribution)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range profiles {
+			response := p.GetProfile()
+			response.Distribution = p.GetDistribution()
+
+			responses = append(responses, *response)
+		}
+	}
+
+	return responses, nil
+}
+
 // GetProfile finds cluster profile from database by given name and cloud type
 func GetProfile(distribution string, name string) (ClusterProfile, error) {
 	db := config.DB()
@@ -16,6 +16,7 @@ package cluster
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -40,11 +41,13 @@ const (
 	Deleting = "DELETING"
 	Warning  = "WARNING"
 	Error    = "ERROR"
+	Sleeping = "SLEEPING"
 
 	CreatingMessage = "Cluster creation is in progress"
 	RunningMessage  = "Cluster is running"
 	UpdatingMessage = "Update is in progress"
 	DeletingMessage = "Termination is in progress"
+	SleepingMessage = "Cluster is sleeping"
 )
 
 // Cloud constants
@@ -120,12 +123,42 @@ type CreateClusterRequest struct {
 	SecretIds    []string                 `json:"secretIds,omitempty" yaml:"secretIds,omitempty"`
 	SecretName   string                   `json:"secretName" yaml:"secretName"`
 	ProfileName  string                   `json:"profileName" yaml:"profileName"`
+	Distribution string                   `json:"distribution,omitempty" yaml:"distribution,omitempty"`
 	PostHooks    PostHooks                `json:"postHooks" yaml:"postHooks"`
 	Properties   *CreateClusterProperties `json:"properties" yaml:"properties" binding:"required"`
 	ScaleOptions *ScaleOptions            `json:"scaleOptions,omitempty" yaml:"scaleOptions,omitempty"`
 	TtlMinutes   uint                     `json:"ttlMinutes,omitempty" yaml:"ttlMinutes,omitempty"`
 }
 
+// ImportClusterRequest describes an existing cluster to be imported into Pipeline
+type ImportClusterRequest struct {
+	Name          string `json:"name" yaml:"name" binding:"required"`
+	Location      string `json:"location" yaml:"location" binding:"required"`
+	Cloud         string `json:"cloud" yaml:"cloud" binding:"required"`
+	SecretId      string `json:"secretId" yaml:"secretId"`
+	SecretName    string `json:"secretName" yaml:"secretName"`
+	ResourceGroup string `json:"resourceGroup,omitempty" yaml:"resourceGroup,omitempty"`
+}
+
+// CloneClusterRequest describes the overrides of a cluster clone and what to copy from the source cluster
+type CloneClusterRequest struct {
+	Name           string    `json:"name" yaml:"name" binding:"required"`
+	Location       string    `json:"location,omitempty" yaml:"location,omitempty"`
+	SecretId       string    `json:"secretId,omitempty" yaml:"secretId,omitempty"`
+	SecretName     string    `json:"secretName,omitempty" yaml:"secretName,omitempty"`
+	PostHooks      PostHooks `json:"postHooks,omitempty" yaml:"postHooks,omitempty"`
+	Deployments    []string  `json:"deployments,omitempty" yaml:"deployments,omitempty"`
+	AllDeployments bool      `json:"allDeployments,omitempty" yaml:"allDeployments,omitempty"`
+	Secrets        []string  `json:"secrets,omitempty" yaml:"secrets,omitempty"`
+}
+
+// CloneClusterResponse describes Pipeline's CloneCluster API response
+type CloneClusterResponse struct {
+	Name       string   `json:"name"`
+	ResourceID uint     `json:"id"`
+	Warnings   []string `json:"warnings,omitempty"`
+}
+
 // CreateClusterProperties contains the cluster flavor specific properties.
 type CreateClusterProperties struct {
 	// TODO (colin): Deprecated
@@ -483,20 +516,24 @@ func (r *UpdateClusterRequest) preValidate() {
 
 // ClusterProfileResponse describes Pipeline's ClusterProfile API responses
 type ClusterProfileResponse struct {
-	Name       string                    `json:"name" binding:"required"`
-	Location   string                    `json:"location" binding:"required"`
-	Cloud      string                    `json:"cloud" binding:"required"`
-	TtlMinutes uint                      `json:"ttlMinutes,omitempty" yaml:"ttlMinutes,omitempty"`
-	Properties *ClusterProfileProperties `json:"properties" binding:"required"`
+	Name         string                    `json:"name" binding:"required"`
+	Location     string                    `json:"location" binding:"required"`
+	Cloud        string                    `json:"cloud" binding:"required"`
+	Distribution string                    `json:"distribution,omitempty"`
+	Global       bool                      `json:"global,omitempty"`
+	TtlMinutes   uint                      `json:"ttlMinutes,omitempty" yaml:"ttlMinutes,omitempty"`
+	Properties   *ClusterProfileProperties `json:"properties" binding:"required"`
 }
 
 // ClusterProfileRequest describes CreateClusterProfile request
+// Fields left empty are inherited from the global default profile of the distribution.
 type ClusterProfileRequest struct {
-	Name       string                    `json:"name" binding:"required"`
-	Location   string                    `json:"location" binding:"required"`
-	Cloud      string                    `json:"cloud" binding:"required"`
-	TtlMinutes uint                      `json:"ttlMinutes,omitempty" yaml:"ttlMinutes,omitempty"`
-	Properties *ClusterProfileProperties `json:"properties" binding:"required"`
+	Name         string                    `json:"name" binding:"required"`
+	Location     string                    `json:"location"`
+	Cloud        string                    `json:"cloud" binding:"required"`
+	Distribution string                    `json:"distribution,omitempty"`
+	TtlMinutes   uint                      `json:"ttlMinutes,omitempty" yaml:"ttlMinutes,omitempty"`
+	Properties   *ClusterProfileProperties `json:"properties" binding:"required"`
 }
 
 type ClusterProfileProperties struct {
@@ -508,6 +545,10 @@ type ClusterProfileProperties struct {
 	AKS *aks.ClusterProfileAKS `json:"aks,omitempty"`
 	GKE *gke.ClusterProfileGKE `json:"gke,omitempty"`
 	OKE *oke.Cluster           `json:"oke,omitempty"`
+
+	// PKE profiles hold the fields of the corresponding cluster creation request.
+	PKE        map[string]interface{} `json:"pke,omitempty"`
+	PKEOnAzure map[string]interface{} `json:"pkeOnAzure,omitempty"`
 }
 
 // CloudInfoRequest describes Cloud info requests
@@ -619,16 +660,36 @@ type NodePoolLabel struct {
 // CreateClusterRequest creates a CreateClusterRequest model from profile
 func (p *ClusterProfileResponse) CreateClusterRequest(createRequest *CreateClusterRequest) (*CreateClusterRequest, error) {
 	response := &CreateClusterRequest{
-		Name:        createRequest.Name,
-		Location:    p.Location,
-		Cloud:       p.Cloud,
-		SecretId:    createRequest.SecretId,
-		ProfileName: p.Name,
-		Properties:  &CreateClusterProperties{},
-		TtlMinutes:  p.TtlMinutes,
+		Name:         createRequest.Name,
+		Location:     p.Location,
+		Cloud:        p.Cloud,
+		SecretId:     createRequest.SecretId,
+		ProfileName:  p.Name,
+		Distribution: p.Distribution,
+		Properties:   &CreateClusterProperties{},
+		TtlMinutes:   p.TtlMinutes,
+	}
+
+	// Profiles without a location (like the default PKE profile) are usable in any region
+	if response.Location == "" {
+		response.Location = createRequest.Location
+	}
+
+	if p.Distribution == PKE && p.Cloud == Amazon {
+		var properties pke.CreateClusterPKE
+		raw, err := json.Marshal(p.Properties.PKE)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(raw, &properties); err != nil {
+			return nil, fmt.Errorf("invalid PKE profile: %s", err.Error())
+		}
+		response.Properties.CreateClusterPKE = &properties
+
+		return response, nil
 	}
 
-	switch p.Cloud { // TODO(Ecsy): distribution???
+	switch p.Cloud {
 	case Alibaba:
 		response.Properties.CreateClusterACK = &ack.CreateClusterACK{
 			RegionID:  p.Properties.ACK.RegionID,
@@ -51,6 +51,18 @@ const awsNoUpdatesError = "No updates are to be performed."
 type EksClusterContext struct {
 	Session     *session.Session
 	ClusterName string
+
+	// Context cancels the long running AWS calls of the actions, no calls are cancelled when it is nil
+	Context aws.Context
+}
+
+// awsContext returns the context of the AWS calls made by the actions.
+func (c *EksClusterContext) awsContext() aws.Context {
+	if c.Context == nil {
+		return aws.BackgroundContext()
+	}
+
+	return c.Context
 }
 
 // EksClusterCreateUpdateContext describes the properties of an EKS cluster creation
@@ -242,13 +254,13 @@ func (a *CreateVPCAndRolesAction) ExecuteAction(input interface{}) (interface{},
 		TemplateBody:     aws.String(templateBody),
 		TimeoutInMinutes: aws.Int64(10),
 	}
-	_, err = cloudformationSrv.CreateStack(createStackInput)
+	err = createStackIfNotExists(a.context.awsContext(), a.log, cloudformationSrv, createStackInput)
 	if err != nil {
-		return nil, emperror.Wrap(err, "create stack failed")
+		return nil, err
 	}
 
 	describeStacksInput := &cloudformation.DescribeStacksInput{StackName: aws.String(a.stackName)}
-	err = cloudformationSrv.WaitUntilStackCreateComplete(describeStacksInput)
+	err = cloudformationSrv.WaitUntilStackCreateCompleteWithContext(a.context.awsContext(), describeStacksInput)
 	if err != nil {
 		return nil, onAwsStackFailure(a.log, err, a.stackName, cloudformationSrv)
 	}
@@ -353,15 +365,17 @@ var _ utils.RevocableAction = (*CreateClusterUserAccessKeyAction)(nil)
 
 // CreateClusterUserAccessKeyAction describes the cluster user to create access key and secret for.
 type CreateClusterUserAccessKeyAction struct {
-	context *EksClusterCreateUpdateContext
-	log     logrus.FieldLogger
+	context        *EksClusterCreateUpdateContext
+	organizationID uint
+	log            logrus.FieldLogger
 }
 
 //
-func NewCreateClusterUserAccessKeyAction(log logrus.FieldLogger, creationContext *EksClusterCreateUpdateContext) *CreateClusterUserAccessKeyAction {
+func NewCreateClusterUserAccessKeyAction(log logrus.FieldLogger, creationContext *EksClusterCreateUpdateContext, orgID uint) *CreateClusterUserAccessKeyAction {
 	return &CreateClusterUserAccessKeyAction{
-		context: creationContext,
-		log:     log,
+		context:        creationContext,
+		organizationID: orgID,
+		log:            log,
 	}
 }
 
@@ -370,13 +384,44 @@ func (a *CreateClusterUserAccessKeyAction) GetName() string {
 	return "CreateClusterUserAccessKeyAction"
 }
 
-// ExecuteAction executes this CreateClusterUserAccessKeyAction
+// ExecuteAction executes this CreateClusterUserAccessKeyAction.
+// The access key persisted by a previous attempt is reused, other access keys of the cluster user are deleted:
+// their secret cannot be retrieved anymore.
 func (a *CreateClusterUserAccessKeyAction) ExecuteAction(input interface{}) (output interface{}, err error) {
 	a.log.Infoln("EXECUTE CreateClusterUserAccessKeyAction, cluster user name: ", a.context.ClusterName)
 
 	iamSvc := iam.New(a.context.Session)
 	clusterUserName := aws.String(a.context.ClusterName)
 
+	persistedAccessKeyId, persistedSecretAccessKey, err := GetClusterUserAccessKeyIdAndSecretVault(a.organizationID, a.context.ClusterName)
+	if err != nil && errors.Cause(err) != secret.ErrSecretNotExists {
+		return nil, err
+	}
+
+	accessKeys, err := amazon.GetUserAccessKeys(iamSvc, clusterUserName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "querying IAM user '%s' access keys failed", a.context.ClusterName)
+	}
+
+	for _, accessKey := range accessKeys {
+		if persistedAccessKeyId != "" && aws.StringValue(accessKey.AccessKeyId) == persistedAccessKeyId {
+			a.log.Infof("reusing persisted cluster user access key: %s", persistedAccessKeyId)
+
+			a.context.ClusterUserAccessKeyId = persistedAccessKeyId
+			a.context.ClusterUserSecretAccessKey = persistedSecretAccessKey
+
+			return nil, nil
+		}
+	}
+
+	for _, accessKey := range accessKeys {
+		a.log.Infof("deleting unknown cluster user access key: %s", aws.StringValue(accessKey.AccessKeyId))
+
+		if err := amazon.DeleteUserAccessKey(iamSvc, clusterUserName, accessKey.AccessKeyId); err != nil {
+			return nil, errors.Wrapf(err, "deleting Amazon access key '%s' failed", aws.StringValue(accessKey.AccessKeyId))
+		}
+	}
+
 	accessKey, err := amazon.CreateUserAccessKey(iamSvc, clusterUserName)
 	if err != nil {
 		return nil, err
@@ -388,14 +433,14 @@ func (a *CreateClusterUserAccessKeyAction) ExecuteAction(input interface{}) (out
 	return nil, nil
 }
 
-// UndoAction rolls back this CreateClusterUserAccessKeyAction
+// UndoAction rolls back this CreateClusterUserAccessKeyAction by deleting every access key of the cluster user,
+// including the ones created by interrupted attempts.
 func (a *CreateClusterUserAccessKeyAction) UndoAction() error {
-	a.log.Infof("EXECUTE UNDO CreateClusterUserAccessKeyAction, deleting cluster user access key: %s", a.context.ClusterUserAccessKeyId)
+	a.log.Infof("EXECUTE UNDO CreateClusterUserAccessKeyAction, deleting cluster user access keys: %s", a.context.ClusterName)
 
-	iamSvc := iam.New(a.context.Session)
-	clusterUserName := aws.String(a.context.ClusterName)
+	deleteContext := NewEksClusterDeleteContext(a.context.Session, a.context.ClusterName)
 
-	err := amazon.DeleteUserAccessKey(iamSvc, clusterUserName, aws.String(a.context.ClusterUserAccessKeyId))
+	_, err := NewDeleteClusterUserAccessKeyAction(a.log, deleteContext).ExecuteAction(nil)
 	return err
 }
 
@@ -582,34 +627,49 @@ func (a *CreateEksClusterAction) ExecuteAction(input interface{}) (output interf
 		createClusterInput.Version = aws.String(a.kubernetesVersion)
 	}
 
-	result, err := eksSvc.CreateCluster(createClusterInput)
-	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			a.log.Errorf("CreateCluster error [%s]: %s", aerr.Code(), aerr.Error())
-		} else {
-			a.log.Errorf("CreateCluster error: %s", err.Error())
+	describeClusterInput := &eks.DescribeClusterInput{
+		Name: aws.String(a.context.ClusterName),
+	}
+
+	// a previous attempt may have already started the creation
+	existing, err := eksSvc.DescribeClusterWithContext(a.context.awsContext(), describeClusterInput)
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == eks.ErrCodeResourceNotFoundException {
+		_, err = eksSvc.CreateClusterWithContext(a.context.awsContext(), createClusterInput)
+		if err != nil {
+			if aerr, ok := err.(awserr.Error); ok {
+				a.log.Errorf("CreateCluster error [%s]: %s", aerr.Code(), aerr.Error())
+			} else {
+				a.log.Errorf("CreateCluster error: %s", err.Error())
+			}
+			return nil, err
 		}
-		return nil, err
+	} else if err != nil {
+		return nil, emperror.Wrap(err, "failed to describe EKS cluster")
+	} else {
+		status := aws.StringValue(existing.Cluster.Status)
+		if status != eks.ClusterStatusCreating && status != eks.ClusterStatusActive {
+			return nil, errors.Errorf("EKS cluster created by a previous attempt is in %s state", status)
+		}
+
+		a.log.Infof("EKS cluster already exists (%s), skipping creation", status)
 	}
 
 	//wait for ready status
 	startTime := time.Now()
 	a.log.Info("Waiting for EKS cluster creation")
-	describeClusterInput := &eks.DescribeClusterInput{
-		Name: aws.String(a.context.ClusterName),
-	}
-	err = a.waitUntilClusterCreateComplete(describeClusterInput)
+	err = a.waitUntilClusterCreateCompleteWithContext(a.context.awsContext(), describeClusterInput)
 	if err != nil {
 		return nil, err
 	}
 	endTime := time.Now()
 	a.log.Infoln("EKS cluster created successfully in", endTime.Sub(startTime).String())
 
-	return result.Cluster, nil
-}
+	result, err := eksSvc.DescribeClusterWithContext(a.context.awsContext(), describeClusterInput)
+	if err != nil {
+		return nil, emperror.Wrap(err, "failed to describe EKS cluster")
+	}
 
-func (a *CreateEksClusterAction) waitUntilClusterCreateComplete(input *eks.DescribeClusterInput) error {
-	return a.waitUntilClusterCreateCompleteWithContext(aws.BackgroundContext(), input)
+	return result.Cluster, nil
 }
 
 func (a *CreateEksClusterAction) waitUntilClusterCreateCompleteWithContext(ctx aws.Context, input *eks.DescribeClusterInput, opts ...request.WaiterOption) error {
@@ -668,6 +728,10 @@ func (a *CreateEksClusterAction) UndoAction() (err error) {
 		Name: aws.String(a.context.ClusterName),
 	}
 	_, err = eksSvc.DeleteCluster(deleteClusterInput)
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == eks.ErrCodeResourceNotFoundException {
+		return nil
+	}
+
 	return err
 }
 
@@ -917,7 +981,7 @@ func (a *CreateUpdateNodePoolStackAction) ExecuteAction(input interface{}) (outp
 					TemplateBody:       aws.String(a.context.NodePoolTemplate),
 					TimeoutInMinutes:   aws.Int64(10),
 				}
-				_, err = cloudformationSrv.CreateStack(createStackInput)
+				err := createStackIfNotExists(a.context.awsContext(), a.log, cloudformationSrv, createStackInput)
 				if err != nil {
 					errorChan <- emperror.Wrapf(err, "could not create '%s' CF stack", stackName)
 					return
@@ -955,10 +1019,11 @@ func (a *CreateUpdateNodePoolStackAction) ExecuteAction(input interface{}) (outp
 
 			describeStacksInput := &cloudformation.DescribeStacksInput{StackName: aws.String(stackName)}
 
+			var err error
 			if a.isCreate {
-				err = cloudformationSrv.WaitUntilStackCreateComplete(describeStacksInput)
+				err = cloudformationSrv.WaitUntilStackCreateCompleteWithContext(a.context.awsContext(), describeStacksInput)
 			} else if waitOnCreateUpdate {
-				err = cloudformationSrv.WaitUntilStackUpdateComplete(describeStacksInput)
+				err = cloudformationSrv.WaitUntilStackUpdateCompleteWithContext(a.context.awsContext(), describeStacksInput)
 			}
 
 			if err != nil {
@@ -966,7 +1031,7 @@ func (a *CreateUpdateNodePoolStackAction) ExecuteAction(input interface{}) (outp
 				return
 			}
 
-			_, err := cloudformationSrv.DescribeStacks(describeStacksInput)
+			_, err = cloudformationSrv.DescribeStacks(describeStacksInput)
 			if err != nil {
 				errorChan <- err
 				return
@@ -1092,7 +1157,8 @@ func (a *PersistClusterUserAccessKeyAction) ExecuteAction(input interface{}) (ou
 		},
 	}
 
-	if _, err := secret.Store.Store(a.organizationID, &secretRequest); err != nil {
+	// a previous attempt may have already persisted an access key
+	if _, err := secret.Store.CreateOrUpdate(a.organizationID, &secretRequest); err != nil {
 		return nil, errors.Wrapf(err, "failed to create/update secret: %s", secretName)
 	}
 
@@ -1161,6 +1227,12 @@ func (a *UploadSSHKeyAction) ExecuteAction(input interface{}) (output interface{
 		PublicKeyMaterial: []byte(a.context.SSHKey.PublicKeyData), // []byte `locationName:"publicKeyMaterial" type:"blob" required:"true"`
 	}
 	output, err = ec2srv.ImportKeyPair(importKeyPairInput)
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "InvalidKeyPair.Duplicate" {
+		a.log.Infof("SSH key %s already exists, skipping upload", a.context.SSHKeyName)
+
+		return nil, nil
+	}
+
 	return output, err
 }
 
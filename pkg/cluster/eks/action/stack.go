@@ -0,0 +1,92 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package action
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/goph/emperror"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// describeStack returns the stack with the given name or nil if it does not exist.
+func describeStack(ctx aws.Context, cloudformationSrv *cloudformation.CloudFormation, stackName string) (*cloudformation.Stack, error) {
+	output, err := cloudformationSrv.DescribeStacksWithContext(ctx, &cloudformation.DescribeStacksInput{StackName: aws.String(stackName)})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "ValidationError" && strings.Contains(awsErr.Message(), "does not exist") {
+			return nil, nil
+		}
+
+		return nil, emperror.WrapWith(err, "failed to describe stack", "stack", stackName)
+	}
+
+	if len(output.Stacks) == 0 {
+		return nil, nil
+	}
+
+	return output.Stacks[0], nil
+}
+
+// createStackIfNotExists starts the creation of a CloudFormation stack unless a previous attempt already did,
+// so that a retried creation step picks up the stack of the interrupted one.
+// Stacks left behind by a failed attempt are deleted before they are created again.
+// The caller has to wait for the creation to complete.
+func createStackIfNotExists(ctx aws.Context, log logrus.FieldLogger, cloudformationSrv *cloudformation.CloudFormation, input *cloudformation.CreateStackInput) error {
+	stackName := aws.StringValue(input.StackName)
+
+	stack, err := describeStack(ctx, cloudformationSrv, stackName)
+	if err != nil {
+		return err
+	}
+
+	if stack != nil {
+		describeStacksInput := &cloudformation.DescribeStacksInput{StackName: input.StackName}
+
+		switch status := aws.StringValue(stack.StackStatus); status {
+		case cloudformation.StackStatusCreateComplete, cloudformation.StackStatusCreateInProgress:
+			log.Infof("stack %s already exists (%s), skipping creation", stackName, status)
+
+			return nil
+
+		case cloudformation.StackStatusCreateFailed, cloudformation.StackStatusRollbackComplete, cloudformation.StackStatusRollbackFailed:
+			log.Infof("deleting stack %s left behind by a failed creation (%s)", stackName, status)
+
+			_, err := cloudformationSrv.DeleteStackWithContext(ctx, &cloudformation.DeleteStackInput{StackName: input.StackName})
+			if err != nil {
+				return emperror.WrapWith(err, "failed to delete stack", "stack", stackName)
+			}
+
+			fallthrough
+
+		case cloudformation.StackStatusDeleteInProgress:
+			if err := cloudformationSrv.WaitUntilStackDeleteCompleteWithContext(ctx, describeStacksInput); err != nil {
+				return onAwsStackFailure(log, err, stackName, cloudformationSrv)
+			}
+
+		default:
+			return errors.Errorf("stack %s is in unexpected state: %s", stackName, status)
+		}
+	}
+
+	if _, err := cloudformationSrv.CreateStackWithContext(ctx, input); err != nil {
+		return emperror.WrapWith(err, "create stack failed", "stack", stackName)
+	}
+
+	return nil
+}
@@ -162,6 +162,87 @@ type GetDeploymentResponse struct {
 	Values       map[string]interface{} `json:"values"`
 }
 
+// DeploymentRevision describes a revision of a helm deployment
+type DeploymentRevision struct {
+	Revision     int32     `json:"revision"`
+	Chart        string    `json:"chart"`
+	ChartName    string    `json:"chartName"`
+	ChartVersion string    `json:"chartVersion"`
+	AppVersion   string    `json:"appVersion,omitempty"`
+	Status       string    `json:"status"`
+	Description  string    `json:"description"`
+	CreatedAt    time.Time `json:"createdAt,omitempty"`
+	Updated      time.Time `json:"updatedAt,omitempty"`
+}
+
+// RollbackDeploymentRequest describes a helm deployment rollback request
+type RollbackDeploymentRequest struct {
+	Revision int32 `json:"revision" binding:"required"`
+	Wait     bool  `json:"wait,omitempty"`
+	Timeout  int64 `json:"timeout,omitempty" binding:"min=0"`
+	Force    bool  `json:"force,omitempty"`
+	Recreate bool  `json:"recreate,omitempty"`
+	DryRun   bool  `json:"dryRun,omitempty"`
+}
+
+// RollbackDeploymentResponse describes a helm deployment rollback response
+type RollbackDeploymentResponse struct {
+	ReleaseName  string        `json:"releaseName"`
+	Revision     int32         `json:"revision"`
+	RolledBackTo int32         `json:"rolledBackTo"`
+	Status       string        `json:"status"`
+	ValuesDiff   []ValueChange `json:"valuesDiff"`
+}
+
+// ValueChange describes a changed value between two helm deployment revisions
+type ValueChange struct {
+	Key  string      `json:"key"`
+	From interface{} `json:"from,omitempty"`
+	To   interface{} `json:"to,omitempty"`
+}
+
+// DeploymentDiffResponse describes the changes a helm deployment upgrade would make
+type DeploymentDiffResponse struct {
+	ReleaseName string         `json:"releaseName"`
+	Revision    int32          `json:"revision"`
+	Chart       string         `json:"chart"`
+	NewChart    string         `json:"newChart"`
+	Resources   []ResourceDiff `json:"resources"`
+	ValuesDiff  []ValueChange  `json:"valuesDiff"`
+}
+
+// Resource change types
+const (
+	ResourceAdded    = "added"
+	ResourceRemoved  = "removed"
+	ResourceModified = "modified"
+)
+
+// ResourceDiff describes the change of a K8s resource of a helm deployment as a unified diff
+type ResourceDiff struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Change    string `json:"change"`
+	Diff      string `json:"diff"`
+}
+
+// RepositoryRequest describes a helm repository create/modify request
+type RepositoryRequest struct {
+	Name       string `json:"name"`
+	URL        string `json:"url"`
+	SecretID   string `json:"secretId,omitempty"`
+	SecretName string `json:"secretName,omitempty"`
+}
+
+// Repository describes a helm repository of an organization
+type Repository struct {
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	Cache    string `json:"cache"`
+	SecretID string `json:"secretId,omitempty"`
+}
+
 // GetDeploymentResourcesResponse lists the resources of a helm deployment
 type GetDeploymentResourcesResponse struct {
 	DeploymentResources []DeploymentResource `json:"resources"`
@@ -317,6 +317,20 @@ func (cc *CloudConnection) GetVirtualMachineScaleSetsClient() *VirtualMachineSca
 	}
 }
 
+// VirtualMachineScaleSetVMsClient extends compute.VirtualMachineScaleSetVMsClient
+type VirtualMachineScaleSetVMsClient struct {
+	compute.VirtualMachineScaleSetVMsClient
+}
+
+// GetVirtualMachineScaleSetVMsClient returns a VirtualMachineScaleSetVMsClient instance
+func (cc *CloudConnection) GetVirtualMachineScaleSetVMsClient() *VirtualMachineScaleSetVMsClient {
+	return &VirtualMachineScaleSetVMsClient{
+		compute.VirtualMachineScaleSetVMsClient{
+			BaseClient: *cc.getComputeBaseClient(),
+		},
+	}
+}
+
 // VirtualMachineSizesClient extends compute.VirtualMachineSizesClient
 type VirtualMachineSizesClient struct {
 	compute.VirtualMachineSizesClient
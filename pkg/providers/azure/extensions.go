@@ -160,6 +160,19 @@ func (client *VirtualMachinesClient) ListAll(ctx context.Context, resourceGroupN
 	return
 }
 
+// ListAll returns all virtual machines belonging to the specified virtual machine scale set
+func (client *VirtualMachineScaleSetVMsClient) ListAll(ctx context.Context, resourceGroupName, vmssName string) (res []compute.VirtualMachineScaleSetVM, err error) {
+	rp, err := client.List(ctx, resourceGroupName, vmssName, "", "", "")
+	for rp.NotDone() {
+		if err != nil {
+			return res, err
+		}
+		res = append(res, rp.Values()...)
+		err = rp.NextWithContext(ctx)
+	}
+	return
+}
+
 // ListMachineTypes returns all machine types available at the specified location
 func (client *VirtualMachineSizesClient) ListMachineTypes(ctx context.Context, location string) (res cluster.MachineTypes, err error) {
 	l, err := client.List(ctx, location)
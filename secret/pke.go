@@ -0,0 +1,124 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	secretTypes "github.com/banzaicloud/pipeline/pkg/secret"
+	"github.com/pkg/errors"
+)
+
+// Validity of the generated CAs, matches the lease TTLs of the Vault PKI engines
+const (
+	rootCAValidity         = 43801 * time.Hour
+	intermediateCAValidity = 43800 * time.Hour
+)
+
+// generateLocalPKEValues generates the PKE CAs in process for secret stores without a PKI engine.
+func generateLocalPKEValues(value *CreateSecretRequest) error {
+	clusterID := getClusterIDFromTags(value.Tags)
+	if clusterID == "" {
+		return errors.New("clusterID is missing from the tags")
+	}
+
+	rootCert, rootKey, err := generateCACert(fmt.Sprintf("cluster-%s-ca", clusterID), rootCAValidity, nil, nil)
+	if err != nil {
+		return errors.Wrapf(err, "Error generating root CA for cluster %s", clusterID)
+	}
+	ca := encodeCertificatePEM(rootCert)
+
+	intermediates := map[string]*certificate{}
+	for _, commonName := range []string{
+		secretTypes.KubernetesCACommonName,
+		secretTypes.EtcdCACommonName,
+		secretTypes.KubernetesFrontProxyCACommonName,
+	} {
+		cert, key, err := generateCACert(commonName, intermediateCAValidity, rootCert, rootKey)
+		if err != nil {
+			return errors.Wrapf(err, "error generating %s intermediate cert for cluster %s", commonName, clusterID)
+		}
+
+		intermediates[commonName] = &certificate{
+			Cert: encodeCertificatePEM(cert),
+			Key:  strings.TrimSpace(string(encodePrivateKeyPEM(key))),
+		}
+	}
+
+	return setPKEValues(
+		value,
+		clusterID,
+		ca,
+		intermediates[secretTypes.KubernetesCACommonName],
+		intermediates[secretTypes.EtcdCACommonName],
+		intermediates[secretTypes.KubernetesFrontProxyCACommonName],
+	)
+}
+
+// generateCACert generates a CA certificate signed by the parent, or a self-signed one if parent is nil.
+func generateCACert(commonName string, validity time.Duration, parent *x509.Certificate, parentKey *rsa.PrivateKey) (*x509.Certificate, *rsa.PrivateKey, error) {
+	key, err := rsa.GenerateKey(rand.Reader, rsaKeySize)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to generate key")
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to generate serial number")
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             now.Add(-30 * time.Second),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to create certificate")
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to parse certificate")
+	}
+
+	return cert, key, nil
+}
+
+func encodeCertificatePEM(cert *x509.Certificate) string {
+	block := pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: cert.Raw,
+	}
+	return strings.TrimSpace(string(pem.EncodeToMemory(&block)))
+}
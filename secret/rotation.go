@@ -0,0 +1,114 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"time"
+
+	secretTypes "github.com/banzaicloud/pipeline/pkg/secret"
+	"github.com/pkg/errors"
+)
+
+// ErrSecretNotRotatable is returned when the values of a secret cannot be regenerated
+// nolint: gochecknoglobals
+var ErrSecretNotRotatable = errors.New("secret values cannot be regenerated")
+
+// IsRotatableType checks whether the values of a secret type can be regenerated
+func IsRotatableType(secretType string) bool {
+	switch secretType {
+	case secretTypes.TLSSecretType, secretTypes.PasswordSecretType, secretTypes.HtpasswdSecretType:
+		return true
+	default:
+		return false
+	}
+}
+
+// NewRotationRequest creates an update request for the secret with freshly generated values.
+// Password secrets are regenerated using passwordFormat (method,length) or the default format.
+func NewRotationRequest(item *SecretItemResponse, passwordFormat string) (*CreateSecretRequest, error) {
+	values := map[string]string{}
+
+	switch item.Type {
+	case secretTypes.TLSSecretType:
+		// Only generated TLS secrets know their hosts
+		if item.Values[secretTypes.TLSHosts] == "" {
+			return nil, errors.Wrap(ErrSecretNotRotatable, "TLS secret has no hosts")
+		}
+
+		values[secretTypes.TLSHosts] = item.Values[secretTypes.TLSHosts]
+		if validity, ok := item.Values[secretTypes.TLSValidity]; ok {
+			values[secretTypes.TLSValidity] = validity
+		}
+
+	case secretTypes.PasswordSecretType:
+		if passwordFormat == "" {
+			passwordFormat = DefaultPasswordFormat
+		}
+
+		for key, value := range item.Values {
+			values[key] = value
+		}
+		values[secretTypes.Password] = passwordFormat
+
+	case secretTypes.HtpasswdSecretType:
+		if item.Values[secretTypes.Username] == "" {
+			return nil, errors.Wrap(ErrSecretNotRotatable, "htpasswd secret has no username")
+		}
+
+		values[secretTypes.Username] = item.Values[secretTypes.Username]
+
+	default:
+		return nil, errors.Wrapf(ErrSecretNotRotatable, "unsupported secret type: %s", item.Type)
+	}
+
+	request := &CreateSecretRequest{
+		Name:    item.Name,
+		Type:    item.Type,
+		Values:  values,
+		Tags:    item.Tags,
+		Version: &item.Version,
+	}
+
+	if err := generateValuesIfNeeded(request); err != nil {
+		return nil, errors.Wrap(err, "failed to regenerate secret values")
+	}
+
+	return request, nil
+}
+
+// CertificateExpiry returns the earliest expiration time of the certificates in a TLS secret
+func CertificateExpiry(item *SecretItemResponse) (time.Time, bool) {
+	var expiry time.Time
+
+	for _, key := range []string{secretTypes.CACert, secretTypes.ServerCert, secretTypes.ClientCert} {
+		block, _ := pem.Decode([]byte(item.Values[key]))
+		if block == nil {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			continue
+		}
+
+		if expiry.IsZero() || cert.NotAfter.Before(expiry) {
+			expiry = cert.NotAfter
+		}
+	}
+
+	return expiry, !expiry.IsZero()
+}
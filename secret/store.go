@@ -29,17 +29,15 @@ import (
 	"time"
 
 	"github.com/banzaicloud/bank-vaults/pkg/tls"
-	"github.com/banzaicloud/bank-vaults/pkg/vault"
+	"github.com/banzaicloud/pipeline/config"
 	secretTypes "github.com/banzaicloud/pipeline/pkg/secret"
 	"github.com/banzaicloud/pipeline/secret/verify"
-	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/jinzhu/gorm"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
-	"github.com/spf13/cast"
 	"github.com/spf13/viper"
 	"golang.org/x/crypto/bcrypt"
-	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 const (
@@ -48,9 +46,31 @@ const (
 	PublicKeyBlockType     = "PUBLIC KEY"
 )
 
-// Store object that wraps up vault logical store
+// Secret store backends
+const (
+	VaultBackend    = "vault"
+	DatabaseBackend = "database"
+)
+
+// SecretStore persists and retrieves organization secrets.
+type SecretStore interface {
+	Store(organizationID uint, request *CreateSecretRequest) (string, error)
+	Update(organizationID uint, secretID string, request *CreateSecretRequest) error
+	Get(organizationID uint, secretID string) (*SecretItemResponse, error)
+	GetByName(organizationID uint, name string) (*SecretItemResponse, error)
+	List(organizationID uint, query *secretTypes.ListSecretsQuery) ([]*SecretItemResponse, error)
+	Delete(organizationID uint, secretID string) error
+	DeleteByClusterUID(organizationID uint, clusterUID string) error
+	GetOrCreate(organizationID uint, request *CreateSecretRequest) (string, error)
+	CreateOrUpdate(organizationID uint, request *CreateSecretRequest) (string, error)
+	ListVersions(organizationID uint, secretID string) ([]*SecretVersionResponse, error)
+	GetVersion(organizationID uint, secretID string, version int) (*SecretItemResponse, error)
+	RestoreVersion(organizationID uint, secretID string, version int, updatedBy string) error
+}
+
+// Store object that wraps up the configured secret store backend
 // nolint: gochecknoglobals
-var Store *secretStore
+var Store SecretStore
 
 // RestrictedStore object that wraps the main secret store and restricts access to certain items
 // nolint: gochecknoglobals
@@ -61,13 +81,34 @@ var RestrictedStore *restrictedSecretStore
 var ErrSecretNotExists = fmt.Errorf("There's no secret with this ID")
 
 func init() {
-	Store = newVaultSecretStore()
-	RestrictedStore = &restrictedSecretStore{Store}
+	// Vault is the default backend and needs no other dependency, other backends are set up by InitStore
+	if backend := viper.GetString(config.SecretStoreBackend); backend == VaultBackend || backend == "" {
+		setStore(newVaultSecretStore())
+	}
 }
 
-type secretStore struct {
-	Client  *vault.Client
-	Logical *vaultapi.Logical
+// InitStore sets up the configured secret store backend.
+func InitStore(db *gorm.DB) error {
+	switch backend := viper.GetString(config.SecretStoreBackend); backend {
+	case DatabaseBackend:
+		store, err := newDatabaseSecretStore(db, viper.GetString(config.SecretStoreDatabaseKeyFile))
+		if err != nil {
+			return errors.Wrap(err, "failed to create database secret store")
+		}
+
+		setStore(store)
+	case VaultBackend, "":
+		// The Vault store is already set up
+	default:
+		return errors.Errorf("unknown secret store backend: %s", backend)
+	}
+
+	return nil
+}
+
+func setStore(store SecretStore) {
+	Store = store
+	RestrictedStore = &restrictedSecretStore{Store}
 }
 
 // CreateSecretResponse API response for AddSecrets
@@ -147,16 +188,6 @@ type AllowedFilteredSecretTypesResponse struct {
 // AllowedSecretTypesResponse for API response for AllowedSecretTypes
 type AllowedSecretTypesResponse map[string]secretTypes.Meta
 
-func newVaultSecretStore() *secretStore {
-	role := "pipeline"
-	client, err := vault.NewClient(role)
-	if err != nil {
-		panic(err)
-	}
-	logical := client.Vault().Logical()
-	return &secretStore{Client: client, Logical: logical}
-}
-
 // GenerateSecretIDFromName generates a "unique by name per organization" id for Secrets
 func GenerateSecretIDFromName(name string) string {
 	return string(fmt.Sprintf("%x", sha256.Sum256([]byte(name))))
@@ -235,8 +266,8 @@ func (r *CreateSecretRequest) ValidateAsNew(verifier verify.Verifier) error {
 	return nil
 }
 
-// DeleteByClusterUID Delete secrets by ClusterUID
-func (ss *secretStore) DeleteByClusterUID(orgID uint, clusterUID string) error {
+// deleteByClusterUID deletes every secret tagged with the cluster UID
+func deleteByClusterUID(store SecretStore, orgID uint, clusterUID string) error {
 	if clusterUID == "" {
 		return errors.New("clusterUID is empty")
 	}
@@ -244,7 +275,7 @@ func (ss *secretStore) DeleteByClusterUID(orgID uint, clusterUID string) error {
 	log := log.WithFields(logrus.Fields{"organization": orgID, "clusterUID": clusterUID})
 
 	clusterUIDTag := clusterUIDTag(clusterUID)
-	secrets, err := Store.List(orgID,
+	secrets, err := store.List(orgID,
 		&secretTypes.ListSecretsQuery{
 			Tags: []string{clusterUIDTag},
 		})
@@ -256,7 +287,7 @@ func (ss *secretStore) DeleteByClusterUID(orgID uint, clusterUID string) error {
 
 	for _, s := range secrets {
 		log := log.WithFields(logrus.Fields{"secret": s.ID, "secretName": s.Name})
-		err := Store.Delete(orgID, s.ID)
+		err := store.Delete(orgID, s.ID)
 		if err != nil {
 			log.Errorf("Error during delete secret: %s", err.Error())
 		}
@@ -266,127 +297,18 @@ func (ss *secretStore) DeleteByClusterUID(orgID uint, clusterUID string) error {
 	return nil
 }
 
-// Delete secret secret/orgs/:orgid:/:id: scope
-func (ss *secretStore) Delete(organizationID uint, secretID string) error {
-
-	path := secretMetadataPath(organizationID, secretID)
-
-	log.Debugln("Delete secret:", path)
-
-	secret, err := ss.Get(organizationID, secretID)
-	if err != nil {
-		return errors.Wrap(err, "Error during querying secret before deletion")
-	}
-
-	if _, err := ss.Logical.Delete(path); err != nil {
-		return errors.Wrap(err, "Error during deleting secret")
-	}
-
-	// if type is distribution, unmount all pki engines
-	if secret.Type == secretTypes.PKESecretType {
-		clusterID := getClusterIDFromTags(secret.Tags)
-		basePath := clusterPKIPath(organizationID, clusterID)
-
-		path = fmt.Sprintf("%s/ca", basePath)
-		err = ss.Client.Vault().Sys().Unmount(path)
-		if err != nil {
-			log.Warnf("failed to unmount %s: %s", path, err)
-		}
-
-		path = fmt.Sprintf("%s/%s", basePath, secretTypes.KubernetesCACommonName)
-		err = ss.Client.Vault().Sys().Unmount(path)
-		if err != nil {
-			log.Warnf("failed to unmount %s: %s", path, err)
-		}
-
-		path = fmt.Sprintf("%s/%s", basePath, secretTypes.EtcdCACommonName)
-		err = ss.Client.Vault().Sys().Unmount(path)
-		if err != nil {
-			log.Warnf("failed to unmount %s: %s", path, err)
-		}
-
-		path = fmt.Sprintf("%s/%s", basePath, secretTypes.KubernetesFrontProxyCACommonName)
-		err = ss.Client.Vault().Sys().Unmount(path)
-		if err != nil {
-			log.Warnf("failed to unmount %s: %s", path, err)
-		}
-	}
-
-	return nil
-}
-
-// Save secret secret/orgs/:orgid:/:id: scope
-func (ss *secretStore) Store(organizationID uint, request *CreateSecretRequest) (string, error) {
-
-	// We allow only Kubernetes compatible Secret names
-	if errorList := validation.IsDNS1123Subdomain(request.Name); errorList != nil {
-		return "", errors.New(errorList[0])
-	}
-
-	secretID := GenerateSecretID(request)
-	path := secretDataPath(organizationID, secretID)
-
-	if err := ss.generateValuesIfNeeded(organizationID, request); err != nil {
-		return "", err
-	}
-
-	sort.Strings(request.Tags)
-
-	data, err := secretData(0, request)
-	if err != nil {
-		return "", err
-	}
-
-	if _, err := ss.Logical.Write(path, data); err != nil {
-		return "", errors.Wrap(err, "Error during storing secret")
-	}
-
-	return secretID, nil
-}
-
-// Update secret secret/orgs/:orgid:/:id: scope
-func (ss *secretStore) Update(organizationID uint, secretID string, request *CreateSecretRequest) error {
-
-	if GenerateSecretID(request) != secretID {
-		return errors.New("Secret name cannot be changed")
-	}
-
-	path := secretDataPath(organizationID, secretID)
-
-	log.Debugln("Update secret:", path)
-
-	sort.Strings(request.Tags)
-
-	// If secret doesn't exists, create it.
-	version := 0
-	if request.Version != nil {
-		version = *request.Version
-	}
-
-	data, err := secretData(version, request)
-	if err != nil {
-		return err
-	}
-
-	if _, err := ss.Logical.Write(path, data); err != nil {
-		return errors.Wrap(err, "Error during updating secret")
-	}
-
-	return nil
-}
-
-// GetOrCreate create new secret or get if it's exist. secret/orgs/:orgid:/:id: scope
-func (ss *secretStore) GetOrCreate(organizationID uint, value *CreateSecretRequest) (string, error) {
+// getOrCreate returns the ID of an existing secret or stores a new one
+func getOrCreate(store SecretStore, organizationID uint, value *CreateSecretRequest) (string, error) {
 	secretID := GenerateSecretID(value)
 
 	// Try to get the secret version first
-	if secret, err := Store.Get(organizationID, secretID); err != nil && err != ErrSecretNotExists {
+	if secret, err := store.Get(organizationID, secretID); err != nil && err != ErrSecretNotExists {
 		log.Errorf("Error during checking secret: %s", err.Error())
 		return "", err
 	} else if secret != nil {
 		return secret.ID, nil
 	} else {
-		secretID, err = Store.Store(organizationID, value)
+		secretID, err = store.Store(organizationID, value)
 		if err != nil {
 			log.Errorf("Error during storing secret: %s", err.Error())
 			return "", err
@@ -395,24 +317,24 @@ func (ss *secretStore) GetOrCreate(organizationID uint, value *CreateSecretReque
 	return secretID, nil
 }
 
-// CreateOrUpdate create new secret or update if it's exist. secret/orgs/:orgid:/:id: scope
-func (ss *secretStore) CreateOrUpdate(organizationID uint, value *CreateSecretRequest) (string, error) {
+// createOrUpdate stores a new secret or updates the existing one
+func createOrUpdate(store SecretStore, organizationID uint, value *CreateSecretRequest) (string, error) {
 
 	secretID := GenerateSecretID(value)
 
 	// Try to get the secret version first
-	if secret, err := Store.Get(organizationID, secretID); err != nil && err != ErrSecretNotExists {
+	if secret, err := store.Get(organizationID, secretID); err != nil && err != ErrSecretNotExists {
 		log.Errorf("Error during checking secret: %s", err.Error())
 		return "", err
 	} else if secret != nil {
 		value.Version = &(secret.Version)
-		err := Store.Update(organizationID, secretID, value)
+		err := store.Update(organizationID, secretID, value)
 		if err != nil {
 			log.Errorf("Error during updating secret: %s", err.Error())
 			return "", err
 		}
 	} else {
-		secretID, err = Store.Store(organizationID, value)
+		secretID, err = store.Store(organizationID, value)
 		if err != nil {
 			log.Errorf("Error during storing secret: %s", err.Error())
 			return "", err
@@ -421,62 +343,11 @@ func (ss *secretStore) CreateOrUpdate(organizationID uint, value *CreateSecretRe
 	return secretID, nil
 }
 
-func parseSecret(secretID string, secret *vaultapi.Secret, values bool) (*SecretItemResponse, error) {
-
-	data := cast.ToStringMap(secret.Data["data"])
-	metadata := cast.ToStringMap(secret.Data["metadata"])
-
-	version, _ := metadata["version"].(json.Number).Int64()
-
-	updatedAt, err := time.Parse(time.RFC3339, metadata["created_time"].(string))
-	if err != nil {
-		return nil, err
-	}
-
-	response := SecretItemResponse{
-		ID:        secretID,
-		Version:   int(version),
-		UpdatedAt: updatedAt,
-		Tags:      []string{},
-	}
-
-	if err := mapstructure.Decode(data["value"], &response); err != nil {
-		return nil, err
-	}
-
-	if !values {
-		// Clear the values otherwise
-		for k := range response.Values {
-			response.Values[k] = "<hidden>"
-		}
-	}
-
-	return &response, nil
-}
-
-// Retrieve secret secret/orgs/:orgid:/:id: scope
-func (ss *secretStore) Get(organizationID uint, secretID string) (*SecretItemResponse, error) {
-
-	path := secretDataPath(organizationID, secretID)
-
-	secret, err := ss.Logical.Read(path)
-
-	if err != nil {
-		return nil, errors.Wrap(err, "Error during reading secret")
-	}
-
-	if secret == nil {
-		return nil, ErrSecretNotExists
-	}
-
-	return parseSecret(secretID, secret, true)
-}
-
-// Retrieve secret by secret Name secret/orgs/:orgid:/:id: scope
-func (ss *secretStore) GetByName(organizationID uint, name string) (*SecretItemResponse, error) {
+// getByName retrieves a secret by its name
+func getByName(store SecretStore, organizationID uint, name string) (*SecretItemResponse, error) {
 
 	secretID := GenerateSecretIDFromName(name)
-	secret, err := Store.Get(organizationID, secretID)
+	secret, err := store.Get(organizationID, secretID)
 	if err == ErrSecretNotExists {
 		return nil, err
 	} else if err != nil {
@@ -490,82 +361,16 @@ func (ss *secretStore) GetByName(organizationID uint, name string) (*SecretItemR
 	return secret, nil
 }
 
-func (ss *secretStore) getSecretIDs(orgid uint, query *secretTypes.ListSecretsQuery) ([]string, error) {
-	if len(query.IDs) > 0 {
-		return query.IDs, nil
-	}
-
-	listPath := fmt.Sprintf("secret/metadata/orgs/%d", orgid)
-
-	list, err := ss.Logical.List(listPath)
-	if err != nil {
-		return nil, err
-	}
-
-	if list != nil {
-		keys := cast.ToStringSlice(list.Data["keys"])
-		res := make([]string, len(keys))
-		for i, key := range keys {
-			res[i] = string(key)
-		}
-		return res, nil
-	}
-
-	return nil, nil
+// matchesQuery checks whether a secret matches the type and tag filters of a list query
+func matchesQuery(secret *SecretItemResponse, query *secretTypes.ListSecretsQuery) bool {
+	return (query.Type == secretTypes.AllSecrets || secret.Type == query.Type) && hasTags(secret.Tags, query.Tags)
 }
 
-// List secret secret/orgs/:orgid:/ scope
-func (ss *secretStore) List(orgid uint, query *secretTypes.ListSecretsQuery) ([]*SecretItemResponse, error) {
-
-	log.Debugf("Searching for secrets [orgid: %d, query: %#v]", orgid, query)
-
-	secretIDs, err := ss.getSecretIDs(orgid, query)
-	if err != nil {
-		log.Errorf("Error listing secrets: %s", err.Error())
-		return nil, err
+// hideValues clears the secret values
+func hideValues(secret *SecretItemResponse) {
+	for k := range secret.Values {
+		secret.Values[k] = "<hidden>"
 	}
-
-	responseItems := []*SecretItemResponse{}
-
-	for _, secretID := range secretIDs {
-
-		if secret, err := ss.Logical.Read(secretDataPath(orgid, secretID)); err != nil {
-
-			log.Errorf("Error listing secrets: %s", err.Error())
-			return nil, err
-
-		} else if secret != nil {
-
-			sir, err := parseSecret(secretID, secret, query.Values)
-			if err != nil {
-				return nil, err
-			}
-
-			if (query.Type == secretTypes.AllSecrets || sir.Type == query.Type) && hasTags(sir.Tags, query.Tags) {
-				responseItems = append(responseItems, sir)
-			}
-		}
-	}
-
-	return responseItems, nil
-}
-
-func secretData(version int, request *CreateSecretRequest) (map[string]interface{}, error) {
-	valueData := map[string]interface{}{}
-
-	if err := mapstructure.Decode(request, &valueData); err != nil {
-		return nil, errors.Wrap(err, "Error during encoding secret")
-	}
-
-	return vault.NewData(version, map[string]interface{}{"value": valueData}), nil
-}
-
-func secretDataPath(organizationID uint, secretID string) string {
-	return fmt.Sprintf("secret/data/orgs/%d/%s", organizationID, secretID)
-}
-
-func secretMetadataPath(organizationID uint, secretID string) string {
-	return fmt.Sprintf("secret/metadata/orgs/%d/%s", organizationID, secretID)
 }
 
 func hasTags(tags []string, searchingTag []string) bool {
@@ -600,7 +405,9 @@ func IsCASError(err error) bool {
 	return strings.Contains(err.Error(), "check-and-set parameter did not match the current version")
 }
 
-func (ss *secretStore) generateValuesIfNeeded(organizationID uint, value *CreateSecretRequest) error {
+// generateValuesIfNeeded generates the values of TLS, password and htpasswd secrets if requested.
+// PKE secrets are generated by the secret store backends.
+func generateValuesIfNeeded(value *CreateSecretRequest) error {
 	if value.Type == secretTypes.TLSSecretType && len(value.Values) <= 2 {
 		// If we are not storing a full TLS secret instead of it's a request to generate one
 
@@ -660,163 +467,39 @@ func (ss *secretStore) generateValuesIfNeeded(organizationID uint, value *Create
 
 			value.Values[secretTypes.HtpasswdFile] = fmt.Sprintf("%s:%s", username, string(passwordHash))
 		}
-
-	} else if value.Type == secretTypes.PKESecretType {
-		clusterID := getClusterIDFromTags(value.Tags)
-		if clusterID == "" {
-			return errors.New("clusterID is missing from the tags")
-		}
-
-		mountInput := vaultapi.MountInput{
-			Type:        "pki",
-			Description: fmt.Sprintf("root PKI engine for cluster %s", clusterID),
-			Config: vaultapi.MountConfigInput{
-				MaxLeaseTTL:     "43801h",
-				DefaultLeaseTTL: "43801h",
-			},
-		}
-
-		// Mount a separate PKI engine for the cluster
-		basePath := clusterPKIPath(organizationID, clusterID)
-		path := fmt.Sprintf("%s/ca", basePath)
-
-		err := ss.Client.Vault().Sys().Mount(path, &mountInput)
-		if err != nil {
-			return errors.Wrapf(err, "Error mounting pki engine for cluster %s", clusterID)
-		}
-
-		// Generate the root CA
-		rootCAData := map[string]interface{}{
-			"common_name": fmt.Sprintf("cluster-%s-ca", clusterID),
-		}
-
-		_, err = ss.Logical.Write(fmt.Sprintf("%s/root/generate/internal", path), rootCAData)
-		if err != nil {
-			// Unmount the pki engine first
-			if err := ss.Client.Vault().Sys().Unmount(path); err != nil {
-				log.Warnf("failed to unmount %s: %s", path, err)
-			}
-			return errors.Wrapf(err, "Error generating root CA for cluster %s", clusterID)
-		}
-
-		// Get root CA
-		rootCA, err := ss.Logical.Read(fmt.Sprintf("%s/cert/ca", path))
-		if err != nil {
-			// Unmount the pki engine first
-			if err := ss.Client.Vault().Sys().Unmount(path); err != nil {
-				log.Warnf("failed to unmount %s: %s", path, err)
-			}
-			return errors.Wrapf(err, "Error reading root CA for cluster %s", clusterID)
-		}
-		ca := rootCA.Data["certificate"].(string)
-
-		// Generate the intermediate CAs
-		kubernetesCA, err := ss.generateIntermediateCert(clusterID, basePath, secretTypes.KubernetesCACommonName)
-		if err != nil {
-			// Unmount the pki backend first
-			if err := ss.Client.Vault().Sys().Unmount(path); err != nil {
-				log.Warnf("failed to unmount %s: %s", path, err)
-			}
-			return err
-		}
-
-		etcdCA, err := ss.generateIntermediateCert(clusterID, basePath, secretTypes.EtcdCACommonName)
-		if err != nil {
-			// Unmount the pki backend first
-			if err := ss.Client.Vault().Sys().Unmount(path); err != nil {
-				log.Warnf("failed to unmount %s: %s", path, err)
-			}
-			return err
-		}
-
-		frontProxyCA, err := ss.generateIntermediateCert(clusterID, basePath, secretTypes.KubernetesFrontProxyCACommonName)
-		if err != nil {
-			// Unmount the pki backend first
-			if err := ss.Client.Vault().Sys().Unmount(path); err != nil {
-				log.Warnf("failed to unmount %s: %s", path, err)
-			}
-			return err
-		}
-
-		// Service Account key-pair
-		saPub, saPriv, err := generateSAKeyPair(clusterID)
-		if err != nil {
-			return err
-		}
-
-		// Encryption Secret
-		var rnd = make([]byte, 32)
-		_, err = rand.Read(rnd)
-		if err != nil {
-			return err
-		}
-		encryptionSecret := base64.StdEncoding.EncodeToString(rnd)
-
-		value.Values[secretTypes.KubernetesCAKey] = kubernetesCA.Key
-		value.Values[secretTypes.KubernetesCACert] = kubernetesCA.Cert + "\n" + ca
-		value.Values[secretTypes.KubernetesCASigningCert] = kubernetesCA.Cert
-		value.Values[secretTypes.EtcdCAKey] = etcdCA.Key
-		value.Values[secretTypes.EtcdCACert] = etcdCA.Cert + "\n" + ca
-		value.Values[secretTypes.FrontProxyCAKey] = frontProxyCA.Key
-		value.Values[secretTypes.FrontProxyCACert] = frontProxyCA.Cert + "\n" + ca
-		value.Values[secretTypes.SAPub] = saPub
-		value.Values[secretTypes.SAKey] = saPriv
-		value.Values[secretTypes.EncryptionSecret] = encryptionSecret
 	}
 
 	return nil
 }
 
-func (ss *secretStore) generateIntermediateCert(clusterID, basePath, commonName string) (*certificate, error) {
-	mountInput := vaultapi.MountInput{
-		Type:        "pki",
-		Description: fmt.Sprintf("%s intermediate PKI engine for cluster %s", commonName, clusterID),
-		Config: vaultapi.MountConfigInput{
-			MaxLeaseTTL:     "43800h",
-			DefaultLeaseTTL: "43800h",
-		},
-	}
-
-	path := fmt.Sprintf("%s/%s", basePath, commonName)
-
-	// Each intermediate and ca cert needs it's own pki mount, see:
-	// https://github.com/hashicorp/vault/issues/1586#issuecomment-230300216
-	err := ss.Client.Vault().Sys().Mount(path, &mountInput)
+// setPKEValues fills a PKE secret with the generated CAs, service account key-pair and encryption secret
+func setPKEValues(value *CreateSecretRequest, clusterID, ca string, kubernetesCA, etcdCA, frontProxyCA *certificate) error {
+	// Service Account key-pair
+	saPub, saPriv, err := generateSAKeyPair(clusterID)
 	if err != nil {
-		return nil, errors.Wrapf(err, "error mounting %s intermediate pki engine for cluster %s", commonName, clusterID)
-	}
-
-	caData := map[string]interface{}{
-		"common_name": commonName,
+		return err
 	}
 
-	caSecret, err := ss.Logical.Write(fmt.Sprintf("%s/intermediate/generate/exported", path), caData)
+	// Encryption Secret
+	var rnd = make([]byte, 32)
+	_, err = rand.Read(rnd)
 	if err != nil {
-		// Unmount the pki backend first
-		if err := ss.Client.Vault().Sys().Unmount(path); err != nil {
-			log.Warnf("failed to unmount %s: %s", path, err)
-		}
-		return nil, errors.Wrapf(err, "error generating %s intermediate cert for cluster %s", commonName, clusterID)
-	}
-
-	caSignData := map[string]interface{}{
-		"csr":    caSecret.Data["csr"],
-		"format": "pem_bundle",
+		return err
 	}
+	encryptionSecret := base64.StdEncoding.EncodeToString(rnd)
 
-	caCertSecret, err := ss.Logical.Write(fmt.Sprintf("%s/ca/root/sign-intermediate", basePath), caSignData)
-	if err != nil {
-		// Unmount the pki backend first
-		if err := ss.Client.Vault().Sys().Unmount(path); err != nil {
-			log.Warnf("failed to unmount %s: %s", path, err)
-		}
-		return nil, errors.Wrapf(err, "error signing %s intermediate cert for cluster %s", commonName, clusterID)
-	}
+	value.Values[secretTypes.KubernetesCAKey] = kubernetesCA.Key
+	value.Values[secretTypes.KubernetesCACert] = kubernetesCA.Cert + "\n" + ca
+	value.Values[secretTypes.KubernetesCASigningCert] = kubernetesCA.Cert
+	value.Values[secretTypes.EtcdCAKey] = etcdCA.Key
+	value.Values[secretTypes.EtcdCACert] = etcdCA.Cert + "\n" + ca
+	value.Values[secretTypes.FrontProxyCAKey] = frontProxyCA.Key
+	value.Values[secretTypes.FrontProxyCACert] = frontProxyCA.Cert + "\n" + ca
+	value.Values[secretTypes.SAPub] = saPub
+	value.Values[secretTypes.SAKey] = saPriv
+	value.Values[secretTypes.EncryptionSecret] = encryptionSecret
 
-	return &certificate{
-		Key:  caSecret.Data["private_key"].(string),
-		Cert: caCertSecret.Data["certificate"].(string),
-	}, nil
+	return nil
 }
 
 type certificate struct {
@@ -842,10 +525,6 @@ func getClusterIDFromTags(tags []string) string {
 	return ""
 }
 
-func clusterPKIPath(organizationID uint, clusterID string) string {
-	return fmt.Sprintf("clusters/%d/%s/pki", organizationID, clusterID)
-}
-
 func generateSAKeyPair(clusterID string) (pub, priv string, err error) {
 	pk, err := rsa.GenerateKey(rand.Reader, rsaKeySize)
 	if err != nil {
@@ -0,0 +1,448 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	secretTypes "github.com/banzaicloud/pipeline/pkg/secret"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// TableName constants
+const (
+	secretVersionTableName = "secret_versions"
+)
+
+// errCASMismatch uses the same message as Vault so that IsCASError works for every backend
+// nolint: gochecknoglobals
+var errCASMismatch = errors.New("check-and-set parameter did not match the current version")
+
+// secretVersionModel is a single, encrypted version of a secret.
+type secretVersionModel struct {
+	ID             uint      `gorm:"primary_key"`
+	OrganizationID uint      `gorm:"not null;unique_index:idx_secret_versions_org_secret_version"`
+	SecretID       string    `gorm:"size:64;not null;unique_index:idx_secret_versions_org_secret_version"`
+	Version        int       `gorm:"not null;unique_index:idx_secret_versions_org_secret_version"`
+	Data           string    `gorm:"type:text;not null"`
+	CreatedAt      time.Time `gorm:"not null"`
+}
+
+// TableName specifies a database table name for the model.
+func (secretVersionModel) TableName() string {
+	return secretVersionTableName
+}
+
+// secretVersionData is the content of a secret version before encryption.
+type secretVersionData struct {
+	Name      string            `json:"name"`
+	Type      string            `json:"type"`
+	Values    map[string]string `json:"values"`
+	Tags      []string          `json:"tags"`
+	UpdatedBy string            `json:"updatedBy,omitempty"`
+}
+
+// databaseSecretStore stores secrets encrypted in the Pipeline database.
+// Every write creates a new version, writes are guarded by the same check-and-set rules as in Vault.
+type databaseSecretStore struct {
+	db     *gorm.DB
+	cipher cipher.AEAD
+}
+
+func newDatabaseSecretStore(db *gorm.DB, keyFile string) (*databaseSecretStore, error) {
+	if keyFile == "" {
+		return nil, errors.New("encryption key file is required for the database secret store")
+	}
+
+	encodedKey, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read encryption key file")
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encodedKey)))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode encryption key")
+	}
+
+	return newDatabaseSecretStoreWithKey(db, key)
+}
+
+func newDatabaseSecretStoreWithKey(db *gorm.DB, key []byte) (*databaseSecretStore, error) {
+	if len(key) != 32 {
+		return nil, errors.New("encryption key must be 256 bits long")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create cipher")
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create cipher")
+	}
+
+	return &databaseSecretStore{db: db, cipher: aead}, nil
+}
+
+// DeleteByClusterUID Delete secrets by ClusterUID
+func (ss *databaseSecretStore) DeleteByClusterUID(orgID uint, clusterUID string) error {
+	return deleteByClusterUID(ss, orgID, clusterUID)
+}
+
+// Delete deletes every version of a secret
+func (ss *databaseSecretStore) Delete(organizationID uint, secretID string) error {
+
+	log.Debugln("Delete secret:", organizationID, secretID)
+
+	if _, err := ss.Get(organizationID, secretID); err != nil {
+		return errors.Wrap(err, "Error during querying secret before deletion")
+	}
+
+	err := ss.db.Where(&secretVersionModel{OrganizationID: organizationID, SecretID: secretID}).Delete(&secretVersionModel{}).Error
+	if err != nil {
+		return errors.Wrap(err, "Error during deleting secret")
+	}
+
+	return nil
+}
+
+// Store saves a new secret
+func (ss *databaseSecretStore) Store(organizationID uint, request *CreateSecretRequest) (string, error) {
+
+	// We allow only Kubernetes compatible Secret names
+	if errorList := validation.IsDNS1123Subdomain(request.Name); errorList != nil {
+		return "", errors.New(errorList[0])
+	}
+
+	secretID := GenerateSecretID(request)
+
+	if err := generateValuesIfNeeded(request); err != nil {
+		return "", err
+	}
+
+	if request.Type == secretTypes.PKESecretType {
+		if err := generateLocalPKEValues(request); err != nil {
+			return "", err
+		}
+	}
+
+	sort.Strings(request.Tags)
+
+	if err := ss.write(organizationID, secretID, 0, request); err != nil {
+		return "", errors.Wrap(err, "Error during storing secret")
+	}
+
+	return secretID, nil
+}
+
+// Update creates a new version of an existing secret
+func (ss *databaseSecretStore) Update(organizationID uint, secretID string, request *CreateSecretRequest) error {
+
+	if GenerateSecretID(request) != secretID {
+		return errors.New("Secret name cannot be changed")
+	}
+
+	log.Debugln("Update secret:", organizationID, secretID)
+
+	sort.Strings(request.Tags)
+
+	// If secret doesn't exists, create it.
+	version := 0
+	if request.Version != nil {
+		version = *request.Version
+	}
+
+	if err := ss.write(organizationID, secretID, version, request); err != nil {
+		return errors.Wrap(err, "Error during updating secret")
+	}
+
+	return nil
+}
+
+// write stores a new version of the secret if the current version matches cas.
+// A zero cas means the secret must not exist yet.
+func (ss *databaseSecretStore) write(organizationID uint, secretID string, cas int, request *CreateSecretRequest) error {
+	tx := ss.db.Begin()
+	if err := tx.Error; err != nil {
+		return err
+	}
+
+	current, err := latestSecretVersion(tx, organizationID, secretID)
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		tx.Rollback()
+		return err
+	}
+
+	currentVersion := 0
+	if err == nil {
+		currentVersion = current.Version
+	}
+
+	if currentVersion != cas {
+		tx.Rollback()
+		return errCASMismatch
+	}
+
+	model := secretVersionModel{
+		OrganizationID: organizationID,
+		SecretID:       secretID,
+		Version:        currentVersion + 1,
+	}
+
+	model.Data, err = ss.encrypt(&model, secretVersionData{
+		Name:      request.Name,
+		Type:      request.Type,
+		Values:    request.Values,
+		Tags:      request.Tags,
+		UpdatedBy: request.UpdatedBy,
+	})
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Create(&model).Error; err != nil {
+		tx.Rollback()
+		return ss.writeError(err, &model)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return ss.writeError(err, &model)
+	}
+
+	return nil
+}
+
+// writeError returns errCASMismatch if a failed write lost the race against a concurrent write of the same version.
+// Concurrent writers pass the version check at the same time, but only one of them can insert the next version.
+func (ss *databaseSecretStore) writeError(err error, model *secretVersionModel) error {
+	latest, latestErr := latestSecretVersion(ss.db, model.OrganizationID, model.SecretID)
+	if latestErr == nil && latest.Version >= model.Version {
+		return errCASMismatch
+	}
+
+	return err
+}
+
+// GetOrCreate create new secret or get if it's exist.
+func (ss *databaseSecretStore) GetOrCreate(organizationID uint, value *CreateSecretRequest) (string, error) {
+	return getOrCreate(ss, organizationID, value)
+}
+
+// CreateOrUpdate create new secret or update if it's exist.
+func (ss *databaseSecretStore) CreateOrUpdate(organizationID uint, value *CreateSecretRequest) (string, error) {
+	return createOrUpdate(ss, organizationID, value)
+}
+
+// Get retrieves the latest version of a secret
+func (ss *databaseSecretStore) Get(organizationID uint, secretID string) (*SecretItemResponse, error) {
+
+	model, err := latestSecretVersion(ss.db, organizationID, secretID)
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, ErrSecretNotExists
+	} else if err != nil {
+		return nil, errors.Wrap(err, "Error during reading secret")
+	}
+
+	return ss.parseSecret(model)
+}
+
+// GetByName retrieves a secret by its name
+func (ss *databaseSecretStore) GetByName(organizationID uint, name string) (*SecretItemResponse, error) {
+	return getByName(ss, organizationID, name)
+}
+
+// List lists the secrets of an organization
+func (ss *databaseSecretStore) List(orgid uint, query *secretTypes.ListSecretsQuery) ([]*SecretItemResponse, error) {
+
+	log.Debugf("Searching for secrets [orgid: %d, query: %#v]", orgid, query)
+
+	secretIDs := query.IDs
+	if len(secretIDs) == 0 {
+		err := ss.db.Model(&secretVersionModel{}).
+			Where("organization_id = ?", orgid).
+			Order("secret_id").
+			Pluck("DISTINCT secret_id", &secretIDs).Error
+		if err != nil {
+			log.Errorf("Error listing secrets: %s", err.Error())
+			return nil, err
+		}
+	}
+
+	responseItems := []*SecretItemResponse{}
+
+	for _, secretID := range secretIDs {
+		sir, err := ss.Get(orgid, secretID)
+		if err == ErrSecretNotExists {
+			continue
+		} else if err != nil {
+			log.Errorf("Error listing secrets: %s", err.Error())
+			return nil, err
+		}
+
+		if !query.Values {
+			hideValues(sir)
+		}
+
+		if matchesQuery(sir, query) {
+			responseItems = append(responseItems, sir)
+		}
+	}
+
+	return responseItems, nil
+}
+
+// ListVersions lists the available versions of a secret, latest first
+func (ss *databaseSecretStore) ListVersions(organizationID uint, secretID string) ([]*SecretVersionResponse, error) {
+	var models []secretVersionModel
+
+	err := ss.db.
+		Where(&secretVersionModel{OrganizationID: organizationID, SecretID: secretID}).
+		Order("version DESC").
+		Find(&models).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "Error during reading secret versions")
+	}
+
+	if len(models) == 0 {
+		return nil, ErrSecretNotExists
+	}
+
+	versions := make([]*SecretVersionResponse, 0, len(models))
+
+	for i := range models {
+		item, err := ss.parseSecret(&models[i])
+		if err != nil {
+			return nil, err
+		}
+
+		versions = append(versions, versionResponse(item, models[0].Version))
+	}
+
+	return versions, nil
+}
+
+// GetVersion retrieves a specific version of a secret
+func (ss *databaseSecretStore) GetVersion(organizationID uint, secretID string, version int) (*SecretItemResponse, error) {
+	var model secretVersionModel
+
+	err := ss.db.
+		Where(&secretVersionModel{OrganizationID: organizationID, SecretID: secretID, Version: version}).
+		First(&model).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, ErrSecretVersionNotExists
+	} else if err != nil {
+		return nil, errors.Wrap(err, "Error during reading secret version")
+	}
+
+	return ss.parseSecret(&model)
+}
+
+// RestoreVersion writes the content of an older version as the new current version of the secret
+func (ss *databaseSecretStore) RestoreVersion(organizationID uint, secretID string, version int, updatedBy string) error {
+	return restoreVersion(ss, organizationID, secretID, version, updatedBy)
+}
+
+func (ss *databaseSecretStore) parseSecret(model *secretVersionModel) (*SecretItemResponse, error) {
+	var data secretVersionData
+	if err := ss.decrypt(model, &data); err != nil {
+		return nil, err
+	}
+
+	response := SecretItemResponse{
+		ID:        model.SecretID,
+		Name:      data.Name,
+		Type:      data.Type,
+		Values:    data.Values,
+		Tags:      data.Tags,
+		Version:   model.Version,
+		UpdatedAt: model.CreatedAt,
+		UpdatedBy: data.UpdatedBy,
+	}
+
+	if response.Tags == nil {
+		response.Tags = []string{}
+	}
+
+	return &response, nil
+}
+
+// additionalData binds the ciphertext of a secret version to its row,
+// so that it cannot be decrypted after being copied to another organization, secret or version.
+func additionalData(model *secretVersionModel) []byte {
+	return []byte(fmt.Sprintf("%d/%s/%d", model.OrganizationID, model.SecretID, model.Version))
+}
+
+func (ss *databaseSecretStore) encrypt(model *secretVersionModel, data secretVersionData) (string, error) {
+	plaintext, err := json.Marshal(data)
+	if err != nil {
+		return "", errors.Wrap(err, "Error during encoding secret")
+	}
+
+	nonce := make([]byte, ss.cipher.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", errors.Wrap(err, "failed to generate nonce")
+	}
+
+	ciphertext := ss.cipher.Seal(nonce, nonce, plaintext, additionalData(model))
+
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+func (ss *databaseSecretStore) decrypt(model *secretVersionModel, data *secretVersionData) error {
+	ciphertext, err := base64.StdEncoding.DecodeString(model.Data)
+	if err != nil {
+		return errors.Wrap(err, "failed to decode secret")
+	}
+
+	nonceSize := ss.cipher.NonceSize()
+	if len(ciphertext) < nonceSize {
+		return errors.New("failed to decrypt secret: ciphertext too short")
+	}
+
+	plaintext, err := ss.cipher.Open(nil, ciphertext[:nonceSize], ciphertext[nonceSize:], additionalData(model))
+	if err != nil {
+		return errors.Wrap(err, "failed to decrypt secret")
+	}
+
+	return errors.Wrap(json.Unmarshal(plaintext, data), "Error during decoding secret")
+}
+
+func latestSecretVersion(db *gorm.DB, organizationID uint, secretID string) (*secretVersionModel, error) {
+	var model secretVersionModel
+
+	err := db.
+		Where(&secretVersionModel{OrganizationID: organizationID, SecretID: secretID}).
+		Order("version DESC").
+		First(&model).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &model, nil
+}
@@ -0,0 +1,365 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/banzaicloud/pipeline/config"
+	secretTypes "github.com/banzaicloud/pipeline/pkg/secret"
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDatabaseSecretStore(t *testing.T) (*gorm.DB, *databaseSecretStore) {
+	db, err := gorm.Open("sqlite3", "file::memory:")
+	require.NoError(t, err)
+
+	require.NoError(t, db.AutoMigrate(&secretVersionModel{}).Error)
+
+	store, err := newDatabaseSecretStoreWithKey(db, bytes.Repeat([]byte{1}, 32))
+	require.NoError(t, err)
+
+	return db, store
+}
+
+func newTestPasswordRequest(name string, tags ...string) *CreateSecretRequest {
+	return &CreateSecretRequest{
+		Name: name,
+		Type: secretTypes.PasswordSecretType,
+		Values: map[string]string{
+			secretTypes.Username: "user",
+			secretTypes.Password: "pass",
+		},
+		Tags: tags,
+	}
+}
+
+func TestDatabaseSecretStore_StoreAndGet(t *testing.T) {
+	db, store := newTestDatabaseSecretStore(t)
+	defer db.Close()
+
+	secretID, err := store.Store(1, newTestPasswordRequest("my-secret", "b", "a"))
+	require.NoError(t, err)
+	assert.Equal(t, GenerateSecretIDFromName("my-secret"), secretID)
+
+	item, err := store.Get(1, secretID)
+	require.NoError(t, err)
+	assert.Equal(t, "my-secret", item.Name)
+	assert.Equal(t, 1, item.Version)
+	assert.Equal(t, "pass", item.Values[secretTypes.Password])
+	assert.Equal(t, []string{"a", "b"}, item.Tags)
+
+	var model secretVersionModel
+	require.NoError(t, db.First(&model).Error)
+	assert.NotContains(t, model.Data, "pass")
+
+	_, err = store.Get(2, secretID)
+	assert.Equal(t, ErrSecretNotExists, err)
+
+	_, err = store.Store(1, newTestPasswordRequest("my-secret"))
+	require.Error(t, err)
+	assert.True(t, IsCASError(err))
+}
+
+func TestDatabaseSecretStore_CopiedRow(t *testing.T) {
+	db, store := newTestDatabaseSecretStore(t)
+	defer db.Close()
+
+	secretID, err := store.Store(1, newTestPasswordRequest("my-secret"))
+	require.NoError(t, err)
+
+	var model secretVersionModel
+	require.NoError(t, db.First(&model).Error)
+
+	otherSecretID := GenerateSecretIDFromName("other-secret")
+
+	for _, copied := range []secretVersionModel{
+		{OrganizationID: 2, SecretID: secretID, Version: 1, Data: model.Data},
+		{OrganizationID: 1, SecretID: otherSecretID, Version: 1, Data: model.Data},
+	} {
+		copied := copied
+		require.NoError(t, db.Create(&copied).Error)
+
+		_, err = store.Get(copied.OrganizationID, copied.SecretID)
+		assert.Error(t, err)
+	}
+}
+
+func TestDatabaseSecretStore_Update(t *testing.T) {
+	db, store := newTestDatabaseSecretStore(t)
+	defer db.Close()
+
+	secretID, err := store.Store(1, newTestPasswordRequest("my-secret"))
+	require.NoError(t, err)
+
+	request := newTestPasswordRequest("my-secret")
+	request.Values[secretTypes.Password] = "changed"
+
+	err = store.Update(1, secretID, request)
+	assert.True(t, IsCASError(err), "update without version must fail for an existing secret")
+
+	version := 1
+	request.Version = &version
+	require.NoError(t, store.Update(1, secretID, request))
+
+	err = store.Update(1, secretID, request)
+	assert.True(t, IsCASError(err), "update with a stale version must fail")
+
+	item, err := store.Get(1, secretID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, item.Version)
+	assert.Equal(t, "changed", item.Values[secretTypes.Password])
+
+	err = store.Update(1, secretID, newTestPasswordRequest("other-secret"))
+	assert.EqualError(t, err, "Secret name cannot be changed")
+}
+
+func TestDatabaseSecretStore_ConcurrentWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secret-store")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// WAL mode lets a second connection commit while the first one is between its version check and insert
+	dsn := filepath.Join(dir, "secrets.db") + "?_journal_mode=WAL&_busy_timeout=5000"
+
+	db, err := gorm.Open("sqlite3", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NoError(t, db.AutoMigrate(&secretVersionModel{}).Error)
+
+	otherDB, err := gorm.Open("sqlite3", dsn)
+	require.NoError(t, err)
+	defer otherDB.Close()
+
+	key := bytes.Repeat([]byte{1}, 32)
+
+	store, err := newDatabaseSecretStoreWithKey(db, key)
+	require.NoError(t, err)
+
+	otherStore, err := newDatabaseSecretStoreWithKey(otherDB, key)
+	require.NoError(t, err)
+
+	secretID, err := store.Store(1, newTestPasswordRequest("my-secret"))
+	require.NoError(t, err)
+
+	version := 1
+
+	var concurrentErr error
+	concurrentWrite := false
+	db.Callback().Create().Before("gorm:create").Register("test:concurrent_write", func(scope *gorm.Scope) {
+		if concurrentWrite {
+			return
+		}
+		concurrentWrite = true
+
+		request := newTestPasswordRequest("my-secret")
+		request.Version = &version
+		concurrentErr = otherStore.Update(1, secretID, request)
+	})
+
+	request := newTestPasswordRequest("my-secret")
+	request.Values[secretTypes.Password] = "lost"
+	request.Version = &version
+
+	err = store.Update(1, secretID, request)
+	require.NoError(t, concurrentErr)
+	assert.True(t, IsCASError(err), "the write losing the race must fail with a CAS error, got: %v", err)
+
+	item, err := store.Get(1, secretID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, item.Version)
+	assert.Equal(t, "pass", item.Values[secretTypes.Password])
+}
+
+func TestDatabaseSecretStore_GetOrCreateAndCreateOrUpdate(t *testing.T) {
+	db, store := newTestDatabaseSecretStore(t)
+	defer db.Close()
+
+	secretID, err := store.GetOrCreate(1, newTestPasswordRequest("my-secret"))
+	require.NoError(t, err)
+
+	request := newTestPasswordRequest("my-secret")
+	request.Values[secretTypes.Password] = "changed"
+
+	sameID, err := store.GetOrCreate(1, request)
+	require.NoError(t, err)
+	assert.Equal(t, secretID, sameID)
+
+	item, err := store.GetByName(1, "my-secret")
+	require.NoError(t, err)
+	assert.Equal(t, "pass", item.Values[secretTypes.Password])
+
+	_, err = store.CreateOrUpdate(1, request)
+	require.NoError(t, err)
+
+	item, err = store.GetByName(1, "my-secret")
+	require.NoError(t, err)
+	assert.Equal(t, 2, item.Version)
+	assert.Equal(t, "changed", item.Values[secretTypes.Password])
+}
+
+func TestDatabaseSecretStore_ListAndDelete(t *testing.T) {
+	db, store := newTestDatabaseSecretStore(t)
+	defer db.Close()
+
+	_, err := store.Store(1, newTestPasswordRequest("first", clusterUIDTag("uid")))
+	require.NoError(t, err)
+	_, err = store.Store(1, newTestPasswordRequest("second"))
+	require.NoError(t, err)
+	_, err = store.Store(2, newTestPasswordRequest("third", clusterUIDTag("uid")))
+	require.NoError(t, err)
+
+	items, err := store.List(1, &secretTypes.ListSecretsQuery{Type: secretTypes.AllSecrets})
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	for _, item := range items {
+		assert.Equal(t, "<hidden>", item.Values[secretTypes.Password])
+	}
+
+	items, err = store.List(1, &secretTypes.ListSecretsQuery{Type: secretTypes.AllSecrets, Tags: []string{clusterUIDTag("uid")}, Values: true})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "first", items[0].Name)
+	assert.Equal(t, "pass", items[0].Values[secretTypes.Password])
+
+	require.NoError(t, store.DeleteByClusterUID(1, "uid"))
+
+	items, err = store.List(1, &secretTypes.ListSecretsQuery{Type: secretTypes.AllSecrets})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "second", items[0].Name)
+
+	items, err = store.List(2, &secretTypes.ListSecretsQuery{Type: secretTypes.AllSecrets})
+	require.NoError(t, err)
+	assert.Len(t, items, 1)
+
+	assert.Error(t, store.Delete(1, GenerateSecretIDFromName("first")))
+}
+
+func TestDatabaseSecretStore_GeneratePKE(t *testing.T) {
+	db, store := newTestDatabaseSecretStore(t)
+	defer db.Close()
+
+	secretID, err := store.Store(1, &CreateSecretRequest{
+		Name:   "pke-ca",
+		Type:   secretTypes.PKESecretType,
+		Values: map[string]string{},
+		Tags:   []string{clusterIDTagName + ":1"},
+	})
+	require.NoError(t, err)
+
+	item, err := store.Get(1, secretID)
+	require.NoError(t, err)
+
+	for _, key := range []string{
+		secretTypes.KubernetesCAKey,
+		secretTypes.KubernetesCACert,
+		secretTypes.EtcdCAKey,
+		secretTypes.FrontProxyCAKey,
+		secretTypes.SAPub,
+		secretTypes.EncryptionSecret,
+	} {
+		assert.NotEmpty(t, item.Values[key], key)
+	}
+}
+
+func TestDatabaseSecretStore_Versions(t *testing.T) {
+	db, store := newTestDatabaseSecretStore(t)
+	defer db.Close()
+
+	request := newTestPasswordRequest("my-secret")
+	request.UpdatedBy = "alice"
+	secretID, err := store.Store(1, request)
+	require.NoError(t, err)
+
+	request = newTestPasswordRequest("my-secret")
+	request.Values[secretTypes.Password] = "changed"
+	request.UpdatedBy = "bob"
+	_, err = store.CreateOrUpdate(1, request)
+	require.NoError(t, err)
+
+	versions, err := store.ListVersions(1, secretID)
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, 2, versions[0].Version)
+	assert.Equal(t, "bob", versions[0].UpdatedBy)
+	assert.True(t, versions[0].Current)
+	assert.Equal(t, 1, versions[1].Version)
+	assert.Equal(t, "alice", versions[1].UpdatedBy)
+	assert.False(t, versions[1].Current)
+
+	item, err := store.GetVersion(1, secretID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "pass", item.Values[secretTypes.Password])
+
+	_, err = store.GetVersion(1, secretID, 5)
+	assert.Equal(t, ErrSecretVersionNotExists, err)
+
+	_, err = store.ListVersions(1, GenerateSecretIDFromName("missing"))
+	assert.Equal(t, ErrSecretNotExists, err)
+
+	require.NoError(t, store.RestoreVersion(1, secretID, 1, "carol"))
+
+	item, err = store.Get(1, secretID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, item.Version)
+	assert.Equal(t, "pass", item.Values[secretTypes.Password])
+	assert.Equal(t, "carol", item.UpdatedBy)
+}
+
+func TestInitStore(t *testing.T) {
+	db, err := gorm.Open("sqlite3", "file::memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	store, restrictedStore := Store, RestrictedStore
+	defer func() {
+		Store, RestrictedStore = store, restrictedStore
+		viper.Set(config.SecretStoreBackend, nil)
+		viper.Set(config.SecretStoreDatabaseKeyFile, nil)
+	}()
+
+	keyFile, err := ioutil.TempFile("", "secret-key")
+	require.NoError(t, err)
+	defer os.Remove(keyFile.Name())
+
+	_, err = keyFile.WriteString(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)))
+	require.NoError(t, err)
+	require.NoError(t, keyFile.Close())
+
+	viper.Set(config.SecretStoreBackend, DatabaseBackend)
+
+	assert.Error(t, InitStore(db))
+
+	viper.Set(config.SecretStoreDatabaseKeyFile, keyFile.Name())
+
+	require.NoError(t, InitStore(db))
+	assert.IsType(t, &databaseSecretStore{}, Store)
+	assert.Equal(t, Store, RestrictedStore.SecretStore)
+
+	viper.Set(config.SecretStoreBackend, "unknown")
+
+	assert.Error(t, InitStore(db))
+}
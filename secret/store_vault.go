@@ -0,0 +1,520 @@
+// Copyright © 2018 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/banzaicloud/bank-vaults/pkg/vault"
+	secretTypes "github.com/banzaicloud/pipeline/pkg/secret"
+	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/spf13/cast"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// vaultSecretStore stores secrets in Vault KV v2 under secret/orgs/:orgid:/:id:
+type vaultSecretStore struct {
+	Client  *vault.Client
+	Logical *vaultapi.Logical
+}
+
+func newVaultSecretStore() *vaultSecretStore {
+	role := "pipeline"
+	client, err := vault.NewClient(role)
+	if err != nil {
+		panic(err)
+	}
+	logical := client.Vault().Logical()
+	return &vaultSecretStore{Client: client, Logical: logical}
+}
+
+// DeleteByClusterUID Delete secrets by ClusterUID
+func (ss *vaultSecretStore) DeleteByClusterUID(orgID uint, clusterUID string) error {
+	return deleteByClusterUID(ss, orgID, clusterUID)
+}
+
+// Delete secret secret/orgs/:orgid:/:id: scope
+func (ss *vaultSecretStore) Delete(organizationID uint, secretID string) error {
+
+	path := secretMetadataPath(organizationID, secretID)
+
+	log.Debugln("Delete secret:", path)
+
+	secret, err := ss.Get(organizationID, secretID)
+	if err != nil {
+		return errors.Wrap(err, "Error during querying secret before deletion")
+	}
+
+	if _, err := ss.Logical.Delete(path); err != nil {
+		return errors.Wrap(err, "Error during deleting secret")
+	}
+
+	// if type is distribution, unmount all pki engines
+	if secret.Type == secretTypes.PKESecretType {
+		clusterID := getClusterIDFromTags(secret.Tags)
+		basePath := clusterPKIPath(organizationID, clusterID)
+
+		path = fmt.Sprintf("%s/ca", basePath)
+		err = ss.Client.Vault().Sys().Unmount(path)
+		if err != nil {
+			log.Warnf("failed to unmount %s: %s", path, err)
+		}
+
+		path = fmt.Sprintf("%s/%s", basePath, secretTypes.KubernetesCACommonName)
+		err = ss.Client.Vault().Sys().Unmount(path)
+		if err != nil {
+			log.Warnf("failed to unmount %s: %s", path, err)
+		}
+
+		path = fmt.Sprintf("%s/%s", basePath, secretTypes.EtcdCACommonName)
+		err = ss.Client.Vault().Sys().Unmount(path)
+		if err != nil {
+			log.Warnf("failed to unmount %s: %s", path, err)
+		}
+
+		path = fmt.Sprintf("%s/%s", basePath, secretTypes.KubernetesFrontProxyCACommonName)
+		err = ss.Client.Vault().Sys().Unmount(path)
+		if err != nil {
+			log.Warnf("failed to unmount %s: %s", path, err)
+		}
+	}
+
+	return nil
+}
+
+// Save secret secret/orgs/:orgid:/:id: scope
+func (ss *vaultSecretStore) Store(organizationID uint, request *CreateSecretRequest) (string, error) {
+
+	// We allow only Kubernetes compatible Secret names
+	if errorList := validation.IsDNS1123Subdomain(request.Name); errorList != nil {
+		return "", errors.New(errorList[0])
+	}
+
+	secretID := GenerateSecretID(request)
+	path := secretDataPath(organizationID, secretID)
+
+	if err := generateValuesIfNeeded(request); err != nil {
+		return "", err
+	}
+
+	if request.Type == secretTypes.PKESecretType {
+		if err := ss.generatePKEValues(organizationID, request); err != nil {
+			return "", err
+		}
+	}
+
+	sort.Strings(request.Tags)
+
+	data, err := secretData(0, request)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := ss.Logical.Write(path, data); err != nil {
+		return "", errors.Wrap(err, "Error during storing secret")
+	}
+
+	return secretID, nil
+}
+
+// Update secret secret/orgs/:orgid:/:id: scope
+func (ss *vaultSecretStore) Update(organizationID uint, secretID string, request *CreateSecretRequest) error {
+
+	if GenerateSecretID(request) != secretID {
+		return errors.New("Secret name cannot be changed")
+	}
+
+	path := secretDataPath(organizationID, secretID)
+
+	log.Debugln("Update secret:", path)
+
+	sort.Strings(request.Tags)
+
+	// If secret doesn't exists, create it.
+	version := 0
+	if request.Version != nil {
+		version = *request.Version
+	}
+
+	data, err := secretData(version, request)
+	if err != nil {
+		return err
+	}
+
+	if _, err := ss.Logical.Write(path, data); err != nil {
+		return errors.Wrap(err, "Error during updating secret")
+	}
+
+	return nil
+}
+
+// GetOrCreate create new secret or get if it's exist. secret/orgs/:orgid:/:id: scope
+func (ss *vaultSecretStore) GetOrCreate(organizationID uint, value *CreateSecretRequest) (string, error) {
+	return getOrCreate(ss, organizationID, value)
+}
+
+// CreateOrUpdate create new secret or update if it's exist. secret/orgs/:orgid:/:id: scope
+func (ss *vaultSecretStore) CreateOrUpdate(organizationID uint, value *CreateSecretRequest) (string, error) {
+	return createOrUpdate(ss, organizationID, value)
+}
+
+func parseSecret(secretID string, secret *vaultapi.Secret, values bool) (*SecretItemResponse, error) {
+
+	data := cast.ToStringMap(secret.Data["data"])
+	metadata := cast.ToStringMap(secret.Data["metadata"])
+
+	version, _ := metadata["version"].(json.Number).Int64()
+
+	updatedAt, err := time.Parse(time.RFC3339, metadata["created_time"].(string))
+	if err != nil {
+		return nil, err
+	}
+
+	response := SecretItemResponse{
+		ID:        secretID,
+		Version:   int(version),
+		UpdatedAt: updatedAt,
+		Tags:      []string{},
+	}
+
+	if err := mapstructure.Decode(data["value"], &response); err != nil {
+		return nil, err
+	}
+
+	if !values {
+		hideValues(&response)
+	}
+
+	return &response, nil
+}
+
+// Retrieve secret secret/orgs/:orgid:/:id: scope
+func (ss *vaultSecretStore) Get(organizationID uint, secretID string) (*SecretItemResponse, error) {
+
+	path := secretDataPath(organizationID, secretID)
+
+	secret, err := ss.Logical.Read(path)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Error during reading secret")
+	}
+
+	if secret == nil {
+		return nil, ErrSecretNotExists
+	}
+
+	return parseSecret(secretID, secret, true)
+}
+
+// Retrieve secret by secret Name secret/orgs/:orgid:/:id: scope
+func (ss *vaultSecretStore) GetByName(organizationID uint, name string) (*SecretItemResponse, error) {
+	return getByName(ss, organizationID, name)
+}
+
+// ListVersions lists the available versions of a secret, latest first
+func (ss *vaultSecretStore) ListVersions(organizationID uint, secretID string) ([]*SecretVersionResponse, error) {
+
+	metadata, err := ss.Logical.Read(secretMetadataPath(organizationID, secretID))
+	if err != nil {
+		return nil, errors.Wrap(err, "Error during reading secret metadata")
+	}
+
+	if metadata == nil {
+		return nil, ErrSecretNotExists
+	}
+
+	currentVersionNumber, ok := metadata.Data["current_version"].(json.Number)
+	if !ok {
+		return nil, errors.Errorf("invalid current version in secret metadata: %v", metadata.Data["current_version"])
+	}
+
+	currentVersion, err := currentVersionNumber.Int64()
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid current version in secret metadata")
+	}
+
+	versions := []*SecretVersionResponse{}
+
+	for key, value := range cast.ToStringMap(metadata.Data["versions"]) {
+		versionMetadata := cast.ToStringMap(value)
+
+		// Deleted and destroyed versions cannot be read anymore
+		if cast.ToBool(versionMetadata["destroyed"]) || cast.ToString(versionMetadata["deletion_time"]) != "" {
+			continue
+		}
+
+		version, err := strconv.Atoi(key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid secret version: %s", key)
+		}
+
+		item, err := ss.GetVersion(organizationID, secretID, version)
+		if err == ErrSecretVersionNotExists {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		versions = append(versions, versionResponse(item, int(currentVersion)))
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Version > versions[j].Version
+	})
+
+	return versions, nil
+}
+
+// GetVersion retrieves a specific version of a secret
+func (ss *vaultSecretStore) GetVersion(organizationID uint, secretID string, version int) (*SecretItemResponse, error) {
+
+	path := secretDataPath(organizationID, secretID)
+
+	secret, err := ss.Logical.ReadWithData(path, map[string][]string{"version": {strconv.Itoa(version)}})
+	if err != nil {
+		return nil, errors.Wrap(err, "Error during reading secret version")
+	}
+
+	if secret == nil || secret.Data["data"] == nil {
+		return nil, ErrSecretVersionNotExists
+	}
+
+	return parseSecret(secretID, secret, true)
+}
+
+// RestoreVersion writes the content of an older version as the new current version of the secret
+func (ss *vaultSecretStore) RestoreVersion(organizationID uint, secretID string, version int, updatedBy string) error {
+	return restoreVersion(ss, organizationID, secretID, version, updatedBy)
+}
+
+func (ss *vaultSecretStore) getSecretIDs(orgid uint, query *secretTypes.ListSecretsQuery) ([]string, error) {
+	if len(query.IDs) > 0 {
+		return query.IDs, nil
+	}
+
+	listPath := fmt.Sprintf("secret/metadata/orgs/%d", orgid)
+
+	list, err := ss.Logical.List(listPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if list != nil {
+		keys := cast.ToStringSlice(list.Data["keys"])
+		res := make([]string, len(keys))
+		for i, key := range keys {
+			res[i] = string(key)
+		}
+		return res, nil
+	}
+
+	return nil, nil
+}
+
+// List secret secret/orgs/:orgid:/ scope
+func (ss *vaultSecretStore) List(orgid uint, query *secretTypes.ListSecretsQuery) ([]*SecretItemResponse, error) {
+
+	log.Debugf("Searching for secrets [orgid: %d, query: %#v]", orgid, query)
+
+	secretIDs, err := ss.getSecretIDs(orgid, query)
+	if err != nil {
+		log.Errorf("Error listing secrets: %s", err.Error())
+		return nil, err
+	}
+
+	responseItems := []*SecretItemResponse{}
+
+	for _, secretID := range secretIDs {
+
+		if secret, err := ss.Logical.Read(secretDataPath(orgid, secretID)); err != nil {
+
+			log.Errorf("Error listing secrets: %s", err.Error())
+			return nil, err
+
+		} else if secret != nil {
+
+			sir, err := parseSecret(secretID, secret, query.Values)
+			if err != nil {
+				return nil, err
+			}
+
+			if matchesQuery(sir, query) {
+				responseItems = append(responseItems, sir)
+			}
+		}
+	}
+
+	return responseItems, nil
+}
+
+func secretData(version int, request *CreateSecretRequest) (map[string]interface{}, error) {
+	valueData := map[string]interface{}{}
+
+	if err := mapstructure.Decode(request, &valueData); err != nil {
+		return nil, errors.Wrap(err, "Error during encoding secret")
+	}
+
+	return vault.NewData(version, map[string]interface{}{"value": valueData}), nil
+}
+
+func secretDataPath(organizationID uint, secretID string) string {
+	return fmt.Sprintf("secret/data/orgs/%d/%s", organizationID, secretID)
+}
+
+func secretMetadataPath(organizationID uint, secretID string) string {
+	return fmt.Sprintf("secret/metadata/orgs/%d/%s", organizationID, secretID)
+}
+
+func (ss *vaultSecretStore) generatePKEValues(organizationID uint, value *CreateSecretRequest) error {
+	clusterID := getClusterIDFromTags(value.Tags)
+	if clusterID == "" {
+		return errors.New("clusterID is missing from the tags")
+	}
+
+	mountInput := vaultapi.MountInput{
+		Type:        "pki",
+		Description: fmt.Sprintf("root PKI engine for cluster %s", clusterID),
+		Config: vaultapi.MountConfigInput{
+			MaxLeaseTTL:     "43801h",
+			DefaultLeaseTTL: "43801h",
+		},
+	}
+
+	// Mount a separate PKI engine for the cluster
+	basePath := clusterPKIPath(organizationID, clusterID)
+	path := fmt.Sprintf("%s/ca", basePath)
+
+	err := ss.Client.Vault().Sys().Mount(path, &mountInput)
+	if err != nil {
+		return errors.Wrapf(err, "Error mounting pki engine for cluster %s", clusterID)
+	}
+
+	// Generate the root CA
+	rootCAData := map[string]interface{}{
+		"common_name": fmt.Sprintf("cluster-%s-ca", clusterID),
+	}
+
+	_, err = ss.Logical.Write(fmt.Sprintf("%s/root/generate/internal", path), rootCAData)
+	if err != nil {
+		// Unmount the pki engine first
+		if err := ss.Client.Vault().Sys().Unmount(path); err != nil {
+			log.Warnf("failed to unmount %s: %s", path, err)
+		}
+		return errors.Wrapf(err, "Error generating root CA for cluster %s", clusterID)
+	}
+
+	// Get root CA
+	rootCA, err := ss.Logical.Read(fmt.Sprintf("%s/cert/ca", path))
+	if err != nil {
+		// Unmount the pki engine first
+		if err := ss.Client.Vault().Sys().Unmount(path); err != nil {
+			log.Warnf("failed to unmount %s: %s", path, err)
+		}
+		return errors.Wrapf(err, "Error reading root CA for cluster %s", clusterID)
+	}
+	ca := rootCA.Data["certificate"].(string)
+
+	// Generate the intermediate CAs
+	kubernetesCA, err := ss.generateIntermediateCert(clusterID, basePath, secretTypes.KubernetesCACommonName)
+	if err != nil {
+		// Unmount the pki backend first
+		if err := ss.Client.Vault().Sys().Unmount(path); err != nil {
+			log.Warnf("failed to unmount %s: %s", path, err)
+		}
+		return err
+	}
+
+	etcdCA, err := ss.generateIntermediateCert(clusterID, basePath, secretTypes.EtcdCACommonName)
+	if err != nil {
+		// Unmount the pki backend first
+		if err := ss.Client.Vault().Sys().Unmount(path); err != nil {
+			log.Warnf("failed to unmount %s: %s", path, err)
+		}
+		return err
+	}
+
+	frontProxyCA, err := ss.generateIntermediateCert(clusterID, basePath, secretTypes.KubernetesFrontProxyCACommonName)
+	if err != nil {
+		// Unmount the pki backend first
+		if err := ss.Client.Vault().Sys().Unmount(path); err != nil {
+			log.Warnf("failed to unmount %s: %s", path, err)
+		}
+		return err
+	}
+
+	return setPKEValues(value, clusterID, ca, kubernetesCA, etcdCA, frontProxyCA)
+}
+
+func (ss *vaultSecretStore) generateIntermediateCert(clusterID, basePath, commonName string) (*certificate, error) {
+	mountInput := vaultapi.MountInput{
+		Type:        "pki",
+		Description: fmt.Sprintf("%s intermediate PKI engine for cluster %s", commonName, clusterID),
+		Config: vaultapi.MountConfigInput{
+			MaxLeaseTTL:     "43800h",
+			DefaultLeaseTTL: "43800h",
+		},
+	}
+
+	path := fmt.Sprintf("%s/%s", basePath, commonName)
+
+	// Each intermediate and ca cert needs it's own pki mount, see:
+	// https://github.com/hashicorp/vault/issues/1586#issuecomment-230300216
+	err := ss.Client.Vault().Sys().Mount(path, &mountInput)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error mounting %s intermediate pki engine for cluster %s", commonName, clusterID)
+	}
+
+	caData := map[string]interface{}{
+		"common_name": commonName,
+	}
+
+	caSecret, err := ss.Logical.Write(fmt.Sprintf("%s/intermediate/generate/exported", path), caData)
+	if err != nil {
+		// Unmount the pki backend first
+		if err := ss.Client.Vault().Sys().Unmount(path); err != nil {
+			log.Warnf("failed to unmount %s: %s", path, err)
+		}
+		return nil, errors.Wrapf(err, "error generating %s intermediate cert for cluster %s", commonName, clusterID)
+	}
+
+	caSignData := map[string]interface{}{
+		"csr":    caSecret.Data["csr"],
+		"format": "pem_bundle",
+	}
+
+	caCertSecret, err := ss.Logical.Write(fmt.Sprintf("%s/ca/root/sign-intermediate", basePath), caSignData)
+	if err != nil {
+		// Unmount the pki backend first
+		if err := ss.Client.Vault().Sys().Unmount(path); err != nil {
+			log.Warnf("failed to unmount %s: %s", path, err)
+		}
+		return nil, errors.Wrapf(err, "error signing %s intermediate cert for cluster %s", commonName, clusterID)
+	}
+
+	return &certificate{
+		Key:  caSecret.Data["private_key"].(string),
+		Cert: caCertSecret.Data["certificate"].(string),
+	}, nil
+}
+
+func clusterPKIPath(organizationID uint, clusterID string) string {
+	return fmt.Sprintf("clusters/%d/%s/pki", organizationID, clusterID)
+}
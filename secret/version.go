@@ -0,0 +1,76 @@
+// Copyright © 2019 Banzai Cloud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrSecretVersionNotExists denotes 'Not Found' errors for secret versions
+// nolint: gochecknoglobals
+var ErrSecretVersionNotExists = fmt.Errorf("There's no secret version with this ID")
+
+// SecretVersionResponse describes a single version of a secret
+type SecretVersionResponse struct {
+	Version   int       `json:"version"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	UpdatedBy string    `json:"updatedBy,omitempty"`
+	Current   bool      `json:"current"`
+}
+
+// restoreVersion writes the content of an older secret version as the new current version
+func restoreVersion(store SecretStore, organizationID uint, secretID string, version int, updatedBy string) error {
+	current, err := store.Get(organizationID, secretID)
+	if err != nil {
+		return err
+	}
+
+	if current.Version == version {
+		return nil
+	}
+
+	item, err := store.GetVersion(organizationID, secretID, version)
+	if err != nil {
+		return err
+	}
+
+	request := CreateSecretRequest{
+		Name:      item.Name,
+		Type:      item.Type,
+		Values:    item.Values,
+		Tags:      item.Tags,
+		Version:   &current.Version,
+		UpdatedBy: updatedBy,
+	}
+
+	if err := store.Update(organizationID, secretID, &request); err != nil {
+		return errors.Wrapf(err, "Error during restoring secret version %d", version)
+	}
+
+	return nil
+}
+
+// versionResponse creates a version description from a secret
+func versionResponse(item *SecretItemResponse, currentVersion int) *SecretVersionResponse {
+	return &SecretVersionResponse{
+		Version:   item.Version,
+		UpdatedAt: item.UpdatedAt,
+		UpdatedBy: item.UpdatedBy,
+		Current:   item.Version == currentVersion,
+	}
+}